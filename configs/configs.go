@@ -21,6 +21,8 @@ import (
 	"github.com/araddon/dateparse"
 	"github.com/caarlos0/env/v11"
 	"github.com/komkom/toml"
+
+	"codeberg.org/readeck/readeck/pkg/urlcanon"
 )
 
 var (
@@ -48,6 +50,8 @@ type config struct {
 	Bookmarks    configBookmarks `json:"bookmarks"`
 	Worker       configWorker    `json:"worker"`
 	Metrics      configMetrics   `json:"metrics"`
+	OIDC         configOIDC      `json:"oidc"`
+	Storage      configStorage   `json:"storage"`
 	Commissioned bool            `json:"-"`
 }
 
@@ -78,7 +82,22 @@ type configSession struct {
 }
 
 type configBookmarks struct {
-	PublicShareTTL int `json:"public_share_ttl" env:"PUBLIC_SHARE_TTL"`
+	PublicShareTTL   int `json:"public_share_ttl" env:"PUBLIC_SHARE_TTL"`
+	FeedPollInterval int `json:"feed_poll_interval" env:"FEED_POLL_INTERVAL"` // in minutes
+	TrashRetention   int `json:"trash_retention" env:"TRASH_RETENTION"`       // in days
+	// TrackingParams is the list of query parameters removed from
+	// a new bookmark's URL. An entry can contain a "*" wildcard.
+	TrackingParams []string `json:"tracking_params" env:"TRACKING_PARAMS"`
+	// MergeDuplicates returns the existing bookmark instead of creating
+	// a new one when a URL was already saved.
+	MergeDuplicates bool `json:"merge_duplicates" env:"MERGE_DUPLICATES"`
+	// EPUBImageWidth and EPUBImageHeight are the maximum dimensions,
+	// in pixels, of the images in an e-book. They should match the
+	// screen of the e-readers.
+	EPUBImageWidth  int `json:"epub_image_width" env:"EPUB_IMAGE_WIDTH"`
+	EPUBImageHeight int `json:"epub_image_height" env:"EPUB_IMAGE_HEIGHT"`
+	// EPUBGrayscale converts the images of an e-book to grayscale.
+	EPUBGrayscale bool `json:"epub_grayscale" env:"EPUB_GRAYSCALE"`
 }
 
 type configEmail struct {
@@ -91,12 +110,22 @@ type configEmail struct {
 	Insecure    bool            `json:"insecure" env:"MAIL_INSECURE,unset"`
 	From        configEmailAddr `json:"from" env:"MAIL_FROM,unset"`
 	FromNoReply configEmailAddr `json:"from_noreply" env:"MAIL_FROMNOREPLY,unset"`
+	Inbound     configInbound   `json:"inbound"`
+}
+
+type configInbound struct {
+	Host     string `json:"host" env:"MAIL_INBOUND_HOST"`
+	Port     int    `json:"port" env:"MAIL_INBOUND_PORT"`
+	Protocol string `json:"protocol" env:"MAIL_INBOUND_PROTOCOL"` // smtp or lmtp
+	Domain   string `json:"domain" env:"MAIL_INBOUND_DOMAIN"`
+	MaxSize  int    `json:"max_size" env:"MAIL_INBOUND_MAX_SIZE"` // in MiB
 }
 
 type configWorker struct {
 	DSN         string `json:"dsn" env:"WORKER_DSN,unset"`
 	NumWorkers  int    `json:"num_workers" env:"WORKER_NUMBER"`
 	StartWorker bool   `json:"start_worker" env:"WORKER_START"`
+	Retries     int    `json:"retries" env:"WORKER_RETRIES"`
 }
 
 type configExtractor struct {
@@ -111,6 +140,43 @@ type configMetrics struct {
 	Port int    `json:"port" env:"METRICS_PORT"`
 }
 
+type configOIDC struct {
+	Issuer        string            `json:"issuer" env:"OIDC_ISSUER"`
+	ClientID      string            `json:"client_id" env:"OIDC_CLIENT_ID"`
+	ClientSecret  string            `json:"client_secret" env:"OIDC_CLIENT_SECRET,unset"`
+	Scopes        []string          `json:"scopes" env:"OIDC_SCOPES"`
+	Label         string            `json:"label" env:"OIDC_LABEL"`
+	AutoProvision bool              `json:"auto_provision" env:"OIDC_AUTO_PROVISION"`
+	DefaultGroup  string            `json:"default_group" env:"OIDC_DEFAULT_GROUP"`
+	GroupClaim    string            `json:"group_claim" env:"OIDC_GROUP_CLAIM"`
+	GroupMap      map[string]string `json:"group_map" env:"OIDC_GROUP_MAP"`
+}
+
+type configStorage struct {
+	Type string          `json:"type" env:"STORAGE_TYPE"` // local or s3
+	S3   configStorageS3 `json:"s3"`
+}
+
+type configStorageS3 struct {
+	Endpoint  string `json:"endpoint" env:"STORAGE_S3_ENDPOINT"`
+	Region    string `json:"region" env:"STORAGE_S3_REGION"`
+	Bucket    string `json:"bucket" env:"STORAGE_S3_BUCKET"`
+	Prefix    string `json:"prefix" env:"STORAGE_S3_PREFIX"`
+	AccessKey string `json:"access_key" env:"STORAGE_S3_ACCESS_KEY,unset"`
+	SecretKey string `json:"secret_key" env:"STORAGE_S3_SECRET_KEY,unset"`
+	PathStyle bool   `json:"path_style" env:"STORAGE_S3_PATH_STYLE"`
+}
+
+// Enabled returns true when the inbound email server is configured.
+func (c configInbound) Enabled() bool {
+	return c.Port > 0 && c.Domain != ""
+}
+
+// Enabled returns true when an OpenID Connect provider is configured.
+func (c configOIDC) Enabled() bool {
+	return c.Issuer != "" && c.ClientID != ""
+}
+
 type configEmailAddr struct {
 	*mail.Address
 }
@@ -314,14 +380,26 @@ var Config = config{
 	Database: configDB{},
 	Email: configEmail{
 		Port: 25,
+		Inbound: configInbound{
+			Host:     "127.0.0.1",
+			Port:     0,
+			Protocol: "smtp",
+			MaxSize:  25,
+		},
 	},
 	Bookmarks: configBookmarks{
-		PublicShareTTL: 24,
+		PublicShareTTL:   24,
+		FeedPollInterval: 30,
+		TrashRetention:   30,
+		TrackingParams:   urlcanon.DefaultTrackingParams,
+		EPUBImageWidth:   1264,
+		EPUBImageHeight:  1680,
 	},
 	Worker: configWorker{
 		DSN:         "memory://",
 		NumWorkers:  max(1, runtime.NumCPU()-1),
 		StartWorker: true,
+		Retries:     3,
 	},
 	Extractor: configExtractor{
 		NumWorkers:     runtime.NumCPU(),
@@ -336,6 +414,16 @@ var Config = config{
 		Host: "127.0.0.1",
 		Port: 0,
 	},
+	OIDC: configOIDC{
+		Scopes:        []string{"openid", "profile", "email"},
+		Label:         "Single Sign-On",
+		AutoProvision: true,
+		DefaultGroup:  "user",
+		GroupMap:      map[string]string{},
+	},
+	Storage: configStorage{
+		Type: "local",
+	},
 }
 
 // LoadConfiguration loads the configuration file.
@@ -365,6 +453,8 @@ func InitConfiguration() {
 
 	Config.Email.From.setDefault()
 	Config.Email.FromNoReply.setDefault()
+	Config.Email.Inbound.Domain = strings.ToLower(strings.TrimSpace(Config.Email.Inbound.Domain))
+	Config.Email.Inbound.Protocol = strings.ToLower(Config.Email.Inbound.Protocol)
 
 	if Config.Server.BaseURL != nil {
 		Config.Server.BaseURL.normalize()
@@ -130,6 +130,26 @@ func TestEnvVars(t *testing.T) {
 			assert.NoError(err)
 			assert.Equal(48, cf.Bookmarks.PublicShareTTL)
 		}},
+		{"READECK_TRASH_RETENTION", "7", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal(7, cf.Bookmarks.TrashRetention)
+		}},
+		{"READECK_TRACKING_PARAMS", "utm_*,ref", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal([]string{"utm_*", "ref"}, cf.Bookmarks.TrackingParams)
+		}},
+		{"READECK_MERGE_DUPLICATES", "1", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.True(cf.Bookmarks.MergeDuplicates)
+		}},
+		{"READECK_EPUB_IMAGE_WIDTH", "1072", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal(1072, cf.Bookmarks.EPUBImageWidth)
+		}},
+		{"READECK_EPUB_GRAYSCALE", "true", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.True(cf.Bookmarks.EPUBGrayscale)
+		}},
 		{"READECK_WORKER_DSN", "memory://", func(assert *require.Assertions, cf config, err error) {
 			assert.NoError(err)
 			assert.Equal("memory://", cf.Worker.DSN)
@@ -146,6 +166,10 @@ func TestEnvVars(t *testing.T) {
 			assert.NoError(err)
 			assert.True(cf.Worker.StartWorker)
 		}},
+		{"READECK_WORKER_RETRIES", "5", func(assert *require.Assertions, cf config, err error) {
+			assert.NoError(err)
+			assert.Equal(5, cf.Worker.Retries)
+		}},
 		{"READECK_METRICS_HOST", "::1", func(assert *require.Assertions, cf config, err error) {
 			assert.NoError(err)
 			assert.Equal("::1", cf.Metrics.Host)
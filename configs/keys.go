@@ -17,6 +17,9 @@ const (
 	keyToken   = "api_token"
 	keySession = "session"
 	keyCSRF    = "csrf"
+	keyOIDC    = "oidc"
+	keyTOTP    = "totp"
+	keyLogins  = "site_logins"
 )
 
 // KeyMaterial contains the signing and encryption keys.
@@ -25,6 +28,9 @@ type KeyMaterial struct {
 	tokenKey   []byte
 	sessionKey []byte
 	csrfKey    []byte
+	oidcKey    []byte
+	totpKey    []byte
+	loginsKey  []byte
 }
 
 func hkdfHashFunc() hash.Hash {
@@ -54,6 +60,24 @@ func (km KeyMaterial) CSRFKey() []byte {
 	return km.csrfKey
 }
 
+// OIDCKey returns a 256-bit key used by the OpenID Connect
+// authorization request's secure cookie.
+func (km KeyMaterial) OIDCKey() []byte {
+	return km.oidcKey
+}
+
+// TOTPKey returns a 256-bit key used by the secure cookie
+// holding a login waiting for its two-factor authentication.
+func (km KeyMaterial) TOTPKey() []byte {
+	return km.totpKey
+}
+
+// LoginsKey returns a 256-bit key used to encrypt the
+// users' website credentials.
+func (km KeyMaterial) LoginsKey() []byte {
+	return km.loginsKey
+}
+
 func (km KeyMaterial) mustExpand(name string, keyLength int) []byte {
 	k, err := km.Expand(name, keyLength)
 	if err != nil {
@@ -77,4 +101,7 @@ func loadKeys() {
 	Keys.tokenKey = Keys.mustExpand(keyToken, 32)
 	Keys.sessionKey = Keys.mustExpand(keySession, 32)
 	Keys.csrfKey = Keys.mustExpand(keyCSRF, 32)
+	Keys.oidcKey = Keys.mustExpand(keyOIDC, 32)
+	Keys.totpKey = Keys.mustExpand(keyTOTP, 32)
+	Keys.loginsKey = Keys.mustExpand(keyLogins, 32)
 }
@@ -84,11 +84,11 @@ func TestGetPermissions(t *testing.T) {
 		},
 		{
 			[]string{"scoped_bookmarks_r"},
-			[]string{"api:bookmarks:collections:read", "api:bookmarks:export", "api:bookmarks:read", "api:opds:read", "api:profile:read", "api:profile:tokens:delete"},
+			[]string{"api:bookmarks:collections:read", "api:bookmarks:export", "api:bookmarks:feeds:read", "api:bookmarks:newspapers:read", "api:bookmarks:read", "api:opds:read", "api:profile:read", "api:profile:tokens:delete"},
 		},
 		{
 			[]string{"scoped_bookmarks_w"},
-			[]string{"api:bookmarks:collections:write", "api:bookmarks:write", "api:profile:read", "api:profile:tokens:delete"},
+			[]string{"api:bookmarks:collections:write", "api:bookmarks:feeds:write", "api:bookmarks:newspapers:write", "api:bookmarks:write", "api:profile:read", "api:profile:tokens:delete"},
 		},
 		{
 			[]string{"unknown"},
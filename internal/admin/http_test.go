@@ -178,6 +178,35 @@ func TestPermissions(t *testing.T) {
 					}
 				},
 			},
+			RequestTest{
+				Target: "/admin/tasks",
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin":
+						r.AssertStatus(t, 200)
+					case "":
+						r.AssertStatus(t, 303)
+						r.AssertRedirect(t, "/login")
+					default:
+						r.AssertStatus(t, 403)
+					}
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/admin/tasks/RuXBpzio59ktWTEHDodLPU/retry",
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin":
+						r.AssertStatus(t, 404)
+					case "":
+						r.AssertStatus(t, 303)
+						r.AssertRedirect(t, "/login")
+					default:
+						r.AssertStatus(t, 403)
+					}
+				},
+			},
 			RequestTest{
 				Method: "POST",
 				Target: fmt.Sprintf("/admin/users/%s/delete", u2.User.UID),
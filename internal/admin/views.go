@@ -39,6 +39,15 @@ func newAdminViews(api *adminAPI) *adminViews {
 		r.With(api.withUser).Post("/users/{uid:[a-zA-Z0-9]{18,22}}/delete", h.userDelete)
 	})
 
+	r.With(api.srv.WithPermission("admin:tasks", "read")).Group(func(r chi.Router) {
+		r.With(h.withDeadLetterList).Get("/tasks", h.taskList)
+	})
+
+	r.With(api.srv.WithPermission("admin:tasks", "write")).Group(func(r chi.Router) {
+		r.With(h.withDeadLetter).Post("/tasks/{uid:[a-zA-Z0-9]{18,22}}/retry", h.taskRetry)
+		r.With(h.withDeadLetter).Post("/tasks/{uid:[a-zA-Z0-9]{18,22}}/delete", h.taskDelete)
+	})
+
 	return h
 }
 
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package admin
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/internal/server"
+)
+
+type (
+	ctxDeadLetterListKey struct{}
+	ctxDeadLetterKey     struct{}
+)
+
+type deadLetterList struct {
+	Pagination server.Pagination
+	Items      []*bus.DeadLetter
+}
+
+func (h *adminViews) taskList(w http.ResponseWriter, r *http.Request) {
+	tr := h.srv.Locale(r)
+	dl := r.Context().Value(ctxDeadLetterListKey{}).(deadLetterList)
+
+	ctx := server.TC{
+		"Pagination": dl.Pagination,
+		"Tasks":      dl.Items,
+		"Protocol":   bus.Protocol(),
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Failed Tasks")},
+	})
+
+	h.srv.RenderTemplate(w, r, 200, "/admin/task_list", ctx)
+}
+
+func (h *adminViews) taskRetry(w http.ResponseWriter, r *http.Request) {
+	d := r.Context().Value(ctxDeadLetterKey{}).(*bus.DeadLetter)
+
+	if err := d.Retry(); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	tr := h.srv.Locale(r)
+	h.srv.AddFlash(w, r, "success", tr.Gettext("The task was launched again."))
+	h.srv.Redirect(w, r, "/admin/tasks")
+}
+
+func (h *adminViews) taskDelete(w http.ResponseWriter, r *http.Request) {
+	d := r.Context().Value(ctxDeadLetterKey{}).(*bus.DeadLetter)
+
+	if err := d.Delete(); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	tr := h.srv.Locale(r)
+	h.srv.AddFlash(w, r, "success", tr.Gettext("Task removed."))
+	h.srv.Redirect(w, r, "/admin/tasks")
+}
+
+func (h *adminViews) withDeadLetterList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := deadLetterList{}
+
+		pf := h.srv.GetPageParams(r, 50)
+		if pf == nil {
+			h.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ds := bus.DeadLetters.Query().
+			Order(goqu.I("created").Desc()).
+			Limit(uint(pf.Limit())).
+			Offset(uint(pf.Offset()))
+
+		count, err := ds.ClearOrder().ClearLimit().ClearOffset().Count()
+		if err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+
+		res.Items = []*bus.DeadLetter{}
+		if err = ds.ScanStructs(&res.Items); err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+
+		res.Pagination = h.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset())
+
+		ctx := context.WithValue(r.Context(), ctxDeadLetterListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *adminViews) withDeadLetter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d, err := bus.DeadLetters.GetOne(goqu.C("uid").Eq(chi.URLParam(r, "uid")))
+		if err != nil {
+			h.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxDeadLetterKey{}, d)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
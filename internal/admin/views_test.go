@@ -5,13 +5,16 @@
 package admin_test
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/url"
 	"testing"
 
+	"github.com/doug-martin/goqu/v9"
 	"github.com/stretchr/testify/require"
 
+	"codeberg.org/readeck/readeck/internal/bus"
 	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
 )
 
@@ -185,4 +188,74 @@ func TestViews(t *testing.T) {
 			},
 		)
 	})
+
+	t.Run("tasks", func(t *testing.T) {
+		d1 := &bus.DeadLetter{
+			Name:     "bookmark.create",
+			OpID:     "12",
+			Data:     `{"id":12}`,
+			Attempts: 4,
+			Error:    "some failure",
+		}
+		d2 := &bus.DeadLetter{
+			Name:     "user.delete",
+			OpID:     `"abc"`,
+			Data:     "3",
+			Attempts: 4,
+			Error:    "another failure",
+		}
+		for _, d := range []*bus.DeadLetter{d1, d2} {
+			require.NoError(t, bus.DeadLetters.Create(d))
+		}
+
+		RunRequestSequence(t, client, "admin",
+			RequestTest{
+				Target:         "/admin/tasks",
+				ExpectStatus:   200,
+				ExpectContains: "some failure",
+			},
+			RequestTest{
+				Method:         "POST",
+				Target:         "/admin/tasks/" + d1.UID + "/retry",
+				ExpectStatus:   303,
+				ExpectRedirect: "/admin/tasks",
+				Assert: func(t *testing.T, _ *Response) {
+					assert := require.New(t)
+					evt := map[string]interface{}{}
+
+					// The task was launched with its ID and data
+					assert.Len(Events().Records("task"), 1)
+					assert.NoError(json.Unmarshal(Events().Records("task")[0], &evt))
+					assert.Equal("bookmark.create", evt["name"])
+					assert.Equal("12", fmt.Sprint(evt["id"]))
+
+					p := map[string]interface{}{}
+					assert.NoError(json.Unmarshal([]byte(Store().Get("tasks:bookmark.create:12")), &p))
+					assert.Equal(base64.StdEncoding.EncodeToString([]byte(d1.Data)), p["data"])
+				},
+			},
+			RequestTest{
+				Target:         "/admin/tasks",
+				ExpectStatus:   200,
+				ExpectContains: "another failure",
+				Assert: func(t *testing.T, r *Response) {
+					require.NotContains(t, string(r.Body), "some failure")
+				},
+			},
+			RequestTest{
+				Method:         "POST",
+				Target:         "/admin/tasks/" + d2.UID + "/delete",
+				ExpectStatus:   303,
+				ExpectRedirect: "/admin/tasks",
+			},
+			RequestTest{
+				Target:         "/admin/tasks",
+				ExpectStatus:   200,
+				ExpectContains: "There is no failed task.",
+			},
+		)
+
+		_, err := bus.DeadLetters.GetOne(goqu.C("id").Eq(d2.ID))
+		require.ErrorIs(t, err, bus.ErrDeadLetterNotFound)
+	})
 }
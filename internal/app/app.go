@@ -105,6 +105,11 @@ func InitApp() {
 	}
 	bookmarks.LoadContentScripts()
 
+	// Bookmark file storage
+	if err := bookmarks.InitStorage(); err != nil {
+		fatal("can't initialize file storage", err)
+	}
+
 	// Database URL
 	dsn, err := url.Parse(configs.Config.Database.Source)
 	if err != nil {
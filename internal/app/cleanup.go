@@ -10,13 +10,14 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
-	"path/filepath"
+	"path"
 	"strings"
 
 	"github.com/cristalhq/acmd"
 	"github.com/doug-martin/goqu/v9"
 
 	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/pkg/storage"
 )
 
 func init() {
@@ -81,20 +82,29 @@ func removeLoadingBookmarks() error {
 }
 
 func removeOrphanFiles() error {
-	dirs, err := filepath.Glob(filepath.Join(bookmarks.StoragePath(), "*/*.zip"))
+	files := []string{}
+	err := bookmarks.Storage().Walk("", func(info storage.FileInfo) error {
+		if ok, _ := path.Match("*/*.zip", info.Name); ok {
+			files = append(files, info.Name)
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 	i := 0
-	for _, x := range dirs {
-		bookmarkID := strings.TrimSuffix(filepath.Base(x), ".zip")
-		_, err := bookmarks.Bookmarks.GetOne(goqu.C("uid").Eq(bookmarkID))
-		if err == nil {
-			continue
-		}
-		if !errors.Is(err, bookmarks.ErrBookmarkNotFound) {
+	for _, x := range files {
+		bookmarkID := strings.TrimSuffix(path.Base(x), ".zip")
+		// Bookmarks in the trash keep their files
+		found, err := bookmarks.Bookmarks.QueryAll().
+			Where(goqu.C("uid").Eq(bookmarkID)).
+			Count()
+		if err != nil {
 			return err
 		}
+		if found > 0 {
+			continue
+		}
 
 		l := slog.With(
 			slog.String("file", x),
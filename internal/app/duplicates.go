@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/cristalhq/acmd"
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+)
+
+func init() {
+	commands = append(commands, acmd.Command{
+		Name:        "merge-duplicates",
+		Description: "Merge the bookmarks saved more than once",
+		ExecFunc:    runMergeDuplicates,
+	})
+}
+
+func runMergeDuplicates(_ context.Context, args []string) error {
+	var username string
+
+	var flags appFlags
+	fs := flags.Flags()
+	fs.StringVar(&username, "user", "", "username (all users when empty)")
+	fs.StringVar(&username, "u", "", "username (shorthand)")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	// Init application
+	if err := appPreRun(&flags); err != nil {
+		return err
+	}
+	defer appPostRun()
+
+	userID := 0
+	if username != "" {
+		u, err := users.Users.GetOne(goqu.C("username").Eq(username))
+		if err != nil {
+			return fmt.Errorf("user %s not found", username)
+		}
+		userID = u.ID
+	}
+
+	println("⚙️ merging duplicate bookmarks")
+	count, err := tasks.MergeDuplicates(userID)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		fmt.Printf("  ✅ %d bookmark(s) merged and moved to the trash\n", count)
+	} else {
+		println("  ⭐ no duplicates")
+	}
+	return nil
+}
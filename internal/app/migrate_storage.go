@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/cristalhq/acmd"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/pkg/storage"
+)
+
+func init() {
+	commands = append(commands, acmd.Command{
+		Name:        "migrate-storage",
+		Description: "Copy bookmark files from a storage to another",
+		ExecFunc:    runMigrateStorage,
+	})
+}
+
+func runMigrateStorage(_ context.Context, args []string) error {
+	var from, to string
+	var deleteSource bool
+
+	var flags appFlags
+	fs := flags.Flags()
+	fs.StringVar(&from, "from", "local", "source storage type (local or s3)")
+	fs.StringVar(&to, "to", "s3", "destination storage type (local or s3)")
+	fs.BoolVar(&deleteSource, "delete", false, "remove the files from the source storage once copied")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	if from == to {
+		return errors.New("source and destination storages must be different")
+	}
+
+	// Init application
+	if err := appPreRun(&flags); err != nil {
+		return err
+	}
+	defer appPostRun()
+
+	src, err := bookmarks.NewStorage(from)
+	if err != nil {
+		return err
+	}
+	dst, err := bookmarks.NewStorage(to)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("⚙️ copying files from %s to %s\n", from, to)
+	copied, skipped, err := migrateStorage(dst, src, deleteSource)
+	fmt.Printf("  ✅ %d file(s) copied, %d file(s) already present\n", copied, skipped)
+	return err
+}
+
+// migrateStorage copies every file from src to dst. Files that
+// already exist in dst, with the same size, are skipped.
+func migrateStorage(dst, src storage.Storage, deleteSource bool) (copied, skipped int, err error) {
+	files := []storage.FileInfo{}
+	err = src.Walk("", func(info storage.FileInfo) error {
+		files = append(files, info)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	for _, x := range files {
+		l := slog.With(slog.String("file", x.Name))
+
+		info, err := dst.Stat(x.Name)
+		switch {
+		case err == nil && info.Size == x.Size:
+			skipped++
+		case err == nil || errors.Is(err, os.ErrNotExist):
+			if err = storage.Copy(dst, x.Name, src, x.Name); err != nil {
+				return copied, skipped, err
+			}
+			l.Debug("file copied")
+			copied++
+		default:
+			return copied, skipped, err
+		}
+
+		if deleteSource {
+			if err = src.Remove(x.Name); err != nil {
+				return copied, skipped, err
+			}
+			l.Debug("source file removed")
+		}
+	}
+
+	return
+}
@@ -31,11 +31,14 @@ import (
 	"codeberg.org/readeck/readeck/internal/bus"
 	"codeberg.org/readeck/readeck/internal/cookbook"
 	"codeberg.org/readeck/readeck/internal/dashboard"
+	"codeberg.org/readeck/readeck/internal/kosync"
 	"codeberg.org/readeck/readeck/internal/metrics"
+	"codeberg.org/readeck/readeck/internal/newsletters"
 	"codeberg.org/readeck/readeck/internal/opds"
 	"codeberg.org/readeck/readeck/internal/profile"
 	"codeberg.org/readeck/readeck/internal/server"
 	"codeberg.org/readeck/readeck/internal/videoplayer"
+	"codeberg.org/readeck/readeck/internal/wallabag"
 )
 
 type serveFlags struct {
@@ -124,6 +127,26 @@ func runServer(_ context.Context, args []string) error {
 		}()
 	}
 
+	// Start the inbound email server
+	var inbound *newsletters.Server
+	if configs.Config.Email.Inbound.Enabled() {
+		inbound = newsletters.NewServer()
+		addr := net.JoinHostPort(
+			configs.Config.Email.Inbound.Host,
+			strconv.Itoa(configs.Config.Email.Inbound.Port),
+		)
+		slog.Info("inbound email server",
+			slog.String("protocol", configs.Config.Email.Inbound.Protocol),
+			slog.String("addr", addr),
+			slog.String("domain", configs.Config.Email.Inbound.Domain),
+		)
+		go func() {
+			if err := inbound.ListenAndServe(addr); err != nil {
+				fatal("cannot start the inbound email server", err)
+			}
+		}()
+	}
+
 	// Start the embed standalone worker.
 	startBus := configs.Config.Worker.StartWorker || bus.Protocol() == "memory"
 	if startBus {
@@ -175,6 +198,12 @@ func runServer(_ context.Context, args []string) error {
 	}
 	slog.Info("server stopped")
 
+	if inbound != nil {
+		if err := inbound.Close(); err != nil {
+			slog.Error("inbound email server shutdown", slog.Any("err", err))
+		}
+	}
+
 	if startBus {
 		slog.Info("stopping workers...")
 		bus.Tasks().Stop()
@@ -211,6 +240,12 @@ func InitServer(s *server.Server) error {
 	// OPDS routes
 	opds.SetupRoutes(s)
 
+	// Wallabag API routes
+	wallabag.SetupRoutes(s)
+
+	// KOReader progress sync routes
+	kosync.SetupRoutes(s)
+
 	// User routes
 	profile.SetupRoutes(s)
 
@@ -22,12 +22,13 @@ import (
 
 	"codeberg.org/readeck/readeck/internal/auth/users"
 	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/types"
 )
 
 func init() {
 	commands = append(commands, acmd.Command{
 		Name:        "user",
-		Description: "Create or update a user",
+		Description: "Create or update a user, or reset their two-factor authentication",
 		ExecFunc:    runUser,
 	})
 }
@@ -38,6 +39,7 @@ type userFlags struct {
 	Password string
 	Email    string
 	Group    string
+	ResetTFA bool
 }
 
 func (f *userFlags) Flags() *flag.FlagSet {
@@ -60,6 +62,8 @@ Examples:
 	fs.StringVar(&f.User, "user", "", "username")
 	fs.StringVar(&f.User, "u", "", "username (shorthand)")
 
+	fs.BoolVar(&f.ResetTFA, "reset-2fa", false, "disable the user's two-factor authentication")
+
 	return fs
 }
 
@@ -159,6 +163,16 @@ func (f *userFlags) setEmail(user *users.User) {
 	}
 }
 
+func (f *userFlags) resetTFA(user *users.User) {
+	if !f.ResetTFA || !user.HasTOTP() {
+		return
+	}
+
+	user.TOTPSecret = ""
+	user.TOTPRecovery = types.Strings{}
+	user.SetSeed()
+}
+
 func (f *userFlags) passwordPrompt() (string, error) {
 	fmt.Print("Enter Password: ")
 	p1, err := term.ReadPassword(int(syscall.Stdin))
@@ -220,9 +234,13 @@ func runUser(_ context.Context, args []string) (err error) {
 	}
 
 	if user == nil {
+		if flags.ResetTFA {
+			return fmt.Errorf(`user "%s" does not exist`, flags.User)
+		}
 		user = &users.User{
-			Username: flags.User,
-			Created:  time.Now(),
+			Username:     flags.User,
+			Created:      time.Now(),
+			TOTPRecovery: types.Strings{},
 		}
 	}
 
@@ -232,6 +250,7 @@ func runUser(_ context.Context, args []string) (err error) {
 
 	flags.setGroup(user)
 	flags.setEmail(user)
+	flags.resetTFA(user)
 
 	msg := "created"
 	if user.ID == 0 {
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package auth
+
+import (
+	"crypto/md5" //nolint:gosec
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+)
+
+// KosyncAuthProvider handles the authentication of KOReader's progress
+// synchronization clients. They send a username in the "x-auth-user"
+// header and the MD5 sum of a password in "x-auth-key". The password must
+// be one of the user's API tokens.
+type KosyncAuthProvider struct {
+	TokenAuthProvider
+}
+
+// IsActive returns true when the client sends the kosync headers.
+func (p *KosyncAuthProvider) IsActive(r *http.Request) bool {
+	return r.Header.Get("x-auth-user") != "" && r.Header.Get("x-auth-key") != ""
+}
+
+// Authenticate finds the user's API token matching the "x-auth-key" header.
+func (p *KosyncAuthProvider) Authenticate(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	user, err := users.Users.GetOne(goqu.C("username").Eq(r.Header.Get("x-auth-user")))
+	if err != nil {
+		p.denyAccess(w)
+		return r, err
+	}
+
+	var list []*tokens.Token
+	if err = tokens.Tokens.Query().Where(
+		goqu.C("user_id").Eq(user.ID),
+		goqu.C("is_enabled").Eq(true),
+	).ScanStructs(&list); err != nil {
+		return r, err
+	}
+
+	key := []byte(strings.ToLower(r.Header.Get("x-auth-key")))
+	var token *tokens.Token
+	for _, t := range list {
+		s, err := tokens.EncodeToken(t.UID)
+		if err != nil {
+			continue
+		}
+		h := md5.Sum([]byte(s)) //nolint:gosec
+		if subtle.ConstantTimeCompare(key, []byte(hex.EncodeToString(h[:]))) == 1 {
+			token = t
+			break
+		}
+	}
+
+	if token == nil {
+		p.denyAccess(w)
+		return r, errors.New("invalid kosync key")
+	}
+
+	if token.IsExpired() {
+		p.denyAccess(w)
+		return r, errors.New("expired token")
+	}
+
+	if err := token.Update(goqu.Record{
+		"last_used": time.Now().UTC(),
+	}); err != nil {
+		return r, err
+	}
+
+	return SetRequestAuthInfo(r, &Info{
+		Provider: &ProviderInfo{
+			Name:        "kosync",
+			Application: token.Application,
+			Roles:       token.Roles,
+			ID:          token.UID,
+		},
+		User: user,
+	}), nil
+}
+
+// denyAccess sends the error kosync clients expect.
+func (p *KosyncAuthProvider) denyAccess(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write([]byte(`{"code":2001,"message":"Unauthorized"}`))
+}
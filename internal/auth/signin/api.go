@@ -49,6 +49,35 @@ func (api *authAPI) auth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user.HasTOTP() {
+		// Invalid codes are counted with the web login ones.
+		if totpFailures(user.ID) >= totpMaxFailures {
+			api.srv.Message(w, r, &server.Message{
+				Status:  http.StatusForbidden,
+				Message: errTOTPLocked.Error(),
+			})
+			return
+		}
+
+		if !user.CheckTOTP(f.Get("totp").String()) {
+			n, err := addTOTPFailure(user.ID)
+			if err != nil {
+				api.srv.Error(w, r, err)
+				return
+			}
+			msg := errInvalidTOTP
+			if n >= totpMaxFailures {
+				msg = errTOTPLocked
+			}
+			api.srv.Message(w, r, &server.Message{
+				Status:  http.StatusForbidden,
+				Message: msg.Error(),
+			})
+			return
+		}
+		clearTOTPFailures(user.ID)
+	}
+
 	t := &tokens.Token{
 		UserID:      &user.ID,
 		IsEnabled:   true,
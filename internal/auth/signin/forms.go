@@ -26,6 +26,7 @@ func newTokenLoginForm(tr forms.Translator) *tokenLoginForm {
 		forms.NewTextField("username", forms.Trim, forms.Required),
 		forms.NewTextField("password", forms.Required),
 		forms.NewTextField("application", forms.Required),
+		forms.NewTextField("totp", forms.Trim),
 		users.NewRolesField(tr, nil),
 	)}
 }
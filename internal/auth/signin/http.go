@@ -7,13 +7,17 @@ package signin
 
 import (
 	"net/http"
+	"path"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
 
+	"codeberg.org/readeck/readeck/configs"
 	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/auth/users"
 	"codeberg.org/readeck/readeck/internal/server"
 	"codeberg.org/readeck/readeck/pkg/forms"
+	"codeberg.org/readeck/readeck/pkg/http/securecookie"
 )
 
 // SetupRoutes mounts the routes for the auth domain.
@@ -26,7 +30,8 @@ func SetupRoutes(s *server.Server) {
 
 type authHandler struct {
 	chi.Router
-	srv *server.Server
+	srv        *server.Server
+	totpCookie *securecookie.Handler
 }
 
 func newAuthHandler(s *server.Server) *authHandler {
@@ -37,11 +42,27 @@ func newAuthHandler(s *server.Server) *authHandler {
 		s.Csrf,
 	)
 
-	h := &authHandler{r, s}
+	h := &authHandler{
+		Router: r,
+		srv:    s,
+		totpCookie: securecookie.NewHandler(
+			securecookie.Key(configs.Keys.TOTPKey()),
+			securecookie.WithPath(path.Join(s.BasePath, "/login/totp")),
+			securecookie.WithMaxAge(int(totpLoginTTL.Seconds())),
+			securecookie.WithName("rdk_totp"),
+		),
+	}
 	s.AddRoute("/login", r)
 	r.Get("/", h.login)
 	r.Post("/", h.login)
 
+	r.Get("/totp", h.totp)
+	r.Post("/totp", h.totp)
+
+	if configs.Config.OIDC.Enabled() {
+		r.Mount("/oidc", newOIDCHandler(s))
+	}
+
 	r.With(s.WithPermission("email", "send")).Route("/recover", func(r chi.Router) {
 		r.Get("/", h.recover)
 		r.Post("/", h.recover)
@@ -77,24 +98,14 @@ func (h *authHandler) login(w http.ResponseWriter, r *http.Request) {
 		if f.IsValid() {
 			user := checkUser(f)
 			if user != nil {
-				// User is authenticated, let's carry on
-				sess := h.srv.GetSession(r)
-				sess.Payload.User = user.ID
-				sess.Payload.Seed = user.Seed
-				sess.Save(w, r)
-
-				// Renew CSRF token
-				h.srv.RenewCsrf(w, r)
-
-				// Get redirection from a form "redirect" parameter
-				// Since it goes to Redirect(), it will be sanitized there
-				// and can only stay within the app.
-				redir := f.Get("redirect").String()
-				if redir == "" || strings.HasPrefix(redir, "/login") {
-					redir = "/"
+				// The user needs a second authentication step
+				if user.HasTOTP() {
+					h.startTOTP(w, r, user, f.Get("redirect").String())
+					return
 				}
 
-				h.srv.Redirect(w, r, redir)
+				// User is authenticated, let's carry on
+				loginUser(h.srv, w, r, user, f.Get("redirect").String())
 				return
 			}
 			// we must set the content type to avoid the
@@ -105,9 +116,34 @@ func (h *authHandler) login(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusUnprocessableEntity)
 	}
 
-	h.srv.RenderTemplate(w, r, http.StatusOK, "/auth/login", server.TC{
+	ctx := server.TC{
 		"Form": f,
-	})
+	}
+	if configs.Config.OIDC.Enabled() {
+		ctx["OIDCLabel"] = configs.Config.OIDC.Label
+	}
+
+	h.srv.RenderTemplate(w, r, http.StatusOK, "/auth/login", ctx)
+}
+
+// loginUser starts a new session for the user and redirects
+// to the given path.
+func loginUser(s *server.Server, w http.ResponseWriter, r *http.Request, user *users.User, redir string) {
+	sess := s.GetSession(r)
+	sess.Payload.User = user.ID
+	sess.Payload.Seed = user.Seed
+	sess.Save(w, r)
+
+	// Renew CSRF token
+	s.RenewCsrf(w, r)
+
+	// Since the redirection goes to Redirect(), it will be sanitized there
+	// and can only stay within the app.
+	if redir == "" || strings.HasPrefix(redir, "/login") {
+		redir = "/"
+	}
+
+	s.Redirect(w, r, redir)
 }
 
 func (h *authHandler) logout(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,337 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package signin
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/http/securecookie"
+	"codeberg.org/readeck/readeck/pkg/oidc"
+)
+
+// groupRanks lists the user groups, from the lowest to the highest
+// privileges.
+var groupRanks = []string{"none", "user", "staff", "admin"}
+
+var rxInvalidUsername = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+var (
+	errOIDCState    = errors.New("invalid authorization state")
+	errOIDCEmail    = errors.New("no verified email address in claims")
+	errOIDCNoUser   = errors.New("user does not exist")
+	errOIDCLinked   = errors.New("user is linked to another identity")
+	errOIDCUsername = errors.New("could not find an available username")
+)
+
+// oidcRequest is the authorization request information, kept
+// in a cookie between the redirection to the provider and
+// the callback.
+type oidcRequest struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	Redirect string `json:"r"`
+}
+
+// oidcProvider is the authentication provider for the OpenID Connect
+// callback. It's active when the request carries an authorization code
+// and authenticates the user with the provider's ID token.
+type oidcProvider struct {
+	srv    *server.Server
+	client *oidc.Client
+	cookie *securecookie.Handler
+}
+
+type oidcHandler struct {
+	chi.Router
+	srv      *server.Server
+	provider *oidcProvider
+}
+
+func newOIDCHandler(s *server.Server) *oidcHandler {
+	cfg := configs.Config.OIDC
+	p := &oidcProvider{
+		srv:    s,
+		client: oidc.NewClient(cfg.Issuer, cfg.ClientID, cfg.ClientSecret, cfg.Scopes),
+		cookie: securecookie.NewHandler(
+			securecookie.Key(configs.Keys.OIDCKey()),
+			securecookie.WithPath(path.Join(s.BasePath, "/login/oidc")),
+			securecookie.WithMaxAge(600),
+			securecookie.WithName("rdk_oidc"),
+		),
+	}
+
+	r := chi.NewRouter()
+	h := &oidcHandler{r, s, p}
+
+	r.Get("/", h.start)
+	r.With(
+		auth.Init(p),
+		auth.Required,
+	).Get("/callback", h.callback)
+
+	return h
+}
+
+// redirectURI returns the absolute callback URL.
+func (p *oidcProvider) redirectURI(r *http.Request) string {
+	return p.srv.AbsoluteURL(r, "/login/oidc/callback").String()
+}
+
+// IsActive returns true when the request is a provider's callback
+// with an authorization code.
+func (p *oidcProvider) IsActive(r *http.Request) bool {
+	q := r.URL.Query()
+	return q.Get("code") != "" && q.Get("state") != ""
+}
+
+// Authenticate exchanges the authorization code, verifies the ID token
+// and retrieves (or creates) the matching user.
+func (p *oidcProvider) Authenticate(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	// The authorization request can only be used once.
+	req := new(oidcRequest)
+	err := p.cookie.Load(r, req)
+	p.cookie.Delete(w, r)
+	if err != nil {
+		return r, fmt.Errorf("%w: %w", errOIDCState, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(req.State), []byte(r.URL.Query().Get("state"))) != 1 {
+		return r, errOIDCState
+	}
+
+	token, err := p.client.Exchange(r.Context(), r.URL.Query().Get("code"), p.redirectURI(r), req.Verifier)
+	if err != nil {
+		return r, err
+	}
+
+	claims, err := p.client.Verify(r.Context(), token.IDToken, req.Nonce)
+	if err != nil {
+		return r, err
+	}
+
+	// Complete the claims with the userinfo endpoint, when available.
+	info, err := p.client.Userinfo(r.Context(), token.AccessToken)
+	if err != nil {
+		return r, err
+	}
+	if info.String("sub") == claims.String("sub") {
+		for k, v := range info {
+			if _, ok := claims[k]; !ok {
+				claims[k] = v
+			}
+		}
+	}
+
+	u, err := p.getUser(claims)
+	if err != nil {
+		return r, err
+	}
+
+	return auth.SetRequestAuthInfo(r, &auth.Info{
+		Provider: &auth.ProviderInfo{
+			Name: "oidc",
+			ID:   claims.String("sub"),
+		},
+		User: u,
+	}), nil
+}
+
+// getUser returns the user linked to the claims' subject. A user
+// that isn't linked yet is found by its email address, which must be
+// verified by the provider, and is then linked to the subject.
+// When the user doesn't exist, it's created if auto provisioning
+// is enabled. When a group claim is configured, the user's group
+// follows the provider's value on every sign-in.
+func (p *oidcProvider) getUser(claims oidc.Claims) (*users.User, error) {
+	cfg := configs.Config.OIDC
+
+	// The ID token verification guarantees a subject.
+	sub := claims.String("sub")
+	email := claims.String("email")
+	group := claimGroup(claims)
+
+	u, err := users.Users.GetOne(goqu.C("oidc_subject").Eq(sub))
+	if errors.Is(err, users.ErrNotFound) {
+		u, err = linkUser(sub, email, claims)
+	}
+	if err == nil {
+		if group != "" && group != u.Group {
+			u.Group = group
+			if err = u.Update(goqu.Record{"group": group, "updated": time.Now()}); err != nil {
+				return nil, err
+			}
+		}
+		return u, nil
+	}
+	if !errors.Is(err, users.ErrNotFound) {
+		return nil, err
+	}
+
+	if verified, ok := claims.Bool("email_verified"); email == "" || (ok && !verified) {
+		return nil, errOIDCEmail
+	}
+
+	if !cfg.AutoProvision {
+		return nil, fmt.Errorf("%w (%s)", errOIDCNoUser, email)
+	}
+
+	if group == "" {
+		group = cfg.DefaultGroup
+	}
+	username, err := availableUsername(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// The user can't sign in with a password until they
+	// set one in their profile.
+	u = &users.User{
+		Username:    username,
+		Email:       email,
+		Password:    oidc.RandomString(),
+		Group:       group,
+		OIDCSubject: sub,
+	}
+	if err = users.Users.Create(u); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// linkUser links an existing user, found by its email address,
+// to the provider's subject. The email address must be verified and
+// the user can't already be linked to another subject.
+func linkUser(sub, email string, claims oidc.Claims) (*users.User, error) {
+	if email == "" {
+		return nil, users.ErrNotFound
+	}
+
+	u, err := users.Users.GetOne(goqu.C("email").Eq(email))
+	if err != nil {
+		return nil, err
+	}
+
+	if verified, _ := claims.Bool("email_verified"); !verified {
+		return nil, errOIDCEmail
+	}
+	if u.OIDCSubject != "" {
+		return nil, errOIDCLinked
+	}
+
+	u.OIDCSubject = sub
+	if err = u.Update(goqu.Record{"oidc_subject": sub, "updated": time.Now()}); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// claimGroup returns the user group from the configured group claim.
+// The claim's values are translated with the group map or, when there's
+// no map, must be a group name. When several values match, the group
+// with the highest privileges wins.
+// It returns an empty string when there's no group claim configured.
+func claimGroup(claims oidc.Claims) string {
+	cfg := configs.Config.OIDC
+	if cfg.GroupClaim == "" {
+		return ""
+	}
+
+	res := ""
+	for _, v := range claims.Strings(cfg.GroupClaim) {
+		g := v
+		if len(cfg.GroupMap) > 0 {
+			g = cfg.GroupMap[v]
+		}
+		if slices.Index(groupRanks, g) > slices.Index(groupRanks, res) {
+			res = g
+		}
+	}
+
+	if res == "" {
+		res = cfg.DefaultGroup
+	}
+	return res
+}
+
+// availableUsername returns a username that is not in use, based
+// on the "preferred_username" claim or the email address.
+func availableUsername(claims oidc.Claims) (string, error) {
+	name := claims.String("preferred_username")
+	if name == "" {
+		name, _, _ = strings.Cut(claims.String("email"), "@")
+	}
+	name = strings.Trim(rxInvalidUsername.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		name = "user"
+	}
+
+	for i := 1; i <= 100; i++ {
+		candidate := name
+		if i > 1 {
+			candidate += "-" + strconv.Itoa(i)
+		}
+
+		c, err := users.Users.Query().Where(goqu.C("username").Eq(candidate)).Count()
+		if err != nil {
+			return "", err
+		}
+		if c == 0 {
+			return candidate, nil
+		}
+	}
+
+	return "", errOIDCUsername
+}
+
+// start redirects the user to the provider's authorization endpoint.
+func (h *oidcHandler) start(w http.ResponseWriter, r *http.Request) {
+	req := &oidcRequest{
+		State:    oidc.RandomString(),
+		Nonce:    oidc.RandomString(),
+		Verifier: oidc.RandomString(),
+		Redirect: r.URL.Query().Get("r"),
+	}
+
+	u, err := h.provider.client.AuthCodeURL(r.Context(), h.provider.redirectURI(r), req.State, req.Nonce, req.Verifier)
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	if err = h.provider.cookie.Save(w, r, req); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", u)
+	w.WriteHeader(http.StatusSeeOther)
+}
+
+// callback is reached once the provider authenticated the user.
+// It starts a new session.
+func (h *oidcHandler) callback(w http.ResponseWriter, r *http.Request) {
+	// The cookie is still present on the incoming request.
+	req := new(oidcRequest)
+	h.provider.cookie.Load(r, req) //nolint:errcheck
+
+	loginUser(h.srv, w, r, auth.GetRequestUser(r), req.Redirect)
+}
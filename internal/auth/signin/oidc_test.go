@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package signin_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/pkg/oidc/oidctest"
+
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestOIDC(t *testing.T) {
+	idp := oidctest.NewServer("readeck", "secret")
+	defer idp.Close()
+
+	defaultConfig := configs.Config.OIDC
+	configs.Config.OIDC.Issuer = idp.Issuer()
+	configs.Config.OIDC.ClientID = "readeck"
+	configs.Config.OIDC.ClientSecret = "secret"
+	configs.Config.OIDC.GroupClaim = "groups"
+	configs.Config.OIDC.GroupMap = map[string]string{
+		"readeck-admins": "admin",
+		"readeck-staff":  "staff",
+	}
+
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+		configs.Config.OIDC = defaultConfig
+	}()
+
+	client := NewClient(t, app)
+
+	// The provider's authorization endpoint redirects immediately,
+	// we only need the callback URL.
+	idpClient := &http.Client{CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	// signin performs the authorization flow and returns the callback response.
+	signin := func(t *testing.T, claims map[string]any) *Response {
+		client.Logout()
+		idp.Claims = claims
+
+		rsp := client.Get("/login/oidc?r=/bookmarks")
+		rsp.AssertStatus(t, 303)
+		require.True(t, strings.HasPrefix(rsp.Redirect, idp.URL+"/authorize?"))
+
+		idpRsp, err := idpClient.Get(rsp.Redirect)
+		require.NoError(t, err)
+		idpRsp.Body.Close() //nolint:errcheck
+		require.Equal(t, http.StatusFound, idpRsp.StatusCode)
+
+		callback, err := url.Parse(idpRsp.Header.Get("Location"))
+		require.NoError(t, err)
+		require.Equal(t, "/login/oidc/callback", callback.Path)
+
+		return client.Get(callback.RequestURI())
+	}
+
+	t.Run("login view", func(t *testing.T) {
+		rsp := client.Get("/login")
+		rsp.AssertStatus(t, 200)
+		require.Contains(t, string(rsp.Body), "Sign in with Single Sign-On")
+		require.Contains(t, string(rsp.Body), `href="/login/oidc?r="`)
+	})
+
+	t.Run("provision user", func(t *testing.T) {
+		rsp := signin(t, map[string]any{
+			"sub":                "alice",
+			"email":              "alice@example.org",
+			"email_verified":     true,
+			"preferred_username": "alice.doe",
+		})
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, "/bookmarks")
+
+		client.Get("/profile").AssertStatus(t, 200)
+
+		u, err := users.Users.GetOne(goqu.C("email").Eq("alice@example.org"))
+		require.NoError(t, err)
+		require.Equal(t, "alice_doe", u.Username)
+		require.Equal(t, "user", u.Group)
+		require.Equal(t, "alice", u.OIDCSubject)
+	})
+
+	t.Run("username conflict", func(t *testing.T) {
+		rsp := signin(t, map[string]any{
+			"sub":                "other-alice",
+			"email":              "other-alice@example.org",
+			"preferred_username": "alice.doe",
+			"groups":             []string{"readeck-staff", "unknown"},
+		})
+		rsp.AssertStatus(t, 303)
+
+		u, err := users.Users.GetOne(goqu.C("email").Eq("other-alice@example.org"))
+		require.NoError(t, err)
+		require.Equal(t, "alice_doe-2", u.Username)
+		require.Equal(t, "staff", u.Group)
+	})
+
+	t.Run("existing user", func(t *testing.T) {
+		// An existing user is only linked with a verified email address
+		signin(t, map[string]any{
+			"sub":   "user",
+			"email": "user@localhost",
+		}).AssertStatus(t, 403)
+		signin(t, map[string]any{
+			"sub":            "user",
+			"email":          "user@localhost",
+			"email_verified": false,
+		}).AssertStatus(t, 403)
+
+		rsp := signin(t, map[string]any{
+			"sub":            "user",
+			"email":          "user@localhost",
+			"email_verified": true,
+			"groups":         []string{"readeck-staff", "readeck-admins"},
+		})
+		rsp.AssertStatus(t, 303)
+		rsp.AssertRedirect(t, "/bookmarks")
+
+		u, err := users.Users.GetOne(goqu.C("email").Eq("user@localhost"))
+		require.NoError(t, err)
+		require.Equal(t, app.Users["user"].User.ID, u.ID)
+		require.Equal(t, "admin", u.Group)
+		require.Equal(t, "user", u.OIDCSubject)
+
+		client.Get("/admin/users").AssertStatus(t, 200)
+
+		// The linked user is found by its subject, back to the default group
+		signin(t, map[string]any{"sub": "user"}).AssertStatus(t, 303)
+		u, err = users.Users.GetOne(goqu.C("email").Eq("user@localhost"))
+		require.NoError(t, err)
+		require.Equal(t, "user", u.Group)
+		client.Get("/profile").AssertStatus(t, 200)
+
+		// Another identity can't take over a linked user
+		signin(t, map[string]any{
+			"sub":            "mallory",
+			"email":          "user@localhost",
+			"email_verified": true,
+		}).AssertStatus(t, 403)
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		rsp := signin(t, map[string]any{
+			"sub":            "bob",
+			"email":          "bob@example.org",
+			"email_verified": false,
+		})
+		rsp.AssertStatus(t, 403)
+
+		_, err := users.Users.GetOne(goqu.C("email").Eq("bob@example.org"))
+		require.ErrorIs(t, err, users.ErrNotFound)
+	})
+
+	t.Run("no provisioning", func(t *testing.T) {
+		configs.Config.OIDC.AutoProvision = false
+		defer func() {
+			configs.Config.OIDC.AutoProvision = true
+		}()
+
+		signin(t, map[string]any{"sub": "carol", "email": "carol@example.org"}).AssertStatus(t, 403)
+		signin(t, map[string]any{
+			"sub":            "admin",
+			"email":          "admin@localhost",
+			"email_verified": true,
+		}).AssertStatus(t, 303)
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		client.Logout()
+		client.Get("/login/oidc/callback?code=abc&state=abc").AssertStatus(t, 403)
+		client.Get("/profile").AssertStatus(t, 303)
+	})
+}
@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package signin
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+var (
+	errInvalidTOTP = forms.Gettext("Invalid authentication code")
+	errTOTPLocked  = forms.Gettext("Too many invalid authentication codes.")
+)
+
+const (
+	// totpMaxFailures is the number of invalid codes after which
+	// the user can't send any code for a while.
+	totpMaxFailures = 5
+
+	// totpFailureTTL is how long the invalid codes are counted,
+	// after the last one.
+	totpFailureTTL = 15 * time.Minute
+
+	// totpLoginTTL is the lifetime of a pending login.
+	totpLoginTTL = 5 * time.Minute
+)
+
+// totpRequest is a login waiting for its second step. It's kept
+// in a short-lived cookie after a successful password check.
+type totpRequest struct {
+	User     int    `json:"u"`
+	Seed     int    `json:"s"`
+	Redirect string `json:"r"`
+}
+
+// totpFailureKey returns the store key of a user's invalid codes
+// counter. The counter is shared by the web login and the API,
+// so starting a new login doesn't reset it.
+func totpFailureKey(userID int) string {
+	return "totp_fail_" + strconv.Itoa(userID)
+}
+
+// totpFailures returns the number of invalid codes a user sent.
+func totpFailures(userID int) int {
+	n, _ := strconv.Atoi(bus.Store().Get(totpFailureKey(userID)))
+	return n
+}
+
+// addTOTPFailure records an invalid code and returns the number
+// of failures.
+func addTOTPFailure(userID int) (int, error) {
+	n := totpFailures(userID) + 1
+	return n, bus.Store().Set(totpFailureKey(userID), strconv.Itoa(n), totpFailureTTL)
+}
+
+// clearTOTPFailures resets the counter after a valid code.
+func clearTOTPFailures(userID int) {
+	bus.Store().Del(totpFailureKey(userID)) //nolint:errcheck
+}
+
+type totpForm struct {
+	*forms.Form
+}
+
+func newTOTPForm(tr forms.Translator) *totpForm {
+	return &totpForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("code", forms.Trim, forms.Required),
+	)}
+}
+
+// startTOTP saves the pending login and redirects to the
+// authentication code form.
+func (h *authHandler) startTOTP(w http.ResponseWriter, r *http.Request, user *users.User, redir string) {
+	err := h.totpCookie.Save(w, r, totpRequest{
+		User:     user.ID,
+		Seed:     user.Seed,
+		Redirect: redir,
+	})
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Redirect(w, r, "/login/totp")
+}
+
+// totp handles the second step of a password login.
+func (h *authHandler) totp(w http.ResponseWriter, r *http.Request) {
+	req := new(totpRequest)
+	if err := h.totpCookie.Load(r, req); err != nil {
+		h.srv.Redirect(w, r, "/login")
+		return
+	}
+
+	user, err := users.Users.GetOne(goqu.C("id").Eq(req.User))
+	if err != nil || user.Seed != req.Seed || !user.HasTOTP() {
+		h.totpCookie.Delete(w, r)
+		h.srv.Redirect(w, r, "/login")
+		return
+	}
+
+	// Too many failures, the user must start over later.
+	locked := func() {
+		h.totpCookie.Delete(w, r)
+		h.srv.AddFlash(w, r, "error", errTOTPLocked.Translate(h.srv.Locale(r)))
+		h.srv.Redirect(w, r, "/login")
+	}
+	if totpFailures(user.ID) >= totpMaxFailures {
+		locked()
+		return
+	}
+
+	f := newTOTPForm(h.srv.Locale(r))
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if user.CheckTOTP(f.Get("code").String()) {
+				h.totpCookie.Delete(w, r)
+				clearTOTPFailures(user.ID)
+				loginUser(h.srv, w, r, user, req.Redirect)
+				return
+			}
+
+			h.srv.Log(r).Warn("invalid authentication code", slog.String("user", user.Username))
+			n, err := addTOTPFailure(user.ID)
+			if err != nil {
+				h.srv.Error(w, r, err)
+				return
+			}
+			if n >= totpMaxFailures {
+				locked()
+				return
+			}
+			f.AddErrors("code", errInvalidTOTP)
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	h.srv.RenderTemplate(w, r, http.StatusOK, "/auth/totp", server.TC{
+		"Form": f,
+	})
+}
@@ -0,0 +1,253 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package signin_test
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/pkg/totp"
+
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestSigninTOTP(t *testing.T) {
+	app := NewTestApp(t)
+	defer app.Close(t)
+
+	client := NewClient(t, app)
+
+	secret := totp.NewSecret()
+	recovery, err := app.Users["user"].User.EnableTOTP(secret)
+	require.NoError(t, err)
+	require.Len(t, recovery, 10)
+
+	login := RequestTest{
+		Method: "POST",
+		Target: "/login",
+		Form: url.Values{
+			"username": {"user"},
+			"password": {"user"},
+			"redirect": {"/bookmarks"},
+		},
+		ExpectStatus:   303,
+		ExpectRedirect: "/login/totp",
+	}
+
+	code, err := totp.Code(secret, time.Now())
+	require.NoError(t, err)
+
+	// Invalid codes are counted for the user, across logins
+	clearFailures := func() {
+		require.NoError(t, bus.Store().Del(fmt.Sprintf("totp_fail_%d", app.Users["user"].User.ID)))
+	}
+
+	t.Run("totp code", func(t *testing.T) {
+		RunRequestSequence(t, client, "",
+			RequestTest{Target: "/login", ExpectStatus: 200},
+			login,
+			// Not authenticated yet
+			RequestTest{Target: "/profile", ExpectStatus: 303, ExpectRedirect: "/login"},
+			RequestTest{Target: "/login/totp", ExpectStatus: 200},
+			RequestTest{
+				Method:         "POST",
+				Target:         "/login/totp",
+				Form:           url.Values{"code": {"abcdef"}},
+				ExpectStatus:   422,
+				ExpectContains: "Invalid authentication code",
+			},
+			RequestTest{Target: "/login/totp", ExpectStatus: 200},
+			RequestTest{
+				Method:         "POST",
+				Target:         "/login/totp",
+				Form:           url.Values{"code": {code}},
+				ExpectStatus:   303,
+				ExpectRedirect: "/bookmarks",
+			},
+			RequestTest{Target: "/profile", ExpectStatus: 200},
+			// The pending login is gone
+			RequestTest{Target: "/login/totp", ExpectStatus: 303, ExpectRedirect: "/login"},
+		)
+	})
+
+	t.Run("code reuse", func(t *testing.T) {
+		RunRequestSequence(t, client, "",
+			RequestTest{Target: "/login", ExpectStatus: 200},
+			login,
+			RequestTest{Target: "/login/totp", ExpectStatus: 200},
+			RequestTest{
+				Method:         "POST",
+				Target:         "/login/totp",
+				Form:           url.Values{"code": {code}},
+				ExpectStatus:   422,
+				ExpectContains: "Invalid authentication code",
+			},
+		)
+	})
+
+	t.Run("too many failures", func(t *testing.T) {
+		clearFailures()
+		defer clearFailures()
+
+		sequence := []RequestTest{
+			{Target: "/login", ExpectStatus: 200},
+			login,
+		}
+		failure := RequestTest{
+			Method:       "POST",
+			Target:       "/login/totp",
+			Form:         url.Values{"code": {"000000"}},
+			ExpectStatus: 422,
+		}
+		for range 4 {
+			sequence = append(sequence,
+				RequestTest{Target: "/login/totp", ExpectStatus: 200},
+				failure,
+			)
+		}
+
+		// The pending login is dropped on the last failure
+		failure.ExpectStatus = 303
+		failure.ExpectRedirect = "/login"
+		sequence = append(sequence,
+			RequestTest{Target: "/login/totp", ExpectStatus: 200},
+			failure,
+			RequestTest{Target: "/login/totp", ExpectStatus: 303, ExpectRedirect: "/login"},
+			RequestTest{Target: "/profile", ExpectStatus: 303, ExpectRedirect: "/login"},
+			// A new login doesn't reset the failures
+			RequestTest{Target: "/login", ExpectStatus: 200},
+			login,
+			RequestTest{Target: "/login/totp", ExpectStatus: 303, ExpectRedirect: "/login"},
+			RequestTest{
+				Target:         "/login",
+				ExpectStatus:   200,
+				ExpectContains: "Too many invalid authentication codes.",
+			},
+			// Nor does the API
+			RequestTest{
+				Method: "POST",
+				Target: "/api/auth",
+				JSON: map[string]string{
+					"application": "test",
+					"username":    "user",
+					"password":    "user",
+					"totp":        code,
+				},
+				ExpectStatus: 403,
+				ExpectJSON:   `{"status":403,"message":"Too many invalid authentication codes."}`,
+			},
+		)
+
+		RunRequestSequence(t, client, "", sequence...)
+	})
+
+	t.Run("recovery code", func(t *testing.T) {
+		RunRequestSequence(t, client, "",
+			RequestTest{Target: "/login", ExpectStatus: 200},
+			login,
+			RequestTest{Target: "/login/totp", ExpectStatus: 200},
+			RequestTest{
+				Method:         "POST",
+				Target:         "/login/totp",
+				Form:           url.Values{"code": {recovery[0]}},
+				ExpectStatus:   303,
+				ExpectRedirect: "/bookmarks",
+			},
+			RequestTest{Target: "/profile", ExpectStatus: 200},
+		)
+
+		// A recovery code can only be used once
+		RunRequestSequence(t, client, "",
+			RequestTest{Target: "/login", ExpectStatus: 200},
+			login,
+			RequestTest{Target: "/login/totp", ExpectStatus: 200},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/login/totp",
+				Form:         url.Values{"code": {recovery[0]}},
+				ExpectStatus: 422,
+			},
+		)
+	})
+
+	t.Run("no pending login", func(t *testing.T) {
+		RunRequestSequence(t, client, "",
+			RequestTest{Target: "/login/totp", ExpectStatus: 303, ExpectRedirect: "/login"},
+		)
+	})
+
+	t.Run("api", func(t *testing.T) {
+		clearFailures()
+		defer clearFailures()
+
+		// The current code was already used
+		code, err := totp.Code(secret, time.Now().Add(totp.Period))
+		require.NoError(t, err)
+
+		RunRequestSequence(t, client, "",
+			RequestTest{
+				Method: "POST",
+				Target: "/api/auth",
+				JSON: map[string]string{
+					"application": "test",
+					"username":    "user",
+					"password":    "user",
+				},
+				ExpectStatus: 403,
+				ExpectJSON:   `{"status":403,"message":"Invalid authentication code"}`,
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/auth",
+				JSON: map[string]string{
+					"application": "test",
+					"username":    "user",
+					"password":    "user",
+					"totp":        code,
+				},
+				ExpectStatus: 201,
+			},
+			// Other users are not concerned
+			RequestTest{
+				Method: "POST",
+				Target: "/api/auth",
+				JSON: map[string]string{
+					"application": "test",
+					"username":    "staff",
+					"password":    "staff",
+				},
+				ExpectStatus: 201,
+			},
+		)
+
+		// Invalid codes sent to the API lock the web login too
+		failure := RequestTest{
+			Method: "POST",
+			Target: "/api/auth",
+			JSON: map[string]string{
+				"application": "test",
+				"username":    "user",
+				"password":    "user",
+				"totp":        "000000",
+			},
+			ExpectStatus: 403,
+			ExpectJSON:   `{"status":403,"message":"Invalid authentication code"}`,
+		}
+		sequence := []RequestTest{failure, failure, failure, failure}
+		failure.ExpectJSON = `{"status":403,"message":"Too many invalid authentication codes."}`
+		sequence = append(sequence,
+			failure,
+			RequestTest{Target: "/login", ExpectStatus: 200},
+			login,
+			RequestTest{Target: "/login/totp", ExpectStatus: 303, ExpectRedirect: "/login"},
+		)
+		RunRequestSequence(t, client, "", sequence...)
+	})
+}
@@ -62,6 +62,12 @@ type User struct {
 	Group    string        `db:"group"`
 	Settings *UserSettings `db:"settings"`
 	Seed     int           `db:"seed"`
+
+	TOTPSecret   string        `db:"totp_secret"`
+	TOTPRecovery types.Strings `db:"totp_recovery"`
+	TOTPLastStep int64         `db:"totp_last_step"`
+
+	OIDCSubject string `db:"oidc_subject"`
 }
 
 // Manager is a query helper for user entries.
@@ -109,6 +115,9 @@ func (m *Manager) Create(user *User) error {
 	user.Updated = user.Created
 	user.UID = base58.NewUUID()
 	user.SetSeed()
+	if user.TOTPRecovery == nil {
+		user.TOTPRecovery = types.Strings{}
+	}
 
 	ds := db.Q().Insert(TableName).
 		Rows(user).
@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package users
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/pkg/totp"
+)
+
+const recoveryCodeCount = 10
+
+// HasTOTP returns true when the user enabled two-factor authentication.
+func (u *User) HasTOTP() bool {
+	return u.TOTPSecret != ""
+}
+
+// CheckTOTP returns true when the given code is a valid TOTP code
+// or one of the user's recovery codes. A TOTP code, like a recovery
+// code, can only be used once.
+func (u *User) CheckTOTP(code string) bool {
+	if !u.HasTOTP() {
+		return false
+	}
+
+	if step, ok := totp.ValidateStep(u.TOTPSecret, code, time.Now()); ok {
+		return u.useTOTPStep(int64(step)) //nolint:gosec
+	}
+
+	h := hashRecoveryCode(code)
+	idx := slices.Index(u.TOTPRecovery, h)
+	if idx == -1 {
+		return false
+	}
+
+	u.TOTPRecovery = slices.Delete(u.TOTPRecovery, idx, idx+1)
+	return u.Update(goqu.Record{"totp_recovery": u.TOTPRecovery}) == nil
+}
+
+// useTOTPStep records the time step of an accepted code. It returns
+// false when this step, or a later one, was already used.
+// The condition is in the query so two concurrent requests can't
+// use the same code.
+func (u *User) useTOTPStep(step int64) bool {
+	res, err := db.Q().Update(TableName).Prepared(true).
+		Set(goqu.Record{"totp_last_step": step}).
+		Where(
+			goqu.C("id").Eq(u.ID),
+			goqu.C("totp_last_step").Lt(step),
+		).
+		Executor().Exec()
+	if err != nil {
+		return false
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false
+	}
+
+	u.TOTPLastStep = step
+	return true
+}
+
+// EnableTOTP saves the user's TOTP secret and returns a new set
+// of recovery codes.
+func (u *User) EnableTOTP(secret string) ([]string, error) {
+	codes, hashes := newRecoveryCodes()
+	err := u.Update(goqu.Record{
+		"totp_secret":   secret,
+		"totp_recovery": hashes,
+		"updated":       time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u.TOTPSecret = secret
+	u.TOTPRecovery = hashes
+	return codes, nil
+}
+
+// ResetRecoveryCodes replaces the user's recovery codes
+// and returns the new ones.
+func (u *User) ResetRecoveryCodes() ([]string, error) {
+	codes, hashes := newRecoveryCodes()
+	if err := u.Update(goqu.Record{"totp_recovery": hashes, "updated": time.Now()}); err != nil {
+		return nil, err
+	}
+
+	u.TOTPRecovery = hashes
+	return codes, nil
+}
+
+// DisableTOTP removes the user's TOTP secret and recovery codes.
+func (u *User) DisableTOTP() error {
+	u.TOTPSecret = ""
+	u.TOTPRecovery = types.Strings{}
+
+	return u.Update(goqu.Record{
+		"totp_secret":   u.TOTPSecret,
+		"totp_recovery": u.TOTPRecovery,
+		"updated":       time.Now(),
+	})
+}
+
+// newRecoveryCodes returns a list of recovery codes and their hashes.
+// Only the hashes are stored.
+func newRecoveryCodes() ([]string, types.Strings) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make(types.Strings, recoveryCodeCount)
+
+	for i := range codes {
+		code := strings.ToLower(rand.Text())
+		codes[i] = code[:5] + "-" + code[5:10]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes
+}
+
+// hashRecoveryCode returns the hash of a normalized recovery code.
+// The codes are random enough for a simple hash to be safe.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	h := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(h[:])
+}
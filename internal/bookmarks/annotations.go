@@ -29,6 +29,7 @@ type BookmarkAnnotation struct {
 	Color         string    `json:"color"`
 	Created       time.Time `json:"created"`
 	Text          string    `json:"text"`
+	Note          string    `json:"note"`
 }
 
 // Scan loads a BookmarkAnnotations instance from a column.
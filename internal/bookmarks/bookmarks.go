@@ -11,7 +11,6 @@ import (
 	"encoding/json"
 	"errors"
 	"log/slog"
-	"os"
 	"path"
 	"path/filepath"
 	"slices"
@@ -26,6 +25,7 @@ import (
 	"codeberg.org/readeck/readeck/internal/db/exp"
 	"codeberg.org/readeck/readeck/internal/db/types"
 	"codeberg.org/readeck/readeck/pkg/base58"
+	"codeberg.org/readeck/readeck/pkg/storage"
 )
 
 // BookmarkState is the current bookmark state.
@@ -78,6 +78,7 @@ type Bookmark struct {
 	State         BookmarkState       `db:"state"`
 	URL           string              `db:"url"`
 	InitialURL    string              `db:"initial_url"`
+	CanonicalURL  string              `db:"canonical_url"`
 	Title         string              `db:"title"`
 	Domain        string              `db:"domain"`
 	Site          string              `db:"site"`
@@ -90,6 +91,7 @@ type Bookmark struct {
 	Description   string              `db:"description"`
 	Text          string              `db:"text"`
 	WordCount     int                 `db:"word_count"`
+	Simhash       int64               `db:"simhash"`
 	Duration      int                 `db:"duration"`
 	Embed         string              `db:"embed"`
 	FilePath      string              `db:"file_path"`
@@ -102,6 +104,13 @@ type Bookmark struct {
 	IsMarked      bool                `db:"is_marked"`
 	Annotations   BookmarkAnnotations `db:"annotations"`
 	Links         BookmarkLinks       `db:"links"`
+	FeedID        *int                `db:"feed_id"`
+	// RefreshInterval is the number of days between two automatic
+	// refreshes of the bookmark's content. 0 disables the refresh.
+	RefreshInterval int        `db:"refresh_interval"`
+	Refreshed       *time.Time `db:"refreshed"`
+	// Deleted is the date the bookmark was moved to the trash.
+	Deleted *time.Time `db:"deleted"`
 }
 
 // BookmarkManager is a query helper for bookmark entries.
@@ -120,6 +129,9 @@ func (m *BookmarkManager) Create(bookmark *Bookmark) error {
 	if bookmark.InitialURL == "" {
 		bookmark.InitialURL = bookmark.URL
 	}
+	if bookmark.CanonicalURL == "" {
+		bookmark.CanonicalURL = CanonicalKeyString(bookmark.URL)
+	}
 
 	ds := db.Q().Insert(TableName).
 		Rows(bookmark).
@@ -135,10 +147,23 @@ func (m *BookmarkManager) Create(bookmark *Bookmark) error {
 }
 
 // Query returns a prepared goqu SelectDataset that can be extended later.
+// It leaves out the bookmarks in the trash.
 func (m *BookmarkManager) Query() *goqu.SelectDataset {
+	return m.QueryAll().Where(goqu.I("b.deleted").IsNull())
+}
+
+// QueryAll returns a prepared goqu SelectDataset that includes
+// the bookmarks in the trash.
+func (m *BookmarkManager) QueryAll() *goqu.SelectDataset {
 	return db.Q().From(goqu.T(TableName).As("b")).Prepared(true)
 }
 
+// Trashed returns a prepared goqu SelectDataset with only
+// the bookmarks in the trash.
+func (m *BookmarkManager) Trashed() *goqu.SelectDataset {
+	return m.QueryAll().Where(goqu.I("b.deleted").IsNotNull())
+}
+
 // GetOne executes the a select query and returns the first result or an error
 // when there's no result.
 func (m *BookmarkManager) GetOne(expressions ...goqu.Expression) (*Bookmark, error) {
@@ -160,7 +185,7 @@ func (m *BookmarkManager) GetOne(expressions ...goqu.Expression) (*Bookmark, err
 // holds a file, we can't only rely on the foreign key cascade
 // deletion. Hence this.
 func (m *BookmarkManager) DeleteUserBookmakrs(u *users.User) error {
-	ds := Bookmarks.Query().
+	ds := Bookmarks.QueryAll().
 		Where(goqu.C("user_id").Eq(u.ID))
 
 	items := []*Bookmark{}
@@ -213,6 +238,7 @@ func (m *BookmarkManager) GetLabels() *goqu.SelectDataset {
 					else '[]' end
 					)`).As("name"),
 			).
+			Where(goqu.I("b.deleted").IsNull()).
 			GroupBy(goqu.C("name")).
 			Order(goqu.C("name").Asc()).
 			Prepared(true)
@@ -226,7 +252,10 @@ func (m *BookmarkManager) GetLabels() *goqu.SelectDataset {
 				goqu.T(TableName).As("b"),
 				goqu.Func("json_each", goqu.C("labels").Table("b")).As("l"),
 			).
-			Where(goqu.C("value").Table("l").Neq(nil)).
+			Where(
+				goqu.I("b.deleted").IsNull(),
+				goqu.C("value").Table("l").Neq(nil),
+			).
 			GroupBy(goqu.C("name")).
 			Order(goqu.L("`name` COLLATE UNICODE").Asc()).
 			Prepared(true)
@@ -250,6 +279,7 @@ func (m *BookmarkManager) GetAnnotations() *goqu.SelectDataset {
 		ds = ds.SelectAppend(
 			goqu.L(`a->>'id'`).As("annotation_id"),
 			goqu.L(`a->>'text'`).As("annotation_text"),
+			goqu.L(`COALESCE((a->>'note'), '')`).As("annotation_note"),
 			goqu.L(`(a->>'created')::timestamptz`).As("annotation_created"),
 			goqu.L(`COALESCE((a->>'color'), 'yellow')`).As("annotation_color"),
 		).
@@ -265,6 +295,7 @@ func (m *BookmarkManager) GetAnnotations() *goqu.SelectDataset {
 		ds = ds.SelectAppend(
 			goqu.Func("json_extract", goqu.I("a.value"), "$.id").As("annotation_id"),
 			goqu.Func("json_extract", goqu.I("a.value"), "$.text").As("annotation_text"),
+			goqu.Func("COALESCE", goqu.Func("json_extract", goqu.I("a.value"), "$.note"), "").As("annotation_note"),
 			goqu.Func("json_extract", goqu.I("a.value"), "$.created").As("annotation_created"),
 			goqu.Func("COALESCE", goqu.Func("json_extract", goqu.I("a.value"), "$.color"), "yellow").As("annotation_color"),
 		).
@@ -342,12 +373,13 @@ func (m *BookmarkManager) CountAll(u *users.User) (CountResult, error) {
 	return res, nil
 }
 
-// RenameLabel renames or deletes a label in all bookmarks for a given user.
+// RenameLabel renames or deletes a label in all bookmarks for a given user,
+// including the ones in the trash.
 // If "newLabel" is empty, the label is deleted.
 func (m *BookmarkManager) RenameLabel(u *users.User, oldLabel, newLabel string) (ids []int, err error) {
 	ids = make([]int, 0)
 
-	ds := Bookmarks.Query().
+	ds := Bookmarks.QueryAll().
 		Select("b.id", "b.labels").
 		Where(goqu.C("user_id").Eq(u.ID))
 	ds = exp.JSONListFilter(ds, goqu.I("b.labels").Eq(oldLabel))
@@ -385,28 +417,19 @@ func (m *BookmarkManager) RenameLabel(u *users.User, oldLabel, newLabel string)
 		return nil, err
 	}
 
+	if err = Deletions.Log(&u.ID, DeletedLabel, oldLabel); err != nil {
+		return nil, err
+	}
+
 	return
 }
 
 // DiskUsage returns the total size of bookmarks on disk, as int64.
 func (m *BookmarkManager) DiskUsage() (uint64, error) {
-	dir := StoragePath()
 	var totalSize uint64
 
-	if _, err := os.Lstat(dir); err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return 0, nil
-		}
-		return 0, err
-	}
-
-	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			totalSize += uint64(info.Size())
-		}
+	err := Storage().Walk("", func(info storage.FileInfo) error {
+		totalSize += uint64(info.Size)
 		return nil
 	})
 	if err != nil {
@@ -417,24 +440,56 @@ func (m *BookmarkManager) DiskUsage() (uint64, error) {
 }
 
 // Update updates some bookmark values.
+// The labels it removes are added to the deletion log.
 func (b *Bookmark) Update(v interface{}) error {
 	if b.ID == 0 {
 		return errors.New("No ID")
 	}
 
+	var labels []string
+	hasLabels := false
 	switch v := v.(type) {
 	case map[string]interface{}:
 		v["updated"] = time.Now()
+		switch l := v["labels"].(type) {
+		case types.Strings:
+			labels, hasLabels = l, true
+		case []string:
+			labels, hasLabels = l, true
+		}
+	case *Bookmark:
+		labels, hasLabels = v.Labels, true
 	default:
 		//
 	}
 
+	// Fetch the stored labels, the instance might already
+	// contain the new ones.
+	var previous types.Strings
+	if hasLabels {
+		if _, err := Bookmarks.QueryAll().
+			Select("labels").
+			Where(goqu.C("id").Eq(b.ID)).
+			ScanVal(&previous); err != nil {
+			return err
+		}
+	}
+
 	_, err := db.Q().Update(TableName).Prepared(true).
 		Set(v).
 		Where(goqu.C("id").Eq(b.ID)).
 		Executor().Exec()
+	if err != nil {
+		return err
+	}
 
-	return err
+	if hasLabels {
+		removed := slices.DeleteFunc(previous, func(s string) bool {
+			return slices.Contains(labels, s)
+		})
+		return Deletions.Log(b.UserID, DeletedLabel, removed...)
+	}
+	return nil
 }
 
 // Save updates all the bookmark values.
@@ -443,6 +498,37 @@ func (b *Bookmark) Save() error {
 	return b.Update(b)
 }
 
+// Trash moves a bookmark to the trash. It stays there until it's
+// restored or removed for good.
+func (b *Bookmark) Trash() error {
+	now := time.Now()
+	_, err := db.Q().Update(TableName).Prepared(true).
+		Set(goqu.Record{"deleted": now}).
+		Where(goqu.C("id").Eq(b.ID)).
+		Executor().Exec()
+	if err != nil {
+		return err
+	}
+
+	b.Deleted = &now
+	return b.logDeletion()
+}
+
+// Restore takes a bookmark out of the trash.
+func (b *Bookmark) Restore() error {
+	b.Updated = time.Now()
+	_, err := db.Q().Update(TableName).Prepared(true).
+		Set(goqu.Record{"deleted": nil, "updated": b.Updated}).
+		Where(goqu.C("id").Eq(b.ID)).
+		Executor().Exec()
+	if err != nil {
+		return err
+	}
+
+	b.Deleted = nil
+	return nil
+}
+
 // Delete removes a bookmark from the database.
 func (b *Bookmark) Delete() error {
 	_, err := db.Q().Delete(TableName).Prepared(true).
@@ -453,7 +539,20 @@ func (b *Bookmark) Delete() error {
 	}
 
 	b.RemoveFiles()
-	return nil
+	b.removeRevisionFiles()
+
+	// A bookmark in the trash is already in the deletion log.
+	if b.Deleted != nil {
+		return nil
+	}
+	return b.logDeletion()
+}
+
+func (b *Bookmark) logDeletion() error {
+	if err := Deletions.Log(b.UserID, DeletedBookmark, b.UID); err != nil {
+		return err
+	}
+	return Deletions.Log(b.UserID, DeletedLabel, b.Labels...)
 }
 
 // StateName returns the current bookmark state name.
@@ -476,40 +575,26 @@ func (b *Bookmark) GetBaseFileURL() (string, error) {
 
 // RemoveFiles removes all the bookmark's files.
 func (b *Bookmark) RemoveFiles() {
-	filename := b.GetFilePath()
-	if filename == "" {
+	name := b.GetFileName()
+	if name == "" {
 		return
 	}
 
-	l := slog.With(slog.String("path", filename))
-	if err := os.Remove(filename); err != nil {
+	l := slog.With(slog.String("path", name))
+	if err := Storage().Remove(name); err != nil {
 		l.Error("", slog.Any("err", err))
 	} else {
 		l.Debug("file removed")
 	}
-
-	// Remove empty directories up to the base
-	dirname := path.Dir(filename)
-	if stat, _ := os.Stat(dirname); stat == nil {
-		return
-	}
-	for dirname != "." {
-		// Just try to remove and if it's not empty it will complain
-		d := dirname
-		if err := os.Remove(d); err != nil {
-			break
-		}
-		slog.Debug("directory removed", slog.String("dir", dirname))
-		dirname = path.Dir(dirname)
-	}
 }
 
-// GetFilePath returns the bookmark's associated file path.
-func (b *Bookmark) GetFilePath() string {
+// GetFileName returns the bookmark's associated file name
+// in the storage.
+func (b *Bookmark) GetFileName() string {
 	if b.FilePath == "" {
 		return ""
 	}
-	return filepath.Join(StoragePath(), b.FilePath+".zip")
+	return b.FilePath + ".zip"
 }
 
 // replaceLabel replaces "old" label with "new" in the
@@ -637,6 +722,7 @@ type AnnotationQueryResult struct {
 	Bookmark Bookmark         `db:"b"`
 	ID       string           `db:"annotation_id"`
 	Text     string           `db:"annotation_text"`
+	Note     string           `db:"annotation_note"`
 	Created  types.TimeString `db:"annotation_created"`
 	Color    string           `db:"annotation_color"`
 }
@@ -17,8 +17,16 @@ import (
 const (
 	// CollectionTable is the collection table name in database.
 	CollectionTable = "bookmark_collection"
+
+	// DigestDaily is the daily email digest schedule.
+	DigestDaily = "daily"
+	// DigestWeekly is the weekly email digest schedule.
+	DigestWeekly = "weekly"
 )
 
+// DigestSchedules is the list of email digest schedules.
+var DigestSchedules = []string{DigestDaily, DigestWeekly}
+
 var (
 	// Collections is the collection query manager.
 	Collections = CollectionManager{}
@@ -37,16 +45,37 @@ type Collection struct {
 	Name     string    `db:"name"`
 	IsPinned bool      `db:"is_pinned"`
 	Filters  Filters   `db:"filters"`
+
+	// DigestSchedule is the email digest frequency.
+	// The digest is disabled when empty.
+	DigestSchedule string     `db:"digest_schedule"`
+	DigestSent     *time.Time `db:"digest_sent"`
+
+	// Deleted is the date the collection was moved to the trash.
+	Deleted *time.Time `db:"deleted"`
 }
 
 // CollectionManager is a query helper for bookmark entries.
 type CollectionManager struct{}
 
 // Query returns a prepared goqu SelectDataset that can be extended later.
+// It leaves out the collections in the trash.
 func (m *CollectionManager) Query() *goqu.SelectDataset {
+	return m.QueryAll().Where(goqu.I("c.deleted").IsNull())
+}
+
+// QueryAll returns a prepared goqu SelectDataset that includes
+// the collections in the trash.
+func (m *CollectionManager) QueryAll() *goqu.SelectDataset {
 	return db.Q().From(goqu.T(CollectionTable).As("c")).Prepared(true)
 }
 
+// Trashed returns a prepared goqu SelectDataset with only
+// the collections in the trash.
+func (m *CollectionManager) Trashed() *goqu.SelectDataset {
+	return m.QueryAll().Where(goqu.I("c.deleted").IsNotNull())
+}
+
 // GetOne executes the a select query and returns the first result or an error
 // when there's no result.
 func (m *CollectionManager) GetOne(expressions ...goqu.Expression) (*Collection, error) {
@@ -114,11 +143,87 @@ func (c *Collection) Save() error {
 	return c.Update(c)
 }
 
+// Trash moves a collection to the trash.
+func (c *Collection) Trash() error {
+	now := time.Now()
+	_, err := db.Q().Update(CollectionTable).Prepared(true).
+		Set(goqu.Record{"deleted": now}).
+		Where(goqu.C("id").Eq(c.ID)).
+		Executor().Exec()
+	if err != nil {
+		return err
+	}
+
+	c.Deleted = &now
+	return Deletions.Log(c.UserID, DeletedCollection, c.UID)
+}
+
+// Restore takes a collection out of the trash.
+func (c *Collection) Restore() error {
+	c.Updated = time.Now()
+	_, err := db.Q().Update(CollectionTable).Prepared(true).
+		Set(goqu.Record{"deleted": nil, "updated": c.Updated}).
+		Where(goqu.C("id").Eq(c.ID)).
+		Executor().Exec()
+	if err != nil {
+		return err
+	}
+
+	c.Deleted = nil
+	return nil
+}
+
 // Delete removes a collection from the database.
 func (c *Collection) Delete() error {
 	_, err := db.Q().Delete(CollectionTable).Prepared(true).
 		Where(goqu.C("id").Eq(c.ID)).
 		Executor().Exec()
+	if err != nil {
+		return err
+	}
+
+	// A collection in the trash is already in the deletion log.
+	if c.Deleted != nil {
+		return nil
+	}
+	return Deletions.Log(c.UserID, DeletedCollection, c.UID)
+}
+
+// DigestPeriod returns the duration between two email digests.
+// It's zero when the digest is disabled.
+func (c *Collection) DigestPeriod() time.Duration {
+	switch c.DigestSchedule {
+	case DigestDaily:
+		return 24 * time.Hour
+	case DigestWeekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// IsDigestDue returns true when the collection's email digest
+// must be sent.
+func (c *Collection) IsDigestDue(now time.Time) bool {
+	period := c.DigestPeriod()
+	if period == 0 {
+		return false
+	}
+	if c.DigestSent == nil {
+		return true
+	}
+
+	// Leave some room for the polling interval
+	return now.Sub(*c.DigestSent) >= period-10*time.Minute
+}
+
+// SetDigestSent saves the last email digest date. Unlike [Collection.Update],
+// it leaves the collection's update date untouched.
+func (c *Collection) SetDigestSent(t time.Time) error {
+	c.DigestSent = &t
+	_, err := db.Q().Update(CollectionTable).Prepared(true).
+		Set(goqu.Record{"digest_sent": t}).
+		Where(goqu.C("id").Eq(c.ID)).
+		Executor().Exec()
 
 	return err
 }
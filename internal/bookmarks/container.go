@@ -18,10 +18,11 @@ var (
 	rxHTMLEnd   = regexp.MustCompile(`</body>\s*</html>\s*$`)
 )
 
-// BookmarkContainer is a wrapper around zip.ReadCloser
+// BookmarkContainer is a wrapper around zip.Reader
 // to handle a bookmark's zipfile.
 type BookmarkContainer struct {
-	*zip.ReadCloser
+	*zip.Reader
+	file            io.Closer
 	articleFilename string
 	articleContent  *strings.Builder
 }
@@ -29,18 +30,25 @@ type BookmarkContainer struct {
 // OpenContainer opens the bookmark's zipfile and returns a new
 // bookmarkContainer instance.
 func (b *Bookmark) OpenContainer() (*BookmarkContainer, error) { //revive:disable:unexported-return
-	p := b.GetFilePath()
-	if p == "" {
+	name := b.GetFileName()
+	if name == "" {
 		return nil, os.ErrNotExist
 	}
 
-	z, err := zip.OpenReader(p)
+	f, err := Storage().Open(name)
 	if err != nil {
 		return nil, err
 	}
 
+	z, err := zip.NewReader(f, f.Size())
+	if err != nil {
+		f.Close() //nolint:errcheck
+		return nil, err
+	}
+
 	res := &BookmarkContainer{
-		ReadCloser:     z,
+		Reader:         z,
+		file:           f,
 		articleContent: new(strings.Builder),
 	}
 	if a, ok := b.Files["article"]; ok {
@@ -50,6 +58,11 @@ func (b *Bookmark) OpenContainer() (*BookmarkContainer, error) { //revive:disabl
 	return res, nil
 }
 
+// Close closes the container's zipfile.
+func (c *BookmarkContainer) Close() error {
+	return c.file.Close()
+}
+
 // Lookup return a [*zip.File] with the given name, when it exists.
 func (c *BookmarkContainer) Lookup(name string) (*zip.File, bool) {
 	for _, entry := range c.File {
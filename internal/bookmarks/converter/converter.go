@@ -11,6 +11,7 @@ import (
 	"net/http"
 
 	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/searchstring"
 )
 
 type contextKey struct {
@@ -21,6 +22,7 @@ var (
 	ctxURLReplaceKey         = &contextKey{"baseURL"}
 	ctxAnnotationTagKey      = &contextKey{"annotationTag"}
 	ctxAnnotationCallbackKey = &contextKey{"annotationCallback"}
+	ctxHighlightKey          = &contextKey{"highlight"}
 )
 
 // Exporter describes a bookmarks exporter.
@@ -50,3 +52,14 @@ func getAnnotationTag(ctx context.Context) (tag string, callback annotationCallb
 	callback, _ = ctx.Value(ctxAnnotationCallbackKey).(annotationCallback)
 	return
 }
+
+// WithHighlight adds to context the search terms to highlight
+// in the article.
+func WithHighlight(ctx context.Context, terms []searchstring.SearchTerm) context.Context {
+	return context.WithValue(ctx, ctxHighlightKey, terms)
+}
+
+func getHighlight(ctx context.Context) []searchstring.SearchTerm {
+	terms, _ := ctx.Value(ctxHighlightKey).([]searchstring.SearchTerm)
+	return terms
+}
@@ -7,13 +7,10 @@ package converter
 import (
 	"bytes"
 	"context"
-	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"path"
-	"strings"
-	"time"
 
 	"github.com/CloudyKit/jet/v6"
 	"github.com/wneessen/go-mail"
@@ -189,11 +186,7 @@ func (e EPUBEmailExporter) Export(ctx context.Context, _ io.Writer, r *http.Requ
 	if err := ee.Export(ctx, w, r, []*bookmarks.Bookmark{b}); err != nil {
 		return err
 	}
-	if err := msg.AttachReader(fmt.Sprintf(
-		"%s-%s.epub",
-		b.Created.Format(time.DateOnly),
-		utils.Slug(strings.TrimSuffix(utils.ShortText(b.Title, 40), "...")),
-	), w); err != nil {
+	if err := msg.AttachReader(epubFilename(b.Title, b.Created), w); err != nil {
 		return err
 	}
 
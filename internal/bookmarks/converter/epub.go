@@ -16,12 +16,17 @@ import (
 	"time"
 
 	"github.com/CloudyKit/jet/v6"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/go-shiori/dom"
 	"github.com/google/uuid"
+	"golang.org/x/net/html"
 
 	"codeberg.org/readeck/readeck/assets"
+	"codeberg.org/readeck/readeck/configs"
 	"codeberg.org/readeck/readeck/internal/bookmarks"
 	"codeberg.org/readeck/readeck/internal/server"
 	"codeberg.org/readeck/readeck/pkg/epub"
+	"codeberg.org/readeck/readeck/pkg/img"
 	"codeberg.org/readeck/readeck/pkg/utils"
 )
 
@@ -32,6 +37,7 @@ type EPUBExporter struct {
 	HTMLConverter
 	baseURL      *url.URL
 	templateVars jet.VarMap
+	kepub        bool
 	Collection   *bookmarks.Collection
 }
 
@@ -44,6 +50,14 @@ func NewEPUBExporter(baseURL *url.URL, templateVars jet.VarMap) EPUBExporter {
 	}
 }
 
+// NewKEPUBExporter returns a new [EPUBExporter] instance that produces
+// Kobo EPUB files.
+func NewKEPUBExporter(baseURL *url.URL, templateVars jet.VarMap) EPUBExporter {
+	e := NewEPUBExporter(baseURL, templateVars)
+	e.kepub = true
+	return e
+}
+
 // Export implements [Exporter].
 // It writes an EPUB file on the provided [io.Writer].
 func (e EPUBExporter) Export(ctx context.Context, w io.Writer, _ *http.Request, bookmarkList []*bookmarks.Bookmark) error {
@@ -65,12 +79,17 @@ func (e EPUBExporter) Export(ctx context.Context, w io.Writer, _ *http.Request,
 		id += x.UID
 	}
 
+	contentType := "application/epub+zip"
+	filename := epubFilename(title, date)
+	if e.kepub {
+		contentType = epub.KepubContentType
+		filename = strings.TrimSuffix(filename, ".epub") + ".kepub.epub"
+	}
+
 	if w, ok := w.(http.ResponseWriter); ok {
-		w.Header().Set("Content-Type", "application/epub+zip")
+		w.Header().Set("Content-Type", contentType)
 		w.Header().Set("Content-Disposition", fmt.Sprintf(
-			`attachment; filename="%s-%s.epub"`,
-			date.Format(time.DateOnly),
-			utils.Slug(strings.TrimSuffix(utils.ShortText(title, 40), "...")),
+			`attachment; filename="%s"`, filename,
 		))
 	}
 
@@ -78,6 +97,7 @@ func (e EPUBExporter) Export(ctx context.Context, w io.Writer, _ *http.Request,
 	if err != nil {
 		return err
 	}
+	m.kepub = e.kepub
 
 	defer func() {
 		if err == nil {
@@ -98,15 +118,25 @@ func (e EPUBExporter) Export(ctx context.Context, w io.Writer, _ *http.Request,
 	return nil
 }
 
+// epubFilename returns the file name of an EPUB file.
+func epubFilename(title string, date time.Time) string {
+	return fmt.Sprintf(
+		"%s-%s.epub",
+		date.Format(time.DateOnly),
+		utils.Slug(strings.TrimSuffix(utils.ShortText(title, 40), "...")),
+	)
+}
+
 // epubMaker is a wrapper around epub.Writer with extra methods to
 // create an epub file from one or many bookmarks.
 type epubMaker struct {
 	*epub.Writer
+	kepub bool
 }
 
 // newEpubMaker creates a new EpubMaker instance.
 func newEpubMaker(w io.Writer, id uuid.UUID) (*epubMaker, error) {
-	m := &epubMaker{epub.New(w)}
+	m := &epubMaker{Writer: epub.New(w)}
 	if err := m.Bootstrap(); err != nil {
 		return nil, err
 	}
@@ -140,6 +170,9 @@ func (m *epubMaker) addBookmark(ctx context.Context, e EPUBExporter, b *bookmark
 	defer c.Close()
 
 	// Add all the resource files to the book. They are only images for now.
+	// The images that were renamed or left out during their conversion
+	// are replaced in the article.
+	images := map[string]string{}
 	for _, x := range c.ListResources() {
 		err = func() error {
 			fp, err := x.Open()
@@ -147,11 +180,17 @@ func (m *epubMaker) addBookmark(ctx context.Context, e EPUBExporter, b *bookmark
 				return err
 			}
 			defer fp.Close() //nolint:errcheck
-			return m.AddImage(
+
+			name := path.Join("Images", path.Base(x.Name))
+			newName, err := m.addImage(
 				"res-"+strings.TrimSuffix(path.Base(x.Name), path.Ext(x.Name)),
-				path.Join("Images", path.Base(x.Name)),
+				name,
 				fp,
 			)
+			if newName != name {
+				images[path.Base(name)] = path.Base(newName)
+			}
+			return err
 		}()
 		if err != nil {
 			return
@@ -179,11 +218,15 @@ func (m *epubMaker) addBookmark(ctx context.Context, e EPUBExporter, b *bookmark
 			}
 			defer fp.Close() //nolint:errcheck
 
-			return m.AddImage(
+			v.Name, err = m.addImage(
 				fmt.Sprintf("%s-%s", k, b.UID),
 				v.Name,
 				fp,
 			)
+			if v.Name == "" {
+				delete(resources, k)
+			}
+			return err
 		}()
 		if err != nil {
 			return
@@ -195,6 +238,11 @@ func (m *epubMaker) addBookmark(ctx context.Context, e EPUBExporter, b *bookmark
 	if err != nil {
 		return err
 	}
+	if len(images) > 0 {
+		if html, err = replaceEPUBImages(html, images); err != nil {
+			return err
+		}
+	}
 	tpl, err := server.GetTemplate("epub/bookmark.jet.html")
 	if err != nil {
 		return err
@@ -209,6 +257,14 @@ func (m *epubMaker) addBookmark(ctx context.Context, e EPUBExporter, b *bookmark
 		return err
 	}
 
+	if m.kepub {
+		kbuf := new(bytes.Buffer)
+		if err := epub.ConvertKepub(kbuf, buf); err != nil {
+			return err
+		}
+		buf = kbuf
+	}
+
 	return m.AddChapter(
 		"page-"+b.UID,
 		b.Title,
@@ -216,3 +272,150 @@ func (m *epubMaker) addBookmark(ctx context.Context, e EPUBExporter, b *bookmark
 		buf,
 	)
 }
+
+// addImage adds an image to the epub file, once converted with
+// [convertEPUBImage]. It returns the name of the image in the book,
+// with its new extension, or an empty string when the image was
+// left out.
+func (m *epubMaker) addImage(id, name string, r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	data, ext, ok := convertEPUBImage(data)
+	if !ok {
+		return "", nil
+	}
+
+	name = strings.TrimSuffix(name, path.Ext(name)) + ext
+	return name, m.AddImage(id, name, bytes.NewReader(data))
+}
+
+// epubImageTypes are the image types every e-reader can display,
+// with their file extension.
+// epubSVGMaxSize is the maximum width and height of a rendered SVG image.
+const epubSVGMaxSize = 2048
+
+var epubImageTypes = map[string]string{
+	"image/gif":  ".gif",
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// convertEPUBImage converts an image for the e-readers.
+// SVG images are rendered to PNG, WebP images are converted to JPEG
+// and the other image types, like BMP or ICO, to PNG.
+// There is no AVIF decoder, so AVIF images are left out.
+// The images are resized to fit on the screen set in the configuration,
+// and converted to grayscale when it's enabled.
+//
+// It returns the image data and its file extension. When an image
+// can't be converted, the original one is returned if the e-readers
+// can display it, otherwise ok is false.
+func convertEPUBImage(data []byte) (res []byte, ext string, ok bool) {
+	contentType := mimetype.Detect(data).String()
+	ext, supported := epubImageTypes[contentType]
+
+	// Fallback to the original image when possible
+	defer func() {
+		if !ok && supported {
+			res, ext, ok = data, epubImageTypes[contentType], true
+		}
+	}()
+
+	im, err := img.New(contentType, bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	defer im.Close() //nolint:errcheck
+
+	cf := configs.Config.Bookmarks
+	maxW, maxH := uint(max(0, cf.EPUBImageWidth)), uint(max(0, cf.EPUBImageHeight))
+	if supported && !cf.EPUBGrayscale &&
+		(maxW == 0 || im.Width() <= maxW) && (maxH == 0 || im.Height() <= maxH) {
+		return data, ext, true
+	}
+
+	if err = img.Fit(im, maxW, maxH); err != nil {
+		return
+	}
+
+	switch contentType {
+	case "image/svg+xml":
+		// The SVG is rendered at its declared size when there is no
+		// size limit in the configuration.
+		if err = img.Fit(im, epubSVGMaxSize, epubSVGMaxSize); err != nil {
+			return
+		}
+		if im, err = im.(*img.SvgImage).Rasterize(); err != nil {
+			return
+		}
+		ext = ".png"
+	case "image/webp":
+		ext = ".jpg"
+	default:
+		if !supported {
+			ext = ".png"
+		}
+	}
+
+	filters := []img.ImageFilter{
+		func(im img.Image) error { return im.SetFormat(strings.TrimPrefix(ext, ".")) },
+		func(im img.Image) error { return im.SetQuality(80) },
+		func(im img.Image) error { return im.SetCompression(img.CompressionBest) },
+	}
+	if cf.EPUBGrayscale {
+		filters = append(filters, func(im img.Image) error { return im.Grayscale() })
+	}
+	if err = img.Pipeline(im, filters...); err != nil {
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	if err = im.Encode(buf); err != nil {
+		return
+	}
+	return buf.Bytes(), ext, true
+}
+
+// replaceEPUBImages replaces the images of an article with their converted
+// version. An image that was left out is replaced by its alternative text.
+func replaceEPUBImages(input *strings.Reader, images map[string]string) (*strings.Reader, error) {
+	doc, err := html.Parse(input)
+	if err != nil {
+		input.Seek(0, 0) //nolint:errcheck
+		return input, err
+	}
+
+	rename := func(n *html.Node, attr string) (string, bool) {
+		name, ok := strings.CutPrefix(dom.GetAttribute(n, attr), "./Images/")
+		if !ok {
+			return "", false
+		}
+		newName, ok := images[name]
+		if ok && newName != "" {
+			dom.SetAttribute(n, attr, "./Images/"+newName)
+		}
+		return newName, ok
+	}
+
+	for _, n := range dom.QuerySelectorAll(doc, "a[href]") {
+		if newName, ok := rename(n, "href"); ok && newName == "" {
+			dom.RemoveAttribute(n, "href")
+		}
+	}
+	for _, n := range dom.QuerySelectorAll(doc, "img[src]") {
+		if newName, ok := rename(n, "src"); ok && newName == "" {
+			dom.ReplaceChild(n.Parent, dom.CreateTextNode(dom.GetAttribute(n, "alt")), n)
+		}
+	}
+
+	buf := new(strings.Builder)
+	if err = html.Render(buf, doc); err != nil {
+		input.Seek(0, 0) //nolint:errcheck
+		return input, err
+	}
+
+	return strings.NewReader(bookmarks.ExtractHTMLBody(buf.String())), nil
+}
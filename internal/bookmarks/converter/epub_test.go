@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package converter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/bmp"
+
+	"codeberg.org/readeck/readeck/configs"
+)
+
+func newPNG(w, h int) []byte {
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, image.NewNRGBA(image.Rect(0, 0, w, h))); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+func TestConvertEPUBImage(t *testing.T) {
+	// A 1x1 lossless WebP image
+	webp, _ := base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+
+	bmpData := new(bytes.Buffer)
+	require.NoError(t, bmp.Encode(bmpData, image.NewRGBA(image.Rect(0, 0, 8, 4))))
+
+	// An AVIF header, there is no decoder for it
+	avif := []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00avifmif1miaf")
+
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 4000 2000">` +
+		`<rect width="2000" height="2000" fill="#f00"/></svg>`)
+	hugeSVG := []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="100000" height="50000">` +
+		`<rect width="50000" height="50000" fill="#f00"/></svg>`)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		ok     bool
+		ext    string
+		format string
+		w, h   int
+	}{
+		{"small png", newPNG(100, 50), true, ".png", "png", 100, 50},
+		{"large png", newPNG(2528, 1000), true, ".png", "png", 1264, 500},
+		{"svg", svg, true, ".png", "png", 1264, 632},
+		{"huge svg", hugeSVG, true, ".png", "png", 1264, 632},
+		{"webp", webp, true, ".jpg", "jpeg", 1, 1},
+		{"bmp", bmpData.Bytes(), true, ".png", "png", 8, 4},
+		{"broken png", newPNG(10, 10)[:40], true, ".png", "", 0, 0},
+		{"avif", avif, false, "", "", 0, 0},
+		{"unknown", []byte("not an image"), false, "", "", 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+			data, ext, ok := convertEPUBImage(test.data)
+			assert.Equal(test.ok, ok)
+			assert.Equal(test.ext, ext)
+			if test.format == "" {
+				return
+			}
+
+			c, format, err := image.DecodeConfig(bytes.NewReader(data))
+			assert.NoError(err)
+			assert.Equal(test.format, format)
+			assert.Equal(test.w, c.Width)
+			assert.Equal(test.h, c.Height)
+		})
+	}
+
+	t.Run("small png kept as is", func(t *testing.T) {
+		src := newPNG(100, 50)
+		data, _, _ := convertEPUBImage(src)
+		require.Equal(t, src, data)
+	})
+
+	t.Run("huge svg without size limit", func(t *testing.T) {
+		w, h := configs.Config.Bookmarks.EPUBImageWidth, configs.Config.Bookmarks.EPUBImageHeight
+		configs.Config.Bookmarks.EPUBImageWidth = 0
+		configs.Config.Bookmarks.EPUBImageHeight = 0
+		defer func() {
+			configs.Config.Bookmarks.EPUBImageWidth = w
+			configs.Config.Bookmarks.EPUBImageHeight = h
+		}()
+
+		data, ext, ok := convertEPUBImage(hugeSVG)
+		require.True(t, ok)
+		require.Equal(t, ".png", ext)
+
+		c, err := png.DecodeConfig(bytes.NewReader(data))
+		require.NoError(t, err)
+		require.Equal(t, 2048, c.Width)
+		require.Equal(t, 1024, c.Height)
+	})
+
+	t.Run("grayscale", func(t *testing.T) {
+		configs.Config.Bookmarks.EPUBGrayscale = true
+		defer func() {
+			configs.Config.Bookmarks.EPUBGrayscale = false
+		}()
+
+		data, ext, ok := convertEPUBImage(newPNG(100, 50))
+		require.True(t, ok)
+		require.Equal(t, ".png", ext)
+
+		m, err := png.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+		require.IsType(t, &image.Gray{}, m)
+	})
+}
+
+func TestReplaceEPUBImages(t *testing.T) {
+	input := strings.NewReader(`<figure>` +
+		`<a href="./Images/a.svg"><img src="./Images/a.svg" alt=""/></a>` +
+		`<img src="./Images/b.avif" alt="A diagram"/>` +
+		`<img src="./Images/c.png" alt=""/>` +
+		`<a href="https://example.org/a.svg">link</a>` +
+		`</figure>`)
+
+	r, err := replaceEPUBImages(input, map[string]string{
+		"a.svg":  "a.png",
+		"b.avif": "",
+	})
+	require.NoError(t, err)
+	res, _ := io.ReadAll(r)
+
+	require.Equal(t, `<figure>`+
+		`<a href="./Images/a.png"><img src="./Images/a.png" alt=""/></a>`+
+		`A diagram`+
+		`<img src="./Images/c.png" alt=""/>`+
+		`<a href="https://example.org/a.svg">link</a>`+
+		`</figure>`,
+		string(res),
+	)
+}
@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package converter
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+
+	"codeberg.org/readeck/readeck/internal/searchstring"
+)
+
+// highlightTag is the element surrounding a search term in a document.
+const highlightTag = "mark"
+
+// highlightSkip lists the elements in which we never look for terms.
+var highlightSkip = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Pre:      true,
+	atom.Code:     true,
+	atom.Mark:     true,
+	atom.Svg:      true,
+	atom.Math:     true,
+	atom.Textarea: true,
+}
+
+// foldWord returns a lower case word without diacritics, the same
+// way the full text search index compares them.
+func foldWord(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	res, _, err := transform.String(t, strings.ToLower(s))
+	if err != nil {
+		return strings.ToLower(s)
+	}
+	return res
+}
+
+// highlighter wraps, in a document, every word matching a list
+// of search terms.
+type highlighter struct {
+	words    map[string]struct{}
+	prefixes []string
+}
+
+func newHighlighter(terms []searchstring.SearchTerm) *highlighter {
+	h := &highlighter{words: map[string]struct{}{}}
+	for _, t := range terms {
+		w := foldWord(t.Value)
+		if t.Wildcard {
+			h.prefixes = append(h.prefixes, w)
+		} else {
+			h.words[w] = struct{}{}
+		}
+	}
+	return h
+}
+
+func (h *highlighter) match(word string) bool {
+	w := foldWord(word)
+	if _, ok := h.words[w]; ok {
+		return true
+	}
+	for _, p := range h.prefixes {
+		if strings.HasPrefix(w, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply wraps the matching words of every text node under root.
+// It returns the number of highlighted words.
+func (h *highlighter) apply(root *html.Node) int {
+	if len(h.words) == 0 && len(h.prefixes) == 0 {
+		return 0
+	}
+
+	nodes := []*html.Node{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch {
+			case c.Type == html.TextNode:
+				nodes = append(nodes, c)
+			case c.Type == html.ElementNode && !highlightSkip[c.DataAtom]:
+				walk(c)
+			}
+		}
+	}
+	walk(root)
+
+	count := 0
+	for _, n := range nodes {
+		count += h.splitNode(n)
+	}
+	return count
+}
+
+// splitNode replaces a text node by a list of text and highlight nodes.
+func (h *highlighter) splitNode(n *html.Node) int {
+	text := n.Data
+	count := 0
+	last := 0
+	start := -1
+
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		if h.match(text[start:end]) {
+			if start > last {
+				n.Parent.InsertBefore(dom.CreateTextNode(text[last:start]), n)
+			}
+			m := dom.CreateElement(highlightTag)
+			dom.AppendChild(m, dom.CreateTextNode(text[start:end]))
+			n.Parent.InsertBefore(m, n)
+			last = end
+			count++
+		}
+		start = -1
+	}
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+
+	switch {
+	case count > 0 && last == len(text):
+		n.Parent.RemoveChild(n)
+	case count > 0:
+		n.Data = text[last:]
+	}
+	return count
+}
@@ -13,6 +13,7 @@ import (
 	"golang.org/x/net/html"
 
 	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/searchstring"
 )
 
 type annotationCallback func(id string, n *html.Node, index int, color string)
@@ -27,8 +28,8 @@ type HTMLConverter struct{}
 // it might be empty or the original one if some transformation failed.
 // This lets us test for error and log them when needed.
 //
-// The converter will use whatever is passed to [WithURLReplacer] and
-// [WithAnnotationTag].
+// The converter will use whatever is passed to [WithURLReplacer],
+// [WithAnnotationTag] and [WithHighlight].
 func (c HTMLConverter) GetArticle(ctx context.Context, b *bookmarks.Bookmark) (*strings.Reader, error) {
 	var err error
 	var bc *bookmarks.BookmarkContainer
@@ -59,7 +60,14 @@ func (c HTMLConverter) GetArticle(ctx context.Context, b *bookmarks.Bookmark) (*
 
 	// Add bookmark annotations
 	if len(b.Annotations) > 0 {
-		return c.addAnnotations(ctx, b, reader)
+		if reader, err = c.addAnnotations(ctx, b, reader); err != nil {
+			return reader, err
+		}
+	}
+
+	// Highlight search terms
+	if terms := getHighlight(ctx); len(terms) > 0 {
+		return c.addHighlights(terms, reader)
 	}
 
 	return reader, nil
@@ -81,12 +89,45 @@ func (c HTMLConverter) addAnnotations(ctx context.Context, b *bookmarks.Bookmark
 	}
 	root := dom.QuerySelector(doc, "body")
 
-	err = b.Annotations.AddToNode(root, tag, callback)
+	// Keep the last node of each annotation, so we can attach the notes
+	lastNodes := map[string]*html.Node{}
+	err = b.Annotations.AddToNode(root, tag, callback, func(id string, n *html.Node, _ int, _ string) {
+		lastNodes[id] = n
+	})
+	if err != nil {
+		input.Seek(0, 0) //nolint:errcheck
+		return input, err
+	}
+
+	for _, a := range b.Annotations {
+		if n, ok := lastNodes[a.ID]; ok && a.Note != "" {
+			dom.SetAttribute(n, "data-annotation-note-id", a.ID)
+			dom.SetAttribute(n, "title", a.Note)
+		}
+	}
+
+	buf := new(strings.Builder)
+	if err = html.Render(buf, doc); err != nil {
+		input.Seek(0, 0) //nolint:errcheck
+		return input, err
+	}
+
+	return strings.NewReader(bookmarks.ExtractHTMLBody(buf.String())), nil
+}
+
+// addHighlights wraps the search terms found in the given document.
+func (c HTMLConverter) addHighlights(terms []searchstring.SearchTerm, input *strings.Reader) (*strings.Reader, error) {
+	doc, err := html.Parse(input)
 	if err != nil {
 		input.Seek(0, 0) //nolint:errcheck
 		return input, err
 	}
 
+	if newHighlighter(terms).apply(dom.QuerySelector(doc, "body")) == 0 {
+		input.Seek(0, 0) //nolint:errcheck
+		return input, nil
+	}
+
 	buf := new(strings.Builder)
 	if err = html.Render(buf, doc); err != nil {
 		input.Seek(0, 0) //nolint:errcheck
@@ -24,6 +24,7 @@ import (
 	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/commonmark"
 	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/table"
 	"github.com/gabriel-vasile/mimetype"
+	"github.com/go-shiori/dom"
 	"golang.org/x/net/html"
 	"golang.org/x/net/idna"
 	"gopkg.in/yaml.v3"
@@ -306,10 +307,26 @@ func (e MarkdownExporter) writeArticle(ctx context.Context, w io.Writer, b *book
 		return err
 	}
 
-	_, err = io.Copy(w, io.MultiReader(intro, bytes.NewReader(md)))
+	_, err = io.Copy(w, io.MultiReader(intro, bytes.NewReader(md), getMarkdownNotes(b)))
 	return err
 }
 
+// getMarkdownNotes returns the annotation notes as markdown footnotes.
+func getMarkdownNotes(b *bookmarks.Bookmark) io.Reader {
+	buf := new(bytes.Buffer)
+	for _, a := range b.Annotations {
+		if a.Note == "" {
+			continue
+		}
+		lines := strings.Split(a.Note, "\n")
+		fmt.Fprintf(buf, "\n[^%s]: %s", a.ID, strings.Join(lines, "\n    "))
+	}
+	if buf.Len() > 0 {
+		buf.WriteString("\n")
+	}
+	return buf
+}
+
 func (e MarkdownExporter) writeResource(mp *multipart.Writer, resource *zip.File, b *bookmarks.Bookmark) error {
 	r, err := resource.Open()
 	if err != nil {
@@ -340,6 +357,7 @@ func (e MarkdownExporter) writeResource(mp *multipart.Writer, resource *zip.File
 
 // html2mdAnnotationPlugin is an html-to-markdown plugin that converts rd-annotation tags
 // to "=={content}==" form, that's compatible with at least Obsidian.
+// An annotation with a note is followed by a footnote reference.
 type html2mdAnnotationPlugin struct{}
 
 func (s *html2mdAnnotationPlugin) Name() string {
@@ -356,11 +374,13 @@ func (s *html2mdAnnotationPlugin) render(ctx converter.Context, w converter.Writ
 	ctx.RenderChildNodes(ctx, buf, n)
 	content := buf.String()
 
-	if strings.TrimSpace(content) == "" {
-		w.WriteString(content) // nolint:errcheck
-		return converter.RenderSuccess
+	if strings.TrimSpace(content) != "" {
+		content = "==" + content + "=="
+	}
+	if id := dom.GetAttribute(n, "data-annotation-note-id"); id != "" {
+		content += "[^" + id + "]"
 	}
-	w.WriteString("==" + content + "==") // nolint:errcheck
+	w.WriteString(content) // nolint:errcheck
 
 	return converter.RenderSuccess
 }
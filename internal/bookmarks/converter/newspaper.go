@@ -0,0 +1,208 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/google/uuid"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/locales"
+	"codeberg.org/readeck/readeck/pkg/strftime"
+	"codeberg.org/readeck/readeck/pkg/utils"
+)
+
+const (
+	coverWidth  = 600
+	coverHeight = 800
+	coverMargin = 40
+)
+
+// NewspaperExporter is a content exporter that produces a newspaper
+// issue. It's an EPUB file with a cover, a table of contents and
+// all the bookmarks of the issue.
+type NewspaperExporter struct {
+	EPUBExporter
+	Title string
+	Date  time.Time
+}
+
+// NewNewspaperExporter returns a new [NewspaperExporter] instance.
+func NewNewspaperExporter(baseURL *url.URL, templateVars jet.VarMap, title string, date time.Time) NewspaperExporter {
+	return NewspaperExporter{
+		EPUBExporter: NewEPUBExporter(baseURL, templateVars),
+		Title:        title,
+		Date:         date,
+	}
+}
+
+// Filename returns the issue's file name.
+func (e NewspaperExporter) Filename() string {
+	return epubFilename(e.Title, e.Date)
+}
+
+// Export implements [Exporter].
+// It writes the issue's EPUB file on the provided [io.Writer].
+func (e NewspaperExporter) Export(ctx context.Context, w io.Writer, _ *http.Request, bookmarkList []*bookmarks.Bookmark) error {
+	if w, ok := w.(http.ResponseWriter); ok {
+		w.Header().Set("Content-Type", "application/epub+zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(
+			`attachment; filename="%s"`, e.Filename(),
+		))
+	}
+
+	// An issue's ID only depends on its title and date
+	id := uuid.NewSHA1(uuidURL, []byte(e.Title+"/"+e.Date.Format(time.DateOnly)))
+	m, err := newEpubMaker(w, id)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err == nil {
+			m.SetTitle(e.Title + " - " + e.Date.Format(time.DateOnly))
+			m.SetCreator("Readeck")
+			m.SetDate(e.Date)
+			err = m.WritePackage()
+		}
+		m.Close() //nolint:errcheck
+	}()
+
+	if err = m.addCover(e, bookmarkList); err != nil {
+		return err
+	}
+	if err = m.addContents(e, bookmarkList); err != nil {
+		return err
+	}
+
+	ctx = WithURLReplacer(ctx, "./_resources/", "./Images/")
+	for _, b := range bookmarkList {
+		if err = m.addBookmark(ctx, e.EPUBExporter, b, e.templateVars); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dateString returns the issue's date, in the templates' language.
+func (e NewspaperExporter) dateString() string {
+	if v, ok := e.templateVars["translator"]; ok {
+		if tr, ok := v.Interface().(*locales.Locale); ok {
+			return strftime.New(tr).Strftime("%A %e %B %Y", e.Date)
+		}
+	}
+	return strftime.Strftime("%A %e %B %Y", e.Date)
+}
+
+// addContents adds the issue's table of contents to the epub file.
+func (m *epubMaker) addContents(e NewspaperExporter, bookmarkList []*bookmarks.Bookmark) error {
+	tpl, err := server.GetTemplate("epub/newspaper.jet.html")
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	if err = tpl.Execute(buf, e.templateVars, map[string]any{
+		"Title": e.Title,
+		"Date":  e.Date,
+		"Items": bookmarkList,
+	}); err != nil {
+		return err
+	}
+
+	return m.AddChapter("contents", e.Title, "contents.html", buf)
+}
+
+// addCover adds the issue's cover image to the epub file.
+func (m *epubMaker) addCover(e NewspaperExporter, bookmarkList []*bookmarks.Bookmark) error {
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, newspaperCover(e.Title, e.dateString(), bookmarkList)); err != nil {
+		return err
+	}
+	return m.AddCover("Images/cover.png", buf)
+}
+
+// newspaperCover draws a cover with the issue's title, date and
+// the titles of its articles.
+func newspaperCover(title, date string, bookmarkList []*bookmarks.Bookmark) image.Image {
+	res := image.NewGray(image.Rect(0, 0, coverWidth, coverHeight))
+	draw.Draw(res, res.Bounds(), image.White, image.Point{}, draw.Src)
+
+	face := basicfont.Face7x13
+	maxChars := func(scale int) int {
+		return (coverWidth - coverMargin*2) / (face.Advance * scale)
+	}
+	rule := func(y int) {
+		draw.Draw(res, image.Rect(coverMargin, y, coverWidth-coverMargin, y+3), image.Black, image.Point{}, draw.Src)
+	}
+
+	// Title
+	scale := 4
+	if len([]rune(title)) > maxChars(scale) {
+		scale = 3
+	}
+	y := coverMargin
+	drawCoverText(res, utils.ShortText(title, maxChars(scale)-3), coverMargin, y, scale)
+	y += face.Height*scale + 12
+	rule(y)
+
+	// Date
+	y += 12
+	drawCoverText(res, utils.ShortText(date, maxChars(2)-3), coverMargin, y, 2)
+	y += face.Height*2 + 12
+	rule(y)
+
+	// Articles
+	y += 24
+	for _, b := range bookmarkList {
+		if y+face.Height*2 > coverHeight-coverMargin {
+			break
+		}
+		drawCoverText(res, "- "+utils.ShortText(b.Title, maxChars(2)-5), coverMargin, y, 2)
+		y += face.Height*2 + 8
+	}
+
+	return res
+}
+
+// drawCoverText draws a text on the cover, with its upper left corner at x, y.
+// The basic font is scaled up to be readable on a full page cover.
+func drawCoverText(dst *image.Gray, s string, x, y, scale int) {
+	face := basicfont.Face7x13
+	mask := image.NewAlpha(image.Rect(0, 0, len([]rune(s))*face.Advance, face.Height))
+	drawer := &font.Drawer{
+		Dst:  mask,
+		Src:  image.Opaque,
+		Face: face,
+		Dot:  fixed.P(0, face.Ascent),
+	}
+	drawer.DrawString(s)
+
+	big := image.NewAlpha(image.Rect(0, 0, mask.Rect.Dx()*scale, mask.Rect.Dy()*scale))
+	xdraw.NearestNeighbor.Scale(big, big.Rect, mask, mask.Rect, xdraw.Src, nil)
+
+	draw.DrawMask(dst, big.Rect.Add(image.Pt(x, y)),
+		image.NewUniform(color.Gray{Y: 0x20}), image.Point{},
+		big, image.Point{}, draw.Over,
+	)
+}
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks
+
+import (
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+)
+
+const (
+	// DeletionTable is the deletion log table name in database.
+	DeletionTable = "sync_deletion"
+
+	// DeletedBookmark is the deletion kind of a bookmark.
+	DeletedBookmark = "bookmark"
+	// DeletedCollection is the deletion kind of a collection.
+	DeletedCollection = "collection"
+	// DeletedLabel is the deletion kind of a label removed from
+	// one or more bookmarks. The label might still exist on
+	// other bookmarks.
+	DeletedLabel = "label"
+)
+
+// Deletions is the deletion log query manager.
+var Deletions = DeletionManager{}
+
+// Deletion is a deletion log entry (a tombstone) in database. It keeps
+// track of the removed bookmarks, collections and labels so clients
+// can synchronize their own copy.
+type Deletion struct {
+	ID      int       `db:"id" goqu:"skipinsert,skipupdate"`
+	UserID  *int      `db:"user_id"`
+	Created time.Time `db:"created"`
+	Kind    string    `db:"kind"`
+	// Name is the UID of a bookmark or a collection,
+	// or a label name.
+	Name string `db:"name"`
+}
+
+// DeletionManager is a query helper for deletion log entries.
+type DeletionManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *DeletionManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(DeletionTable).As("sd")).Prepared(true)
+}
+
+// Log adds one entry per name to the deletion log.
+func (m *DeletionManager) Log(userID *int, kind string, names ...string) error {
+	if userID == nil || len(names) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	rows := make([]any, len(names))
+	for i, name := range names {
+		rows[i] = Deletion{UserID: userID, Created: now, Kind: kind, Name: name}
+	}
+
+	_, err := db.Q().Insert(DeletionTable).
+		Rows(rows...).
+		Prepared(true).
+		Executor().Exec()
+	return err
+}
+
+// LastID returns the most recent deletion log ID of a user.
+func (m *DeletionManager) LastID(userID int) (int, error) {
+	var id int
+	_, err := m.Query().
+		Select(goqu.COALESCE(goqu.MAX("id"), 0)).
+		Where(goqu.C("user_id").Eq(userID)).
+		ScanVal(&id)
+	return id, err
+}
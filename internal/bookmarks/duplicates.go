@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks
+
+import (
+	"net/url"
+	"slices"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/pkg/urlcanon"
+)
+
+func urlCanonicalizer() *urlcanon.Canonicalizer {
+	return urlcanon.New(configs.Config.Bookmarks.TrackingParams...)
+}
+
+// CleanURL returns the URL without its fragment and tracking parameters.
+// An AMP address is replaced by the original page's address.
+func CleanURL(u *url.URL) *url.URL {
+	return urlCanonicalizer().Clean(u)
+}
+
+// CanonicalKey returns the key used to compare bookmark URLs.
+func CanonicalKey(u *url.URL) string {
+	return urlCanonicalizer().Key(u)
+}
+
+// CanonicalKeyString is like CanonicalKey with a string URL.
+// It returns an empty string when the URL is not valid.
+func CanonicalKeyString(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	return CanonicalKey(u)
+}
+
+// FindDuplicates returns the bookmarks of a user, not in the trash,
+// saved with the same URL. The oldest bookmark comes first.
+func (m *BookmarkManager) FindDuplicates(userID int, u *url.URL) ([]*Bookmark, error) {
+	clean := CleanURL(u).String()
+	ds := m.Query().
+		Where(
+			goqu.I("b.user_id").Eq(userID),
+			goqu.Or(
+				goqu.I("b.canonical_url").Eq(CanonicalKey(u)),
+				goqu.I("b.url").Eq(clean),
+				goqu.I("b.initial_url").Eq(clean),
+			),
+		).
+		Order(goqu.I("b.created").Asc(), goqu.I("b.id").Asc())
+
+	res := []*Bookmark{}
+	if err := ds.ScanStructs(&res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DuplicateGroup is a list of bookmark IDs sharing the same URL.
+type DuplicateGroup struct {
+	UserID       int
+	CanonicalURL string
+	IDs          []int
+}
+
+// GetDuplicateGroups returns the groups of duplicate bookmarks, outside of
+// the trash. The IDs of each group are ordered from the oldest bookmark.
+// When userID is 0, it returns the groups of all the users.
+func (m *BookmarkManager) GetDuplicateGroups(userID int) ([]*DuplicateGroup, error) {
+	ds := m.Query().
+		Select(goqu.I("b.user_id"), goqu.I("b.canonical_url")).
+		Where(goqu.I("b.canonical_url").Neq("")).
+		GroupBy(goqu.I("b.user_id"), goqu.I("b.canonical_url")).
+		Having(goqu.COUNT(goqu.I("b.id")).Gt(1)).
+		Order(goqu.I("b.user_id").Asc(), goqu.I("b.canonical_url").Asc())
+	if userID > 0 {
+		ds = ds.Where(goqu.I("b.user_id").Eq(userID))
+	}
+
+	var groups []*DuplicateGroup
+	var rows []struct {
+		UserID       int    `db:"user_id"`
+		CanonicalURL string `db:"canonical_url"`
+	}
+	if err := ds.ScanStructs(&rows); err != nil {
+		return nil, err
+	}
+
+	for _, r := range rows {
+		g := &DuplicateGroup{UserID: r.UserID, CanonicalURL: r.CanonicalURL}
+		if err := m.Query().
+			Select(goqu.I("b.id")).
+			Where(
+				goqu.I("b.user_id").Eq(r.UserID),
+				goqu.I("b.canonical_url").Eq(r.CanonicalURL),
+			).
+			Order(goqu.I("b.created").Asc(), goqu.I("b.id").Asc()).
+			ScanVals(&g.IDs); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+
+	return groups, nil
+}
+
+// Merge merges other bookmarks into the current one and moves them to the trash.
+// The bookmark receives the labels and annotations of the other bookmarks.
+// It's a favorite or archived when any of them is, and keeps the furthest
+// reading progress.
+func (b *Bookmark) Merge(others ...*Bookmark) error {
+	labels := slices.Clone(b.Labels)
+	annotations := slices.Clone(b.Annotations)
+	isMarked := b.IsMarked
+	isArchived := b.IsArchived
+	readProgress := b.ReadProgress
+	readAnchor := b.ReadAnchor
+
+	for _, o := range others {
+		if o.ID == b.ID || *o.UserID != *b.UserID {
+			continue
+		}
+		labels = append(labels, o.Labels...)
+		for _, a := range o.Annotations {
+			if annotations.Get(a.ID) == nil {
+				annotations.Add(a)
+			}
+		}
+		isMarked = isMarked || o.IsMarked
+		isArchived = isArchived || o.IsArchived
+		if o.ReadProgress > readProgress {
+			readProgress = o.ReadProgress
+			readAnchor = o.ReadAnchor
+		}
+	}
+
+	slices.Sort(labels)
+	labels = slices.Compact(labels)
+
+	if err := b.Update(map[string]interface{}{
+		"labels":        labels,
+		"annotations":   annotations,
+		"is_marked":     isMarked,
+		"is_archived":   isArchived,
+		"read_progress": readProgress,
+		"read_anchor":   readAnchor,
+	}); err != nil {
+		return err
+	}
+	b.Labels = labels
+	b.Annotations = annotations
+	b.IsMarked = isMarked
+	b.IsArchived = isArchived
+	b.ReadProgress = readProgress
+	b.ReadAnchor = readAnchor
+
+	for _, o := range others {
+		if o.ID == b.ID || *o.UserID != *b.UserID {
+			continue
+		}
+		if err := o.Trash(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
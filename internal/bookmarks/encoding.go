@@ -18,6 +18,11 @@ import (
 	"codeberg.org/readeck/readeck/pkg/base58"
 )
 
+const (
+	keyBookmarkShare    = "bookmark_share_"
+	keyCollectionDigest = "collection_digest_"
+)
+
 // EncodeID returns an 160-bit base58 encoded ID and timestamp.
 // This provide stateless expiration.
 //
@@ -33,6 +38,28 @@ import (
 //
 // This returns a base58 encoded string.
 func EncodeID(id uint64, expires time.Time) (string, error) {
+	return encodeID(keyBookmarkShare, id, expires)
+}
+
+// DecodeID deciphers a base58 encoded value
+// into a timestamp and a unsigned integer.
+func DecodeID(text string) (uint64, time.Time, error) {
+	return decodeID(keyBookmarkShare, text)
+}
+
+// EncodeDigestID returns an encoded collection ID, using the
+// same scheme as [EncodeID], for email digest links. It uses its
+// own key so a digest token can't be used as a share token.
+func EncodeDigestID(id uint64, expires time.Time) (string, error) {
+	return encodeID(keyCollectionDigest, id, expires)
+}
+
+// DecodeDigestID deciphers a value returned by [EncodeDigestID].
+func DecodeDigestID(text string) (uint64, time.Time, error) {
+	return decodeID(keyCollectionDigest, text)
+}
+
+func encodeID(keyPrefix string, id uint64, expires time.Time) (string, error) {
 	msg := make([]byte, 20)
 	binary.LittleEndian.PutUint32(msg[12:16], uint32(id))
 
@@ -45,7 +72,7 @@ func EncodeID(id uint64, expires time.Time) (string, error) {
 		return "", err
 	}
 
-	k, err := configs.Keys.Expand("bookmark_share_"+string(salt), 32)
+	k, err := configs.Keys.Expand(keyPrefix+string(salt), 32)
 	if err != nil {
 		return "", err
 	}
@@ -63,9 +90,7 @@ func EncodeID(id uint64, expires time.Time) (string, error) {
 	return base58.EncodeToString(msg), nil
 }
 
-// DecodeID deciphers a base58 encoded value
-// into a timestamp and a unsigned integer.
-func DecodeID(text string) (uint64, time.Time, error) {
+func decodeID(keyPrefix, text string) (uint64, time.Time, error) {
 	// Load the base64 encoded value. It must be exactly 16 bytes.
 	msg, err := base58.DecodeString(text)
 	if err != nil {
@@ -78,7 +103,7 @@ func DecodeID(text string) (uint64, time.Time, error) {
 
 	// Get mac and salt
 	salt := msg[:4]
-	k, _ := configs.Keys.Expand("bookmark_share_"+string(salt), 32)
+	k, _ := configs.Keys.Expand(keyPrefix+string(salt), 32)
 
 	// Verify MAC
 	h, err := blake2b.New(8, k)
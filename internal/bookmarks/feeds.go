@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks
+
+import (
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+const (
+	// FeedTable is the feed subscription table name in database.
+	FeedTable = "bookmark_feed"
+)
+
+var (
+	// Feeds is the feed subscription query manager.
+	Feeds = FeedManager{}
+
+	// ErrFeedNotFound is returned when a feed record was not found.
+	ErrFeedNotFound = errors.New("not found")
+)
+
+// Feed is a feed subscription record in the database.
+// Every new entry of a feed becomes a bookmark that receives
+// the feed's labels.
+type Feed struct {
+	ID           int           `db:"id" goqu:"skipinsert,skipupdate"`
+	UID          string        `db:"uid"`
+	UserID       *int          `db:"user_id"`
+	Created      time.Time     `db:"created" goqu:"skipupdate"`
+	Updated      time.Time     `db:"updated"`
+	LastFetched  *time.Time    `db:"last_fetched"`
+	URL          string        `db:"url"`
+	Title        string        `db:"title"`
+	SiteURL      string        `db:"site_url"`
+	Labels       types.Strings `db:"labels"`
+	ArchiveAfter int           `db:"archive_after"`
+	Entries      types.Strings `db:"entries"`
+	LastError    string        `db:"last_error"`
+}
+
+// FeedManager is a query helper for feed entries.
+type FeedManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *FeedManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(FeedTable).As("f")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *FeedManager) GetOne(expressions ...goqu.Expression) (*Feed, error) {
+	var f Feed
+	found, err := m.Query().Where(expressions...).ScanStruct(&f)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrFeedNotFound
+	}
+
+	return &f, nil
+}
+
+// Create inserts a new feed in the database.
+func (m *FeedManager) Create(feed *Feed) error {
+	if feed.UserID == nil {
+		return errors.New("no feed user")
+	}
+
+	feed.Created = time.Now()
+	feed.Updated = feed.Created
+	feed.UID = base58.NewUUID()
+
+	if feed.Labels == nil {
+		feed.Labels = types.Strings{}
+	}
+	if feed.Entries == nil {
+		feed.Entries = types.Strings{}
+	}
+
+	ds := db.Q().Insert(FeedTable).
+		Rows(feed).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	feed.ID = id
+
+	return nil
+}
+
+// Update updates some feed values.
+func (f *Feed) Update(v interface{}) error {
+	if f.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		v["updated"] = time.Now()
+	default:
+		//
+	}
+
+	_, err := db.Q().Update(FeedTable).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(f.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Save updates all the feed values.
+func (f *Feed) Save() error {
+	f.Updated = time.Now()
+	return f.Update(f)
+}
+
+// Delete removes a feed from the database.
+// The bookmarks created from the feed are kept.
+func (f *Feed) Delete() error {
+	_, err := db.Q().Delete(FeedTable).Prepared(true).
+		Where(goqu.C("id").Eq(f.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// ArchiveBookmarks archives the feed's bookmarks that are older than
+// the feed's ArchiveAfter value (in days). Favorite bookmarks are
+// left untouched.
+// It returns the number of archived bookmarks.
+func (f *Feed) ArchiveBookmarks() (int64, error) {
+	if f.ArchiveAfter <= 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	res, err := db.Q().Update(TableName).Prepared(true).
+		Set(goqu.Record{"is_archived": true, "updated": now}).
+		Where(
+			goqu.C("feed_id").Eq(f.ID),
+			goqu.C("is_archived").Eq(false),
+			goqu.C("is_marked").Eq(false),
+			goqu.C("created").Lt(now.AddDate(0, 0, -f.ArchiveAfter)),
+		).
+		Executor().Exec()
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// GetSumStrings returns the string used to generate the etag
+// of the feed(s).
+func (f *Feed) GetSumStrings() []string {
+	return []string{f.UID, f.Updated.String()}
+}
@@ -7,7 +7,10 @@ package bookmarks
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"html"
 	"reflect"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/doug-martin/goqu/v9"
@@ -24,12 +27,19 @@ const (
 	filtersReadStatusUnread  = "unread"
 	filtersReadStatusReading = "reading"
 	filtersReadStatusRead    = "read"
+
+	filtersDuplicateAny = "yes"
+
+	// filtersIDListSize is the maximum number of IDs in
+	// a single "IN" condition.
+	filtersIDListSize = 500
 )
 
 // Filters is the filter list shared between the filter form
 // and collection data.
 type Filters struct {
-	sq searchstring.SearchQuery
+	sq         searchstring.SearchQuery
+	duplicates map[string][]int
 
 	Search     string        `json:"search"`
 	Title      string        `json:"title"`
@@ -182,7 +192,7 @@ func (f *Filters) updateValues() {
 	f.sq = f.sq.Dedup()
 
 	// Remove field definition for unallowed fields
-	f.sq = f.sq.Unfield("title", "author", "site", "label")
+	f.sq = f.sq.Unfield("title", "author", "site", "label", "note", "duplicate")
 
 	// Then, restore the specific properties
 	updateValues := func(name string, p *string) {
@@ -192,7 +202,16 @@ func (f *Filters) updateValues() {
 		}
 	}
 
-	updateValues("", &f.Search)
+	// Notes and duplicates have no dedicated property and stay
+	// in the free form search
+	search := searchstring.SearchQuery{Terms: []searchstring.SearchTerm{}}
+	for _, t := range f.sq.Terms {
+		if t.Field == "" || t.Field == "note" || t.Field == "duplicate" {
+			search.Terms = append(search.Terms, t)
+		}
+	}
+	f.Search = search.String()
+
 	updateValues("title", &f.Title)
 	updateValues("author", &f.Author)
 	updateValues("site", &f.Site)
@@ -204,12 +223,14 @@ func (f *Filters) updateValues() {
 func (f Filters) ToSelectDataSet(ds *goqu.SelectDataset) *goqu.SelectDataset {
 	(&f).updateValues()
 
-	// Separate labels from the final search string
+	// Separate labels and duplicates from the final search string
 	var labels searchstring.SearchQuery
+	var duplicates searchstring.SearchQuery
 	var search searchstring.SearchQuery
 	if len(f.sq.Terms) > 0 {
 		labels, search = f.sq.PopField("label")
 		labels = labels.RemoveFieldInfo()
+		duplicates, search = search.PopField("duplicate")
 	}
 
 	// Label filter
@@ -231,6 +252,11 @@ func (f Filters) ToSelectDataSet(ds *goqu.SelectDataset) *goqu.SelectDataset {
 		ds = exp.JSONListFilter(ds, l...)
 	}
 
+	// Duplicate filter
+	for _, x := range duplicates.Terms {
+		ds = ds.Where(idListCondition(f.duplicates[x.Value], x.Exclude))
+	}
+
 	// Build the search query
 	if len(search.Terms) > 0 {
 		ds = searchstring.BuildSQL(ds, search, searchConfig[ds.Dialect().Dialect()])
@@ -319,6 +345,121 @@ func (f Filters) ToSelectDataSet(ds *goqu.SelectDataset) *goqu.SelectDataset {
 	return ds
 }
 
+// LoadDuplicates finds the bookmarks matched by the "duplicate" search
+// terms, among the bookmarks of the given user.
+// "duplicate:yes" matches the bookmarks having nearly the same text as
+// another bookmark. "duplicate:<id>" matches the bookmark with this ID
+// and the ones with nearly the same text.
+// It must be called before [Filters.ToSelectDataSet], otherwise these
+// terms don't match any bookmark.
+func (f *Filters) LoadDuplicates(userID int) error {
+	f.updateValues()
+	duplicates, _ := f.sq.PopField("duplicate")
+	if len(duplicates.Terms) == 0 {
+		return nil
+	}
+
+	list, err := getFingerprints(goqu.I("b.user_id").Eq(userID))
+	if err != nil {
+		return err
+	}
+
+	f.duplicates = map[string][]int{}
+	for _, t := range duplicates.Terms {
+		if t.Value == filtersDuplicateAny {
+			f.duplicates[t.Value] = duplicateIDs(list)
+			continue
+		}
+		for _, x := range list {
+			if x.UID == t.Value {
+				f.duplicates[t.Value] = similarIDs(list, x.UserID, x.Simhash)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// idListCondition returns a condition matching the bookmarks with the
+// given IDs, or the other ones when exclude is true. Long lists are split
+// to stay under the database's limits.
+func idListCondition(ids []int, exclude bool) goquexp.Expression {
+	switch {
+	case len(ids) == 0 && exclude:
+		return goqu.L("1 = 1")
+	case len(ids) == 0:
+		return goqu.L("1 = 0")
+	}
+
+	col := goqu.I("b.id")
+	conditions := []goquexp.Expression{}
+	for chunk := range slices.Chunk(ids, filtersIDListSize) {
+		if exclude {
+			conditions = append(conditions, col.NotIn(chunk))
+		} else {
+			conditions = append(conditions, col.In(chunk))
+		}
+	}
+
+	if exclude {
+		return goqu.And(conditions...)
+	}
+	return goqu.Or(conditions...)
+}
+
+// Snippets returns, for each of the given bookmark IDs, an HTML excerpt
+// of the text surrounding the terms matching the search query. The terms
+// are wrapped in a "mark" element. Bookmarks without any matching term
+// in their text are left out.
+func (f Filters) Snippets(ids []int) (map[int]string, error) {
+	res := map[int]string{}
+	(&f).updateValues()
+
+	_, search := f.sq.PopField("label")
+	_, search = search.PopField("duplicate")
+	if len(ids) == 0 || len(search.Terms) == 0 {
+		return res, nil
+	}
+
+	ds := Bookmarks.Query()
+	cfg := searchConfig[ds.Dialect().Dialect()]
+	expr := searchstring.SnippetSQL(ds.Dialect().Dialect(), search, cfg)
+	if expr == nil {
+		return res, nil
+	}
+
+	var items []struct {
+		ID      int    `db:"id"`
+		Snippet string `db:"snippet"`
+	}
+	ds = searchstring.BuildSQL(
+		ds.Select(goqu.I("b.id"), goqu.L("?", expr).As("snippet")).
+			Where(goqu.I("b.id").In(ids)),
+		search, cfg,
+	)
+	if err := ds.ScanStructs(&items); err != nil {
+		return nil, err
+	}
+
+	for _, x := range items {
+		if strings.Contains(x.Snippet, searchstring.SnippetStart) {
+			res[x.ID] = snippetToHTML(x.Snippet)
+		}
+	}
+	return res, nil
+}
+
+// snippetToHTML escapes a snippet and replaces its markers
+// with "mark" elements.
+func snippetToHTML(s string) string {
+	s = html.EscapeString(strings.Join(strings.Fields(s), " "))
+	return strings.NewReplacer(
+		searchstring.SnippetStart, "<mark>",
+		searchstring.SnippetEnd, "</mark>",
+	).Replace(s)
+}
+
 var searchConfig = map[string]*searchstring.BuilderConfig{
 	"sqlite3": searchstring.NewBuilderConfig(
 		goqu.I("b.id"),
@@ -329,17 +470,19 @@ var searchConfig = map[string]*searchstring.BuilderConfig{
 			{"author", "author"},
 			{"site", "site"},
 			{"label", "label"},
+			{"note", "note"},
 		},
-	),
+	).WithSnippet("3"), // bookmark_idx.text
 	"postgres": searchstring.NewBuilderConfig(
 		goqu.I("b.id"),
 		goqu.I("bookmark_search.bookmark_id"),
 		[][2]string{
-			{"", `bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label"`},
+			{"", `bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label" || bookmark_search.note`},
 			{"title", "bookmark_search.title"},
 			{"author", "bookmark_search.author"},
 			{"site", "bookmark_search.site"},
 			{"label", "bookmark_search.label"},
+			{"note", "bookmark_search.note"},
 		},
-	),
+	).WithSnippet("b.text"),
 }
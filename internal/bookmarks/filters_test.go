@@ -246,6 +246,48 @@ func TestFilters(t *testing.T) {
 				"range_end": ""
 			}`,
 		},
+		{
+			`{
+				"search": "test note:idea label:XYZ"
+			}`,
+			`{
+				"search": "test note:idea",
+				"title": "",
+				"author": "",
+				"site": "",
+				"type": null,
+				"labels": "XYZ",
+				"read_status": null,
+				"is_marked": null,
+				"is_archived": null,
+				"is_loaded": null,
+				"has_errors": null,
+				"has_labels": null,
+				"range_start": "",
+				"range_end": ""
+			}`,
+		},
+		{
+			`{
+				"search": "test -duplicate:yes label:XYZ duplicate:abcd foo:bar"
+			}`,
+			`{
+				"search": "test -duplicate:yes duplicate:abcd foo:bar",
+				"title": "",
+				"author": "",
+				"site": "",
+				"type": null,
+				"labels": "XYZ",
+				"read_status": null,
+				"is_marked": null,
+				"is_archived": null,
+				"is_loaded": null,
+				"has_errors": null,
+				"has_labels": null,
+				"range_start": "",
+				"range_end": ""
+			}`,
+		},
 	}))
 
 	t.Run("to form", runFiltersToForm([]struct {
@@ -449,6 +491,16 @@ func TestFilters(t *testing.T) {
 		filters  bookmarks.Filters
 		expected [2]string
 	}{
+		{
+			// Duplicates are only found by LoadDuplicates
+			bookmarks.Filters{
+				Search: "duplicate:yes -duplicate:abcd",
+			},
+			[2]string{
+				"SELECT `b`.* FROM `bookmark` WHERE (1 = 0 AND 1 = 1)",
+				`SELECT "b".* FROM "bookmark" WHERE (1 = 0 AND 1 = 1)`,
+			},
+		},
 		{
 			bookmarks.Filters{
 				Title: "title--",
@@ -464,7 +516,7 @@ func TestFilters(t *testing.T) {
 			},
 			[2]string{
 				"SELECT `b`.* FROM `bookmark` INNER JOIN `bookmark_idx` ON (`bookmark_idx`.`rowid` = `b`.`id`) WHERE `bookmark_idx` match 'catchall:oooooo AND -catchall:\"test\" AND title:\"title\"' ORDER BY rank ASC",
-				`SELECT "b".* FROM "bookmark" INNER JOIN "bookmark_search" ON ("bookmark_search"."bookmark_id" = "b"."id") WHERE (bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label" @@ to_tsquery('ts', '(test)') AND bookmark_search.title @@ to_tsquery('ts', '(title)')) ORDER BY ts_rank_cd(bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label", to_tsquery('ts', '(test)')) DESC, ts_rank_cd(bookmark_search.title, to_tsquery('ts', '(title)')) DESC`,
+				`SELECT "b".* FROM "bookmark" INNER JOIN "bookmark_search" ON ("bookmark_search"."bookmark_id" = "b"."id") WHERE (bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label" || bookmark_search.note @@ to_tsquery('ts', '(test)') AND bookmark_search.title @@ to_tsquery('ts', '(title)')) ORDER BY ts_rank_cd(bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label" || bookmark_search.note, to_tsquery('ts', '(test)')) DESC, ts_rank_cd(bookmark_search.title, to_tsquery('ts', '(title)')) DESC`,
 			},
 		},
 		{
@@ -473,7 +525,7 @@ func TestFilters(t *testing.T) {
 			},
 			[2]string{
 				"SELECT `b`.* FROM `bookmark` INNER JOIN `bookmark_idx` ON (`bookmark_idx`.`rowid` = `b`.`id`) WHERE `bookmark_idx` match 'catchall:oooooo AND -catchall:\"test\" NOT title:\"title\"' ORDER BY rank ASC",
-				`SELECT "b".* FROM "bookmark" INNER JOIN "bookmark_search" ON ("bookmark_search"."bookmark_id" = "b"."id") WHERE (bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label" @@ to_tsquery('ts', '(test)') AND bookmark_search.title @@ to_tsquery('ts', '!(title)')) ORDER BY ts_rank_cd(bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label", to_tsquery('ts', '(test)')) DESC, ts_rank_cd(bookmark_search.title, to_tsquery('ts', '!(title)')) DESC`,
+				`SELECT "b".* FROM "bookmark" INNER JOIN "bookmark_search" ON ("bookmark_search"."bookmark_id" = "b"."id") WHERE (bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label" || bookmark_search.note @@ to_tsquery('ts', '(test)') AND bookmark_search.title @@ to_tsquery('ts', '!(title)')) ORDER BY ts_rank_cd(bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label" || bookmark_search.note, to_tsquery('ts', '(test)')) DESC, ts_rank_cd(bookmark_search.title, to_tsquery('ts', '!(title)')) DESC`,
 			},
 		},
 		{
@@ -483,7 +535,16 @@ func TestFilters(t *testing.T) {
 			},
 			[2]string{
 				"SELECT `b`.* FROM `bookmark` INNER JOIN `bookmark_idx` ON (`bookmark_idx`.`rowid` = `b`.`id`) WHERE `bookmark_idx` match 'catchall:oooooo AND -catchall:\"test\" AND title:\"title\" AND title:\"x\"' ORDER BY rank ASC",
-				`SELECT "b".* FROM "bookmark" INNER JOIN "bookmark_search" ON ("bookmark_search"."bookmark_id" = "b"."id") WHERE (bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label" @@ to_tsquery('ts', '(test)') AND bookmark_search.title @@ to_tsquery('ts', '(title) & (x)')) ORDER BY ts_rank_cd(bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label", to_tsquery('ts', '(test)')) DESC, ts_rank_cd(bookmark_search.title, to_tsquery('ts', '(title) & (x)')) DESC`,
+				`SELECT "b".* FROM "bookmark" INNER JOIN "bookmark_search" ON ("bookmark_search"."bookmark_id" = "b"."id") WHERE (bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label" || bookmark_search.note @@ to_tsquery('ts', '(test)') AND bookmark_search.title @@ to_tsquery('ts', '(title) & (x)')) ORDER BY ts_rank_cd(bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label" || bookmark_search.note, to_tsquery('ts', '(test)')) DESC, ts_rank_cd(bookmark_search.title, to_tsquery('ts', '(title) & (x)')) DESC`,
+			},
+		},
+		{
+			bookmarks.Filters{
+				Search: "test note:idea*",
+			},
+			[2]string{
+				"SELECT `b`.* FROM `bookmark` INNER JOIN `bookmark_idx` ON (`bookmark_idx`.`rowid` = `b`.`id`) WHERE `bookmark_idx` match 'catchall:oooooo AND -catchall:\"test\" AND note:\"idea\"*' ORDER BY rank ASC",
+				`SELECT "b".* FROM "bookmark" INNER JOIN "bookmark_search" ON ("bookmark_search"."bookmark_id" = "b"."id") WHERE (bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label" || bookmark_search.note @@ to_tsquery('ts', '(test)') AND bookmark_search.note @@ to_tsquery('ts', '(idea:*)')) ORDER BY ts_rank_cd(bookmark_search.title || bookmark_search.description || bookmark_search."text" || bookmark_search.site || bookmark_search."label" || bookmark_search.note, to_tsquery('ts', '(test)')) DESC, ts_rank_cd(bookmark_search.note, to_tsquery('ts', '(idea:*)')) DESC`,
 			},
 		},
 		{
@@ -16,8 +16,6 @@ import (
 	"slices"
 	"time"
 
-	"github.com/doug-martin/goqu/v9"
-
 	"codeberg.org/readeck/readeck/internal/auth/users"
 	"codeberg.org/readeck/readeck/internal/bookmarks"
 	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
@@ -216,7 +214,7 @@ func (imp importer) createBookmark(next func() (BookmarkImporter, error)) (*book
 	if !slices.Contains(allowedSchemes, uri.Scheme) {
 		return nil, fmt.Errorf("%w: invalid scheme %s (%s)", ErrIgnore, uri.Scheme, uri)
 	}
-	uri.Fragment = ""
+	uri = bookmarks.CleanURL(uri)
 
 	b := &bookmarks.Bookmark{
 		UserID:   &imp.user.ID,
@@ -227,17 +225,11 @@ func (imp importer) createBookmark(next func() (BookmarkImporter, error)) (*book
 	}
 
 	if !imp.allowDuplicates {
-		count, err := bookmarks.Bookmarks.Query().Where(
-			goqu.C("user_id").Eq(imp.user.ID),
-			goqu.Or(
-				goqu.C("url").Eq(uri.String()),
-				goqu.C("initial_url").Eq(uri.String()),
-			),
-		).Prepared(true).Count()
+		duplicates, err := bookmarks.Bookmarks.FindDuplicates(imp.user.ID, uri)
 		if err != nil {
 			return nil, err
 		}
-		if count > 0 {
+		if len(duplicates) > 0 {
 			return nil, fmt.Errorf("already exists, %w", ErrIgnore)
 		}
 	}
@@ -64,7 +64,7 @@ func init() {
 				}
 				return res
 			}),
-			superbus.WithTaskHandler(importExtractHandler),
+			superbus.WithTaskHandlerE(importExtractHandler),
 		)
 	})
 }
@@ -111,7 +111,7 @@ func importBookmarksHandler(data interface{}) {
 	})
 }
 
-func importExtractHandler(data interface{}) {
+func importExtractHandler(data interface{}) error {
 	params := data.(tasks.ExtractParams)
 	trackID := GetTrackID(params.RequestID)
 
@@ -134,7 +134,7 @@ func importExtractHandler(data interface{}) {
 		}
 	}()
 
-	tasks.ExtractPage(params)
+	return tasks.ExtractPage(params)
 }
 
 func getStoreProgressList(trackID string) (ids []int) {
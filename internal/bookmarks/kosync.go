@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks
+
+import (
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+)
+
+const (
+	// KosyncDocumentTable is the KOReader document table name in database.
+	KosyncDocumentTable = "kosync_document"
+)
+
+var (
+	// KosyncDocuments is the KOReader document query manager.
+	KosyncDocuments = KosyncDocumentManager{}
+
+	// ErrKosyncDocumentNotFound is returned when a document record was not found.
+	ErrKosyncDocumentNotFound = errors.New("not found")
+)
+
+// KosyncDocument is a document KOReader synchronizes the reading
+// progress of. KOReader identifies a document with a digest of its
+// file or file name. When the file is a bookmark's e-book, the document
+// is linked to the bookmark.
+type KosyncDocument struct {
+	ID         int       `db:"id" goqu:"skipinsert,skipupdate"`
+	UserID     int       `db:"user_id"`
+	BookmarkID *int      `db:"bookmark_id"`
+	Document   string    `db:"document"`
+	Updated    time.Time `db:"updated"`
+	Progress   string    `db:"progress"`
+	Percentage float64   `db:"percentage"`
+	Device     string    `db:"device"`
+	DeviceID   string    `db:"device_id"`
+}
+
+// KosyncDocumentManager is a query helper for KOReader document entries.
+type KosyncDocumentManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *KosyncDocumentManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(KosyncDocumentTable).As("k")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *KosyncDocumentManager) GetOne(expressions ...goqu.Expression) (*KosyncDocument, error) {
+	var d KosyncDocument
+	found, err := m.Query().Where(expressions...).ScanStruct(&d)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrKosyncDocumentNotFound
+	}
+
+	return &d, nil
+}
+
+// GetDocument returns a user's document.
+func (m *KosyncDocumentManager) GetDocument(userID int, document string) (*KosyncDocument, error) {
+	return m.GetOne(
+		goqu.C("user_id").Eq(userID),
+		goqu.C("document").Eq(document),
+	)
+}
+
+// Create inserts a new document in the database.
+func (m *KosyncDocumentManager) Create(d *KosyncDocument) error {
+	if d.UserID == 0 {
+		return errors.New("no document user")
+	}
+	if d.Document == "" {
+		return errors.New("no document digest")
+	}
+
+	d.Updated = time.Now()
+	ds := db.Q().Insert(KosyncDocumentTable).
+		Rows(d).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	d.ID = id
+	return nil
+}
+
+// Link links the given document digests to a bookmark.
+// The documents that don't exist yet are created.
+func (m *KosyncDocumentManager) Link(b *Bookmark, documents ...string) error {
+	for _, x := range documents {
+		d, err := m.GetDocument(*b.UserID, x)
+		switch {
+		case errors.Is(err, ErrKosyncDocumentNotFound):
+			err = m.Create(&KosyncDocument{
+				UserID:     *b.UserID,
+				BookmarkID: &b.ID,
+				Document:   x,
+			})
+		case err == nil && (d.BookmarkID == nil || *d.BookmarkID != b.ID):
+			err = d.Update(goqu.Record{"bookmark_id": b.ID})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Update updates some document values.
+func (d *KosyncDocument) Update(v interface{}) error {
+	if d.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	_, err := db.Q().Update(KosyncDocumentTable).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(d.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Save updates all the document values.
+func (d *KosyncDocument) Save() error {
+	d.Updated = time.Now()
+	return d.Update(d)
+}
@@ -0,0 +1,246 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	_ "time/tzdata" // the schedules' time zones must exist on every system
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+const (
+	// NewspaperTable is the newspaper table name in database.
+	NewspaperTable = "bookmark_newspaper"
+
+	// NewspaperDaily is the every day schedule.
+	NewspaperDaily = "daily"
+	// NewspaperWeekdays is the monday to friday schedule.
+	NewspaperWeekdays = "weekdays"
+	// NewspaperWeekly is the once a week schedule.
+	NewspaperWeekly = "weekly"
+)
+
+// NewspaperSchedules is the list of newspaper schedules.
+var NewspaperSchedules = []string{NewspaperDaily, NewspaperWeekdays, NewspaperWeekly}
+
+var (
+	// Newspapers is the newspaper query manager.
+	Newspapers = NewspaperManager{}
+
+	// ErrNewspaperNotFound is returned when a newspaper record was not found.
+	ErrNewspaperNotFound = errors.New("not found")
+)
+
+// Newspaper is a scheduled delivery of the unread bookmarks of a
+// collection. On every issue, the bookmarks saved since the previous
+// one are bundled in an EPUB file that is sent by email and/or
+// published in the OPDS catalog.
+type Newspaper struct {
+	ID            int           `db:"id" goqu:"skipinsert,skipupdate"`
+	UID           string        `db:"uid"`
+	UserID        *int          `db:"user_id"`
+	CollectionID  int           `db:"collection_id"`
+	Created       time.Time     `db:"created" goqu:"skipupdate"`
+	Updated       time.Time     `db:"updated"`
+	Title         string        `db:"title"`
+	Schedule      string        `db:"schedule"`
+	Weekday       int           `db:"weekday"`
+	DeliveryTime  string        `db:"delivery_time"`
+	Timezone      string        `db:"timezone"`
+	MaxItems      int           `db:"max_items"`
+	SendEmail     bool          `db:"send_email"`
+	PublishOPDS   bool          `db:"publish_opds"`
+	AfterDelivery string        `db:"after_delivery"`
+	LastRun       *time.Time    `db:"last_run"`
+	LastDelivery  *time.Time    `db:"last_delivery"`
+	IssueDate     *time.Time    `db:"issue_date"`
+	IssueItems    types.Strings `db:"issue_items"`
+	LastError     string        `db:"last_error"`
+}
+
+// NewspaperManager is a query helper for newspaper entries.
+type NewspaperManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *NewspaperManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(NewspaperTable).As("n")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *NewspaperManager) GetOne(expressions ...goqu.Expression) (*Newspaper, error) {
+	var n Newspaper
+	found, err := m.Query().Where(expressions...).ScanStruct(&n)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrNewspaperNotFound
+	}
+
+	return &n, nil
+}
+
+// Create inserts a new newspaper in the database.
+func (m *NewspaperManager) Create(n *Newspaper) error {
+	if n.UserID == nil {
+		return errors.New("no newspaper user")
+	}
+
+	n.Created = time.Now()
+	n.Updated = n.Created
+	n.UID = base58.NewUUID()
+
+	if n.IssueItems == nil {
+		n.IssueItems = types.Strings{}
+	}
+
+	ds := db.Q().Insert(NewspaperTable).
+		Rows(n).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	n.ID = id
+
+	return nil
+}
+
+// Update updates some newspaper values.
+func (n *Newspaper) Update(v interface{}) error {
+	if n.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		v["updated"] = time.Now()
+	default:
+		//
+	}
+
+	_, err := db.Q().Update(NewspaperTable).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(n.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Save updates all the newspaper values.
+func (n *Newspaper) Save() error {
+	n.Updated = time.Now()
+	return n.Update(n)
+}
+
+// Delete removes a newspaper from the database.
+func (n *Newspaper) Delete() error {
+	_, err := db.Q().Delete(NewspaperTable).Prepared(true).
+		Where(goqu.C("id").Eq(n.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// SetDelivered saves the result of an issue run. Unlike [Newspaper.Update],
+// it leaves the newspaper's update date untouched.
+// The issue is only replaced when it has items. The delivery date only
+// moves when the run succeeded, so the next issue contains the
+// bookmarks of a failed one.
+func (n *Newspaper) SetDelivered(t time.Time, items types.Strings, lastError string) error {
+	record := goqu.Record{"last_run": t, "last_error": lastError}
+	n.LastRun = &t
+	n.LastError = lastError
+	if lastError == "" {
+		record["last_delivery"] = t
+		n.LastDelivery = &t
+	}
+	if len(items) > 0 {
+		record["issue_date"] = t
+		record["issue_items"] = items
+		n.IssueDate = &t
+		n.IssueItems = items
+	}
+
+	_, err := db.Q().Update(NewspaperTable).Prepared(true).
+		Set(record).
+		Where(goqu.C("id").Eq(n.ID)).
+		Executor().Exec()
+	return err
+}
+
+// Location returns the newspaper's time zone.
+func (n *Newspaper) Location() *time.Location {
+	if loc, err := time.LoadLocation(n.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// Period returns the usual duration between two issues.
+func (n *Newspaper) Period() time.Duration {
+	if n.Schedule == NewspaperWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// LastSchedule returns the latest scheduled delivery time
+// that is not after now.
+func (n *Newspaper) LastSchedule(now time.Time) time.Time {
+	var hour, minute int
+	fmt.Sscanf(n.DeliveryTime, "%d:%d", &hour, &minute) //nolint:errcheck
+
+	now = now.In(n.Location())
+	t := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if t.After(now) {
+		t = t.AddDate(0, 0, -1)
+	}
+
+	for i := 0; i < 7 && !n.isDeliveryDay(t.Weekday()); i++ {
+		t = t.AddDate(0, 0, -1)
+	}
+
+	return t
+}
+
+// isDeliveryDay returns true when an issue is delivered on the given day.
+func (n *Newspaper) isDeliveryDay(wd time.Weekday) bool {
+	switch n.Schedule {
+	case NewspaperWeekdays:
+		return wd != time.Saturday && wd != time.Sunday
+	case NewspaperWeekly:
+		return int(wd) == n.Weekday
+	}
+	return true
+}
+
+// IsDue returns true when an issue must be delivered. That's when
+// a scheduled time passed since the previous issue or, for a new
+// newspaper, since its creation.
+func (n *Newspaper) IsDue(now time.Time) bool {
+	since := n.Created
+	if n.LastRun != nil {
+		since = *n.LastRun
+	}
+	return n.LastSchedule(now).After(since)
+}
+
+// GetSumStrings returns the string used to generate the etag
+// of the newspaper(s).
+func (n *Newspaper) GetSumStrings() []string {
+	return []string{n.UID, n.Updated.String()}
+}
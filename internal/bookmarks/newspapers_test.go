@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+)
+
+func TestNewspaperSchedule(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	// Friday, 5:30 in Paris
+	now := time.Date(2025, 3, 14, 5, 30, 0, 0, paris)
+
+	tests := []struct {
+		schedule string
+		weekday  int
+		expected time.Time
+	}{
+		{bookmarks.NewspaperDaily, 0, time.Date(2025, 3, 13, 6, 0, 0, 0, paris)},
+		{bookmarks.NewspaperWeekdays, 0, time.Date(2025, 3, 13, 6, 0, 0, 0, paris)},
+		{bookmarks.NewspaperWeekly, 5, time.Date(2025, 3, 7, 6, 0, 0, 0, paris)},
+		{bookmarks.NewspaperWeekly, 1, time.Date(2025, 3, 10, 6, 0, 0, 0, paris)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.schedule, func(t *testing.T) {
+			n := &bookmarks.Newspaper{
+				Schedule:     test.schedule,
+				Weekday:      test.weekday,
+				DeliveryTime: "06:00",
+				Timezone:     "Europe/Paris",
+			}
+			require.True(t, test.expected.Equal(n.LastSchedule(now)), n.LastSchedule(now))
+		})
+	}
+
+	t.Run("weekend", func(t *testing.T) {
+		n := &bookmarks.Newspaper{
+			Schedule:     bookmarks.NewspaperWeekdays,
+			DeliveryTime: "06:00",
+			Timezone:     "Europe/Paris",
+		}
+		sunday := time.Date(2025, 3, 16, 12, 0, 0, 0, paris)
+		require.True(t, time.Date(2025, 3, 14, 6, 0, 0, 0, paris).Equal(n.LastSchedule(sunday)))
+	})
+
+	t.Run("due", func(t *testing.T) {
+		n := &bookmarks.Newspaper{
+			Created:      time.Date(2025, 3, 13, 8, 0, 0, 0, paris),
+			Schedule:     bookmarks.NewspaperDaily,
+			DeliveryTime: "06:00",
+			Timezone:     "Europe/Paris",
+		}
+
+		// Created after the last scheduled time
+		require.False(t, n.IsDue(now))
+		require.True(t, n.IsDue(now.Add(time.Hour)))
+
+		// UTC is one hour behind Paris
+		n.Timezone = "UTC"
+		require.False(t, n.IsDue(now.Add(time.Hour)))
+		require.True(t, n.IsDue(now.Add(2*time.Hour)))
+
+		lastRun := now.Add(2 * time.Hour)
+		n.LastRun = &lastRun
+		require.False(t, n.IsDue(now.Add(3*time.Hour)))
+		require.True(t, n.IsDue(now.Add(26*time.Hour)))
+	})
+}
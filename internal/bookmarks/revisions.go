@@ -0,0 +1,192 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/pmezard/go-difflib/difflib"
+
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/pkg/base58"
+	"codeberg.org/readeck/readeck/pkg/storage"
+)
+
+const (
+	// RevisionTable is the bookmark revision table name in database.
+	RevisionTable = "bookmark_revision"
+
+	// revisionDirSuffix is appended to a bookmark's file path
+	// to get the folder holding its revisions.
+	revisionDirSuffix = "-revisions"
+)
+
+var (
+	// Revisions is the bookmark revision query manager.
+	Revisions = RevisionManager{}
+
+	// ErrRevisionNotFound is returned when a revision record was not found.
+	ErrRevisionNotFound = errors.New("not found")
+)
+
+// Revision is a previous version of a bookmark, kept when the bookmark
+// is refreshed. It holds the text and a copy of the archive as they were
+// before the refresh, and the diff between this text and the one
+// that replaced it.
+type Revision struct {
+	ID         int       `db:"id" goqu:"skipinsert,skipupdate"`
+	UID        string    `db:"uid"`
+	BookmarkID int       `db:"bookmark_id"`
+	Created    time.Time `db:"created" goqu:"skipupdate"`
+	Title      string    `db:"title"`
+	Text       string    `db:"text"`
+	WordCount  int       `db:"word_count"`
+	FilePath   string    `db:"file_path"`
+	Diff       string    `db:"diff"`
+}
+
+// RevisionManager is a query helper for revision entries.
+type RevisionManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *RevisionManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(RevisionTable).As("r")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *RevisionManager) GetOne(expressions ...goqu.Expression) (*Revision, error) {
+	var r Revision
+	found, err := m.Query().Where(expressions...).ScanStruct(&r)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrRevisionNotFound
+	}
+
+	return &r, nil
+}
+
+// Create inserts a new revision in the database.
+func (m *RevisionManager) Create(r *Revision) error {
+	if r.BookmarkID == 0 {
+		return errors.New("no revision bookmark")
+	}
+
+	if r.Created.IsZero() {
+		r.Created = time.Now()
+	}
+	r.UID = base58.NewUUID()
+
+	ds := db.Q().Insert(RevisionTable).
+		Rows(r).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	r.ID = id
+	return nil
+}
+
+// Update updates some revision values.
+func (r *Revision) Update(v interface{}) error {
+	if r.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	_, err := db.Q().Update(RevisionTable).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(r.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Delete removes a revision from the database, with its file.
+func (r *Revision) Delete() error {
+	_, err := db.Q().Delete(RevisionTable).Prepared(true).
+		Where(goqu.C("id").Eq(r.ID)).
+		Executor().Exec()
+	if err != nil {
+		return err
+	}
+
+	if r.FilePath != "" {
+		if err := Storage().Remove(r.FilePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			slog.Error("", slog.String("path", r.FilePath), slog.Any("err", err))
+		}
+	}
+	return nil
+}
+
+// NewRevision saves the bookmark's current state as a new revision.
+// The bookmark's archive, when it exists, is copied to a dated file
+// next to it.
+func (b *Bookmark) NewRevision() (*Revision, error) {
+	r := &Revision{
+		BookmarkID: b.ID,
+		Created:    time.Now(),
+		Title:      b.Title,
+		Text:       b.Text,
+		WordCount:  b.WordCount,
+	}
+
+	if src := b.GetFileName(); src != "" {
+		if _, err := Storage().Stat(src); err == nil {
+			r.FilePath = path.Join(
+				b.FilePath+revisionDirSuffix,
+				r.Created.UTC().Format("20060102T150405.000000000")+".zip",
+			)
+			if err := storage.Copy(Storage(), r.FilePath, Storage(), src); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := Revisions.Create(r); err != nil {
+		if r.FilePath != "" {
+			Storage().Remove(r.FilePath) // nolint:errcheck
+		}
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// removeRevisionFiles removes the folder holding the bookmark's revisions.
+func (b *Bookmark) removeRevisionFiles() {
+	if b.FilePath == "" {
+		return
+	}
+
+	dirname := b.FilePath + revisionDirSuffix
+	if err := Storage().RemoveAll(dirname); err != nil {
+		slog.Error("", slog.String("dir", dirname), slog.Any("err", err))
+	}
+}
+
+// TextDiff returns a unified diff between two texts. Paragraphs
+// are separated by empty lines so the diff works line by line.
+func TextDiff(from, to, fromName, toName string) string {
+	res, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(strings.TrimSpace(from) + "\n"),
+		B:        difflib.SplitLines(strings.TrimSpace(to) + "\n"),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  1,
+	})
+	return res
+}
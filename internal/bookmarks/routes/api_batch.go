@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+type ctxBatchKey struct{}
+
+type batchStatus struct {
+	ID        string `json:"id"`
+	Href      string `json:"href"`
+	Operation string `json:"operation"`
+	Total     int    `json:"total"`
+	Done      int    `json:"done"`
+	Failed    int    `json:"failed"`
+	Finished  bool   `json:"finished"`
+	Export    string `json:"export,omitempty"`
+}
+
+func (api *apiRouter) newBatchStatus(r *http.Request, b *tasks.Batch) batchStatus {
+	res := batchStatus{
+		ID:        b.ID,
+		Href:      api.srv.AbsoluteURL(r, "/api/bookmarks/batch", b.ID).String(),
+		Operation: b.Operation,
+		Total:     len(b.IDs),
+		Done:      b.Done,
+		Failed:    b.Failed,
+		Finished:  b.IsFinished(),
+	}
+	if b.Operation == tasks.BatchExport {
+		res.Export = api.srv.AbsoluteURL(r, "/api/bookmarks/batch", b.ID, "export.epub").String()
+	}
+
+	return res
+}
+
+// startBatch validates the batch form, resolves the selected bookmarks
+// and starts the batch. It returns nil when the form is not valid.
+func (api *apiRouter) startBatch(r *http.Request, f *batchForm) (*tasks.Batch, error) {
+	forms.Bind(f, r)
+	if !f.IsValid() {
+		return nil, nil
+	}
+
+	_, hasCollection := r.Context().Value(ctxCollectionKey{}).(*bookmarks.Collection)
+	filters := newContextFilterForm(r.Context(), api.srv.Locale(r))
+	forms.BindURL(filters, r)
+
+	userID := auth.GetRequestUser(r).ID
+	ids, err := f.selection(userID, filters, hasCollection)
+	if err != nil || !f.IsValid() {
+		return nil, err
+	}
+
+	b := tasks.NewBatch(userID, f.Get("operation").String(), f.labels(), ids)
+	if err = b.Start(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (api *apiRouter) bookmarkBatch(w http.ResponseWriter, r *http.Request) {
+	f := newBatchForm(api.srv.Locale(r))
+	b, err := api.startBatch(r, f)
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+	if b == nil {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	w.Header().Set("Location", api.srv.AbsoluteURL(r, ".", b.ID).String())
+	api.srv.Render(w, r, http.StatusAccepted, api.newBatchStatus(r, b))
+}
+
+func (api *apiRouter) bookmarkBatchStatus(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBatchKey{}).(*tasks.Batch)
+	api.srv.Render(w, r, http.StatusOK, api.newBatchStatus(r, b))
+}
+
+func (api *apiRouter) withBatch(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := tasks.GetBatch(chi.URLParam(r, "id"))
+		if err != nil {
+			if errors.Is(err, tasks.ErrBatchNotFound) {
+				api.srv.Status(w, r, http.StatusNotFound)
+			} else {
+				api.srv.Error(w, r, err)
+			}
+			return
+		}
+
+		if b.UserID != auth.GetRequestUser(r).ID {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxBatchKey{}, b)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// withBatchList loads the bookmarks of an export batch,
+// as a bookmark list.
+func (api *apiRouter) withBatchList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := r.Context().Value(ctxBatchKey{}).(*tasks.Batch)
+		if b.Operation != tasks.BatchExport {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		res := bookmarkList{items: []*bookmarks.Bookmark{}}
+		if err := bookmarks.Bookmarks.Query().
+			Where(
+				goqu.I("b.user_id").Eq(b.UserID),
+				goqu.I("b.id").In(b.IDs),
+			).
+			Order(goqu.I("b.created").Desc()).
+			ScanStructs(&res.items); err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxBookmarkListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
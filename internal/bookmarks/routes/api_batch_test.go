@@ -0,0 +1,270 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes_test
+
+import (
+	"net/url"
+	"path"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestBatch(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	u := app.Users["user"]
+
+	list := []*bookmarks.Bookmark{}
+	for i, title := range []string{"Batch 1", "Batch 2", "Batch 3"} {
+		b := &bookmarks.Bookmark{
+			UserID: &u.User.ID,
+			State:  bookmarks.StateLoaded,
+			URL:    "https://example.net/batch-" + string(rune('a'+i)),
+			Title:  title,
+			Labels: []string{"batch"},
+		}
+		require.NoError(t, bookmarks.Bookmarks.Create(b))
+		list = append(list, b)
+	}
+
+	getBookmark := func(t *testing.T, b *bookmarks.Bookmark) *bookmarks.Bookmark {
+		res, err := bookmarks.Bookmarks.GetOne(goqu.I("b.id").Eq(b.ID))
+		require.NoError(t, err)
+		return res
+	}
+
+	// runBatch runs the batch given in the response's location.
+	runBatch := func(t *testing.T, r *Response) *tasks.Batch {
+		b, err := tasks.GetBatch(path.Base(r.Header.Get("Location")))
+		require.NoError(t, err)
+		require.NoError(t, b.Run())
+		return b
+	}
+
+	t.Run("api", func(t *testing.T) {
+		RunRequestSequence(t, client, "user",
+			RequestTest{
+				Method: "POST",
+				Target: "/api/bookmarks/batch",
+				JSON: map[string]any{
+					"operation": "archive",
+				},
+				ExpectStatus: 422,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, ".errors", []any{"no bookmark selected"})
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/bookmarks/batch",
+				JSON: map[string]any{
+					"operation": "add_labels",
+					"id":        []string{list[0].UID},
+				},
+				ExpectStatus: 422,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, ".fields.labels.errors", []any{"field is required"})
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/bookmarks/batch",
+				JSON: map[string]any{
+					"operation": "add_labels",
+					"labels":    []string{"later", "bulk"},
+					"id":        []string{list[0].UID, list[1].UID},
+				},
+				ExpectStatus: 202,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, ".operation", "add_labels")
+					r.AssertJQ(t, ".total", 2.0)
+					r.AssertJQ(t, ".finished", false)
+
+					require.Len(t, Events().Records("task"), 1)
+					b := runBatch(t, r)
+					require.Equal(t, 2, b.Done)
+
+					require.EqualValues(t, []string{"batch", "bulk", "later"}, getBookmark(t, list[0]).Labels)
+					require.EqualValues(t, []string{"batch", "bulk", "later"}, getBookmark(t, list[1]).Labels)
+					require.EqualValues(t, []string{"batch"}, getBookmark(t, list[2]).Labels)
+				},
+			},
+			RequestTest{
+				Target:       "{{ (index .History 0).Header.Get `Location` }}",
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, ".done", 2.0)
+					r.AssertJQ(t, ".failed", 0.0)
+					r.AssertJQ(t, ".finished", true)
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/bookmarks/batch?labels=bulk",
+				JSON: map[string]any{
+					"operation": "archive",
+				},
+				ExpectStatus: 202,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, ".total", 2.0)
+					runBatch(t, r)
+
+					require.True(t, getBookmark(t, list[0]).IsArchived)
+					require.True(t, getBookmark(t, list[1]).IsArchived)
+					require.False(t, getBookmark(t, list[2]).IsArchived)
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/bookmarks/batch?labels=batch",
+				JSON: map[string]any{
+					"operation": "remove_labels",
+					"labels":    []string{"batch", "later"},
+				},
+				ExpectStatus: 202,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, ".total", 3.0)
+					runBatch(t, r)
+
+					require.EqualValues(t, []string{"bulk"}, getBookmark(t, list[0]).Labels)
+					require.Empty(t, getBookmark(t, list[2]).Labels)
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/bookmarks/batch",
+				JSON: map[string]any{
+					"operation": "read",
+					"all":       true,
+				},
+				ExpectStatus: 202,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, ".total", 4.0)
+					runBatch(t, r)
+
+					require.Equal(t, 100, getBookmark(t, list[2]).ReadProgress)
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/bookmarks/batch",
+				JSON: map[string]any{
+					"operation": "delete",
+					"id":        []string{list[2].UID},
+				},
+				ExpectStatus: 202,
+				Assert: func(t *testing.T, r *Response) {
+					runBatch(t, r)
+
+					count, err := bookmarks.Bookmarks.Trashed().
+						Where(goqu.I("b.id").Eq(list[2].ID)).
+						Count()
+					require.NoError(t, err)
+					require.Equal(t, int64(1), count)
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/bookmarks/batch",
+				JSON: map[string]any{
+					"operation": "export",
+					"id":        []string{u.Bookmarks[0].UID},
+				},
+				ExpectStatus: 202,
+				Assert: func(t *testing.T, r *Response) {
+					// An export has nothing to run
+					r.AssertJQ(t, ".finished", true)
+				},
+			},
+			RequestTest{
+				Target:       "{{ (index .History 0).Header.Get `Location` }}/export.epub",
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					require.Equal(t, "application/epub+zip", r.Header.Get("content-type"))
+				},
+			},
+		)
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		b := tasks.NewBatch(u.User.ID, tasks.BatchExport, nil, []int{u.Bookmarks[0].ID})
+		require.NoError(t, b.Start())
+
+		RunRequestSequence(t, client, "staff",
+			RequestTest{
+				Target:       "/api/bookmarks/batch/" + b.ID,
+				ExpectStatus: 404,
+			},
+			RequestTest{
+				Target:       "/api/bookmarks/batch/" + b.ID + "/export.epub",
+				ExpectStatus: 404,
+			},
+		)
+	})
+
+	t.Run("views", func(t *testing.T) {
+		RunRequestSequence(t, client, "user",
+			RequestTest{
+				Target:         "/bookmarks",
+				ExpectStatus:   200,
+				ExpectContains: `form="bookmark-batch"`,
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/bookmarks/batch",
+				Form: url.Values{
+					"operation": {"mark"},
+					"id":        {list[0].UID, list[1].UID},
+					"_to":       {"/bookmarks/archives"},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/bookmarks/archives",
+				Assert: func(t *testing.T, _ *Response) {
+					require.Len(t, Events().Records("task"), 1)
+				},
+			},
+			RequestTest{
+				Target:         "/bookmarks/archives",
+				ExpectStatus:   200,
+				ExpectContains: "The action is running on 2 bookmarks.",
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/bookmarks/batch",
+				Form: url.Values{
+					"operation": {"mark"},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/bookmarks",
+			},
+			RequestTest{
+				Target:         "/bookmarks",
+				ExpectStatus:   200,
+				ExpectContains: "Select at least one bookmark and an action.",
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/bookmarks/batch",
+				Form: url.Values{
+					"operation": {"export"},
+					"id":        {u.Bookmarks[0].UID},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: `/api/bookmarks/batch/[a-zA-Z0-9]+/export\.epub$`,
+			},
+		)
+	})
+}
@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -27,9 +28,11 @@ import (
 	"codeberg.org/readeck/readeck/internal/bookmarks"
 	"codeberg.org/readeck/readeck/internal/bookmarks/converter"
 	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/internal/searchstring"
 	"codeberg.org/readeck/readeck/internal/server"
 	"codeberg.org/readeck/readeck/pkg/annotate"
 	"codeberg.org/readeck/readeck/pkg/forms"
+	"codeberg.org/readeck/readeck/pkg/koreader"
 	"codeberg.org/readeck/readeck/pkg/zipfs"
 )
 
@@ -70,6 +73,7 @@ func (api *apiRouter) bookmarkList(w http.ResponseWriter, r *http.Request) {
 	bl.Items = make([]bookmarkItem, len(bl.items))
 	for i, item := range bl.items {
 		bl.Items[i] = newBookmarkItem(api.srv, r, item, ".")
+		bl.Items[i].Snippet = bl.snippets[item.ID]
 	}
 
 	api.srv.SendPaginationHeaders(w, r, bl.Pagination)
@@ -101,12 +105,18 @@ func (api *apiRouter) bookmarkArticle(w http.ResponseWriter, r *http.Request) {
 	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
 
 	bi := newBookmarkItem(api.srv, r, b, "")
+	bi.setHighlight(r)
 	buf, err := bi.getArticle()
 	if err != nil {
 		api.srv.Log(r).Error("", slog.Any("err", err))
 	}
 
 	if api.srv.IsTurboRequest(r) {
+		similar, err := b.GetSimilar()
+		if err != nil {
+			api.srv.Log(r).Error("", slog.Any("err", err))
+		}
+
 		api.srv.RenderTurboStream(w, r,
 			"/bookmarks/components/content_block", "replace",
 			"bookmark-content-"+b.UID, map[string]interface{}{
@@ -119,7 +129,8 @@ func (api *apiRouter) bookmarkArticle(w http.ResponseWriter, r *http.Request) {
 		api.srv.RenderTurboStream(w, r,
 			"/bookmarks/components/sidebar", "replace",
 			"bookmark-sidebar-"+b.UID, map[string]interface{}{
-				"Item": bi,
+				"Item":    bi,
+				"Similar": similar,
 			}, nil,
 		)
 		return
@@ -135,11 +146,17 @@ func (api *apiRouter) bookmarkArticle(w http.ResponseWriter, r *http.Request) {
 func (api *apiRouter) bookmarkExport(w http.ResponseWriter, r *http.Request) {
 	var exporter converter.Exporter
 	switch chi.URLParam(r, "format") {
-	case "epub":
+	case "epub", "kepub":
 		exp := converter.NewEPUBExporter(
 			api.srv.AbsoluteURL(r, "/"),
 			api.srv.TemplateVars(r),
 		)
+		if chi.URLParam(r, "format") == "kepub" {
+			exp = converter.NewKEPUBExporter(
+				api.srv.AbsoluteURL(r, "/"),
+				api.srv.TemplateVars(r),
+			)
+		}
 		if collection, ok := r.Context().Value(ctxCollectionKey{}).(*bookmarks.Collection); ok {
 			exp.Collection = collection
 		}
@@ -176,8 +193,21 @@ func (api *apiRouter) bookmarkExport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A bookmark's e-book is a document KOReader can synchronize.
+	// Its digest is computed while it's sent.
+	var kw *kosyncWriter
+	if _, ok := exporter.(converter.EPUBExporter); ok && r.Context().Value(ctxBookmarkKey{}) != nil {
+		kw = &kosyncWriter{w, koreader.NewPartialMD5()}
+		w = kw
+	}
+
 	if err := exporter.Export(context.Background(), w, r, items); err != nil {
 		api.srv.Error(w, r, err)
+		return
+	}
+
+	if kw != nil {
+		api.linkKosyncDocuments(*kw, items[0])
 	}
 }
 
@@ -208,6 +238,16 @@ func (api *apiRouter) bookmarkCreate(w http.ResponseWriter, r *http.Request) {
 		WithType("text/html").
 		Write(w)
 
+	if f.merged {
+		api.srv.TextMessage(w, r, http.StatusOK, "Link already saved")
+		return
+	}
+
+	for _, x := range f.duplicates {
+		server.NewLink(api.srv.AbsoluteURL(r, ".", x.UID).String()).
+			WithRel("duplicate").
+			Write(w)
+	}
 	api.srv.TextMessage(w, r, http.StatusAccepted, "Link submited")
 }
 
@@ -275,10 +315,10 @@ func (api *apiRouter) bookmarkUpdate(w http.ResponseWriter, r *http.Request) {
 	api.srv.Render(w, r, http.StatusOK, updated)
 }
 
-// bookmarkDelete deletes a bookmark.
+// bookmarkDelete moves a bookmark to the trash.
 func (api *apiRouter) bookmarkDelete(w http.ResponseWriter, r *http.Request) {
 	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
-	if err := b.Delete(); err != nil {
+	if err := b.Trash(); err != nil {
 		api.srv.Error(w, r, err)
 		return
 	}
@@ -318,7 +358,9 @@ func (api *apiRouter) bookmarkResource(w http.ResponseWriter, r *http.Request) {
 	*r2.URL = *r.URL
 	r2.URL.Path = p
 
-	fs := zipfs.HTTPZipFile(b.GetFilePath())
+	fs := zipfs.HTTPZipOpener(func() (zipfs.File, error) {
+		return bookmarks.Storage().Open(b.GetFileName())
+	})
 	fs.ServeHTTP(w, r2)
 }
 
@@ -383,7 +425,7 @@ func (api *apiRouter) labelUpdate(w http.ResponseWriter, r *http.Request) {
 func (api *apiRouter) labelDelete(w http.ResponseWriter, r *http.Request) {
 	label := r.Context().Value(ctxLabelKey{}).(string)
 
-	ids, err := bookmarks.Bookmarks.RenameLabel(auth.GetRequestUser(r), label, "")
+	ids, err := bookmarks.Trash.TrashLabel(auth.GetRequestUser(r), label)
 	if err != nil {
 		api.srv.Error(w, r, err)
 		return
@@ -453,8 +495,7 @@ func (api *apiRouter) annotationUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	annotation := b.Annotations.Get(id)
-	annotation.Color = f.Get("color").String()
+	f.update(b.Annotations.Get(id))
 	update := map[string]interface{}{
 		"annotations": b.Annotations,
 	}
@@ -547,6 +588,8 @@ func (api *apiRouter) withBookmarkFilters(next http.Handler) http.Handler {
 			filters.setType("photo")
 		case "videos":
 			filters.setType("video")
+		case "documents":
+			filters.setType("pdf")
 		}
 
 		next.ServeHTTP(w, r.WithContext(filters.saveContext(r.Context())))
@@ -685,9 +728,14 @@ func (api *apiRouter) withBookmarkList(next http.Handler) http.Handler {
 		filterForm := newContextFilterForm(r.Context(), api.srv.Locale(r))
 		forms.BindURL(filterForm, r)
 
+		var filters bookmarks.Filters
 		if filterForm.IsValid() {
-			filters := bookmarks.NewFiltersFromForm(filterForm)
+			filters = bookmarks.NewFiltersFromForm(filterForm)
 			filters.UpdateForm(filterForm)
+			if err := filters.LoadDuplicates(auth.GetRequestUser(r).ID); err != nil {
+				api.srv.Error(w, r, err)
+				return
+			}
 			ds = filters.ToSelectDataSet(ds)
 		}
 
@@ -740,6 +788,19 @@ func (api *apiRouter) withBookmarkList(next http.Handler) http.Handler {
 			return
 		}
 
+		// Text snippets for a search
+		if filters.Search != "" {
+			res.search = filters.Search
+			ids := make([]int, len(res.items))
+			for i, x := range res.items {
+				ids[i] = x.ID
+			}
+			if res.snippets, err = filters.Snippets(ids); err != nil {
+				api.srv.Error(w, r, err)
+				return
+			}
+		}
+
 		res.Pagination = api.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset())
 
 		ctx := filterForm.saveContext(r.Context())
@@ -908,6 +969,8 @@ func (api *apiRouter) withShareEmail(next http.Handler) http.Handler {
 // bookmarkList is a paginated list of BookmarkItem instances.
 type bookmarkList struct {
 	items      []*bookmarks.Bookmark
+	search     string
+	snippets   map[int]string
 	Pagination server.Pagination
 	Items      []bookmarkItem
 }
@@ -934,43 +997,48 @@ type bookmarkCount struct {
 type bookmarkItem struct {
 	*bookmarks.Bookmark `json:"-"`
 
-	ID            string                        `json:"id"`
-	Href          string                        `json:"href"`
-	Created       time.Time                     `json:"created"`
-	Updated       time.Time                     `json:"updated"`
-	State         bookmarks.BookmarkState       `json:"state"`
-	Loaded        bool                          `json:"loaded"`
-	URL           string                        `json:"url"`
-	Title         string                        `json:"title"`
-	SiteName      string                        `json:"site_name"`
-	Site          string                        `json:"site"`
-	Published     *time.Time                    `json:"published,omitempty"`
-	Authors       []string                      `json:"authors"`
-	Lang          string                        `json:"lang"`
-	TextDirection string                        `json:"text_direction"`
-	DocumentType  string                        `json:"document_type"`
-	Type          string                        `json:"type"`
-	HasArticle    bool                          `json:"has_article"`
-	Description   string                        `json:"description"`
-	IsDeleted     bool                          `json:"is_deleted"`
-	IsMarked      bool                          `json:"is_marked"`
-	IsArchived    bool                          `json:"is_archived"`
-	Labels        []string                      `json:"labels"`
-	ReadProgress  int                           `json:"read_progress"`
-	ReadAnchor    string                        `json:"read_anchor,omitempty"`
-	Annotations   bookmarks.BookmarkAnnotations `json:"-"`
-	Resources     map[string]*bookmarkFile      `json:"resources"`
-	Embed         string                        `json:"embed,omitempty"`
-	EmbedHostname string                        `json:"embed_domain,omitempty"`
-	Errors        []string                      `json:"errors,omitempty"`
-	Links         bookmarks.BookmarkLinks       `json:"links,omitempty"`
-	WordCount     int                           `json:"word_count,omitempty"`
-	ReadingTime   int                           `json:"reading_time,omitempty"`
+	ID              string                        `json:"id"`
+	Href            string                        `json:"href"`
+	Created         time.Time                     `json:"created"`
+	Updated         time.Time                     `json:"updated"`
+	State           bookmarks.BookmarkState       `json:"state"`
+	Loaded          bool                          `json:"loaded"`
+	URL             string                        `json:"url"`
+	Title           string                        `json:"title"`
+	SiteName        string                        `json:"site_name"`
+	Site            string                        `json:"site"`
+	Published       *time.Time                    `json:"published,omitempty"`
+	Authors         []string                      `json:"authors"`
+	Lang            string                        `json:"lang"`
+	TextDirection   string                        `json:"text_direction"`
+	DocumentType    string                        `json:"document_type"`
+	Type            string                        `json:"type"`
+	HasArticle      bool                          `json:"has_article"`
+	Description     string                        `json:"description"`
+	Snippet         string                        `json:"snippet,omitempty"`
+	SearchQuery     string                        `json:"-"`
+	IsDeleted       bool                          `json:"is_deleted"`
+	IsMarked        bool                          `json:"is_marked"`
+	IsArchived      bool                          `json:"is_archived"`
+	Labels          []string                      `json:"labels"`
+	ReadProgress    int                           `json:"read_progress"`
+	ReadAnchor      string                        `json:"read_anchor,omitempty"`
+	Annotations     bookmarks.BookmarkAnnotations `json:"-"`
+	Resources       map[string]*bookmarkFile      `json:"resources"`
+	Embed           string                        `json:"embed,omitempty"`
+	EmbedHostname   string                        `json:"embed_domain,omitempty"`
+	Errors          []string                      `json:"errors,omitempty"`
+	Links           bookmarks.BookmarkLinks       `json:"links,omitempty"`
+	WordCount       int                           `json:"word_count,omitempty"`
+	ReadingTime     int                           `json:"reading_time,omitempty"`
+	RefreshInterval int                           `json:"refresh_interval"`
+	Refreshed       *time.Time                    `json:"refreshed,omitempty"`
 
 	baseURL            *url.URL
 	mediaURL           *url.URL
 	annotationTag      string
 	annotationCallback func(id string, n *html.Node, index int, color string)
+	highlight          []searchstring.SearchTerm
 }
 
 // bookmarkFile is a file attached to a bookmark. If the file is
@@ -989,34 +1057,36 @@ func newBookmarkItem(
 	base string,
 ) bookmarkItem {
 	res := bookmarkItem{
-		Bookmark:      b,
-		ID:            b.UID,
-		Href:          s.AbsoluteURL(r, base, b.UID).String(),
-		Created:       b.Created,
-		Updated:       b.Updated,
-		State:         b.State,
-		Loaded:        b.State != bookmarks.StateLoading,
-		URL:           b.URL,
-		Title:         b.Title,
-		SiteName:      b.SiteName,
-		Site:          b.Site,
-		Published:     b.Published,
-		Authors:       b.Authors,
-		Lang:          b.Lang,
-		TextDirection: b.TextDirection,
-		DocumentType:  b.DocumentType,
-		Description:   b.Description,
-		IsDeleted:     tasks.DeleteBookmarkTask.IsRunning(b.ID),
-		IsMarked:      b.IsMarked,
-		IsArchived:    b.IsArchived,
-		ReadProgress:  b.ReadProgress,
-		ReadAnchor:    b.ReadAnchor,
-		WordCount:     b.WordCount,
-		ReadingTime:   b.ReadingTime(),
-		Labels:        make([]string, 0),
-		Annotations:   b.Annotations,
-		Resources:     make(map[string]*bookmarkFile),
-		Links:         b.Links,
+		Bookmark:        b,
+		ID:              b.UID,
+		Href:            s.AbsoluteURL(r, base, b.UID).String(),
+		Created:         b.Created,
+		Updated:         b.Updated,
+		State:           b.State,
+		Loaded:          b.State != bookmarks.StateLoading,
+		URL:             b.URL,
+		Title:           b.Title,
+		SiteName:        b.SiteName,
+		Site:            b.Site,
+		Published:       b.Published,
+		Authors:         b.Authors,
+		Lang:            b.Lang,
+		TextDirection:   b.TextDirection,
+		DocumentType:    b.DocumentType,
+		Description:     b.Description,
+		IsDeleted:       tasks.DeleteBookmarkTask.IsRunning(b.ID),
+		IsMarked:        b.IsMarked,
+		IsArchived:      b.IsArchived,
+		ReadProgress:    b.ReadProgress,
+		ReadAnchor:      b.ReadAnchor,
+		WordCount:       b.WordCount,
+		ReadingTime:     b.ReadingTime(),
+		RefreshInterval: b.RefreshInterval,
+		Refreshed:       b.Refreshed,
+		Labels:          make([]string, 0),
+		Annotations:     b.Annotations,
+		Resources:       make(map[string]*bookmarkFile),
+		Links:           b.Links,
 
 		baseURL:       s.AbsoluteURL(r, "/"),
 		annotationTag: "rd-annotation",
@@ -1074,6 +1144,11 @@ func newBookmarkItem(
 			Src: s.AbsoluteURL(r, base, b.UID, "x", v.Name).String(),
 		}
 	}
+	if v, ok := b.Files["document"]; ok {
+		res.Resources["document"] = &bookmarkFile{
+			Src: s.AbsoluteURL(r, base, b.UID, "x", v.Name).String(),
+		}
+	}
 	if v, ok := b.Files["log"]; ok {
 		res.Resources["log"] = &bookmarkFile{
 			Src: s.AbsoluteURL(r, base, b.UID, "x", v.Name).String(),
@@ -1101,6 +1176,10 @@ func (bi bookmarkItem) getArticle() (*strings.Reader, error) {
 	)
 	// Set annotation tag and callback
 	ctx = converter.WithAnnotationTag(ctx, bi.annotationTag, bi.annotationCallback)
+	// Set the search terms to highlight
+	if len(bi.highlight) > 0 {
+		ctx = converter.WithHighlight(ctx, bi.highlight)
+	}
 
 	// Get article from converter
 	return converter.HTMLConverter{}.GetArticle(
@@ -1109,6 +1188,19 @@ func (bi bookmarkItem) getArticle() (*strings.Reader, error) {
 	)
 }
 
+// setHighlight sets the search terms to highlight in the article,
+// from the "q" query parameter. Only the free text terms are kept.
+func (bi *bookmarkItem) setHighlight(r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		return
+	}
+
+	bi.highlight = slices.DeleteFunc(searchstring.ParseQuery(q).Words(), func(t searchstring.SearchTerm) bool {
+		return t.Field != ""
+	})
+}
+
 // setEmbed sets the Embed and EmbedHostname item properties.
 // The original embed value must be an iframe. We extract the "src"
 // URL and store its hostname that we can later use in the CSP policy.
@@ -1221,6 +1313,7 @@ type annotationItem struct {
 	ID               string    `json:"id"`
 	Href             string    `json:"href"`
 	Text             string    `json:"text"`
+	Note             string    `json:"note"`
 	Created          time.Time `json:"created"`
 	Color            string    `json:"color"`
 	BookmarkID       string    `json:"bookmark_id"`
@@ -1240,6 +1333,7 @@ func newAnnotationItem(
 		Href: s.AbsoluteURL(r, "/api/bookmarks", a.Bookmark.UID, "annotations", a.ID).
 			String(),
 		Text:             a.Text,
+		Note:             a.Note,
 		Created:          time.Time(a.Created),
 		Color:            a.Color,
 		BookmarkID:       a.Bookmark.UID,
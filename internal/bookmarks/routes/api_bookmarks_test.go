@@ -5,10 +5,14 @@
 package routes_test
 
 import (
+	"archive/zip"
+	"bytes"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
+	"codeberg.org/readeck/readeck/internal/bookmarks"
 	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
 )
 
@@ -44,3 +48,219 @@ func TestBookmarkAPIShare(t *testing.T) {
 		},
 	)
 }
+
+func TestBookmarkAPIAnnotationNotes(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	// The fixture archive contains an article
+	b := app.Users["user"].Bookmarks[0]
+	b.Files = bookmarks.BookmarkFiles{"article": {Name: "index.html"}}
+	require.NoError(t, b.Save())
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Method: "POST",
+			Target: "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}/annotations",
+			JSON: map[string]any{
+				"start_selector": "section/div[1]",
+				"start_offset":   8,
+				"end_selector":   "section/div[1]",
+				"end_offset":     12,
+				"color":          "red",
+				"note":           " first note ",
+			},
+			ExpectStatus: 201,
+			Assert: func(t *testing.T, r *Response) {
+				require.Equal(t, "2003", r.JSON.(map[string]any)["text"])
+				require.Equal(t, "first note", r.JSON.(map[string]any)["note"])
+			},
+		},
+		RequestTest{
+			Method: "PATCH",
+			Target: "{{(index .History 0).Redirect}}",
+			JSON: map[string]any{
+				"note": "Remember this date",
+			},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				annotations := r.JSON.(map[string]any)["annotations"].([]any)
+				require.Len(t, annotations, 1)
+				require.Equal(t, "red", annotations[0].(map[string]any)["color"])
+				require.Equal(t, "Remember this date", annotations[0].(map[string]any)["note"])
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/annotations",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				items := r.JSON.([]any)
+				require.Len(t, items, 1)
+				require.Equal(t, "Remember this date", items[0].(map[string]any)["note"])
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks?search=note:remember",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Len(t, r.JSON.([]any), 1)
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks?search=note:forgotten",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Len(t, r.JSON.([]any), 0)
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}/article.md",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Regexp(t, `==2003==\[\^\w+\]`, string(r.Body))
+				require.Regexp(t, `\n\[\^\w+\]: Remember this date\n$`, string(r.Body))
+			},
+		},
+	)
+}
+
+func TestBookmarkAPISearchSnippets(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	b := app.Users["user"].Bookmarks[0]
+	b.Files = bookmarks.BookmarkFiles{"article": {Name: "index.html"}}
+	b.Title = "Go"
+	b.Text = "Go is a statically typed, compiled high-level programming language. " +
+		"It is syntactically similar to C, but also has memory safety, garbage collection, " +
+		"structural typing, and CSP-style concurrency & channels. It is often referred to " +
+		"as Golang because of its former domain name."
+	require.NoError(t, b.Save())
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Target:       "/api/bookmarks?search=concurrency",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				items := r.JSON.([]any)
+				require.Len(t, items, 1)
+				snippet := items[0].(map[string]any)["snippet"].(string)
+				require.Contains(t, snippet, "CSP-style <mark>concurrency</mark> &amp; channels.")
+			},
+		},
+		RequestTest{
+			// The term only matches the title, there's no snippet
+			Target:       "/api/bookmarks?search=title:go",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				items := r.JSON.([]any)
+				require.Len(t, items, 1)
+				require.NotContains(t, items[0].(map[string]any), "snippet")
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				for _, x := range r.JSON.([]any) {
+					require.NotContains(t, x.(map[string]any), "snippet")
+				}
+			},
+		},
+		RequestTest{
+			Target:         "/bookmarks?search=concurrency",
+			ExpectStatus:   200,
+			ExpectContains: `CSP-style <mark>concurrency</mark> &amp; channels.`,
+			Assert: func(t *testing.T, r *Response) {
+				require.Contains(t, string(r.Body), "/bookmarks/"+b.UID+"?q=concurrency")
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}/article?q=agent%20programm*%20-uses%20title:other",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				body := string(r.Body)
+				require.Contains(t, body, "For the 2003 <mark>agent</mark>-based <mark>programming</mark> language")
+				require.Contains(t, body, "For other uses, see")
+				require.NotContains(t, body, "<mark>other</mark>")
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}/article",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.NotContains(t, string(r.Body), "<mark>")
+			},
+		},
+		RequestTest{
+			Target:         "/bookmarks/{{(index .User.Bookmarks 0).UID}}?q=AGENT",
+			ExpectStatus:   200,
+			ExpectContains: "For the 2003 <mark>agent</mark>-based",
+		},
+	)
+}
+
+func TestBookmarkAPIExportKepub(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	b := app.Users["user"].Bookmarks[0]
+	b.Title = "Go. The programming language"
+	b.Files = bookmarks.BookmarkFiles{"article": {Name: "index.html"}}
+	require.NoError(t, b.Save())
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Target:       "/api/bookmarks/" + b.UID + "/article.kepub",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Equal(t, "application/kepub+zip", r.Header.Get("Content-Type"))
+				require.Regexp(t, `filename=".+\.kepub\.epub"$`, r.Header.Get("Content-Disposition"))
+
+				z, err := zip.NewReader(bytes.NewReader(r.Body), int64(len(r.Body)))
+				require.NoError(t, err)
+				fp, err := z.Open("OEBPS/" + b.UID + ".html")
+				require.NoError(t, err)
+				defer fp.Close() //nolint:errcheck
+				chapter, err := io.ReadAll(fp)
+				require.NoError(t, err)
+
+				require.Contains(t, string(chapter), `<?xml version="1.0" encoding="UTF-8"?>`)
+				require.Contains(t, string(chapter), `<div id="book-columns"><div id="book-inner">`)
+				require.Contains(t, string(chapter),
+					`<h1 class="title"><span class="koboSpan" id="kobo.1.1">Go. </span>`+
+						`<span class="koboSpan" id="kobo.1.2">The programming language</span></h1>`,
+				)
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/export.kepub",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Equal(t, "application/kepub+zip", r.Header.Get("Content-Type"))
+			},
+		},
+		RequestTest{
+			Target:         "/opds/bookmarks/all",
+			ExpectStatus:   200,
+			ExpectContains: "/api/bookmarks/" + b.UID + "/article.kepub",
+		},
+	)
+}
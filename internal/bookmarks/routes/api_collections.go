@@ -87,7 +87,7 @@ func (api *apiRouter) collectionUpdate(w http.ResponseWriter, r *http.Request) {
 
 func (api *apiRouter) collectionDelete(w http.ResponseWriter, r *http.Request) {
 	c := r.Context().Value(ctxCollectionKey{}).(*bookmarks.Collection)
-	if err := c.Delete(); err != nil {
+	if err := c.Trash(); err != nil {
 		api.srv.Error(w, r, err)
 		return
 	}
@@ -109,6 +109,7 @@ func (api *apiRouter) withColletionList(next http.Handler) http.Handler {
 			Select(
 				"c.id", "c.uid", "c.user_id", "c.created", "c.updated",
 				"c.name", "c.is_pinned", "c.filters",
+				"c.digest_schedule", "c.digest_sent",
 			).
 			Where(
 				goqu.C("user_id").Table("c").Eq(auth.GetRequestUser(r).ID),
@@ -184,6 +185,10 @@ type collectionItem struct {
 	IsPinned  bool      `json:"is_pinned"`
 	IsDeleted bool      `json:"is_deleted"`
 
+	// Email digest
+	DigestSchedule string     `json:"digest_schedule"`
+	DigestSent     *time.Time `json:"digest_sent"`
+
 	// Filters
 	Search     string        `json:"search"`
 	Title      string        `json:"title"`
@@ -212,6 +217,10 @@ func newCollectionItem(s *server.Server, r *http.Request, c *bookmarks.Collectio
 		IsPinned:   c.IsPinned,
 		IsDeleted:  tasks.DeleteCollectionTask.IsRunning(c.ID),
 
+		// Email digest
+		DigestSchedule: c.DigestSchedule,
+		DigestSent:     c.DigestSent,
+
 		// Filters
 		Search:     c.Filters.Search,
 		Title:      c.Filters.Title,
@@ -46,6 +46,12 @@ func TestCollectionAPI(t *testing.T) {
 						"value": false,
 						"errors": null
 					},
+					"digest_schedule": {
+						"is_null": true,
+						"is_bound": false,
+						"value": "",
+						"errors": null
+					},
 					"has_errors": {
 						"is_null": true,
 						"is_bound": false,
@@ -178,6 +184,8 @@ func TestCollectionAPI(t *testing.T) {
 				"name": "test-collection",
 				"is_pinned": false,
 				"is_deleted": false,
+				"digest_schedule": "",
+				"digest_sent": null,
 				"search":"",
 				"title":"",
 				"author":"",
@@ -222,6 +230,8 @@ func TestCollectionAPI(t *testing.T) {
 					"name": "new name",
 					"is_pinned": true,
 					"is_deleted": false,
+					"digest_schedule": "",
+					"digest_sent": null,
 					"search":"",
 					"title":"",
 					"author":"",
@@ -268,6 +278,8 @@ func TestCollectionAPI(t *testing.T) {
 				"name": "new name",
 				"is_pinned": true,
 				"is_deleted": false,
+				"digest_schedule": "",
+				"digest_sent": null,
 				"search":"",
 				"title":"",
 				"author":"",
@@ -313,6 +325,8 @@ func TestCollectionAPI(t *testing.T) {
 				"name": "new name",
 				"is_pinned": true,
 				"is_deleted": false,
+				"digest_schedule": "",
+				"digest_sent": null,
 				"search":"",
 				"title":"",
 				"author":"",
@@ -363,6 +377,8 @@ func TestCollectionAPI(t *testing.T) {
 				"name": "new name",
 				"is_pinned": true,
 				"is_deleted": false,
+				"digest_schedule": "",
+				"digest_sent": null,
 				"search":"some search",
 				"title":"tt",
 				"author":"",
@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+// bookmarkRef is a short bookmark description.
+type bookmarkRef struct {
+	ID           string    `json:"id"`
+	Href         string    `json:"href"`
+	URL          string    `json:"url"`
+	Title        string    `json:"title"`
+	Created      time.Time `json:"created"`
+	IsArchived   bool      `json:"is_archived"`
+	IsMarked     bool      `json:"is_marked"`
+	ReadProgress int       `json:"read_progress"`
+}
+
+type lookupResult struct {
+	URL       string        `json:"url"`
+	Bookmarks []bookmarkRef `json:"bookmarks"`
+}
+
+type duplicateGroup struct {
+	CanonicalURL string        `json:"canonical_url"`
+	Bookmarks    []bookmarkRef `json:"bookmarks"`
+}
+
+func (api *apiRouter) newBookmarkRef(r *http.Request, b *bookmarks.Bookmark) bookmarkRef {
+	return bookmarkRef{
+		ID:           b.UID,
+		Href:         api.srv.AbsoluteURL(r, "/api/bookmarks", b.UID).String(),
+		URL:          b.URL,
+		Title:        b.Title,
+		Created:      b.Created,
+		IsArchived:   b.IsArchived,
+		IsMarked:     b.IsMarked,
+		ReadProgress: b.ReadProgress,
+	}
+}
+
+// bookmarkLookup tells, for a list of URLs, which ones are already saved.
+func (api *apiRouter) bookmarkLookup(w http.ResponseWriter, r *http.Request) {
+	f := newLookupForm(api.srv.Locale(r))
+	if r.Method == http.MethodGet {
+		forms.BindURL(f, r)
+	} else {
+		forms.Bind(f, r)
+	}
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	found, err := f.lookup(auth.GetRequestUser(r).ID)
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	res := []lookupResult{}
+	for _, u := range f.urls() {
+		item := lookupResult{URL: u, Bookmarks: []bookmarkRef{}}
+		for _, b := range found[u] {
+			item.Bookmarks = append(item.Bookmarks, api.newBookmarkRef(r, b))
+		}
+		res = append(res, item)
+	}
+
+	api.srv.Render(w, r, http.StatusOK, res)
+}
+
+// duplicateList returns the groups of bookmarks saved with the same URL.
+func (api *apiRouter) duplicateList(w http.ResponseWriter, r *http.Request) {
+	groups, err := bookmarks.Bookmarks.GetDuplicateGroups(auth.GetRequestUser(r).ID)
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	res := []duplicateGroup{}
+	for _, g := range groups {
+		var items []*bookmarks.Bookmark
+		if err := bookmarks.Bookmarks.Query().
+			Where(goqu.I("b.id").In(g.IDs)).
+			Order(goqu.I("b.created").Asc(), goqu.I("b.id").Asc()).
+			ScanStructs(&items); err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		item := duplicateGroup{CanonicalURL: g.CanonicalURL, Bookmarks: []bookmarkRef{}}
+		for _, b := range items {
+			item.Bookmarks = append(item.Bookmarks, api.newBookmarkRef(r, b))
+		}
+		res = append(res, item)
+	}
+
+	api.srv.Render(w, r, http.StatusOK, res)
+}
+
+// duplicateMerge starts the task that merges the user's duplicates.
+func (api *apiRouter) duplicateMerge(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetRequestUser(r).ID
+	if err := tasks.MergeDuplicatesTask.Run(userID, userID); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.TextMessage(w, r, http.StatusAccepted, "Merging duplicates")
+}
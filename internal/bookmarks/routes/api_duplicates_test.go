@@ -0,0 +1,234 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes_test
+
+import (
+	"net/url"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/internal/db/types"
+
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestDuplicates(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	u := app.Users["user"]
+
+	getBookmark := func(t *testing.T, uid string) *bookmarks.Bookmark {
+		res, err := bookmarks.Bookmarks.GetOne(goqu.I("b.uid").Eq(uid))
+		require.NoError(t, err)
+		return res
+	}
+	bookmarkID := func(r *Response) string {
+		return path.Base(r.Header.Get("Location"))
+	}
+
+	t.Run("create", func(t *testing.T) {
+		var first string
+		RunRequestSequence(t, client, "user",
+			RequestTest{
+				Method: "POST",
+				Target: "/api/bookmarks",
+				JSON: map[string]any{
+					"url":    "https://example.org/dup/article/?utm_source=rss&utm_medium=feed&id=3#top",
+					"labels": []string{"first"},
+				},
+				ExpectStatus: 202,
+				Assert: func(t *testing.T, r *Response) {
+					first = bookmarkID(r)
+					b := getBookmark(t, first)
+					require.Equal(t, "https://example.org/dup/article/?id=3", b.URL)
+					require.Equal(t, "example.org/dup/article?id=3", b.CanonicalURL)
+					for _, l := range r.Header.Values("Link") {
+						require.NotContains(t, l, `rel="duplicate"`)
+					}
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/bookmarks",
+				JSON: map[string]any{
+					"url": "https://www.google.com/amp/s/www.example.org/dup/article/amp?id=3",
+				},
+				ExpectStatus: 202,
+				Assert: func(t *testing.T, r *Response) {
+					require.NotEqual(t, first, bookmarkID(r))
+					found := false
+					for _, l := range r.Header.Values("Link") {
+						found = found || strings.HasSuffix(l, "/api/bookmarks/"+first+`>; rel="duplicate"`)
+					}
+					require.True(t, found)
+				},
+			},
+		)
+
+		configs.Config.Bookmarks.MergeDuplicates = true
+		defer func() {
+			configs.Config.Bookmarks.MergeDuplicates = false
+		}()
+
+		RunRequestSequence(t, client, "user",
+			RequestTest{
+				Method: "POST",
+				Target: "/api/bookmarks",
+				JSON: map[string]any{
+					"url":    "http://example.org/dup/article?id=3&fbclid=abc",
+					"labels": []string{"second"},
+				},
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					require.Equal(t, first, bookmarkID(r))
+					require.EqualValues(t, []string{"first", "second"}, getBookmark(t, first).Labels)
+				},
+			},
+		)
+	})
+
+	t.Run("views", func(t *testing.T) {
+		RunRequestSequence(t, client, "user",
+			RequestTest{Target: "/bookmarks", ExpectStatus: 200},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/bookmarks",
+				Form:         url.Values{"url": {"https://example.org/dup/article?id=3"}},
+				ExpectStatus: 303,
+			},
+			RequestTest{
+				Target:         "/bookmarks",
+				ExpectStatus:   200,
+				ExpectContains: "You already saved this link.",
+			},
+		)
+	})
+
+	t.Run("lookup", func(t *testing.T) {
+		RunRequestSequence(t, client, "user",
+			RequestTest{
+				Method: "POST",
+				Target: "/api/bookmarks/lookup",
+				JSON: map[string]any{
+					"url": []string{
+						"https://example.org/dup/article?utm_campaign=x&id=3",
+						"https://example.org/not-saved",
+						"not a url",
+					},
+				},
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, "length", 3)
+					r.AssertJQ(t, ".[0].url", "https://example.org/dup/article?utm_campaign=x&id=3")
+					r.AssertJQ(t, ".[0].bookmarks | length", 3)
+					r.AssertJQ(t, ".[1].bookmarks", []any{})
+					r.AssertJQ(t, ".[2].bookmarks", []any{})
+				},
+			},
+			RequestTest{
+				Target:       "/api/bookmarks/lookup?url=" + u.Bookmarks[0].URL,
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, ".[0].bookmarks[0].id", u.Bookmarks[0].UID)
+				},
+			},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/api/bookmarks/lookup",
+				JSON:         map[string]any{},
+				ExpectStatus: 422,
+			},
+		)
+
+		// Other users don't see the bookmarks
+		RunRequestSequence(t, client, "staff",
+			RequestTest{
+				Target:       "/api/bookmarks/lookup?url=https://example.org/dup/article?id=3",
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, ".[0].bookmarks", []any{})
+				},
+			},
+		)
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		var ids []int
+		require.NoError(t, bookmarks.Bookmarks.Query().
+			Select(goqu.I("b.id")).
+			Where(goqu.I("b.canonical_url").Eq("example.org/dup/article?id=3")).
+			Order(goqu.I("b.id").Asc()).
+			ScanVals(&ids))
+		require.Len(t, ids, 3)
+
+		// Some state on the duplicate, to be merged into the first bookmark.
+		dup, err := bookmarks.Bookmarks.GetOne(goqu.I("b.id").Eq(ids[1]))
+		require.NoError(t, err)
+		require.NoError(t, dup.Update(map[string]any{
+			"labels":        types.Strings{"amp"},
+			"is_marked":     true,
+			"read_progress": 40,
+			"annotations": bookmarks.BookmarkAnnotations{
+				{ID: "note1", Text: "some text", Color: "yellow"},
+			},
+		}))
+
+		RunRequestSequence(t, client, "user",
+			RequestTest{
+				Target:       "/api/bookmarks/duplicates",
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, "length", 1)
+					r.AssertJQ(t, ".[0].canonical_url", "example.org/dup/article?id=3")
+					r.AssertJQ(t, ".[0].bookmarks | length", 3)
+				},
+			},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/api/bookmarks/duplicates/merge",
+				JSON:         true,
+				ExpectStatus: 202,
+				Assert: func(t *testing.T, _ *Response) {
+					require.Len(t, Events().Records("task"), 1)
+
+					count, err := tasks.MergeDuplicates(u.User.ID)
+					require.NoError(t, err)
+					require.Equal(t, 2, count)
+
+					b, err := bookmarks.Bookmarks.GetOne(goqu.I("b.id").Eq(ids[0]))
+					require.NoError(t, err)
+					require.EqualValues(t, []string{"amp", "first", "second"}, b.Labels)
+					require.True(t, b.IsMarked)
+					require.Equal(t, 40, b.ReadProgress)
+					require.NotNil(t, b.Annotations.Get("note1"))
+
+					trashed, err := bookmarks.Bookmarks.Trashed().
+						Where(goqu.I("b.id").In(ids[1:])).
+						Count()
+					require.NoError(t, err)
+					require.Equal(t, int64(2), trashed)
+				},
+			},
+			RequestTest{
+				Target:       "/api/bookmarks/duplicates",
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, "length", 0)
+				},
+			},
+		)
+	})
+}
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+type (
+	ctxFeedListKey struct{}
+	ctxFeedKey     struct{}
+)
+
+func (api *apiRouter) feedList(w http.ResponseWriter, r *http.Request) {
+	fl := r.Context().Value(ctxFeedListKey{}).(feedList)
+
+	fl.Items = make([]feedItem, len(fl.items))
+	for i, item := range fl.items {
+		fl.Items[i] = newFeedItem(api.srv, r, item, ".")
+	}
+
+	api.srv.SendPaginationHeaders(w, r, fl.Pagination)
+	api.srv.Render(w, r, http.StatusOK, fl.Items)
+}
+
+func (api *apiRouter) feedInfo(w http.ResponseWriter, r *http.Request) {
+	f := r.Context().Value(ctxFeedKey{}).(*bookmarks.Feed)
+	item := newFeedItem(api.srv, r, f, "./..")
+
+	api.srv.Render(w, r, http.StatusOK, item)
+}
+
+func (api *apiRouter) feedCreate(w http.ResponseWriter, r *http.Request) {
+	f := newFeedForm(api.srv.Locale(r), auth.GetRequestUser(r).ID)
+
+	forms.Bind(f, r)
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	feed, err := f.createFeed()
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", api.srv.AbsoluteURL(r, ".", feed.UID).String())
+	api.srv.TextMessage(w, r, http.StatusCreated, "Feed created")
+}
+
+func (api *apiRouter) feedUpdate(w http.ResponseWriter, r *http.Request) {
+	feed := r.Context().Value(ctxFeedKey{}).(*bookmarks.Feed)
+
+	f := newFeedForm(api.srv.Locale(r), auth.GetRequestUser(r).ID)
+	f.setFeed(feed)
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	updated, err := f.updateFeed(feed)
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Render(w, r, http.StatusOK, updated)
+}
+
+func (api *apiRouter) feedRefresh(w http.ResponseWriter, r *http.Request) {
+	feed := r.Context().Value(ctxFeedKey{}).(*bookmarks.Feed)
+
+	if err := tasks.FetchFeedTask.Run(feed.ID, feed.ID); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.TextMessage(w, r, http.StatusAccepted, "Feed refresh started")
+}
+
+func (api *apiRouter) feedDelete(w http.ResponseWriter, r *http.Request) {
+	feed := r.Context().Value(ctxFeedKey{}).(*bookmarks.Feed)
+	if err := feed.Delete(); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Status(w, r, http.StatusNoContent)
+}
+
+func (api *apiRouter) withFeedList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := feedList{}
+
+		pf := api.srv.GetPageParams(r, 30)
+		if pf == nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ds := bookmarks.Feeds.Query().
+			Where(
+				goqu.C("user_id").Table("f").Eq(auth.GetRequestUser(r).ID),
+			)
+
+		ds = ds.Order(goqu.I("title").Asc()).
+			Limit(uint(pf.Limit())).
+			Offset(uint(pf.Offset()))
+
+		count, err := ds.ClearOrder().ClearLimit().ClearOffset().Count()
+		if err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		res.items = []*bookmarks.Feed{}
+		if err := ds.ScanStructs(&res.items); err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		res.Pagination = api.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset())
+
+		ctx := context.WithValue(r.Context(), ctxFeedListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *apiRouter) withFeed(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid := chi.URLParam(r, "uid")
+
+		f, err := bookmarks.Feeds.GetOne(
+			goqu.C("uid").Eq(uid),
+			goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+		)
+		if err != nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxFeedKey{}, f)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type feedList struct {
+	items      []*bookmarks.Feed
+	Pagination server.Pagination
+	Items      []feedItem
+}
+
+type feedItem struct {
+	*bookmarks.Feed `json:"-"`
+
+	ID           string     `json:"id"`
+	Href         string     `json:"href"`
+	Created      time.Time  `json:"created"`
+	Updated      time.Time  `json:"updated"`
+	LastFetched  *time.Time `json:"last_fetched"`
+	URL          string     `json:"url"`
+	Title        string     `json:"title"`
+	SiteURL      string     `json:"site_url"`
+	Labels       []string   `json:"labels"`
+	ArchiveAfter int        `json:"archive_after"`
+	LastError    string     `json:"last_error"`
+	IsFetching   bool       `json:"is_fetching"`
+}
+
+func newFeedItem(s *server.Server, r *http.Request, f *bookmarks.Feed, base string) feedItem {
+	res := feedItem{
+		Feed:         f,
+		ID:           f.UID,
+		Href:         s.AbsoluteURL(r, base, f.UID).String(),
+		Created:      f.Created,
+		Updated:      f.Updated,
+		LastFetched:  f.LastFetched,
+		URL:          f.URL,
+		Title:        f.Title,
+		SiteURL:      f.SiteURL,
+		Labels:       f.Labels,
+		ArchiveAfter: f.ArchiveAfter,
+		LastError:    f.LastError,
+		IsFetching:   tasks.FetchFeedTask.IsRunning(f.ID),
+	}
+	if res.Labels == nil {
+		res.Labels = []string{}
+	}
+
+	return res
+}
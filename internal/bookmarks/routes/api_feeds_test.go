@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes_test
+
+import (
+	"testing"
+
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestFeedAPI(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/bookmarks/feeds",
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+		RequestTest{
+			Method:       "POST",
+			Target:       "/api/bookmarks/feeds",
+			JSON:         map[string]interface{}{},
+			ExpectStatus: 422,
+			ExpectJSON: `{
+				"is_valid": false,
+				"errors": null,
+				"fields": {
+					"archive_after": {
+						"is_null": true,
+						"is_bound": false,
+						"value": 0,
+						"errors": null
+					},
+					"labels": {
+						"is_null": true,
+						"is_bound": false,
+						"value": null,
+						"errors": null
+					},
+					"title": {
+						"is_null": true,
+						"is_bound": false,
+						"value": "",
+						"errors": null
+					},
+					"url": {
+						"is_null": true,
+						"is_bound": false,
+						"value": "",
+						"errors": [
+							"field is required"
+						]
+					}
+				}
+			}`,
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/api/bookmarks/feeds",
+			JSON: map[string]interface{}{
+				"url":           "https://example.org/feed.xml",
+				"labels":        []string{"news", "tech, web"},
+				"archive_after": 7,
+			},
+			ExpectStatus:   201,
+			ExpectRedirect: "/api/bookmarks/feeds/.+",
+			ExpectJSON:     `{"status":201,"message":"Feed created"}`,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 0).Redirect }}",
+			ExpectStatus: 200,
+			ExpectJSON: `{
+				"id": "<<PRESENCE>>",
+				"href": "<<PRESENCE>>",
+				"created": "<<PRESENCE>>",
+				"updated": "<<PRESENCE>>",
+				"last_fetched": null,
+				"url": "https://example.org/feed.xml",
+				"title": "",
+				"site_url": "",
+				"labels": ["news", "tech", "web"],
+				"archive_after": 7,
+				"last_error": "",
+				"is_fetching": true
+			}`,
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/api/bookmarks/feeds",
+			JSON: map[string]interface{}{
+				"url": "https://example.org/feed.xml",
+			},
+			ExpectStatus: 422,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".fields.url.errors", []any{"you are already subscribed to this feed"})
+			},
+		},
+		RequestTest{
+			Method: "PATCH",
+			Target: "{{ (index .History 1).Path }}",
+			JSON: map[string]interface{}{
+				"title":         "Example",
+				"archive_after": 0,
+			},
+			ExpectStatus: 200,
+			ExpectJSON: `{
+				"id": "<<PRESENCE>>",
+				"title": "Example",
+				"archive_after": 0,
+				"updated": "<<PRESENCE>>"
+			}`,
+		},
+		RequestTest{
+			Method: "PATCH",
+			Target: "{{ (index .History 2).Path }}",
+			JSON: map[string]interface{}{
+				"archive_after": -1,
+			},
+			ExpectStatus: 422,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/bookmarks/feeds",
+			ExpectStatus: 200,
+			ExpectJSON: `[
+				{
+					"id": "<<PRESENCE>>",
+					"href": "<<PRESENCE>>",
+					"created": "<<PRESENCE>>",
+					"updated": "<<PRESENCE>>",
+					"last_fetched": null,
+					"url": "https://example.org/feed.xml",
+					"title": "Example",
+					"site_url": "",
+					"labels": ["news", "tech", "web"],
+					"archive_after": 0,
+					"last_error": "",
+					"is_fetching": true
+				}
+			]`,
+		},
+		RequestTest{
+			Method:       "POST",
+			Target:       "{{ (index .History 4).Path }}/refresh",
+			JSON:         true,
+			ExpectStatus: 202,
+		},
+		RequestTest{
+			Method:       "DELETE",
+			Target:       "{{ (index .History 5).Path }}",
+			JSON:         true,
+			ExpectStatus: 204,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 6).Path }}",
+			ExpectStatus: 404,
+		},
+	)
+
+	RunRequestSequence(t, client, "staff",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/bookmarks/feeds",
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+	)
+}
@@ -0,0 +1,253 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/converter"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+type (
+	ctxNewspaperListKey struct{}
+	ctxNewspaperKey     struct{}
+)
+
+func (api *apiRouter) newspaperList(w http.ResponseWriter, r *http.Request) {
+	nl := r.Context().Value(ctxNewspaperListKey{}).(newspaperList)
+
+	nl.Items = make([]newspaperItem, len(nl.items))
+	for i, item := range nl.items {
+		nl.Items[i] = newNewspaperItem(api.srv, r, item, ".")
+	}
+
+	api.srv.SendPaginationHeaders(w, r, nl.Pagination)
+	api.srv.Render(w, r, http.StatusOK, nl.Items)
+}
+
+func (api *apiRouter) newspaperInfo(w http.ResponseWriter, r *http.Request) {
+	n := r.Context().Value(ctxNewspaperKey{}).(*bookmarks.Newspaper)
+	item := newNewspaperItem(api.srv, r, n, "./..")
+
+	api.srv.Render(w, r, http.StatusOK, item)
+}
+
+func (api *apiRouter) newspaperCreate(w http.ResponseWriter, r *http.Request) {
+	f := newNewspaperForm(api.srv.Locale(r), auth.GetRequestUser(r).ID)
+
+	forms.Bind(f, r)
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	n, err := f.createNewspaper()
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", api.srv.AbsoluteURL(r, ".", n.UID).String())
+	api.srv.TextMessage(w, r, http.StatusCreated, "Newspaper created")
+}
+
+func (api *apiRouter) newspaperUpdate(w http.ResponseWriter, r *http.Request) {
+	n := r.Context().Value(ctxNewspaperKey{}).(*bookmarks.Newspaper)
+
+	f := newNewspaperForm(api.srv.Locale(r), auth.GetRequestUser(r).ID)
+	f.setNewspaper(n)
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	updated, err := f.updateNewspaper(n)
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Render(w, r, http.StatusOK, updated)
+}
+
+func (api *apiRouter) newspaperDeliver(w http.ResponseWriter, r *http.Request) {
+	n := r.Context().Value(ctxNewspaperKey{}).(*bookmarks.Newspaper)
+
+	if err := tasks.DeliverNewspaperTask.Run(n.ID, n.ID); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.TextMessage(w, r, http.StatusAccepted, "Newspaper delivery started")
+}
+
+func (api *apiRouter) newspaperDelete(w http.ResponseWriter, r *http.Request) {
+	n := r.Context().Value(ctxNewspaperKey{}).(*bookmarks.Newspaper)
+	if err := n.Delete(); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Status(w, r, http.StatusNoContent)
+}
+
+// newspaperIssue sends the EPUB file of a newspaper's latest issue.
+// Only the issues published in the OPDS catalog are available.
+func (api *apiRouter) newspaperIssue(w http.ResponseWriter, r *http.Request) {
+	n := r.Context().Value(ctxNewspaperKey{}).(*bookmarks.Newspaper)
+	if !n.PublishOPDS || n.IssueDate == nil {
+		api.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+
+	items, err := tasks.NewspaperLatestIssue(n)
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	exporter := converter.NewNewspaperExporter(
+		api.srv.AbsoluteURL(r, "/"),
+		api.srv.TemplateVars(r),
+		n.Title, n.IssueDate.In(n.Location()),
+	)
+	if err = exporter.Export(r.Context(), w, r, items); err != nil {
+		api.srv.Error(w, r, err)
+	}
+}
+
+func (api *apiRouter) withNewspaperList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := newspaperList{}
+
+		pf := api.srv.GetPageParams(r, 30)
+		if pf == nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ds := bookmarks.Newspapers.Query().
+			Where(
+				goqu.C("user_id").Table("n").Eq(auth.GetRequestUser(r).ID),
+			)
+
+		ds = ds.Order(goqu.I("title").Asc()).
+			Limit(uint(pf.Limit())).
+			Offset(uint(pf.Offset()))
+
+		count, err := ds.ClearOrder().ClearLimit().ClearOffset().Count()
+		if err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		res.items = []*bookmarks.Newspaper{}
+		if err := ds.ScanStructs(&res.items); err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		res.Pagination = api.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset())
+
+		ctx := context.WithValue(r.Context(), ctxNewspaperListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *apiRouter) withNewspaper(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid := chi.URLParam(r, "uid")
+
+		n, err := bookmarks.Newspapers.GetOne(
+			goqu.C("uid").Eq(uid),
+			goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+		)
+		if err != nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxNewspaperKey{}, n)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type newspaperList struct {
+	items      []*bookmarks.Newspaper
+	Pagination server.Pagination
+	Items      []newspaperItem
+}
+
+type newspaperItem struct {
+	*bookmarks.Newspaper `json:"-"`
+
+	ID             string     `json:"id"`
+	Href           string     `json:"href"`
+	Created        time.Time  `json:"created"`
+	Updated        time.Time  `json:"updated"`
+	Title          string     `json:"title"`
+	Collection     string     `json:"collection"`
+	CollectionName string     `json:"collection_name"`
+	Schedule       string     `json:"schedule"`
+	Weekday        int        `json:"weekday"`
+	DeliveryTime   string     `json:"delivery_time"`
+	Timezone       string     `json:"timezone"`
+	MaxItems       int        `json:"max_items"`
+	SendEmail      bool       `json:"send_email"`
+	PublishOPDS    bool       `json:"publish_opds"`
+	AfterDelivery  string     `json:"after_delivery"`
+	LastRun        *time.Time `json:"last_run"`
+	IssueDate      *time.Time `json:"issue_date"`
+	IssueItems     int        `json:"issue_items"`
+	IssueURL       string     `json:"issue_url,omitempty"`
+	LastError      string     `json:"last_error"`
+	IsDelivering   bool       `json:"is_delivering"`
+}
+
+func newNewspaperItem(s *server.Server, r *http.Request, n *bookmarks.Newspaper, base string) newspaperItem {
+	res := newspaperItem{
+		Newspaper:     n,
+		ID:            n.UID,
+		Href:          s.AbsoluteURL(r, base, n.UID).String(),
+		Created:       n.Created,
+		Updated:       n.Updated,
+		Title:         n.Title,
+		Schedule:      n.Schedule,
+		Weekday:       n.Weekday,
+		DeliveryTime:  n.DeliveryTime,
+		Timezone:      n.Timezone,
+		MaxItems:      n.MaxItems,
+		SendEmail:     n.SendEmail,
+		PublishOPDS:   n.PublishOPDS,
+		AfterDelivery: n.AfterDelivery,
+		LastRun:       n.LastRun,
+		IssueDate:     n.IssueDate,
+		IssueItems:    len(n.IssueItems),
+		LastError:     n.LastError,
+		IsDelivering:  tasks.DeliverNewspaperTask.IsRunning(n.ID),
+	}
+
+	if c, err := bookmarks.Collections.GetOne(goqu.C("id").Eq(n.CollectionID)); err == nil {
+		res.Collection = c.UID
+		res.CollectionName = c.Name
+	}
+	if n.PublishOPDS && n.IssueDate != nil {
+		res.IssueURL = s.AbsoluteURL(r, "/api/bookmarks/newspapers", n.UID, "issue.epub").String()
+	}
+
+	return res
+}
@@ -0,0 +1,272 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestNewspaperAPI(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	u := app.Users["user"]
+
+	c := &bookmarks.Collection{UserID: &u.User.ID, Name: "morning"}
+	require.NoError(t, bookmarks.Collections.Create(c))
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/bookmarks/newspapers",
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+		RequestTest{
+			Method:       "POST",
+			Target:       "/api/bookmarks/newspapers",
+			JSON:         map[string]interface{}{},
+			ExpectStatus: 422,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".errors", []any{"choose at least one delivery method"})
+				r.AssertJQ(t, ".fields.title.errors", []any{"field is required"})
+				r.AssertJQ(t, ".fields.collection.errors", []any{"field is required"})
+			},
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/api/bookmarks/newspapers",
+			JSON: map[string]interface{}{
+				"title":         "Morning News",
+				"collection":    c.UID,
+				"delivery_time": "25:00",
+				"timezone":      "Mars/Olympus",
+				"publish_opds":  true,
+			},
+			ExpectStatus: 422,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".fields.delivery_time.errors", []any{"invalid time, the format is HH:MM"})
+				r.AssertJQ(t, ".fields.timezone.errors", []any{"unknown time zone"})
+			},
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/api/bookmarks/newspapers",
+			JSON: map[string]interface{}{
+				"title":          "Morning News",
+				"collection":     c.UID,
+				"timezone":       "Europe/Paris",
+				"send_email":     true,
+				"publish_opds":   true,
+				"after_delivery": "read",
+			},
+			ExpectStatus:   201,
+			ExpectRedirect: "/api/bookmarks/newspapers/.+",
+			ExpectJSON:     `{"status":201,"message":"Newspaper created"}`,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 0).Redirect }}",
+			ExpectStatus: 200,
+			ExpectJSON: `{
+				"id": "<<PRESENCE>>",
+				"href": "<<PRESENCE>>",
+				"created": "<<PRESENCE>>",
+				"updated": "<<PRESENCE>>",
+				"title": "Morning News",
+				"collection": "` + c.UID + `",
+				"collection_name": "morning",
+				"schedule": "daily",
+				"weekday": 1,
+				"delivery_time": "06:00",
+				"timezone": "Europe/Paris",
+				"max_items": 0,
+				"send_email": true,
+				"publish_opds": true,
+				"after_delivery": "read",
+				"last_run": null,
+				"issue_date": null,
+				"issue_items": 0,
+				"last_error": "",
+				"is_delivering": false
+			}`,
+		},
+		RequestTest{
+			Method: "PATCH",
+			Target: "{{ (index .History 0).Path }}",
+			JSON: map[string]interface{}{
+				"schedule":      "weekly",
+				"weekday":       0,
+				"delivery_time": "07:30",
+			},
+			ExpectStatus: 200,
+			ExpectJSON: `{
+				"id": "<<PRESENCE>>",
+				"schedule": "weekly",
+				"weekday": 0,
+				"delivery_time": "07:30",
+				"updated": "<<PRESENCE>>"
+			}`,
+		},
+		RequestTest{
+			Method: "PATCH",
+			Target: "{{ (index .History 0).Path }}",
+			JSON: map[string]interface{}{
+				"send_email":   false,
+				"publish_opds": false,
+			},
+			ExpectStatus: 422,
+		},
+		RequestTest{
+			Method: "PATCH",
+			Target: "{{ (index .History 0).Path }}",
+			JSON: map[string]interface{}{
+				"schedule": "monthly",
+			},
+			ExpectStatus: 422,
+		},
+		// No issue yet
+		RequestTest{
+			Target:       "{{ (index .History 0).Path }}/issue.epub",
+			JSON:         true,
+			ExpectStatus: 404,
+		},
+	)
+
+	n, err := bookmarks.Newspapers.GetOne(goqu.C("collection_id").Eq(c.ID))
+	require.NoError(t, err)
+	require.Equal(t, bookmarks.NewspaperWeekly, n.Schedule)
+
+	t.Run("failed delivery", func(t *testing.T) {
+		lastDelivery := time.Now().Add(-time.Hour)
+		n.LastDelivery = &lastDelivery
+
+		// No e-reader address
+		now := time.Now()
+		require.EqualError(t, tasks.DeliverNewspaper(n, u.User, now), "no e-reader email address")
+		require.WithinDuration(t, now, *n.LastRun, time.Second)
+		require.Equal(t, lastDelivery, *n.LastDelivery)
+
+		saved, err := bookmarks.Newspapers.GetOne(goqu.C("id").Eq(n.ID))
+		require.NoError(t, err)
+		require.Equal(t, "no e-reader email address", saved.LastError)
+		require.Nil(t, saved.LastDelivery)
+	})
+
+	t.Run("deliver", func(t *testing.T) {
+		// The bookmark was saved before the failed run,
+		// it's in the next issue.
+		b := u.Bookmarks[0]
+		require.Less(t, b.ReadProgress, 100)
+		require.True(t, b.Created.Before(*n.LastRun))
+
+		u.User.Settings.EmailSettings.EpubTo = "ereader@localhost"
+		require.NoError(t, u.User.Save())
+
+		now := time.Now()
+
+		app.LastEmail = ""
+		require.NoError(t, tasks.DeliverNewspaper(n, u.User, now))
+		require.Contains(t, app.LastEmail, "To: <ereader@localhost>")
+		require.Contains(t, app.LastEmail, "morning-news.epub")
+
+		n, err = bookmarks.Newspapers.GetOne(goqu.C("id").Eq(n.ID))
+		require.NoError(t, err)
+		require.Empty(t, n.LastError)
+		require.WithinDuration(t, now, *n.LastRun, time.Second)
+		require.WithinDuration(t, now, *n.LastDelivery, time.Second)
+		require.WithinDuration(t, now, *n.IssueDate, time.Second)
+		require.Equal(t, []string{b.UID}, []string(n.IssueItems))
+
+		// The bookmark was marked as read
+		b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(b.ID))
+		require.NoError(t, err)
+		require.Equal(t, 100, b.ReadProgress)
+
+		// Nothing new, the issue is kept
+		app.LastEmail = ""
+		require.NoError(t, tasks.DeliverNewspaper(n, u.User, now.Add(time.Minute)))
+		require.Empty(t, app.LastEmail)
+		require.Equal(t, []string{b.UID}, []string(n.IssueItems))
+	})
+
+	t.Run("issue", func(t *testing.T) {
+		RunRequestSequence(t, client, "user",
+			RequestTest{
+				Target:       "/api/bookmarks/newspapers/" + n.UID + "/issue.epub",
+				JSON:         true,
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					require.Equal(t, "application/epub+zip", r.Header.Get("Content-Type"))
+					z, err := zip.NewReader(bytes.NewReader(r.Body), int64(len(r.Body)))
+					require.NoError(t, err)
+
+					files := map[string]*zip.File{}
+					for _, f := range z.File {
+						files[f.Name] = f
+					}
+					require.Contains(t, files, "OEBPS/Images/cover.png")
+					require.Contains(t, files, "OEBPS/contents.html")
+					require.Contains(t, files, "OEBPS/"+u.Bookmarks[0].UID+".html")
+
+					fp, err := files["OEBPS/content.opf"].Open()
+					require.NoError(t, err)
+					defer fp.Close() //nolint:errcheck
+					opf, err := io.ReadAll(fp)
+					require.NoError(t, err)
+					require.Contains(t, string(opf), `<meta name="cover" content="cover-image"></meta>`)
+				},
+			},
+			RequestTest{
+				Target:         "/opds/bookmarks/newspapers",
+				ExpectStatus:   200,
+				ExpectContains: "/api/bookmarks/newspapers/" + n.UID + "/issue.epub",
+			},
+		)
+	})
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Method:       "POST",
+			Target:       "/api/bookmarks/newspapers/" + n.UID + "/deliver",
+			JSON:         true,
+			ExpectStatus: 202,
+		},
+		RequestTest{
+			Method:       "DELETE",
+			Target:       "/api/bookmarks/newspapers/" + n.UID,
+			JSON:         true,
+			ExpectStatus: 204,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/bookmarks/newspapers/" + n.UID,
+			ExpectStatus: 404,
+		},
+	)
+
+	RunRequestSequence(t, client, "staff",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/bookmarks/newspapers",
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+	)
+}
@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/internal/server"
+)
+
+// revisionCurrent is the special revision ID that
+// designates the bookmark's current content.
+const revisionCurrent = "current"
+
+type (
+	ctxRevisionListKey struct{}
+	ctxRevisionKey     struct{}
+)
+
+func (api *apiRouter) revisionList(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	rl := r.Context().Value(ctxRevisionListKey{}).(revisionList)
+
+	rl.Items = make([]revisionItem, len(rl.items))
+	for i, item := range rl.items {
+		rl.Items[i] = newRevisionItem(api.srv, r, b, item)
+	}
+
+	api.srv.SendPaginationHeaders(w, r, rl.Pagination)
+	api.srv.Render(w, r, http.StatusOK, rl.Items)
+}
+
+func (api *apiRouter) revisionInfo(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	rev := r.Context().Value(ctxRevisionKey{}).(*bookmarks.Revision)
+	item := newRevisionItem(api.srv, r, b, rev)
+	item.Text = rev.Text
+	item.Diff = rev.Diff
+
+	api.srv.Render(w, r, http.StatusOK, item)
+}
+
+// revisionDiff returns the diff between two revisions. The "from" and "to"
+// query parameters are revision IDs or "current" for the bookmark's
+// current content. "to" defaults to "current" and "from" to the
+// most recent revision.
+func (api *apiRouter) revisionDiff(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		to = revisionCurrent
+	}
+
+	// getRevision returns a revision from its ID, the most recent
+	// revision when id is empty or the bookmark's content.
+	getRevision := func(id string) (*bookmarks.Revision, error) {
+		if id == revisionCurrent {
+			return &bookmarks.Revision{
+				UID:       revisionCurrent,
+				Created:   b.Updated,
+				Title:     b.Title,
+				Text:      b.Text,
+				WordCount: b.WordCount,
+			}, nil
+		}
+
+		ds := bookmarks.Revisions.Query().
+			Where(goqu.C("bookmark_id").Eq(b.ID)).
+			Order(goqu.C("created").Desc()).
+			Limit(1)
+		if id != "" {
+			ds = ds.Where(goqu.C("uid").Eq(id))
+		}
+
+		var rev bookmarks.Revision
+		found, err := ds.ScanStruct(&rev)
+		switch {
+		case err != nil:
+			return nil, err
+		case !found:
+			return nil, bookmarks.ErrRevisionNotFound
+		}
+		return &rev, nil
+	}
+
+	revFrom, err := getRevision(from)
+	if err != nil {
+		api.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+	revTo, err := getRevision(to)
+	if err != nil {
+		api.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+
+	api.srv.Render(w, r, http.StatusOK, revisionDiff{
+		From: newRevisionItem(api.srv, r, b, revFrom),
+		To:   newRevisionItem(api.srv, r, b, revTo),
+		Diff: bookmarks.TextDiff(
+			revFrom.Text, revTo.Text,
+			revFrom.UID, revTo.UID,
+		),
+	})
+}
+
+func (api *apiRouter) bookmarkRefresh(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+
+	if err := tasks.RefreshBookmarkTask.Run(b.ID, b.ID); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.TextMessage(w, r, http.StatusAccepted, "Bookmark refresh started")
+}
+
+func (api *apiRouter) withRevisionList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+		res := revisionList{}
+
+		pf := api.srv.GetPageParams(r, 30)
+		if pf == nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ds := bookmarks.Revisions.Query().
+			Where(goqu.C("bookmark_id").Eq(b.ID))
+
+		ds = ds.Order(goqu.C("created").Desc()).
+			Limit(uint(pf.Limit())).
+			Offset(uint(pf.Offset()))
+
+		count, err := ds.ClearOrder().ClearLimit().ClearOffset().Count()
+		if err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		res.items = []*bookmarks.Revision{}
+		if err := ds.ScanStructs(&res.items); err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		res.Pagination = api.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset())
+
+		ctx := context.WithValue(r.Context(), ctxRevisionListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *apiRouter) withRevision(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+
+		rev, err := bookmarks.Revisions.GetOne(
+			goqu.C("uid").Eq(chi.URLParam(r, "id")),
+			goqu.C("bookmark_id").Eq(b.ID),
+		)
+		if err != nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxRevisionKey{}, rev)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type revisionList struct {
+	items      []*bookmarks.Revision
+	Pagination server.Pagination
+	Items      []revisionItem
+}
+
+type revisionItem struct {
+	ID        string    `json:"id"`
+	Href      string    `json:"href"`
+	Created   time.Time `json:"created"`
+	Title     string    `json:"title"`
+	WordCount int       `json:"word_count"`
+	Text      string    `json:"text,omitempty"`
+	Diff      string    `json:"diff,omitempty"`
+}
+
+type revisionDiff struct {
+	From revisionItem `json:"from"`
+	To   revisionItem `json:"to"`
+	Diff string       `json:"diff"`
+}
+
+func newRevisionItem(
+	s *server.Server,
+	r *http.Request,
+	b *bookmarks.Bookmark,
+	rev *bookmarks.Revision,
+) revisionItem {
+	res := revisionItem{
+		ID:        rev.UID,
+		Created:   rev.Created,
+		Title:     rev.Title,
+		WordCount: rev.WordCount,
+	}
+	if rev.ID != 0 {
+		res.Href = s.AbsoluteURL(r, "/api/bookmarks", b.UID, "revisions", rev.UID).String()
+	} else {
+		res.Href = s.AbsoluteURL(r, "/api/bookmarks", b.UID).String()
+	}
+
+	return res
+}
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestRevisionAPI(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	b := app.Users["user"].Bookmarks[0]
+	other := app.Users["staff"].Bookmarks[0]
+	b.Text = "First paragraph.\n\nSecond paragraph."
+	require.NoError(t, b.Save())
+
+	rev, err := b.NewRevision()
+	require.NoError(t, err)
+	_, err = bookmarks.Storage().Stat(rev.FilePath)
+	require.NoError(t, err)
+
+	b.Text = "First paragraph.\n\nSecond paragraph, updated."
+	require.NoError(t, b.Save())
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}",
+			JSON:         map[string]any{"refresh_interval": 400},
+			ExpectStatus: 400,
+		},
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}",
+			JSON:         map[string]any{"refresh_interval": 7},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Equal(t, float64(7), r.JSON.(map[string]any)["refresh_interval"])
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Equal(t, float64(7), r.JSON.(map[string]any)["refresh_interval"])
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}/revisions",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				items := r.JSON.([]any)
+				require.Len(t, items, 1)
+				require.Equal(t, rev.UID, items[0].(map[string]any)["id"])
+				require.Equal(t, float64(rev.WordCount), items[0].(map[string]any)["word_count"])
+				require.NotContains(t, items[0], "text")
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}/revisions/" + rev.UID,
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Equal(t, "First paragraph.\n\nSecond paragraph.", r.JSON.(map[string]any)["text"])
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/" + other.UID + "/revisions/" + rev.UID,
+			JSON:         true,
+			ExpectStatus: 404,
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}/revisions/diff",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				res := r.JSON.(map[string]any)
+				require.Equal(t, rev.UID, res["from"].(map[string]any)["id"])
+				require.Equal(t, "current", res["to"].(map[string]any)["id"])
+				require.Contains(t, res["diff"], "--- "+rev.UID+"\n+++ current\n")
+				require.Contains(t, res["diff"], "-Second paragraph.\n+Second paragraph, updated.\n")
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}/revisions/diff?from=current&to=" + rev.UID,
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Contains(t, r.JSON.(map[string]any)["diff"], "-Second paragraph, updated.\n+Second paragraph.\n")
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}/revisions/diff?from=abcdefghijklmnopqrstuv",
+			JSON:         true,
+			ExpectStatus: 404,
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/" + other.UID + "/revisions/diff",
+			JSON:         true,
+			ExpectStatus: 404,
+		},
+	)
+
+	// Revisions are removed with the bookmark
+	require.NoError(t, b.Delete())
+	_, err = bookmarks.Storage().Stat(rev.FilePath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
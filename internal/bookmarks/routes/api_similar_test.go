@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes_test
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+const similarText = `
+The town council met on Tuesday evening to discuss the future of the old
+railway station, a building that has stood empty for more than a decade.
+Several residents came forward with proposals, ranging from a covered market
+to a small museum dedicated to the history of the line. The mayor reminded
+the audience that any project would have to find its own funding, since the
+municipal budget is already stretched by the renovation of the two schools.
+A local association presented a detailed plan for a cooperative café and
+workshop space, run by volunteers and financed by a crowdfunding campaign.
+The debate went on for nearly three hours. In the end, the council agreed to
+launch a public consultation during the summer and to commission a study of
+the renovation costs. A final decision is expected before the end of the year.
+`
+
+func TestSimilar(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	u := app.Users["user"]
+	texts := []string{
+		similarText,
+		"Originally published by the Town Gazette.\n" + similarText + "\nShare this story.",
+		"Too short to compare.",
+	}
+	list := []*bookmarks.Bookmark{}
+	for i, text := range texts {
+		b := &bookmarks.Bookmark{
+			UserID:  &u.User.ID,
+			State:   bookmarks.StateLoaded,
+			URL:     "https://example.net/similar-" + string(rune('a'+i)),
+			Title:   "Similar " + string(rune('a'+i)),
+			Text:    text,
+			Simhash: bookmarks.TextFingerprint(text),
+		}
+		require.NoError(t, bookmarks.Bookmarks.Create(b))
+		list = append(list, b)
+	}
+	first, second, short := list[0].UID, list[1].UID, list[2].UID
+	pair := []any{first, second}
+	if second < first {
+		pair = []any{second, first}
+	}
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Target:         "/bookmarks/" + first,
+			ExpectStatus:   200,
+			ExpectContains: "/bookmarks/" + second + `"`,
+		},
+		RequestTest{
+			Target:       "/bookmarks/" + short,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.NotContains(t, string(r.Body), "Similar bookmarks")
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks?search=duplicate:yes",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "map(.id) | sort", pair)
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks?search=duplicate:" + second,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "map(.id) | sort", pair)
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks?search=-duplicate:yes",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "length", len(u.Bookmarks)+1)
+				r.AssertJQ(t, `map(select(.id == "`+first+`")) | length`, 0)
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks?search=duplicate:" + short,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "length", 0)
+			},
+		},
+	)
+
+	// Other users' bookmarks are never similar
+	RunRequestSequence(t, client, "staff",
+		RequestTest{
+			Target:       "/api/bookmarks?search=duplicate:" + first,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, "length", 0)
+			},
+		},
+	)
+
+	// A long list of duplicates
+	staff := app.Users["staff"]
+	for i := range 600 {
+		require.NoError(t, bookmarks.Bookmarks.Create(&bookmarks.Bookmark{
+			UserID:  &staff.User.ID,
+			State:   bookmarks.StateLoaded,
+			URL:     fmt.Sprintf("https://example.net/copy-%d", i),
+			Title:   "Copy",
+			Simhash: list[0].Simhash,
+		}))
+	}
+
+	RunRequestSequence(t, client, "staff",
+		RequestTest{
+			Target:       "/api/bookmarks?search=duplicate:yes",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Equal(t, "600", r.Header.Get("Total-Count"))
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks?search=-duplicate:yes",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Equal(t, strconv.Itoa(len(staff.Bookmarks)), r.Header.Get("Total-Count"))
+			},
+		},
+	)
+}
@@ -0,0 +1,294 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/pkg/forms"
+	"codeberg.org/readeck/readeck/pkg/http/accept"
+)
+
+// syncResult contains the changes since a cursor, and the cursor
+// the client must send on its next synchronization.
+type syncResult struct {
+	Cursor      string                       `json:"cursor"`
+	HasMore     bool                         `json:"has_more"`
+	Bookmarks   syncChanges[bookmarkItem]    `json:"bookmarks"`
+	Collections *syncChanges[collectionItem] `json:"collections,omitempty"`
+	Labels      syncLabels                   `json:"labels"`
+
+	items []*bookmarks.Bookmark
+}
+
+// syncChanges is a list of created, updated and deleted items.
+// Deleted items are only identified by their ID.
+type syncChanges[T any] struct {
+	Created []T      `json:"created"`
+	Updated []T      `json:"updated"`
+	Deleted []string `json:"deleted"`
+}
+
+// syncLabels contains the labels of the changed bookmarks and the
+// labels that don't exist anymore.
+type syncLabels struct {
+	Updated []*labelItem `json:"updated"`
+	Deleted []string     `json:"deleted"`
+}
+
+// syncChanges returns the bookmark, collection and label changes since
+// the given cursor. The result is paginated with the cursor it contains.
+// With "Accept: application/zip", the response is a zip file that
+// contains the JSON result and the archives of the changed bookmarks.
+func (api *apiRouter) syncChanges(w http.ResponseWriter, r *http.Request) {
+	f := newSyncForm(api.srv.Locale(r))
+	forms.BindURL(f, r)
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusBadRequest, f)
+		return
+	}
+
+	user := auth.GetRequestUser(r)
+	cursor, err := f.cursor(user.ID)
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	res, err := api.newSyncResult(r, cursor, f.limit())
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	if accept.NegotiateContentType(r.Header, []string{"application/json", "application/zip"}, "application/json") == "application/zip" {
+		api.syncArchive(w, r, res)
+		return
+	}
+
+	api.srv.Render(w, r, http.StatusOK, res)
+}
+
+func (api *apiRouter) newSyncResult(r *http.Request, cursor syncCursor, limit int) (*syncResult, error) {
+	userID := auth.GetRequestUser(r).ID
+	res := &syncResult{
+		Bookmarks: syncChanges[bookmarkItem]{
+			Created: []bookmarkItem{}, Updated: []bookmarkItem{}, Deleted: []string{},
+		},
+		Labels: syncLabels{Updated: []*labelItem{}, Deleted: []string{}},
+		items:  []*bookmarks.Bookmark{},
+	}
+
+	// Bookmarks
+	ds := bookmarks.Bookmarks.Query().
+		Select(
+			"b.id", "b.uid", "b.created", "b.updated", "b.published", "b.state",
+			"b.url", "b.title", "b.domain", "b.site", "b.site_name", "b.authors",
+			"b.lang", "b.dir", "b.type", "b.is_marked", "b.is_archived",
+			"b.read_progress", "b.read_anchor", "b.labels", "b.description",
+			"b.word_count", "b.duration", "b.file_path", "b.files", "b.annotations",
+			"b.links", "b.refresh_interval", "b.refreshed",
+		).
+		Where(goqu.C("user_id").Table("b").Eq(userID))
+	ds = syncPositionFilter(ds, cursor.Bookmarks).Limit(uint(limit + 1))
+	if err := ds.ScanStructs(&res.items); err != nil {
+		return nil, err
+	}
+	if len(res.items) > limit {
+		res.items = res.items[:limit]
+		res.HasMore = true
+	}
+
+	// The cursor moves with every item, an item is new when it was
+	// created after the position the client sent.
+	since := cursor.Bookmarks.Updated
+	labels := []string{}
+	for _, b := range res.items {
+		item := newBookmarkItem(api.srv, r, b, ".")
+		if b.Created.After(since) {
+			res.Bookmarks.Created = append(res.Bookmarks.Created, item)
+		} else {
+			res.Bookmarks.Updated = append(res.Bookmarks.Updated, item)
+		}
+		labels = append(labels, b.Labels...)
+		cursor.Bookmarks = syncPosition{b.Updated, b.ID}
+	}
+
+	// Collections
+	if auth.HasPermission(r, "api:bookmarks:collections", "read") {
+		res.Collections = &syncChanges[collectionItem]{
+			Created: []collectionItem{}, Updated: []collectionItem{}, Deleted: []string{},
+		}
+
+		items := []*bookmarks.Collection{}
+		ds := bookmarks.Collections.Query().
+			Where(goqu.C("user_id").Table("c").Eq(userID))
+		ds = syncPositionFilter(ds, cursor.Collections).Limit(uint(limit + 1))
+		if err := ds.ScanStructs(&items); err != nil {
+			return nil, err
+		}
+		if len(items) > limit {
+			items = items[:limit]
+			res.HasMore = true
+		}
+
+		since := cursor.Collections.Updated
+		for _, c := range items {
+			item := newCollectionItem(api.srv, r, c, "./collections")
+			if c.Created.After(since) {
+				res.Collections.Created = append(res.Collections.Created, item)
+			} else {
+				res.Collections.Updated = append(res.Collections.Updated, item)
+			}
+			cursor.Collections = syncPosition{c.Updated, c.ID}
+		}
+	}
+
+	// Deletions
+	deletions := []*bookmarks.Deletion{}
+	err := bookmarks.Deletions.Query().
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("id").Gt(cursor.Deletions),
+		).
+		Order(goqu.C("id").Asc()).
+		Limit(uint(limit + 1)).
+		ScanStructs(&deletions)
+	if err != nil {
+		return nil, err
+	}
+	if len(deletions) > limit {
+		deletions = deletions[:limit]
+		res.HasMore = true
+	}
+
+	for _, d := range deletions {
+		switch d.Kind {
+		case bookmarks.DeletedBookmark:
+			res.Bookmarks.Deleted = append(res.Bookmarks.Deleted, d.Name)
+		case bookmarks.DeletedCollection:
+			if res.Collections != nil {
+				res.Collections.Deleted = append(res.Collections.Deleted, d.Name)
+			}
+		case bookmarks.DeletedLabel:
+			labels = append(labels, d.Name)
+		}
+		cursor.Deletions = d.ID
+	}
+
+	// Labels
+	// A label from a changed bookmark or from the deletion log is updated
+	// when it still exists, and deleted otherwise.
+	slices.Sort(labels)
+	labels = slices.Compact(labels)
+	if len(labels) > 0 {
+		err = bookmarks.Bookmarks.GetLabels().
+			Where(
+				goqu.C("user_id").Table("b").Eq(userID),
+				goqu.I("name").In(labels),
+			).
+			ScanStructs(&res.Labels.Updated)
+		if err != nil {
+			return nil, err
+		}
+
+		base := api.srv.AbsoluteURL(r, "/api/bookmarks")
+		for _, item := range res.Labels.Updated {
+			item.setURLs(base)
+			labels = slices.DeleteFunc(labels, func(s string) bool {
+				return s == string(item.Name)
+			})
+		}
+		res.Labels.Deleted = labels
+	}
+
+	res.Cursor = cursor.String()
+	return res, nil
+}
+
+// syncArchive writes a zip file containing the synchronization result
+// as "sync.json" and the archive of every changed bookmark as
+// "bookmarks/{id}.zip".
+func (api *apiRouter) syncArchive(w http.ResponseWriter, r *http.Request, res *syncResult) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(
+		`attachment; filename="%s-readeck-sync.zip"`,
+		time.Now().Format(time.DateOnly),
+	))
+
+	zw := zip.NewWriter(w)
+	defer zw.Close() //nolint:errcheck
+
+	fw, err := zw.Create("sync.json")
+	if err != nil {
+		api.srv.Log(r).Error("", slog.Any("err", err))
+		return
+	}
+	enc := json.NewEncoder(fw)
+	enc.SetEscapeHTML(false)
+	if err = enc.Encode(res); err != nil {
+		api.srv.Log(r).Error("", slog.Any("err", err))
+		return
+	}
+
+	for _, b := range res.items {
+		if b.GetFileName() == "" {
+			continue
+		}
+		if err := addSyncArchive(zw, b); err != nil {
+			api.srv.Log(r).Error("sync archive",
+				slog.String("bookmark", b.UID),
+				slog.Any("err", err),
+			)
+		}
+	}
+}
+
+func addSyncArchive(zw *zip.Writer, b *bookmarks.Bookmark) error {
+	fp, err := bookmarks.Storage().Open(b.GetFileName())
+	if err != nil {
+		return err
+	}
+	defer fp.Close() //nolint:errcheck
+
+	// The archive is already compressed
+	fw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     "bookmarks/" + b.UID + ".zip",
+		Method:   zip.Store,
+		Modified: b.Updated,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(fw, fp)
+	return err
+}
+
+// syncPositionFilter returns a dataset with the items after a position,
+// ordered by update date and ID.
+func syncPositionFilter(ds *goqu.SelectDataset, p syncPosition) *goqu.SelectDataset {
+	if !p.Updated.IsZero() {
+		ds = ds.Where(goqu.Or(
+			goqu.C("updated").Gt(p.Updated),
+			goqu.And(
+				goqu.C("updated").Eq(p.Updated),
+				goqu.C("id").Gt(p.ID),
+			),
+		))
+	}
+	return ds.Order(goqu.C("updated").Asc(), goqu.C("id").Asc())
+}
@@ -0,0 +1,224 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/db"
+
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestSyncAPI(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	b := app.Users["user"].Bookmarks[0]
+
+	b2 := &bookmarks.Bookmark{
+		UserID: &app.Users["user"].User.ID,
+		URL:    "https://example.net/sync",
+		Title:  "Sync",
+		Labels: []string{"sync"},
+	}
+	require.NoError(t, bookmarks.Bookmarks.Create(b2))
+
+	// ids returns the IDs or names of a result list
+	ids := func(r *Response, group, list string) []string {
+		res := []string{}
+		for _, x := range r.JSON.(map[string]any)[group].(map[string]any)[list].([]any) {
+			switch x := x.(type) {
+			case string:
+				res = append(res, x)
+			case map[string]any:
+				if id, ok := x["id"]; ok {
+					res = append(res, id.(string))
+				} else {
+					res = append(res, x["name"].(string))
+				}
+			}
+		}
+		return res
+	}
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Target:       "/api/bookmarks/sync?cursor=nope",
+			JSON:         true,
+			ExpectStatus: 400,
+			ExpectJQ:     []any{".fields.cursor.errors", []any{"invalid cursor"}},
+		},
+		RequestTest{
+			// First synchronization, paginated
+			Target:       "/api/bookmarks/sync?limit=1",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.True(t, r.JSON.(map[string]any)["has_more"].(bool))
+				require.Equal(t, []string{b.UID}, ids(r, "bookmarks", "created"))
+				require.Empty(t, ids(r, "bookmarks", "deleted"))
+			},
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/sync?limit=1&cursor={{ (index .History 0).JSON.cursor }}",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.False(t, r.JSON.(map[string]any)["has_more"].(bool))
+				require.Equal(t, []string{b2.UID}, ids(r, "bookmarks", "created"))
+				require.Equal(t, []string{"sync"}, ids(r, "labels", "updated"))
+			},
+		},
+		RequestTest{
+			// Nothing changed
+			Target:       "/api/bookmarks/sync?cursor={{ (index .History 0).JSON.cursor }}",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Empty(t, ids(r, "bookmarks", "created"))
+				require.Empty(t, ids(r, "bookmarks", "updated"))
+				require.Empty(t, ids(r, "labels", "updated"))
+				require.Empty(t, ids(r, "collections", "created"))
+			},
+		},
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "/api/bookmarks/" + b2.UID,
+			JSON:         map[string]any{"labels": []string{"other"}},
+			ExpectStatus: 200,
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/api/bookmarks/collections",
+			JSON: map[string]any{
+				"name": "Sync",
+			},
+			ExpectStatus: 201,
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/sync?cursor={{ (index .History 2).JSON.cursor }}",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Empty(t, ids(r, "bookmarks", "created"))
+				require.Equal(t, []string{b2.UID}, ids(r, "bookmarks", "updated"))
+				require.Equal(t, []string{"other"}, ids(r, "labels", "updated"))
+				require.Equal(t, []string{"sync"}, ids(r, "labels", "deleted"))
+				require.Len(t, ids(r, "collections", "created"), 1)
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			Target:       "/api/bookmarks/" + b2.UID,
+			JSON:         true,
+			ExpectStatus: 204,
+		},
+		RequestTest{
+			Method:       "DELETE",
+			Target:       "/api/bookmarks/collections/{{ index (index (index .History 1).JSON.collections.created 0) \"id\" }}",
+			JSON:         true,
+			ExpectStatus: 204,
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/sync?cursor={{ (index .History 2).JSON.cursor }}",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Empty(t, ids(r, "bookmarks", "updated"))
+				require.Equal(t, []string{b2.UID}, ids(r, "bookmarks", "deleted"))
+				require.Equal(t, []string{"other"}, ids(r, "labels", "deleted"))
+				require.Len(t, ids(r, "collections", "deleted"), 1)
+			},
+		},
+	)
+
+	t.Run("first sync", func(t *testing.T) {
+		staff := app.Users["staff"]
+		sb := &bookmarks.Bookmark{
+			UserID: &staff.User.ID,
+			URL:    "https://example.net/staff",
+			Title:  "Staff",
+		}
+		require.NoError(t, bookmarks.Bookmarks.Create(sb))
+		for _, name := range []string{"c1", "c2"} {
+			require.NoError(t, bookmarks.Collections.Create(&bookmarks.Collection{
+				UserID: &staff.User.ID,
+				Name:   name,
+			}))
+		}
+
+		// Every item has the same dates, so the cursor moves without
+		// passing any creation date.
+		now := time.Now().UTC().Truncate(time.Second)
+		for _, table := range []string{bookmarks.TableName, bookmarks.CollectionTable} {
+			_, err := db.Q().Update(table).
+				Set(goqu.Record{"created": now, "updated": now}).
+				Where(goqu.C("user_id").Eq(staff.User.ID)).
+				Executor().Exec()
+			require.NoError(t, err)
+		}
+
+		RunRequestSequence(t, client, "staff",
+			RequestTest{
+				Target:       "/api/bookmarks/sync?limit=10",
+				JSON:         true,
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					require.False(t, r.JSON.(map[string]any)["has_more"].(bool))
+					require.Equal(t,
+						[]string{staff.Bookmarks[0].UID, sb.UID},
+						ids(r, "bookmarks", "created"),
+					)
+					require.Empty(t, ids(r, "bookmarks", "updated"))
+					require.Len(t, ids(r, "collections", "created"), 2)
+					require.Empty(t, ids(r, "collections", "updated"))
+				},
+			},
+		)
+	})
+
+	t.Run("archives", func(t *testing.T) {
+		app.Users["user"].Login(client)
+		defer client.Logout()
+
+		req := client.NewRequest("GET", "/api/bookmarks/sync", nil)
+		req.Header.Set("Accept", "application/zip")
+		r := client.Request(req)
+		r.AssertStatus(t, 200)
+		require.Equal(t, "application/zip", r.Header.Get("Content-Type"))
+
+		zr, err := zip.NewReader(bytes.NewReader(r.Body), int64(len(r.Body)))
+		require.NoError(t, err)
+
+		names := []string{}
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		require.Equal(t, []string{"sync.json", "bookmarks/" + b.UID + ".zip"}, names)
+
+		fp, err := zr.Open("sync.json")
+		require.NoError(t, err)
+		defer fp.Close() //nolint:errcheck
+		data, err := io.ReadAll(fp)
+		require.NoError(t, err)
+
+		var res map[string]any
+		require.NoError(t, json.Unmarshal(data, &res))
+		require.NotEmpty(t, res["cursor"])
+	})
+}
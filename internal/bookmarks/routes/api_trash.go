@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/internal/server"
+)
+
+type (
+	ctxTrashListKey struct{}
+	ctxTrashItemKey struct{}
+)
+
+type trashList struct {
+	Pagination server.Pagination
+	Items      []trashItem
+}
+
+type trashItem struct {
+	Kind    string    `json:"kind"`
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Deleted time.Time `json:"deleted"`
+	Expires time.Time `json:"expires"`
+}
+
+func (api *apiRouter) trashList(w http.ResponseWriter, r *http.Request) {
+	tl := r.Context().Value(ctxTrashListKey{}).(trashList)
+
+	api.srv.SendPaginationHeaders(w, r, tl.Pagination)
+	api.srv.Render(w, r, http.StatusOK, tl.Items)
+}
+
+func (api *apiRouter) trashEmpty(w http.ResponseWriter, r *http.Request) {
+	if err := bookmarks.Trash.Empty(auth.GetRequestUser(r).ID); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Status(w, r, http.StatusNoContent)
+}
+
+func (api *apiRouter) trashRestore(w http.ResponseWriter, r *http.Request) {
+	item := r.Context().Value(ctxTrashItemKey{}).(bookmarks.Trashable)
+	if err := item.Restore(); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Status(w, r, http.StatusNoContent)
+}
+
+func (api *apiRouter) trashPurge(w http.ResponseWriter, r *http.Request) {
+	item := r.Context().Value(ctxTrashItemKey{}).(bookmarks.Trashable)
+	if err := item.Delete(); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Status(w, r, http.StatusNoContent)
+}
+
+func (api *apiRouter) withTrashList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := trashList{}
+
+		pf := api.srv.GetPageParams(r, 50)
+		if pf == nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ds := bookmarks.Trash.Query(auth.GetRequestUser(r).ID).
+			Order(goqu.C("deleted").Desc()).
+			Limit(uint(pf.Limit())).
+			Offset(uint(pf.Offset()))
+
+		count, err := ds.ClearOrder().ClearLimit().ClearOffset().Count()
+		if err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		items := []*bookmarks.TrashItem{}
+		if err = ds.ScanStructs(&items); err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		res.Items = make([]trashItem, len(items))
+		for i, x := range items {
+			res.Items[i] = trashItem{
+				Kind:    x.Kind,
+				ID:      x.UID,
+				Name:    x.Name,
+				Deleted: x.Deleted,
+				Expires: tasks.TrashExpiration(x.Deleted),
+			}
+		}
+
+		res.Pagination = api.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset())
+
+		ctx := context.WithValue(r.Context(), ctxTrashListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *apiRouter) withTrashItem(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		item, err := bookmarks.Trash.GetOne(
+			auth.GetRequestUser(r).ID,
+			chi.URLParam(r, "kind"),
+			chi.URLParam(r, "uid"),
+		)
+		if err != nil {
+			if errors.Is(err, bookmarks.ErrTrashItemNotFound) {
+				api.srv.Status(w, r, http.StatusNotFound)
+			} else {
+				api.srv.Error(w, r, err)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxTrashItemKey{}, item)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,230 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/db"
+
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestTrash(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	u := app.Users["user"]
+
+	b1 := &bookmarks.Bookmark{
+		UserID: &u.User.ID,
+		State:  bookmarks.StateLoaded,
+		URL:    "https://example.net/trash-1",
+		Title:  "Trashed bookmark",
+		Labels: []string{"keep", "old"},
+	}
+	b2 := &bookmarks.Bookmark{
+		UserID: &u.User.ID,
+		State:  bookmarks.StateLoaded,
+		URL:    "https://example.net/trash-2",
+		Title:  "Other bookmark",
+		Labels: []string{"old"},
+	}
+	for _, b := range []*bookmarks.Bookmark{b1, b2} {
+		require.NoError(t, bookmarks.Bookmarks.Create(b))
+	}
+
+	c := &bookmarks.Collection{
+		UserID: &u.User.ID,
+		Name:   "Trashed collection",
+	}
+	require.NoError(t, bookmarks.Collections.Create(c))
+
+	getLabels := func(t *testing.T, b *bookmarks.Bookmark) []string {
+		var x bookmarks.Bookmark
+		_, err := bookmarks.Bookmarks.QueryAll().
+			Where(goqu.I("b.id").Eq(b.ID)).
+			ScanStruct(&x)
+		require.NoError(t, err)
+		return x.Labels
+	}
+
+	t.Run("api", func(t *testing.T) {
+		RunRequestSequence(t, client, "user",
+			RequestTest{
+				JSON:         true,
+				Target:       "/api/bookmarks/trash",
+				ExpectStatus: 200,
+				ExpectJSON:   `[]`,
+			},
+			RequestTest{
+				JSON:         true,
+				Method:       "DELETE",
+				Target:       "/api/bookmarks/" + b1.UID,
+				ExpectStatus: 204,
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       "/api/bookmarks/" + b1.UID,
+				ExpectStatus: 404,
+			},
+			RequestTest{
+				JSON:         true,
+				Method:       "DELETE",
+				Target:       "/api/bookmarks/collections/" + c.UID,
+				ExpectStatus: 204,
+			},
+			RequestTest{
+				JSON:         true,
+				Method:       "DELETE",
+				Target:       "/api/bookmarks/labels/old",
+				ExpectStatus: 204,
+				Assert: func(t *testing.T, _ *Response) {
+					// The label is removed from the bookmark in the trash as well
+					require.Equal(t, []string{"keep"}, getLabels(t, b1))
+					require.Empty(t, getLabels(t, b2))
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       "/api/bookmarks/trash",
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, "[.[] | .kind]", []any{"label", "collection", "bookmark"})
+					r.AssertJQ(t, "[.[] | .name]", []any{"old", "Trashed collection", "Trashed bookmark"})
+					r.AssertJQ(t, ".[2].id", b1.UID)
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Method:       "POST",
+				Target:       "/api/bookmarks/trash/bookmark/" + b1.UID + "/restore",
+				ExpectStatus: 204,
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       "/api/bookmarks/" + b1.UID,
+				ExpectStatus: 200,
+			},
+			RequestTest{
+				JSON:         true,
+				Method:       "POST",
+				Target:       "/api/bookmarks/trash/collection/" + b1.UID + "/restore",
+				ExpectStatus: 404,
+			},
+			RequestTest{
+				JSON:         true,
+				Method:       "DELETE",
+				Target:       "/api/bookmarks/trash/collection/" + c.UID,
+				ExpectStatus: 204,
+				Assert: func(t *testing.T, _ *Response) {
+					count, err := bookmarks.Collections.QueryAll().
+						Where(goqu.I("c.id").Eq(c.ID)).
+						Count()
+					require.NoError(t, err)
+					require.Equal(t, int64(0), count)
+				},
+			},
+			RequestTest{
+				JSON:         true,
+				Target:       "/api/bookmarks/trash",
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					r.AssertJQ(t, "[.[] | .kind]", []any{"label"})
+				},
+			},
+		)
+	})
+
+	t.Run("views", func(t *testing.T) {
+		var l bookmarks.TrashedLabel
+		_, err := bookmarks.Trash.Labels().ScanStruct(&l)
+		require.NoError(t, err)
+
+		RunRequestSequence(t, client, "user",
+			RequestTest{
+				Target:         "/bookmarks/trash",
+				ExpectStatus:   200,
+				ExpectContains: "stay in the trash for 30 days",
+			},
+			RequestTest{
+				Method:         "POST",
+				Target:         "/bookmarks/trash/label/" + l.UID + "/restore",
+				Form:           url.Values{},
+				ExpectStatus:   303,
+				ExpectRedirect: "/bookmarks/trash",
+				Assert: func(t *testing.T, _ *Response) {
+					require.Equal(t, []string{"keep", "old"}, getLabels(t, b1))
+					require.Equal(t, []string{"old"}, getLabels(t, b2))
+				},
+			},
+			RequestTest{
+				Target:         "/bookmarks/trash",
+				ExpectStatus:   200,
+				ExpectContains: "The trash is empty.",
+				Assert: func(t *testing.T, _ *Response) {
+					require.NoError(t, b2.Trash())
+				},
+			},
+			RequestTest{
+				Target:         "/bookmarks/trash",
+				ExpectStatus:   200,
+				ExpectContains: "Other bookmark",
+			},
+			RequestTest{
+				Method:         "POST",
+				Target:         "/bookmarks/trash/empty",
+				Form:           url.Values{},
+				ExpectStatus:   303,
+				ExpectRedirect: "/bookmarks/trash",
+				Assert: func(t *testing.T, _ *Response) {
+					count, err := bookmarks.Bookmarks.QueryAll().
+						Where(goqu.I("b.id").Eq(b2.ID)).
+						Count()
+					require.NoError(t, err)
+					require.Equal(t, int64(0), count)
+				},
+			},
+		)
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		assert := require.New(t)
+
+		b3 := &bookmarks.Bookmark{
+			UserID: &u.User.ID,
+			State:  bookmarks.StateLoaded,
+			URL:    "https://example.net/trash-3",
+			Title:  "Old bookmark",
+		}
+		assert.NoError(bookmarks.Bookmarks.Create(b3))
+		assert.NoError(b3.Trash())
+		assert.NoError(b1.Trash())
+
+		// b3 was deleted a long time ago
+		_, err := db.Q().Update(bookmarks.TableName).Prepared(true).
+			Set(goqu.Record{"deleted": time.Now().AddDate(0, 0, -40)}).
+			Where(goqu.C("id").Eq(b3.ID)).
+			Executor().Exec()
+		assert.NoError(err)
+
+		assert.NoError(bookmarks.Trash.Purge(time.Now().AddDate(0, 0, -30)))
+
+		ids := []int{}
+		assert.NoError(bookmarks.Bookmarks.Trashed().
+			Select(goqu.I("b.id")).
+			ScanVals(&ids))
+		assert.Equal([]int{b1.ID}, ids)
+	})
+}
@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes_test
+
+import (
+	"io"
+	"mime/quotedprintable"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestCollectionDigest(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	u := app.Users["user"]
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Method: "POST",
+			Target: "/api/bookmarks/collections",
+			JSON: map[string]interface{}{
+				"name":            "digest",
+				"digest_schedule": "daily",
+			},
+			ExpectStatus: 201,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 0).Redirect }}",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Equal(t, "daily", r.JSON.(map[string]any)["digest_schedule"])
+				require.NotNil(t, r.JSON.(map[string]any)["digest_sent"])
+			},
+		},
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "{{ (index .History 0).Path }}",
+			JSON:         map[string]interface{}{"digest_schedule": "monthly"},
+			ExpectStatus: 422,
+		},
+		RequestTest{
+			Method:       "PATCH",
+			Target:       "{{ (index .History 0).Path }}",
+			JSON:         map[string]interface{}{"digest_schedule": "weekly"},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Equal(t, "weekly", r.JSON.(map[string]any)["digest_schedule"])
+			},
+		},
+	)
+
+	c, err := bookmarks.Collections.GetOne(
+		goqu.C("user_id").Eq(u.User.ID),
+		goqu.C("name").Eq("digest"),
+	)
+	require.NoError(t, err)
+	require.Equal(t, bookmarks.DigestWeekly, c.DigestSchedule)
+
+	now := time.Now()
+	require.False(t, c.IsDigestDue(now))
+	require.True(t, c.IsDigestDue(now.Add(7*24*time.Hour)))
+
+	// Nothing new since the subscription, no message is sent
+	app.LastEmail = ""
+	require.NoError(t, tasks.SendDigest(c, u.User, now))
+	require.Empty(t, app.LastEmail)
+
+	since := now.Add(-time.Hour)
+	c.DigestSent = &since
+	require.NoError(t, tasks.SendDigest(c, u.User, now))
+	require.NotEmpty(t, app.LastEmail)
+
+	c, err = bookmarks.Collections.GetOne(goqu.C("id").Eq(c.ID))
+	require.NoError(t, err)
+	require.WithinDuration(t, now, *c.DigestSent, time.Second)
+
+	require.Contains(t, app.LastEmail, "List-Unsubscribe-Post: List-Unsubscribe=One-Click")
+	msg, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(app.LastEmail)))
+	require.NoError(t, err)
+	require.Contains(t, string(msg), u.Bookmarks[0].UID)
+
+	m := regexp.MustCompile(`/@digest/([a-zA-Z0-9_-]+)`).FindStringSubmatch(string(msg))
+	require.Len(t, m, 2)
+	token := m[1]
+
+	RunRequestSequence(t, client, "",
+		RequestTest{
+			Target:         "/@digest/" + token,
+			ExpectStatus:   200,
+			ExpectContains: "Do you want to stop receiving",
+		},
+		RequestTest{
+			Target:       "/@digest/nope",
+			ExpectStatus: 404,
+		},
+		// A digest token can't be used to open a shared bookmark
+		RequestTest{
+			Target:       "/@b/" + token,
+			ExpectStatus: 404,
+		},
+		RequestTest{
+			Method:         "POST",
+			Target:         "/@digest/" + token,
+			Form:           map[string][]string{"List-Unsubscribe": {"One-Click"}},
+			ExpectStatus:   200,
+			ExpectContains: "anymore",
+		},
+	)
+
+	c, err = bookmarks.Collections.GetOne(goqu.C("id").Eq(c.ID))
+	require.NoError(t, err)
+	require.Empty(t, c.DigestSchedule)
+}
@@ -13,6 +13,7 @@ import (
 	"golang.org/x/net/html"
 
 	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/webhooks"
 	"codeberg.org/readeck/readeck/pkg/base58"
 	"codeberg.org/readeck/readeck/pkg/forms"
 )
@@ -21,11 +22,34 @@ type annotationForm struct {
 	*forms.Form
 }
 
-func newAnnotationUpdateForm(tr forms.Translator) *forms.Form {
-	return forms.Must(
+type annotationUpdateForm struct {
+	*forms.Form
+}
+
+func newAnnotationUpdateForm(tr forms.Translator) *annotationUpdateForm {
+	return &annotationUpdateForm{forms.Must(
 		forms.WithTranslator(context.Background(), tr),
-		forms.NewTextField("color", forms.Trim, forms.Required),
-	)
+		forms.NewTextField("color", forms.Trim),
+		forms.NewTextField("note", forms.Trim),
+	)}
+}
+
+// update sets the annotation's color and note, when they are
+// present in the form.
+func (f *annotationUpdateForm) update(annotation *bookmarks.BookmarkAnnotation) {
+	for _, field := range f.Fields() {
+		if !field.IsBound() || field.IsNil() {
+			continue
+		}
+		switch field.Name() {
+		case "color":
+			if field.String() != "" {
+				annotation.Color = field.String()
+			}
+		case "note":
+			annotation.Note = field.String()
+		}
+	}
 }
 
 func newAnnotationForm(tr forms.Translator) *annotationForm {
@@ -36,6 +60,7 @@ func newAnnotationForm(tr forms.Translator) *annotationForm {
 		forms.NewTextField("end_selector", forms.Required, forms.Trim),
 		forms.NewIntegerField("end_offset", forms.Required, forms.Gte(0)),
 		forms.NewTextField("color", forms.Required, forms.Trim),
+		forms.NewTextField("note", forms.Trim),
 	)}
 }
 
@@ -47,6 +72,7 @@ func (f *annotationForm) addToBookmark(bi *bookmarkItem) (*bookmarks.BookmarkAnn
 		EndSelector:   f.Get("end_selector").String(),
 		EndOffset:     f.Get("end_offset").Value().(int),
 		Color:         f.Get("color").String(),
+		Note:          f.Get("note").String(),
 		Created:       time.Now(),
 	}
 
@@ -89,6 +115,7 @@ func (f *annotationForm) addToBookmark(bi *bookmarkItem) (*bookmarks.BookmarkAnn
 	if err != nil {
 		return nil, err
 	}
+	webhooks.SendBookmarkEvent(webhooks.EventBookmarkAnnotated, b, annotation)
 
 	return annotation, nil
 }
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+var errBatchNoSelection = forms.Gettext("no bookmark selected")
+
+type batchForm struct {
+	*forms.Form
+}
+
+func newBatchForm(tr forms.Translator) *batchForm {
+	return &batchForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("operation",
+			forms.Choices(
+				forms.Choice(tr.Gettext("Add labels"), tasks.BatchAddLabels),
+				forms.Choice(tr.Gettext("Remove labels"), tasks.BatchRemoveLabels),
+				forms.Choice(tr.Gettext("Move to archive"), tasks.BatchArchive),
+				forms.Choice(tr.Gettext("Remove from archive"), tasks.BatchUnarchive),
+				forms.Choice(tr.Gettext("Add to favorites"), tasks.BatchMark),
+				forms.Choice(tr.Gettext("Remove from favorites"), tasks.BatchUnmark),
+				forms.Choice(tr.Gettext("Mark as read"), tasks.BatchRead),
+				forms.Choice(tr.Gettext("Mark as unread"), tasks.BatchUnread),
+				forms.Choice(tr.Gettext("Delete"), tasks.BatchDelete),
+				forms.Choice(tr.Gettext("Refresh content"), tasks.BatchRefresh),
+				forms.Choice(tr.Gettext("Export"), tasks.BatchExport),
+			),
+			forms.Trim, forms.Required,
+		),
+		forms.NewTextListField("labels", forms.Trim, forms.DiscardEmpty),
+		forms.NewTextListField("id", forms.Trim, forms.DiscardEmpty),
+		forms.NewBooleanField("all"),
+		forms.NewTextField("_to", forms.Trim),
+	)}
+}
+
+// Validate checks that a label operation receives labels.
+func (f *batchForm) Validate() {
+	switch f.Get("operation").String() {
+	case tasks.BatchAddLabels, tasks.BatchRemoveLabels:
+		if len(f.labels()) == 0 {
+			f.AddErrors("labels", forms.ErrRequired)
+		}
+	}
+}
+
+func (f *batchForm) labels() []string {
+	if f.Get("labels").IsNil() {
+		return nil
+	}
+	return f.Get("labels").(forms.TypedField[[]string]).V()
+}
+
+func (f *batchForm) ids() []string {
+	if f.Get("id").IsNil() {
+		return nil
+	}
+	return f.Get("id").(forms.TypedField[[]string]).V()
+}
+
+// selection returns the IDs of the bookmarks the operation applies to.
+// They're either the given "id" list, the bookmarks matching a collection
+// or filters, or every bookmark when "all" is set. Without any of these,
+// the form receives an error.
+func (f *batchForm) selection(userID int, filters *filterForm, hasCollection bool) ([]int, error) {
+	ds := bookmarks.Bookmarks.Query().
+		Select(goqu.I("b.id")).
+		Where(goqu.I("b.user_id").Eq(userID)).
+		Order(goqu.I("b.created").Desc())
+
+	all, _ := f.Get("all").Value().(bool)
+	switch {
+	case len(f.ids()) > 0:
+		ds = ds.Where(goqu.I("b.uid").In(f.ids()))
+	case hasCollection || filters.hasValues():
+		if !filters.IsValid() {
+			f.AddErrors("", errBatchNoSelection)
+			return nil, nil
+		}
+		bf := bookmarks.NewFiltersFromForm(filters)
+		if err := bf.LoadDuplicates(userID); err != nil {
+			return nil, err
+		}
+		ds = bf.ToSelectDataSet(ds)
+	case all:
+	default:
+		f.AddErrors("", errBatchNoSelection)
+		return nil, nil
+	}
+
+	res := []int{}
+	if err := ds.ScanVals(&res); err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		f.AddErrors("", errBatchNoSelection)
+	}
+	return res, nil
+}
@@ -21,6 +21,7 @@ import (
 	goquexp "github.com/doug-martin/goqu/v9/exp"
 	"github.com/wneessen/go-mail"
 
+	"codeberg.org/readeck/readeck/configs"
 	"codeberg.org/readeck/readeck/internal/auth"
 	"codeberg.org/readeck/readeck/internal/auth/users"
 	"codeberg.org/readeck/readeck/internal/bookmarks"
@@ -30,6 +31,7 @@ import (
 	"codeberg.org/readeck/readeck/internal/email"
 	"codeberg.org/readeck/readeck/internal/searchstring"
 	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/internal/webhooks"
 	"codeberg.org/readeck/readeck/locales"
 	"codeberg.org/readeck/readeck/pkg/forms"
 	"codeberg.org/readeck/readeck/pkg/timetoken"
@@ -45,6 +47,7 @@ const (
 	filtersTitleArticles
 	filtersTitleVideos
 	filtersTitlePictures
+	filtersTitleDocuments
 )
 
 const (
@@ -60,6 +63,11 @@ type createForm struct {
 	userID    int
 	requestID string
 	resources []tasks.MultipartResource
+	// duplicates are the existing bookmarks with the same URL.
+	duplicates []*bookmarks.Bookmark
+	// merged is true when no bookmark was created and
+	// the first duplicate was returned instead.
+	merged bool
 }
 
 func newCreateForm(tr forms.Translator, userID int, requestID string) *createForm {
@@ -148,8 +156,29 @@ func (f *createForm) createBookmark() (b *bookmarks.Bookmark, err error) {
 		return nil, errors.New("form is not bound")
 	}
 
-	uri, _ := url.Parse(f.Get("url").String())
-	uri.Fragment = ""
+	src, _ := url.Parse(f.Get("url").String())
+	uri := bookmarks.CleanURL(src)
+
+	// The resources sent with the original URL are
+	// for the cleaned URL now.
+	for i := range f.resources {
+		if f.resources[i].URL == src.String() {
+			f.resources[i].URL = uri.String()
+		}
+	}
+
+	defer func() {
+		if err != nil {
+			f.AddErrors("", forms.ErrUnexpected)
+		}
+	}()
+
+	if f.duplicates, err = bookmarks.Bookmarks.FindDuplicates(f.userID, uri); err != nil {
+		return
+	}
+	if len(f.duplicates) > 0 && configs.Config.Bookmarks.MergeDuplicates {
+		return f.mergeBookmark(f.duplicates[0])
+	}
 
 	b = &bookmarks.Bookmark{
 		UserID:   &f.userID,
@@ -166,15 +195,10 @@ func (f *createForm) createBookmark() (b *bookmarks.Bookmark, err error) {
 		b.Labels = slices.Compact(b.Labels)
 	}
 
-	defer func() {
-		if err != nil {
-			f.AddErrors("", forms.ErrUnexpected)
-		}
-	}()
-
 	if err = bookmarks.Bookmarks.Create(b); err != nil {
 		return
 	}
+	webhooks.SendBookmarkEvent(webhooks.EventBookmarkCreated, b, nil)
 
 	// Start extraction job
 	err = tasks.ExtractPageTask.Run(b.ID, tasks.ExtractParams{
@@ -186,6 +210,29 @@ func (f *createForm) createBookmark() (b *bookmarks.Bookmark, err error) {
 	return
 }
 
+// mergeBookmark adds the form's labels to an existing bookmark
+// and returns it.
+func (f *createForm) mergeBookmark(b *bookmarks.Bookmark) (*bookmarks.Bookmark, error) {
+	f.merged = true
+	if f.Get("labels").IsNil() {
+		return b, nil
+	}
+
+	labels := append(slices.Clone(b.Labels), f.Get("labels").(forms.TypedField[[]string]).V()...)
+	slices.Sort(labels)
+	labels = slices.Compact(labels)
+	if slices.Equal(labels, b.Labels) {
+		return b, nil
+	}
+
+	if err := b.Update(map[string]interface{}{"labels": labels}); err != nil {
+		return nil, err
+	}
+	b.Labels = labels
+	webhooks.SendBookmarkEvent(webhooks.EventBookmarkLabeled, b, nil)
+	return b, nil
+}
+
 type updateForm struct {
 	*forms.Form
 }
@@ -199,6 +246,7 @@ func newUpdateForm(tr forms.Translator) *updateForm {
 		forms.NewBooleanField("is_deleted"),
 		forms.NewIntegerField("read_progress", forms.Gte(0), forms.Lte(100)),
 		forms.NewTextField("read_anchor", forms.Trim),
+		forms.NewIntegerField("refresh_interval", forms.Gte(0), forms.Lte(365)),
 		forms.NewTextListField("labels", forms.Trim, forms.DiscardEmpty),
 		forms.NewTextListField("add_labels", forms.Trim, forms.DiscardEmpty),
 		forms.NewTextListField("remove_labels", forms.Trim, forms.DiscardEmpty),
@@ -210,6 +258,8 @@ func (f *updateForm) update(b *bookmarks.Bookmark) (updated map[string]interface
 	updated = map[string]interface{}{}
 	var deleted *bool
 	labelsChanged := false
+	wasMarked, wasArchived, wasRead := b.IsMarked, b.IsArchived, b.ReadProgress == 100
+	previousLabels := slices.Clone(b.Labels)
 
 	for _, field := range f.Fields() {
 		if !field.IsBound() || field.IsNil() {
@@ -236,6 +286,9 @@ func (f *updateForm) update(b *bookmarks.Bookmark) (updated map[string]interface
 		case "read_anchor":
 			b.ReadAnchor = field.String()
 			updated[n] = field.Value()
+		case "refresh_interval":
+			b.RefreshInterval = field.(forms.TypedField[int]).V()
+			updated[n] = field.Value()
 		// labels, add_labels and remove_labels are declared and
 		// processed in this order.
 		case "labels":
@@ -278,6 +331,20 @@ func (f *updateForm) update(b *bookmarks.Bookmark) (updated map[string]interface
 			return
 		}
 
+		if b.IsMarked && !wasMarked {
+			webhooks.SendBookmarkEvent(webhooks.EventBookmarkMarked, b, nil)
+		}
+		if b.IsArchived && !wasArchived {
+			webhooks.SendBookmarkEvent(webhooks.EventBookmarkArchived, b, nil)
+		}
+		if labelsChanged && !slices.Equal(b.Labels, previousLabels) {
+			webhooks.SendBookmarkEvent(webhooks.EventBookmarkLabeled, b, nil)
+		}
+		if b.ReadProgress == 100 && !wasRead {
+			if err = tasks.TriggerRules(b, bookmarks.RuleEventRead); err != nil {
+				return
+			}
+		}
 	}
 
 	if deleted != nil {
@@ -380,6 +447,7 @@ func newFilterForm(tr forms.Translator) *filterForm {
 				forms.Choice(tr.Gettext("Article"), "article"),
 				forms.Choice(tr.Gettext("Picture"), "photo"),
 				forms.Choice(tr.Gettext("Video"), "video"),
+				forms.Choice(tr.Gettext("PDF Document"), "pdf"),
 			), forms.Trim),
 			forms.NewBooleanField("is_loaded"),
 			forms.NewBooleanField("has_errors"),
@@ -477,6 +545,20 @@ func (f *filterForm) IsActive() bool {
 	return false
 }
 
+// hasValues returns true when at least one filter is set.
+func (f *filterForm) hasValues() bool {
+	for _, field := range f.Fields() {
+		switch field.Name() {
+		case "bf", "id", "updated_since":
+			continue
+		}
+		if !field.IsNil() && field.String() != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func (f *filterForm) GetQueryString() string {
 	q := url.Values{}
 	for _, field := range f.Fields() {
@@ -521,6 +603,8 @@ func (f *filterForm) setType(v string) {
 		f.title = filtersTitlePictures
 	case "video":
 		f.title = filtersTitleVideos
+	case "pdf":
+		f.title = filtersTitleDocuments
 	}
 }
 
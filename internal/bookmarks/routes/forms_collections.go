@@ -55,6 +55,11 @@ func newCollectionForm(tr forms.Translator, r *http.Request) *collectionForm {
 				return nil
 			})),
 			forms.NewBooleanField("is_pinned"),
+			forms.NewTextField("digest_schedule", forms.Trim, forms.Choices(
+				forms.Choice(tr.Pgettext("digest", "Never"), ""),
+				forms.Choice(tr.Pgettext("digest", "Daily"), bookmarks.DigestDaily),
+				forms.Choice(tr.Pgettext("digest", "Weekly"), bookmarks.DigestWeekly),
+			)),
 		),
 	)}
 }
@@ -69,6 +74,7 @@ func (f *collectionForm) setCollection(c *bookmarks.Collection) {
 	// Regular values
 	f.Get("name").Set(c.Name)
 	f.Get("is_pinned").Set(c.IsPinned)
+	f.Get("digest_schedule").Set(c.DigestSchedule)
 
 	c.Filters.UpdateForm(f)
 }
@@ -86,9 +92,16 @@ func (f *collectionForm) createCollection(userID int) (*bookmarks.Collection, er
 	}
 
 	c := &bookmarks.Collection{
-		UserID:  &userID,
-		Name:    f.Get("name").String(),
-		Filters: bookmarks.NewFiltersFromForm(f),
+		UserID:         &userID,
+		Name:           f.Get("name").String(),
+		Filters:        bookmarks.NewFiltersFromForm(f),
+		DigestSchedule: f.Get("digest_schedule").String(),
+	}
+	if c.DigestSchedule != "" {
+		// The first digest only contains the bookmarks
+		// added after the subscription.
+		now := time.Now()
+		c.DigestSent = &now
 	}
 
 	err = bookmarks.Collections.Create(c)
@@ -112,6 +125,14 @@ func (f *collectionForm) updateCollection(c *bookmarks.Collection) (res map[stri
 				res[name] = field.Value()
 				updateMap[name] = field.Value()
 			}
+		case "digest_schedule":
+			if field.IsBound() {
+				res[name] = field.String()
+				updateMap[name] = field.String()
+				if field.String() != "" && c.DigestSchedule == "" {
+					updateMap["digest_sent"] = time.Now()
+				}
+			}
 		default:
 			if field.IsBound() {
 				res[name] = field.Value()
@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"net/url"
+	"slices"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+// maxLookupURLs is the number of URLs a lookup accepts.
+const maxLookupURLs = 200
+
+type lookupForm struct {
+	*forms.Form
+}
+
+func newLookupForm(tr forms.Translator) *lookupForm {
+	return &lookupForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextListField("url", forms.Trim, forms.DiscardEmpty),
+	)}
+}
+
+// Validate checks the number of URLs.
+func (f *lookupForm) Validate() {
+	switch n := len(f.urls()); {
+	case n == 0:
+		f.AddErrors("url", forms.ErrRequired)
+	case n > maxLookupURLs:
+		f.AddErrors("url", forms.Gettext("no more than %d URLs", maxLookupURLs))
+	}
+}
+
+func (f *lookupForm) urls() []string {
+	if f.Get("url").IsNil() {
+		return nil
+	}
+	return f.Get("url").(forms.TypedField[[]string]).V()
+}
+
+// lookup returns, for each URL of the form, the user's bookmarks
+// saved with this URL. Bookmarks in the trash are left out.
+func (f *lookupForm) lookup(userID int) (map[string][]*bookmarks.Bookmark, error) {
+	res := map[string][]*bookmarks.Bookmark{}
+
+	// Every URL is matched by its canonical key, its clean URL
+	// or the bookmark's initial URL.
+	keys := map[string][]string{}
+	cleaned := map[string][]string{}
+	for _, s := range f.urls() {
+		res[s] = []*bookmarks.Bookmark{}
+		u, err := url.Parse(s)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		k := bookmarks.CanonicalKey(u)
+		keys[k] = append(keys[k], s)
+		c := bookmarks.CleanURL(u).String()
+		cleaned[c] = append(cleaned[c], s)
+	}
+	if len(keys) == 0 {
+		return res, nil
+	}
+
+	keyList := make([]string, 0, len(keys))
+	for k := range keys {
+		keyList = append(keyList, k)
+	}
+	cleanList := make([]string, 0, len(cleaned))
+	for k := range cleaned {
+		cleanList = append(cleanList, k)
+	}
+
+	var items []*bookmarks.Bookmark
+	if err := bookmarks.Bookmarks.Query().
+		Select(
+			"b.id", "b.uid", "b.user_id", "b.created", "b.url", "b.initial_url", "b.canonical_url",
+			"b.title", "b.is_archived", "b.is_marked", "b.read_progress",
+		).
+		Where(
+			goqu.I("b.user_id").Eq(userID),
+			goqu.Or(
+				goqu.I("b.canonical_url").In(keyList),
+				goqu.I("b.url").In(cleanList),
+				goqu.I("b.initial_url").In(cleanList),
+			),
+		).
+		Order(goqu.I("b.created").Asc(), goqu.I("b.id").Asc()).
+		ScanStructs(&items); err != nil {
+		return nil, err
+	}
+
+	for _, b := range items {
+		seen := map[string]bool{}
+		for _, s := range slices.Concat(keys[b.CanonicalURL], cleaned[b.URL], cleaned[b.InitialURL]) {
+			if seen[s] {
+				continue
+			}
+			seen[s] = true
+			res[s] = append(res[s], b)
+		}
+	}
+
+	return res, nil
+}
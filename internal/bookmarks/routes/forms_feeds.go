@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+var errFeedExists = forms.Gettext("you are already subscribed to this feed")
+
+type feedForm struct {
+	*forms.Form
+	userID int
+	feed   *bookmarks.Feed
+}
+
+func newFeedForm(tr forms.Translator, userID int) *feedForm {
+	res := &feedForm{userID: userID}
+	res.Form = forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("url",
+			forms.Trim,
+			forms.FieldValidatorFunc(func(f forms.Field) error {
+				// The URL is only required on creation
+				if res.feed == nil {
+					return forms.Required(f)
+				}
+				return forms.RequiredOrNil(f)
+			}),
+			forms.IsURL(validSchemes...),
+		),
+		forms.NewTextField("title", forms.Trim),
+		forms.NewTextListField("labels", forms.Trim, forms.DiscardEmpty),
+		forms.NewIntegerField("archive_after", forms.Gte(0), forms.Lte(3650)),
+	)
+
+	return res
+}
+
+func (f *feedForm) setFeed(feed *bookmarks.Feed) {
+	f.feed = feed
+	f.Get("url").Set(feed.URL)
+	f.Get("title").Set(feed.Title)
+	f.Get("labels").Set([]string(feed.Labels))
+	f.Get("archive_after").Set(feed.ArchiveAfter)
+}
+
+// Validate checks that the user is not already subscribed to the feed.
+func (f *feedForm) Validate() {
+	if !f.Get("url").IsBound() || f.Get("url").IsNil() || !f.Get("url").IsValid() {
+		return
+	}
+
+	ds := bookmarks.Feeds.Query().Where(
+		goqu.C("user_id").Eq(f.userID),
+		goqu.C("url").Eq(f.Get("url").String()),
+	)
+	if f.feed != nil {
+		ds = ds.Where(goqu.C("id").Neq(f.feed.ID))
+	}
+
+	if c, err := ds.Count(); err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+	} else if c > 0 {
+		f.AddErrors("url", errFeedExists)
+	}
+}
+
+// labels returns the form's labels.
+func (f *feedForm) labels() types.Strings {
+	return splitLabels(f.Get("labels"))
+}
+
+// splitLabels returns the labels of a text list field. A value can
+// contain several comma separated labels, as sent by the HTML forms.
+func splitLabels(field forms.Field) types.Strings {
+	res := types.Strings{}
+	if field.IsNil() {
+		return res
+	}
+
+	for _, x := range field.(forms.TypedField[[]string]).V() {
+		for _, label := range strings.Split(x, ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				res = append(res, label)
+			}
+		}
+	}
+	slices.Sort(res)
+	return slices.Compact(res)
+}
+
+// createFeed creates a new feed subscription and launches its
+// first fetch.
+func (f *feedForm) createFeed() (feed *bookmarks.Feed, err error) {
+	if !f.IsBound() {
+		return nil, errors.New("form is not bound")
+	}
+
+	defer func() {
+		if err != nil {
+			f.AddErrors("", forms.ErrUnexpected)
+		}
+	}()
+
+	feed = &bookmarks.Feed{
+		UserID: &f.userID,
+		URL:    f.Get("url").String(),
+		Title:  f.Get("title").String(),
+		Labels: f.labels(),
+	}
+	if !f.Get("archive_after").IsNil() {
+		feed.ArchiveAfter = f.Get("archive_after").(forms.TypedField[int]).V()
+	}
+
+	if err = bookmarks.Feeds.Create(feed); err != nil {
+		return
+	}
+
+	err = tasks.FetchFeedTask.Run(feed.ID, feed.ID)
+	return
+}
+
+// updateFeed updates a feed subscription. When the URL changes,
+// the feed is considered new and its entries are fetched again.
+func (f *feedForm) updateFeed(feed *bookmarks.Feed) (res map[string]any, err error) {
+	if !f.IsBound() {
+		err = errors.New("form is not bound")
+		return
+	}
+
+	res = map[string]any{}
+	updateMap := map[string]any{}
+	refetch := false
+
+	for name, field := range f.Fields() {
+		if !field.IsBound() || field.IsNil() {
+			continue
+		}
+		switch name {
+		case "url":
+			if field.String() == feed.URL {
+				continue
+			}
+			refetch = true
+			updateMap["last_fetched"] = nil
+			updateMap["entries"] = types.Strings{}
+			updateMap["last_error"] = ""
+			updateMap["url"] = field.String()
+			res["url"] = field.String()
+		case "labels":
+			updateMap["labels"] = f.labels()
+			res["labels"] = updateMap["labels"]
+		default:
+			updateMap[name] = field.Value()
+			res[name] = field.Value()
+		}
+	}
+
+	if len(res) > 0 {
+		res["updated"] = time.Now()
+		updateMap["updated"] = res["updated"]
+		if err = feed.Update(updateMap); err != nil {
+			f.AddErrors("", forms.ErrUnexpected)
+			return
+		}
+	}
+
+	if refetch {
+		if err = tasks.FetchFeedTask.Run(feed.ID, feed.ID); err != nil {
+			f.AddErrors("", forms.ErrUnexpected)
+			return
+		}
+	}
+
+	res["id"] = feed.UID
+	return
+}
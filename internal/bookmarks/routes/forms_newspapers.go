@@ -0,0 +1,217 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+var (
+	rxDeliveryTime = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+	errInvalidDeliveryTime = forms.Gettext("invalid time, the format is HH:MM")
+	errInvalidTimezone     = forms.Gettext("unknown time zone")
+	errNoDelivery          = forms.Gettext("choose at least one delivery method")
+)
+
+type newspaperForm struct {
+	*forms.Form
+	userID      int
+	newspaper   *bookmarks.Newspaper
+	collections map[string]int
+}
+
+func newNewspaperForm(tr forms.Translator, userID int) *newspaperForm {
+	res := &newspaperForm{userID: userID, collections: map[string]int{}}
+
+	// The collection choices are the user's collections
+	var items []*bookmarks.Collection
+	collectionChoices := [][2]string{}
+	if err := bookmarks.Collections.Query().
+		Where(goqu.C("user_id").Eq(userID)).
+		Order(goqu.I("name").Asc()).
+		ScanStructs(&items); err == nil {
+		for _, c := range items {
+			collectionChoices = append(collectionChoices, [2]string{c.UID, c.Name})
+			res.collections[c.UID] = c.ID
+		}
+	}
+
+	// Required on creation only
+	required := forms.FieldValidatorFunc(func(f forms.Field) error {
+		if res.newspaper == nil {
+			return forms.Required(f)
+		}
+		return forms.RequiredOrNil(f)
+	})
+
+	res.Form = forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("title", forms.Trim, required),
+		forms.NewTextField("collection", forms.Trim, required,
+			forms.ChoicesPairs(collectionChoices),
+		),
+		forms.NewTextField("schedule", forms.Trim, forms.Default(bookmarks.NewspaperDaily), forms.Choices(
+			forms.Choice(tr.Pgettext("newspaper", "Every day"), bookmarks.NewspaperDaily),
+			forms.Choice(tr.Pgettext("newspaper", "Monday to Friday"), bookmarks.NewspaperWeekdays),
+			forms.Choice(tr.Pgettext("newspaper", "Once a week"), bookmarks.NewspaperWeekly),
+		)),
+		forms.NewIntegerField("weekday", forms.Default(1), forms.Choices(
+			forms.Choice(tr.Gettext("Monday"), 1),
+			forms.Choice(tr.Gettext("Tuesday"), 2),
+			forms.Choice(tr.Gettext("Wednesday"), 3),
+			forms.Choice(tr.Gettext("Thursday"), 4),
+			forms.Choice(tr.Gettext("Friday"), 5),
+			forms.Choice(tr.Gettext("Saturday"), 6),
+			forms.Choice(tr.Gettext("Sunday"), 0),
+		)),
+		forms.NewTextField("delivery_time", forms.Trim, forms.Default("06:00"),
+			forms.RequiredOrNil,
+			forms.TypedValidator(rxDeliveryTime.MatchString, errInvalidDeliveryTime),
+		),
+		forms.NewTextField("timezone", forms.Trim, forms.Default("UTC"),
+			forms.RequiredOrNil,
+			forms.TypedValidator(func(v string) bool {
+				_, err := time.LoadLocation(v)
+				return err == nil
+			}, errInvalidTimezone),
+		),
+		forms.NewIntegerField("max_items", forms.Gte(0), forms.Lte(tasks.NewspaperMaxItems)),
+		forms.NewBooleanField("send_email"),
+		forms.NewBooleanField("publish_opds"),
+		forms.NewTextField("after_delivery", forms.Trim, forms.Choices(
+			forms.Choice(tr.Pgettext("newspaper", "Nothing"), ""),
+			forms.Choice(tr.Gettext("Mark as read"), tasks.BatchRead),
+			forms.Choice(tr.Gettext("Add to favorites"), tasks.BatchMark),
+			forms.Choice(tr.Gettext("Archive"), tasks.BatchArchive),
+		)),
+	)
+
+	return res
+}
+
+func (f *newspaperForm) setNewspaper(n *bookmarks.Newspaper) {
+	f.newspaper = n
+	f.Get("title").Set(n.Title)
+	for uid, id := range f.collections {
+		if id == n.CollectionID {
+			f.Get("collection").Set(uid)
+		}
+	}
+	f.Get("schedule").Set(n.Schedule)
+	f.Get("weekday").Set(n.Weekday)
+	f.Get("delivery_time").Set(n.DeliveryTime)
+	f.Get("timezone").Set(n.Timezone)
+	f.Get("max_items").Set(n.MaxItems)
+	f.Get("send_email").Set(n.SendEmail)
+	f.Get("publish_opds").Set(n.PublishOPDS)
+	f.Get("after_delivery").Set(n.AfterDelivery)
+}
+
+// Validate checks that the newspaper is delivered somewhere.
+func (f *newspaperForm) Validate() {
+	sendEmail := f.boolValue("send_email")
+	publishOPDS := f.boolValue("publish_opds")
+	if f.newspaper != nil {
+		if !f.Get("send_email").IsBound() {
+			sendEmail = f.newspaper.SendEmail
+		}
+		if !f.Get("publish_opds").IsBound() {
+			publishOPDS = f.newspaper.PublishOPDS
+		}
+	}
+
+	if !sendEmail && !publishOPDS {
+		f.AddErrors("", errNoDelivery)
+	}
+}
+
+// boolValue returns the value of a boolean field. It's false when
+// the field is null.
+func (f *newspaperForm) boolValue(name string) bool {
+	field := f.Get(name)
+	return !field.IsNil() && field.Value().(bool)
+}
+
+// createNewspaper creates a new newspaper. Its first issue is
+// delivered on the next scheduled time.
+func (f *newspaperForm) createNewspaper() (n *bookmarks.Newspaper, err error) {
+	if !f.IsBound() {
+		return nil, errors.New("form is not bound")
+	}
+
+	defer func() {
+		if err != nil {
+			f.AddErrors("", forms.ErrUnexpected)
+		}
+	}()
+
+	n = &bookmarks.Newspaper{
+		UserID:        &f.userID,
+		CollectionID:  f.collections[f.Get("collection").String()],
+		Title:         f.Get("title").String(),
+		Schedule:      f.Get("schedule").String(),
+		DeliveryTime:  f.Get("delivery_time").String(),
+		Timezone:      f.Get("timezone").String(),
+		SendEmail:     f.boolValue("send_email"),
+		PublishOPDS:   f.boolValue("publish_opds"),
+		AfterDelivery: f.Get("after_delivery").String(),
+	}
+	if !f.Get("weekday").IsNil() {
+		n.Weekday = f.Get("weekday").(forms.TypedField[int]).V()
+	}
+	if !f.Get("max_items").IsNil() {
+		n.MaxItems = f.Get("max_items").(forms.TypedField[int]).V()
+	}
+
+	err = bookmarks.Newspapers.Create(n)
+	return
+}
+
+// updateNewspaper updates a newspaper.
+func (f *newspaperForm) updateNewspaper(n *bookmarks.Newspaper) (res map[string]any, err error) {
+	if !f.IsBound() {
+		err = errors.New("form is not bound")
+		return
+	}
+
+	res = map[string]any{}
+	updateMap := map[string]any{}
+
+	for name, field := range f.Fields() {
+		if !field.IsBound() || field.IsNil() {
+			continue
+		}
+		switch name {
+		case "collection":
+			updateMap["collection_id"] = f.collections[field.String()]
+			res[name] = field.String()
+		default:
+			updateMap[name] = field.Value()
+			res[name] = field.Value()
+		}
+	}
+
+	if len(res) > 0 {
+		res["updated"] = time.Now()
+		updateMap["updated"] = res["updated"]
+		if err = n.Update(updateMap); err != nil {
+			f.AddErrors("", forms.ErrUnexpected)
+			return
+		}
+	}
+
+	res["id"] = n.UID
+	return
+}
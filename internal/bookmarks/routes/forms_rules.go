@@ -0,0 +1,198 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+var (
+	errRuleNoEvent  = forms.Gettext("select at least one event")
+	errRuleNoAction = forms.Gettext("select at least one action")
+)
+
+type ruleForm struct {
+	*forms.Form
+	userID int
+}
+
+func newRuleForm(tr forms.Translator, userID int) *ruleForm {
+	return &ruleForm{
+		Form: forms.Must(
+			forms.WithTranslator(context.Background(), tr),
+			forms.NewTextField("name", forms.Trim, forms.Required),
+			forms.NewBooleanField("is_enabled"),
+			forms.NewTextListField("events",
+				forms.Choices(
+					forms.Choice(tr.Gettext("Bookmark saved"), bookmarks.RuleEventSaved),
+					forms.Choice(tr.Gettext("Bookmark read"), bookmarks.RuleEventRead),
+				),
+				forms.Trim, forms.DiscardEmpty,
+			),
+
+			// Conditions
+			forms.NewTextField("search", forms.Trim),
+			forms.NewTextField("site", forms.Trim),
+			forms.NewTextField("domain", forms.Trim),
+			forms.NewTextListField("type", forms.Choices(
+				forms.Choice(tr.Gettext("Article"), "article"),
+				forms.Choice(tr.Gettext("Picture"), "photo"),
+				forms.Choice(tr.Gettext("Video"), "video"),
+				forms.Choice(tr.Gettext("PDF Document"), "pdf"),
+			), forms.Trim, forms.DiscardEmpty),
+			forms.NewIntegerField("min_words", forms.Gte(0)),
+			forms.NewIntegerField("max_words", forms.Gte(0)),
+
+			// Actions
+			forms.NewTextListField("labels", forms.Trim, forms.DiscardEmpty),
+			forms.NewBooleanField("is_marked"),
+			forms.NewBooleanField("is_archived"),
+			forms.NewIntegerField("read_progress", forms.Gte(0), forms.Lte(100)),
+			forms.NewBooleanField("send_epub"),
+		),
+		userID: userID,
+	}
+}
+
+func (f *ruleForm) setRule(r *bookmarks.Rule) {
+	f.Get("name").Set(r.Name)
+	f.Get("is_enabled").Set(r.IsEnabled)
+	f.Get("events").Set([]string(r.Events))
+
+	f.Get("search").Set(r.Conditions.Search)
+	f.Get("site").Set(r.Conditions.Site)
+	f.Get("domain").Set(r.Conditions.Domain)
+	f.Get("type").Set([]string(r.Conditions.Type))
+	if r.Conditions.MinWords > 0 {
+		f.Get("min_words").Set(r.Conditions.MinWords)
+	}
+	if r.Conditions.MaxWords > 0 {
+		f.Get("max_words").Set(r.Conditions.MaxWords)
+	}
+
+	f.Get("labels").Set([]string(r.Actions.Labels))
+	f.Get("is_marked").Set(r.Actions.IsMarked)
+	f.Get("is_archived").Set(r.Actions.IsArchived)
+	if r.Actions.ReadProgress != nil {
+		f.Get("read_progress").Set(*r.Actions.ReadProgress)
+	}
+	f.Get("send_epub").Set(r.Actions.SendEPUB)
+}
+
+// Validate checks that the rule runs on at least one event
+// and performs at least one action.
+func (f *ruleForm) Validate() {
+	if len(f.events()) == 0 {
+		f.AddErrors("events", errRuleNoEvent)
+	}
+
+	a := f.actions()
+	if len(a.Labels) == 0 && !a.IsMarked && !a.IsArchived && a.ReadProgress == nil && !a.SendEPUB {
+		f.AddErrors("", errRuleNoAction)
+	}
+}
+
+// events returns the selected events, in their declaration order.
+func (f *ruleForm) events() types.Strings {
+	res := types.Strings{}
+	if f.Get("events").IsNil() {
+		return res
+	}
+
+	for _, e := range bookmarks.RuleEvents {
+		if slices.Contains(f.Get("events").(forms.TypedField[[]string]).V(), e) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func (f *ruleForm) conditions() bookmarks.RuleConditions {
+	res := bookmarks.RuleConditions{
+		Search: f.Get("search").String(),
+		Site:   f.Get("site").String(),
+		Domain: f.Get("domain").String(),
+		Type:   types.Strings{},
+	}
+	if !f.Get("type").IsNil() {
+		res.Type = f.Get("type").(forms.TypedField[[]string]).V()
+	}
+	if !f.Get("min_words").IsNil() {
+		res.MinWords = f.Get("min_words").(forms.TypedField[int]).V()
+	}
+	if !f.Get("max_words").IsNil() {
+		res.MaxWords = f.Get("max_words").(forms.TypedField[int]).V()
+	}
+
+	return res
+}
+
+func (f *ruleForm) actions() bookmarks.RuleActions {
+	res := bookmarks.RuleActions{
+		Labels:     splitLabels(f.Get("labels")),
+		IsMarked:   f.boolValue("is_marked"),
+		IsArchived: f.boolValue("is_archived"),
+		SendEPUB:   f.boolValue("send_epub"),
+	}
+	if !f.Get("read_progress").IsNil() {
+		p := f.Get("read_progress").(forms.TypedField[int]).V()
+		res.ReadProgress = &p
+	}
+
+	return res
+}
+
+func (f *ruleForm) boolValue(name string) bool {
+	return !f.Get(name).IsNil() && f.Get(name).(forms.TypedField[bool]).V()
+}
+
+// createRule creates a new rule.
+func (f *ruleForm) createRule() (r *bookmarks.Rule, err error) {
+	if !f.IsBound() {
+		return nil, errors.New("form is not bound")
+	}
+
+	defer func() {
+		if err != nil {
+			f.AddErrors("", forms.ErrUnexpected)
+		}
+	}()
+
+	r = &bookmarks.Rule{
+		UserID:     &f.userID,
+		Name:       f.Get("name").String(),
+		IsEnabled:  f.boolValue("is_enabled"),
+		Events:     f.events(),
+		Conditions: f.conditions(),
+		Actions:    f.actions(),
+	}
+
+	err = bookmarks.Rules.Create(r)
+	return
+}
+
+// updateRule replaces all the rule's values.
+func (f *ruleForm) updateRule(r *bookmarks.Rule) (err error) {
+	if !f.IsBound() {
+		return errors.New("form is not bound")
+	}
+
+	r.Name = f.Get("name").String()
+	r.IsEnabled = f.boolValue("is_enabled")
+	r.Events = f.events()
+	r.Conditions = f.conditions()
+	r.Actions = f.actions()
+
+	if err = r.Save(); err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+	}
+	return
+}
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+const (
+	syncDefaultLimit = 100
+	syncMaxLimit     = 500
+)
+
+var errInvalidCursor = forms.Gettext("invalid cursor")
+
+// syncCursor is a client position in the bookmark, collection
+// and deletion log changes. Clients receive it as an opaque string.
+type syncCursor struct {
+	Bookmarks   syncPosition `json:"b"`
+	Collections syncPosition `json:"c"`
+	Deletions   int          `json:"d"`
+}
+
+// syncPosition is the last item sent in a list ordered
+// by update date and ID.
+type syncPosition struct {
+	Updated time.Time `json:"t"`
+	ID      int       `json:"i"`
+}
+
+// String returns the encoded cursor.
+func (c syncCursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func parseSyncCursor(s string) (c syncCursor, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &c); err != nil {
+		return
+	}
+	if c.Bookmarks.ID < 0 || c.Collections.ID < 0 || c.Deletions < 0 {
+		err = errors.New("negative cursor value")
+	}
+	return
+}
+
+type syncForm struct {
+	*forms.Form
+}
+
+func newSyncForm(tr forms.Translator) *syncForm {
+	return &syncForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("cursor",
+			forms.Trim,
+			forms.ValueValidatorFunc[string](func(f forms.Field, v string) error {
+				if f.IsNil() || v == "" {
+					return nil
+				}
+				if _, err := parseSyncCursor(v); err != nil {
+					return errInvalidCursor
+				}
+				return nil
+			}),
+		),
+		forms.NewIntegerField("limit", forms.Gte(1), forms.Lte(syncMaxLimit)),
+	)}
+}
+
+// limit returns the maximum number of items of each list.
+func (f *syncForm) limit() int {
+	if f.Get("limit").IsNil() {
+		return syncDefaultLimit
+	}
+	return f.Get("limit").(forms.TypedField[int]).V()
+}
+
+// cursor returns the given cursor. Without a cursor, the client
+// receives all the bookmarks and collections but none of the
+// past deletions.
+func (f *syncForm) cursor(userID int) (syncCursor, error) {
+	if v := f.Get("cursor").String(); v != "" {
+		return parseSyncCursor(v)
+	}
+
+	id, err := bookmarks.Deletions.LastID(userID)
+	return syncCursor{Deletions: id}, err
+}
@@ -52,6 +52,9 @@ func SetupRoutes(s *server.Server) {
 
 	// Publicly shared bookmark
 	s.AddRoute("/@b", newSharedViewsRouter(api))
+
+	// Collection digest unsubscribe links
+	s.AddRoute("/@digest", newDigestViewsRouter(api))
 }
 
 // newAPIRouter returns an apiRouter with all the routes set up.
@@ -68,10 +71,19 @@ func newAPIRouter(s *server.Server) *apiRouter {
 			api.withBookmarkList,
 		).Get("/", api.bookmarkList)
 		r.With(api.withBookmarkList).Get("/count", api.bookmarkCount)
+		r.Get("/sync", api.syncChanges)
+		r.Get("/lookup", api.bookmarkLookup)
+		r.Post("/lookup", api.bookmarkLookup)
+		r.Get("/duplicates", api.duplicateList)
 		r.With(api.withBookmark).Route("/{uid:[a-zA-Z0-9]{18,22}}", func(r chi.Router) {
 			r.Get("/", api.bookmarkInfo)
 			r.Get("/article", api.bookmarkArticle)
 			r.Get("/annotations", api.bookmarkAnnotations)
+			r.Route("/revisions", func(r chi.Router) {
+				r.With(api.withRevisionList).Get("/", api.revisionList)
+				r.Get("/diff", api.revisionDiff)
+				r.With(api.withRevision).Get("/{id:[a-zA-Z0-9]{18,22}}", api.revisionInfo)
+			})
 			r.With(api.srv.WithPermission("api:bookmarks", "export")).Route(
 				"/share", func(r chi.Router) {
 					r.With(
@@ -99,19 +111,29 @@ func newAPIRouter(s *server.Server) *apiRouter {
 			r.With(
 				api.withBookmark,
 			).Get("/{uid:[a-zA-Z0-9]{18,22}}/article.{format}", api.bookmarkExport)
+			r.With(
+				api.withBatch,
+				api.withBatchList,
+			).Get("/batch/{id:[a-zA-Z0-9]{18,22}}/export.{format}", api.bookmarkExport)
 		})
 
 		r.Route("/labels", func(r chi.Router) {
 			r.With(api.withLabelList).Get("/", api.labelList)
 			r.With(api.withLabel).Get("/{label}", api.labelInfo)
 		})
+
+		r.With(api.withTrashList).Get("/trash", api.trashList)
+		r.With(api.withBatch).Get("/batch/{id:[a-zA-Z0-9]{18,22}}", api.bookmarkBatchStatus)
 	})
 
 	r.With(api.srv.WithPermission("api:bookmarks", "write")).Group(func(r chi.Router) {
 		r.Post("/", api.bookmarkCreate)
+		r.With(api.withCollectionFilters).Post("/batch", api.bookmarkBatch)
+		r.Post("/duplicates/merge", api.duplicateMerge)
 		r.With(api.withBookmark).Group(func(r chi.Router) {
 			r.Patch("/{uid:[a-zA-Z0-9]{18,22}}", api.bookmarkUpdate)
 			r.Delete("/{uid:[a-zA-Z0-9]{18,22}}", api.bookmarkDelete)
+			r.Post("/{uid:[a-zA-Z0-9]{18,22}}/refresh", api.bookmarkRefresh)
 			r.Post("/{uid:[a-zA-Z0-9]{18,22}}/annotations", api.annotationCreate)
 			r.Patch(
 				"/{uid:[a-zA-Z0-9]{18,22}}/annotations/{id:[a-zA-Z0-9]{18,22}}",
@@ -124,6 +146,12 @@ func newAPIRouter(s *server.Server) *apiRouter {
 		})
 		r.With(api.withLabel).Patch("/labels/{label}", api.labelUpdate)
 		r.With(api.withLabel).Delete("/labels/{label}", api.labelDelete)
+
+		r.Delete("/trash", api.trashEmpty)
+		r.With(api.withTrashItem).Group(func(r chi.Router) {
+			r.Post("/trash/{kind:(bookmark|collection|label)}/{uid:[a-zA-Z0-9]{18,22}}/restore", api.trashRestore)
+			r.Delete("/trash/{kind:(bookmark|collection|label)}/{uid:[a-zA-Z0-9]{18,22}}", api.trashPurge)
+		})
 	})
 
 	// Collection API
@@ -142,6 +170,41 @@ func newAPIRouter(s *server.Server) *apiRouter {
 			})
 	})
 
+	// Feed API
+	r.Route("/feeds", func(r chi.Router) {
+		r.With(api.srv.WithPermission("api:bookmarks:feeds", "read")).
+			Group(func(r chi.Router) {
+				r.With(api.withFeedList).Get("/", api.feedList)
+				r.With(api.withFeed).Get("/{uid:[a-zA-Z0-9]{18,22}}", api.feedInfo)
+			})
+
+		r.With(api.srv.WithPermission("api:bookmarks:feeds", "write")).
+			Group(func(r chi.Router) {
+				r.Post("/", api.feedCreate)
+				r.With(api.withFeed).Patch("/{uid:[a-zA-Z0-9]{18,22}}", api.feedUpdate)
+				r.With(api.withFeed).Delete("/{uid:[a-zA-Z0-9]{18,22}}", api.feedDelete)
+				r.With(api.withFeed).Post("/{uid:[a-zA-Z0-9]{18,22}}/refresh", api.feedRefresh)
+			})
+	})
+
+	// Newspaper API
+	r.Route("/newspapers", func(r chi.Router) {
+		r.With(api.srv.WithPermission("api:bookmarks:newspapers", "read")).
+			Group(func(r chi.Router) {
+				r.With(api.withNewspaperList).Get("/", api.newspaperList)
+				r.With(api.withNewspaper).Get("/{uid:[a-zA-Z0-9]{18,22}}", api.newspaperInfo)
+				r.With(api.withNewspaper).Get("/{uid:[a-zA-Z0-9]{18,22}}/issue.epub", api.newspaperIssue)
+			})
+
+		r.With(api.srv.WithPermission("api:bookmarks:newspapers", "write")).
+			Group(func(r chi.Router) {
+				r.Post("/", api.newspaperCreate)
+				r.With(api.withNewspaper).Patch("/{uid:[a-zA-Z0-9]{18,22}}", api.newspaperUpdate)
+				r.With(api.withNewspaper).Delete("/{uid:[a-zA-Z0-9]{18,22}}", api.newspaperDelete)
+				r.With(api.withNewspaper).Post("/{uid:[a-zA-Z0-9]{18,22}}/deliver", api.newspaperDeliver)
+			})
+	})
+
 	// Import API
 	r.Route("/import", func(r chi.Router) {
 		r.With(api.srv.WithPermission("api:bookmarks:import", "write")).Group(func(r chi.Router) {
@@ -170,7 +233,7 @@ func newViewsRouter(api *apiRouter) *viewsRouter {
 				api.withBookmarkFilters,
 				api.withBookmarkOrdering,
 				api.withBookmarkList,
-			).Get("/{filter:(unread|archives|favorites|articles|videos|pictures)}", h.bookmarkList)
+			).Get("/{filter:(unread|archives|favorites|articles|videos|pictures|documents)}", h.bookmarkList)
 
 			r.With(
 				api.srv.WithCustomErrorTemplate(404, "/bookmarks/bookmark_missing"),
@@ -198,6 +261,7 @@ func newViewsRouter(api *apiRouter) *viewsRouter {
 			r.With(api.withAnnotationList).Route("/highlights", func(r chi.Router) {
 				r.Get("/", h.annotationList)
 			})
+			r.With(api.withTrashList).Get("/trash", h.trashList)
 		})
 	})
 
@@ -212,6 +276,12 @@ func newViewsRouter(api *apiRouter) *viewsRouter {
 				r.Post("/labels/{label}", h.labelInfo)
 				r.Post("/labels/{label}/delete", h.labelDelete)
 			})
+			r.Post("/batch", h.bookmarkBatch)
+			r.Post("/trash/empty", h.trashEmpty)
+			r.With(api.withTrashItem).Group(func(r chi.Router) {
+				r.Post("/trash/{kind:(bookmark|collection|label)}/{uid:[a-zA-Z0-9]{18,22}}/restore", h.trashRestore)
+				r.Post("/trash/{kind:(bookmark|collection|label)}/{uid:[a-zA-Z0-9]{18,22}}/delete", h.trashPurge)
+			})
 		})
 	})
 
@@ -247,6 +317,72 @@ func newViewsRouter(api *apiRouter) *viewsRouter {
 		})
 	})
 
+	// Feed views
+	r.Route("/feeds", func(r chi.Router) {
+		r.With(h.srv.WithPermission("bookmarks:feeds", "read")).Group(func(r chi.Router) {
+			r.With(h.withBaseContext).Group(func(r chi.Router) {
+				r.With(api.withFeedList).Get("/", h.feedList)
+				r.With(api.withFeed).Get("/{uid:[a-zA-Z0-9]{18,22}}", h.feedInfo)
+			})
+		})
+
+		r.With(h.srv.WithPermission("bookmarks:feeds", "write")).Group(func(r chi.Router) {
+			r.With(h.withBaseContext).Group(func(r chi.Router) {
+				r.With(api.withFeedList).Post("/", h.feedList)
+				r.With(api.withFeed).Group(func(r chi.Router) {
+					r.Post("/{uid:[a-zA-Z0-9]{18,22}}", h.feedInfo)
+					r.Post("/{uid:[a-zA-Z0-9]{18,22}}/refresh", h.feedRefresh)
+					r.Post("/{uid:[a-zA-Z0-9]{18,22}}/delete", h.feedDelete)
+				})
+			})
+		})
+	})
+
+	// Newspaper views
+	r.Route("/newspapers", func(r chi.Router) {
+		r.With(h.srv.WithPermission("bookmarks:newspapers", "read")).Group(func(r chi.Router) {
+			r.With(h.withBaseContext).Group(func(r chi.Router) {
+				r.With(api.withNewspaperList).Get("/", h.newspaperList)
+				r.With(api.withNewspaper).Get("/{uid:[a-zA-Z0-9]{18,22}}", h.newspaperInfo)
+			})
+		})
+
+		r.With(h.srv.WithPermission("bookmarks:newspapers", "write")).Group(func(r chi.Router) {
+			r.With(h.withBaseContext).Group(func(r chi.Router) {
+				r.With(api.withNewspaperList).Post("/", h.newspaperList)
+				r.With(api.withNewspaper).Group(func(r chi.Router) {
+					r.Post("/{uid:[a-zA-Z0-9]{18,22}}", h.newspaperInfo)
+					r.Post("/{uid:[a-zA-Z0-9]{18,22}}/deliver", h.newspaperDeliver)
+					r.Post("/{uid:[a-zA-Z0-9]{18,22}}/delete", h.newspaperDelete)
+				})
+			})
+		})
+	})
+
+	// Rule views
+	r.Route("/rules", func(r chi.Router) {
+		r.With(h.srv.WithPermission("bookmarks:rules", "read")).Group(func(r chi.Router) {
+			r.With(h.withBaseContext).Group(func(r chi.Router) {
+				r.With(h.withRuleList).Get("/", h.ruleList)
+				r.With(h.withRule).Group(func(r chi.Router) {
+					r.Get("/{uid:[a-zA-Z0-9]{18,22}}", h.ruleInfo)
+					r.Get("/{uid:[a-zA-Z0-9]{18,22}}/test", h.ruleTest)
+				})
+			})
+		})
+
+		r.With(h.srv.WithPermission("bookmarks:rules", "write")).Group(func(r chi.Router) {
+			r.With(h.withBaseContext).Group(func(r chi.Router) {
+				r.With(h.withRuleList).Post("/", h.ruleList)
+				r.With(h.withRule).Group(func(r chi.Router) {
+					r.Post("/{uid:[a-zA-Z0-9]{18,22}}", h.ruleInfo)
+					r.Post("/{uid:[a-zA-Z0-9]{18,22}}/apply", h.ruleApply)
+					r.Post("/{uid:[a-zA-Z0-9]{18,22}}/delete", h.ruleDelete)
+				})
+			})
+		})
+	})
+
 	// Import views
 	r.Route("/import", func(r chi.Router) {
 		r.With(h.srv.WithPermission("bookmarks:import", "write")).Group(func(r chi.Router) {
@@ -290,12 +426,13 @@ func mediaRoutes(_ *server.Server) http.Handler {
 			r2.URL.Path = p
 
 			zipfile := path.Join(
-				bookmarks.StoragePath(),
 				chi.URLParam(r, "prefix"),
 				chi.URLParam(r, "fname")+".zip",
 			)
 
-			fs := zipfs.HTTPZipFile(zipfile)
+			fs := zipfs.HTTPZipOpener(func() (zipfs.File, error) {
+				return bookmarks.Storage().Open(zipfile)
+			})
 			fs.ServeHTTP(w, r2, func(w http.ResponseWriter, status int) {
 				// Anything that comes from a bookmark resource needs a strict policy
 				// We allow unsafe-inline for SVG embed styles
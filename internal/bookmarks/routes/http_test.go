@@ -119,6 +119,19 @@ func TestPermissions(t *testing.T) {
 					}
 				},
 			},
+			RequestTest{
+				Target: "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}/article.kepub",
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 200)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					case "":
+						r.AssertStatus(t, 401)
+					}
+				},
+			},
 			RequestTest{
 				Target: "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}/article.md",
 				Assert: func(t *testing.T, r *Response) {
@@ -242,6 +255,88 @@ func TestPermissions(t *testing.T) {
 					}
 				},
 			},
+			RequestTest{
+				Target: "/api/bookmarks/trash",
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 200)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					case "":
+						r.AssertStatus(t, 401)
+					}
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/bookmarks/batch",
+				JSON:   map[string]string{},
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 422)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					case "":
+						r.AssertStatus(t, 401)
+					}
+				},
+			},
+			RequestTest{
+				Target: "/api/bookmarks/lookup?url=https://example.net/",
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 200)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					case "":
+						r.AssertStatus(t, 401)
+					}
+				},
+			},
+			RequestTest{
+				Target: "/api/bookmarks/duplicates",
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 200)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					case "":
+						r.AssertStatus(t, 401)
+					}
+				},
+			},
+			RequestTest{
+				Target: "/api/bookmarks/batch/RuXBpzio59ktWTEHDodLPU",
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 404)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					case "":
+						r.AssertStatus(t, 401)
+					}
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/api/bookmarks/trash/bookmark/RuXBpzio59ktWTEHDodLPU/restore",
+				JSON:   map[string]string{},
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 404)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					case "":
+						r.AssertStatus(t, 401)
+					}
+				},
+			},
 			RequestTest{
 				Method: "POST",
 				Target: "/api/bookmarks/import/text",
@@ -409,6 +504,108 @@ func TestPermissions(t *testing.T) {
 					}
 				},
 			},
+			RequestTest{
+				Target: "/bookmarks/feeds",
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 200)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					default:
+						r.AssertStatus(t, 303)
+						r.AssertRedirect(t, "/login")
+					}
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/bookmarks/feeds",
+				Form:   url.Values{},
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 422)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					default:
+						r.AssertStatus(t, 303)
+						r.AssertRedirect(t, "/login")
+					}
+				},
+			},
+			RequestTest{
+				Target: "/bookmarks/feeds/RuXBpzio59ktWTEHDodLPU",
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 404)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					default:
+						r.AssertStatus(t, 303)
+						r.AssertRedirect(t, "/login")
+					}
+				},
+			},
+			RequestTest{
+				Target: "/bookmarks/rules",
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 200)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					default:
+						r.AssertStatus(t, 303)
+						r.AssertRedirect(t, "/login")
+					}
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/bookmarks/rules",
+				Form:   url.Values{},
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 422)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					default:
+						r.AssertStatus(t, 303)
+						r.AssertRedirect(t, "/login")
+					}
+				},
+			},
+			RequestTest{
+				Target: "/bookmarks/rules/RuXBpzio59ktWTEHDodLPU",
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 404)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					default:
+						r.AssertStatus(t, 303)
+						r.AssertRedirect(t, "/login")
+					}
+				},
+			},
+			RequestTest{
+				Target: "/bookmarks/trash",
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 200)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					default:
+						r.AssertStatus(t, 303)
+						r.AssertRedirect(t, "/login")
+					}
+				},
+			},
 			RequestTest{
 				Target: "/bookmarks/highlights",
 				Assert: func(t *testing.T, r *Response) {
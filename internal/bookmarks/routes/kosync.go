@@ -0,0 +1,275 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/converter"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+	"codeberg.org/readeck/readeck/pkg/koreader"
+)
+
+const (
+	// kosyncXPointer is the XPointer of the article in a bookmark's e-book.
+	// It has only one chapter and the article is its main element.
+	kosyncXPointer = "/body/DocFragment[1]/body/main"
+
+	// kosyncDevice and kosyncDeviceID identify the progress
+	// made in Readeck.
+	kosyncDevice   = "Readeck"
+	kosyncDeviceID = "readeck"
+)
+
+type kosyncRouter struct {
+	chi.Router
+	*apiRouter
+}
+
+// kosyncProgress is a document reading progress, as KOReader sends
+// and receives it.
+type kosyncProgress struct {
+	Document   string  `json:"document"`
+	Progress   string  `json:"progress"`
+	Percentage float64 `json:"percentage"`
+	Device     string  `json:"device"`
+	DeviceID   string  `json:"device_id"`
+	Timestamp  int64   `json:"timestamp,omitempty"`
+}
+
+// kosyncWriter is an [http.ResponseWriter] that computes the KOReader
+// digest of the response body.
+type kosyncWriter struct {
+	http.ResponseWriter
+	digest *koreader.PartialMD5
+}
+
+func (w kosyncWriter) Write(p []byte) (int, error) {
+	w.digest.Write(p) //nolint:errcheck
+	return w.ResponseWriter.Write(p)
+}
+
+// NewKosyncRouteHandler returns a chi Router handler with the
+// KOReader progress synchronization routes for the bookmark domain.
+// A document is a bookmark when its e-book was exported from Readeck.
+func NewKosyncRouteHandler(s *server.Server) func(r chi.Router) {
+	return func(r chi.Router) {
+		h := &kosyncRouter{r, newAPIRouter(s)}
+
+		r.With(h.srv.WithPermission("api:bookmarks", "read")).
+			Get("/syncs/progress/{document}", h.progressInfo)
+		r.With(h.srv.WithPermission("api:bookmarks", "write")).
+			Put("/syncs/progress", h.progressUpdate)
+	}
+}
+
+// KosyncError sends an error the way KOReader expects it.
+func KosyncError(w http.ResponseWriter, status int, code int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck
+		"code":    code,
+		"message": message,
+	})
+}
+
+// progressInfo returns the progress of a document. When the document is
+// a bookmark whose progress changed in Readeck since the last
+// synchronization, it returns the bookmark's progress.
+func (h *kosyncRouter) progressInfo(w http.ResponseWriter, r *http.Request) {
+	d, err := bookmarks.KosyncDocuments.GetDocument(auth.GetRequestUser(r).ID, chi.URLParam(r, "document"))
+	if errors.Is(err, bookmarks.ErrKosyncDocumentNotFound) {
+		h.srv.Render(w, r, http.StatusOK, struct{}{})
+		return
+	}
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	res := kosyncProgress{
+		Document:   d.Document,
+		Progress:   d.Progress,
+		Percentage: d.Percentage,
+		Device:     d.Device,
+		DeviceID:   d.DeviceID,
+		Timestamp:  d.Updated.Unix(),
+	}
+
+	if d.BookmarkID != nil {
+		b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(*d.BookmarkID))
+		if err != nil && !errors.Is(err, bookmarks.ErrBookmarkNotFound) {
+			h.srv.Error(w, r, err)
+			return
+		}
+		if b != nil && b.ReadProgress != kosyncReadProgress(d.Percentage) && b.Updated.After(d.Updated) {
+			res.Progress = kosyncXPointer
+			if root := kosyncArticle(b); root != nil {
+				res.Progress = koreader.ToXPointer(root, kosyncXPointer, kosyncAnchor(root, b))
+			}
+			res.Percentage = float64(b.ReadProgress) / 100
+			res.Device = kosyncDevice
+			res.DeviceID = kosyncDeviceID
+			res.Timestamp = b.Updated.Unix()
+		}
+	}
+
+	if res.Progress == "" {
+		// The document was only exported
+		h.srv.Render(w, r, http.StatusOK, struct{}{})
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, res)
+}
+
+// progressUpdate saves the progress of a document. When the document is
+// a bookmark, it updates the bookmark's reading progress.
+func (h *kosyncRouter) progressUpdate(w http.ResponseWriter, r *http.Request) {
+	var p kosyncProgress
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil ||
+		p.Progress == "" || p.Percentage < 0 || p.Percentage > 1 {
+		KosyncError(w, http.StatusForbidden, 2003, "Invalid request")
+		return
+	}
+	if p.Document == "" {
+		KosyncError(w, http.StatusForbidden, 2004, "Field 'document' not provided.")
+		return
+	}
+
+	userID := auth.GetRequestUser(r).ID
+	d, err := bookmarks.KosyncDocuments.GetDocument(userID, p.Document)
+	if errors.Is(err, bookmarks.ErrKosyncDocumentNotFound) {
+		d = &bookmarks.KosyncDocument{UserID: userID, Document: p.Document}
+	} else if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	d.Progress = p.Progress
+	d.Percentage = p.Percentage
+	d.Device = p.Device
+	d.DeviceID = p.DeviceID
+	if d.ID == 0 {
+		err = bookmarks.KosyncDocuments.Create(d)
+	} else {
+		err = d.Save()
+	}
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	if d.BookmarkID != nil {
+		if err = h.updateBookmark(r, *d.BookmarkID, p); err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+	}
+
+	h.srv.Render(w, r, http.StatusOK, map[string]any{
+		"document":  d.Document,
+		"timestamp": d.Updated.Unix(),
+	})
+}
+
+// updateBookmark sets a bookmark's reading progress and position
+// from a KOReader progress.
+func (h *kosyncRouter) updateBookmark(r *http.Request, id int, p kosyncProgress) error {
+	b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(id))
+	if errors.Is(err, bookmarks.ErrBookmarkNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	progress := kosyncReadProgress(p.Percentage)
+	anchor := ""
+	if progress > 0 && progress < 100 {
+		if root := kosyncArticle(b); root != nil {
+			anchor = koreader.ToSelector(root, kosyncXPointer, p.Progress)
+		}
+	}
+	if progress == b.ReadProgress && anchor == b.ReadAnchor {
+		return nil
+	}
+
+	f := newUpdateForm(h.srv.Locale(r))
+	forms.BindValues(f, url.Values{
+		"read_progress": {strconv.Itoa(progress)},
+		"read_anchor":   {anchor},
+	})
+	_, err = f.update(b)
+	return err
+}
+
+// linkKosyncDocuments links a bookmark to the digests KOReader gives
+// to its exported e-book.
+func (api *apiRouter) linkKosyncDocuments(w kosyncWriter, b *bookmarks.Bookmark) {
+	documents := []string{w.digest.Sum()}
+	if _, params, err := mime.ParseMediaType(w.Header().Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		documents = append(documents, koreader.FilenameMD5(params["filename"]))
+	}
+
+	if err := bookmarks.KosyncDocuments.Link(b, documents...); err != nil {
+		slog.Error("kosync document", slog.Any("err", err))
+	}
+}
+
+// kosyncReadProgress converts a KOReader percentage to a reading progress.
+func kosyncReadProgress(percentage float64) int {
+	return int(math.Max(0, math.Min(100, math.Round(percentage*100))))
+}
+
+// kosyncArticle returns the root node of a bookmark's article.
+// It's the content of the e-book's main element.
+func kosyncArticle(b *bookmarks.Bookmark) *html.Node {
+	buf, err := converter.HTMLConverter{}.GetArticle(context.Background(), b)
+	if err != nil || buf.Len() == 0 {
+		return nil
+	}
+	doc, err := html.Parse(buf)
+	if err != nil {
+		return nil
+	}
+	return dom.QuerySelector(doc, "body")
+}
+
+// kosyncAnchor returns a bookmark's reading position. A finished
+// article's position is its last element.
+func kosyncAnchor(root *html.Node, b *bookmarks.Bookmark) string {
+	if b.ReadProgress < 100 {
+		return b.ReadAnchor
+	}
+	var last *html.Node
+	n := 0
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			last = c
+			n++
+		}
+	}
+	if last != nil {
+		return last.Data + ":nth-child(" + strconv.Itoa(n) + ")"
+	}
+	return ""
+}
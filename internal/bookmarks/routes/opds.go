@@ -6,15 +6,18 @@ package routes
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 
 	"codeberg.org/readeck/readeck/internal/auth"
 	"codeberg.org/readeck/readeck/internal/bookmarks"
 	"codeberg.org/readeck/readeck/internal/opds/catalog"
 	"codeberg.org/readeck/readeck/internal/server"
 	"codeberg.org/readeck/readeck/pkg/base58"
+	"codeberg.org/readeck/readeck/pkg/epub"
 	"codeberg.org/readeck/readeck/pkg/opds"
 )
 
@@ -33,9 +36,14 @@ func NewOPDSRouteHandler(s *server.Server) func(r chi.Router) {
 			r.With(h.withCollectionFilters, h.withBookmarkList).Get("/all", h.bookmarkList)
 			r.With(h.withBookmarkFilters, h.withBookmarkList).
 				Get("/{filter:(unread|archives|favorites)}", h.bookmarkList)
+			r.With(h.withLabelList).Get("/labels", h.labelList)
+			r.With(h.withLabel, h.withBookmarkList).Get("/labels/{label}", h.bookmarkList)
 			r.With(h.withColletionList).Get("/collections", h.collectionList)
 			r.With(h.withCollection).Get("/collections/{uid}", h.collectionInfo)
 		})
+
+		r.With(h.srv.WithPermission("api:bookmarks:newspapers", "read")).
+			Get("/newspapers", h.newspaperList)
 	}
 }
 
@@ -51,17 +59,21 @@ func (h *opdsRouter) bookmarkList(w http.ResponseWriter, r *http.Request) {
 	bl := r.Context().Value(ctxBookmarkListKey{}).(bookmarkList)
 	tr := h.srv.Locale(r)
 
+	title := tr.Gettext("Readeck Bookmarks")
+	if label, ok := r.Context().Value(ctxLabelKey{}).(string); ok {
+		title = tr.Gettext("Readeck Label: %s", label)
+	} else if item, ok := r.Context().Value(ctxCollectionKey{}).(*bookmarks.Collection); ok {
+		title = tr.Gettext("Readeck Collection: %s", item.Name)
+	}
+
 	c := catalog.New(h.srv, r,
 		catalog.WithFeedType(opds.OPDSTypeAcquisistion),
-		catalog.WithTitle(tr.Gettext("Readeck Bookmarks")),
+		catalog.WithTitle(title),
 		catalog.WithURL(h.srv.AbsoluteURL(r).String()),
 		catalog.WithUpdated(lastUpdate),
+		catalog.WithPagination(h.srv, r, bl.Pagination),
+		h.withFacets(r),
 		func(feed *opds.Feed) {
-			links := h.srv.GetPaginationLinks(r, bl.Pagination)
-			for _, x := range links {
-				catalog.WithLink(opds.OPDSTypeAcquisistion, x.Rel, x.URL)(feed)
-			}
-
 			for _, b := range bl.items {
 				id, _ := base58.DecodeUUID(b.UID)
 				issued := b.Created
@@ -74,6 +86,48 @@ func (h *opdsRouter) bookmarkList(w http.ResponseWriter, r *http.Request) {
 					h.srv.AbsoluteURL(r, "/api/bookmarks", b.UID, "article.epub").String(),
 					issued, b.Created, b.Updated,
 					b.SiteName, b.Lang, b.Description,
+					catalog.WithAcquisitionLink(
+						epub.KepubContentType,
+						h.srv.AbsoluteURL(r, "/api/bookmarks", b.UID, "article.kepub").String(),
+						tr.Gettext("Kobo ebook"),
+					),
+				)(feed)
+			}
+		},
+	)
+
+	if err := c.Render(w, r); err != nil {
+		h.srv.Error(w, r, err)
+	}
+}
+
+func (h *opdsRouter) labelList(w http.ResponseWriter, r *http.Request) {
+	lastUpdate, err := bookmarks.Bookmarks.GetLastUpdate(
+		goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+	)
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	labels := r.Context().Value(ctxLabelListKey{}).([]*labelItem)
+	tr := h.srv.Locale(r)
+
+	c := catalog.New(h.srv, r,
+		catalog.WithFeedType(opds.OPDSTypeNavigation),
+		catalog.WithTitle(tr.Gettext("Readeck Bookmark Labels")),
+		catalog.WithURL(h.srv.AbsoluteURL(r).String()),
+		catalog.WithUpdated(lastUpdate),
+		func(feed *opds.Feed) {
+			// The label is escaped as a query value, like in the API
+			base := h.srv.AbsoluteURL(r, "./").String()
+			for _, item := range labels {
+				catalog.WithNavEntry(
+					string(item.Name), lastUpdate,
+					base+item.Name.Path(),
+					func(e *opds.Entry) {
+						e.Content.Content = tr.Ngettext("%d bookmark", "%d bookmarks", item.Count, item.Count)
+					},
 				)(feed)
 			}
 		},
@@ -101,16 +155,21 @@ func (h *opdsRouter) collectionList(w http.ResponseWriter, r *http.Request) {
 		catalog.WithTitle(tr.Gettext("Readeck Bookmark Collections")),
 		catalog.WithURL(h.srv.AbsoluteURL(r).String()),
 		catalog.WithUpdated(lastUpdate),
+		catalog.WithPagination(h.srv, r, cl.Pagination),
 		func(feed *opds.Feed) {
-			links := h.srv.GetPaginationLinks(r, cl.Pagination)
-			for _, x := range links {
-				catalog.WithLink(opds.OPDSTypeAcquisistion, x.Rel, x.URL)(feed)
-			}
-
 			for _, item := range cl.items {
 				catalog.WithNavEntry(
 					item.Name, lastUpdate,
 					h.srv.AbsoluteURL(r, ".", item.UID).String(),
+					func(e *opds.Entry) {
+						// Download the collection without browsing it
+						e.Links = append(e.Links, opds.Link{
+							Rel:      "http://opds-spec.org/acquisition",
+							TypeLink: "application/epub+zip",
+							Href:     h.collectionEbookURL(r, item),
+							Title:    tr.Gettext("Collection ebook - %s", item.Name),
+						})
+					},
 				)(feed)
 			}
 		},
@@ -142,7 +201,7 @@ func (h *opdsRouter) collectionInfo(w http.ResponseWriter, r *http.Request) {
 			id, _ := base58.DecodeUUID(item.UID)
 			catalog.WithBookEntry(
 				id, tr.Gettext("Collection ebook - %s", item.Name),
-				h.srv.AbsoluteURL(r, "/api/bookmarks", "export.epub?collection="+item.UID+"&sort=created").String(),
+				h.collectionEbookURL(r, item),
 				item.Created, item.Created, item.Updated,
 				"Readeck", "", "",
 			)(feed)
@@ -158,3 +217,101 @@ func (h *opdsRouter) collectionInfo(w http.ResponseWriter, r *http.Request) {
 		h.srv.Error(w, r, err)
 	}
 }
+
+// newspaperList returns an acquisition feed with the latest issue
+// of every newspaper published in the catalog.
+func (h *opdsRouter) newspaperList(w http.ResponseWriter, r *http.Request) {
+	var items []*bookmarks.Newspaper
+	if err := bookmarks.Newspapers.Query().
+		Where(
+			goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+			goqu.C("publish_opds").IsTrue(),
+			goqu.C("issue_date").IsNotNull(),
+		).
+		Order(goqu.I("issue_date").Desc()).
+		ScanStructs(&items); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	lastUpdate := time.Now()
+	if len(items) > 0 {
+		lastUpdate = *items[0].IssueDate
+	}
+
+	tr := h.srv.Locale(r)
+
+	c := catalog.New(h.srv, r,
+		catalog.WithFeedType(opds.OPDSTypeAcquisistion),
+		catalog.WithTitle(tr.Gettext("Readeck Newspapers")),
+		catalog.WithURL(h.srv.AbsoluteURL(r).String()),
+		catalog.WithUpdated(lastUpdate),
+		func(feed *opds.Feed) {
+			for _, n := range items {
+				// Every issue is a new book
+				date := n.IssueDate.In(n.Location())
+				id := uuid.NewSHA1(uuid.NameSpaceURL, []byte(n.UID+"/"+date.Format(time.DateOnly)))
+				catalog.WithBookEntry(
+					id, n.Title+" - "+date.Format(time.DateOnly),
+					h.srv.AbsoluteURL(r, "/api/bookmarks/newspapers", n.UID, "issue.epub").String(),
+					date, date, date,
+					"Readeck", "", tr.Ngettext("%d article", "%d articles", len(n.IssueItems), len(n.IssueItems)),
+				)(feed)
+			}
+		},
+	)
+
+	if err := c.Render(w, r); err != nil {
+		h.srv.Error(w, r, err)
+	}
+}
+
+// collectionEbookURL returns the URL of a collection's ebook.
+func (h *opdsRouter) collectionEbookURL(r *http.Request, item *bookmarks.Collection) string {
+	return h.srv.AbsoluteURL(r, "/api/bookmarks", "export.epub?collection="+item.UID+"&sort=created").String()
+}
+
+// withFacets adds the facet links of a bookmark feed. A facet sets
+// the read status or the type filter on the current feed.
+func (h *opdsRouter) withFacets(r *http.Request) func(*opds.Feed) {
+	tr := h.srv.Locale(r)
+	groups := []struct {
+		name    string
+		title   string
+		choices [][2]string
+	}{
+		{"read_status", tr.Gettext("Read status"), [][2]string{
+			{tr.Pgettext("status", "Unviewed"), filtersReadStatusUnread},
+			{tr.Pgettext("status", "In-Progress"), filtersReadStatusReading},
+			{tr.Pgettext("status", "Completed"), filtersReadStatusRead},
+		}},
+		{"type", tr.Gettext("Type"), [][2]string{
+			{tr.Gettext("Article"), "article"},
+			{tr.Gettext("Picture"), "photo"},
+			{tr.Gettext("Video"), "video"},
+			{tr.Gettext("PDF Document"), "pdf"},
+		}},
+	}
+
+	facetURL := func(name, value string) string {
+		u := h.srv.AbsoluteURL(r)
+		q := u.Query()
+		q.Del("offset")
+		q.Del(name)
+		if value != "" {
+			q.Set(name, value)
+		}
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+
+	return func(feed *opds.Feed) {
+		for _, g := range groups {
+			current := r.URL.Query().Get(g.name)
+			catalog.WithFacet(g.title, tr.Gettext("All"), facetURL(g.name, ""), current == "")(feed)
+			for _, c := range g.choices {
+				catalog.WithFacet(g.title, c[0], facetURL(g.name, c[1]), current == c[1])(feed)
+			}
+		}
+	}
+}
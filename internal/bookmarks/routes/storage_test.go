@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+	"codeberg.org/readeck/readeck/pkg/storage"
+	"codeberg.org/readeck/readeck/pkg/storage/s3test"
+)
+
+func TestS3Storage(t *testing.T) {
+	app := NewTestApp(t)
+	srv := s3test.NewServer("readeck", "access-key")
+	defer func() {
+		srv.Close()
+		configs.Config.Storage.Type = "local"
+		require.NoError(t, bookmarks.InitStorage())
+		app.Close(t)
+	}()
+
+	configs.Config.Storage.Type = "s3"
+	configs.Config.Storage.S3.Endpoint = srv.URL
+	configs.Config.Storage.S3.Bucket = "readeck"
+	configs.Config.Storage.S3.AccessKey = "access-key"
+	configs.Config.Storage.S3.SecretKey = "secret"
+	configs.Config.Storage.S3.PathStyle = true
+
+	// Move the bookmark files to the object storage
+	local := bookmarks.Storage()
+	require.NoError(t, bookmarks.InitStorage())
+	err := local.Walk("", func(info storage.FileInfo) error {
+		return storage.Copy(bookmarks.Storage(), info.Name, local, info.Name)
+	})
+	require.NoError(t, err)
+	require.NoError(t, local.RemoveAll(app.Users["user"].Bookmarks[0].UID[:2]))
+
+	b := app.Users["user"].Bookmarks[0]
+	require.Contains(t, srv.Keys(), b.GetFileName())
+
+	client := NewClient(t, app)
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Target:       "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}/x/props.json",
+			ExpectStatus: 200,
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}/x/nope.json",
+			ExpectStatus: 404,
+		},
+		RequestTest{
+			Target:       "/api/bookmarks/{{(index .User.Bookmarks 0).UID}}/article",
+			ExpectStatus: 200,
+		},
+		RequestTest{
+			Target:       "/bm/{{(slice (index .User.Bookmarks 0).UID 0 2)}}/{{(index .User.Bookmarks 0).UID}}/img/icon.png",
+			ExpectStatus: 200,
+		},
+	)
+
+	// Zip entries are read with range requests
+	ranges := 0
+	for _, x := range srv.Requests() {
+		if x.Key == b.GetFileName() && x.Range != "" {
+			ranges++
+		}
+	}
+	require.Positive(t, ranges)
+
+	rev, err := b.NewRevision()
+	require.NoError(t, err)
+	require.Contains(t, srv.Keys(), rev.FilePath)
+
+	require.NoError(t, b.Delete())
+	require.NotContains(t, srv.Keys(), b.GetFileName())
+	require.NotContains(t, srv.Keys(), rev.FilePath)
+}
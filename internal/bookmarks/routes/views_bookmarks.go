@@ -62,6 +62,14 @@ func (h *viewsRouter) bookmarkList(w http.ResponseWriter, r *http.Request) {
 			if b, err := f.createBookmark(); err != nil {
 				h.srv.Log(r).Error("", slog.Any("err", err))
 			} else {
+				tr := h.srv.Locale(r)
+				switch {
+				case f.merged:
+					h.srv.AddFlash(w, r, "info", tr.Gettext("This link is already in your bookmarks."))
+				case len(f.duplicates) > 0:
+					h.srv.AddFlash(w, r, "info", tr.Gettext("You already saved this link. It's now twice in your bookmarks."))
+				}
+
 				redir := []string{"/bookmarks"}
 				if h.srv.IsTurboRequest(r) {
 					redir = append(redir, "unread")
@@ -89,6 +97,8 @@ func (h *viewsRouter) bookmarkList(w http.ResponseWriter, r *http.Request) {
 	bl.Items = make([]bookmarkItem, len(bl.items))
 	for i, item := range bl.items {
 		bl.Items[i] = newBookmarkItem(h.srv, r, item, ".")
+		bl.Items[i].Snippet = bl.snippets[item.ID]
+		bl.Items[i].SearchQuery = bl.search
 	}
 
 	tr := h.srv.Locale(r)
@@ -117,6 +127,8 @@ func (h *viewsRouter) bookmarkList(w http.ResponseWriter, r *http.Request) {
 				title = tr.Gettext("Pictures")
 			case filtersTitleVideos:
 				title = tr.Gettext("Videos")
+			case filtersTitleDocuments:
+				title = tr.Gettext("Documents")
 			}
 		}
 	}
@@ -133,6 +145,7 @@ func (h *viewsRouter) bookmarkInfo(w http.ResponseWriter, r *http.Request) {
 		h.srv.Log(r).Error("", slog.Any("err", err))
 	}
 	item.Errors = b.Errors
+	item.setHighlight(r)
 
 	ctx := r.Context().Value(ctxBaseContextKey{}).(server.TC)
 	ctx["Item"] = item
@@ -143,6 +156,11 @@ func (h *viewsRouter) bookmarkInfo(w http.ResponseWriter, r *http.Request) {
 		h.srv.Log(r).Error("", slog.Any("err", err))
 	}
 
+	ctx["Similar"], err = b.GetSimilar()
+	if err != nil {
+		h.srv.Log(r).Error("", slog.Any("err", err))
+	}
+
 	// Load bookmark debug information if the user needs them.
 	if user.Settings.DebugInfo {
 		c, err := b.OpenContainer()
@@ -224,6 +242,36 @@ func (h *viewsRouter) bookmarkDelete(w http.ResponseWriter, r *http.Request) {
 	h.srv.Redirect(w, r, redir)
 }
 
+func (h *viewsRouter) bookmarkBatch(w http.ResponseWriter, r *http.Request) {
+	tr := h.srv.Locale(r)
+	f := newBatchForm(tr)
+	b, err := h.startBatch(r, f)
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	redir := "/bookmarks"
+	if f.Get("_to").String() != "" {
+		redir = f.Get("_to").String()
+	}
+
+	switch {
+	case b == nil:
+		h.srv.AddFlash(w, r, "error", tr.Gettext("Select at least one bookmark and an action."))
+	case b.Operation == tasks.BatchExport:
+		redir = "/api/bookmarks/batch/" + b.ID + "/export.epub"
+	default:
+		h.srv.AddFlash(w, r, "success", tr.Ngettext(
+			"The action is running on %d bookmark.",
+			"The action is running on %d bookmarks.",
+			len(b.IDs), len(b.IDs),
+		))
+	}
+
+	h.srv.Redirect(w, r, redir)
+}
+
 func (h *viewsRouter) bookmarkShareLink(w http.ResponseWriter, r *http.Request) {
 	info := r.Context().Value(ctxSharedInfoKey{}).(linkShareInfo)
 
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/server"
+)
+
+type (
+	ctxDigestCollectionKey struct{}
+)
+
+// newDigestViewsRouter returns the public router that handles
+// the collection digest unsubscribe links. It doesn't need any
+// session or CSRF token since the link itself is the credential.
+func newDigestViewsRouter(api *apiRouter) *publicViewsRouter {
+	r := chi.NewRouter()
+	h := &publicViewsRouter{r, api}
+
+	r.With(h.withDigestCollection).Route("/{id:[a-zA-Z0-9_-]+}", func(r chi.Router) {
+		r.Get("/", h.digestUnsubscribe)
+		r.Post("/", h.digestUnsubscribe)
+	})
+	return h
+}
+
+func (h *publicViewsRouter) withDigestCollection(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, expires, err := bookmarks.DecodeDigestID(chi.URLParam(r, "id"))
+		if err != nil {
+			h.srv.Log(r).Warn("digest unsubscribe", slog.Any("err", err))
+			h.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+		if expires.Before(time.Now()) {
+			h.srv.Status(w, r, http.StatusGone)
+			return
+		}
+
+		c, err := bookmarks.Collections.GetOne(goqu.C("id").Eq(id))
+		if err != nil {
+			h.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxDigestCollectionKey{}, c)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// digestUnsubscribe shows a confirmation page on GET and disables
+// the collection digest on POST. The POST request also handles
+// the one-click unsubscribe from email clients (RFC 8058).
+func (h *publicViewsRouter) digestUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	c := r.Context().Value(ctxDigestCollectionKey{}).(*bookmarks.Collection)
+
+	if r.Method == http.MethodPost && c.DigestSchedule != "" {
+		if err := c.Update(map[string]interface{}{"digest_schedule": ""}); err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+		c.DigestSchedule = ""
+	}
+
+	h.srv.RenderTemplate(w, r, http.StatusOK, "bookmarks/digest_unsubscribe", server.TC{
+		"Collection": c,
+		"Done":       c.DigestSchedule == "",
+	})
+}
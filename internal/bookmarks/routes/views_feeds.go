@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"log/slog"
+	"net/http"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+func (h *viewsRouter) feedList(w http.ResponseWriter, r *http.Request) {
+	f := newFeedForm(h.srv.Locale(r), auth.GetRequestUser(r).ID)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if feed, err := f.createFeed(); err != nil {
+				h.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				tr := h.srv.Locale(r)
+				h.srv.AddFlash(w, r, "success", tr.Gettext("Feed added."))
+				h.srv.Redirect(w, r, ".", feed.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	fl := r.Context().Value(ctxFeedListKey{}).(feedList)
+	fl.Items = make([]feedItem, len(fl.items))
+	for i, item := range fl.items {
+		fl.Items[i] = newFeedItem(h.srv, r, item, ".")
+	}
+
+	ctx := r.Context().Value(ctxBaseContextKey{}).(server.TC)
+	ctx["Form"] = f
+	ctx["Pagination"] = fl.Pagination
+	ctx["Feeds"] = fl.Items
+
+	h.srv.RenderTemplate(w, r, 200, "/bookmarks/feed_list", ctx)
+}
+
+func (h *viewsRouter) feedInfo(w http.ResponseWriter, r *http.Request) {
+	feed := r.Context().Value(ctxFeedKey{}).(*bookmarks.Feed)
+
+	f := newFeedForm(h.srv.Locale(r), auth.GetRequestUser(r).ID)
+	f.setFeed(feed)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if _, err := f.updateFeed(feed); err != nil {
+				h.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				tr := h.srv.Locale(r)
+				h.srv.AddFlash(w, r, "success", tr.Gettext("Feed updated."))
+				h.srv.Redirect(w, r, feed.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	ctx := r.Context().Value(ctxBaseContextKey{}).(server.TC)
+	ctx["Item"] = newFeedItem(h.srv, r, feed, "./..")
+	ctx["Form"] = f
+
+	h.srv.RenderTemplate(w, r, 200, "/bookmarks/feed", ctx)
+}
+
+func (h *viewsRouter) feedRefresh(w http.ResponseWriter, r *http.Request) {
+	feed := r.Context().Value(ctxFeedKey{}).(*bookmarks.Feed)
+
+	if err := tasks.FetchFeedTask.Run(feed.ID, feed.ID); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	tr := h.srv.Locale(r)
+	h.srv.AddFlash(w, r, "info", tr.Gettext("The feed will be refreshed in a few seconds."))
+	h.srv.Redirect(w, r, "/bookmarks/feeds", feed.UID)
+}
+
+func (h *viewsRouter) feedDelete(w http.ResponseWriter, r *http.Request) {
+	feed := r.Context().Value(ctxFeedKey{}).(*bookmarks.Feed)
+
+	if err := feed.Delete(); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	tr := h.srv.Locale(r)
+	h.srv.AddFlash(w, r, "success", tr.Gettext("Feed removed."))
+	h.srv.Redirect(w, r, "/bookmarks/feeds")
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestFeedViews(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	f := &bookmarks.Feed{
+		UserID: &app.Users["user"].User.ID,
+		URL:    "https://example.org/feed.xml",
+		Title:  "Example",
+	}
+	require.NoError(t, bookmarks.Feeds.Create(f))
+
+	// The archive delay field can't be negative
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Target:         "/bookmarks/feeds",
+			ExpectStatus:   200,
+			ExpectContains: `min="0"`,
+		},
+		RequestTest{
+			Target:         "/bookmarks/feeds/" + f.UID,
+			ExpectStatus:   200,
+			ExpectContains: `min="0"`,
+		},
+	)
+}
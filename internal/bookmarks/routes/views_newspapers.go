@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"log/slog"
+	"net/http"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+func (h *viewsRouter) newspaperList(w http.ResponseWriter, r *http.Request) {
+	f := newNewspaperForm(h.srv.Locale(r), auth.GetRequestUser(r).ID)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if n, err := f.createNewspaper(); err != nil {
+				h.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				tr := h.srv.Locale(r)
+				h.srv.AddFlash(w, r, "success", tr.Gettext("Newspaper created."))
+				h.srv.Redirect(w, r, ".", n.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	nl := r.Context().Value(ctxNewspaperListKey{}).(newspaperList)
+	nl.Items = make([]newspaperItem, len(nl.items))
+	for i, item := range nl.items {
+		nl.Items[i] = newNewspaperItem(h.srv, r, item, ".")
+	}
+
+	ctx := r.Context().Value(ctxBaseContextKey{}).(server.TC)
+	ctx["Form"] = f
+	ctx["Pagination"] = nl.Pagination
+	ctx["Newspapers"] = nl.Items
+
+	h.srv.RenderTemplate(w, r, 200, "/bookmarks/newspaper_list", ctx)
+}
+
+func (h *viewsRouter) newspaperInfo(w http.ResponseWriter, r *http.Request) {
+	n := r.Context().Value(ctxNewspaperKey{}).(*bookmarks.Newspaper)
+
+	f := newNewspaperForm(h.srv.Locale(r), auth.GetRequestUser(r).ID)
+	f.setNewspaper(n)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if _, err := f.updateNewspaper(n); err != nil {
+				h.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				tr := h.srv.Locale(r)
+				h.srv.AddFlash(w, r, "success", tr.Gettext("Newspaper updated."))
+				h.srv.Redirect(w, r, n.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	ctx := r.Context().Value(ctxBaseContextKey{}).(server.TC)
+	ctx["Item"] = newNewspaperItem(h.srv, r, n, "./..")
+	ctx["Form"] = f
+
+	h.srv.RenderTemplate(w, r, 200, "/bookmarks/newspaper", ctx)
+}
+
+func (h *viewsRouter) newspaperDeliver(w http.ResponseWriter, r *http.Request) {
+	n := r.Context().Value(ctxNewspaperKey{}).(*bookmarks.Newspaper)
+
+	if err := tasks.DeliverNewspaperTask.Run(n.ID, n.ID); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	tr := h.srv.Locale(r)
+	h.srv.AddFlash(w, r, "info", tr.Gettext("The newspaper will be delivered in a few seconds."))
+	h.srv.Redirect(w, r, "/bookmarks/newspapers", n.UID)
+}
+
+func (h *viewsRouter) newspaperDelete(w http.ResponseWriter, r *http.Request) {
+	n := r.Context().Value(ctxNewspaperKey{}).(*bookmarks.Newspaper)
+
+	if err := n.Delete(); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	tr := h.srv.Locale(r)
+	h.srv.AddFlash(w, r, "success", tr.Gettext("Newspaper removed."))
+	h.srv.Redirect(w, r, "/bookmarks/newspapers")
+}
@@ -0,0 +1,221 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+type (
+	ctxRuleListKey struct{}
+	ctxRuleKey     struct{}
+)
+
+type ruleList struct {
+	Pagination server.Pagination
+	Items      []*bookmarks.Rule
+}
+
+func (h *viewsRouter) ruleList(w http.ResponseWriter, r *http.Request) {
+	f := newRuleForm(h.srv.Locale(r), auth.GetRequestUser(r).ID)
+
+	switch r.Method {
+	case http.MethodGet:
+		f.Get("is_enabled").Set(true)
+		f.Get("events").Set([]string{bookmarks.RuleEventSaved})
+	case http.MethodPost:
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if rule, err := f.createRule(); err != nil {
+				h.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				tr := h.srv.Locale(r)
+				h.srv.AddFlash(w, r, "success", tr.Gettext("Rule created."))
+				h.srv.Redirect(w, r, ".", rule.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	rl := r.Context().Value(ctxRuleListKey{}).(ruleList)
+
+	ctx := r.Context().Value(ctxBaseContextKey{}).(server.TC)
+	ctx["Form"] = f
+	ctx["Pagination"] = rl.Pagination
+	ctx["Rules"] = rl.Items
+
+	h.srv.RenderTemplate(w, r, 200, "/bookmarks/rule_list", ctx)
+}
+
+func (h *viewsRouter) ruleInfo(w http.ResponseWriter, r *http.Request) {
+	rule := r.Context().Value(ctxRuleKey{}).(*bookmarks.Rule)
+
+	f := newRuleForm(h.srv.Locale(r), auth.GetRequestUser(r).ID)
+	f.setRule(rule)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if err := f.updateRule(rule); err != nil {
+				h.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				tr := h.srv.Locale(r)
+				h.srv.AddFlash(w, r, "success", tr.Gettext("Rule updated."))
+				h.srv.Redirect(w, r, rule.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	ctx := r.Context().Value(ctxBaseContextKey{}).(server.TC)
+	ctx["Item"] = rule
+	ctx["Form"] = f
+	ctx["IsApplying"] = tasks.ApplyRuleTask.IsRunning(rule.ID)
+
+	h.srv.RenderTemplate(w, r, 200, "/bookmarks/rule", ctx)
+}
+
+// ruleTest lists the existing bookmarks matching the rule's conditions.
+func (h *viewsRouter) ruleTest(w http.ResponseWriter, r *http.Request) {
+	rule := r.Context().Value(ctxRuleKey{}).(*bookmarks.Rule)
+
+	pf := h.srv.GetPageParams(r, listDefaultLimit)
+	if pf == nil {
+		h.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+
+	ds, err := rule.Bookmarks()
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+	ds = ds.
+		Order(goqu.I("b.created").Desc()).
+		Limit(uint(pf.Limit())).
+		Offset(uint(pf.Offset()))
+
+	count, err := ds.ClearOrder().ClearLimit().ClearOffset().Count()
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	var items []*bookmarks.Bookmark
+	if err = ds.ScanStructs(&items); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	bl := make([]bookmarkItem, len(items))
+	for i, item := range items {
+		bl[i] = newBookmarkItem(h.srv, r, item, "/bookmarks")
+	}
+
+	ctx := r.Context().Value(ctxBaseContextKey{}).(server.TC)
+	ctx["Item"] = rule
+	ctx["MatchCount"] = int(count)
+	ctx["Pagination"] = h.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset())
+	ctx["Bookmarks"] = bl
+
+	h.srv.RenderTemplate(w, r, 200, "/bookmarks/rule_test", ctx)
+}
+
+// ruleApply launches the task that applies the rule
+// to the existing bookmarks.
+func (h *viewsRouter) ruleApply(w http.ResponseWriter, r *http.Request) {
+	rule := r.Context().Value(ctxRuleKey{}).(*bookmarks.Rule)
+
+	if err := tasks.ApplyRuleTask.Run(rule.ID, rule.ID); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	tr := h.srv.Locale(r)
+	h.srv.AddFlash(w, r, "info", tr.Gettext("The rule will be applied to your bookmarks in a few seconds."))
+	h.srv.Redirect(w, r, "/bookmarks/rules", rule.UID)
+}
+
+func (h *viewsRouter) ruleDelete(w http.ResponseWriter, r *http.Request) {
+	rule := r.Context().Value(ctxRuleKey{}).(*bookmarks.Rule)
+
+	if err := rule.Delete(); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	tr := h.srv.Locale(r)
+	h.srv.AddFlash(w, r, "success", tr.Gettext("Rule removed."))
+	h.srv.Redirect(w, r, "/bookmarks/rules")
+}
+
+func (h *viewsRouter) withRuleList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := ruleList{}
+
+		pf := h.srv.GetPageParams(r, 30)
+		if pf == nil {
+			h.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ds := bookmarks.Rules.Query().
+			Where(
+				goqu.C("user_id").Table("r").Eq(auth.GetRequestUser(r).ID),
+			)
+
+		ds = ds.Order(goqu.I("name").Asc()).
+			Limit(uint(pf.Limit())).
+			Offset(uint(pf.Offset()))
+
+		count, err := ds.ClearOrder().ClearLimit().ClearOffset().Count()
+		if err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+
+		res.Items = []*bookmarks.Rule{}
+		if err := ds.ScanStructs(&res.Items); err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+
+		res.Pagination = h.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset())
+
+		ctx := context.WithValue(r.Context(), ctxRuleListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *viewsRouter) withRule(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid := chi.URLParam(r, "uid")
+
+		rule, err := bookmarks.Rules.GetOne(
+			goqu.C("uid").Eq(uid),
+			goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+		)
+		if err != nil {
+			h.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxRuleKey{}, rule)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,252 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/internal/webhooks"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestRuleViews(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	u := app.Users["user"]
+
+	b1 := &bookmarks.Bookmark{
+		UserID:    &u.User.ID,
+		State:     bookmarks.StateLoaded,
+		URL:       "https://blog.example.net/python",
+		Title:     "Python tips",
+		Site:      "blog.example.net",
+		Domain:    "example.net",
+		WordCount: 800,
+	}
+	b2 := &bookmarks.Bookmark{
+		UserID:    &u.User.ID,
+		State:     bookmarks.StateLoaded,
+		URL:       "https://example.org/python",
+		Title:     "Python recipes",
+		Site:      "example.org",
+		Domain:    "example.org",
+		WordCount: 100,
+	}
+	for _, b := range []*bookmarks.Bookmark{b1, b2} {
+		require.NoError(t, bookmarks.Bookmarks.Create(b))
+	}
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Target:         "/bookmarks/rules",
+			ExpectStatus:   200,
+			ExpectContains: "Add a rule",
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/bookmarks/rules",
+			Form: url.Values{
+				"name":       {"Long Python"},
+				"is_enabled": {"t"},
+				"events":     {"saved"},
+			},
+			ExpectStatus:   422,
+			ExpectContains: "select at least one action",
+		},
+		RequestTest{
+			// Reload the page for a new CSRF token
+			Target:       "/bookmarks/rules",
+			ExpectStatus: 200,
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/bookmarks/rules",
+			Form: url.Values{
+				"name":       {"Long Python"},
+				"is_enabled": {"t"},
+				"events":     {"saved"},
+				"search":     {"python"},
+				"domain":     {"example.net"},
+				"min_words":  {"500"},
+				"labels":     {"dev, python"},
+				"is_marked":  {"t"},
+			},
+			ExpectStatus:   303,
+			ExpectRedirect: "/bookmarks/rules/[a-zA-Z0-9]{18,22}$",
+		},
+		RequestTest{
+			Target:         "{{ (index .History 0).Redirect }}",
+			ExpectStatus:   200,
+			ExpectContains: "Long Python",
+		},
+		RequestTest{
+			Target:         "{{ (index .History 1).Redirect }}/test",
+			ExpectStatus:   200,
+			ExpectContains: "1 bookmark matches this rule.",
+		},
+		RequestTest{
+			Method:         "POST",
+			Target:         "{{ (index .History 2).Redirect }}/apply",
+			ExpectStatus:   303,
+			ExpectRedirect: "/bookmarks/rules/[a-zA-Z0-9]{18,22}$",
+		},
+		RequestTest{
+			Target:         "{{ (index .History 0).Redirect }}",
+			ExpectStatus:   200,
+			ExpectContains: "The rule will be applied",
+			Assert: func(t *testing.T, _ *Response) {
+				var op superbus.Operation
+				records := Events().Records("task")
+				require.Len(t, records, 1)
+				require.NoError(t, json.Unmarshal(records[0], &op))
+				require.Equal(t, "rule.apply", op.Name)
+			},
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "{{ (index .History 1).Redirect }}",
+			Form: url.Values{
+				"name":       {"Python"},
+				"is_enabled": {"t"},
+				"events":     {"saved", "read"},
+				"search":     {"python"},
+				"domain":     {""},
+				"min_words":  {""},
+				"labels":     {"python"},
+				"is_marked":  {"f"},
+			},
+			ExpectStatus:   303,
+			ExpectRedirect: "/bookmarks/rules/[a-zA-Z0-9]{18,22}$",
+		},
+		RequestTest{
+			Target:         "{{ (index .History 0).Redirect }}/test",
+			ExpectStatus:   200,
+			ExpectContains: "2 bookmarks match this rule.",
+		},
+	)
+
+	rule, err := bookmarks.Rules.GetOne(goqu.C("user_id").Eq(u.User.ID))
+	require.NoError(t, err)
+	require.Equal(t, "Python", rule.Name)
+	require.Equal(t, []string{"saved", "read"}, []string(rule.Events))
+	require.Equal(t, []string{"python"}, []string(rule.Actions.Labels))
+
+	w := &webhooks.Webhook{
+		UserID: &u.User.ID,
+		URL:    "https://hooks.example.net/",
+		Events: []string{
+			webhooks.EventBookmarkLabeled,
+			webhooks.EventBookmarkArchived,
+			webhooks.EventBookmarkMarked,
+		},
+		IsEnabled: true,
+	}
+	require.NoError(t, webhooks.Webhooks.Create(w))
+	getEvents := func() []string {
+		res := []string{}
+		require.NoError(t, webhooks.Deliveries.Query().
+			Select(goqu.C("event")).
+			Where(goqu.C("webhook_id").Eq(w.ID)).
+			Order(goqu.C("id").Asc()).
+			ScanVals(&res))
+		return res
+	}
+
+	t.Run("run rules", func(t *testing.T) {
+		other, err := bookmarks.Rules.ForEvent(u.User.ID, bookmarks.RuleEventRead)
+		require.NoError(t, err)
+		require.Len(t, other, 1)
+
+		require.NoError(t, tasks.RunRules(b1, bookmarks.RuleEventSaved))
+		b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(b1.ID))
+		require.NoError(t, err)
+		require.Equal(t, []string{"python"}, []string(b.Labels))
+		require.False(t, b.IsMarked)
+		require.Equal(t, []string{webhooks.EventBookmarkLabeled}, getEvents())
+
+		// Unknown event, nothing changes
+		require.NoError(t, tasks.RunRules(b2, "nope"))
+		b, err = bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(b2.ID))
+		require.NoError(t, err)
+		require.Empty(t, b.Labels)
+	})
+
+	t.Run("apply rule", func(t *testing.T) {
+		rule.Actions.IsArchived = true
+		count, err := tasks.ApplyRule(rule)
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+
+		for _, id := range []int{b1.ID, b2.ID} {
+			b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(id))
+			require.NoError(t, err)
+			require.Equal(t, []string{"python"}, []string(b.Labels))
+			require.True(t, b.IsArchived)
+		}
+		require.Equal(t, []string{
+			webhooks.EventBookmarkLabeled,
+			webhooks.EventBookmarkArchived,
+			webhooks.EventBookmarkArchived,
+			webhooks.EventBookmarkLabeled,
+		}, getEvents())
+
+		// Nothing left to change
+		count, err = tasks.ApplyRule(rule)
+		require.NoError(t, err)
+		require.Equal(t, 0, count)
+		require.Len(t, getEvents(), 4)
+		Events().Clear()
+	})
+
+	t.Run("read event", func(t *testing.T) {
+		RunRequestSequence(t, client, "user",
+			RequestTest{
+				Method:       "PATCH",
+				Target:       "/api/bookmarks/" + b2.UID,
+				JSON:         map[string]any{"read_progress": 100},
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, _ *Response) {
+					var op superbus.Operation
+					records := Events().Records("task")
+					require.Len(t, records, 1)
+					require.NoError(t, json.Unmarshal(records[0], &op))
+					require.Equal(t, "bookmark.rules", op.Name)
+					require.Equal(t, fmt.Sprintf("%d:read", b2.ID), op.ID)
+				},
+			},
+		)
+	})
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Target:       "/bookmarks/rules/" + rule.UID,
+			ExpectStatus: 200,
+		},
+		RequestTest{
+			Method:         "POST",
+			Target:         "/bookmarks/rules/" + rule.UID + "/delete",
+			ExpectStatus:   303,
+			ExpectRedirect: "/bookmarks/rules$",
+		},
+		RequestTest{
+			Target:       "/bookmarks/rules/" + rule.UID,
+			ExpectStatus: 404,
+		},
+	)
+}
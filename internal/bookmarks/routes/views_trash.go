@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"net/http"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/server"
+)
+
+func (h *viewsRouter) trashList(w http.ResponseWriter, r *http.Request) {
+	tl := r.Context().Value(ctxTrashListKey{}).(trashList)
+
+	ctx := r.Context().Value(ctxBaseContextKey{}).(server.TC)
+	ctx["Pagination"] = tl.Pagination
+	ctx["Items"] = tl.Items
+	ctx["Retention"] = configs.Config.Bookmarks.TrashRetention
+
+	h.srv.RenderTemplate(w, r, 200, "/bookmarks/trash", ctx)
+}
+
+func (h *viewsRouter) trashEmpty(w http.ResponseWriter, r *http.Request) {
+	if err := bookmarks.Trash.Empty(auth.GetRequestUser(r).ID); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	tr := h.srv.Locale(r)
+	h.srv.AddFlash(w, r, "success", tr.Gettext("The trash is now empty."))
+	h.srv.Redirect(w, r, "/bookmarks/trash")
+}
+
+func (h *viewsRouter) trashRestore(w http.ResponseWriter, r *http.Request) {
+	item := r.Context().Value(ctxTrashItemKey{}).(bookmarks.Trashable)
+	if err := item.Restore(); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	tr := h.srv.Locale(r)
+	h.srv.AddFlash(w, r, "success", tr.Gettext("Item restored."))
+	h.srv.Redirect(w, r, "/bookmarks/trash")
+}
+
+func (h *viewsRouter) trashPurge(w http.ResponseWriter, r *http.Request) {
+	item := r.Context().Value(ctxTrashItemKey{}).(bookmarks.Trashable)
+	if err := item.Delete(); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	tr := h.srv.Locale(r)
+	h.srv.AddFlash(w, r, "success", tr.Gettext("Item removed for good."))
+	h.srv.Redirect(w, r, "/bookmarks/trash")
+}
@@ -0,0 +1,980 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	goquexp "github.com/doug-martin/goqu/v9/exp"
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/annotate"
+	"codeberg.org/readeck/readeck/pkg/forms"
+	"codeberg.org/readeck/readeck/pkg/utils"
+)
+
+type (
+	ctxWallabagAnnotationKey struct{}
+)
+
+const (
+	wallabagDefaultLimit = 30
+	wallabagTimeFormat   = "2006-01-02T15:04:05-0700"
+)
+
+type wallabagRouter struct {
+	chi.Router
+	*apiRouter
+}
+
+// NewWallabagRouteHandler returns a chi Router handler with the
+// wallabag compatible API routes for the bookmark domain.
+// Entries are bookmarks, tags are labels and annotations are
+// the bookmark annotations.
+func NewWallabagRouteHandler(s *server.Server) func(r chi.Router) {
+	return func(r chi.Router) {
+		h := &wallabagRouter{r, newAPIRouter(s)}
+
+		r.With(h.srv.WithPermission("api:bookmarks", "read")).Group(func(r chi.Router) {
+			r.Get("/entries", h.entryList)
+			r.Get("/entries/exists", h.entryExists)
+			r.With(h.withEntry).Get("/entries/{id:[0-9]+}", h.entryInfo)
+			r.With(h.withEntry).Get("/entries/{id:[0-9]+}/tags", h.entryTags)
+			r.With(h.srv.WithPermission("api:bookmarks", "export"), h.withEntry).
+				Get("/entries/{id:[0-9]+}/export.{format}", h.bookmarkExport)
+			r.Get("/tags", h.tagList)
+			r.With(h.withEntry).Get("/annotations/{id:[0-9]+}", h.annotationList)
+		})
+
+		r.With(h.srv.WithPermission("api:bookmarks", "write")).Group(func(r chi.Router) {
+			r.Post("/entries", h.entryCreate)
+			r.With(h.withEntry).Group(func(r chi.Router) {
+				r.Patch("/entries/{id:[0-9]+}", h.entryUpdate)
+				r.Delete("/entries/{id:[0-9]+}", h.entryDelete)
+				r.Post("/entries/{id:[0-9]+}/tags", h.entryAddTags)
+				r.Delete("/entries/{id:[0-9]+}/tags/{tag:[0-9]+}", h.entryRemoveTag)
+				r.Post("/annotations/{id:[0-9]+}", h.annotationCreate)
+			})
+			r.Delete("/tag/label", h.tagDeleteByLabel)
+			r.Delete("/tags/label", h.tagDeleteByLabel)
+			r.Delete("/tags/{tag:[0-9]+}", h.tagDelete)
+			r.With(h.withAnnotation).Group(func(r chi.Router) {
+				r.Put("/annotations/{id:[0-9]+}", h.annotationUpdate)
+				r.Delete("/annotations/{id:[0-9]+}", h.annotationDelete)
+			})
+		})
+	}
+}
+
+// entryList renders a paginated list of entries. It supports the wallabag
+// filters and the "since" parameter that clients use to only
+// retrieve the entries updated after their last synchronization.
+func (h *wallabagRouter) entryList(w http.ResponseWriter, r *http.Request) {
+	f := newWallabagListForm(h.srv.Locale(r))
+	forms.BindURL(f, r)
+	if !f.IsValid() {
+		h.srv.Render(w, r, http.StatusBadRequest, f)
+		return
+	}
+
+	ds := bookmarks.Bookmarks.Query().
+		Where(goqu.C("user_id").Table("b").Eq(auth.GetRequestUser(r).ID))
+	ds = f.filters().ToSelectDataSet(ds)
+
+	if v := f.Get("since").(forms.TypedField[int]).V(); v > 0 {
+		ds = ds.Where(goqu.C("updated").Table("b").Gte(time.Unix(int64(v), 0).UTC()))
+	}
+	if v := f.Get("domain_name").String(); v != "" {
+		ds = ds.Where(goqu.Or(
+			goqu.C("domain").Table("b").Eq(v),
+			goqu.C("site").Table("b").Eq(v),
+		))
+	}
+
+	count, err := ds.Count()
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	page := max(1, f.Get("page").(forms.TypedField[int]).V())
+	limit := f.Get("perPage").(forms.TypedField[int]).V()
+	if limit == 0 {
+		limit = wallabagDefaultLimit
+	}
+	res := wallabagEntryList{
+		Page:  page,
+		Limit: limit,
+		Pages: max(1, int(math.Ceil(float64(count)/float64(limit)))),
+		Total: int(count),
+		Links: map[string]wallabagLink{},
+	}
+	if page > res.Pages {
+		h.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+
+	items := []*bookmarks.Bookmark{}
+	err = ds.Order(f.order()).
+		Limit(uint(limit)).
+		Offset(uint((page - 1) * limit)).
+		ScanStructs(&items)
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	withContent := f.Get("detail").String() != "metadata"
+	res.Embedded.Items = make([]wallabagEntry, len(items))
+	for i, b := range items {
+		res.Embedded.Items[i] = h.newEntry(r, b, withContent)
+	}
+
+	pageURL := func(p int) wallabagLink {
+		u := h.srv.AbsoluteURL(r)
+		q := u.Query()
+		q.Set("page", strconv.Itoa(p))
+		u.RawQuery = q.Encode()
+		return wallabagLink{u.String()}
+	}
+	res.Links["self"] = pageURL(page)
+	res.Links["first"] = pageURL(1)
+	res.Links["last"] = pageURL(res.Pages)
+	if page < res.Pages {
+		res.Links["next"] = pageURL(page + 1)
+	}
+	if page > 1 {
+		res.Links["previous"] = pageURL(page - 1)
+	}
+
+	h.srv.Render(w, r, http.StatusOK, res)
+}
+
+// entryExists checks if one or several URLs are saved. It accepts
+// clear URLs and their SHA1 hash, and returns either a boolean
+// or the entry ID for each URL.
+func (h *wallabagRouter) entryExists(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	returnID := q.Get("return_id") == "1"
+
+	single := ""
+	urls, hashed := q["urls[]"], q["hashed_urls[]"]
+	if u := q.Get("url"); u != "" {
+		single, urls, hashed = u, []string{u}, nil
+	} else if u := q.Get("hashed_url"); u != "" {
+		single, urls, hashed = u, nil, []string{u}
+	}
+
+	found, err := findWallabagEntries(auth.GetRequestUser(r).ID, urls, hashed)
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	result := func(u string) any {
+		id, ok := found[u]
+		switch {
+		case !ok && returnID:
+			return nil
+		case !ok:
+			return false
+		case returnID:
+			return id
+		}
+		return true
+	}
+
+	if single != "" {
+		h.srv.Render(w, r, http.StatusOK, map[string]any{"exists": result(single)})
+		return
+	}
+
+	res := map[string]any{}
+	for _, u := range append(urls, hashed...) {
+		res[u] = result(u)
+	}
+	h.srv.Render(w, r, http.StatusOK, res)
+}
+
+// findWallabagEntries returns the IDs of the user's bookmarks, indexed
+// by their URL and initial URL, and the hashes of these URLs.
+// Plain URLs are looked up in the database. Hashed URLs can only be
+// matched by loading every bookmark of the user.
+func findWallabagEntries(userID int, urls, hashed []string) (map[string]int, error) {
+	found := map[string]int{}
+	if len(urls) == 0 && len(hashed) == 0 {
+		return found, nil
+	}
+
+	ds := bookmarks.Bookmarks.Query().
+		Select("b.id", "b.url", "b.initial_url").
+		Where(goqu.C("user_id").Table("b").Eq(userID))
+	if len(hashed) == 0 {
+		ds = ds.Where(goqu.Or(
+			goqu.C("url").Table("b").In(urls),
+			goqu.C("initial_url").Table("b").In(urls),
+		))
+	}
+
+	var items []struct {
+		ID         int    `db:"id"`
+		URL        string `db:"url"`
+		InitialURL string `db:"initial_url"`
+	}
+	if err := ds.ScanStructs(&items); err != nil {
+		return nil, err
+	}
+
+	for _, x := range items {
+		for _, u := range []string{x.InitialURL, x.URL} {
+			found[u] = x.ID
+			if len(hashed) > 0 {
+				found[wallabagHashURL(u)] = x.ID
+			}
+		}
+	}
+	return found, nil
+}
+
+func (h *wallabagRouter) entryInfo(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	h.srv.Render(w, r, http.StatusOK, h.newEntry(r, b, true))
+}
+
+// entryCreate saves a new entry. When the URL already exists, the existing
+// entry is updated and returned instead.
+func (h *wallabagRouter) entryCreate(w http.ResponseWriter, r *http.Request) {
+	values, err := wallabagValues(r)
+	if err != nil {
+		h.srv.Status(w, r, http.StatusBadRequest)
+		return
+	}
+	user := auth.GetRequestUser(r)
+
+	if u := values.Get("url"); u != "" {
+		b, err := bookmarks.Bookmarks.GetOne(
+			goqu.C("user_id").Eq(user.ID),
+			goqu.Or(goqu.C("url").Eq(u), goqu.C("initial_url").Eq(u)),
+		)
+		if err == nil {
+			h.updateEntry(w, r, b, values)
+			return
+		}
+	}
+
+	f := newCreateForm(h.srv.Locale(r), user.ID, h.srv.GetReqID(r))
+	forms.BindValues(f, url.Values{
+		"url":    values["url"],
+		"title":  values["title"],
+		"labels": wallabagTags(values.Get("tags")),
+	})
+	if !f.IsValid() {
+		h.srv.Render(w, r, http.StatusBadRequest, f)
+		return
+	}
+
+	// A client can send the page content, which is then used instead
+	// of fetching the URL.
+	if content := values.Get("content"); content != "" {
+		f.resources = append(f.resources, tasks.MultipartResource{
+			URL:     f.Get("url").String(),
+			Headers: map[string]string{"content-type": "text/html; charset=utf-8"},
+			Data:    []byte(content),
+		})
+	}
+
+	b, err := f.createBookmark()
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	// Archived and starred flags are set once the entry exists.
+	uf := newWallabagUpdateForm(h.srv.Locale(r), values)
+	if _, err = uf.update(b); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, h.newEntry(r, b, false))
+}
+
+func (h *wallabagRouter) entryUpdate(w http.ResponseWriter, r *http.Request) {
+	values, err := wallabagValues(r)
+	if err != nil {
+		h.srv.Status(w, r, http.StatusBadRequest)
+		return
+	}
+
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	h.updateEntry(w, r, b, values)
+}
+
+func (h *wallabagRouter) updateEntry(w http.ResponseWriter, r *http.Request, b *bookmarks.Bookmark, values url.Values) {
+	f := newWallabagUpdateForm(h.srv.Locale(r), values)
+	if !f.IsValid() {
+		h.srv.Render(w, r, http.StatusBadRequest, f)
+		return
+	}
+
+	if _, err := f.update(b); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, h.newEntry(r, b, true))
+}
+
+// entryDelete moves an entry to the trash and returns it, or only its ID when
+// the "expect" parameter is "id".
+func (h *wallabagRouter) entryDelete(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+
+	var res any = h.newEntry(r, b, false)
+	if r.URL.Query().Get("expect") == "id" {
+		res = map[string]int{"id": b.ID}
+	}
+
+	if err := b.Trash(); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, res)
+}
+
+func (h *wallabagRouter) entryTags(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	h.srv.Render(w, r, http.StatusOK, newWallabagTags(b.Labels))
+}
+
+func (h *wallabagRouter) entryAddTags(w http.ResponseWriter, r *http.Request) {
+	values, err := wallabagValues(r)
+	if err != nil {
+		h.srv.Status(w, r, http.StatusBadRequest)
+		return
+	}
+
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	f := newUpdateForm(h.srv.Locale(r))
+	forms.BindValues(f, url.Values{"add_labels": wallabagTags(values.Get("tags"))})
+	if _, err := f.update(b); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, h.newEntry(r, b, true))
+}
+
+func (h *wallabagRouter) entryRemoveTag(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	id, _ := strconv.Atoi(chi.URLParam(r, "tag"))
+
+	i := slices.IndexFunc(b.Labels, func(s string) bool {
+		return wallabagID(s) == id
+	})
+	if i < 0 {
+		h.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+
+	f := newUpdateForm(h.srv.Locale(r))
+	forms.BindValues(f, url.Values{"remove_labels": {b.Labels[i]}})
+	if _, err := f.update(b); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, h.newEntry(r, b, true))
+}
+
+func (h *wallabagRouter) tagList(w http.ResponseWriter, r *http.Request) {
+	var labels []*labelItem
+	err := bookmarks.Bookmarks.GetLabels().
+		Where(goqu.C("user_id").Table("b").Eq(auth.GetRequestUser(r).ID)).
+		ScanStructs(&labels)
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	names := make([]string, len(labels))
+	for i, x := range labels {
+		names[i] = string(x.Name)
+	}
+	h.srv.Render(w, r, http.StatusOK, newWallabagTags(names))
+}
+
+// tagDeleteByLabel removes one or several labels from every entry.
+// "/tag/label" receives one "tag" parameter and "/tags/label"
+// a comma separated list in a "tags" parameter.
+func (h *wallabagRouter) tagDeleteByLabel(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	names := wallabagTags(q.Get("tags"))
+	if v := strings.TrimSpace(q.Get("tag")); v != "" {
+		names = append(names, v)
+	}
+
+	res := []wallabagTag{}
+	for _, name := range names {
+		ids, err := bookmarks.Trash.TrashLabel(auth.GetRequestUser(r), name)
+		if err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+		if len(ids) > 0 {
+			res = append(res, newWallabagTag(name))
+		}
+	}
+
+	if len(res) == 0 {
+		h.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+	if q.Has("tag") {
+		h.srv.Render(w, r, http.StatusOK, res[0])
+		return
+	}
+	h.srv.Render(w, r, http.StatusOK, res)
+}
+
+func (h *wallabagRouter) tagDelete(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "tag"))
+	user := auth.GetRequestUser(r)
+
+	var labels []*labelItem
+	err := bookmarks.Bookmarks.GetLabels().
+		Where(goqu.C("user_id").Table("b").Eq(user.ID)).
+		ScanStructs(&labels)
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	i := slices.IndexFunc(labels, func(x *labelItem) bool {
+		return wallabagID(string(x.Name)) == id
+	})
+	if i < 0 {
+		h.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+
+	name := string(labels[i].Name)
+	if _, err = bookmarks.Trash.TrashLabel(user, name); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, newWallabagTag(name))
+}
+
+func (h *wallabagRouter) annotationList(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	rows := newWallabagAnnotations(b.Annotations)
+	h.srv.Render(w, r, http.StatusOK, map[string]any{
+		"total": len(rows),
+		"rows":  rows,
+	})
+}
+
+// annotationCreate adds an annotation to an entry. Wallabag's ranges
+// use XPath like selectors, relative to the article, that are the same
+// as the annotation selectors with a leading slash.
+func (h *wallabagRouter) annotationCreate(w http.ResponseWriter, r *http.Request) {
+	var data wallabagAnnotation
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || len(data.Ranges) == 0 {
+		h.srv.Status(w, r, http.StatusBadRequest)
+		return
+	}
+
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	f := newAnnotationForm(h.srv.Locale(r))
+	forms.BindValues(f, url.Values{
+		"start_selector": {strings.TrimPrefix(data.Ranges[0].Start, "/")},
+		"start_offset":   {data.Ranges[0].StartOffset.String()},
+		"end_selector":   {strings.TrimPrefix(data.Ranges[0].End, "/")},
+		"end_offset":     {data.Ranges[0].EndOffset.String()},
+		"color":          {"yellow"},
+		"note":           {data.Text},
+	})
+	if !f.IsValid() {
+		h.srv.Render(w, r, http.StatusBadRequest, f)
+		return
+	}
+
+	bi := newBookmarkItem(h.srv, r, b, "")
+	annotation, err := f.addToBookmark(&bi)
+	if err != nil {
+		if errors.As(err, &annotate.ErrAnotate) {
+			h.srv.Message(w, r, &server.Message{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		} else {
+			h.srv.Error(w, r, err)
+		}
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, newWallabagAnnotation(annotation))
+}
+
+func (h *wallabagRouter) annotationUpdate(w http.ResponseWriter, r *http.Request) {
+	values, err := wallabagValues(r)
+	if err != nil {
+		h.srv.Status(w, r, http.StatusBadRequest)
+		return
+	}
+
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	annotation := r.Context().Value(ctxWallabagAnnotationKey{}).(*bookmarks.BookmarkAnnotation)
+
+	f := newAnnotationUpdateForm(h.srv.Locale(r))
+	forms.BindValues(f, url.Values{"note": values["text"]})
+	f.update(annotation)
+
+	if err = b.Update(map[string]interface{}{
+		"annotations": b.Annotations,
+	}); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, newWallabagAnnotation(annotation))
+}
+
+func (h *wallabagRouter) annotationDelete(w http.ResponseWriter, r *http.Request) {
+	b := r.Context().Value(ctxBookmarkKey{}).(*bookmarks.Bookmark)
+	annotation := r.Context().Value(ctxWallabagAnnotationKey{}).(*bookmarks.BookmarkAnnotation)
+
+	b.Annotations.Delete(annotation.ID)
+	if err := b.Update(map[string]interface{}{
+		"annotations": b.Annotations,
+	}); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	h.srv.Render(w, r, http.StatusOK, newWallabagAnnotation(annotation))
+}
+
+// withEntry fetches the bookmark with the numeric ID given in the
+// route and adds it into the request's context.
+func (h *wallabagRouter) withEntry(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+		b, err := bookmarks.Bookmarks.GetOne(
+			goqu.C("id").Eq(id),
+			goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+		)
+		if err != nil {
+			h.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxBookmarkKey{}, b)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// withAnnotation finds the annotation with the numeric ID given in
+// the route and adds it, with its bookmark, into the request's context.
+func (h *wallabagRouter) withAnnotation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+		var items []*bookmarks.AnnotationQueryResult
+		err := bookmarks.Bookmarks.GetAnnotations().
+			Where(goqu.C("user_id").Table("b").Eq(auth.GetRequestUser(r).ID)).
+			ScanStructs(&items)
+		if err != nil {
+			h.srv.Error(w, r, err)
+			return
+		}
+
+		i := slices.IndexFunc(items, func(x *bookmarks.AnnotationQueryResult) bool {
+			return wallabagID(x.ID) == id
+		})
+		if i < 0 {
+			h.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(items[i].Bookmark.ID))
+		if err != nil {
+			h.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+		annotation := b.Annotations.Get(items[i].ID)
+		if annotation == nil {
+			h.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxBookmarkKey{}, b)
+		ctx = context.WithValue(ctx, ctxWallabagAnnotationKey{}, annotation)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// newEntry returns a wallabag entry from a bookmark.
+func (h *wallabagRouter) newEntry(r *http.Request, b *bookmarks.Bookmark, withContent bool) wallabagEntry {
+	user := auth.GetRequestUser(r)
+	bi := newBookmarkItem(h.srv, r, b, "")
+
+	// Clients read the content outside of Readeck so the media
+	// URLs must be absolute.
+	bi.baseURL = h.srv.AbsoluteURL(r, "/")
+	bi.mediaURL = bi.baseURL.JoinPath("/bm", b.FilePath)
+
+	res := wallabagEntry{
+		ID:             b.ID,
+		Title:          b.Title,
+		URL:            b.URL,
+		HashedURL:      wallabagHashURL(b.URL),
+		GivenURL:       b.InitialURL,
+		HashedGivenURL: wallabagHashURL(b.InitialURL),
+		IsArchived:     wallabagBool(b.IsArchived),
+		IsStarred:      wallabagBool(b.IsMarked),
+		CreatedAt:      wallabagTime(b.Created),
+		UpdatedAt:      wallabagTime(b.Updated),
+		PublishedBy:    b.Authors,
+		Annotations:    newWallabagAnnotations(b.Annotations),
+		Mimetype:       "text/html",
+		Language:       b.Lang,
+		ReadingTime:    b.ReadingTime(),
+		DomainName:     b.Site,
+		Tags:           newWallabagTags(b.Labels),
+		UserID:         user.ID,
+		UserName:       user.Username,
+		UserEmail:      user.Email,
+		Links: map[string]wallabagLink{
+			"self": {h.srv.AbsoluteURL(r, "/wallabag/api/entries", strconv.Itoa(b.ID)).String()},
+		},
+	}
+	if res.PublishedBy == nil {
+		res.PublishedBy = []string{}
+	}
+	if b.Published != nil && !b.Published.IsZero() {
+		res.PublishedAt = (*wallabagTime)(b.Published)
+	}
+	if v, ok := b.Files["image"]; ok {
+		res.PreviewPicture = bi.mediaURL.String() + "/" + v.Name
+	}
+
+	if withContent && bi.HasArticle {
+		// Annotations are sent apart, they're not rendered in the content.
+		bi.annotationTag = ""
+		buf, err := bi.getArticle()
+		if err != nil {
+			h.srv.Log(r).Error("", slog.Any("err", err))
+		} else {
+			c, _ := io.ReadAll(buf)
+			res.Content = string(c)
+		}
+	}
+
+	return res
+}
+
+type wallabagListForm struct {
+	*forms.Form
+}
+
+func newWallabagListForm(tr forms.Translator) *wallabagListForm {
+	return &wallabagListForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewBooleanField("archive"),
+		forms.NewBooleanField("starred"),
+		forms.NewTextField("sort", forms.Trim, forms.Default("created"), forms.ChoicesPairs([][2]string{
+			{"created", "created"}, {"updated", "updated"}, {"archived", "archived"},
+		})),
+		forms.NewTextField("order", forms.Trim, forms.Default("desc"), forms.ChoicesPairs([][2]string{
+			{"asc", "asc"}, {"desc", "desc"},
+		})),
+		forms.NewIntegerField("page", forms.Default(1), forms.Gte(1)),
+		forms.NewIntegerField("perPage", forms.Default(wallabagDefaultLimit), forms.Gte(1), forms.Lte(500)),
+		forms.NewTextField("tags", forms.Trim),
+		forms.NewIntegerField("since", forms.Default(0), forms.Gte(0)),
+		forms.NewTextField("detail", forms.Trim),
+		forms.NewTextField("domain_name", forms.Trim),
+	)}
+}
+
+// filters returns the bookmark filters matching the archive, starred
+// and tags parameters. Every given tag must be present on an entry.
+func (f *wallabagListForm) filters() bookmarks.Filters {
+	res := bookmarks.Filters{}
+	if field := f.Get("archive"); !field.IsNil() {
+		res.IsArchived = new(bool)
+		*res.IsArchived = field.(forms.TypedField[bool]).V()
+	}
+	if field := f.Get("starred"); !field.IsNil() {
+		res.IsMarked = new(bool)
+		*res.IsMarked = field.(forms.TypedField[bool]).V()
+	}
+
+	labels := wallabagTags(f.Get("tags").String())
+	for i, x := range labels {
+		labels[i] = strconv.Quote(x)
+	}
+	res.Labels = strings.Join(labels, " ")
+
+	return res
+}
+
+// order returns the list ordering. Bookmarks don't record when they were
+// archived, "archived" then sorts the entries by update date.
+func (f *wallabagListForm) order() goquexp.OrderedExpression {
+	col := goqu.C("created").Table("b")
+	if f.Get("sort").String() != "created" {
+		col = goqu.C("updated").Table("b")
+	}
+
+	if f.Get("order").String() == "asc" {
+		return col.Asc()
+	}
+	return col.Desc()
+}
+
+// newWallabagUpdateForm returns an [updateForm] bound with the
+// wallabag entry values.
+func newWallabagUpdateForm(tr forms.Translator, values url.Values) *updateForm {
+	data := url.Values{}
+	if values.Has("title") {
+		data["title"] = values["title"]
+	}
+	if values.Has("archive") {
+		data["is_archived"] = values["archive"]
+	}
+	if values.Has("starred") {
+		data["is_marked"] = values["starred"]
+	}
+	if tags := wallabagTags(values.Get("tags")); len(tags) > 0 {
+		data["labels"] = tags
+	}
+
+	f := newUpdateForm(tr)
+	forms.BindValues(f, data)
+	return f
+}
+
+type wallabagEntryList struct {
+	Page     int                     `json:"page"`
+	Limit    int                     `json:"limit"`
+	Pages    int                     `json:"pages"`
+	Total    int                     `json:"total"`
+	Links    map[string]wallabagLink `json:"_links"`
+	Embedded struct {
+		Items []wallabagEntry `json:"items"`
+	} `json:"_embedded"`
+}
+
+type wallabagEntry struct {
+	ID             int                     `json:"id"`
+	Title          string                  `json:"title"`
+	URL            string                  `json:"url"`
+	HashedURL      string                  `json:"hashed_url"`
+	GivenURL       string                  `json:"given_url"`
+	HashedGivenURL string                  `json:"hashed_given_url"`
+	IsArchived     int                     `json:"is_archived"`
+	IsStarred      int                     `json:"is_starred"`
+	IsPublic       bool                    `json:"is_public"`
+	Content        string                  `json:"content"`
+	CreatedAt      wallabagTime            `json:"created_at"`
+	UpdatedAt      wallabagTime            `json:"updated_at"`
+	PublishedAt    *wallabagTime           `json:"published_at"`
+	PublishedBy    []string                `json:"published_by"`
+	StarredAt      *wallabagTime           `json:"starred_at"`
+	ArchivedAt     *wallabagTime           `json:"archived_at"`
+	Annotations    []wallabagAnnotation    `json:"annotations"`
+	Mimetype       string                  `json:"mimetype"`
+	Language       string                  `json:"language"`
+	ReadingTime    int                     `json:"reading_time"`
+	DomainName     string                  `json:"domain_name"`
+	PreviewPicture string                  `json:"preview_picture,omitempty"`
+	Tags           []wallabagTag           `json:"tags"`
+	UserID         int                     `json:"user_id"`
+	UserName       string                  `json:"user_name"`
+	UserEmail      string                  `json:"user_email"`
+	Links          map[string]wallabagLink `json:"_links"`
+}
+
+type wallabagLink struct {
+	Href string `json:"href"`
+}
+
+type wallabagTag struct {
+	ID    int    `json:"id"`
+	Label string `json:"label"`
+	Slug  string `json:"slug"`
+}
+
+func newWallabagTag(name string) wallabagTag {
+	return wallabagTag{
+		ID:    wallabagID(name),
+		Label: name,
+		Slug:  utils.Slug(name),
+	}
+}
+
+func newWallabagTags(labels []string) []wallabagTag {
+	res := make([]wallabagTag, len(labels))
+	for i, x := range labels {
+		res[i] = newWallabagTag(x)
+	}
+	return res
+}
+
+type wallabagAnnotation struct {
+	ID                     int             `json:"id"`
+	AnnotatorSchemaVersion string          `json:"annotator_schema_version"`
+	Text                   string          `json:"text"`
+	Quote                  string          `json:"quote"`
+	Ranges                 []wallabagRange `json:"ranges"`
+	CreatedAt              wallabagTime    `json:"created_at"`
+	UpdatedAt              wallabagTime    `json:"updated_at"`
+}
+
+type wallabagRange struct {
+	Start       string      `json:"start"`
+	StartOffset json.Number `json:"startOffset"`
+	End         string      `json:"end"`
+	EndOffset   json.Number `json:"endOffset"`
+}
+
+func newWallabagAnnotation(a *bookmarks.BookmarkAnnotation) wallabagAnnotation {
+	return wallabagAnnotation{
+		ID:                     wallabagID(a.ID),
+		AnnotatorSchemaVersion: "v1.0",
+		Text:                   a.Note,
+		Quote:                  a.Text,
+		Ranges: []wallabagRange{{
+			Start:       "/" + a.StartSelector,
+			StartOffset: json.Number(strconv.Itoa(a.StartOffset)),
+			End:         "/" + a.EndSelector,
+			EndOffset:   json.Number(strconv.Itoa(a.EndOffset)),
+		}},
+		CreatedAt: wallabagTime(a.Created),
+		UpdatedAt: wallabagTime(a.Created),
+	}
+}
+
+func newWallabagAnnotations(annotations bookmarks.BookmarkAnnotations) []wallabagAnnotation {
+	res := make([]wallabagAnnotation, len(annotations))
+	for i, x := range annotations {
+		res[i] = newWallabagAnnotation(x)
+	}
+	return res
+}
+
+// wallabagTime is a time value encoded like PHP's ISO 8601 format.
+type wallabagTime time.Time
+
+func (t wallabagTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(t).Format(wallabagTimeFormat))
+}
+
+// wallabagID returns a stable, positive, numeric ID for values that
+// don't have one in Readeck, like labels and annotations.
+func wallabagID(s string) int {
+	return int(crc32.ChecksumIEEE([]byte(s)) & 0x7fffffff)
+}
+
+// wallabagHashURL returns the SHA1 hash of a URL, as used
+// by wallabag to check if an entry exists.
+func wallabagHashURL(s string) string {
+	h := sha1.Sum([]byte(s)) //nolint:gosec
+	return hex.EncodeToString(h[:])
+}
+
+func wallabagBool(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// wallabagTags splits a comma separated list of tags.
+func wallabagTags(s string) []string {
+	res := []string{}
+	for _, x := range strings.Split(s, ",") {
+		if x = strings.TrimSpace(x); x != "" {
+			res = append(res, x)
+		}
+	}
+	return res
+}
+
+// wallabagValues returns the request's query string and body values.
+// Wallabag clients send JSON or form data, with values that don't always
+// have the expected type (ie. "archive": 1), so every value is
+// converted to a string before binding a form.
+func wallabagValues(r *http.Request) (url.Values, error) {
+	res := r.URL.Query()
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("content-type"))
+	switch mediaType {
+	case "application/json", "text/json":
+		data := map[string]any{}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		for k, v := range data {
+			switch v := v.(type) {
+			case string:
+				res.Set(k, v)
+			case bool:
+				res.Set(k, strconv.FormatBool(v))
+			case float64:
+				res.Set(k, strconv.FormatFloat(v, 'f', -1, 64))
+			case []any:
+				res.Del(k)
+				for _, x := range v {
+					if s, ok := x.(string); ok {
+						res.Add(k, s)
+					}
+				}
+			}
+		}
+	case "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		for k, v := range r.PostForm {
+			res[k] = v
+		}
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			return nil, err
+		}
+		for k, v := range r.PostForm {
+			res[k] = v
+		}
+	}
+
+	return res, nil
+}
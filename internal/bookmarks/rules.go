@@ -0,0 +1,331 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/exp"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+const (
+	// RuleTable is the rule table name in database.
+	RuleTable = "bookmark_rule"
+
+	// RuleEventSaved is the event sent when a new bookmark's
+	// extraction is done.
+	RuleEventSaved = "saved"
+	// RuleEventRead is the event sent when a bookmark's reading
+	// progress reaches 100%.
+	RuleEventRead = "read"
+)
+
+// RuleEvents is the list of events a rule can run on.
+var RuleEvents = []string{RuleEventSaved, RuleEventRead}
+
+var (
+	// Rules is the rule query manager.
+	Rules = RuleManager{}
+
+	// ErrRuleNotFound is returned when a rule record was not found.
+	ErrRuleNotFound = errors.New("not found")
+)
+
+// Rule is a user defined rule in the database. When one of its events
+// occurs on a bookmark matching its conditions, the rule's actions
+// are applied to the bookmark.
+type Rule struct {
+	ID         int            `db:"id" goqu:"skipinsert,skipupdate"`
+	UID        string         `db:"uid"`
+	UserID     *int           `db:"user_id"`
+	Created    time.Time      `db:"created" goqu:"skipupdate"`
+	Updated    time.Time      `db:"updated"`
+	Name       string         `db:"name"`
+	IsEnabled  bool           `db:"is_enabled"`
+	Events     types.Strings  `db:"events"`
+	Conditions RuleConditions `db:"conditions"`
+	Actions    RuleActions    `db:"actions"`
+}
+
+// RuleConditions are the conditions a bookmark must match for a rule
+// to apply. Empty values are ignored.
+type RuleConditions struct {
+	// Search uses the same syntax as the bookmark search.
+	Search   string        `json:"search"`
+	Site     string        `json:"site"`
+	Domain   string        `json:"domain"`
+	Type     types.Strings `json:"type"`
+	MinWords int           `json:"min_words"`
+	MaxWords int           `json:"max_words"`
+}
+
+// Scan loads a [RuleConditions] instance from a column.
+func (c *RuleConditions) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	v, err := types.JSONBytes(value)
+	if err != nil {
+		return err
+	}
+	json.Unmarshal(v, c) //nolint:errcheck
+	return nil
+}
+
+// Value encodes a [RuleConditions] value for storage.
+func (c RuleConditions) Value() (driver.Value, error) {
+	v, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return string(v), nil
+}
+
+// ToSelectDataSet adds the conditions to the given [*goqu.SelectDataset]
+// and returns it. The bookmarks are the given user's ones.
+func (c RuleConditions) ToSelectDataSet(ds *goqu.SelectDataset, userID int) (*goqu.SelectDataset, error) {
+	filters := Filters{
+		Search: c.Search,
+		Site:   c.Site,
+		Type:   c.Type,
+	}
+	if err := filters.LoadDuplicates(userID); err != nil {
+		return nil, err
+	}
+	ds = filters.ToSelectDataSet(ds)
+
+	if c.Domain != "" {
+		ds = ds.Where(goqu.C("domain").Table("b").Eq(c.Domain))
+	}
+	if c.MinWords > 0 {
+		ds = ds.Where(goqu.C("word_count").Table("b").Gte(c.MinWords))
+	}
+	if c.MaxWords > 0 {
+		ds = ds.Where(goqu.C("word_count").Table("b").Lte(c.MaxWords))
+	}
+
+	return ds, nil
+}
+
+// RuleActions are the changes a rule applies to a bookmark.
+type RuleActions struct {
+	Labels       types.Strings `json:"labels"`
+	IsMarked     bool          `json:"is_marked"`
+	IsArchived   bool          `json:"is_archived"`
+	ReadProgress *int          `json:"read_progress"`
+	// SendEPUB sends the bookmark, as an EPUB file,
+	// to the user's e-reader address.
+	SendEPUB bool `json:"send_epub"`
+}
+
+// Scan loads a [RuleActions] instance from a column.
+func (a *RuleActions) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	v, err := types.JSONBytes(value)
+	if err != nil {
+		return err
+	}
+	json.Unmarshal(v, a) //nolint:errcheck
+	return nil
+}
+
+// Value encodes a [RuleActions] value for storage.
+func (a RuleActions) Value() (driver.Value, error) {
+	v, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	return string(v), nil
+}
+
+// RuleManager is a query helper for rule entries.
+type RuleManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *RuleManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(RuleTable).As("r")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *RuleManager) GetOne(expressions ...goqu.Expression) (*Rule, error) {
+	var r Rule
+	found, err := m.Query().Where(expressions...).ScanStruct(&r)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrRuleNotFound
+	}
+
+	return &r, nil
+}
+
+// ForEvent returns the user's enabled rules that run on the given event.
+func (m *RuleManager) ForEvent(userID int, event string) ([]*Rule, error) {
+	var items []*Rule
+	err := m.Query().
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("is_enabled").Eq(true),
+		).
+		Order(goqu.C("id").Asc()).
+		ScanStructs(&items)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(items, func(r *Rule) bool {
+		return !slices.Contains(r.Events, event)
+	}), nil
+}
+
+// Create inserts a new rule in the database.
+func (m *RuleManager) Create(rule *Rule) error {
+	if rule.UserID == nil {
+		return errors.New("no rule user")
+	}
+
+	rule.Created = time.Now()
+	rule.Updated = rule.Created
+	rule.UID = base58.NewUUID()
+
+	if rule.Events == nil {
+		rule.Events = types.Strings{}
+	}
+	if rule.Actions.Labels == nil {
+		rule.Actions.Labels = types.Strings{}
+	}
+
+	ds := db.Q().Insert(RuleTable).
+		Rows(rule).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	rule.ID = id
+
+	return nil
+}
+
+// Update updates some rule values.
+func (r *Rule) Update(v interface{}) error {
+	if r.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		v["updated"] = time.Now()
+	default:
+		//
+	}
+
+	_, err := db.Q().Update(RuleTable).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(r.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Save updates all the rule values.
+func (r *Rule) Save() error {
+	r.Updated = time.Now()
+	return r.Update(r)
+}
+
+// Delete removes a rule from the database.
+func (r *Rule) Delete() error {
+	_, err := db.Q().Delete(RuleTable).Prepared(true).
+		Where(goqu.C("id").Eq(r.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Bookmarks returns a dataset of the rule owner's bookmarks
+// matching the rule's conditions.
+func (r *Rule) Bookmarks() (*goqu.SelectDataset, error) {
+	// The search condition joins the full text table, so the
+	// selected columns must be qualified.
+	ds := Bookmarks.Query().Select(goqu.I("b.*")).Where(
+		goqu.C("user_id").Table("b").Eq(*r.UserID),
+		goqu.C("state").Table("b").Eq(StateLoaded),
+	)
+	return r.Conditions.ToSelectDataSet(ds, *r.UserID)
+}
+
+// Matches returns true when the bookmark matches the rule's conditions.
+func (r *Rule) Matches(b *Bookmark) (bool, error) {
+	ds, err := r.Bookmarks()
+	if err != nil {
+		return false, err
+	}
+	count, err := ds.Where(goqu.C("id").Table("b").Eq(b.ID)).Count()
+	return count > 0, err
+}
+
+// Apply applies the rule's actions to a bookmark. Sending the bookmark
+// to an e-reader and the webhook events are left to the caller.
+// It returns true when the bookmark was changed.
+func (r *Rule) Apply(b *Bookmark) (bool, error) {
+	updated := map[string]interface{}{}
+
+	if len(r.Actions.Labels) > 0 {
+		labels := append(slices.Clone(b.Labels), r.Actions.Labels...)
+		slices.SortFunc(labels, exp.UnaccentCompare)
+		labels = slices.Compact(labels)
+		if !slices.Equal(labels, b.Labels) {
+			b.Labels = labels
+			updated["labels"] = b.Labels
+		}
+	}
+	if r.Actions.IsMarked && !b.IsMarked {
+		b.IsMarked = true
+		updated["is_marked"] = true
+	}
+	if r.Actions.IsArchived && !b.IsArchived {
+		b.IsArchived = true
+		updated["is_archived"] = true
+	}
+	if p := r.Actions.ReadProgress; p != nil && *p != b.ReadProgress {
+		b.ReadProgress = *p
+		updated["read_progress"] = b.ReadProgress
+		if b.ReadProgress == 0 || b.ReadProgress == 100 {
+			b.ReadAnchor = ""
+			updated["read_anchor"] = ""
+		}
+	}
+
+	if len(updated) == 0 {
+		return false, nil
+	}
+
+	return true, b.Update(updated)
+}
+
+// GetSumStrings returns the string used to generate the etag
+// of the rule(s).
+func (r *Rule) GetSumStrings() []string {
+	return []string{r.UID, r.Updated.String()}
+}
@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks
+
+import (
+	"github.com/doug-martin/goqu/v9"
+	goquexp "github.com/doug-martin/goqu/v9/exp"
+
+	"codeberg.org/readeck/readeck/pkg/simhash"
+)
+
+// TextFingerprint returns the similarity hash of a bookmark's text.
+// Bookmarks with close fingerprints have nearly the same content, even
+// when their URLs have nothing in common. It returns 0 when the text
+// is too short.
+func TextFingerprint(text string) int64 {
+	return int64(simhash.Sum(text)) //nolint:gosec
+}
+
+type fingerprint struct {
+	ID      int    `db:"id"`
+	UID     string `db:"uid"`
+	UserID  int    `db:"user_id"`
+	Simhash int64  `db:"simhash"`
+}
+
+// getFingerprints returns the fingerprints of the bookmarks, outside of the
+// trash, matching the given conditions. Empty fingerprints are left out.
+func getFingerprints(conditions ...goquexp.Expression) ([]fingerprint, error) {
+	res := []fingerprint{}
+	err := Bookmarks.Query().
+		Select(goqu.I("b.id"), goqu.I("b.uid"), goqu.I("b.user_id"), goqu.I("b.simhash")).
+		Where(goqu.I("b.simhash").Neq(0)).
+		Where(conditions...).
+		ScanStructs(&res)
+	return res, err
+}
+
+// similarIDs returns the IDs of the fingerprints close to h, owned by the
+// given user.
+func similarIDs(list []fingerprint, userID int, h int64) []int {
+	res := []int{}
+	for _, x := range list {
+		if x.UserID == userID && simhash.Similar(uint64(h), uint64(x.Simhash)) { //nolint:gosec
+			res = append(res, x.ID)
+		}
+	}
+	return res
+}
+
+// duplicateIDs returns the IDs of the fingerprints close to at least
+// one other fingerprint of the same user.
+func duplicateIDs(list []fingerprint) []int {
+	indexes := map[int]*simhash.Index{}
+	for _, x := range list {
+		if _, ok := indexes[x.UserID]; !ok {
+			indexes[x.UserID] = simhash.NewIndex()
+		}
+		indexes[x.UserID].Add(x.ID, uint64(x.Simhash)) //nolint:gosec
+	}
+
+	res := []int{}
+	for _, x := range list {
+		if len(indexes[x.UserID].Find(uint64(x.Simhash))) > 1 { //nolint:gosec
+			res = append(res, x.ID)
+		}
+	}
+	return res
+}
+
+// GetSimilar returns the bookmarks, outside of the trash, with nearly the
+// same text as the bookmark. The most recent bookmarks come first.
+func (b *Bookmark) GetSimilar() ([]*Bookmark, error) {
+	res := []*Bookmark{}
+	if b.Simhash == 0 || b.UserID == nil {
+		return res, nil
+	}
+
+	list, err := getFingerprints(
+		goqu.I("b.user_id").Eq(*b.UserID),
+		goqu.I("b.id").Neq(b.ID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := similarIDs(list, *b.UserID, b.Simhash)
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	err = Bookmarks.Query().
+		Where(goqu.I("b.id").In(ids)).
+		Order(goqu.I("b.created").Desc(), goqu.I("b.id").Desc()).
+		ScanStructs(&res)
+	return res, err
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks
+
+import (
+	"fmt"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/pkg/storage"
+)
+
+var fileStorage storage.Storage
+
+// InitStorage sets up the bookmark file storage from the configuration.
+func InitStorage() error {
+	s, err := NewStorage(configs.Config.Storage.Type)
+	if err != nil {
+		return err
+	}
+	fileStorage = s
+	return nil
+}
+
+// Storage returns the bookmark file storage. It defaults to the
+// local storage when [InitStorage] was not called.
+func Storage() storage.Storage {
+	if fileStorage == nil {
+		return storage.NewLocal(StoragePath())
+	}
+	return fileStorage
+}
+
+// NewStorage returns a new storage of the given type ("local" or "s3"),
+// using the configuration.
+func NewStorage(kind string) (storage.Storage, error) {
+	switch kind {
+	case "", "local":
+		return storage.NewLocal(StoragePath()), nil
+	case "s3":
+		cfg := configs.Config.Storage.S3
+		return storage.NewS3(storage.S3Config{
+			Endpoint:  cfg.Endpoint,
+			Region:    cfg.Region,
+			Bucket:    cfg.Bucket,
+			Prefix:    cfg.Prefix,
+			AccessKey: cfg.AccessKey,
+			SecretKey: cfg.SecretKey,
+			PathStyle: cfg.PathStyle,
+		})
+	}
+
+	return nil, fmt.Errorf("unknown storage type %q", kind)
+}
@@ -0,0 +1,265 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/internal/db/exp"
+	"codeberg.org/readeck/readeck/internal/webhooks"
+	"codeberg.org/readeck/readeck/pkg/base58"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+// Batch operations.
+const (
+	BatchAddLabels    = "add_labels"
+	BatchRemoveLabels = "remove_labels"
+	BatchArchive      = "archive"
+	BatchUnarchive    = "unarchive"
+	BatchMark         = "mark"
+	BatchUnmark       = "unmark"
+	BatchRead         = "read"
+	BatchUnread       = "unread"
+	BatchDelete       = "delete"
+	BatchRefresh      = "refresh"
+	BatchExport       = "export"
+)
+
+const (
+	// batchExpiration is the time a batch stays in the store.
+	batchExpiration = 24 * time.Hour
+
+	// batchSaveInterval is the number of bookmarks processed
+	// between two progress updates.
+	batchSaveInterval = 20
+)
+
+var (
+	// BatchTask is the task that applies a batch operation.
+	BatchTask superbus.Task
+
+	// ErrBatchNotFound is returned when a batch does not exist.
+	ErrBatchNotFound = errors.New("batch not found")
+)
+
+// Batch is an operation applied to a list of bookmarks.
+// It lives in the bus store and carries its own progress.
+type Batch struct {
+	ID        string   `json:"id"`
+	UserID    int      `json:"user_id"`
+	Operation string   `json:"operation"`
+	Labels    []string `json:"labels,omitempty"`
+	IDs       []int    `json:"ids"`
+	Done      int      `json:"done"`
+	Failed    int      `json:"failed"`
+}
+
+func init() {
+	bus.OnReady(func() {
+		BatchTask = bus.Tasks().NewTask(
+			"bookmarks.batch",
+			superbus.WithUnmarshall(func(data []byte) interface{} {
+				var res string
+				err := json.Unmarshal(data, &res)
+				if err != nil {
+					panic(err)
+				}
+				return res
+			}),
+			superbus.WithTaskHandler(batchHandler),
+		)
+	})
+}
+
+// NewBatch returns a new [Batch] for the given user and bookmark IDs.
+func NewBatch(userID int, operation string, labels []string, ids []int) *Batch {
+	return &Batch{
+		ID:        base58.NewUUID(),
+		UserID:    userID,
+		Operation: operation,
+		Labels:    labels,
+		IDs:       ids,
+	}
+}
+
+// GetBatch loads a batch from the store.
+func GetBatch(id string) (*Batch, error) {
+	data := bus.Store().Get("bookmark_batch_" + id)
+	if data == "" {
+		return nil, ErrBatchNotFound
+	}
+
+	res := new(Batch)
+	if err := json.Unmarshal([]byte(data), res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (b *Batch) save() error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return bus.Store().Set("bookmark_batch_"+b.ID, string(data), batchExpiration)
+}
+
+// Start saves the batch and launches its task. An export has
+// nothing to process and is ready as soon as it's saved.
+func (b *Batch) Start() error {
+	if b.Operation == BatchExport {
+		b.Done = len(b.IDs)
+	}
+	if err := b.save(); err != nil {
+		return err
+	}
+	if b.IsFinished() {
+		return nil
+	}
+
+	return BatchTask.Run(b.ID, b.ID)
+}
+
+// IsFinished returns true when every bookmark was processed.
+func (b *Batch) IsFinished() bool {
+	return b.Done+b.Failed >= len(b.IDs)
+}
+
+// Run applies the operation to every bookmark that was not
+// processed yet. The progress is saved on a regular basis.
+// Caution: it should only run inside a task.
+func (b *Batch) Run() error {
+	logger := slog.With(
+		slog.String("batch", b.ID),
+		slog.String("operation", b.Operation),
+	)
+
+	for i, id := range b.IDs[b.Done+b.Failed:] {
+		if err := b.apply(id); err != nil {
+			logger.Error("batch operation",
+				slog.Int("bookmark_id", id),
+				slog.Any("err", err),
+			)
+			b.Failed++
+		} else {
+			b.Done++
+		}
+
+		if (i+1)%batchSaveInterval == 0 {
+			if err := b.save(); err != nil {
+				return err
+			}
+		}
+	}
+
+	logger.Info("batch finished",
+		slog.Int("done", b.Done),
+		slog.Int("failed", b.Failed),
+	)
+	return b.save()
+}
+
+func (b *Batch) apply(id int) error {
+	bm, err := bookmarks.Bookmarks.GetOne(
+		goqu.I("b.id").Eq(id),
+		goqu.I("b.user_id").Eq(b.UserID),
+	)
+	if err != nil {
+		return err
+	}
+
+	updated := map[string]interface{}{}
+	switch b.Operation {
+	case BatchAddLabels, BatchRemoveLabels:
+		labels := slices.Clone(bm.Labels)
+		if b.Operation == BatchAddLabels {
+			labels = append(labels, b.Labels...)
+		} else {
+			labels = slices.DeleteFunc(labels, func(s string) bool {
+				return slices.Contains(b.Labels, s)
+			})
+		}
+		slices.SortFunc(labels, exp.UnaccentCompare)
+		labels = slices.Compact(labels)
+		if slices.Equal(labels, bm.Labels) {
+			return nil
+		}
+		bm.Labels = labels
+		updated["labels"] = labels
+	case BatchArchive, BatchUnarchive:
+		v := b.Operation == BatchArchive
+		if bm.IsArchived == v {
+			return nil
+		}
+		bm.IsArchived = v
+		updated["is_archived"] = v
+	case BatchMark, BatchUnmark:
+		v := b.Operation == BatchMark
+		if bm.IsMarked == v {
+			return nil
+		}
+		bm.IsMarked = v
+		updated["is_marked"] = v
+	case BatchRead, BatchUnread:
+		v := 0
+		if b.Operation == BatchRead {
+			v = 100
+		}
+		if bm.ReadProgress == v {
+			return nil
+		}
+		bm.ReadProgress = v
+		updated["read_progress"] = v
+		updated["read_anchor"] = ""
+	case BatchDelete:
+		return bm.Trash()
+	case BatchRefresh:
+		return RefreshBookmark(bm)
+	default:
+		return nil
+	}
+
+	updated["updated"] = time.Now()
+	if err = bm.Update(updated); err != nil {
+		return err
+	}
+
+	switch b.Operation {
+	case BatchAddLabels, BatchRemoveLabels:
+		webhooks.SendBookmarkEvent(webhooks.EventBookmarkLabeled, bm, nil)
+	case BatchArchive:
+		webhooks.SendBookmarkEvent(webhooks.EventBookmarkArchived, bm, nil)
+	case BatchMark:
+		webhooks.SendBookmarkEvent(webhooks.EventBookmarkMarked, bm, nil)
+	case BatchRead:
+		return TriggerRules(bm, bookmarks.RuleEventRead)
+	}
+
+	return nil
+}
+
+func batchHandler(data interface{}) {
+	id := data.(string)
+	logger := slog.With(slog.String("batch", id))
+
+	b, err := GetBatch(id)
+	if err != nil {
+		logger.Error("batch retrieve", slog.Any("err", err))
+		return
+	}
+
+	if err = b.Run(); err != nil {
+		logger.Error("batch progress", slog.Any("err", err))
+	}
+}
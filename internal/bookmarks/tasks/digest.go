@@ -0,0 +1,198 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tasks
+
+import (
+	"log/slog"
+	"net"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/wneessen/go-mail"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/internal/email"
+	"codeberg.org/readeck/readeck/locales"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+	"codeberg.org/readeck/readeck/pkg/utils"
+)
+
+const (
+	// digestPollInterval is the interval between two checks
+	// of the collections with an email digest.
+	digestPollInterval = time.Hour
+
+	// digestMaxItems is the maximum number of bookmarks in a digest.
+	digestMaxItems = 50
+
+	// digestUnsubscribeTTL is the validity of an unsubscribe link.
+	digestUnsubscribeTTL = 90 * 24 * time.Hour
+)
+
+// PollDigestsTask is the periodic task that sends the
+// collection email digests.
+var PollDigestsTask superbus.Task
+
+func init() {
+	bus.OnReady(func() {
+		PollDigestsTask = bus.Tasks().NewTask(
+			"collection.digest_poll",
+			superbus.WithTaskInterval(digestPollInterval),
+			superbus.WithTaskHandler(pollDigestsHandler),
+		)
+	})
+}
+
+func pollDigestsHandler(_ interface{}) {
+	if !email.CanSendEmail() {
+		return
+	}
+
+	var items []struct {
+		Collection *bookmarks.Collection `db:"c"`
+		User       *users.User           `db:"u"`
+	}
+	err := bookmarks.Collections.Query().
+		Join(goqu.T(users.TableName).As("u"), goqu.On(goqu.I("u.id").Eq(goqu.I("c.user_id")))).
+		Where(goqu.I("c.digest_schedule").Neq("")).
+		Order(goqu.I("c.id").Asc()).
+		ScanStructs(&items)
+	if err != nil {
+		slog.Error("digest collection list", slog.Any("err", err))
+		return
+	}
+
+	now := time.Now()
+	for _, x := range items {
+		if !x.Collection.IsDigestDue(now) {
+			continue
+		}
+		if err := SendDigest(x.Collection, x.User, now); err != nil {
+			slog.Error("collection digest",
+				slog.Int("collection_id", x.Collection.ID),
+				slog.Any("err", err),
+			)
+		}
+	}
+}
+
+// SendDigest sends the collection's email digest, listing the bookmarks
+// matching the collection that were added since the last digest.
+// No message is sent when there's no new bookmark.
+func SendDigest(c *bookmarks.Collection, u *users.User, now time.Time) error {
+	since := now.Add(-c.DigestPeriod())
+	if c.DigestSent != nil {
+		since = *c.DigestSent
+	}
+
+	if u.Email != "" && u.HasPermission("email", "send") {
+		ds := bookmarks.Bookmarks.Query().
+			Where(
+				goqu.C("user_id").Table("b").Eq(u.ID),
+				goqu.C("state").Table("b").Eq(bookmarks.StateLoaded),
+				goqu.C("created").Table("b").Gt(since),
+				goqu.C("created").Table("b").Lte(now),
+			)
+		filters := c.Filters
+		if err := filters.LoadDuplicates(u.ID); err != nil {
+			return err
+		}
+		ds = filters.ToSelectDataSet(ds).
+			Order(goqu.I("b.created").Desc()).
+			Limit(digestMaxItems)
+
+		var items []*bookmarks.Bookmark
+		if err := ds.ScanStructs(&items); err != nil {
+			return err
+		}
+
+		if len(items) > 0 {
+			if err := sendDigestEmail(c, u, items, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	return c.SetDigestSent(now)
+}
+
+func sendDigestEmail(c *bookmarks.Collection, u *users.User, items []*bookmarks.Bookmark, now time.Time) error {
+	token, err := bookmarks.EncodeDigestID(uint64(c.ID), now.Add(digestUnsubscribeTTL))
+	if err != nil {
+		return err
+	}
+
+	siteURL := getSiteURL()
+	unsubscribeURL := siteURL.JoinPath("@digest", token).String()
+
+	tr := locales.LoadTranslation(u.Settings.Lang)
+	vars := make(jet.VarMap).
+		Set("translator", tr).
+		Set("gettext", tr.Gettext).
+		Set("ngettext", tr.Ngettext).
+		Set("pgettext", tr.Pgettext).
+		Set("npgettext", tr.Npgettext)
+
+	msg, err := email.NewMsg(
+		configs.Config.Email.FromNoReply.String(),
+		u.Email,
+		"[Readeck] "+tr.Ngettext(
+			"%s: %d new bookmark", "%s: %d new bookmarks", len(items),
+			utils.ShortText(c.Name, 60), len(items),
+		),
+		func(msg *mail.Msg) error {
+			msg.SetGenHeader(mail.HeaderListUnsubscribe, "<"+unsubscribeURL+">")
+			msg.SetGenHeader(mail.HeaderListUnsubscribePost, "List-Unsubscribe=One-Click")
+			return nil
+		},
+		email.WithHTMLTemplate(
+			"/emails/digest",
+			vars,
+			map[string]any{
+				"Collection":     c,
+				"CollectionURL":  siteURL.JoinPath("bookmarks/collections", c.UID).String(),
+				"BookmarkURL":    siteURL.JoinPath("bookmarks").String() + "/",
+				"Items":          items,
+				"SiteURL":        siteURL.String(),
+				"UnsubscribeURL": unsubscribeURL,
+			},
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	return email.Sender.SendEmail(msg)
+}
+
+// getSiteURL returns the instance's root URL. Without a configured
+// base URL, it's built from the server's host and port.
+func getSiteURL() *url.URL {
+	if u := configs.Config.Server.BaseURL; u != nil && u.IsHTTP() {
+		res := *u.URL
+		return &res
+	}
+
+	p := path.Clean("/" + configs.Config.Server.Prefix)
+	if p != "/" {
+		p += "/"
+	}
+
+	return &url.URL{
+		Scheme: "http",
+		Host: net.JoinHostPort(
+			configs.Config.Server.Host,
+			strconv.Itoa(configs.Config.Server.Port),
+		),
+		Path: p,
+	}
+}
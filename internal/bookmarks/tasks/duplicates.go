@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tasks
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+// MergeDuplicatesTask merges the duplicate bookmarks of a user.
+// Its parameter is the user ID.
+var MergeDuplicatesTask superbus.Task
+
+func init() {
+	bus.OnReady(func() {
+		MergeDuplicatesTask = bus.Tasks().NewTask(
+			"bookmarks.merge_duplicates",
+			superbus.WithUnmarshall(func(data []byte) interface{} {
+				var res int
+				err := json.Unmarshal(data, &res)
+				if err != nil {
+					panic(err)
+				}
+				return res
+			}),
+			superbus.WithTaskHandler(mergeDuplicatesHandler),
+		)
+	})
+}
+
+// MergeDuplicates merges every group of bookmarks saved with the same URL.
+// The oldest bookmark of a group receives the labels, highlights and status
+// of the others, which go to the trash.
+// When userID is 0, it merges the duplicates of all the users.
+// It returns the number of bookmarks moved to the trash.
+func MergeDuplicates(userID int) (int, error) {
+	groups, err := bookmarks.Bookmarks.GetDuplicateGroups(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, g := range groups {
+		var items []*bookmarks.Bookmark
+		if err := bookmarks.Bookmarks.Query().
+			Where(goqu.I("b.id").In(g.IDs)).
+			Order(goqu.I("b.created").Asc(), goqu.I("b.id").Asc()).
+			ScanStructs(&items); err != nil {
+			return count, err
+		}
+		if len(items) < 2 {
+			continue
+		}
+
+		if err := items[0].Merge(items[1:]...); err != nil {
+			return count, err
+		}
+		count += len(items) - 1
+	}
+
+	return count, nil
+}
+
+func mergeDuplicatesHandler(data interface{}) {
+	userID := data.(int)
+	logger := slog.With(slog.Int("user_id", userID))
+
+	count, err := MergeDuplicates(userID)
+	if err != nil {
+		logger.Error("merge duplicates", slog.Any("err", err))
+		return
+	}
+	logger.Info("duplicates merged", slog.Int("count", count))
+}
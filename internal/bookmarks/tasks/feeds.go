@@ -0,0 +1,243 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/internal/webhooks"
+	"codeberg.org/readeck/readeck/pkg/extract"
+	"codeberg.org/readeck/readeck/pkg/feed"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+// maxFeedSize is the maximum size of a feed document.
+const maxFeedSize = 10 << 20
+
+var (
+	// FetchFeedTask is the task that fetches one feed.
+	FetchFeedTask superbus.Task
+	// PollFeedsTask is the periodic task that fetches every feed.
+	PollFeedsTask superbus.Task
+)
+
+func init() {
+	bus.OnReady(func() {
+		FetchFeedTask = bus.Tasks().NewTask(
+			"feed.fetch",
+			superbus.WithUnmarshall(func(data []byte) interface{} {
+				var res int
+				err := json.Unmarshal(data, &res)
+				if err != nil {
+					panic(err)
+				}
+				return res
+			}),
+			superbus.WithTaskHandler(fetchFeedHandler),
+		)
+
+		PollFeedsTask = bus.Tasks().NewTask(
+			"feed.poll",
+			superbus.WithTaskInterval(
+				time.Duration(configs.Config.Bookmarks.FeedPollInterval)*time.Minute,
+			),
+			superbus.WithTaskHandler(pollFeedsHandler),
+		)
+	})
+}
+
+func fetchFeedHandler(data interface{}) {
+	id := data.(int)
+	logger := slog.With(slog.Int("feed_id", id))
+
+	f, err := bookmarks.Feeds.GetOne(goqu.C("id").Eq(id))
+	if err != nil {
+		logger.Error("feed retrieve", slog.Any("err", err))
+		return
+	}
+
+	if err = FetchFeed(f); err != nil {
+		logger.Error("feed fetch", slog.Any("err", err))
+	}
+}
+
+func pollFeedsHandler(_ interface{}) {
+	var items []*bookmarks.Feed
+	if err := bookmarks.Feeds.Query().Order(goqu.C("id").Asc()).ScanStructs(&items); err != nil {
+		slog.Error("feed list", slog.Any("err", err))
+		return
+	}
+
+	for _, f := range items {
+		if err := FetchFeed(f); err != nil {
+			slog.Error("feed fetch",
+				slog.Int("feed_id", f.ID),
+				slog.Any("err", err),
+			)
+		}
+	}
+}
+
+// FetchFeed retrieves a feed document and creates a bookmark for every
+// new entry. The first time a feed is fetched, its current entries
+// are only marked as seen, so a new subscription doesn't flood the
+// bookmark list.
+// Once done, the feed's old bookmarks are archived when the feed
+// has an ArchiveAfter value.
+func FetchFeed(f *bookmarks.Feed) error {
+	logger := slog.With(
+		slog.Int("feed_id", f.ID),
+		slog.String("url", f.URL),
+	)
+
+	firstFetch := f.LastFetched == nil
+	now := time.Now()
+	f.LastFetched = &now
+
+	doc, err := loadFeed(f.URL)
+	if err != nil {
+		f.LastError = err.Error()
+		if err := f.Save(); err != nil {
+			logger.Error("saving feed", slog.Any("err", err))
+		}
+		return err
+	}
+
+	f.LastError = ""
+	f.SiteURL = doc.Link
+	if f.Title == "" {
+		f.Title = doc.Title
+	}
+	if f.Title == "" {
+		f.Title = f.URL
+	}
+
+	seen := map[string]bool{}
+	for _, x := range f.Entries {
+		seen[x] = true
+	}
+
+	// Feeds usually list their most recent entry first. Going backward
+	// gives the bookmarks the same order as the entries.
+	entries := types.Strings{}
+	done := map[string]bool{}
+	created := 0
+	for _, item := range slices.Backward(doc.Items) {
+		if done[item.ID] {
+			continue
+		}
+		if !firstFetch && !seen[item.ID] {
+			if err := createFeedBookmark(f, item); err != nil {
+				logger.Error("bookmark create",
+					slog.String("entry", item.ID),
+					slog.Any("err", err),
+				)
+				continue
+			}
+			created++
+		}
+		done[item.ID] = true
+		entries = append(entries, item.ID)
+	}
+	f.Entries = entries
+
+	if err = f.Save(); err != nil {
+		return err
+	}
+
+	archived, err := f.ArchiveBookmarks()
+	if err != nil {
+		return err
+	}
+
+	logger.Info("feed fetched",
+		slog.Int("created", created),
+		slog.Int64("archived", archived),
+	)
+	return nil
+}
+
+// loadFeed retrieves and parses a feed document. It uses the
+// extractor's HTTP client and its settings (denied IPs and proxies).
+func loadFeed(src string) (*feed.Feed, error) {
+	proxyList := make([]extract.ProxyMatcher, len(configs.Config.Extractor.ProxyMatch))
+	for i, x := range configs.Config.Extractor.ProxyMatch {
+		proxyList[i] = x
+	}
+
+	ex, err := extract.New(
+		src,
+		extract.SetDeniedIPs(configs.ExtractorDeniedIPs()),
+		extract.SetProxyList(proxyList),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	client := ex.Client()
+	extract.SetHeader(client, "Accept",
+		"application/rss+xml, application/atom+xml, application/rdf+xml, application/xml;q=0.9, text/xml;q=0.8",
+	)
+
+	rsp, err := client.Get(ex.URL.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close() //nolint:errcheck
+
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid status code (%d)", rsp.StatusCode)
+	}
+
+	return feed.Parse(io.LimitReader(rsp.Body, maxFeedSize), rsp.Request.URL)
+}
+
+// createFeedBookmark creates a new bookmark from a feed entry
+// and starts its extraction.
+func createFeedBookmark(f *bookmarks.Feed, item feed.Item) error {
+	uri, err := url.Parse(item.Link)
+	if err != nil {
+		return err
+	}
+	uri.Fragment = ""
+
+	b := &bookmarks.Bookmark{
+		UserID:   f.UserID,
+		FeedID:   &f.ID,
+		State:    bookmarks.StateLoading,
+		URL:      uri.String(),
+		Title:    item.Title,
+		Site:     uri.Hostname(),
+		SiteName: uri.Hostname(),
+		Labels:   slices.Clone(f.Labels),
+	}
+	if b.Labels == nil {
+		b.Labels = types.Strings{}
+	}
+
+	if err = bookmarks.Bookmarks.Create(b); err != nil {
+		return err
+	}
+	webhooks.SendBookmarkEvent(webhooks.EventBookmarkCreated, b, nil)
+
+	return ExtractPageTask.Run(b.ID, ExtractParams{
+		BookmarkID: b.ID,
+		RequestID:  f.UID,
+		FindMain:   true,
+	})
+}
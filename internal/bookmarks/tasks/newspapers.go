@@ -0,0 +1,260 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/converter"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/internal/email"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+const (
+	// newspaperPollInterval is the interval between two checks
+	// of the newspaper schedules.
+	newspaperPollInterval = 5 * time.Minute
+
+	// NewspaperMaxItems is the maximum number of bookmarks in an issue.
+	NewspaperMaxItems = 100
+)
+
+var (
+	// DeliverNewspaperTask is the task that delivers a newspaper's issue.
+	DeliverNewspaperTask superbus.Task
+	// PollNewspapersTask is the periodic task that delivers the
+	// newspapers on schedule.
+	PollNewspapersTask superbus.Task
+
+	errNewspaperNoEmail    = errors.New("emails are not available")
+	errNewspaperNoEPUBAddr = errors.New("no e-reader email address")
+)
+
+func init() {
+	bus.OnReady(func() {
+		DeliverNewspaperTask = bus.Tasks().NewTask(
+			"newspaper.deliver",
+			superbus.WithUnmarshall(func(data []byte) interface{} {
+				var res int
+				err := json.Unmarshal(data, &res)
+				if err != nil {
+					panic(err)
+				}
+				return res
+			}),
+			superbus.WithTaskHandler(deliverNewspaperHandler),
+		)
+
+		PollNewspapersTask = bus.Tasks().NewTask(
+			"newspaper.poll",
+			superbus.WithTaskInterval(newspaperPollInterval),
+			superbus.WithTaskHandler(pollNewspapersHandler),
+		)
+	})
+}
+
+func deliverNewspaperHandler(data interface{}) {
+	id := data.(int)
+	logger := slog.With(slog.Int("newspaper_id", id))
+
+	n, err := bookmarks.Newspapers.GetOne(goqu.C("id").Eq(id))
+	if err != nil {
+		logger.Error("newspaper retrieve", slog.Any("err", err))
+		return
+	}
+	u, err := users.Users.GetOne(goqu.C("id").Eq(*n.UserID))
+	if err != nil {
+		logger.Error("newspaper user", slog.Any("err", err))
+		return
+	}
+
+	if err = DeliverNewspaper(n, u, time.Now()); err != nil {
+		logger.Error("newspaper delivery", slog.Any("err", err))
+	}
+}
+
+func pollNewspapersHandler(_ interface{}) {
+	var items []*bookmarks.Newspaper
+	if err := bookmarks.Newspapers.Query().
+		Order(goqu.I("n.id").Asc()).
+		ScanStructs(&items); err != nil {
+		slog.Error("newspaper list", slog.Any("err", err))
+		return
+	}
+
+	now := time.Now()
+	for _, n := range items {
+		if !n.IsDue(now) {
+			continue
+		}
+		if err := DeliverNewspaperTask.Run(n.ID, n.ID); err != nil {
+			slog.Error("newspaper delivery",
+				slog.Int("newspaper_id", n.ID),
+				slog.Any("err", err),
+			)
+		}
+	}
+}
+
+// DeliverNewspaper builds a newspaper's issue with the unread bookmarks
+// of its collection that were saved since the previous issue. The issue
+// is sent by email and/or published in the OPDS catalog. Once delivered,
+// the issue's bookmarks receive the newspaper's after delivery operation.
+// Nothing is delivered when there's no new bookmark.
+func DeliverNewspaper(n *bookmarks.Newspaper, u *users.User, now time.Time) error {
+	items, err := NewspaperIssue(n, now)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return n.SetDelivered(now, nil, "")
+	}
+
+	lastError := ""
+	if n.SendEmail {
+		if err = sendNewspaperEmail(n, u, items, now); err != nil {
+			lastError = err.Error()
+		}
+	}
+
+	uids := make(types.Strings, len(items))
+	ids := make([]int, len(items))
+	for i, b := range items {
+		uids[i] = b.UID
+		ids[i] = b.ID
+	}
+
+	// The bookmarks are left untouched when the issue could not be sent
+	if n.AfterDelivery != "" && lastError == "" {
+		if err = NewBatch(u.ID, n.AfterDelivery, nil, ids).Run(); err != nil {
+			return err
+		}
+	}
+
+	if err = n.SetDelivered(now, uids, lastError); err != nil {
+		return err
+	}
+	if lastError != "" {
+		return errors.New(lastError)
+	}
+	return nil
+}
+
+// NewspaperIssue returns the bookmarks of a newspaper's issue delivered
+// at the given time. They are the unread and not archived bookmarks
+// of the newspaper's collection, saved since the last successful issue.
+func NewspaperIssue(n *bookmarks.Newspaper, now time.Time) ([]*bookmarks.Bookmark, error) {
+	c, err := bookmarks.Collections.GetOne(goqu.C("id").Eq(n.CollectionID))
+	if err != nil {
+		return nil, err
+	}
+
+	since := now.Add(-n.Period())
+	if n.LastDelivery != nil {
+		since = *n.LastDelivery
+	}
+
+	limit := n.MaxItems
+	if limit <= 0 || limit > NewspaperMaxItems {
+		limit = NewspaperMaxItems
+	}
+
+	ds := bookmarks.Bookmarks.Query().
+		Where(
+			goqu.C("user_id").Table("b").Eq(*n.UserID),
+			goqu.C("state").Table("b").Eq(bookmarks.StateLoaded),
+			goqu.C("is_archived").Table("b").Eq(false),
+			goqu.C("read_progress").Table("b").Lt(100),
+			goqu.C("created").Table("b").Gt(since),
+			goqu.C("created").Table("b").Lte(now),
+		)
+	filters := c.Filters
+	if err := filters.LoadDuplicates(*n.UserID); err != nil {
+		return nil, err
+	}
+	ds = filters.ToSelectDataSet(ds).
+		Order(goqu.I("b.created").Desc()).
+		Limit(uint(limit))
+
+	var items []*bookmarks.Bookmark
+	if err = ds.ScanStructs(&items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// NewspaperLatestIssue returns the bookmarks of a newspaper's latest
+// issue. The bookmarks removed since the delivery are left out.
+func NewspaperLatestIssue(n *bookmarks.Newspaper) ([]*bookmarks.Bookmark, error) {
+	items := []*bookmarks.Bookmark{}
+	if len(n.IssueItems) == 0 {
+		return items, nil
+	}
+
+	err := bookmarks.Bookmarks.Query().
+		Where(
+			goqu.C("user_id").Table("b").Eq(*n.UserID),
+			goqu.C("uid").Table("b").In([]string(n.IssueItems)),
+		).
+		Order(goqu.I("b.created").Desc()).
+		ScanStructs(&items)
+	return items, err
+}
+
+// sendNewspaperEmail sends an issue, as an EPUB attachment,
+// to the user's e-reader address.
+func sendNewspaperEmail(n *bookmarks.Newspaper, u *users.User, items []*bookmarks.Bookmark, now time.Time) error {
+	if !email.CanSendEmail() || !u.HasPermission("email", "send") {
+		return errNewspaperNoEmail
+	}
+	if u.Settings == nil || u.Settings.EmailSettings.EpubTo == "" {
+		return errNewspaperNoEPUBAddr
+	}
+
+	siteURL := getSiteURL()
+	vars := userTemplateVars(u)
+	exporter := converter.NewNewspaperExporter(siteURL, vars, n.Title, now.In(n.Location()))
+
+	msg, err := email.NewMsg(
+		configs.Config.Email.FromNoReply.String(),
+		u.Settings.EmailSettings.EpubTo,
+		"[Readeck EPUB] "+n.Title+" - "+exporter.Date.Format(time.DateOnly),
+		email.WithMDTemplate(
+			"/emails/newspaper.jet.md",
+			vars,
+			map[string]any{
+				"Title":   n.Title,
+				"Date":    exporter.Date,
+				"Items":   items,
+				"SiteURL": siteURL.String(),
+			},
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	if err = exporter.Export(context.Background(), buf, nil, items); err != nil {
+		return err
+	}
+	if err = msg.AttachReader(exporter.Filename(), buf); err != nil {
+		return err
+	}
+
+	return email.Sender.SendEmail(msg)
+}
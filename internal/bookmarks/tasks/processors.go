@@ -6,6 +6,7 @@ package tasks
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/url"
 	"slices"
@@ -13,8 +14,10 @@ import (
 	"github.com/go-shiori/dom"
 
 	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/sitelogins"
 	"codeberg.org/readeck/readeck/pkg/bleach"
 	"codeberg.org/readeck/readeck/pkg/extract"
+	"codeberg.org/readeck/readeck/pkg/extract/contentscripts"
 	"codeberg.org/readeck/readeck/pkg/http/linkheader"
 )
 
@@ -145,3 +148,40 @@ func GetExtractedLinks(ctx context.Context) bookmarks.BookmarkLinks {
 	}
 	return bookmarks.BookmarkLinks{}
 }
+
+// siteLoginProvider returns a [contentscripts.LoginProvider] that
+// looks for the bookmark owner's credentials.
+// The credentials of a user are never used for another one's bookmark.
+func siteLoginProvider(b *bookmarks.Bookmark) contentscripts.LoginProvider {
+	return func(src *url.URL) *contentscripts.LoginCredentials {
+		if b.UserID == nil {
+			return nil
+		}
+
+		l, err := sitelogins.Logins.FindForHost(*b.UserID, src.Hostname())
+		if err != nil {
+			if !errors.Is(err, sitelogins.ErrNotFound) {
+				slog.Error("site login", slog.Any("err", err))
+			}
+			return nil
+		}
+
+		c, err := l.Credentials()
+		if err != nil {
+			slog.Error("site login", slog.String("domain", l.Domain), slog.Any("err", err))
+			return nil
+		}
+
+		cookies, err := c.HTTPCookies()
+		if err != nil {
+			slog.Warn("site login cookies", slog.String("domain", l.Domain), slog.Any("err", err))
+		}
+
+		return &contentscripts.LoginCredentials{
+			Domain:   l.Domain,
+			Username: c.Username,
+			Password: c.Password,
+			Cookies:  cookies,
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tasks
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+// refreshPollInterval is the interval between two checks
+// for bookmarks that are due for a refresh.
+const refreshPollInterval = time.Hour
+
+var (
+	// RefreshBookmarkTask is the task that refreshes one bookmark.
+	RefreshBookmarkTask superbus.Task
+	// PollRefreshTask is the periodic task that refreshes the bookmarks
+	// that are due for a refresh.
+	PollRefreshTask superbus.Task
+)
+
+func init() {
+	bus.OnReady(func() {
+		RefreshBookmarkTask = bus.Tasks().NewTask(
+			"bookmark.refresh",
+			superbus.WithUnmarshall(func(data []byte) interface{} {
+				var res int
+				err := json.Unmarshal(data, &res)
+				if err != nil {
+					panic(err)
+				}
+				return res
+			}),
+			superbus.WithTaskHandler(refreshBookmarkHandler),
+		)
+
+		PollRefreshTask = bus.Tasks().NewTask(
+			"bookmark.refresh_poll",
+			superbus.WithTaskInterval(refreshPollInterval),
+			superbus.WithTaskHandler(pollRefreshHandler),
+		)
+	})
+}
+
+func refreshBookmarkHandler(data interface{}) {
+	id := data.(int)
+	logger := slog.With(slog.Int("bookmark_id", id))
+
+	b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(id))
+	if err != nil {
+		logger.Error("bookmark retrieve", slog.Any("err", err))
+		return
+	}
+
+	if err = RefreshBookmark(b); err != nil {
+		logger.Error("bookmark refresh", slog.Any("err", err))
+	}
+}
+
+func pollRefreshHandler(_ interface{}) {
+	var items []*bookmarks.Bookmark
+	err := bookmarks.Bookmarks.Query().
+		Where(
+			goqu.C("refresh_interval").Gt(0),
+			goqu.C("state").Neq(bookmarks.StateLoading),
+		).
+		Order(goqu.C("id").Asc()).
+		ScanStructs(&items)
+	if err != nil {
+		slog.Error("bookmark list", slog.Any("err", err))
+		return
+	}
+
+	// Every refresh runs in its own task. A refresh still pending
+	// from the previous check is not launched again.
+	now := time.Now()
+	for _, b := range items {
+		if !isRefreshDue(b, now) || RefreshBookmarkTask.IsRunning(b.ID) {
+			continue
+		}
+		if err := RefreshBookmarkTask.Run(b.ID, b.ID); err != nil {
+			slog.Error("bookmark refresh",
+				slog.Int("bookmark_id", b.ID),
+				slog.Any("err", err),
+			)
+		}
+	}
+}
+
+// isRefreshDue returns true when the bookmark's last refresh
+// (or its creation) is older than its refresh interval.
+func isRefreshDue(b *bookmarks.Bookmark, now time.Time) bool {
+	last := b.Created
+	if b.Refreshed != nil {
+		last = *b.Refreshed
+	}
+	return !last.AddDate(0, 0, b.RefreshInterval).After(now)
+}
+
+// RefreshBookmark runs a new extraction of a bookmark. The bookmark's
+// content is first saved as a revision. When the new text differs from
+// the previous one, the revision is kept along with the diff between the
+// two texts. Otherwise, the revision is discarded.
+func RefreshBookmark(b *bookmarks.Bookmark) error {
+	logger := slog.With(slog.Int("bookmark_id", b.ID))
+
+	r, err := b.NewRevision()
+	if err != nil {
+		return err
+	}
+
+	if err = ExtractPage(ExtractParams{
+		BookmarkID: b.ID,
+		FindMain:   true,
+	}); err != nil {
+		return err
+	}
+
+	nb, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(b.ID))
+	if err != nil {
+		return err
+	}
+	*b = *nb
+
+	now := time.Now()
+	if err = b.Update(map[string]interface{}{"refreshed": now}); err != nil {
+		return err
+	}
+	b.Refreshed = &now
+
+	if b.Text == r.Text {
+		logger.Debug("bookmark content unchanged")
+		return r.Delete()
+	}
+
+	logger.Info("bookmark content changed", slog.String("revision", r.UID))
+	return r.Update(goqu.Record{
+		"diff": bookmarks.TextDiff(
+			r.Text, b.Text,
+			r.Created.Format(time.RFC3339), now.Format(time.RFC3339),
+		),
+	})
+}
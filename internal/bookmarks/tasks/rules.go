@@ -0,0 +1,265 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/converter"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/internal/email"
+	"codeberg.org/readeck/readeck/internal/profile/preferences"
+	"codeberg.org/readeck/readeck/internal/webhooks"
+	"codeberg.org/readeck/readeck/locales"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+// ruleApplyBatchSize is the number of bookmarks loaded at once
+// when a rule is applied to existing bookmarks.
+const ruleApplyBatchSize = 100
+
+var (
+	// RunRulesTask is the task that runs the user's rules on a bookmark
+	// after an event.
+	RunRulesTask superbus.Task
+	// ApplyRuleTask is the task that applies a rule to all the
+	// existing bookmarks matching its conditions.
+	ApplyRuleTask superbus.Task
+)
+
+// RuleEventParams contains the rule event parameters.
+type RuleEventParams struct {
+	BookmarkID int
+	Event      string
+}
+
+func init() {
+	bus.OnReady(func() {
+		RunRulesTask = bus.Tasks().NewTask(
+			"bookmark.rules",
+			superbus.WithUnmarshall(func(data []byte) interface{} {
+				var res RuleEventParams
+				err := json.Unmarshal(data, &res)
+				if err != nil {
+					panic(err)
+				}
+				return res
+			}),
+			superbus.WithTaskHandler(runRulesHandler),
+		)
+
+		ApplyRuleTask = bus.Tasks().NewTask(
+			"rule.apply",
+			superbus.WithUnmarshall(func(data []byte) interface{} {
+				var res int
+				err := json.Unmarshal(data, &res)
+				if err != nil {
+					panic(err)
+				}
+				return res
+			}),
+			superbus.WithTaskHandler(applyRuleHandler),
+		)
+	})
+}
+
+// TriggerRules launches the task that runs the user's rules
+// for an event on a bookmark.
+func TriggerRules(b *bookmarks.Bookmark, event string) error {
+	return RunRulesTask.Run(
+		fmt.Sprintf("%d:%s", b.ID, event),
+		RuleEventParams{BookmarkID: b.ID, Event: event},
+	)
+}
+
+func runRulesHandler(data interface{}) {
+	params := data.(RuleEventParams)
+	logger := slog.With(
+		slog.Int("bookmark_id", params.BookmarkID),
+		slog.String("event", params.Event),
+	)
+
+	b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(params.BookmarkID))
+	if err != nil {
+		logger.Error("bookmark retrieve", slog.Any("err", err))
+		return
+	}
+
+	if err = RunRules(b, params.Event); err != nil {
+		logger.Error("bookmark rules", slog.Any("err", err))
+	}
+}
+
+// RunRules applies every enabled rule of the bookmark's owner that runs
+// on the event and whose conditions match the bookmark.
+func RunRules(b *bookmarks.Bookmark, event string) error {
+	if b.UserID == nil {
+		return nil
+	}
+
+	rules, err := bookmarks.Rules.ForEvent(*b.UserID, event)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+
+	var u *users.User
+	for _, r := range rules {
+		ok, err := r.Matches(b)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		slog.Debug("applying rule",
+			slog.Int("bookmark_id", b.ID),
+			slog.Int("rule_id", r.ID),
+		)
+		if _, err = applyRule(r, b); err != nil {
+			return err
+		}
+
+		if r.Actions.SendEPUB {
+			if u == nil {
+				if u, err = users.Users.GetOne(goqu.C("id").Eq(*b.UserID)); err != nil {
+					return err
+				}
+			}
+			if err = sendToEReader(b, u); err != nil {
+				slog.Error("send to e-reader",
+					slog.Int("bookmark_id", b.ID),
+					slog.Any("err", err),
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyRule applies a rule's actions to a bookmark and sends
+// a webhook event for every state the rule changed.
+func applyRule(r *bookmarks.Rule, b *bookmarks.Bookmark) (bool, error) {
+	wasMarked := b.IsMarked
+	wasArchived := b.IsArchived
+	previousLabels := slices.Clone(b.Labels)
+
+	changed, err := r.Apply(b)
+	if err != nil || !changed {
+		return changed, err
+	}
+
+	if b.IsMarked && !wasMarked {
+		webhooks.SendBookmarkEvent(webhooks.EventBookmarkMarked, b, nil)
+	}
+	if b.IsArchived && !wasArchived {
+		webhooks.SendBookmarkEvent(webhooks.EventBookmarkArchived, b, nil)
+	}
+	if !slices.Equal(b.Labels, previousLabels) {
+		webhooks.SendBookmarkEvent(webhooks.EventBookmarkLabeled, b, nil)
+	}
+	return true, nil
+}
+
+// sendToEReader sends a bookmark, as an EPUB file, to the user's
+// e-reader address. Nothing is sent when emails are disabled or
+// when the user has no e-reader address.
+func sendToEReader(b *bookmarks.Bookmark, u *users.User) error {
+	if !email.CanSendEmail() || !u.HasPermission("email", "send") {
+		return nil
+	}
+	if u.Settings == nil || u.Settings.EmailSettings.EpubTo == "" {
+		return nil
+	}
+
+	exporter := converter.NewEPUBEmailExporter(
+		u.Settings.EmailSettings.EpubTo,
+		getSiteURL(),
+		userTemplateVars(u),
+	)
+	return exporter.Export(context.Background(), nil, nil, []*bookmarks.Bookmark{b})
+}
+
+// userTemplateVars returns the template variables used to render
+// an e-book or a message for a user, outside of any request.
+func userTemplateVars(u *users.User) jet.VarMap {
+	tr := locales.LoadTranslation(u.Settings.Lang)
+	return make(jet.VarMap).
+		Set("user", u).
+		Set("preferences", preferences.New(u, nil)).
+		Set("translator", tr).
+		Set("gettext", tr.Gettext).
+		Set("ngettext", tr.Ngettext).
+		Set("pgettext", tr.Pgettext).
+		Set("npgettext", tr.Npgettext)
+}
+
+func applyRuleHandler(data interface{}) {
+	id := data.(int)
+	logger := slog.With(slog.Int("rule_id", id))
+
+	r, err := bookmarks.Rules.GetOne(goqu.C("id").Eq(id))
+	if err != nil {
+		logger.Error("rule retrieve", slog.Any("err", err))
+		return
+	}
+
+	count, err := ApplyRule(r)
+	if err != nil {
+		logger.Error("rule apply", slog.Any("err", err))
+		return
+	}
+
+	logger.Info("rule applied", slog.Int("bookmarks", count))
+}
+
+// ApplyRule applies a rule's actions to all the existing bookmarks
+// matching its conditions. Bookmarks are never sent to an e-reader
+// this way.
+// It returns the number of changed bookmarks.
+func ApplyRule(r *bookmarks.Rule) (int, error) {
+	ds, err := r.Bookmarks()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	lastID := 0
+	for {
+		var items []*bookmarks.Bookmark
+		err := ds.
+			Where(goqu.C("id").Table("b").Gt(lastID)).
+			Order(goqu.I("b.id").Asc()).
+			Limit(ruleApplyBatchSize).
+			ScanStructs(&items)
+		if err != nil {
+			return count, err
+		}
+		if len(items) == 0 {
+			return count, nil
+		}
+
+		for _, b := range items {
+			changed, err := applyRule(r, b)
+			if err != nil {
+				return count, err
+			}
+			if changed {
+				count++
+			}
+			lastID = b.ID
+		}
+	}
+}
@@ -10,12 +10,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
 	"net/url"
-	"os"
 	"path"
-	"path/filepath"
 	"runtime"
 	"runtime/debug"
 	"slices"
@@ -32,6 +33,7 @@ import (
 	"codeberg.org/readeck/readeck/internal/bookmarks"
 	"codeberg.org/readeck/readeck/internal/bus"
 	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/internal/webhooks"
 	"codeberg.org/readeck/readeck/pkg/archiver"
 	"codeberg.org/readeck/readeck/pkg/extract"
 	"codeberg.org/readeck/readeck/pkg/extract/contents"
@@ -44,11 +46,14 @@ import (
 var (
 	// ExtractPageTask is the bookmark creation task.
 	ExtractPageTask superbus.Task
-	// DeleteBookmarkTask is the bookmark deletion task.
+	// DeleteBookmarkTask is the bookmark deletion task. It moves
+	// the bookmark to the trash.
 	DeleteBookmarkTask superbus.Task
-	// DeleteCollectionTask is the collection deletion task.
+	// DeleteCollectionTask is the collection deletion task. It moves
+	// the collection to the trash.
 	DeleteCollectionTask superbus.Task
-	// DeleteLabelTask is the label deletion task.
+	// DeleteLabelTask is the label deletion task. It moves
+	// the label to the trash.
 	DeleteLabelTask superbus.Task
 )
 
@@ -87,7 +92,7 @@ func init() {
 				}
 				return res
 			}),
-			superbus.WithTaskHandler(extractPageHandler),
+			superbus.WithTaskHandlerE(extractPageHandler),
 		)
 
 		DeleteBookmarkTask = bus.Tasks().NewTask(
@@ -101,7 +106,7 @@ func init() {
 				}
 				return res
 			}),
-			superbus.WithTaskHandler(deleteBookmarkHandler),
+			superbus.WithTaskHandlerE(deleteBookmarkHandler),
 		)
 
 		DeleteCollectionTask = bus.Tasks().NewTask(
@@ -115,7 +120,7 @@ func init() {
 				}
 				return res
 			}),
-			superbus.WithTaskHandler(deleteCollectionHandler),
+			superbus.WithTaskHandlerE(deleteCollectionHandler),
 		)
 
 		DeleteLabelTask = bus.Tasks().NewTask(
@@ -129,57 +134,66 @@ func init() {
 				}
 				return res
 			}),
-			superbus.WithTaskHandler(deleteLabelHandler),
+			superbus.WithTaskHandlerE(deleteLabelHandler),
 		)
 	})
 }
 
 // ExtractPage is the public function that run an extraction synchronously.
+// It returns an error when the extraction could succeed on a later attempt.
 // Caution: it will panic and should only be run insisde another task.
-func ExtractPage(params ExtractParams) {
-	extractPageHandler(params)
+func ExtractPage(params ExtractParams) error {
+	return extractPageHandler(params)
 }
 
-func deleteBookmarkHandler(data interface{}) {
+// deleteBookmarkHandler moves a bookmark to the trash. It returns an
+// error, so the task is retried, unless the bookmark is already gone.
+func deleteBookmarkHandler(data interface{}) error {
 	id := data.(int)
 	logger := slog.With(slog.Int("id", id))
 
 	logger.Debug("deleting bookmark")
 	b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(id))
+	if errors.Is(err, bookmarks.ErrBookmarkNotFound) {
+		logger.Warn("bookmark retrieve", slog.Any("err", err))
+		return nil
+	}
 	if err != nil {
-		logger.Error("bookmark retrieve", slog.Any("err", err))
-		return
+		return fmt.Errorf("bookmark retrieve: %w", err)
 	}
 
-	if err := b.Delete(); err != nil {
-		logger.Error("bookmark removal", slog.Any("err", err))
-		return
+	if err := b.Trash(); err != nil {
+		return fmt.Errorf("bookmark removal: %w", err)
 	}
 
-	logger.Info("bookmark removed")
+	logger.Info("bookmark moved to trash")
+	return nil
 }
 
-func deleteCollectionHandler(data interface{}) {
+func deleteCollectionHandler(data interface{}) error {
 	id := data.(int)
 	logger := slog.With(slog.Int("id", id))
 
 	logger.Debug("deleting collection")
 
 	c, err := bookmarks.Collections.GetOne(goqu.C("id").Eq(id))
+	if errors.Is(err, bookmarks.ErrCollectionNotFound) {
+		logger.Warn("collection retrieve", slog.Any("err", err))
+		return nil
+	}
 	if err != nil {
-		logger.Error("collection retrieve", slog.Any("err", err))
-		return
+		return fmt.Errorf("collection retrieve: %w", err)
 	}
 
-	if err := c.Delete(); err != nil {
-		logger.Error("collection removal", slog.Any("err", err))
-		return
+	if err := c.Trash(); err != nil {
+		return fmt.Errorf("collection removal: %w", err)
 	}
 
-	logger.Info("collection removed")
+	logger.Info("collection moved to trash")
+	return nil
 }
 
-func deleteLabelHandler(data interface{}) {
+func deleteLabelHandler(data interface{}) error {
 	params := data.(LabelDeleteParams)
 	logger := slog.With(
 		slog.Int("user", params.UserID),
@@ -188,22 +202,44 @@ func deleteLabelHandler(data interface{}) {
 	logger.Debug("deleting label")
 
 	u, err := users.Users.GetOne(goqu.C("id").Eq(params.UserID))
+	if errors.Is(err, users.ErrNotFound) {
+		logger.Warn("user retrieve", slog.Any("err", err))
+		return nil
+	}
 	if err != nil {
-		logger.Error("user retrieve", slog.Any("err", err))
-		return
+		return fmt.Errorf("user retrieve: %w", err)
+	}
+
+	if _, err = bookmarks.Trash.TrashLabel(u, params.Name); err != nil {
+		return fmt.Errorf("label remove: %w", err)
 	}
 
-	if _, err = bookmarks.Bookmarks.RenameLabel(u, params.Name, ""); err != nil {
-		logger.Error("label remove", slog.Any("err", err))
-		return
+	logger.Info("label moved to trash")
+	return nil
+}
+
+// isTemporaryError returns true when a resource could not be loaded
+// because of an error that could go away on a later attempt: a network
+// error, a timeout or a server error.
+func isTemporaryError(err error) bool {
+	var se *extract.StatusError
+	if errors.As(err, &se) {
+		return se.Code >= 500 || se.Code == http.StatusTooManyRequests
 	}
 
-	logger.Info("label removed")
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	return errors.As(err, &opErr) || errors.As(err, &dnsErr) ||
+		(errors.As(err, &netErr) && netErr.Timeout())
 }
 
-func extractPageHandler(data interface{}) {
+// extractPageHandler runs a bookmark's extraction. It returns an
+// error, so the task is retried, when the bookmark could not be
+// retrieved or saved, or when a new bookmark's page could not be
+// loaded because of a temporary error.
+func extractPageHandler(data interface{}) (err error) {
 	var b *bookmarks.Bookmark
-	var err error
 
 	params := data.(ExtractParams)
 
@@ -239,11 +275,14 @@ func extractPageHandler(data interface{}) {
 
 		// Then save the whole thing
 		if !saved {
-			if err := b.Save(); err != nil {
-				logger.Error("saving bookmark", slog.Any("err", err))
+			if saveErr := b.Save(); saveErr != nil {
+				logger.Error("saving bookmark", slog.Any("err", saveErr))
+				err = fmt.Errorf("saving bookmark: %w", saveErr)
 			}
 		}
 
+		webhooks.SendBookmarkEvent(webhooks.EventBookmarkExtracted, b, nil)
+
 		metricCreation.WithLabelValues(b.StateName()).Inc()
 		metricTiming.WithLabelValues(b.StateName()).Observe(time.Since(start).Seconds())
 		metricResources.Observe(float64(resourceCount))
@@ -251,10 +290,21 @@ func extractPageHandler(data interface{}) {
 	}()
 
 	b, err = bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(params.BookmarkID))
-	if err != nil {
+	if errors.Is(err, bookmarks.ErrBookmarkNotFound) {
 		logger.Error("", slog.Any("err", err))
-		return
+		return nil
 	}
+	if err != nil {
+		return fmt.Errorf("bookmark retrieve: %w", err)
+	}
+
+	// A new bookmark whose previous attempt failed is extracted
+	// again from scratch.
+	if b.State == bookmarks.StateError && b.FilePath == "" {
+		b.State = bookmarks.StateLoading
+		b.Errors = types.Strings{}
+	}
+	isNew := b.State == bookmarks.StateLoading
 
 	proxyList := make([]extract.ProxyMatcher, len(configs.Config.Extractor.ProxyMatch))
 	for i, x := range configs.Config.Extractor.ProxyMatch {
@@ -272,7 +322,7 @@ func extractPageHandler(data interface{}) {
 	)
 	if err != nil {
 		logger.Error("", slog.Any("err", err))
-		return
+		return nil
 	}
 
 	for _, x := range params.Resources {
@@ -292,6 +342,7 @@ func extractPageHandler(data interface{}) {
 		meta.ExtractFavicon,
 		meta.ExtractPicture,
 		contentscripts.LoadSiteConfig,
+		conditionnalProcessor(!ex.IsInCache(b.URL), contentscripts.SiteLogin(siteLoginProvider(b))),
 		conditionnalProcessor(params.FindMain, contentscripts.ReplaceStrings),
 		// Only when the page is not in cache
 		conditionnalProcessor(!ex.IsInCache(b.URL), contentscripts.FindContentPage),
@@ -317,6 +368,15 @@ func extractPageHandler(data interface{}) {
 	}
 
 	ex.Run()
+
+	if loadErr := ex.LoadError(); isNew && !saved && isTemporaryError(loadErr) {
+		// The page could be available later, the bookmark is in
+		// error until the next attempt.
+		b.State = bookmarks.StateError
+		b.Errors = append(b.Errors, loadErr.Error())
+		return fmt.Errorf("loading page: %w", loadErr)
+	}
+	return nil
 }
 
 func conditionnalProcessor(test bool, p extract.Processor) extract.Processor {
@@ -346,8 +406,18 @@ func saveBookmark(b *bookmarks.Bookmark, saved *bool, resourceCount *int) extrac
 			return next
 		}
 
+		// Rules only run on the first extraction, not on a refresh
+		isNew := b.State == bookmarks.StateLoading
+
+		// A new bookmark is extracted again later when its page
+		// could not be loaded for now.
+		if isNew && isTemporaryError(ex.LoadError()) {
+			return next
+		}
+
 		b.Updated = time.Now()
 		b.URL = drop.UnescapedURL()
+		b.CanonicalURL = bookmarks.CanonicalKey(canonicalURL(drop))
 		b.State = bookmarks.StateLoaded
 		b.Domain = drop.Domain
 		b.Site = drop.URL.Hostname()
@@ -359,6 +429,7 @@ func saveBookmark(b *bookmarks.Bookmark, saved *bool, resourceCount *int) extrac
 		b.Description = drop.Description
 		b.Text = ex.Text
 		b.WordCount = len(strings.Fields(b.Text))
+		b.Simhash = bookmarks.TextFingerprint(b.Text)
 
 		if b.Title == "" {
 			b.Title = drop.Title
@@ -414,11 +485,36 @@ func saveBookmark(b *bookmarks.Bookmark, saved *bool, resourceCount *int) extrac
 			m.Log().Error("", slog.Any("err", err))
 			return next
 		}
+		if isNew {
+			if err := TriggerRules(b, bookmarks.RuleEventSaved); err != nil {
+				m.Log().Error("bookmark rules", slog.Any("err", err))
+			}
+		}
 		*saved = true
 		return next
 	}
 }
 
+// canonicalURL returns the URL given by the page's "link rel=canonical"
+// element or, when it's missing or doesn't look right, the page's URL.
+func canonicalURL(drop *extract.Drop) *url.URL {
+	v := drop.Meta.LookupGet("link.canonical")
+	if v == "" {
+		return drop.URL
+	}
+	u, err := drop.URL.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return drop.URL
+	}
+
+	// Some sites give their home page as the canonical URL
+	// of every page.
+	if strings.Trim(u.Path, "/") == "" && strings.Trim(drop.URL.Path, "/") != "" {
+		return drop.URL
+	}
+	return u
+}
+
 // fetchLinksProcessor retrieves the link list (from extractLinksProcessor) and
 // process all of them to get some information (content type, title when possible...)
 // The link list is then saved into the bookmark.
@@ -444,7 +540,7 @@ func fetchLinksProcessor(b *bookmarks.Bookmark) extract.Processor {
 					return err
 				}
 				d := extract.NewDrop(URL)
-				err = d.Load(m.Extractor.Client())
+				err = d.LoadLink(m.Extractor.Client())
 				if err != nil {
 					m.Log().Warn("extract link error",
 						slog.String("url", d.URL.String()),
@@ -499,32 +595,29 @@ func fetchLinksProcessor(b *bookmarks.Bookmark) extract.Processor {
 	}
 }
 
-func createZipFile(b *bookmarks.Bookmark, ex *extract.Extractor, arc *archiver.Archiver) error {
+func createZipFile(b *bookmarks.Bookmark, ex *extract.Extractor, arc *archiver.Archiver) (err error) {
 	// Fail fast
 	fileURL, err := b.GetBaseFileURL()
 	if err != nil {
 		return err
 	}
-	zipFile := filepath.Join(bookmarks.StoragePath(), fileURL+".zip")
 
 	b.FilePath = fileURL
 	b.Files = bookmarks.BookmarkFiles{}
 
 	// Create the zip file
-	z := zipfs.NewZipRW(nil, nil, 0)
+	w, err := bookmarks.Storage().Create(b.GetFileName())
+	if err != nil {
+		return err
+	}
+	z := zipfs.NewZipRW(w, nil, 0)
 	defer func() {
-		if err := z.Close(); err != nil {
-			panic(err)
+		// The file is sent to the storage on close, which can fail
+		if cerr := z.Close(); cerr != nil && err == nil {
+			err = cerr
 		}
 	}()
 
-	if err = os.MkdirAll(filepath.Dir(zipFile), 0o750); err != nil {
-		return err
-	}
-	if err = z.AddDestFile(zipFile); err != nil {
-		return err
-	}
-
 	// Add images
 	for k, p := range ex.Drop().Pictures {
 		name := path.Join("img", p.Name(k))
@@ -537,6 +630,17 @@ func createZipFile(b *bookmarks.Bookmark, ex *extract.Extractor, arc *archiver.A
 		b.Files[k] = &bookmarks.BookmarkFile{Name: name, Type: p.Type, Size: p.Size}
 	}
 
+	// Add the original document
+	if d := ex.Drop(); len(d.Document) > 0 && d.DocumentType == "pdf" {
+		if err = z.Add(
+			&zip.FileHeader{Name: "document.pdf"},
+			bytes.NewReader(d.Document),
+		); err != nil {
+			return err
+		}
+		b.Files["document"] = &bookmarks.BookmarkFile{Name: "document.pdf", Type: "application/pdf"}
+	}
+
 	// Add HTML content
 	if arc != nil && len(arc.Result) > 0 {
 		if err = z.Add(
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tasks_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestExtractPageErrors(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	status := http.StatusServiceUnavailable
+	httpmock.RegisterResponder("GET", "http://192.0.2.1/page", func(req *http.Request) (*http.Response, error) {
+		rsp := httpmock.NewStringResponse(status,
+			`<html><head><title>Test page</title></head><body><p>Some content.</p></body></html>`,
+		)
+		rsp.Request = req
+		rsp.Header.Set("Content-Type", "text/html; charset=utf-8")
+		return rsp, nil
+	})
+
+	newBookmark := func() *bookmarks.Bookmark {
+		b := &bookmarks.Bookmark{
+			UserID: &app.Users["user"].User.ID,
+			State:  bookmarks.StateLoading,
+			URL:    "http://192.0.2.1/page",
+		}
+		require.NoError(t, bookmarks.Bookmarks.Create(b))
+		return b
+	}
+	getBookmark := func(id int) *bookmarks.Bookmark {
+		b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(id))
+		require.NoError(t, err)
+		return b
+	}
+
+	t.Run("temporary error", func(t *testing.T) {
+		status = http.StatusServiceUnavailable
+		b := newBookmark()
+
+		err := tasks.ExtractPage(tasks.ExtractParams{BookmarkID: b.ID, FindMain: true})
+		require.EqualError(t, err, "loading page: Invalid status code (503)")
+		b = getBookmark(b.ID)
+		require.Equal(t, bookmarks.StateError, b.State)
+		require.Contains(t, b.Errors, "Invalid status code (503)")
+
+		// The next attempt succeeds
+		status = http.StatusOK
+		require.NoError(t, tasks.ExtractPage(tasks.ExtractParams{BookmarkID: b.ID, FindMain: true}))
+		b = getBookmark(b.ID)
+		require.Equal(t, bookmarks.StateLoaded, b.State)
+		require.Empty(t, b.Errors)
+		require.Equal(t, "Test page", b.Title)
+	})
+
+	t.Run("permanent error", func(t *testing.T) {
+		status = http.StatusNotFound
+		b := newBookmark()
+
+		require.NoError(t, tasks.ExtractPage(tasks.ExtractParams{BookmarkID: b.ID, FindMain: true}))
+		b = getBookmark(b.ID)
+		require.Equal(t, bookmarks.StateLoaded, b.State)
+		require.NotEmpty(t, b.Errors)
+	})
+
+	t.Run("deleted bookmark", func(t *testing.T) {
+		require.NoError(t, tasks.ExtractPage(tasks.ExtractParams{BookmarkID: 999999, FindMain: true}))
+	})
+}
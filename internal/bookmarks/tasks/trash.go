@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tasks
+
+import (
+	"log/slog"
+	"time"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+// trashPurgeInterval is the interval between two purges of the trash.
+const trashPurgeInterval = time.Hour
+
+// PurgeTrashTask is the periodic task that removes the items
+// that stayed in the trash longer than the retention period.
+var PurgeTrashTask superbus.Task
+
+func init() {
+	bus.OnReady(func() {
+		PurgeTrashTask = bus.Tasks().NewTask(
+			"trash.purge",
+			superbus.WithTaskInterval(trashPurgeInterval),
+			superbus.WithTaskHandler(purgeTrashHandler),
+		)
+	})
+}
+
+// TrashExpiration returns the date after which an item moved
+// to the trash at the given date is removed for good.
+func TrashExpiration(deleted time.Time) time.Time {
+	return deleted.AddDate(0, 0, configs.Config.Bookmarks.TrashRetention)
+}
+
+func purgeTrashHandler(_ interface{}) {
+	before := time.Now().AddDate(0, 0, -configs.Config.Bookmarks.TrashRetention)
+	if err := bookmarks.Trash.Purge(before); err != nil {
+		slog.Error("trash purge", slog.Any("err", err))
+	}
+}
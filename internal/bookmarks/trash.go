@@ -0,0 +1,251 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bookmarks
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/exp"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+// LabelTrashTable is the trashed labels table name in database.
+const LabelTrashTable = "bookmark_label_trash"
+
+var (
+	// Trash is the trash query manager.
+	Trash = TrashManager{}
+
+	// ErrTrashItemNotFound is returned when a trash item was not found.
+	ErrTrashItemNotFound = errors.New("not found")
+)
+
+// TrashedLabel is a label removed from a user's bookmarks. It keeps
+// the list of bookmarks that carried the label so it can be
+// restored.
+type TrashedLabel struct {
+	ID        int           `db:"id" goqu:"skipinsert,skipupdate"`
+	UID       string        `db:"uid"`
+	UserID    *int          `db:"user_id"`
+	Deleted   time.Time     `db:"deleted"`
+	Name      string        `db:"name"`
+	Bookmarks types.Strings `db:"bookmarks"`
+}
+
+// Trashable is an item of the trash. It can be restored or
+// removed for good.
+type Trashable interface {
+	Restore() error
+	Delete() error
+}
+
+// TrashItem is a bookmark, a collection or a label in the trash.
+type TrashItem struct {
+	Kind    string    `db:"kind"`
+	UID     string    `db:"uid"`
+	Name    string    `db:"name"`
+	Deleted time.Time `db:"deleted"`
+}
+
+// TrashManager is a query helper for the items in the trash.
+type TrashManager struct{}
+
+// Labels returns a prepared goqu SelectDataset on the trashed labels.
+func (m *TrashManager) Labels() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(LabelTrashTable).As("lt")).Prepared(true)
+}
+
+// Query returns a dataset with all the trash items of a user,
+// as [TrashItem] records.
+func (m *TrashManager) Query(userID int) *goqu.SelectDataset {
+	b := Bookmarks.Trashed().
+		Select(
+			goqu.L("'"+DeletedBookmark+"'").As("kind"),
+			goqu.I("b.uid"),
+			goqu.I("b.title").As("name"),
+			goqu.I("b.deleted"),
+		).
+		Where(goqu.I("b.user_id").Eq(userID))
+	c := Collections.Trashed().
+		Select(
+			goqu.L("'"+DeletedCollection+"'").As("kind"),
+			goqu.I("c.uid"),
+			goqu.I("c.name"),
+			goqu.I("c.deleted"),
+		).
+		Where(goqu.I("c.user_id").Eq(userID))
+	l := m.Labels().
+		Select(
+			goqu.L("'"+DeletedLabel+"'").As("kind"),
+			goqu.I("lt.uid"),
+			goqu.I("lt.name"),
+			goqu.I("lt.deleted"),
+		).
+		Where(goqu.I("lt.user_id").Eq(userID))
+
+	return db.Q().From(b.UnionAll(c).UnionAll(l).As("t")).Prepared(true)
+}
+
+// GetOne returns a user's trash item of the given kind.
+func (m *TrashManager) GetOne(userID int, kind, uid string) (Trashable, error) {
+	var res Trashable
+	var found bool
+	var err error
+
+	switch kind {
+	case DeletedBookmark:
+		b := new(Bookmark)
+		found, err = Bookmarks.Trashed().
+			Where(goqu.I("b.user_id").Eq(userID), goqu.I("b.uid").Eq(uid)).
+			ScanStruct(b)
+		res = b
+	case DeletedCollection:
+		c := new(Collection)
+		found, err = Collections.Trashed().
+			Where(goqu.I("c.user_id").Eq(userID), goqu.I("c.uid").Eq(uid)).
+			ScanStruct(c)
+		res = c
+	case DeletedLabel:
+		l := new(TrashedLabel)
+		found, err = m.Labels().
+			Where(goqu.I("lt.user_id").Eq(userID), goqu.I("lt.uid").Eq(uid)).
+			ScanStruct(l)
+		res = l
+	}
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrTrashItemNotFound
+	}
+
+	return res, nil
+}
+
+// TrashLabel removes a label from all the bookmarks of a user and
+// keeps a record of it in the trash. It returns the IDs of the
+// bookmarks that carried the label.
+func (m *TrashManager) TrashLabel(u *users.User, name string) (ids []int, err error) {
+	if ids, err = Bookmarks.RenameLabel(u, name, ""); err != nil || len(ids) == 0 {
+		return
+	}
+
+	l := &TrashedLabel{
+		UID:     base58.NewUUID(),
+		UserID:  &u.ID,
+		Deleted: time.Now(),
+		Name:    name,
+	}
+	if err = Bookmarks.QueryAll().
+		Select(goqu.I("b.uid")).
+		Where(goqu.I("b.id").In(ids)).
+		ScanVals(&l.Bookmarks); err != nil {
+		return
+	}
+
+	_, err = db.Q().Insert(LabelTrashTable).
+		Rows(l).
+		Prepared(true).
+		Executor().Exec()
+	return
+}
+
+// Purge removes for good every item that was moved to the trash
+// before the given date.
+func (m *TrashManager) Purge(before time.Time) error {
+	return m.purge(
+		goqu.I("b.deleted").Lt(before),
+		goqu.I("c.deleted").Lt(before),
+		goqu.I("lt.deleted").Lt(before),
+	)
+}
+
+// Empty removes for good every item in the trash of a user.
+func (m *TrashManager) Empty(userID int) error {
+	return m.purge(
+		goqu.I("b.user_id").Eq(userID),
+		goqu.I("c.user_id").Eq(userID),
+		goqu.I("lt.user_id").Eq(userID),
+	)
+}
+
+func (m *TrashManager) purge(bookmarkExp, collectionExp, labelExp goqu.Expression) error {
+	bl := []*Bookmark{}
+	if err := Bookmarks.Trashed().Where(bookmarkExp).ScanStructs(&bl); err != nil {
+		return err
+	}
+	for _, b := range bl {
+		if err := b.Delete(); err != nil {
+			return err
+		}
+	}
+
+	cl := []*Collection{}
+	if err := Collections.Trashed().Where(collectionExp).ScanStructs(&cl); err != nil {
+		return err
+	}
+	for _, c := range cl {
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+
+	ids := []int{}
+	if err := m.Labels().Select(goqu.I("lt.id")).Where(labelExp).ScanVals(&ids); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := db.Q().Delete(LabelTrashTable).Prepared(true).
+		Where(goqu.C("id").In(ids)).
+		Executor().Exec()
+	return err
+}
+
+// Restore adds the label back to the bookmarks that carried it.
+func (l *TrashedLabel) Restore() error {
+	if len(l.Bookmarks) > 0 {
+		list := []*Bookmark{}
+		if err := Bookmarks.QueryAll().
+			Select("b.id", "b.labels").
+			Where(
+				goqu.I("b.user_id").Eq(*l.UserID),
+				goqu.I("b.uid").In([]string(l.Bookmarks)),
+			).
+			ScanStructs(&list); err != nil {
+			return err
+		}
+
+		for _, b := range list {
+			if slices.Contains(b.Labels, l.Name) {
+				continue
+			}
+			b.Labels = append(b.Labels, l.Name)
+			slices.SortFunc(b.Labels, exp.UnaccentCompare)
+			if err := b.Update(map[string]interface{}{"labels": b.Labels}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return l.Delete()
+}
+
+// Delete removes the trashed label record.
+func (l *TrashedLabel) Delete() error {
+	_, err := db.Q().Delete(LabelTrashTable).Prepared(true).
+		Where(goqu.C("id").Eq(l.ID)).
+		Executor().Exec()
+	return err
+}
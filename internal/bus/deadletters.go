@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/pkg/base58"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+// DeadLetterTable is the dead letter table name in database.
+const DeadLetterTable = "bus_dead_letter"
+
+var (
+	// DeadLetters is the dead letter query manager.
+	DeadLetters = DeadLetterManager{}
+
+	// ErrDeadLetterNotFound is returned when a dead letter record was not found.
+	ErrDeadLetterNotFound = errors.New("not found")
+)
+
+// DeadLetter is a task that failed on all its attempts.
+// It keeps the task's data so it can be launched again.
+type DeadLetter struct {
+	ID       int       `db:"id" goqu:"skipinsert,skipupdate"`
+	UID      string    `db:"uid"`
+	Created  time.Time `db:"created"`
+	Name     string    `db:"name"`
+	OpID     string    `db:"op_id"`
+	Data     string    `db:"data"`
+	Attempts int       `db:"attempts"`
+	Error    string    `db:"error"`
+}
+
+// DeadLetterManager is a query helper for dead letter entries.
+type DeadLetterManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *DeadLetterManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(DeadLetterTable).As("dl")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *DeadLetterManager) GetOne(expressions ...goqu.Expression) (*DeadLetter, error) {
+	var d DeadLetter
+	found, err := m.Query().Where(expressions...).ScanStruct(&d)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrDeadLetterNotFound
+	}
+
+	return &d, nil
+}
+
+// Create inserts a new dead letter in the database.
+func (m *DeadLetterManager) Create(d *DeadLetter) error {
+	d.Created = time.Now()
+	d.UID = base58.NewUUID()
+
+	ds := db.Q().Insert(DeadLetterTable).
+		Rows(d).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	d.ID = id
+	return nil
+}
+
+// Delete removes a dead letter from the database.
+func (d *DeadLetter) Delete() error {
+	_, err := db.Q().Delete(DeadLetterTable).Prepared(true).
+		Where(goqu.C("id").Eq(d.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Retry launches the task again, with its original ID and data,
+// and removes the dead letter.
+func (d *DeadLetter) Retry() error {
+	// Numbers must keep their original form since the operation ID
+	// is part of the task's store key.
+	var id interface{}
+	dec := json.NewDecoder(bytes.NewReader([]byte(d.OpID)))
+	dec.UseNumber()
+	if err := dec.Decode(&id); err != nil {
+		return err
+	}
+
+	if err := Tasks().Launch(d.Name, id, 0, json.RawMessage(d.Data)); err != nil {
+		return err
+	}
+
+	return d.Delete()
+}
+
+// saveDeadLetter is the task manager's dead letter handler.
+func saveDeadLetter(op *superbus.Operation, p *superbus.Payload, cause error) {
+	l := slog.With(slog.Any("operation", *op))
+
+	opID, err := json.Marshal(op.ID)
+	if err != nil {
+		l.Error("dead letter", slog.Any("err", err))
+		return
+	}
+
+	d := &DeadLetter{
+		Name:     op.Name,
+		OpID:     string(opID),
+		Data:     string(p.Data),
+		Attempts: p.Attempt + 1,
+		Error:    cause.Error(),
+	}
+	if err = DeadLetters.Create(d); err != nil {
+		l.Error("dead letter", slog.Any("err", err))
+		return
+	}
+
+	l.Error("task failed", slog.Int("attempts", d.Attempts))
+}
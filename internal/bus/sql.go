@@ -0,0 +1,300 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bus
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+const (
+	eventTable = "bus_event"
+	storeTable = "bus_store"
+
+	// eventBatchSize is the number of events fetched on every poll.
+	eventBatchSize = 20
+)
+
+// SQLEventManager is an event manager using the main database.
+// An event is only removed once it's acknowledged. When the process
+// stops before that, the event is delivered again once its lock expires.
+type SQLEventManager struct {
+	wg       *sync.WaitGroup
+	stop     chan struct{}
+	notify   chan struct{}
+	handlers map[string]superbus.EventHandler
+	owner    string
+	interval time.Duration
+	lease    time.Duration
+
+	mu      sync.Mutex
+	running map[int]struct{}
+}
+
+type sqlEvent struct {
+	ID          int        `db:"id" goqu:"skipinsert,skipupdate"`
+	Created     time.Time  `db:"created"`
+	Name        string     `db:"name"`
+	Value       string     `db:"value"`
+	Owner       string     `db:"owner"`
+	LockedUntil *time.Time `db:"locked_until"`
+}
+
+// NewSQLEventManager creates an SQLEventManager instance.
+func NewSQLEventManager() *SQLEventManager {
+	return &SQLEventManager{
+		wg:       &sync.WaitGroup{},
+		stop:     make(chan struct{}),
+		notify:   make(chan struct{}, 1),
+		handlers: make(map[string]superbus.EventHandler),
+		owner:    uuid.NewString(),
+		interval: time.Second * 2,
+		lease:    time.Minute,
+		running:  make(map[int]struct{}),
+	}
+}
+
+// Listen polls the database for new events. The locks of the events
+// being processed are renewed until the events are acknowledged.
+func (m *SQLEventManager) Listen() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		poll := time.NewTicker(m.interval)
+		defer poll.Stop()
+		renew := time.NewTicker(m.lease / 3)
+		defer renew.Stop()
+
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-renew.C:
+				if err := m.renew(); err != nil {
+					slog.Error("renewing event locks", slog.Any("err", err))
+				}
+			case <-m.notify:
+				m.poll()
+			case <-poll.C:
+				m.poll()
+			}
+		}
+	}()
+}
+
+// Stop stops the event listener.
+func (m *SQLEventManager) Stop() {
+	m.stop <- struct{}{}
+	m.wg.Wait()
+}
+
+// Push saves an event in the database.
+func (m *SQLEventManager) Push(name string, value []byte) error {
+	_, err := db.Q().Insert(eventTable).Prepared(true).
+		Rows(sqlEvent{
+			Created: time.Now(),
+			Name:    name,
+			Value:   string(value),
+		}).
+		Executor().Exec()
+	if err != nil {
+		return err
+	}
+
+	// Wake up the listener of this process
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// On registers a event handler for a given event.
+func (m *SQLEventManager) On(name string, f superbus.EventHandler) {
+	m.handlers[name] = f
+}
+
+// Ack removes an event from the database.
+func (m *SQLEventManager) Ack(e superbus.Event) error {
+	m.mu.Lock()
+	delete(m.running, e.ID)
+	m.mu.Unlock()
+
+	_, err := db.Q().Delete(eventTable).Prepared(true).
+		Where(goqu.C("id").Eq(e.ID)).
+		Executor().Exec()
+	return err
+}
+
+// poll fetches and dispatches the available events until there
+// is none left.
+func (m *SQLEventManager) poll() {
+	for {
+		var items []*sqlEvent
+		err := db.Q().From(eventTable).Prepared(true).
+			Where(goqu.Or(
+				goqu.C("locked_until").IsNull(),
+				goqu.C("locked_until").Lt(time.Now()),
+			)).
+			Order(goqu.C("id").Asc()).
+			Limit(eventBatchSize).
+			ScanStructs(&items)
+		if err != nil {
+			slog.Error("polling events", slog.Any("err", err))
+			return
+		}
+
+		for _, item := range items {
+			if !m.lock(item) {
+				continue
+			}
+
+			e := superbus.Event{ID: item.ID, Name: item.Name, Value: []byte(item.Value)}
+			if f, ok := m.handlers[e.Name]; ok {
+				f(e)
+			} else if err := m.Ack(e); err != nil {
+				slog.Error("removing event", slog.Any("err", err))
+			}
+		}
+
+		if len(items) < eventBatchSize {
+			return
+		}
+	}
+}
+
+// lock takes an event for this process. It returns false when another
+// process took it first.
+func (m *SQLEventManager) lock(e *sqlEvent) bool {
+	now := time.Now()
+	res, err := db.Q().Update(eventTable).Prepared(true).
+		Set(goqu.Record{
+			"owner":        m.owner,
+			"locked_until": now.Add(m.lease),
+		}).
+		Where(
+			goqu.C("id").Eq(e.ID),
+			goqu.Or(
+				goqu.C("locked_until").IsNull(),
+				goqu.C("locked_until").Lt(now),
+			),
+		).
+		Executor().Exec()
+	if err != nil {
+		slog.Error("locking event", slog.Any("err", err))
+		return false
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false
+	}
+
+	m.mu.Lock()
+	m.running[e.ID] = struct{}{}
+	m.mu.Unlock()
+	return true
+}
+
+// renew extends the locks of the events this process is working on.
+func (m *SQLEventManager) renew() error {
+	m.mu.Lock()
+	ids := make([]int, 0, len(m.running))
+	for id := range m.running {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := db.Q().Update(eventTable).Prepared(true).
+		Set(goqu.Record{"locked_until": time.Now().Add(m.lease)}).
+		Where(
+			goqu.C("id").In(ids),
+			goqu.C("owner").Eq(m.owner),
+		).
+		Executor().Exec()
+	return err
+}
+
+// SQLStore is a key/value store using the main database.
+type SQLStore struct{}
+
+type sqlStoreItem struct {
+	Key     string     `db:"key"`
+	Value   string     `db:"value"`
+	Expires *time.Time `db:"expires"`
+}
+
+// NewSQLStore returns an SQLStore instance.
+func NewSQLStore() *SQLStore {
+	return &SQLStore{}
+}
+
+// Get returns a value for the given key. Returns an empty string when the
+// value does not exist or has expired.
+func (s *SQLStore) Get(key string) string {
+	var res string
+	_, err := db.Q().From(storeTable).Prepared(true).
+		Select(goqu.C("value")).
+		Where(
+			goqu.C("key").Eq(key),
+			goqu.Or(
+				goqu.C("expires").IsNull(),
+				goqu.C("expires").Gt(time.Now()),
+			),
+		).
+		ScanVal(&res)
+	if err != nil {
+		slog.Error("store get", slog.String("key", key), slog.Any("err", err))
+	}
+
+	return res
+}
+
+// Set insert or replace the value for the given key.
+func (s *SQLStore) Set(key, value string, expiration time.Duration) error {
+	item := sqlStoreItem{Key: key, Value: value}
+	if expiration > 0 {
+		expires := time.Now().Add(expiration)
+		item.Expires = &expires
+	}
+
+	_, err := db.Q().Insert(storeTable).Prepared(true).
+		Rows(item).
+		OnConflict(goqu.DoUpdate("key", goqu.Record{
+			"value":   item.Value,
+			"expires": item.Expires,
+		})).
+		Executor().Exec()
+	return err
+}
+
+// Del removes the given key.
+func (s *SQLStore) Del(key string) error {
+	_, err := db.Q().Delete(storeTable).Prepared(true).
+		Where(goqu.C("key").Eq(key)).
+		Executor().Exec()
+	return err
+}
+
+// Purge removes the expired keys.
+func (s *SQLStore) Purge() (int64, error) {
+	res, err := db.Q().Delete(storeTable).Prepared(true).
+		Where(goqu.C("expires").Lt(time.Now())).
+		Executor().Exec()
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bus_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func waitFor[T any](t *testing.T, ch chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(time.Second * 5):
+		t.Fatal("timeout")
+	}
+	var zero T
+	return zero
+}
+
+func TestSQLBus(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	t.Run("store", func(t *testing.T) {
+		assert := require.New(t)
+		s := bus.NewSQLStore()
+
+		assert.Empty(s.Get("a"))
+		assert.NoError(s.Set("a", "1", 0))
+		assert.Equal("1", s.Get("a"))
+		assert.NoError(s.Set("a", "2", time.Hour))
+		assert.Equal("2", s.Get("a"))
+
+		assert.NoError(s.Set("b", "1", time.Millisecond))
+		time.Sleep(time.Millisecond * 10)
+		assert.Empty(s.Get("b"))
+
+		n, err := s.Purge()
+		assert.NoError(err)
+		assert.Equal(int64(1), n)
+
+		assert.NoError(s.Del("a"))
+		assert.Empty(s.Get("a"))
+	})
+
+	t.Run("tasks", func(t *testing.T) {
+		assert := require.New(t)
+
+		done := make(chan string, 1)
+		failed := make(chan string, 1)
+
+		tm := superbus.NewTaskManager(
+			bus.NewSQLEventManager(), bus.NewSQLStore(),
+			superbus.WithDeadLetterHandler(func(op *superbus.Operation, _ *superbus.Payload, err error) {
+				failed <- op.Name + ": " + err.Error()
+			}),
+		)
+		okTask := tm.NewTask("test.ok", superbus.WithTaskHandler(func(data interface{}) {
+			done <- string(data.([]byte))
+		}))
+		failTask := tm.NewTask("test.fail", superbus.WithTaskHandler(func(_ interface{}) {
+			panic("boom")
+		}))
+		errTask := tm.NewTask("test.error", superbus.WithTaskHandlerE(func(_ interface{}) error {
+			return errors.New("not now")
+		}))
+		tm.Start()
+
+		assert.NoError(okTask.Run(1, "hello"))
+		assert.Equal(`"hello"`, waitFor(t, done))
+
+		assert.NoError(failTask.Run(2, nil))
+		assert.Equal("test.fail: boom", waitFor(t, failed))
+
+		assert.NoError(errTask.Run(3, nil))
+		assert.Equal("test.error: not now", waitFor(t, failed))
+
+		tm.Stop()
+
+		// Every event was acknowledged
+		count, err := db.Q().From("bus_event").Count()
+		assert.NoError(err)
+		assert.Equal(int64(0), count)
+	})
+
+	t.Run("redelivery", func(t *testing.T) {
+		assert := require.New(t)
+
+		// The first process receives the event but never
+		// acknowledges it.
+		received := make(chan superbus.Event, 1)
+		em1 := bus.NewSQLEventManager()
+		em1.On("test", func(e superbus.Event) {
+			received <- e
+		})
+		em1.Listen()
+		assert.NoError(em1.Push("test", []byte("1")))
+		assert.Equal("1", string(waitFor(t, received).Value))
+		em1.Stop()
+
+		// Its lock expires
+		_, err := db.Q().Update("bus_event").Prepared(true).
+			Set(goqu.Record{"locked_until": time.Now().Add(-time.Second)}).
+			Executor().Exec()
+		assert.NoError(err)
+
+		// And the next process receives the event
+		em2 := bus.NewSQLEventManager()
+		em2.On("test", func(e superbus.Event) {
+			received <- e
+		})
+		em2.Listen()
+		defer em2.Stop()
+
+		e := waitFor(t, received)
+		assert.Equal("1", string(e.Value))
+		assert.NoError(em2.Ack(e))
+
+		count, err := db.Q().From("bus_event").Count()
+		assert.NoError(err)
+		assert.Equal(int64(0), count)
+	})
+}
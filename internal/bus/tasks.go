@@ -7,10 +7,12 @@ package bus
 
 import (
 	"fmt"
+	"log/slog"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 
@@ -51,6 +53,9 @@ func Load() error {
 		startRedis(dsn)
 		eventManager = superbus.NewRedisEventManager(rdc)
 		store = superbus.NewRedisStore(rdc, "readeck")
+	case "database":
+		eventManager = NewSQLEventManager()
+		store = NewSQLStore()
 	default:
 		return fmt.Errorf("cannot load worker protocol %s", dsn.Scheme)
 	}
@@ -69,11 +74,30 @@ func LoadCustom(p string, em superbus.EventManager, s superbus.Store) {
 }
 
 func initTaskManager() {
-	taskManager = superbus.NewTaskManager(
-		eventManager, store,
+	options := []superbus.TaskManagerOption{
 		superbus.WithOperationPrefix("tasks"),
 		superbus.WithNumWorkers(configs.Config.Extractor.NumWorkers),
-	)
+		superbus.WithRetries(configs.Config.Worker.Retries),
+		superbus.WithDeadLetterHandler(saveDeadLetter),
+	}
+	if protocol == "database" {
+		// The events outlive the process, so must their payloads.
+		options = append(options, superbus.WithPayloadTTL(time.Hour*24))
+	}
+
+	taskManager = superbus.NewTaskManager(eventManager, store, options...)
+
+	if s, ok := store.(*SQLStore); ok {
+		taskManager.NewTask(
+			"bus.cleanup",
+			superbus.WithTaskInterval(time.Hour),
+			superbus.WithTaskHandler(func(_ interface{}) {
+				if _, err := s.Purge(); err != nil {
+					slog.Error("store cleanup", slog.Any("err", err))
+				}
+			}),
+		)
+	}
 
 	for _, f := range readyFuncs {
 		f()
@@ -98,4 +98,36 @@ var migrationList = []migrationEntry{
 	newMigrationEntry(16, "uuid_fields", migrations.M16uuidFields),
 	newMigrationEntry(17, "user_uid", migrations.M17useruid),
 	newMigrationEntry(18, "auth_last_used", applyMigrationFile("18_auth_last_used.sql")),
+	newMigrationEntry(19, "bookmark_feed", applyMigrationFile("19_bookmark_feed.sql")),
+	newMigrationEntry(20, "webhook", applyMigrationFile("20_webhook.sql")),
+	newMigrationEntry(21, "user_totp", applyMigrationFile("21_user_totp.sql")),
+	newMigrationEntry(22, "bookmark_notes", applyMigrationFile("22_bookmark_notes.sql")),
+	newMigrationEntry(23, "bookmark_revision", applyMigrationFile("23_bookmark_revision.sql")),
+	newMigrationEntry(24, "collection_digest", applyMigrationFile("24_collection_digest.sql")),
+	newMigrationEntry(25, "bookmark_fts_content", func(td *goqu.TxDatabase, f fs.FS) error {
+		// PostgreSQL computes the headlines from the bookmark table
+		if td.Dialect() != "sqlite3" {
+			return nil
+		}
+		return applyMigrationFile("25_bookmark_fts_content.sql")(td, f)
+	}),
+	newMigrationEntry(26, "site_login", applyMigrationFile("26_site_login.sql")),
+	newMigrationEntry(27, "newsletter", applyMigrationFile("27_newsletter.sql")),
+	newMigrationEntry(28, "sync_deletion", applyMigrationFile("28_sync_deletion.sql")),
+	newMigrationEntry(29, "bookmark_rule", applyMigrationFile("29_bookmark_rule.sql")),
+	newMigrationEntry(30, "bus", applyMigrationFile("30_bus.sql")),
+	newMigrationEntry(31, "trash", applyMigrationFile("31_trash.sql")),
+	newMigrationEntry(32, "bookmark_canonical_url",
+		applyMigrationFile("32_bookmark_canonical_url.sql"),
+		migrations.M32canonicalURL,
+	),
+	newMigrationEntry(33, "bookmark_simhash",
+		applyMigrationFile("33_bookmark_simhash.sql"),
+		migrations.M33simhash,
+	),
+	newMigrationEntry(34, "kosync", applyMigrationFile("34_kosync.sql")),
+	newMigrationEntry(35, "bookmark_newspaper", applyMigrationFile("35_bookmark_newspaper.sql")),
+	newMigrationEntry(36, "user_oidc_subject", applyMigrationFile("36_user_oidc_subject.sql")),
+	newMigrationEntry(37, "user_totp_step", applyMigrationFile("37_user_totp_step.sql")),
+	newMigrationEntry(38, "newspaper_last_delivery", applyMigrationFile("38_newspaper_last_delivery.sql")),
 }
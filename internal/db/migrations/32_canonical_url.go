@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package migrations
+
+import (
+	"io/fs"
+	"net/url"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/pkg/urlcanon"
+)
+
+// M32canonicalURL sets the "canonical_url" value of the existing bookmarks.
+func M32canonicalURL(db *goqu.TxDatabase, _ fs.FS) error {
+	type bookmarkURL struct {
+		ID  int    `db:"id"`
+		URL string `db:"url"`
+	}
+	var list []bookmarkURL
+	if err := db.Select("id", "url").From("bookmark").ScanStructs(&list); err != nil {
+		return err
+	}
+
+	c := urlcanon.New(configs.Config.Bookmarks.TrackingParams...)
+	for _, b := range list {
+		u, err := url.Parse(b.URL)
+		if err != nil {
+			continue
+		}
+		if _, err = db.Update("bookmark").
+			Set(goqu.Record{"canonical_url": c.Key(u)}).
+			Where(goqu.C("id").Eq(b.ID)).
+			Executor().Exec(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package migrations
+
+import (
+	"io/fs"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/pkg/simhash"
+)
+
+// M33simhash sets the "simhash" value of the existing bookmarks.
+func M33simhash(db *goqu.TxDatabase, _ fs.FS) error {
+	type bookmarkText struct {
+		ID   int    `db:"id"`
+		Text string `db:"text"`
+	}
+	var list []bookmarkText
+	if err := db.Select("id", "text").From("bookmark").ScanStructs(&list); err != nil {
+		return err
+	}
+
+	for _, b := range list {
+		h := simhash.Sum(b.Text)
+		if h == 0 {
+			continue
+		}
+		if _, err := db.Update("bookmark").
+			Set(goqu.Record{"simhash": int64(h)}). //nolint:gosec
+			Where(goqu.C("id").Eq(b.ID)).
+			Executor().Exec(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS webhook (
+    id          SERIAL      PRIMARY KEY,
+    uid         varchar(32) UNIQUE NOT NULL,
+    user_id     integer     NOT NULL,
+    created     timestamptz NOT NULL,
+    updated     timestamptz NOT NULL,
+    url         text        NOT NULL,
+    secret      text        NOT NULL,
+    events      jsonb       NOT NULL DEFAULT '[]',
+    is_enabled  boolean     NOT NULL DEFAULT true,
+
+    CONSTRAINT fk_webhook_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id           SERIAL      PRIMARY KEY,
+    uid          varchar(32) UNIQUE NOT NULL,
+    webhook_id   integer     NOT NULL,
+    created      timestamptz NOT NULL,
+    event        varchar(64) NOT NULL,
+    payload      text        NOT NULL,
+    attempts     integer     NOT NULL DEFAULT 0,
+    next_attempt timestamptz NULL,
+    delivered    timestamptz NULL,
+    status_code  integer     NOT NULL DEFAULT 0,
+    last_error   text        NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_webhook_delivery_webhook FOREIGN KEY (webhook_id) REFERENCES webhook(id) ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_delivery_webhook_idx ON webhook_delivery (webhook_id, created DESC);
+CREATE INDEX webhook_delivery_next_attempt_idx ON webhook_delivery (next_attempt);
@@ -0,0 +1,57 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+-- Add the highlights and their notes to the full text index
+ALTER TABLE bookmark_search ADD COLUMN note tsvector NOT NULL DEFAULT ''::tsvector;
+
+CREATE OR REPLACE FUNCTION bookmark_annotations_text(annotations jsonb)
+RETURNS text
+LANGUAGE sql
+IMMUTABLE
+AS $$
+	SELECT COALESCE(string_agg(
+		COALESCE(a->>'text', '') || ' ' || COALESCE(a->>'note', ''), ' '
+	), '')
+	FROM jsonb_array_elements(
+		CASE jsonb_typeof(annotations) WHEN 'array' THEN annotations ELSE '[]' END
+	) a
+	WHERE jsonb_typeof(a) = 'object'
+$$;
+
+UPDATE bookmark_search SET note = setweight(to_tsvector('ts', bookmark_annotations_text(b.annotations)), 'B')
+FROM bookmark b WHERE b.id = bookmark_search.bookmark_id;
+
+DROP INDEX IF EXISTS bookmark_search_all_idx;
+CREATE INDEX bookmark_search_all_idx ON bookmark_search USING GIN((title || description || "text" || site || "label" || note));
+CREATE INDEX bookmark_search_note_idx ON bookmark_search USING GIN (note);
+
+CREATE OR REPLACE FUNCTION bookmark_search_update()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+	DELETE FROM bookmark_search WHERE bookmark_id = OLD.id;
+
+	IF tg_op = 'UPDATE' OR tg_op = 'INSERT' THEN
+		INSERT INTO bookmark_search (
+			bookmark_id, title, description, "text", site, author, "label", note
+		) VALUES (
+			NEW.id,
+            setweight(to_tsvector('ts', NEW.title), 'A'),
+            to_tsvector('ts', NEW.description),
+			to_tsvector('ts', NEW."text"),
+            to_tsvector('ts',
+                NEW.site_name || ' ' || NEW.domain || ' ' ||
+                REGEXP_REPLACE(NEW.site, '^www\.', '') || ' ' ||
+                REPLACE(NEW.domain, '.', ' ') ||
+                REPLACE(REGEXP_REPLACE(NEW.site, '^www\.', ''), '.', ' ')
+            ),
+			jsonb_to_tsvector('ts', NEW.authors, '["string"]'),
+			setweight(jsonb_to_tsvector('ts', NEW.labels, '["string"]'), 'A'),
+			setweight(to_tsvector('ts', bookmark_annotations_text(NEW.annotations)), 'B')
+		);
+	END IF;
+	RETURN NEW;
+END;
+$$;
@@ -0,0 +1,17 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS site_login (
+    id       SERIAL      PRIMARY KEY,
+    uid      varchar(32) UNIQUE NOT NULL,
+    user_id  integer     NOT NULL,
+    created  timestamptz NOT NULL,
+    updated  timestamptz NOT NULL,
+    domain   text        NOT NULL,
+    secret   text        NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_site_login_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX site_login_user_domain_idx ON site_login (user_id, domain);
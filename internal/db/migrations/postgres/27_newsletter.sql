@@ -0,0 +1,26 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS newsletter_address (
+    id       SERIAL      PRIMARY KEY,
+    user_id  integer     UNIQUE NOT NULL,
+    created  timestamptz NOT NULL,
+    secret   varchar(64) UNIQUE NOT NULL,
+
+    CONSTRAINT fk_newsletter_address_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS newsletter_sender (
+    id       SERIAL      PRIMARY KEY,
+    uid      varchar(32) UNIQUE NOT NULL,
+    user_id  integer     NOT NULL,
+    created  timestamptz NOT NULL,
+    updated  timestamptz NOT NULL,
+    sender   text        NOT NULL,
+    labels   jsonb       NOT NULL DEFAULT '[]',
+
+    CONSTRAINT fk_newsletter_sender_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX newsletter_sender_user_sender_idx ON newsletter_sender (user_id, sender);
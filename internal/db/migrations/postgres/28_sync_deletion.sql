@@ -0,0 +1,15 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS sync_deletion (
+    id       SERIAL      PRIMARY KEY,
+    user_id  integer     NOT NULL,
+    created  timestamptz NOT NULL,
+    kind     text        NOT NULL,
+    name     text        NOT NULL,
+
+    CONSTRAINT fk_sync_deletion_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE INDEX sync_deletion_user_idx ON sync_deletion (user_id, id);
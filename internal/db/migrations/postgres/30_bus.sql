@@ -0,0 +1,31 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS bus_event (
+    id           SERIAL      PRIMARY KEY,
+    created      timestamptz NOT NULL,
+    name         text        NOT NULL,
+    value        text        NOT NULL,
+    owner        varchar(64) NOT NULL DEFAULT '',
+    locked_until timestamptz NULL
+);
+
+CREATE INDEX bus_event_locked_until_idx ON bus_event (locked_until);
+
+CREATE TABLE IF NOT EXISTS bus_store (
+    key     varchar(255) PRIMARY KEY,
+    value   text         NOT NULL,
+    expires timestamptz  NULL
+);
+
+CREATE TABLE IF NOT EXISTS bus_dead_letter (
+    id       SERIAL      PRIMARY KEY,
+    uid      varchar(32) UNIQUE NOT NULL,
+    created  timestamptz NOT NULL,
+    name     text        NOT NULL,
+    op_id    text        NOT NULL,
+    data     text        NOT NULL,
+    attempts integer     NOT NULL DEFAULT 0,
+    error    text        NOT NULL DEFAULT ''
+);
@@ -0,0 +1,21 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS kosync_document (
+    id          SERIAL           PRIMARY KEY,
+    user_id     integer          NOT NULL,
+    bookmark_id integer          NULL,
+    document    text             NOT NULL,
+    updated     timestamptz      NOT NULL,
+    progress    text             NOT NULL DEFAULT '',
+    percentage  double precision NOT NULL DEFAULT 0,
+    device      text             NOT NULL DEFAULT '',
+    device_id   text             NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_kosync_document_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    CONSTRAINT fk_kosync_document_bookmark FOREIGN KEY (bookmark_id) REFERENCES bookmark(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX kosync_document_user_document_idx ON kosync_document (user_id, document);
+CREATE INDEX kosync_document_bookmark_idx ON kosync_document (bookmark_id);
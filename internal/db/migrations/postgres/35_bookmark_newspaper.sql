@@ -0,0 +1,30 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS bookmark_newspaper (
+    id             SERIAL      PRIMARY KEY,
+    uid            varchar(32) UNIQUE NOT NULL,
+    user_id        integer     NOT NULL,
+    collection_id  integer     NOT NULL,
+    created        timestamptz NOT NULL,
+    updated        timestamptz NOT NULL,
+    title          text        NOT NULL,
+    schedule       text        NOT NULL,
+    weekday        integer     NOT NULL DEFAULT 0,
+    delivery_time  text        NOT NULL,
+    timezone       text        NOT NULL DEFAULT 'UTC',
+    max_items      integer     NOT NULL DEFAULT 0,
+    send_email     boolean     NOT NULL DEFAULT false,
+    publish_opds   boolean     NOT NULL DEFAULT false,
+    after_delivery text        NOT NULL DEFAULT '',
+    last_run       timestamptz NULL,
+    issue_date     timestamptz NULL,
+    issue_items    jsonb       NOT NULL DEFAULT '[]',
+    last_error     text        NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_bookmark_newspaper_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    CONSTRAINT fk_bookmark_newspaper_collection FOREIGN KEY (collection_id) REFERENCES bookmark_collection(id) ON DELETE CASCADE
+);
+
+CREATE INDEX bookmark_newspaper_user_idx ON bookmark_newspaper (user_id);
@@ -0,0 +1,5 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+ALTER TABLE "user" ADD COLUMN totp_last_step bigint NOT NULL DEFAULT 0;
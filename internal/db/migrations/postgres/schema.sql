@@ -18,9 +18,17 @@ CREATE TABLE IF NOT EXISTS "user" (
     password varchar(256) NOT NULL,
     "group"  varchar(64)  NOT NULL DEFAULT 'user',
     settings jsonb        NOT NULL DEFAULT '{}',
-    seed     integer      NOT NULL DEFAULT 0
+    seed     integer      NOT NULL DEFAULT 0,
+
+    totp_secret    varchar(64) NOT NULL DEFAULT '',
+    totp_recovery  jsonb       NOT NULL DEFAULT '[]',
+    totp_last_step bigint      NOT NULL DEFAULT 0,
+
+    oidc_subject text NOT NULL DEFAULT ''
 );
 
+CREATE INDEX user_oidc_subject_idx ON "user" (oidc_subject);
+
 CREATE TABLE IF NOT EXISTS token (
     id          SERIAL        PRIMARY KEY,
     uid         varchar(32)   UNIQUE NOT NULL,
@@ -49,6 +57,26 @@ CREATE TABLE IF NOT EXISTS "credential" (
     CONSTRAINT fk_app_password_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
 );
 
+CREATE TABLE IF NOT EXISTS bookmark_feed (
+    id            SERIAL      PRIMARY KEY,
+    uid           varchar(32) UNIQUE NOT NULL,
+    user_id       integer     NOT NULL,
+    created       timestamptz NOT NULL,
+    updated       timestamptz NOT NULL,
+    last_fetched  timestamptz NULL,
+    url           text        NOT NULL,
+    title         text        NOT NULL,
+    site_url      text        NOT NULL DEFAULT '',
+    labels        jsonb       NOT NULL DEFAULT '[]',
+    archive_after integer     NOT NULL DEFAULT 0,
+    entries       jsonb       NOT NULL DEFAULT '[]',
+    last_error    text        NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_bookmark_feed_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX bookmark_feed_user_url_idx ON bookmark_feed (user_id, url);
+
 CREATE TABLE IF NOT EXISTS bookmark (
     id            SERIAL      PRIMARY KEY,
     uid           varchar(32) UNIQUE NOT NULL,
@@ -60,6 +88,7 @@ CREATE TABLE IF NOT EXISTS bookmark (
     state         integer     NOT NULL DEFAULT 0,
     url           text        NOT NULL,
     initial_url   text        NOT NULL,
+    canonical_url text        NOT NULL DEFAULT '',
     domain        text        NOT NULL,
     title         text        NOT NULL,
     site          text        NOT NULL DEFAULT '',
@@ -72,6 +101,7 @@ CREATE TABLE IF NOT EXISTS bookmark (
     description   text        NOT NULL DEFAULT '',
     "text"        text        NOT NULL DEFAULT '',
     word_count    integer     NOT NULL DEFAULT 0,
+    simhash       bigint      NOT NULL DEFAULT 0,
     duration      integer     NOT NULL DEFAULT 0,
     embed         text        NOT NULL DEFAULT '',
     file_path     text        NOT NULL DEFAULT '',
@@ -82,14 +112,38 @@ CREATE TABLE IF NOT EXISTS bookmark (
     read_anchor   text        NOT NULL DEFAULT '',
     annotations   jsonb       NOT NULL DEFAULT '[]',
     links         jsonb       NOT NULL DEFAULT '[]',
+    feed_id       integer     NULL,
+    refresh_interval integer  NOT NULL DEFAULT 0,
+    refreshed     timestamptz NULL,
+    deleted       timestamptz NULL,
 
-    CONSTRAINT fk_bookmark_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+    CONSTRAINT fk_bookmark_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    CONSTRAINT fk_bookmark_feed FOREIGN KEY (feed_id) REFERENCES bookmark_feed(id) ON DELETE SET NULL
   );
 
 CREATE INDEX bookmark_created_idx ON "bookmark" USING btree (created DESC);
 CREATE INDEX bookmark_updated_idx ON "bookmark" USING btree (updated DESC);
 CREATE INDEX bookmark_url_idx ON "bookmark" (url);
 CREATE INDEX bookmark_initial_url_idx ON "bookmark" (initial_url);
+CREATE INDEX bookmark_canonical_url_idx ON "bookmark" (user_id, canonical_url);
+CREATE INDEX bookmark_feed_id_idx ON "bookmark" (feed_id);
+CREATE INDEX bookmark_deleted_idx ON "bookmark" (deleted);
+
+CREATE TABLE IF NOT EXISTS bookmark_revision (
+    id          SERIAL      PRIMARY KEY,
+    uid         varchar(32) UNIQUE NOT NULL,
+    bookmark_id integer     NOT NULL,
+    created     timestamptz NOT NULL,
+    title       text        NOT NULL DEFAULT '',
+    "text"      text        NOT NULL DEFAULT '',
+    word_count  integer     NOT NULL DEFAULT 0,
+    file_path   text        NOT NULL DEFAULT '',
+    diff        text        NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_bookmark_revision_bookmark FOREIGN KEY (bookmark_id) REFERENCES bookmark(id) ON DELETE CASCADE
+);
+
+CREATE INDEX bookmark_revision_bookmark_idx ON bookmark_revision (bookmark_id, created DESC);
 
 --
 -- Search configuration
@@ -110,15 +164,31 @@ CREATE TABLE bookmark_search (
 	site        tsvector NULL,
 	author      tsvector NULL,
 	"label"     tsvector NULL,
+	note        tsvector NOT NULL DEFAULT ''::tsvector,
 
     CONSTRAINT fk_bookmark_search_bookmark FOREIGN KEY (bookmark_id) REFERENCES bookmark(id) ON DELETE CASCADE
 );
 
-CREATE INDEX bookmark_search_all_idx    ON bookmark_search USING GIN((title || description || "text" || site || "label"));
+CREATE INDEX bookmark_search_all_idx    ON bookmark_search USING GIN((title || description || "text" || site || "label" || note));
 CREATE INDEX bookmark_search_title_idx  ON bookmark_search USING GIN (title);
 CREATE INDEX bookmark_search_site_idx   ON bookmark_search USING GIN (site);
 CREATE INDEX bookmark_search_author_idx ON bookmark_search USING GIN (author);
 CREATE INDEX bookmark_search_label_idx  ON bookmark_search USING GIN (label);
+CREATE INDEX bookmark_search_note_idx   ON bookmark_search USING GIN (note);
+
+CREATE OR REPLACE FUNCTION bookmark_annotations_text(annotations jsonb)
+RETURNS text
+LANGUAGE sql
+IMMUTABLE
+AS $$
+	SELECT COALESCE(string_agg(
+		COALESCE(a->>'text', '') || ' ' || COALESCE(a->>'note', ''), ' '
+	), '')
+	FROM jsonb_array_elements(
+		CASE jsonb_typeof(annotations) WHEN 'array' THEN annotations ELSE '[]' END
+	) a
+	WHERE jsonb_typeof(a) = 'object'
+$$;
 
 CREATE OR REPLACE FUNCTION bookmark_search_update()
 RETURNS trigger
@@ -129,7 +199,7 @@ BEGIN
 
 	IF tg_op = 'UPDATE' OR tg_op = 'INSERT' THEN
 		INSERT INTO bookmark_search (
-			bookmark_id, title, description, "text", site, author, "label"
+			bookmark_id, title, description, "text", site, author, "label", note
 		) VALUES (
 			NEW.id,
             setweight(to_tsvector('ts', NEW.title), 'A'),
@@ -142,7 +212,8 @@ BEGIN
                 REPLACE(REGEXP_REPLACE(NEW.site, '^www\.', ''), '.', ' ')
             ),
 			jsonb_to_tsvector('ts', NEW.authors, '["string"]'),
-			setweight(jsonb_to_tsvector('ts', NEW.labels, '["string"]'), 'A')
+			setweight(jsonb_to_tsvector('ts', NEW.labels, '["string"]'), 'A'),
+			setweight(to_tsvector('ts', bookmark_annotations_text(NEW.annotations)), 'B')
 		);
 	END IF;
 	RETURN NEW;
@@ -161,6 +232,195 @@ CREATE TABLE IF NOT EXISTS bookmark_collection (
     name        text        NOT NULL,
     is_pinned   boolean     NOT NULL DEFAULT false,
     filters     json        NOT NULL DEFAULT '{}',
+    digest_schedule text    NOT NULL DEFAULT '',
+    digest_sent timestamptz NULL,
+    deleted     timestamptz NULL,
 
     CONSTRAINT fk_bookmark_collection_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
 );
+
+CREATE TABLE IF NOT EXISTS webhook (
+    id          SERIAL      PRIMARY KEY,
+    uid         varchar(32) UNIQUE NOT NULL,
+    user_id     integer     NOT NULL,
+    created     timestamptz NOT NULL,
+    updated     timestamptz NOT NULL,
+    url         text        NOT NULL,
+    secret      text        NOT NULL,
+    events      jsonb       NOT NULL DEFAULT '[]',
+    is_enabled  boolean     NOT NULL DEFAULT true,
+
+    CONSTRAINT fk_webhook_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id           SERIAL      PRIMARY KEY,
+    uid          varchar(32) UNIQUE NOT NULL,
+    webhook_id   integer     NOT NULL,
+    created      timestamptz NOT NULL,
+    event        varchar(64) NOT NULL,
+    payload      text        NOT NULL,
+    attempts     integer     NOT NULL DEFAULT 0,
+    next_attempt timestamptz NULL,
+    delivered    timestamptz NULL,
+    status_code  integer     NOT NULL DEFAULT 0,
+    last_error   text        NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_webhook_delivery_webhook FOREIGN KEY (webhook_id) REFERENCES webhook(id) ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_delivery_webhook_idx ON webhook_delivery (webhook_id, created DESC);
+CREATE INDEX webhook_delivery_next_attempt_idx ON webhook_delivery (next_attempt);
+
+CREATE TABLE IF NOT EXISTS site_login (
+    id       SERIAL      PRIMARY KEY,
+    uid      varchar(32) UNIQUE NOT NULL,
+    user_id  integer     NOT NULL,
+    created  timestamptz NOT NULL,
+    updated  timestamptz NOT NULL,
+    domain   text        NOT NULL,
+    secret   text        NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_site_login_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX site_login_user_domain_idx ON site_login (user_id, domain);
+
+CREATE TABLE IF NOT EXISTS newsletter_address (
+    id       SERIAL      PRIMARY KEY,
+    user_id  integer     UNIQUE NOT NULL,
+    created  timestamptz NOT NULL,
+    secret   varchar(64) UNIQUE NOT NULL,
+
+    CONSTRAINT fk_newsletter_address_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS newsletter_sender (
+    id       SERIAL      PRIMARY KEY,
+    uid      varchar(32) UNIQUE NOT NULL,
+    user_id  integer     NOT NULL,
+    created  timestamptz NOT NULL,
+    updated  timestamptz NOT NULL,
+    sender   text        NOT NULL,
+    labels   jsonb       NOT NULL DEFAULT '[]',
+
+    CONSTRAINT fk_newsletter_sender_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX newsletter_sender_user_sender_idx ON newsletter_sender (user_id, sender);
+
+CREATE TABLE IF NOT EXISTS sync_deletion (
+    id       SERIAL      PRIMARY KEY,
+    user_id  integer     NOT NULL,
+    created  timestamptz NOT NULL,
+    kind     text        NOT NULL,
+    name     text        NOT NULL,
+
+    CONSTRAINT fk_sync_deletion_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE INDEX sync_deletion_user_idx ON sync_deletion (user_id, id);
+
+CREATE TABLE IF NOT EXISTS bookmark_rule (
+    id          SERIAL      PRIMARY KEY,
+    uid         varchar(32) UNIQUE NOT NULL,
+    user_id     integer     NOT NULL,
+    created     timestamptz NOT NULL,
+    updated     timestamptz NOT NULL,
+    name        text        NOT NULL,
+    is_enabled  boolean     NOT NULL DEFAULT true,
+    events      jsonb       NOT NULL DEFAULT '[]',
+    conditions  jsonb       NOT NULL DEFAULT '{}',
+    actions     jsonb       NOT NULL DEFAULT '{}',
+
+    CONSTRAINT fk_bookmark_rule_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE INDEX bookmark_rule_user_idx ON bookmark_rule (user_id);
+
+CREATE TABLE IF NOT EXISTS bus_event (
+    id           SERIAL      PRIMARY KEY,
+    created      timestamptz NOT NULL,
+    name         text        NOT NULL,
+    value        text        NOT NULL,
+    owner        varchar(64) NOT NULL DEFAULT '',
+    locked_until timestamptz NULL
+);
+
+CREATE INDEX bus_event_locked_until_idx ON bus_event (locked_until);
+
+CREATE TABLE IF NOT EXISTS bus_store (
+    key     varchar(255) PRIMARY KEY,
+    value   text         NOT NULL,
+    expires timestamptz  NULL
+);
+
+CREATE TABLE IF NOT EXISTS bus_dead_letter (
+    id       SERIAL      PRIMARY KEY,
+    uid      varchar(32) UNIQUE NOT NULL,
+    created  timestamptz NOT NULL,
+    name     text        NOT NULL,
+    op_id    text        NOT NULL,
+    data     text        NOT NULL,
+    attempts integer     NOT NULL DEFAULT 0,
+    error    text        NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS bookmark_label_trash (
+    id          SERIAL      PRIMARY KEY,
+    uid         varchar(32) UNIQUE NOT NULL,
+    user_id     integer     NOT NULL,
+    deleted     timestamptz NOT NULL,
+    name        text        NOT NULL,
+    bookmarks   jsonb       NOT NULL DEFAULT '[]',
+
+    CONSTRAINT fk_bookmark_label_trash_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+
+CREATE INDEX bookmark_label_trash_user_idx ON bookmark_label_trash (user_id);
+
+CREATE TABLE IF NOT EXISTS kosync_document (
+    id          SERIAL           PRIMARY KEY,
+    user_id     integer          NOT NULL,
+    bookmark_id integer          NULL,
+    document    text             NOT NULL,
+    updated     timestamptz      NOT NULL,
+    progress    text             NOT NULL DEFAULT '',
+    percentage  double precision NOT NULL DEFAULT 0,
+    device      text             NOT NULL DEFAULT '',
+    device_id   text             NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_kosync_document_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    CONSTRAINT fk_kosync_document_bookmark FOREIGN KEY (bookmark_id) REFERENCES bookmark(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX kosync_document_user_document_idx ON kosync_document (user_id, document);
+CREATE INDEX kosync_document_bookmark_idx ON kosync_document (bookmark_id);
+
+CREATE TABLE IF NOT EXISTS bookmark_newspaper (
+    id             SERIAL      PRIMARY KEY,
+    uid            varchar(32) UNIQUE NOT NULL,
+    user_id        integer     NOT NULL,
+    collection_id  integer     NOT NULL,
+    created        timestamptz NOT NULL,
+    updated        timestamptz NOT NULL,
+    title          text        NOT NULL,
+    schedule       text        NOT NULL,
+    weekday        integer     NOT NULL DEFAULT 0,
+    delivery_time  text        NOT NULL,
+    timezone       text        NOT NULL DEFAULT 'UTC',
+    max_items      integer     NOT NULL DEFAULT 0,
+    send_email     boolean     NOT NULL DEFAULT false,
+    publish_opds   boolean     NOT NULL DEFAULT false,
+    after_delivery text        NOT NULL DEFAULT '',
+    last_run       timestamptz NULL,
+    issue_date     timestamptz NULL,
+    issue_items    jsonb       NOT NULL DEFAULT '[]',
+    last_delivery  timestamptz NULL,
+    last_error     text        NOT NULL DEFAULT '',
+
+    CONSTRAINT fk_bookmark_newspaper_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    CONSTRAINT fk_bookmark_newspaper_collection FOREIGN KEY (collection_id) REFERENCES bookmark_collection(id) ON DELETE CASCADE
+);
+
+CREATE INDEX bookmark_newspaper_user_idx ON bookmark_newspaper (user_id);
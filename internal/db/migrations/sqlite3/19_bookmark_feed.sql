@@ -0,0 +1,26 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS bookmark_feed (
+    id            integer  PRIMARY KEY AUTOINCREMENT,
+    uid           text     UNIQUE NOT NULL,
+    user_id       integer  NOT NULL,
+    created       datetime NOT NULL,
+    updated       datetime NOT NULL,
+    last_fetched  datetime NULL,
+    url           text     NOT NULL,
+    title         text     NOT NULL,
+    site_url      text     NOT NULL DEFAULT "",
+    labels        json     NOT NULL DEFAULT "",
+    archive_after integer  NOT NULL DEFAULT 0,
+    entries       json     NOT NULL DEFAULT "",
+    last_error    text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_bookmark_feed_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX bookmark_feed_user_url_idx ON bookmark_feed (user_id, url);
+
+ALTER TABLE bookmark ADD COLUMN feed_id integer NULL REFERENCES bookmark_feed(id) ON DELETE SET NULL;
+CREATE INDEX bookmark_feed_id_idx ON bookmark (feed_id);
@@ -0,0 +1,36 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS webhook (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    uid         text     UNIQUE NOT NULL,
+    user_id     integer  NOT NULL,
+    created     datetime NOT NULL,
+    updated     datetime NOT NULL,
+    url         text     NOT NULL,
+    secret      text     NOT NULL,
+    events      json     NOT NULL DEFAULT "",
+    is_enabled  integer  NOT NULL DEFAULT 1,
+
+    CONSTRAINT fk_webhook_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id           integer  PRIMARY KEY AUTOINCREMENT,
+    uid          text     UNIQUE NOT NULL,
+    webhook_id   integer  NOT NULL,
+    created      datetime NOT NULL,
+    event        text     NOT NULL,
+    payload      text     NOT NULL,
+    attempts     integer  NOT NULL DEFAULT 0,
+    next_attempt datetime NULL,
+    delivered    datetime NULL,
+    status_code  integer  NOT NULL DEFAULT 0,
+    last_error   text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_webhook_delivery_webhook FOREIGN KEY (webhook_id) REFERENCES webhook(id) ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_delivery_webhook_idx ON webhook_delivery (webhook_id, created DESC);
+CREATE INDEX webhook_delivery_next_attempt_idx ON webhook_delivery (next_attempt);
@@ -0,0 +1,6 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+ALTER TABLE "user" ADD COLUMN totp_secret text NOT NULL DEFAULT "";
+ALTER TABLE "user" ADD COLUMN totp_recovery json NOT NULL DEFAULT "[]";
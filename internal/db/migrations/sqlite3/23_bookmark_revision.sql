@@ -0,0 +1,22 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+ALTER TABLE bookmark ADD COLUMN refresh_interval integer NOT NULL DEFAULT 0;
+ALTER TABLE bookmark ADD COLUMN refreshed datetime NULL;
+
+CREATE TABLE IF NOT EXISTS bookmark_revision (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    uid         text     UNIQUE NOT NULL,
+    bookmark_id integer  NOT NULL,
+    created     datetime NOT NULL,
+    title       text     NOT NULL DEFAULT "",
+    text        text     NOT NULL DEFAULT "",
+    word_count  integer  NOT NULL DEFAULT 0,
+    file_path   text     NOT NULL DEFAULT "",
+    diff        text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_bookmark_revision_bookmark FOREIGN KEY (bookmark_id) REFERENCES bookmark(id) ON DELETE CASCADE
+);
+
+CREATE INDEX bookmark_revision_bookmark_idx ON bookmark_revision (bookmark_id, created DESC);
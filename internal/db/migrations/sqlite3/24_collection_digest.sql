@@ -0,0 +1,6 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+ALTER TABLE bookmark_collection ADD COLUMN digest_schedule text NOT NULL DEFAULT '';
+ALTER TABLE bookmark_collection ADD COLUMN digest_sent datetime NULL;
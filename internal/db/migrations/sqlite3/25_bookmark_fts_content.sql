@@ -0,0 +1,38 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+-- The full text index reads its content from a view with the same
+-- columns, so snippet() and highlight() can retrieve the original text.
+DROP TABLE bookmark_idx;
+
+CREATE VIEW IF NOT EXISTS bookmark_idx_content AS
+SELECT b.id, 'oooooo' AS catchall, b.title, b.description, b.text,
+    b.site_name || ' ' || b.site || ' ' || b.domain AS site,
+    b.authors AS author, b.labels AS label,
+    (
+        SELECT group_concat(
+            coalesce(json_extract(a.value, '$.text'), '') || ' ' || coalesce(json_extract(a.value, '$.note'), ''),
+            ' '
+        )
+        FROM json_each(CASE json_valid(b.annotations) WHEN true THEN b.annotations ELSE '[]' END) a
+        WHERE a.type = 'object'
+    ) AS note
+FROM bookmark b;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS bookmark_idx USING fts5(
+    tokenize='unicode61 remove_diacritics 2',
+    content='bookmark_idx_content',
+    content_rowid='id',
+    catchall,
+    title,
+    description,
+    text,
+    site,
+    author,
+    label,
+    note
+);
+
+INSERT INTO bookmark_idx(bookmark_idx, rank) VALUES ('rank', 'bm25(0, 12.0, 6.0, 5.0, 2.0, 4.0, 1.0, 4.0)');
+INSERT INTO bookmark_idx(bookmark_idx) VALUES ('rebuild');
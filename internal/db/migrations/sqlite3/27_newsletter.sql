@@ -0,0 +1,26 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS newsletter_address (
+    id       integer  PRIMARY KEY AUTOINCREMENT,
+    user_id  integer  UNIQUE NOT NULL,
+    created  datetime NOT NULL,
+    secret   text     UNIQUE NOT NULL,
+
+    CONSTRAINT fk_newsletter_address_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS newsletter_sender (
+    id       integer  PRIMARY KEY AUTOINCREMENT,
+    uid      text     UNIQUE NOT NULL,
+    user_id  integer  NOT NULL,
+    created  datetime NOT NULL,
+    updated  datetime NOT NULL,
+    sender   text     NOT NULL,
+    labels   json     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_newsletter_sender_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX newsletter_sender_user_sender_idx ON newsletter_sender (user_id, sender);
@@ -0,0 +1,31 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS bus_event (
+    id           integer  PRIMARY KEY AUTOINCREMENT,
+    created      datetime NOT NULL,
+    name         text     NOT NULL,
+    value        text     NOT NULL,
+    owner        text     NOT NULL DEFAULT "",
+    locked_until datetime NULL
+);
+
+CREATE INDEX bus_event_locked_until_idx ON bus_event (locked_until);
+
+CREATE TABLE IF NOT EXISTS bus_store (
+    key     text     PRIMARY KEY,
+    value   text     NOT NULL,
+    expires datetime NULL
+);
+
+CREATE TABLE IF NOT EXISTS bus_dead_letter (
+    id       integer  PRIMARY KEY AUTOINCREMENT,
+    uid      text     UNIQUE NOT NULL,
+    created  datetime NOT NULL,
+    name     text     NOT NULL,
+    op_id    text     NOT NULL,
+    data     text     NOT NULL,
+    attempts integer  NOT NULL DEFAULT 0,
+    error    text     NOT NULL DEFAULT ""
+);
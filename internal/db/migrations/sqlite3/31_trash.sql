@@ -0,0 +1,21 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+ALTER TABLE bookmark ADD COLUMN deleted datetime NULL;
+CREATE INDEX bookmark_deleted_idx ON "bookmark" (deleted);
+
+ALTER TABLE bookmark_collection ADD COLUMN deleted datetime NULL;
+
+CREATE TABLE IF NOT EXISTS bookmark_label_trash (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    uid         text     UNIQUE NOT NULL,
+    user_id     integer  NOT NULL,
+    deleted     datetime NOT NULL,
+    name        text     NOT NULL,
+    bookmarks   json     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_bookmark_label_trash_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE INDEX bookmark_label_trash_user_idx ON bookmark_label_trash (user_id);
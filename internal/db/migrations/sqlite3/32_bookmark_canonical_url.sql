@@ -0,0 +1,6 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+ALTER TABLE bookmark ADD COLUMN canonical_url text NOT NULL DEFAULT "";
+CREATE INDEX bookmark_canonical_url_idx ON "bookmark" (user_id, canonical_url);
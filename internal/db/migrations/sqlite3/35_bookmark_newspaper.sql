@@ -0,0 +1,30 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+CREATE TABLE IF NOT EXISTS bookmark_newspaper (
+    id             integer  PRIMARY KEY AUTOINCREMENT,
+    uid            text     UNIQUE NOT NULL,
+    user_id        integer  NOT NULL,
+    collection_id  integer  NOT NULL,
+    created        datetime NOT NULL,
+    updated        datetime NOT NULL,
+    title          text     NOT NULL,
+    schedule       text     NOT NULL,
+    weekday        integer  NOT NULL DEFAULT 0,
+    delivery_time  text     NOT NULL,
+    timezone       text     NOT NULL DEFAULT "UTC",
+    max_items      integer  NOT NULL DEFAULT 0,
+    send_email     integer  NOT NULL DEFAULT 0,
+    publish_opds   integer  NOT NULL DEFAULT 0,
+    after_delivery text     NOT NULL DEFAULT "",
+    last_run       datetime NULL,
+    issue_date     datetime NULL,
+    issue_items    json     NOT NULL DEFAULT "",
+    last_error     text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_bookmark_newspaper_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_bookmark_newspaper_collection FOREIGN KEY (collection_id) REFERENCES bookmark_collection(id) ON DELETE CASCADE
+);
+
+CREATE INDEX bookmark_newspaper_user_idx ON bookmark_newspaper (user_id);
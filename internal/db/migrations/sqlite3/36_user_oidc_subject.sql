@@ -0,0 +1,7 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+ALTER TABLE "user" ADD COLUMN oidc_subject text NOT NULL DEFAULT "";
+
+CREATE INDEX user_oidc_subject_idx ON "user" (oidc_subject);
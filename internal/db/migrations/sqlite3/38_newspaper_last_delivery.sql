@@ -0,0 +1,6 @@
+-- SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+--
+-- SPDX-License-Identifier: AGPL-3.0-only
+
+ALTER TABLE bookmark_newspaper ADD COLUMN last_delivery datetime NULL;
+UPDATE bookmark_newspaper SET last_delivery = last_run WHERE last_error = '';
@@ -18,9 +18,17 @@ CREATE TABLE IF NOT EXISTS user (
     password text     NOT NULL,
     `group`  text     NOT NULL DEFAULT "user",
     settings json     NOT NULL DEFAULT "{}",
-    seed     integer  NOT NULL DEFAULT 0
+    seed     integer  NOT NULL DEFAULT 0,
+
+    totp_secret    text    NOT NULL DEFAULT "",
+    totp_recovery  json    NOT NULL DEFAULT "[]",
+    totp_last_step integer NOT NULL DEFAULT 0,
+
+    oidc_subject text NOT NULL DEFAULT ""
 );
 
+CREATE INDEX user_oidc_subject_idx ON "user" (oidc_subject);
+
 CREATE TABLE IF NOT EXISTS token (
     id          integer  PRIMARY KEY AUTOINCREMENT,
     uid         text     UNIQUE NOT NULL,
@@ -49,6 +57,26 @@ CREATE TABLE IF NOT EXISTS credential (
     CONSTRAINT fk_app_password_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
 );
 
+CREATE TABLE IF NOT EXISTS bookmark_feed (
+    id            integer  PRIMARY KEY AUTOINCREMENT,
+    uid           text     UNIQUE NOT NULL,
+    user_id       integer  NOT NULL,
+    created       datetime NOT NULL,
+    updated       datetime NOT NULL,
+    last_fetched  datetime NULL,
+    url           text     NOT NULL,
+    title         text     NOT NULL,
+    site_url      text     NOT NULL DEFAULT "",
+    labels        json     NOT NULL DEFAULT "",
+    archive_after integer  NOT NULL DEFAULT 0,
+    entries       json     NOT NULL DEFAULT "",
+    last_error    text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_bookmark_feed_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX bookmark_feed_user_url_idx ON bookmark_feed (user_id, url);
+
 CREATE TABLE IF NOT EXISTS bookmark (
     id            integer  PRIMARY KEY AUTOINCREMENT,
     uid           text     UNIQUE NOT NULL,
@@ -60,6 +88,7 @@ CREATE TABLE IF NOT EXISTS bookmark (
     state         integer  NOT NULL DEFAULT 0,
     url           text     NOT NULL,
     initial_url   text     NOT NULL,
+    canonical_url text     NOT NULL DEFAULT "",
     title         text     NOT NULL,
     domain        text     NOT NULL DEFAULT "",
     site          text     NOT NULL DEFAULT "",
@@ -72,6 +101,7 @@ CREATE TABLE IF NOT EXISTS bookmark (
     description   text     NOT NULL DEFAULT "",
     text          text     NOT NULL DEFAULT "",
     word_count    integer  NOT NULL DEFAULT 0,
+    simhash       integer  NOT NULL DEFAULT 0,
     duration      integer  NOT NULL DEFAULT 0,
     embed         text     NOT NULL DEFAULT "",
     file_path     text     NOT NULL DEFAULT "",
@@ -82,18 +112,56 @@ CREATE TABLE IF NOT EXISTS bookmark (
     read_anchor   text     NOT NULL DEFAULT "",
     annotations   json     NOT NULL DEFAULT "",
     links         json     NOT NULL DEFAULT "",
+    feed_id       integer  NULL,
+    refresh_interval integer NOT NULL DEFAULT 0,
+    refreshed     datetime NULL,
+    deleted       datetime NULL,
 
-    CONSTRAINT fk_bookmark_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+    CONSTRAINT fk_bookmark_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_bookmark_feed FOREIGN KEY (feed_id) REFERENCES bookmark_feed(id) ON DELETE SET NULL
 );
 
 CREATE INDEX bookmark_created_idx ON "bookmark" (created DESC);
 CREATE INDEX bookmark_updated_idx ON "bookmark" (updated DESC);
 CREATE INDEX bookmark_url_idx ON "bookmark" (url);
 CREATE INDEX bookmark_initial_url_idx ON "bookmark" (initial_url);
+CREATE INDEX bookmark_canonical_url_idx ON "bookmark" (user_id, canonical_url);
+CREATE INDEX bookmark_feed_id_idx ON "bookmark" (feed_id);
+CREATE INDEX bookmark_deleted_idx ON "bookmark" (deleted);
+
+CREATE TABLE IF NOT EXISTS bookmark_revision (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    uid         text     UNIQUE NOT NULL,
+    bookmark_id integer  NOT NULL,
+    created     datetime NOT NULL,
+    title       text     NOT NULL DEFAULT "",
+    text        text     NOT NULL DEFAULT "",
+    word_count  integer  NOT NULL DEFAULT 0,
+    file_path   text     NOT NULL DEFAULT "",
+    diff        text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_bookmark_revision_bookmark FOREIGN KEY (bookmark_id) REFERENCES bookmark(id) ON DELETE CASCADE
+);
+
+CREATE INDEX bookmark_revision_bookmark_idx ON bookmark_revision (bookmark_id, created DESC);
+
+CREATE VIEW IF NOT EXISTS bookmark_idx_content AS
+SELECT b.id, 'oooooo' AS catchall, b.title, b.description, b.text,
+    b.site_name || ' ' || b.site || ' ' || b.domain AS site,
+    b.authors AS author, b.labels AS label,
+    (
+        SELECT group_concat(
+            coalesce(json_extract(a.value, '$.text'), '') || ' ' || coalesce(json_extract(a.value, '$.note'), ''),
+            ' '
+        )
+        FROM json_each(CASE json_valid(b.annotations) WHEN true THEN b.annotations ELSE '[]' END) a
+        WHERE a.type = 'object'
+    ) AS note
+FROM bookmark b;
 
 CREATE VIRTUAL TABLE IF NOT EXISTS bookmark_idx USING fts5(
     tokenize='unicode61 remove_diacritics 2',
-    content='bookmark',
+    content='bookmark_idx_content',
     content_rowid='id',
     catchall,
     title,
@@ -101,40 +169,73 @@ CREATE VIRTUAL TABLE IF NOT EXISTS bookmark_idx USING fts5(
     text,
     site,
     author,
-    label
+    label,
+    note
 );
 
-INSERT INTO bookmark_idx(bookmark_idx, rank) VALUES ('rank', 'bm25(0, 12.0, 6.0, 5.0, 2.0, 4.0)');
+INSERT INTO bookmark_idx(bookmark_idx, rank) VALUES ('rank', 'bm25(0, 12.0, 6.0, 5.0, 2.0, 4.0, 1.0, 4.0)');
 
 DROP TRIGGER IF EXISTS bookmark_ai;
 CREATE TRIGGER bookmark_ai AFTER INSERT ON bookmark BEGIN
     INSERT INTO bookmark_idx (
-        rowid, catchall, title, description, text, site, author, label
+        rowid, catchall, title, description, text, site, author, label, note
     ) VALUES (
-        new.id, 'oooooo', new.title, new.description, new.text, new.site_name || ' ' || new.site || ' ' || new.domain, new.authors, new.labels
+        new.id, 'oooooo', new.title, new.description, new.text, new.site_name || ' ' || new.site || ' ' || new.domain, new.authors, new.labels,
+        (
+            SELECT group_concat(
+                coalesce(json_extract(a.value, '$.text'), '') || ' ' || coalesce(json_extract(a.value, '$.note'), ''),
+                ' '
+            )
+            FROM json_each(CASE json_valid(new.annotations) WHEN true THEN new.annotations ELSE '[]' END) a
+            WHERE a.type = 'object'
+        )
     );
 END;
 
 DROP TRIGGER IF EXISTS bookmark_au;
 CREATE TRIGGER bookmark_au AFTER UPDATE ON bookmark BEGIN
     INSERT INTO bookmark_idx(
-        bookmark_idx, rowid, catchall, title, description, text, site, author, label
+        bookmark_idx, rowid, catchall, title, description, text, site, author, label, note
     ) VALUES (
-        'delete', old.id, 'oooooo', old.title, old.description, old.text, old.site, old.authors, old.labels
+        'delete', old.id, 'oooooo', old.title, old.description, old.text, old.site, old.authors, old.labels,
+        (
+            SELECT group_concat(
+                coalesce(json_extract(a.value, '$.text'), '') || ' ' || coalesce(json_extract(a.value, '$.note'), ''),
+                ' '
+            )
+            FROM json_each(CASE json_valid(old.annotations) WHEN true THEN old.annotations ELSE '[]' END) a
+            WHERE a.type = 'object'
+        )
     );
     INSERT INTO bookmark_idx (
-        rowid, catchall, title, description, text, site, author, label
+        rowid, catchall, title, description, text, site, author, label, note
     ) VALUES (
-        new.id, 'oooooo', new.title, new.description, new.text, new.site_name || ' ' || new.site || ' ' || new.domain, new.authors, new.labels
+        new.id, 'oooooo', new.title, new.description, new.text, new.site_name || ' ' || new.site || ' ' || new.domain, new.authors, new.labels,
+        (
+            SELECT group_concat(
+                coalesce(json_extract(a.value, '$.text'), '') || ' ' || coalesce(json_extract(a.value, '$.note'), ''),
+                ' '
+            )
+            FROM json_each(CASE json_valid(new.annotations) WHEN true THEN new.annotations ELSE '[]' END) a
+            WHERE a.type = 'object'
+        )
     );
 END;
 
 DROP TRIGGER IF EXISTS bookmark_ad;
 CREATE TRIGGER IF NOT EXISTS bookmark_ad AFTER DELETE ON bookmark BEGIN
     INSERT INTO bookmark_idx(
-        bookmark_idx, rowid, catchall, title, description, text, site, author, label
+        bookmark_idx, rowid, catchall, title, description, text, site, author, label, note
     ) VALUES (
-        'delete', old.id, 'oooooo', old.title, old.description, old.text, old.site, old.authors, old.labels
+        'delete', old.id, 'oooooo', old.title, old.description, old.text, old.site, old.authors, old.labels,
+        (
+            SELECT group_concat(
+                coalesce(json_extract(a.value, '$.text'), '') || ' ' || coalesce(json_extract(a.value, '$.note'), ''),
+                ' '
+            )
+            FROM json_each(CASE json_valid(old.annotations) WHEN true THEN old.annotations ELSE '[]' END) a
+            WHERE a.type = 'object'
+        )
     );
 END;
 
@@ -147,6 +248,195 @@ CREATE TABLE IF NOT EXISTS bookmark_collection (
     name        text     NOT NULL,
     is_pinned   integer  NOT NULL DEFAULT 0,
     filters     json     NOT NULL DEFAULT "{}",
+    digest_schedule text NOT NULL DEFAULT '',
+    digest_sent datetime NULL,
+    deleted     datetime NULL,
 
     CONSTRAINT fk_bookmark_collection_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
 );
+
+CREATE TABLE IF NOT EXISTS webhook (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    uid         text     UNIQUE NOT NULL,
+    user_id     integer  NOT NULL,
+    created     datetime NOT NULL,
+    updated     datetime NOT NULL,
+    url         text     NOT NULL,
+    secret      text     NOT NULL,
+    events      json     NOT NULL DEFAULT "",
+    is_enabled  integer  NOT NULL DEFAULT 1,
+
+    CONSTRAINT fk_webhook_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id           integer  PRIMARY KEY AUTOINCREMENT,
+    uid          text     UNIQUE NOT NULL,
+    webhook_id   integer  NOT NULL,
+    created      datetime NOT NULL,
+    event        text     NOT NULL,
+    payload      text     NOT NULL,
+    attempts     integer  NOT NULL DEFAULT 0,
+    next_attempt datetime NULL,
+    delivered    datetime NULL,
+    status_code  integer  NOT NULL DEFAULT 0,
+    last_error   text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_webhook_delivery_webhook FOREIGN KEY (webhook_id) REFERENCES webhook(id) ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_delivery_webhook_idx ON webhook_delivery (webhook_id, created DESC);
+CREATE INDEX webhook_delivery_next_attempt_idx ON webhook_delivery (next_attempt);
+
+CREATE TABLE IF NOT EXISTS site_login (
+    id       integer  PRIMARY KEY AUTOINCREMENT,
+    uid      text     UNIQUE NOT NULL,
+    user_id  integer  NOT NULL,
+    created  datetime NOT NULL,
+    updated  datetime NOT NULL,
+    domain   text     NOT NULL,
+    secret   text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_site_login_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX site_login_user_domain_idx ON site_login (user_id, domain);
+
+CREATE TABLE IF NOT EXISTS newsletter_address (
+    id       integer  PRIMARY KEY AUTOINCREMENT,
+    user_id  integer  UNIQUE NOT NULL,
+    created  datetime NOT NULL,
+    secret   text     UNIQUE NOT NULL,
+
+    CONSTRAINT fk_newsletter_address_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS newsletter_sender (
+    id       integer  PRIMARY KEY AUTOINCREMENT,
+    uid      text     UNIQUE NOT NULL,
+    user_id  integer  NOT NULL,
+    created  datetime NOT NULL,
+    updated  datetime NOT NULL,
+    sender   text     NOT NULL,
+    labels   json     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_newsletter_sender_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX newsletter_sender_user_sender_idx ON newsletter_sender (user_id, sender);
+
+CREATE TABLE IF NOT EXISTS sync_deletion (
+    id       integer  PRIMARY KEY AUTOINCREMENT,
+    user_id  integer  NOT NULL,
+    created  datetime NOT NULL,
+    kind     text     NOT NULL,
+    name     text     NOT NULL,
+
+    CONSTRAINT fk_sync_deletion_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE INDEX sync_deletion_user_idx ON sync_deletion (user_id, id);
+
+CREATE TABLE IF NOT EXISTS bookmark_rule (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    uid         text     UNIQUE NOT NULL,
+    user_id     integer  NOT NULL,
+    created     datetime NOT NULL,
+    updated     datetime NOT NULL,
+    name        text     NOT NULL,
+    is_enabled  integer  NOT NULL DEFAULT 1,
+    events      json     NOT NULL DEFAULT "",
+    conditions  json     NOT NULL DEFAULT "",
+    actions     json     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_bookmark_rule_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE INDEX bookmark_rule_user_idx ON bookmark_rule (user_id);
+
+CREATE TABLE IF NOT EXISTS bus_event (
+    id           integer  PRIMARY KEY AUTOINCREMENT,
+    created      datetime NOT NULL,
+    name         text     NOT NULL,
+    value        text     NOT NULL,
+    owner        text     NOT NULL DEFAULT "",
+    locked_until datetime NULL
+);
+
+CREATE INDEX bus_event_locked_until_idx ON bus_event (locked_until);
+
+CREATE TABLE IF NOT EXISTS bus_store (
+    key     text     PRIMARY KEY,
+    value   text     NOT NULL,
+    expires datetime NULL
+);
+
+CREATE TABLE IF NOT EXISTS bus_dead_letter (
+    id       integer  PRIMARY KEY AUTOINCREMENT,
+    uid      text     UNIQUE NOT NULL,
+    created  datetime NOT NULL,
+    name     text     NOT NULL,
+    op_id    text     NOT NULL,
+    data     text     NOT NULL,
+    attempts integer  NOT NULL DEFAULT 0,
+    error    text     NOT NULL DEFAULT ""
+);
+
+CREATE TABLE IF NOT EXISTS bookmark_label_trash (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    uid         text     UNIQUE NOT NULL,
+    user_id     integer  NOT NULL,
+    deleted     datetime NOT NULL,
+    name        text     NOT NULL,
+    bookmarks   json     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_bookmark_label_trash_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE INDEX bookmark_label_trash_user_idx ON bookmark_label_trash (user_id);
+
+CREATE TABLE IF NOT EXISTS kosync_document (
+    id          integer  PRIMARY KEY AUTOINCREMENT,
+    user_id     integer  NOT NULL,
+    bookmark_id integer  NULL,
+    document    text     NOT NULL,
+    updated     datetime NOT NULL,
+    progress    text     NOT NULL DEFAULT "",
+    percentage  real     NOT NULL DEFAULT 0,
+    device      text     NOT NULL DEFAULT "",
+    device_id   text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_kosync_document_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_kosync_document_bookmark FOREIGN KEY (bookmark_id) REFERENCES bookmark(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX kosync_document_user_document_idx ON kosync_document (user_id, document);
+CREATE INDEX kosync_document_bookmark_idx ON kosync_document (bookmark_id);
+
+CREATE TABLE IF NOT EXISTS bookmark_newspaper (
+    id             integer  PRIMARY KEY AUTOINCREMENT,
+    uid            text     UNIQUE NOT NULL,
+    user_id        integer  NOT NULL,
+    collection_id  integer  NOT NULL,
+    created        datetime NOT NULL,
+    updated        datetime NOT NULL,
+    title          text     NOT NULL,
+    schedule       text     NOT NULL,
+    weekday        integer  NOT NULL DEFAULT 0,
+    delivery_time  text     NOT NULL,
+    timezone       text     NOT NULL DEFAULT "UTC",
+    max_items      integer  NOT NULL DEFAULT 0,
+    send_email     integer  NOT NULL DEFAULT 0,
+    publish_opds   integer  NOT NULL DEFAULT 0,
+    after_delivery text     NOT NULL DEFAULT "",
+    last_run       datetime NULL,
+    issue_date     datetime NULL,
+    issue_items    json     NOT NULL DEFAULT "",
+    last_delivery  datetime NULL,
+    last_error     text     NOT NULL DEFAULT "",
+
+    CONSTRAINT fk_bookmark_newspaper_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_bookmark_newspaper_collection FOREIGN KEY (collection_id) REFERENCES bookmark_collection(id) ON DELETE CASCADE
+);
+
+CREATE INDEX bookmark_newspaper_user_idx ON bookmark_newspaper (user_id);
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package kosync provides a KOReader progress synchronization server,
+// so KOReader devices can share their reading progress with Readeck.
+package kosync
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	bookmark_routes "codeberg.org/readeck/readeck/internal/bookmarks/routes"
+	"codeberg.org/readeck/readeck/internal/server"
+)
+
+type kosyncRouter struct {
+	chi.Router
+	srv *server.Server
+}
+
+// SetupRoutes mounts the progress synchronization routes on "/kosync".
+// KOReader must be configured with this path as its custom sync server.
+func SetupRoutes(s *server.Server) {
+	r := chi.NewRouter()
+	h := &kosyncRouter{r, s}
+
+	r.Get("/healthcheck", h.healthcheck)
+	r.Post("/users/create", h.userCreate)
+
+	// The kosync credentials are only valid on these routes.
+	api := s.AuthenticatedRouter(auth.Init(&auth.KosyncAuthProvider{}))
+	api.With(s.WithPermission("api:profile", "read")).Get("/users/auth", h.userAuth)
+	api.Group(bookmark_routes.NewKosyncRouteHandler(s))
+	r.Mount("/", api)
+
+	s.AddRoute("/kosync", h)
+}
+
+// healthcheck tells the client the server is up.
+func (h *kosyncRouter) healthcheck(w http.ResponseWriter, r *http.Request) {
+	h.srv.Render(w, r, http.StatusOK, map[string]string{"state": "OK"})
+}
+
+// userCreate always fails. Users are Readeck's users and
+// can't register from KOReader.
+func (h *kosyncRouter) userCreate(w http.ResponseWriter, _ *http.Request) {
+	bookmark_routes.KosyncError(w, http.StatusPaymentRequired, 2005, "User registration is disabled.")
+}
+
+// userAuth confirms the client's credentials.
+func (h *kosyncRouter) userAuth(w http.ResponseWriter, r *http.Request) {
+	h.srv.Render(w, r, http.StatusOK, map[string]string{"authorized": "OK"})
+}
@@ -0,0 +1,187 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kosync_test
+
+import (
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+	"codeberg.org/readeck/readeck/pkg/koreader"
+)
+
+func TestKosync(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	h := md5.Sum([]byte(app.Users["user"].APIToken())) //nolint:gosec
+	key := hex.EncodeToString(h[:])
+
+	request := func(method, target string, data any, key string) *Response {
+		req := client.NewJSONRequest(method, target, data)
+		req.Header.Set("Accept", "application/vnd.koreader.v1+json")
+		req.Header.Set("x-auth-user", "user")
+		req.Header.Set("x-auth-key", key)
+		return client.Request(req)
+	}
+
+	b := app.Users["user"].Bookmarks[0]
+	b.Files = bookmarks.BookmarkFiles{"article": {Name: "index.html"}}
+	require.NoError(t, b.Save())
+
+	t.Run("public", func(t *testing.T) {
+		RunRequestSequence(t, client, "",
+			RequestTest{
+				Target:       "/kosync/healthcheck",
+				JSON:         true,
+				ExpectStatus: 200,
+				ExpectJSON:   `{"state": "OK"}`,
+			},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/kosync/users/create",
+				JSON:         map[string]string{"username": "test", "password": "test"},
+				ExpectStatus: 402,
+				ExpectJSON:   `{"code": 2005, "message": "User registration is disabled."}`,
+			},
+		)
+	})
+
+	t.Run("auth", func(t *testing.T) {
+		rsp := request("GET", "/kosync/users/auth", nil, key)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{"authorized": "OK"}`)
+
+		rsp = request("GET", "/kosync/users/auth", nil, "abcdef")
+		rsp.AssertStatus(t, 401)
+		rsp.AssertJSON(t, `{"code": 2001, "message": "Unauthorized"}`)
+
+		// The kosync credentials are not valid outside of kosync
+		rsp = request("GET", "/api/profile", nil, key)
+		rsp.AssertStatus(t, 401)
+	})
+
+	t.Run("unknown document", func(t *testing.T) {
+		rsp := request("GET", "/kosync/syncs/progress/abcdef", nil, key)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{}`)
+
+		rsp = request("PUT", "/kosync/syncs/progress", map[string]any{
+			"document":   "abcdef",
+			"progress":   "/body/DocFragment[3]/body/p[2]/text().0",
+			"percentage": 0.25,
+			"device":     "Kobo",
+			"device_id":  "123",
+		}, key)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{"document": "abcdef", "timestamp": "<<PRESENCE>>"}`)
+
+		rsp = request("GET", "/kosync/syncs/progress/abcdef", nil, key)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{
+			"document": "abcdef",
+			"progress": "/body/DocFragment[3]/body/p[2]/text().0",
+			"percentage": 0.25,
+			"device": "Kobo",
+			"device_id": "123",
+			"timestamp": "<<PRESENCE>>"
+		}`)
+
+		rsp = request("PUT", "/kosync/syncs/progress", map[string]any{
+			"progress":   "/body/DocFragment[3]/body/p[2]/text().0",
+			"percentage": 0.25,
+		}, key)
+		rsp.AssertStatus(t, 403)
+		rsp.AssertJSON(t, `{"code": 2004, "message": "Field 'document' not provided."}`)
+
+		rsp = request("PUT", "/kosync/syncs/progress", map[string]any{
+			"document":   "abcdef",
+			"progress":   "/body/DocFragment[3]/body/p[2]/text().0",
+			"percentage": 2,
+		}, key)
+		rsp.AssertStatus(t, 403)
+		rsp.AssertJSON(t, `{"code": 2003, "message": "Invalid request"}`)
+	})
+
+	var document string
+	t.Run("export", func(t *testing.T) {
+		req := client.NewRequest("GET", "/api/bookmarks/"+b.UID+"/article.epub", nil)
+		req.Header.Set("Authorization", "Bearer "+app.Users["user"].APIToken())
+		rsp := client.Request(req)
+		rsp.AssertStatus(t, 200)
+
+		d := koreader.NewPartialMD5()
+		d.Write(rsp.Body) //nolint:errcheck
+		document = d.Sum()
+
+		count, err := bookmarks.KosyncDocuments.Query().Where(
+			goqu.C("bookmark_id").Eq(b.ID),
+		).Count()
+		require.NoError(t, err)
+		require.EqualValues(t, 2, count)
+
+		doc, err := bookmarks.KosyncDocuments.GetDocument(*b.UserID, document)
+		require.NoError(t, err)
+		require.Equal(t, b.ID, *doc.BookmarkID)
+
+		rsp = request("GET", "/kosync/syncs/progress/"+document, nil, key)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{}`)
+	})
+
+	t.Run("progress from koreader", func(t *testing.T) {
+		rsp := request("PUT", "/kosync/syncs/progress", map[string]any{
+			"document":   document,
+			"progress":   "/body/DocFragment[1]/body/main/section/p[2]/text().4",
+			"percentage": 0.4213,
+			"device":     "Kobo",
+			"device_id":  "123",
+		}, key)
+		rsp.AssertStatus(t, 200)
+
+		b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(b.ID))
+		require.NoError(t, err)
+		require.Equal(t, 42, b.ReadProgress)
+		require.Regexp(t, `^section:nth-child\(1\) > p:nth-child\(\d+\)$`, b.ReadAnchor)
+
+		// The progress is the device's one
+		rsp = request("GET", "/kosync/syncs/progress/"+document, nil, key)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{
+			"document": "<<PRESENCE>>",
+			"progress": "/body/DocFragment[1]/body/main/section/p[2]/text().4",
+			"percentage": 0.4213,
+			"device": "Kobo",
+			"device_id": "123",
+			"timestamp": "<<PRESENCE>>"
+		}`)
+	})
+
+	t.Run("progress from readeck", func(t *testing.T) {
+		b, err := bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(b.ID))
+		require.NoError(t, err)
+		require.NoError(t, b.Update(map[string]any{
+			"read_progress": 100,
+			"read_anchor":   "",
+			"updated":       b.Updated.Add(10e9),
+		}))
+
+		rsp := request("GET", "/kosync/syncs/progress/"+document, nil, key)
+		rsp.AssertStatus(t, 200)
+		data := rsp.JSON.(map[string]any)
+		require.EqualValues(t, 1, data["percentage"])
+		require.Equal(t, "Readeck", data["device"])
+		require.Regexp(t, `^/body/DocFragment\[1\]/body/main/\w+\[\d+\]$`, data["progress"])
+	})
+}
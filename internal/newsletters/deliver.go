@@ -0,0 +1,256 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package newsletters
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/bookmarks/tasks"
+	"codeberg.org/readeck/readeck/internal/webhooks"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+var (
+	// ErrUnknownRecipient is returned when a recipient doesn't
+	// match any user address.
+	ErrUnknownRecipient = errors.New("unknown recipient")
+
+	// ErrInvalidMessage is returned when a message can't be read.
+	ErrInvalidMessage = errors.New("invalid message")
+)
+
+// CheckRecipient returns an error when a recipient doesn't match
+// any active user's address.
+func CheckRecipient(rcpt string) error {
+	_, err := recipientUser(rcpt)
+	return err
+}
+
+// Deliver saves a raw message received by a recipient as a new bookmark.
+// The sender becomes the bookmark's site and its labels are the ones
+// of the matching [Sender] entry.
+func Deliver(rcpt string, data []byte) (*bookmarks.Bookmark, error) {
+	user, err := recipientUser(rcpt)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := ParseMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+	if msg.SenderDomain() == "" {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMessage, errNoSender)
+	}
+
+	src := msg.URL()
+	b := &bookmarks.Bookmark{
+		UserID:   &user.ID,
+		State:    bookmarks.StateLoading,
+		URL:      src.String(),
+		Title:    msg.Subject,
+		Site:     src.Hostname(),
+		SiteName: msg.SenderName(),
+		Labels:   []string{},
+	}
+
+	sender, err := Senders.FindForAddress(user.ID, msg.From.Address)
+	switch {
+	case err == nil:
+		b.Labels = slices.Clone(sender.Labels)
+		slices.Sort(b.Labels)
+		b.Labels = slices.Compact(b.Labels)
+	case !errors.Is(err, ErrNotFound):
+		return nil, err
+	}
+
+	resources, err := msg.Resources(src)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+
+	if err = bookmarks.Bookmarks.Create(b); err != nil {
+		return nil, err
+	}
+	webhooks.SendBookmarkEvent(webhooks.EventBookmarkCreated, b, nil)
+
+	slog.Info("newsletter received",
+		slog.Int("user_id", user.ID),
+		slog.Int("bookmark_id", b.ID),
+		slog.String("from", msg.From.Address),
+	)
+
+	err = tasks.ExtractPageTask.Run(b.ID, tasks.ExtractParams{
+		BookmarkID: b.ID,
+		Resources:  resources,
+		FindMain:   true,
+	})
+	return b, err
+}
+
+// recipientUser returns the user owning a recipient address. A user
+// without the permission to create bookmarks doesn't receive anything.
+func recipientUser(rcpt string) (*users.User, error) {
+	a, err := Addresses.FindByRecipient(rcpt)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrUnknownRecipient
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := users.Users.GetOne(goqu.C("id").Eq(*a.UserID))
+	if errors.Is(err, users.ErrNotFound) {
+		return nil, ErrUnknownRecipient
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.HasPermission("api:bookmarks", "write") {
+		return nil, ErrUnknownRecipient
+	}
+
+	return user, nil
+}
+
+// URL returns the bookmark URL of a message. Newsletters don't have any
+// URL so it's built from the sender's domain and the message ID.
+func (m *Message) URL() *url.URL {
+	id := m.MessageID
+	if id == "" {
+		id = m.From.Address + m.Subject + m.Date.Format(time.RFC3339)
+	}
+	h := sha256.Sum256([]byte(id))
+
+	return &url.URL{
+		Scheme: "https",
+		Host:   m.SenderDomain(),
+		Path:   "/newsletters/" + base58.EncodeToString(h[:16]),
+	}
+}
+
+// Resources returns the extraction resources of a message. The first one
+// is the HTML document, with the message information as meta tags. The
+// inline parts referenced by the document follow, with their "cid:" URLs
+// replaced by URLs relative to the document.
+func (m *Message) Resources(src *url.URL) ([]tasks.MultipartResource, error) {
+	doc, err := html.Parse(strings.NewReader(m.Body()))
+	if err != nil {
+		return nil, err
+	}
+
+	res := []tasks.MultipartResource{{
+		URL:     src.String(),
+		Headers: map[string]string{"content-type": "text/html; charset=utf-8"},
+	}}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			if c.Type == html.ElementNode && c.DataAtom == atom.Meta && isCharsetMeta(c) {
+				// The content is always UTF-8 now
+				n.RemoveChild(c)
+				c = next
+				continue
+			}
+			if c.Type == html.ElementNode {
+				for i, attr := range c.Attr {
+					if attr.Key != "src" && attr.Key != "background" {
+						continue
+					}
+					cid, ok := strings.CutPrefix(attr.Val, "cid:")
+					if !ok {
+						continue
+					}
+					cid, _ = url.PathUnescape(cid)
+					part, ok := m.Inline[cid]
+					if !ok {
+						continue
+					}
+					u := src.JoinPath(base58.EncodeToString([]byte(cid)))
+					c.Attr[i].Val = u.String()
+					res = append(res, tasks.MultipartResource{
+						URL:     u.String(),
+						Headers: map[string]string{"content-type": part.ContentType},
+						Data:    part.Data,
+					})
+				}
+			}
+			walk(c)
+			c = next
+		}
+	}
+	walk(doc)
+
+	if head := findNode(doc, atom.Head); head != nil {
+		meta := [][2]string{
+			{"og:site_name", m.SenderName()},
+			{"author", m.SenderName()},
+			{"article:published_time", m.Date.Format(time.RFC3339)},
+		}
+		for i := len(meta) - 1; i >= 0; i-- {
+			attr := "name"
+			if strings.Contains(meta[i][0], ":") {
+				attr = "property"
+			}
+			head.InsertBefore(&html.Node{
+				Type: html.ElementNode, Data: "meta", DataAtom: atom.Meta,
+				Attr: []html.Attribute{{Key: attr, Val: meta[i][0]}, {Key: "content", Val: meta[i][1]}},
+			}, head.FirstChild)
+		}
+		if findNode(head, atom.Title) == nil && m.Subject != "" {
+			title := &html.Node{Type: html.ElementNode, Data: "title", DataAtom: atom.Title}
+			title.AppendChild(&html.Node{Type: html.TextNode, Data: m.Subject})
+			head.InsertBefore(title, head.FirstChild)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err = html.Render(buf, doc); err != nil {
+		return nil, err
+	}
+	res[0].Data = buf.Bytes()
+
+	return res, nil
+}
+
+func isCharsetMeta(n *html.Node) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == "charset" {
+			return true
+		}
+		if attr.Key == "http-equiv" && strings.EqualFold(attr.Val, "content-type") {
+			return true
+		}
+	}
+	return false
+}
+
+func findNode(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if res := findNode(c, a); res != nil {
+			return res
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package newsletters
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// maxParts is the maximum number of MIME parts read in a message.
+const maxParts = 100
+
+var (
+	errNoSender  = errors.New("message has no sender")
+	errNoContent = errors.New("message has no content")
+)
+
+// InlinePart is a message part referenced by the HTML body,
+// usually an image.
+type InlinePart struct {
+	ContentType string
+	Data        []byte
+}
+
+// Message is a received newsletter.
+type Message struct {
+	From      *mail.Address
+	Subject   string
+	Date      time.Time
+	MessageID string
+	HTML      string
+	Text      string
+
+	// Inline contains the parts with a Content-ID, indexed by this ID.
+	Inline map[string]InlinePart
+}
+
+// ParseMessage reads a raw email message.
+func ParseMessage(r io.Reader) (*Message, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Message{
+		MessageID: strings.Trim(strings.TrimSpace(msg.Header.Get("Message-Id")), "<>"),
+		Inline:    map[string]InlinePart{},
+	}
+
+	dec := &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+	if res.Subject, err = dec.DecodeHeader(msg.Header.Get("Subject")); err != nil {
+		res.Subject = msg.Header.Get("Subject")
+	}
+	res.Subject = strings.TrimSpace(res.Subject)
+
+	if res.Date, err = msg.Header.Date(); err != nil {
+		res.Date = time.Now()
+	}
+
+	from, err := msg.Header.AddressList("From")
+	if err != nil || len(from) == 0 {
+		return nil, errNoSender
+	}
+	res.From = from[0]
+
+	count := 0
+	if err = res.readPart(msg.Header, msg.Body, &count); err != nil {
+		return nil, err
+	}
+	if res.HTML == "" && res.Text == "" {
+		return nil, errNoContent
+	}
+
+	return res, nil
+}
+
+// SenderName returns the sender's name, or its address when
+// the name is empty.
+func (m *Message) SenderName() string {
+	if m.From.Name != "" {
+		return m.From.Name
+	}
+	return m.From.Address
+}
+
+// SenderDomain returns the domain of the sender's address.
+func (m *Message) SenderDomain() string {
+	_, domain, _ := strings.Cut(m.From.Address, "@")
+	return strings.ToLower(domain)
+}
+
+// Body returns the message HTML body. A text only message is
+// converted to HTML paragraphs.
+func (m *Message) Body() string {
+	if m.HTML != "" {
+		return m.HTML
+	}
+
+	buf := new(strings.Builder)
+	for _, p := range strings.Split(strings.ReplaceAll(m.Text, "\r\n", "\n"), "\n\n") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		buf.WriteString("<p>")
+		buf.WriteString(strings.ReplaceAll(html.EscapeString(p), "\n", "<br>\n"))
+		buf.WriteString("</p>\n")
+	}
+	return buf.String()
+}
+
+// partHeader is the part header interface shared by
+// [mail.Header] and [textproto.MIMEHeader].
+type partHeader interface {
+	Get(string) string
+}
+
+// readPart reads a message part and its children. It keeps the first
+// HTML and text parts that are not attachments and the parts having
+// a Content-ID.
+func (m *Message) readPart(header partHeader, body io.Reader, count *int) error {
+	*count++
+	if *count > maxParts {
+		return fmt.Errorf("too many parts (> %d)", maxParts)
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err = m.readPart(p.Header, p, count); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransfer(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	if cid := strings.Trim(strings.TrimSpace(header.Get("Content-Id")), "<>"); cid != "" {
+		m.Inline[cid] = InlinePart{ContentType: mediaType, Data: data}
+		return nil
+	}
+
+	disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	if disposition == "attachment" {
+		return nil
+	}
+
+	switch {
+	case mediaType == "text/html" && m.HTML == "":
+		m.HTML, err = decodeCharset(params["charset"], data)
+	case mediaType == "text/plain" && m.Text == "":
+		m.Text, err = decodeCharset(params["charset"], data)
+	}
+	return err
+}
+
+// decodeTransfer returns a reader that decodes the part's
+// Content-Transfer-Encoding.
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// decodeCharset converts text data to UTF-8.
+func decodeCharset(label string, data []byte) (string, error) {
+	if label == "" || strings.EqualFold(label, "utf-8") || strings.EqualFold(label, "us-ascii") {
+		return string(data), nil
+	}
+
+	r, err := charset.NewReaderLabel(label, bytes.NewReader(data))
+	if err != nil {
+		// Unknown charset, keep the data as is
+		return string(data), nil //nolint:nilerr
+	}
+	res, err := io.ReadAll(r)
+	return string(res), err
+}
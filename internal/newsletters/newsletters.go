@@ -0,0 +1,320 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package newsletters receives the newsletters sent to the users' inbound
+// email addresses and saves them as bookmarks.
+package newsletters
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/internal/sitelogins"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+const (
+	// AddressTableName is the inbound address table name in database.
+	AddressTableName = "newsletter_address"
+
+	// SenderTableName is the sender table name in database.
+	SenderTableName = "newsletter_sender"
+)
+
+var (
+	// Addresses is the inbound address manager.
+	Addresses = AddressManager{}
+
+	// Senders is the sender manager.
+	Senders = SenderManager{}
+
+	// ErrNotFound is returned when a record was not found.
+	ErrNotFound = errors.New("not found")
+
+	secretEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+)
+
+// Address is a user's secret inbound address record in database.
+// A user has only one address, its local part is the secret value.
+type Address struct {
+	ID      int       `db:"id" goqu:"skipinsert,skipupdate"`
+	UserID  *int      `db:"user_id"`
+	Created time.Time `db:"created" goqu:"skipupdate"`
+	Secret  string    `db:"secret"`
+}
+
+// AddressManager is a query helper for inbound address entries.
+type AddressManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *AddressManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(AddressTableName).As("na")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *AddressManager) GetOne(expressions ...goqu.Expression) (*Address, error) {
+	var a Address
+	found, err := m.Query().Where(expressions...).ScanStruct(&a)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrNotFound
+	}
+
+	return &a, nil
+}
+
+// ForUser returns the user's inbound address. It's created on first use.
+func (m *AddressManager) ForUser(userID int) (*Address, error) {
+	a, err := m.GetOne(goqu.C("user_id").Eq(userID))
+	if !errors.Is(err, ErrNotFound) {
+		return a, err
+	}
+
+	a = &Address{
+		UserID:  &userID,
+		Created: time.Now(),
+		Secret:  newSecret(),
+	}
+
+	ds := db.Q().Insert(AddressTableName).
+		Rows(a).
+		Prepared(true)
+
+	a.ID, err = db.InsertWithID(ds, "id")
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// FindByRecipient returns the address matching a recipient. The recipient's
+// domain must be the configured inbound domain. A "+" suffix in the local part
+// is ignored, so "secret+news@domain" is the same as "secret@domain".
+func (m *AddressManager) FindByRecipient(rcpt string) (*Address, error) {
+	local, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(rcpt)), "@")
+	if !ok || domain != configs.Config.Email.Inbound.Domain {
+		return nil, ErrNotFound
+	}
+	local, _, _ = strings.Cut(local, "+")
+	if local == "" {
+		return nil, ErrNotFound
+	}
+
+	return m.GetOne(goqu.C("secret").Eq(local))
+}
+
+// Email returns the full email address.
+func (a *Address) Email() string {
+	return a.Secret + "@" + configs.Config.Email.Inbound.Domain
+}
+
+// Regenerate replaces the address with a new one. The previous address
+// stops receiving messages immediately.
+func (a *Address) Regenerate() error {
+	if a.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	secret := newSecret()
+	_, err := db.Q().Update(AddressTableName).Prepared(true).
+		Set(goqu.Record{"secret": secret}).
+		Where(goqu.C("id").Eq(a.ID)).
+		Executor().Exec()
+	if err != nil {
+		return err
+	}
+
+	a.Secret = secret
+	return nil
+}
+
+// Sender maps an email sender to the labels given to its newsletters.
+// The sender is either a full email address or a domain name, which then
+// matches all its subdomains.
+type Sender struct {
+	ID      int           `db:"id" goqu:"skipinsert,skipupdate"`
+	UID     string        `db:"uid"`
+	UserID  *int          `db:"user_id"`
+	Created time.Time     `db:"created" goqu:"skipupdate"`
+	Updated time.Time     `db:"updated"`
+	Sender  string        `db:"sender"`
+	Labels  types.Strings `db:"labels"`
+}
+
+// SenderManager is a query helper for sender entries.
+type SenderManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *SenderManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(SenderTableName).As("ns")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *SenderManager) GetOne(expressions ...goqu.Expression) (*Sender, error) {
+	var s Sender
+	found, err := m.Query().Where(expressions...).ScanStruct(&s)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrNotFound
+	}
+
+	return &s, nil
+}
+
+// FindForAddress returns the user's sender matching an email address.
+// An entry with the full address wins over a domain one, and the most
+// specific domain wins over its parents.
+func (m *SenderManager) FindForAddress(userID int, addr string) (*Sender, error) {
+	addr = NormalizeSender(addr)
+	_, domain, ok := strings.Cut(addr, "@")
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	candidates := []any{addr, domain}
+	for i := strings.IndexByte(domain, '.'); i >= 0; i = strings.IndexByte(domain, '.') {
+		domain = domain[i+1:]
+		candidates = append(candidates, domain)
+	}
+
+	var items []*Sender
+	err := m.Query().
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("sender").In(candidates...),
+		).
+		ScanStructs(&items)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *Sender
+	for _, x := range items {
+		switch {
+		case res == nil:
+			res = x
+		case strings.Contains(x.Sender, "@"):
+			res = x
+		case !strings.Contains(res.Sender, "@") && len(x.Sender) > len(res.Sender):
+			res = x
+		}
+	}
+	if res == nil {
+		return nil, ErrNotFound
+	}
+	return res, nil
+}
+
+// Create inserts a new sender in the database.
+func (m *SenderManager) Create(s *Sender) error {
+	if s.UserID == nil {
+		return errors.New("no sender user")
+	}
+	if s.Sender == "" {
+		return errors.New("no sender")
+	}
+
+	s.Created = time.Now()
+	s.Updated = s.Created
+	if s.UID == "" {
+		s.UID = base58.NewUUID()
+	}
+	if s.Labels == nil {
+		s.Labels = types.Strings{}
+	}
+
+	ds := db.Q().Insert(SenderTableName).
+		Rows(s).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	s.ID = id
+	return nil
+}
+
+// Update updates some sender values.
+func (s *Sender) Update(v interface{}) error {
+	if s.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		v["updated"] = time.Now()
+	default:
+		//
+	}
+
+	_, err := db.Q().Update(SenderTableName).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(s.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Save updates all the sender values.
+func (s *Sender) Save() error {
+	s.Updated = time.Now()
+	return s.Update(s)
+}
+
+// Delete removes a sender from the database.
+func (s *Sender) Delete() error {
+	_, err := db.Q().Delete(SenderTableName).Prepared(true).
+		Where(goqu.C("id").Eq(s.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// NormalizeSender returns a lower case email address or domain name.
+// It returns an empty string when the value is neither.
+func NormalizeSender(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "@") {
+		return sitelogins.NormalizeDomain(s)
+	}
+
+	addr, err := mail.ParseAddress(s)
+	if err != nil {
+		return ""
+	}
+	local, domain, _ := strings.Cut(strings.ToLower(addr.Address), "@")
+	if domain = sitelogins.NormalizeDomain(domain); local == "" || domain == "" {
+		return ""
+	}
+	return local + "@" + domain
+}
+
+// newSecret returns a new random address local part.
+func newSecret() string {
+	// 15 bytes = 120 bits = 24 base32 characters
+	d := make([]byte, 15)
+	if _, err := rand.Read(d); err != nil {
+		panic(err)
+	}
+	return secretEncoding.EncodeToString(d)
+}
@@ -0,0 +1,340 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package newsletters_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/internal/newsletters"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+const testMessage = "From: \"The Weekly\" <news@mail.example.net>\r\n" +
+	"To: someone@example.org\r\n" +
+	"Subject: =?utf-8?q?Caf=C3=A9_news?=\r\n" +
+	"Date: Mon, 02 Jun 2025 10:00:00 +0200\r\n" +
+	"Message-ID: <abc@mail.example.net>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/related; boundary=\"rel\"\r\n" +
+	"\r\n" +
+	"--rel\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"alt\"\r\n" +
+	"\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"Plain text version\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/html; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"<html><head><meta charset=3D\"iso-8859-1\"></head><body><p>Caf=E9 of the week</p>" +
+	"<img src=3D\"cid:logo@mail\"></body></html>\r\n" +
+	"--alt--\r\n" +
+	"--rel\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"Content-ID: <logo@mail>\r\n" +
+	"\r\n" +
+	"iVBORw0KGgo=\r\n" +
+	"--rel--\r\n"
+
+func TestNormalizeSender(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"News@Example.NET", "news@example.net"},
+		{"The Weekly <news@www.example.net>", "news@example.net"},
+		{"example.net", "example.net"},
+		{"https://www.example.net/", "example.net"},
+		{"not an @ address", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			require.Equal(t, test.expected, newsletters.NormalizeSender(test.value))
+		})
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	msg, err := newsletters.ParseMessage(strings.NewReader(testMessage))
+	require.NoError(t, err)
+
+	require.Equal(t, "The Weekly", msg.SenderName())
+	require.Equal(t, "mail.example.net", msg.SenderDomain())
+	require.Equal(t, "Café news", msg.Subject)
+	require.Equal(t, "abc@mail.example.net", msg.MessageID)
+	require.Equal(t, 2025, msg.Date.Year())
+	require.Contains(t, msg.HTML, "<p>Café of the week</p>")
+	require.Equal(t, "Plain text version", msg.Text)
+	require.Contains(t, msg.Inline, "logo@mail")
+	require.Equal(t, "image/png", msg.Inline["logo@mail"].ContentType)
+	require.Equal(t, []byte("\x89PNG\r\n\x1a\n"), msg.Inline["logo@mail"].Data)
+
+	t.Run("resources", func(t *testing.T) {
+		src := msg.URL()
+		require.Equal(t, "https", src.Scheme)
+		require.Equal(t, "mail.example.net", src.Host)
+		require.True(t, strings.HasPrefix(src.Path, "/newsletters/"))
+
+		resources, err := msg.Resources(src)
+		require.NoError(t, err)
+		require.Len(t, resources, 2)
+
+		require.Equal(t, src.String(), resources[0].URL)
+		doc := string(resources[0].Data)
+		require.Contains(t, doc, `<title>Café news</title>`)
+		require.Contains(t, doc, `<meta property="og:site_name" content="The Weekly"/>`)
+		require.Contains(t, doc, `<meta property="article:published_time" content="2025-06-02T10:00:00+02:00"/>`)
+		require.NotContains(t, doc, "iso-8859-1")
+		require.NotContains(t, doc, "cid:")
+		require.Contains(t, doc, `<img src="`+resources[1].URL+`"/>`)
+
+		require.True(t, strings.HasPrefix(resources[1].URL, src.String()+"/"))
+		require.Equal(t, "image/png", resources[1].Headers["content-type"])
+	})
+
+	t.Run("text only", func(t *testing.T) {
+		msg, err := newsletters.ParseMessage(strings.NewReader(
+			"From: news@example.net\r\nSubject: Hello\r\n\r\nFirst <line>\r\nsecond\r\n\r\nThird\r\n",
+		))
+		require.NoError(t, err)
+		require.Equal(t, "<p>First &lt;line&gt;<br>\nsecond</p>\n<p>Third</p>\n", msg.Body())
+	})
+
+	t.Run("no sender", func(t *testing.T) {
+		_, err := newsletters.ParseMessage(strings.NewReader("Subject: Hello\r\n\r\nText\r\n"))
+		require.Error(t, err)
+	})
+}
+
+func TestDeliver(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	domain := configs.Config.Email.Inbound.Domain
+	configs.Config.Email.Inbound.Domain = "in.example.org"
+	defer func() {
+		configs.Config.Email.Inbound.Domain = domain
+	}()
+
+	user := app.Users["user"].User
+	address, err := newsletters.Addresses.ForUser(user.ID)
+	require.NoError(t, err)
+	require.Len(t, address.Secret, 24)
+	require.Equal(t, address.Secret+"@in.example.org", address.Email())
+
+	// Same address on the next call
+	a2, err := newsletters.Addresses.ForUser(user.ID)
+	require.NoError(t, err)
+	require.Equal(t, address.Secret, a2.Secret)
+
+	require.NoError(t, newsletters.Senders.Create(&newsletters.Sender{
+		UserID: &user.ID, Sender: "example.net", Labels: []string{"news"},
+	}))
+	require.NoError(t, newsletters.Senders.Create(&newsletters.Sender{
+		UserID: &user.ID, Sender: "news@mail.example.net", Labels: []string{"weekly", "cafe"},
+	}))
+
+	t.Run("sender", func(t *testing.T) {
+		s, err := newsletters.Senders.FindForAddress(user.ID, "news@mail.example.net")
+		require.NoError(t, err)
+		require.Equal(t, "news@mail.example.net", s.Sender)
+
+		s, err = newsletters.Senders.FindForAddress(user.ID, "other@mail.example.net")
+		require.NoError(t, err)
+		require.Equal(t, "example.net", s.Sender)
+
+		_, err = newsletters.Senders.FindForAddress(user.ID, "news@example.org")
+		require.ErrorIs(t, err, newsletters.ErrNotFound)
+	})
+
+	t.Run("recipient", func(t *testing.T) {
+		require.NoError(t, newsletters.CheckRecipient(address.Email()))
+		require.NoError(t, newsletters.CheckRecipient(strings.ToUpper(address.Secret)+"+tag@in.example.org"))
+		require.ErrorIs(t, newsletters.CheckRecipient(address.Secret+"@example.org"), newsletters.ErrUnknownRecipient)
+		require.ErrorIs(t, newsletters.CheckRecipient("nope@in.example.org"), newsletters.ErrUnknownRecipient)
+
+		disabled, err := newsletters.Addresses.ForUser(app.Users["disabled"].User.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, newsletters.CheckRecipient(disabled.Email()), newsletters.ErrUnknownRecipient)
+	})
+
+	t.Run("deliver", func(t *testing.T) {
+		defer Events().Clear()
+
+		b, err := newsletters.Deliver(address.Email(), []byte(testMessage))
+		require.NoError(t, err)
+
+		b, err = bookmarks.Bookmarks.GetOne(goqu.C("id").Eq(b.ID))
+		require.NoError(t, err)
+		require.Equal(t, user.ID, *b.UserID)
+		require.Equal(t, "Café news", b.Title)
+		require.Equal(t, "mail.example.net", b.Site)
+		require.Equal(t, "The Weekly", b.SiteName)
+		require.Equal(t, types.Strings{"cafe", "weekly"}, b.Labels)
+
+		require.Len(t, Events().Records("task"), 1)
+		evt := map[string]any{}
+		require.NoError(t, json.Unmarshal(Events().Records("task")[0], &evt))
+		require.Equal(t, "bookmark.create", evt["name"])
+	})
+
+	t.Run("invalid message", func(t *testing.T) {
+		_, err := newsletters.Deliver(address.Email(), []byte("Subject: nothing\r\n\r\n"))
+		require.ErrorIs(t, err, newsletters.ErrInvalidMessage)
+	})
+
+	t.Run("regenerate", func(t *testing.T) {
+		previous := address.Email()
+		require.NoError(t, address.Regenerate())
+		require.NotEqual(t, previous, address.Email())
+		require.ErrorIs(t, newsletters.CheckRecipient(previous), newsletters.ErrUnknownRecipient)
+		require.NoError(t, newsletters.CheckRecipient(address.Email()))
+	})
+}
+
+func TestServer(t *testing.T) {
+	type delivery struct {
+		rcpt string
+		data string
+	}
+
+	run := func(t *testing.T, lmtp bool, fn func(c *textproto.Conn, deliveries *[]delivery)) {
+		deliveries := []delivery{}
+		srv := &newsletters.Server{
+			Hostname: "in.example.org",
+			LMTP:     lmtp,
+			MaxSize:  1024,
+			Recipient: func(rcpt string) error {
+				if strings.HasPrefix(rcpt, "unknown") {
+					return newsletters.ErrUnknownRecipient
+				}
+				return nil
+			},
+			Deliver: func(rcpt string, data []byte) error {
+				if strings.HasPrefix(rcpt, "invalid") {
+					return newsletters.ErrInvalidMessage
+				}
+				deliveries = append(deliveries, delivery{rcpt, string(data)})
+				return nil
+			},
+		}
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go srv.Serve(ln)  //nolint:errcheck
+		defer srv.Close() //nolint:errcheck
+
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		require.NoError(t, err)
+		c := textproto.NewConn(conn)
+		defer c.Close() //nolint:errcheck
+
+		_, _, err = c.ReadResponse(220)
+		require.NoError(t, err)
+
+		fn(c, &deliveries)
+	}
+
+	cmd := func(t *testing.T, c *textproto.Conn, expected int, format string, args ...any) string {
+		t.Helper()
+		id, err := c.Cmd(format, args...)
+		require.NoError(t, err)
+		c.StartResponse(id)
+		defer c.EndResponse(id)
+		_, msg, err := c.ReadResponse(expected)
+		require.NoError(t, err, msg)
+		return msg
+	}
+
+	send := func(t *testing.T, c *textproto.Conn, data string) {
+		t.Helper()
+		cmd(t, c, 354, "DATA")
+		w := c.DotWriter()
+		_, err := w.Write([]byte(data))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	}
+
+	t.Run("smtp", func(t *testing.T) {
+		run(t, false, func(c *textproto.Conn, deliveries *[]delivery) {
+			cmd(t, c, 500, "LHLO client")
+			cmd(t, c, 503, "MAIL FROM:<news@example.net>")
+			msg := cmd(t, c, 250, "EHLO client")
+			require.Contains(t, msg, "SIZE 1024")
+
+			cmd(t, c, 250, "MAIL FROM:<news@example.net>")
+			cmd(t, c, 550, "RCPT TO:<unknown@in.example.org>")
+			cmd(t, c, 250, "RCPT TO:<secret@in.example.org>")
+			send(t, c, "Subject: test\r\n\r\n.hello\r\n")
+			_, _, err := c.ReadResponse(250)
+			require.NoError(t, err)
+
+			require.Equal(t, []delivery{{"secret@in.example.org", "Subject: test\n\n.hello\n"}}, *deliveries)
+
+			// Too big
+			cmd(t, c, 552, "MAIL FROM:<news@example.net> SIZE=2048")
+			cmd(t, c, 250, "MAIL FROM:<news@example.net>")
+			cmd(t, c, 250, "RCPT TO:<secret@in.example.org>")
+			send(t, c, strings.Repeat("a", 2048))
+			_, _, err = c.ReadResponse(552)
+			require.NoError(t, err)
+			require.Len(t, *deliveries, 1)
+
+			cmd(t, c, 221, "QUIT")
+		})
+	})
+
+	t.Run("lmtp", func(t *testing.T) {
+		run(t, true, func(c *textproto.Conn, deliveries *[]delivery) {
+			cmd(t, c, 500, "EHLO client")
+			cmd(t, c, 250, "LHLO client")
+			cmd(t, c, 250, "MAIL FROM:<news@example.net>")
+			cmd(t, c, 250, "RCPT TO:<secret1@in.example.org>")
+			cmd(t, c, 250, "RCPT TO:<invalid@in.example.org>")
+			cmd(t, c, 250, "RCPT TO:<secret2@in.example.org>")
+			send(t, c, "Subject: test\r\n\r\nhello\r\n")
+
+			// One reply per recipient
+			for _, code := range []int{250, 554, 250} {
+				_, _, err := c.ReadResponse(code)
+				require.NoError(t, err)
+			}
+			require.Len(t, *deliveries, 2)
+
+			cmd(t, c, 221, "QUIT")
+		})
+	})
+
+	t.Run("pipelining", func(t *testing.T) {
+		run(t, false, func(c *textproto.Conn, _ *[]delivery) {
+			w := bufio.NewWriter(c.W)
+			w.WriteString("EHLO client\r\nNOOP\r\nRSET\r\n") //nolint:errcheck
+			require.NoError(t, w.Flush())
+
+			for _, code := range []int{250, 250, 250} {
+				_, _, err := c.ReadResponse(code)
+				require.NoError(t, err)
+			}
+		})
+	})
+}
@@ -0,0 +1,394 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package newsletters
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"codeberg.org/readeck/readeck/configs"
+)
+
+const (
+	maxRecipients  = 50
+	commandTimeout = 5 * time.Minute
+)
+
+// Server is a minimal SMTP or LMTP server receiving the newsletters.
+//
+// It doesn't support TLS nor authentication. It's meant to listen on
+// a local or private network and receive the messages relayed by
+// an MTA, or directly from the internet behind a proxy that handles
+// these concerns.
+type Server struct {
+	// Hostname is the name sent in the greeting.
+	Hostname string
+
+	// LMTP switches the server to the LMTP protocol.
+	LMTP bool
+
+	// MaxSize is the maximum message size, in bytes.
+	MaxSize int64
+
+	// Recipient checks a recipient address.
+	Recipient func(rcpt string) error
+
+	// Deliver handles a message received by a recipient.
+	Deliver func(rcpt string, data []byte) error
+
+	mu        sync.Mutex
+	listener  net.Listener
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+	isClosing bool
+}
+
+// NewServer returns a [Server] using the inbound email configuration,
+// that saves the messages it receives with [Deliver].
+func NewServer() *Server {
+	hostname := configs.Config.Email.Inbound.Domain
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+
+	return &Server{
+		Hostname:  hostname,
+		LMTP:      configs.Config.Email.Inbound.Protocol == "lmtp",
+		MaxSize:   int64(configs.Config.Email.Inbound.MaxSize) << 20,
+		Recipient: CheckRecipient,
+		Deliver: func(rcpt string, data []byte) error {
+			_, err := Deliver(rcpt, data)
+			return err
+		},
+	}
+}
+
+// ListenAndServe listens on the given address and serves
+// the incoming connections.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts the incoming connections on a listener. It returns
+// nil once the server is closed.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	s.listener = ln
+	s.conns = map[net.Conn]struct{}{}
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closing := s.isClosing
+			s.mu.Unlock()
+			if closing {
+				return nil
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Close stops the server and closes all the open connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.isClosing = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for c := range s.conns {
+		c.Close() //nolint:errcheck
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// session is an SMTP or LMTP session state.
+type session struct {
+	srv   *Server
+	conn  net.Conn
+	text  *textproto.Conn
+	log   *slog.Logger
+	helo  bool
+	from  *string
+	rcpts []string
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close() //nolint:errcheck
+
+	sess := &session{
+		srv:  s,
+		conn: conn,
+		text: textproto.NewConn(conn),
+		log:  slog.With(slog.String("remote", conn.RemoteAddr().String())),
+	}
+
+	sess.reply(220, fmt.Sprintf("%s %s Readeck ready", s.Hostname, s.protocol()))
+
+	for {
+		conn.SetDeadline(time.Now().Add(commandTimeout)) //nolint:errcheck
+		line, err := sess.text.ReadLine()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				sess.log.Debug("inbound email", slog.Any("err", err))
+			}
+			return
+		}
+
+		cmd, arg, _ := strings.Cut(line, " ")
+		if !sess.command(strings.ToUpper(cmd), strings.TrimSpace(arg)) {
+			return
+		}
+	}
+}
+
+func (s *Server) protocol() string {
+	if s.LMTP {
+		return "LMTP"
+	}
+	return "ESMTP"
+}
+
+// command handles a command and returns false when
+// the session must end.
+func (sess *session) command(cmd, arg string) bool {
+	switch cmd {
+	case "HELO", "EHLO", "LHLO":
+		if (cmd == "LHLO") != sess.srv.LMTP {
+			sess.reply(500, "5.5.1 Unknown command")
+			return true
+		}
+		sess.reset()
+		sess.helo = true
+		if cmd == "HELO" {
+			sess.reply(250, sess.srv.Hostname)
+			return true
+		}
+		sess.reply(250,
+			sess.srv.Hostname,
+			"PIPELINING",
+			"8BITMIME",
+			"ENHANCEDSTATUSCODES",
+			fmt.Sprintf("SIZE %d", sess.srv.MaxSize),
+		)
+	case "MAIL":
+		sess.mail(arg)
+	case "RCPT":
+		sess.rcpt(arg)
+	case "DATA":
+		return sess.data()
+	case "RSET":
+		sess.reset()
+		sess.reply(250, "2.0.0 OK")
+	case "NOOP":
+		sess.reply(250, "2.0.0 OK")
+	case "VRFY":
+		sess.reply(252, "2.5.0 Cannot verify user")
+	case "QUIT":
+		sess.reply(221, "2.0.0 Bye")
+		return false
+	default:
+		sess.reply(500, "5.5.1 Unknown command")
+	}
+	return true
+}
+
+func (sess *session) mail(arg string) {
+	if !sess.helo {
+		sess.reply(503, "5.5.1 Send HELO first")
+		return
+	}
+	if sess.from != nil {
+		sess.reply(503, "5.5.1 Sender already specified")
+		return
+	}
+
+	addr, params, ok := parsePath(arg, "FROM:")
+	if !ok {
+		sess.reply(501, "5.5.4 Syntax: MAIL FROM:<address>")
+		return
+	}
+	for _, p := range params {
+		k, v, _ := strings.Cut(p, "=")
+		if strings.EqualFold(k, "SIZE") {
+			var size int64
+			if _, err := fmt.Sscan(v, &size); err == nil && size > sess.srv.MaxSize {
+				sess.reply(552, "5.3.4 Message too big")
+				return
+			}
+		}
+	}
+
+	sess.from = &addr
+	sess.reply(250, "2.1.0 OK")
+}
+
+func (sess *session) rcpt(arg string) {
+	if sess.from == nil {
+		sess.reply(503, "5.5.1 Send MAIL first")
+		return
+	}
+
+	addr, _, ok := parsePath(arg, "TO:")
+	if !ok || addr == "" {
+		sess.reply(501, "5.5.4 Syntax: RCPT TO:<address>")
+		return
+	}
+	if len(sess.rcpts) >= maxRecipients {
+		sess.reply(452, "4.5.3 Too many recipients")
+		return
+	}
+
+	if err := sess.srv.Recipient(addr); err != nil {
+		if errors.Is(err, ErrUnknownRecipient) {
+			sess.reply(550, "5.1.1 Unknown recipient")
+			return
+		}
+		sess.log.Error("inbound email", slog.Any("err", err))
+		sess.reply(451, "4.3.0 Temporary failure")
+		return
+	}
+
+	sess.rcpts = append(sess.rcpts, addr)
+	sess.reply(250, "2.1.5 OK")
+}
+
+func (sess *session) data() bool {
+	if len(sess.rcpts) == 0 {
+		sess.reply(503, "5.5.1 Send RCPT first")
+		return true
+	}
+
+	sess.reply(354, "Start mail input; end with <CRLF>.<CRLF>")
+
+	r := sess.text.DotReader()
+	data, err := io.ReadAll(io.LimitReader(r, sess.srv.MaxSize+1))
+	if err != nil {
+		return false
+	}
+	if int64(len(data)) > sess.srv.MaxSize {
+		if _, err = io.Copy(io.Discard, r); err != nil {
+			return false
+		}
+		sess.replyAll(552, "5.3.4 Message too big")
+		sess.reset()
+		return true
+	}
+
+	// SMTP sends one reply for all the recipients, LMTP one per recipient.
+	var lastErr error
+	for _, rcpt := range sess.rcpts {
+		err := sess.srv.Deliver(rcpt, data)
+		if err != nil {
+			sess.log.Warn("inbound email",
+				slog.String("rcpt", rcpt),
+				slog.Any("err", err),
+			)
+			lastErr = err
+		}
+		if sess.srv.LMTP {
+			sess.deliveryReply(err)
+		}
+	}
+	if !sess.srv.LMTP {
+		sess.deliveryReply(lastErr)
+	}
+
+	sess.reset()
+	return true
+}
+
+func (sess *session) deliveryReply(err error) {
+	switch {
+	case err == nil:
+		sess.reply(250, "2.0.0 OK")
+	case errors.Is(err, ErrInvalidMessage):
+		sess.reply(554, "5.6.0 Invalid message")
+	case errors.Is(err, ErrUnknownRecipient):
+		sess.reply(550, "5.1.1 Unknown recipient")
+	default:
+		sess.reply(451, "4.3.0 Temporary failure")
+	}
+}
+
+// replyAll sends a reply once for SMTP and once per recipient for LMTP.
+func (sess *session) replyAll(code int, msg string) {
+	n := 1
+	if sess.srv.LMTP {
+		n = len(sess.rcpts)
+	}
+	for range n {
+		sess.reply(code, msg)
+	}
+}
+
+func (sess *session) reset() {
+	sess.from = nil
+	sess.rcpts = nil
+}
+
+// reply sends a single or multiline reply.
+func (sess *session) reply(code int, lines ...string) {
+	w := bufio.NewWriter(sess.conn)
+	for i, line := range lines {
+		sep := "-"
+		if i == len(lines)-1 {
+			sep = " "
+		}
+		fmt.Fprintf(w, "%d%s%s\r\n", code, sep, line)
+	}
+	w.Flush() //nolint:errcheck
+}
+
+// parsePath parses the "FROM:<address> PARAMS" or "TO:<address> PARAMS"
+// command arguments.
+func parsePath(arg, prefix string) (addr string, params []string, ok bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	arg = strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(arg, "<") {
+		return "", nil, false
+	}
+	end := strings.IndexByte(arg, '>')
+	if end < 0 {
+		return "", nil, false
+	}
+
+	return arg[1:end], strings.Fields(arg[end+1:]), true
+}
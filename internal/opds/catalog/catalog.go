@@ -34,6 +34,11 @@ func New(srv *server.Server, r *http.Request, options ...func(*opds.Feed)) *Cata
 				Href:     srv.AbsoluteURL(r, "/opds").String(),
 				TypeLink: opds.OPDSTypeNavigation,
 			},
+			{
+				Rel:      "search",
+				Href:     srv.AbsoluteURL(r, "/opds/search.xml").String(),
+				TypeLink: opds.OpenSearchType,
+			},
 		},
 		Entries: []opds.Entry{},
 		Author: []opds.Author{
@@ -59,13 +64,14 @@ func WithFeedType(t string) func(*opds.Feed) {
 }
 
 // WithURL sets the "self" feed link entry.
+// The link type is the feed type, so it must be set before.
 func WithURL(href string) func(*opds.Feed) {
 	return func(feed *opds.Feed) {
 		feed.ID = opds.URLID(href)
 		feed.Links = append(feed.Links, opds.Link{
 			Rel:      "self",
 			Href:     href,
-			TypeLink: opds.OPDSTypeNavigation,
+			TypeLink: feed.FeedType,
 		})
 	}
 }
@@ -77,6 +83,35 @@ func WithLink(t, rel, href string) func(*opds.Feed) {
 	}
 }
 
+// WithPagination adds the pagination links and the OpenSearch
+// result counts to the feed.
+func WithPagination(srv *server.Server, r *http.Request, p server.Pagination) func(*opds.Feed) {
+	return func(feed *opds.Feed) {
+		feed.TotalResults = p.TotalCount
+		feed.ItemsPerPage = p.Limit
+		feed.StartIndex = p.Offset + 1
+		for _, x := range srv.GetPaginationLinks(r, p) {
+			WithLink(feed.FeedType, x.Rel, x.URL)(feed)
+		}
+	}
+}
+
+// WithFacet adds a facet link to the feed. A facet is a link to
+// the same feed with a different filter. Facets with the same group
+// are mutually exclusive.
+func WithFacet(group, title, href string, active bool) func(*opds.Feed) {
+	return func(feed *opds.Feed) {
+		feed.Links = append(feed.Links, opds.Link{
+			Rel:         opds.RelFacet,
+			Href:        href,
+			TypeLink:    feed.FeedType,
+			Title:       title,
+			FacetGroup:  group,
+			ActiveFacet: active,
+		})
+	}
+}
+
 // WithTitle sets the feed's title.
 func WithTitle(title string) func(*opds.Feed) {
 	return func(feed *opds.Feed) {
@@ -120,6 +155,7 @@ func WithBookEntry(
 	id uuid.UUID, title string, href string,
 	issued, published, updated time.Time,
 	publisher string, language string, description string,
+	options ...func(*opds.Entry),
 ) func(*opds.Feed) {
 	return func(feed *opds.Feed) {
 		e := opds.Entry{
@@ -146,6 +182,9 @@ func WithBookEntry(
 				Content:     bleach.SanitizeString(description),
 			}
 		}
+		for _, f := range options {
+			f(&e)
+		}
 
 		feed.Entries = append(feed.Entries, e)
 	}
@@ -157,6 +196,18 @@ func WithBookEntry(
 	// })
 }
 
+// WithAcquisitionLink adds an alternative acquisition link to an entry.
+func WithAcquisitionLink(contentType, href, title string) func(*opds.Entry) {
+	return func(e *opds.Entry) {
+		e.Links = append(e.Links, opds.Link{
+			Rel:      "http://opds-spec.org/acquisition",
+			TypeLink: contentType,
+			Href:     href,
+			Title:    title,
+		})
+	}
+}
+
 // Render write the full catalog to a writer.
 func (c *Catalog) Render(w http.ResponseWriter, r *http.Request) error {
 	buf := new(bytes.Buffer)
@@ -6,6 +6,7 @@
 package opds
 
 import (
+	"bytes"
 	"net/http"
 
 	"github.com/doug-martin/goqu/v9"
@@ -32,6 +33,7 @@ func SetupRoutes(s *server.Server) {
 	h.Use(middleware.GetHead)
 	h.With(s.WithPermission("api:opds", "read")).Group(func(r chi.Router) {
 		r.Get("/", h.mainCatalog)
+		r.Get("/search.xml", h.searchDescription)
 		r.Route("/bookmarks", bookmark_routes.NewOPDSRouteHandler(s))
 	})
 
@@ -77,9 +79,45 @@ func (h *opdsRouter) mainCatalog(w http.ResponseWriter, r *http.Request) {
 				e.Links[0].TypeLink = opds.OPDSTypeNavigation
 			},
 		),
+		catalog.WithNavEntry(
+			tr.Gettext("Bookmark Labels"), lastUpdate,
+			h.srv.AbsoluteURL(r, ".", "bookmarks/labels").String(),
+			func(e *opds.Entry) {
+				e.Links[0].TypeLink = opds.OPDSTypeNavigation
+			},
+		),
+		catalog.WithNavEntry(
+			tr.Gettext("Newspapers"), lastUpdate,
+			h.srv.AbsoluteURL(r, ".", "bookmarks/newspapers").String(),
+		),
 	)
 
 	if err := c.Render(w, r); err != nil {
 		h.srv.Error(w, r, err)
 	}
 }
+
+// searchDescription returns the OpenSearch description of the catalog.
+// The search returns an acquisition feed of all the matching bookmarks.
+func (h *opdsRouter) searchDescription(w http.ResponseWriter, r *http.Request) {
+	tr := h.srv.Locale(r)
+	d := &opds.OpenSearchDescription{
+		ShortName:   "Readeck",
+		Description: tr.Gettext("Search Readeck Bookmarks"),
+		URLs: []opds.OpenSearchURL{
+			{
+				TypeLink: opds.OPDSTypeAcquisistion,
+				Template: h.srv.AbsoluteURL(r, "/opds/bookmarks/all").String() + "?search={searchTerms}",
+			},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := d.Encode(buf); err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", opds.OpenSearchType)
+	w.Write(buf.Bytes())
+}
@@ -5,11 +5,59 @@
 package opds_test
 
 import (
+	"encoding/xml"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
 	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
 )
 
+type testLink struct {
+	Rel         string `xml:"rel,attr"`
+	Href        string `xml:"href,attr"`
+	Type        string `xml:"type,attr"`
+	Title       string `xml:"title,attr"`
+	FacetGroup  string `xml:"facetGroup,attr"`
+	ActiveFacet bool   `xml:"activeFacet,attr"`
+}
+
+type testFeed struct {
+	Title        string     `xml:"title"`
+	Links        []testLink `xml:"link"`
+	TotalResults int        `xml:"totalResults"`
+	ItemsPerPage int        `xml:"itemsPerPage"`
+	StartIndex   int        `xml:"startIndex"`
+	Entries      []struct {
+		Title string     `xml:"title"`
+		Links []testLink `xml:"link"`
+	} `xml:"entry"`
+}
+
+func parseFeed(t *testing.T, r *Response) *testFeed {
+	feed := &testFeed{}
+	require.NoError(t, xml.Unmarshal(r.Body, feed))
+	return feed
+}
+
+func (f *testFeed) link(rel string) *testLink {
+	for _, x := range f.Links {
+		if x.Rel == rel {
+			return &x
+		}
+	}
+	return nil
+}
+
+func (f *testFeed) entryTitles() []string {
+	res := []string{}
+	for _, e := range f.Entries {
+		res = append(res, e.Title)
+	}
+	return res
+}
+
 func TestPermissions(t *testing.T) {
 	app := NewTestApp(t)
 	defer app.Close(t)
@@ -61,3 +109,176 @@ func TestPermissions(t *testing.T) {
 		)
 	}
 }
+
+func TestCatalog(t *testing.T) {
+	app := NewTestApp(t)
+	defer app.Close(t)
+
+	client := NewClient(t, app)
+	u := app.Users["user"]
+
+	for _, b := range []*bookmarks.Bookmark{
+		{
+			URL: "https://example.net/fox", Title: "The quick brown fox",
+			DocumentType: "article", Labels: []string{"animals", "a/b"},
+		},
+		{
+			URL: "https://example.net/dog", Title: "A lazy dog",
+			DocumentType: "article", Labels: []string{"animals"}, ReadProgress: 100,
+		},
+		{
+			URL: "https://example.net/video", Title: "A video",
+			DocumentType: "video",
+		},
+	} {
+		b.UserID = &u.User.ID
+		b.State = bookmarks.StateLoaded
+		require.NoError(t, bookmarks.Bookmarks.Create(b))
+	}
+
+	c := &bookmarks.Collection{
+		UserID: &u.User.ID,
+		Name:   "Animals",
+	}
+	require.NoError(t, bookmarks.Collections.Create(c))
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Target:       "/opds",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				feed := parseFeed(t, r)
+				require.Contains(t, feed.entryTitles(), "Bookmark Labels")
+				search := feed.link("search")
+				require.NotNil(t, search)
+				require.Equal(t, "application/opensearchdescription+xml", search.Type)
+				require.Equal(t, "http://"+r.URL.Host+"/opds/search.xml", search.Href)
+			},
+		},
+		RequestTest{
+			Target:       "/opds/search.xml",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Equal(t, "application/opensearchdescription+xml", r.Header.Get("content-type"))
+				var d struct {
+					URL struct {
+						Template string `xml:"template,attr"`
+					} `xml:"Url"`
+				}
+				require.NoError(t, xml.Unmarshal(r.Body, &d))
+				require.Equal(t, "http://"+r.URL.Host+"/opds/bookmarks/all?search={searchTerms}", d.URL.Template)
+			},
+		},
+		RequestTest{
+			Target:       "/opds/bookmarks/all?search=fox",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				feed := parseFeed(t, r)
+				require.Equal(t, []string{"The quick brown fox"}, feed.entryTitles())
+				require.Equal(t, 1, feed.TotalResults)
+			},
+		},
+		RequestTest{
+			Target:       "/opds/bookmarks/all?limit=2",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				feed := parseFeed(t, r)
+				require.Len(t, feed.Entries, 2)
+				require.Equal(t, len(u.Bookmarks)+3, feed.TotalResults)
+				require.Equal(t, 2, feed.ItemsPerPage)
+				require.Equal(t, 1, feed.StartIndex)
+				require.NotNil(t, feed.link("next"))
+				require.Nil(t, feed.link("previous"))
+			},
+		},
+		RequestTest{
+			Target:       "/opds/bookmarks/all?read_status=read&limit=2&offset=2",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				feed := parseFeed(t, r)
+				facets := map[string]testLink{}
+				for _, x := range feed.Links {
+					if x.Rel == "http://opds-spec.org/facet" {
+						facets[x.FacetGroup+"/"+x.Title] = x
+					}
+				}
+				require.Len(t, facets, 9)
+
+				active := []string{}
+				for k, x := range facets {
+					if x.ActiveFacet {
+						active = append(active, k)
+					}
+				}
+				require.ElementsMatch(t, []string{"Read status/Completed", "Type/All"}, active)
+				require.Equal(t,
+					"http://"+r.URL.Host+"/opds/bookmarks/all?limit=2&read_status=read&type=video",
+					facets["Type/Video"].Href,
+				)
+				require.Equal(t,
+					"http://"+r.URL.Host+"/opds/bookmarks/all?limit=2",
+					facets["Read status/All"].Href,
+				)
+			},
+		},
+		RequestTest{
+			Target:       "/opds/bookmarks/all?read_status=read",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Equal(t, []string{"A lazy dog"}, parseFeed(t, r).entryTitles())
+			},
+		},
+		RequestTest{
+			Target:       "/opds/bookmarks/unread?type=video",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Equal(t, []string{"A video"}, parseFeed(t, r).entryTitles())
+			},
+		},
+		RequestTest{
+			Target:       "/opds/bookmarks/labels",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				feed := parseFeed(t, r)
+				require.Equal(t, []string{"a/b", "animals", "test label"}, feed.entryTitles())
+				require.Equal(t,
+					"http://"+r.URL.Host+"/opds/bookmarks/labels/a%2Fb",
+					feed.Entries[0].Links[0].Href,
+				)
+			},
+		},
+		RequestTest{
+			Target:       "/opds/bookmarks/labels/animals",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				feed := parseFeed(t, r)
+				require.Equal(t, "Readeck Label: animals", feed.Title)
+				require.ElementsMatch(t, []string{"The quick brown fox", "A lazy dog"}, feed.entryTitles())
+			},
+		},
+		RequestTest{
+			Target:       "/opds/bookmarks/labels/a%2Fb",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Equal(t, []string{"The quick brown fox"}, parseFeed(t, r).entryTitles())
+			},
+		},
+		RequestTest{
+			Target:       "/opds/bookmarks/collections",
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				feed := parseFeed(t, r)
+				require.Equal(t, []string{"Animals"}, feed.entryTitles())
+				links := feed.Entries[0].Links
+				require.Len(t, links, 2)
+				require.Equal(t, "subsection", links[0].Rel)
+				require.Equal(t, "http://opds-spec.org/acquisition", links[1].Rel)
+				require.Equal(t, "application/epub+zip", links[1].Type)
+				require.Equal(t,
+					"http://"+r.URL.Host+"/api/bookmarks/export.epub?collection="+c.UID+"&sort=created",
+					links[1].Href,
+				)
+			},
+		},
+	)
+}
@@ -10,9 +10,7 @@ import (
 	"fmt"
 	"io"
 	"maps"
-	"os"
 	"path"
-	"path/filepath"
 	"slices"
 	"strings"
 
@@ -253,17 +251,17 @@ func (imp *Importer) loadBookmark(tx *goqu.TxDatabase, item *bookmarkItem) (err
 	}
 
 	// Copy files to zipfile
-	dest := filepath.Join(bookmarks.StoragePath(), b.FilePath+".zip")
-	if err = os.MkdirAll(path.Dir(dest), 0o750); err != nil {
-		return err
-	}
-	w, err := os.Create(dest)
+	w, err := bookmarks.Storage().Create(b.GetFileName())
 	if err != nil {
 		return err
 	}
 
 	zw := zipfs.NewZipRW(w, nil, 0)
-	defer zw.Close() // nolint:errcheck
+	defer func() {
+		if cerr := zw.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
 
 	prefix := "bookmarks/" + item.UID + "/container/"
 	for _, f := range imp.zr.File {
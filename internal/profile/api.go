@@ -6,6 +6,7 @@ package profile
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"time"
@@ -17,12 +18,16 @@ import (
 	"codeberg.org/readeck/readeck/internal/auth/tokens"
 	"codeberg.org/readeck/readeck/internal/auth/users"
 	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/internal/webhooks"
 	"codeberg.org/readeck/readeck/pkg/forms"
 )
 
 type (
-	ctxTokenListKey struct{}
-	ctxtTokenKey    struct{}
+	ctxTokenListKey    struct{}
+	ctxtTokenKey       struct{}
+	ctxWebhookListKey  struct{}
+	ctxWebhookKey      struct{}
+	ctxDeliveryListKey struct{}
 )
 
 // profileAPI is the base settings API router.
@@ -50,6 +55,18 @@ func newProfileAPI(s *server.Server) *profileAPI {
 		r.With(api.withToken).Delete("/tokens/{uid}", api.tokenDelete)
 	})
 
+	r.With(api.srv.WithPermission("api:profile:webhooks", "read")).Group(func(r chi.Router) {
+		r.With(api.withWebhookList).Get("/webhooks", api.webhookList)
+		r.With(api.withWebhook).Get("/webhooks/{uid}", api.webhookInfo)
+		r.With(api.withWebhook, api.withDeliveryList).Get("/webhooks/{uid}/deliveries", api.deliveryList)
+	})
+
+	r.With(api.srv.WithPermission("api:profile:webhooks", "write")).Group(func(r chi.Router) {
+		r.Post("/webhooks", api.webhookCreate)
+		r.With(api.withWebhook).Patch("/webhooks/{uid}", api.webhookUpdate)
+		r.With(api.withWebhook).Delete("/webhooks/{uid}", api.webhookDelete)
+	})
+
 	return api
 }
 
@@ -253,3 +270,245 @@ func newTokenItem(s *server.Server, r *http.Request, t *tokens.Token, base strin
 		Roles:     t.Roles,
 	}
 }
+
+func (api *profileAPI) withWebhookList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := webhookList{}
+
+		pf := api.srv.GetPageParams(r, 30)
+		if pf == nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ds := webhooks.Webhooks.Query().
+			Where(
+				goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+			).
+			Order(goqu.C("created").Desc()).
+			Limit(uint(pf.Limit())).
+			Offset(uint(pf.Offset()))
+
+		count, err := ds.ClearOrder().ClearLimit().ClearOffset().Count()
+		if err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		items := []*webhooks.Webhook{}
+		if err := ds.ScanStructs(&items); err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		res.Pagination = api.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset())
+
+		res.Items = make([]webhookItem, len(items))
+		for i, item := range items {
+			res.Items[i] = newWebhookItem(api.srv, r, item, ".")
+		}
+
+		ctx := context.WithValue(r.Context(), ctxWebhookListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *profileAPI) withWebhook(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid := chi.URLParam(r, "uid")
+		wh, err := webhooks.Webhooks.GetOne(
+			goqu.C("uid").Eq(uid),
+			goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+		)
+		if err != nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxWebhookKey{}, wh)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *profileAPI) withDeliveryList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := deliveryList{}
+		wh := r.Context().Value(ctxWebhookKey{}).(*webhooks.Webhook)
+
+		pf := api.srv.GetPageParams(r, 30)
+		if pf == nil {
+			api.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ds := webhooks.Deliveries.Query().
+			Where(
+				goqu.C("webhook_id").Eq(wh.ID),
+			).
+			Order(goqu.C("created").Desc(), goqu.C("id").Desc()).
+			Limit(uint(pf.Limit())).
+			Offset(uint(pf.Offset()))
+
+		count, err := ds.ClearOrder().ClearLimit().ClearOffset().Count()
+		if err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		items := []*webhooks.Delivery{}
+		if err := ds.ScanStructs(&items); err != nil {
+			api.srv.Error(w, r, err)
+			return
+		}
+
+		res.Pagination = api.srv.NewPagination(r, int(count), pf.Limit(), pf.Offset())
+
+		res.Items = make([]deliveryItem, len(items))
+		for i, item := range items {
+			res.Items[i] = newDeliveryItem(item)
+		}
+
+		ctx := context.WithValue(r.Context(), ctxDeliveryListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (api *profileAPI) webhookList(w http.ResponseWriter, r *http.Request) {
+	wl := r.Context().Value(ctxWebhookListKey{}).(webhookList)
+
+	api.srv.SendPaginationHeaders(w, r, wl.Pagination)
+	api.srv.Render(w, r, http.StatusOK, wl.Items)
+}
+
+func (api *profileAPI) webhookInfo(w http.ResponseWriter, r *http.Request) {
+	wh := r.Context().Value(ctxWebhookKey{}).(*webhooks.Webhook)
+	api.srv.Render(w, r, http.StatusOK, newWebhookItem(api.srv, r, wh, "./.."))
+}
+
+func (api *profileAPI) webhookCreate(w http.ResponseWriter, r *http.Request) {
+	f := newWebhookForm(api.srv.Locale(r))
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	wh, err := f.createWebhook(auth.GetRequestUser(r).ID)
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", api.srv.AbsoluteURL(r, ".", wh.UID).String())
+	api.srv.TextMessage(w, r, http.StatusCreated, "Webhook created")
+}
+
+func (api *profileAPI) webhookUpdate(w http.ResponseWriter, r *http.Request) {
+	wh := r.Context().Value(ctxWebhookKey{}).(*webhooks.Webhook)
+
+	f := newWebhookForm(api.srv.Locale(r))
+	f.setWebhook(wh)
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		api.srv.Render(w, r, http.StatusUnprocessableEntity, f)
+		return
+	}
+
+	updated, err := f.updateWebhook(wh)
+	if err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	api.srv.Render(w, r, http.StatusOK, updated)
+}
+
+func (api *profileAPI) webhookDelete(w http.ResponseWriter, r *http.Request) {
+	wh := r.Context().Value(ctxWebhookKey{}).(*webhooks.Webhook)
+	if err := wh.Delete(); err != nil {
+		api.srv.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *profileAPI) deliveryList(w http.ResponseWriter, r *http.Request) {
+	dl := r.Context().Value(ctxDeliveryListKey{}).(deliveryList)
+
+	api.srv.SendPaginationHeaders(w, r, dl.Pagination)
+	api.srv.Render(w, r, http.StatusOK, dl.Items)
+}
+
+type webhookList struct {
+	Pagination server.Pagination
+	Items      []webhookItem
+}
+
+type webhookItem struct {
+	*webhooks.Webhook `json:"-"`
+
+	ID        string    `json:"id"`
+	Href      string    `json:"href"`
+	Created   time.Time `json:"created"`
+	Updated   time.Time `json:"updated"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret"`
+	Events    []string  `json:"events"`
+	IsEnabled bool      `json:"is_enabled"`
+}
+
+func newWebhookItem(s *server.Server, r *http.Request, w *webhooks.Webhook, base string) webhookItem {
+	res := webhookItem{
+		Webhook:   w,
+		ID:        w.UID,
+		Href:      s.AbsoluteURL(r, base, w.UID).String(),
+		Created:   w.Created,
+		Updated:   w.Updated,
+		URL:       w.URL,
+		Secret:    w.Secret,
+		Events:    w.Events,
+		IsEnabled: w.IsEnabled,
+	}
+	if res.Events == nil {
+		res.Events = []string{}
+	}
+
+	return res
+}
+
+type deliveryList struct {
+	Pagination server.Pagination
+	Items      []deliveryItem
+}
+
+type deliveryItem struct {
+	*webhooks.Delivery `json:"-"`
+
+	ID          string          `json:"id"`
+	Created     time.Time       `json:"created"`
+	Event       string          `json:"event"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt *time.Time      `json:"next_attempt"`
+	Delivered   *time.Time      `json:"delivered"`
+	StatusCode  int             `json:"status_code"`
+	LastError   string          `json:"last_error"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+func newDeliveryItem(d *webhooks.Delivery) deliveryItem {
+	return deliveryItem{
+		Delivery:    d,
+		ID:          d.UID,
+		Created:     d.Created,
+		Event:       d.Event,
+		Attempts:    d.Attempts,
+		NextAttempt: d.NextAttempt,
+		Delivered:   d.Delivered,
+		StatusCode:  d.StatusCode,
+		LastError:   d.LastError,
+		Payload:     json.RawMessage(d.Payload),
+	}
+}
@@ -224,3 +224,105 @@ func TestAPIDeleteToken(t *testing.T) {
 		},
 	)
 }
+
+func TestWebhookAPI(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			JSON:         true,
+			Target:       "/api/profile/webhooks",
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/api/profile/webhooks",
+			JSON: map[string]interface{}{
+				"url": "ftp://example.org/",
+			},
+			ExpectStatus: 422,
+			Assert: func(t *testing.T, r *Response) {
+				r.AssertJQ(t, ".fields.url.errors", []any{"invalid URL"})
+			},
+		},
+		RequestTest{
+			Method: "POST",
+			Target: "/api/profile/webhooks",
+			JSON: map[string]interface{}{
+				"url":    "https://example.org/hook",
+				"events": []string{"bookmark.archived", "bookmark.created"},
+			},
+			ExpectStatus:   201,
+			ExpectRedirect: "/api/profile/webhooks/.+",
+			ExpectJSON:     `{"status":201,"message":"Webhook created"}`,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 0).Redirect }}",
+			ExpectStatus: 200,
+			ExpectJSON: `{
+				"id": "<<PRESENCE>>",
+				"href": "<<PRESENCE>>",
+				"created": "<<PRESENCE>>",
+				"updated": "<<PRESENCE>>",
+				"url": "https://example.org/hook",
+				"secret": "<<PRESENCE>>",
+				"events": ["bookmark.created", "bookmark.archived"],
+				"is_enabled": true
+			}`,
+		},
+		RequestTest{
+			Method: "PATCH",
+			Target: "{{ (index .History 0).Path }}",
+			JSON: map[string]interface{}{
+				"events":     []string{},
+				"is_enabled": false,
+			},
+			ExpectStatus: 200,
+			ExpectJSON: `{
+				"id": "<<PRESENCE>>",
+				"updated": "<<PRESENCE>>",
+				"events": [
+					"bookmark.created",
+					"bookmark.extracted",
+					"bookmark.archived",
+					"bookmark.marked",
+					"bookmark.labeled",
+					"bookmark.annotated"
+				],
+				"is_enabled": false
+			}`,
+		},
+		RequestTest{
+			Method: "PATCH",
+			Target: "{{ (index .History 1).Path }}",
+			JSON: map[string]interface{}{
+				"events": []string{"foo"},
+			},
+			ExpectStatus: 422,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 2).Path }}/deliveries",
+			ExpectStatus: 200,
+			ExpectJSON:   `[]`,
+		},
+		RequestTest{
+			Method:       "DELETE",
+			JSON:         true,
+			Target:       "{{ (index .History 3).Path }}",
+			ExpectStatus: 204,
+		},
+		RequestTest{
+			JSON:         true,
+			Target:       "{{ (index .History 4).Path }}",
+			ExpectStatus: 404,
+		},
+	)
+}
@@ -7,6 +7,7 @@ package profile
 import (
 	"context"
 	"errors"
+	"slices"
 	"strings"
 	"time"
 
@@ -14,9 +15,14 @@ import (
 
 	"codeberg.org/readeck/readeck/internal/auth/tokens"
 	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/internal/newsletters"
 	"codeberg.org/readeck/readeck/internal/sessions"
+	"codeberg.org/readeck/readeck/internal/sitelogins"
+	"codeberg.org/readeck/readeck/internal/webhooks"
 	"codeberg.org/readeck/readeck/locales"
 	"codeberg.org/readeck/readeck/pkg/forms"
+	"codeberg.org/readeck/readeck/pkg/totp"
 )
 
 type (
@@ -31,6 +37,12 @@ type profileForm struct {
 var (
 	errInvalidUserOrEmail = forms.Gettext("invalid username and/or email")
 	errInvalidPassword    = forms.Gettext("invalid password")
+	errInvalidTOTP        = forms.Gettext("invalid authentication code")
+	errInvalidDomain      = forms.Gettext("invalid domain name")
+	errInvalidCookies     = forms.Gettext("invalid cookie list")
+	errDomainInUse        = forms.Gettext("you already have a login for this domain")
+	errInvalidSender      = forms.Gettext("invalid email address or domain name")
+	errSenderInUse        = forms.Gettext("you already have labels for this sender")
 )
 
 // newProfileForm returns a ProfileForm instance.
@@ -195,31 +207,33 @@ type passwordForm struct {
 func newPasswordForm(tr forms.Translator) *passwordForm {
 	return &passwordForm{forms.Must(
 		forms.WithTranslator(context.Background(), tr),
-		forms.NewTextField("current", forms.ValueValidatorFunc[string](func(f forms.Field, value string) error {
-			// If a user was passed in context, then "current"
-			// is mandatory and must match the current user
-			// password.
-			form := forms.GetForm(f)
-			u, ok := form.Context().Value(ctxUserFormKey{}).(*users.User)
-			if !ok {
-				return nil
-			}
-			if errs := forms.ApplyValidators[string](f, value, forms.Required); len(errs) > 0 {
-				return errors.Join(errs...)
-			}
-
-			if !u.CheckPassword(value) {
-				return errInvalidPassword
-			}
-
-			return nil
-		})),
+		forms.NewTextField("current", isCurrentPassword),
 		forms.NewTextField("password",
 			forms.Required, users.IsValidPassword,
 		),
 	)}
 }
 
+// isCurrentPassword is a validator that checks, when a user was passed
+// in the form's context, that the value is mandatory and matches
+// the user's password.
+var isCurrentPassword = forms.ValueValidatorFunc[string](func(f forms.Field, value string) error {
+	form := forms.GetForm(f)
+	u, ok := form.Context().Value(ctxUserFormKey{}).(*users.User)
+	if !ok {
+		return nil
+	}
+	if errs := forms.ApplyValidators[string](f, value, forms.Required); len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if !u.CheckPassword(value) {
+		return errInvalidPassword
+	}
+
+	return nil
+})
+
 // setUser adds a user to the wrapping form's context.
 func (f *passwordForm) setUser(u *users.User) {
 	ctx := context.WithValue(f.Context(), ctxUserFormKey{}, u)
@@ -241,6 +255,64 @@ func (f *passwordForm) updatePassword(u *users.User) (err error) {
 	return
 }
 
+// totpForm is the form to enable two-factor authentication.
+// The secret is generated when displaying the form and sent back
+// with a code proving the authenticator application was set up.
+type totpForm struct {
+	*forms.Form
+}
+
+func newTOTPForm(tr forms.Translator) *totpForm {
+	return &totpForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("secret", forms.Trim, forms.Required),
+		forms.NewTextField("code", forms.Trim, forms.Required),
+	)}
+}
+
+// Validate checks the code against the secret.
+func (f *totpForm) Validate() {
+	if !f.Get("secret").IsValid() || !f.Get("code").IsValid() {
+		return
+	}
+
+	if !totp.Validate(f.Get("secret").String(), f.Get("code").String(), time.Now()) {
+		f.AddErrors("code", errInvalidTOTP)
+	}
+}
+
+// enableTOTP saves the user's secret and returns the new recovery codes.
+func (f *totpForm) enableTOTP(u *users.User) (codes []string, err error) {
+	defer func() {
+		if err != nil {
+			f.AddErrors("", forms.ErrUnexpected)
+		}
+	}()
+
+	if codes, err = u.EnableTOTP(f.Get("secret").String()); err != nil {
+		return
+	}
+
+	// Log out every other session
+	err = u.Update(map[string]interface{}{"seed": u.SetSeed()})
+	return
+}
+
+// totpConfirmForm is a form asking for the user's password before
+// changing their two-factor authentication settings.
+type totpConfirmForm struct {
+	*forms.Form
+}
+
+func newTOTPConfirmForm(tr forms.Translator, u *users.User) *totpConfirmForm {
+	f := &totpConfirmForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("current", isCurrentPassword),
+	)}
+	f.SetContext(context.WithValue(f.Context(), ctxUserFormKey{}, u))
+	return f
+}
+
 type sessionPrefForm struct {
 	*forms.Form
 }
@@ -370,3 +442,405 @@ func (f *tokenForm) updateToken(t *tokens.Token) error {
 	}
 	return nil
 }
+
+// webhookForm is the form used for webhook creation and update.
+type webhookForm struct {
+	*forms.Form
+	webhook *webhooks.Webhook
+}
+
+// newWebhookForm returns a webhookForm instance.
+func newWebhookForm(tr forms.Translator) *webhookForm {
+	res := &webhookForm{}
+	res.Form = forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("url",
+			forms.Trim,
+			forms.FieldValidatorFunc(func(f forms.Field) error {
+				// The URL is only required on creation
+				if res.webhook == nil {
+					return forms.Required(f)
+				}
+				return forms.RequiredOrNil(f)
+			}),
+			forms.IsURL("http", "https"),
+		),
+		forms.NewTextListField("events",
+			forms.Choices(
+				forms.Choice(tr.Gettext("Bookmark created"), webhooks.EventBookmarkCreated),
+				forms.Choice(tr.Gettext("Bookmark extraction finished"), webhooks.EventBookmarkExtracted),
+				forms.Choice(tr.Gettext("Bookmark archived"), webhooks.EventBookmarkArchived),
+				forms.Choice(tr.Gettext("Bookmark marked as favorite"), webhooks.EventBookmarkMarked),
+				forms.Choice(tr.Gettext("Bookmark labels changed"), webhooks.EventBookmarkLabeled),
+				forms.Choice(tr.Gettext("Highlight added"), webhooks.EventBookmarkAnnotated),
+			),
+		),
+		forms.NewBooleanField("is_enabled", forms.RequiredOrNil),
+		forms.NewBooleanField("reset_secret"),
+	)
+
+	return res
+}
+
+// setWebhook set the form's values from an existing webhook.
+func (f *webhookForm) setWebhook(w *webhooks.Webhook) {
+	f.webhook = w
+	f.Get("url").Set(w.URL)
+	f.Get("events").Set([]string(w.Events))
+	f.Get("is_enabled").Set(w.IsEnabled)
+}
+
+// events returns the selected events. When none is selected,
+// the webhook receives all of them.
+func (f *webhookForm) events() types.Strings {
+	if f.Get("events").IsNil() || len(f.Get("events").(forms.TypedField[[]string]).V()) == 0 {
+		return slices.Clone(webhooks.Events)
+	}
+
+	res := types.Strings{}
+	for _, e := range webhooks.Events {
+		if slices.Contains(f.Get("events").(forms.TypedField[[]string]).V(), e) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// createWebhook creates a new webhook.
+func (f *webhookForm) createWebhook(userID int) (w *webhooks.Webhook, err error) {
+	if !f.IsBound() {
+		return nil, errors.New("form is not bound")
+	}
+
+	defer func() {
+		if err != nil {
+			f.AddErrors("", forms.ErrUnexpected)
+		}
+	}()
+
+	w = &webhooks.Webhook{
+		UserID:    &userID,
+		URL:       f.Get("url").String(),
+		Events:    f.events(),
+		IsEnabled: f.Get("is_enabled").IsNil() || f.Get("is_enabled").(forms.TypedField[bool]).V(),
+	}
+
+	err = webhooks.Webhooks.Create(w)
+	return
+}
+
+// updateWebhook performs the webhook update.
+func (f *webhookForm) updateWebhook(w *webhooks.Webhook) (res map[string]any, err error) {
+	if !f.IsBound() {
+		err = errors.New("form is not bound")
+		return
+	}
+
+	res = map[string]any{}
+	for _, field := range f.Fields() {
+		if !field.IsBound() || field.IsNil() {
+			continue
+		}
+		switch n := field.Name(); n {
+		case "url":
+			w.URL = field.String()
+			res[n] = w.URL
+		case "events":
+			w.Events = f.events()
+			res[n] = w.Events
+		case "is_enabled":
+			w.IsEnabled = field.(forms.TypedField[bool]).V()
+			res[n] = w.IsEnabled
+		case "reset_secret":
+			if field.(forms.TypedField[bool]).V() {
+				w.Secret = webhooks.NewSecret()
+				res["secret"] = w.Secret
+			}
+		}
+	}
+
+	if len(res) > 0 {
+		if err = w.Save(); err != nil {
+			f.AddErrors("", forms.ErrUnexpected)
+			return
+		}
+		res["updated"] = w.Updated
+	}
+
+	res["id"] = w.UID
+	return
+}
+
+// siteLoginForm is the form used for site login creation and update.
+type siteLoginForm struct {
+	*forms.Form
+	login *sitelogins.SiteLogin
+}
+
+// newSiteLoginForm returns a siteLoginForm instance.
+func newSiteLoginForm(tr forms.Translator, u *users.User) *siteLoginForm {
+	res := &siteLoginForm{}
+	res.Form = forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("domain",
+			forms.Trim,
+			forms.FieldValidatorFunc(func(f forms.Field) error {
+				// The domain is only required on creation
+				if res.login == nil {
+					return forms.Required(f)
+				}
+				return forms.RequiredOrNil(f)
+			}),
+			forms.ValueValidatorFunc[string](func(f forms.Field, v string) error {
+				if f.IsNil() {
+					return nil
+				}
+				if sitelogins.NormalizeDomain(v) == "" {
+					return errInvalidDomain
+				}
+				return nil
+			}),
+		),
+		forms.NewTextField("username", forms.Trim),
+		forms.NewTextField("password"),
+		forms.NewTextField("cookies",
+			forms.Trim,
+			forms.ValueValidatorFunc[string](func(f forms.Field, v string) error {
+				if f.IsNil() {
+					return nil
+				}
+				if _, err := (&sitelogins.Credentials{Cookies: v}).HTTPCookies(); err != nil {
+					return errInvalidCookies
+				}
+				return nil
+			}),
+		),
+	)
+	res.SetContext(context.WithValue(res.Context(), ctxUserFormKey{}, u))
+
+	return res
+}
+
+// setSiteLogin set the form's values from an existing site login.
+// The password and cookies are never sent back.
+func (f *siteLoginForm) setSiteLogin(l *sitelogins.SiteLogin, c *sitelogins.Credentials) {
+	f.login = l
+	f.Get("domain").Set(l.Domain)
+	f.Get("username").Set(c.Username)
+}
+
+// Validate performs extra validation.
+func (f *siteLoginForm) Validate() {
+	u, _ := f.Context().Value(ctxUserFormKey{}).(*users.User)
+	if u == nil || !f.Get("domain").IsBound() || f.Get("domain").IsNil() || len(f.Get("domain").Errors()) > 0 {
+		return
+	}
+
+	// A user has only one login per domain
+	ds := sitelogins.Logins.Query().Where(
+		goqu.C("user_id").Eq(u.ID),
+		goqu.C("domain").Eq(sitelogins.NormalizeDomain(f.Get("domain").String())),
+	)
+	if f.login != nil {
+		ds = ds.Where(goqu.C("id").Neq(f.login.ID))
+	}
+
+	c, err := ds.Count()
+	if err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return
+	}
+	if c > 0 {
+		f.AddErrors("domain", errDomainInUse)
+	}
+}
+
+// createSiteLogin creates a new site login.
+func (f *siteLoginForm) createSiteLogin(userID int) (l *sitelogins.SiteLogin, err error) {
+	if !f.IsBound() {
+		return nil, errors.New("form is not bound")
+	}
+
+	defer func() {
+		if err != nil {
+			f.AddErrors("", forms.ErrUnexpected)
+		}
+	}()
+
+	l = &sitelogins.SiteLogin{
+		UserID: &userID,
+		Domain: sitelogins.NormalizeDomain(f.Get("domain").String()),
+	}
+	err = l.SetCredentials(&sitelogins.Credentials{
+		Username: f.Get("username").String(),
+		Password: f.Get("password").String(),
+		Cookies:  f.Get("cookies").String(),
+	})
+	if err != nil {
+		return
+	}
+
+	err = sitelogins.Logins.Create(l)
+	return
+}
+
+// updateSiteLogin performs the site login update.
+// Empty password and cookies fields keep their current values.
+func (f *siteLoginForm) updateSiteLogin(l *sitelogins.SiteLogin) (err error) {
+	if !f.IsBound() {
+		return errors.New("form is not bound")
+	}
+
+	defer func() {
+		if err != nil {
+			f.AddErrors("", forms.ErrUnexpected)
+		}
+	}()
+
+	c, err := l.Credentials()
+	if err != nil {
+		return
+	}
+
+	for _, field := range f.Fields() {
+		if !field.IsBound() {
+			continue
+		}
+		switch field.Name() {
+		case "domain":
+			if !field.IsNil() {
+				l.Domain = sitelogins.NormalizeDomain(field.String())
+			}
+		case "username":
+			c.Username = field.String()
+		case "password":
+			if field.String() != "" {
+				c.Password = field.String()
+			}
+		case "cookies":
+			if field.String() != "" {
+				c.Cookies = field.String()
+			}
+		}
+	}
+
+	if err = l.SetCredentials(c); err != nil {
+		return
+	}
+	return l.Save()
+}
+
+// newsletterSenderForm is the form used for newsletter sender
+// creation and update.
+type newsletterSenderForm struct {
+	*forms.Form
+	sender *newsletters.Sender
+}
+
+// newNewsletterSenderForm returns a newsletterSenderForm instance.
+func newNewsletterSenderForm(tr forms.Translator, u *users.User) *newsletterSenderForm {
+	res := &newsletterSenderForm{}
+	res.Form = forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("sender",
+			forms.Trim,
+			forms.Required,
+			forms.ValueValidatorFunc[string](func(f forms.Field, v string) error {
+				if f.IsNil() {
+					return nil
+				}
+				if newsletters.NormalizeSender(v) == "" {
+					return errInvalidSender
+				}
+				return nil
+			}),
+		),
+		forms.NewTextField("labels", forms.Trim, forms.Required),
+	)
+	res.SetContext(context.WithValue(res.Context(), ctxUserFormKey{}, u))
+
+	return res
+}
+
+// setSender set the form's values from an existing sender.
+func (f *newsletterSenderForm) setSender(s *newsletters.Sender) {
+	f.sender = s
+	f.Get("sender").Set(s.Sender)
+	f.Get("labels").Set(strings.Join(s.Labels, ", "))
+}
+
+// Validate performs extra validation.
+func (f *newsletterSenderForm) Validate() {
+	u, _ := f.Context().Value(ctxUserFormKey{}).(*users.User)
+	if u == nil || f.Get("sender").IsNil() || len(f.Get("sender").Errors()) > 0 {
+		return
+	}
+
+	// A user has only one entry per sender
+	ds := newsletters.Senders.Query().Where(
+		goqu.C("user_id").Eq(u.ID),
+		goqu.C("sender").Eq(newsletters.NormalizeSender(f.Get("sender").String())),
+	)
+	if f.sender != nil {
+		ds = ds.Where(goqu.C("id").Neq(f.sender.ID))
+	}
+
+	c, err := ds.Count()
+	if err != nil {
+		f.AddErrors("", forms.ErrUnexpected)
+		return
+	}
+	if c > 0 {
+		f.AddErrors("sender", errSenderInUse)
+	}
+}
+
+// labels returns the comma separated labels as a list.
+func (f *newsletterSenderForm) labels() types.Strings {
+	res := types.Strings{}
+	for _, x := range strings.Split(f.Get("labels").String(), ",") {
+		if x = strings.TrimSpace(x); x != "" && !slices.Contains(res, x) {
+			res = append(res, x)
+		}
+	}
+	slices.Sort(res)
+	return res
+}
+
+// createSender creates a new sender.
+func (f *newsletterSenderForm) createSender(userID int) (s *newsletters.Sender, err error) {
+	if !f.IsBound() {
+		return nil, errors.New("form is not bound")
+	}
+
+	defer func() {
+		if err != nil {
+			f.AddErrors("", forms.ErrUnexpected)
+		}
+	}()
+
+	s = &newsletters.Sender{
+		UserID: &userID,
+		Sender: newsletters.NormalizeSender(f.Get("sender").String()),
+		Labels: f.labels(),
+	}
+	err = newsletters.Senders.Create(s)
+	return
+}
+
+// updateSender performs the sender update.
+func (f *newsletterSenderForm) updateSender(s *newsletters.Sender) (err error) {
+	if !f.IsBound() {
+		return errors.New("form is not bound")
+	}
+
+	defer func() {
+		if err != nil {
+			f.AddErrors("", forms.ErrUnexpected)
+		}
+	}()
+
+	s.Sender = newsletters.NormalizeSender(f.Get("sender").String())
+	s.Labels = f.labels()
+	return s.Save()
+}
@@ -5,16 +5,28 @@
 package profile
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 
+	"github.com/doug-martin/goqu/v9"
 	"github.com/go-chi/chi/v5"
 
 	"codeberg.org/readeck/readeck/configs"
 	"codeberg.org/readeck/readeck/internal/auth"
 	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	"codeberg.org/readeck/readeck/internal/newsletters"
 	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/internal/sitelogins"
+	"codeberg.org/readeck/readeck/internal/webhooks"
 	"codeberg.org/readeck/readeck/pkg/forms"
+	"codeberg.org/readeck/readeck/pkg/totp"
+)
+
+type (
+	ctxSiteLoginListKey struct{}
+	ctxSiteLoginKey     struct{}
+	ctxSenderKey        struct{}
 )
 
 // profileViews is an HTTP handler for the user profile web views.
@@ -31,11 +43,15 @@ func newProfileViews(api *profileAPI) *profileViews {
 	r.With(api.srv.WithPermission("profile", "read")).Group(func(r chi.Router) {
 		r.Get("/", v.userProfile)
 		r.Get("/password", v.userPassword)
+		r.Get("/totp", v.userTOTP)
 	})
 
 	r.With(api.srv.WithPermission("profile", "write")).Group(func(r chi.Router) {
 		r.Post("/", v.userProfile)
 		r.Post("/password", v.userPassword)
+		r.Post("/totp", v.userTOTP)
+		r.Post("/totp/disable", v.userTOTPDisable)
+		r.Post("/totp/recovery", v.userTOTPRecovery)
 		r.Post("/session", v.userSession)
 	})
 
@@ -50,6 +66,40 @@ func newProfileViews(api *profileAPI) *profileViews {
 		r.With(api.withToken).Post("/tokens/{uid}/delete", v.tokenDelete)
 	})
 
+	r.With(api.srv.WithPermission("profile:webhooks", "read")).Group(func(r chi.Router) {
+		r.With(api.withWebhookList).Get("/webhooks", v.webhookList)
+		r.With(api.withWebhook, api.withDeliveryList).Get("/webhooks/{uid}", v.webhookInfo)
+	})
+
+	r.With(api.srv.WithPermission("profile:webhooks", "write")).Group(func(r chi.Router) {
+		r.With(api.withWebhookList).Post("/webhooks", v.webhookList)
+		r.With(api.withWebhook, api.withDeliveryList).Post("/webhooks/{uid}", v.webhookInfo)
+		r.With(api.withWebhook).Post("/webhooks/{uid}/delete", v.webhookDelete)
+	})
+
+	r.With(api.srv.WithPermission("profile:logins", "read")).Group(func(r chi.Router) {
+		r.With(v.withSiteLoginList).Get("/logins", v.siteLoginList)
+		r.With(v.withSiteLogin).Get("/logins/{uid}", v.siteLoginInfo)
+	})
+
+	r.With(api.srv.WithPermission("profile:logins", "write")).Group(func(r chi.Router) {
+		r.With(v.withSiteLoginList).Post("/logins", v.siteLoginList)
+		r.With(v.withSiteLogin).Post("/logins/{uid}", v.siteLoginInfo)
+		r.With(v.withSiteLogin).Post("/logins/{uid}/delete", v.siteLoginDelete)
+	})
+
+	r.With(api.srv.WithPermission("profile:newsletters", "read")).Group(func(r chi.Router) {
+		r.Get("/newsletters", v.newsletterInfo)
+		r.With(v.withSender).Get("/newsletters/{uid}", v.newsletterSender)
+	})
+
+	r.With(api.srv.WithPermission("profile:newsletters", "write")).Group(func(r chi.Router) {
+		r.Post("/newsletters", v.newsletterInfo)
+		r.Post("/newsletters/address", v.newsletterAddress)
+		r.With(v.withSender).Post("/newsletters/{uid}", v.newsletterSender)
+		r.With(v.withSender).Post("/newsletters/{uid}/delete", v.newsletterSenderDelete)
+	})
+
 	return v
 }
 
@@ -124,6 +174,134 @@ func (v *profileViews) userPassword(w http.ResponseWriter, r *http.Request) {
 	v.srv.RenderTemplate(w, r, 200, "profile/password", ctx)
 }
 
+// userTOTP handles GET and POST requests on /profile/totp.
+// When two-factor authentication is not enabled, it shows the
+// form to enable it with a new secret.
+func (v *profileViews) userTOTP(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	user := auth.GetRequestUser(r)
+
+	if user.HasTOTP() {
+		if r.Method == http.MethodPost {
+			v.srv.Redirect(w, r, "totp")
+			return
+		}
+		v.renderTOTP(w, r, http.StatusOK, server.TC{
+			"ConfirmForm": newTOTPConfirmForm(tr, user),
+		})
+		return
+	}
+
+	f := newTOTPForm(tr)
+	f.Get("secret").Set(totp.NewSecret())
+	status := http.StatusOK
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if codes, err := f.enableTOTP(user); err != nil {
+				v.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				// Set the new seed in the session.
+				sess := v.srv.GetSession(r)
+				sess.Payload.Seed = user.Seed
+				sess.Save(w, r)
+
+				// The recovery codes are only displayed once.
+				v.renderTOTP(w, r, http.StatusOK, server.TC{
+					"ConfirmForm":   newTOTPConfirmForm(tr, user),
+					"RecoveryCodes": codes,
+				})
+				return
+			}
+		}
+		status = http.StatusUnprocessableEntity
+	}
+
+	v.renderTOTP(w, r, status, server.TC{
+		"Form": f,
+		"URI":  totp.URI("Readeck", user.Username, f.Get("secret").String()),
+	})
+}
+
+// userTOTPDisable disables the user's two-factor authentication.
+func (v *profileViews) userTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	user := auth.GetRequestUser(r)
+	if !user.HasTOTP() {
+		v.srv.Redirect(w, r, "/profile/totp")
+		return
+	}
+
+	f := newTOTPConfirmForm(tr, user)
+	forms.Bind(f, r)
+	if f.IsValid() {
+		err := user.DisableTOTP()
+		if err == nil {
+			err = user.Update(map[string]interface{}{"seed": user.SetSeed()})
+		}
+		if err != nil {
+			v.srv.Error(w, r, err)
+			return
+		}
+
+		// Set the new seed in the session.
+		// We needn't save the session since AddFlash does it already.
+		sess := v.srv.GetSession(r)
+		sess.Payload.Seed = user.Seed
+		v.srv.AddFlash(w, r, "success", tr.Gettext("Two-factor authentication is now disabled."))
+		v.srv.Redirect(w, r, "/profile/totp")
+		return
+	}
+
+	v.renderTOTP(w, r, http.StatusUnprocessableEntity, server.TC{
+		"ConfirmForm": f,
+	})
+}
+
+// userTOTPRecovery replaces the user's recovery codes.
+func (v *profileViews) userTOTPRecovery(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	user := auth.GetRequestUser(r)
+	if !user.HasTOTP() {
+		v.srv.Redirect(w, r, "/profile/totp")
+		return
+	}
+
+	f := newTOTPConfirmForm(tr, user)
+	forms.Bind(f, r)
+	if !f.IsValid() {
+		v.renderTOTP(w, r, http.StatusUnprocessableEntity, server.TC{
+			"ConfirmForm": f,
+		})
+		return
+	}
+
+	codes, err := user.ResetRecoveryCodes()
+	if err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	v.renderTOTP(w, r, http.StatusOK, server.TC{
+		"ConfirmForm":   newTOTPConfirmForm(tr, user),
+		"RecoveryCodes": codes,
+	})
+}
+
+func (v *profileViews) renderTOTP(w http.ResponseWriter, r *http.Request, status int, ctx server.TC) {
+	tr := v.srv.Locale(r)
+	user := auth.GetRequestUser(r)
+
+	ctx["Enabled"] = user.HasTOTP()
+	ctx["RecoveryCount"] = len(user.TOTPRecovery)
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Two-factor authentication")},
+	})
+	v.srv.RenderTemplate(w, r, status, "profile/totp", ctx)
+}
+
 // userSession handles changes of user session preferences.
 // This returns an API response but since it only works with a SessionAuthProvider
 // it makes more sense to have it in the views.
@@ -243,3 +421,380 @@ func (v *profileViews) tokenDelete(w http.ResponseWriter, r *http.Request) {
 	}
 	v.srv.Redirect(w, r, f.Get("_to").String())
 }
+
+func (v *profileViews) webhookList(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	f := newWebhookForm(tr)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if wh, err := f.createWebhook(auth.GetRequestUser(r).ID); err != nil {
+				v.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				v.srv.AddFlash(w, r, "success", tr.Gettext("Webhook created."))
+				v.srv.Redirect(w, r, ".", wh.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	wl := r.Context().Value(ctxWebhookListKey{}).(webhookList)
+
+	ctx := server.TC{
+		"Form":       f,
+		"Pagination": wl.Pagination,
+		"Webhooks":   wl.Items,
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Webhooks")},
+	})
+
+	v.srv.RenderTemplate(w, r, 200, "profile/webhook_list", ctx)
+}
+
+func (v *profileViews) webhookInfo(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	wh := r.Context().Value(ctxWebhookKey{}).(*webhooks.Webhook)
+
+	f := newWebhookForm(tr)
+	f.setWebhook(wh)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if _, err := f.updateWebhook(wh); err != nil {
+				v.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				v.srv.AddFlash(w, r, "success", tr.Gettext("Webhook was updated."))
+				v.srv.Redirect(w, r, wh.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	dl := r.Context().Value(ctxDeliveryListKey{}).(deliveryList)
+
+	ctx := server.TC{
+		"Webhook":    newWebhookItem(v.srv, r, wh, "./.."),
+		"Form":       f,
+		"Pagination": dl.Pagination,
+		"Deliveries": dl.Items,
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Webhooks"), v.srv.AbsoluteURL(r, "/profile/webhooks").String()},
+		{wh.UID},
+	})
+
+	v.srv.RenderTemplate(w, r, 200, "profile/webhook", ctx)
+}
+
+func (v *profileViews) webhookDelete(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	wh := r.Context().Value(ctxWebhookKey{}).(*webhooks.Webhook)
+
+	if err := wh.Delete(); err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	v.srv.AddFlash(w, r, "success", tr.Gettext("Webhook removed."))
+	v.srv.Redirect(w, r, "/profile/webhooks")
+}
+
+// siteLoginItem is a site login with its decrypted credentials
+// summary. It never contains the password or cookie values.
+type siteLoginItem struct {
+	*sitelogins.SiteLogin
+	Username    string
+	HasPassword bool
+	CookieCount int
+	credentials *sitelogins.Credentials
+}
+
+func newSiteLoginItem(l *sitelogins.SiteLogin) (siteLoginItem, error) {
+	c, err := l.Credentials()
+	if err != nil {
+		return siteLoginItem{}, err
+	}
+
+	res := siteLoginItem{
+		SiteLogin:   l,
+		Username:    c.Username,
+		HasPassword: c.Password != "",
+		credentials: c,
+	}
+	if cookies, err := c.HTTPCookies(); err == nil {
+		res.CookieCount = len(cookies)
+	}
+	return res, nil
+}
+
+func (v *profileViews) withSiteLoginList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		items := []*sitelogins.SiteLogin{}
+		err := sitelogins.Logins.Query().
+			Where(goqu.C("user_id").Eq(auth.GetRequestUser(r).ID)).
+			Order(goqu.C("domain").Asc()).
+			ScanStructs(&items)
+		if err != nil {
+			v.srv.Error(w, r, err)
+			return
+		}
+
+		res := make([]siteLoginItem, 0, len(items))
+		for _, x := range items {
+			item, err := newSiteLoginItem(x)
+			if err != nil {
+				// An instance secret key change makes the credentials unreadable.
+				// The login is still listed so it can be deleted.
+				v.srv.Log(r).Warn("site login", slog.String("uid", x.UID), slog.Any("err", err))
+				item = siteLoginItem{SiteLogin: x}
+			}
+			res = append(res, item)
+		}
+
+		ctx := context.WithValue(r.Context(), ctxSiteLoginListKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (v *profileViews) withSiteLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l, err := sitelogins.Logins.GetOne(
+			goqu.C("uid").Eq(chi.URLParam(r, "uid")),
+			goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+		)
+		if err != nil {
+			v.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxSiteLoginKey{}, l)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (v *profileViews) siteLoginList(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	f := newSiteLoginForm(tr, auth.GetRequestUser(r))
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if l, err := f.createSiteLogin(auth.GetRequestUser(r).ID); err != nil {
+				v.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				v.srv.AddFlash(w, r, "success", tr.Gettext("Login saved."))
+				v.srv.Redirect(w, r, ".", l.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	ctx := server.TC{
+		"Form":   f,
+		"Logins": r.Context().Value(ctxSiteLoginListKey{}).([]siteLoginItem),
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Website Logins")},
+	})
+
+	v.srv.RenderTemplate(w, r, 200, "profile/login_list", ctx)
+}
+
+func (v *profileViews) siteLoginInfo(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	l := r.Context().Value(ctxSiteLoginKey{}).(*sitelogins.SiteLogin)
+
+	item, err := newSiteLoginItem(l)
+	if err != nil {
+		v.srv.Log(r).Warn("site login", slog.String("uid", l.UID), slog.Any("err", err))
+		item = siteLoginItem{SiteLogin: l, credentials: &sitelogins.Credentials{}}
+	}
+
+	f := newSiteLoginForm(tr, auth.GetRequestUser(r))
+	f.setSiteLogin(l, item.credentials)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if err := f.updateSiteLogin(l); err != nil {
+				v.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				v.srv.AddFlash(w, r, "success", tr.Gettext("Login was updated."))
+				v.srv.Redirect(w, r, l.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	ctx := server.TC{
+		"Login": item,
+		"Form":  f,
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Website Logins"), v.srv.AbsoluteURL(r, "/profile/logins").String()},
+		{l.Domain},
+	})
+
+	v.srv.RenderTemplate(w, r, 200, "profile/login", ctx)
+}
+
+func (v *profileViews) siteLoginDelete(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	l := r.Context().Value(ctxSiteLoginKey{}).(*sitelogins.SiteLogin)
+
+	if err := l.Delete(); err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	v.srv.AddFlash(w, r, "success", tr.Gettext("Login removed."))
+	v.srv.Redirect(w, r, "/profile/logins")
+}
+
+func (v *profileViews) withSender(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := newsletters.Senders.GetOne(
+			goqu.C("uid").Eq(chi.URLParam(r, "uid")),
+			goqu.C("user_id").Eq(auth.GetRequestUser(r).ID),
+		)
+		if err != nil {
+			v.srv.Status(w, r, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxSenderKey{}, s)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// newsletterInfo shows the user's inbound address and the sender list.
+// It creates a new sender on POST.
+func (v *profileViews) newsletterInfo(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	user := auth.GetRequestUser(r)
+	f := newNewsletterSenderForm(tr, user)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if _, err := f.createSender(user.ID); err != nil {
+				v.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				v.srv.AddFlash(w, r, "success", tr.Gettext("Sender labels saved."))
+				v.srv.Redirect(w, r, "/profile/newsletters")
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	var address *newsletters.Address
+	if configs.Config.Email.Inbound.Enabled() {
+		var err error
+		if address, err = newsletters.Addresses.ForUser(user.ID); err != nil {
+			v.srv.Error(w, r, err)
+			return
+		}
+	}
+
+	senders := []*newsletters.Sender{}
+	err := newsletters.Senders.Query().
+		Where(goqu.C("user_id").Eq(user.ID)).
+		Order(goqu.C("sender").Asc()).
+		ScanStructs(&senders)
+	if err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	ctx := server.TC{
+		"Form":    f,
+		"Address": address,
+		"Senders": senders,
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Newsletters")},
+	})
+
+	v.srv.RenderTemplate(w, r, 200, "profile/newsletters", ctx)
+}
+
+// newsletterAddress replaces the user's inbound address with a new one.
+func (v *profileViews) newsletterAddress(w http.ResponseWriter, r *http.Request) {
+	if !configs.Config.Email.Inbound.Enabled() {
+		v.srv.Status(w, r, http.StatusNotFound)
+		return
+	}
+
+	tr := v.srv.Locale(r)
+	a, err := newsletters.Addresses.ForUser(auth.GetRequestUser(r).ID)
+	if err == nil {
+		err = a.Regenerate()
+	}
+	if err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	v.srv.AddFlash(w, r, "success", tr.Gettext("Your newsletter address has changed."))
+	v.srv.Redirect(w, r, "/profile/newsletters")
+}
+
+func (v *profileViews) newsletterSender(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	s := r.Context().Value(ctxSenderKey{}).(*newsletters.Sender)
+
+	f := newNewsletterSenderForm(tr, auth.GetRequestUser(r))
+	f.setSender(s)
+
+	if r.Method == http.MethodPost {
+		forms.Bind(f, r)
+		if f.IsValid() {
+			if err := f.updateSender(s); err != nil {
+				v.srv.Log(r).Error("", slog.Any("err", err))
+			} else {
+				v.srv.AddFlash(w, r, "success", tr.Gettext("Sender labels were updated."))
+				v.srv.Redirect(w, r, s.UID)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	ctx := server.TC{
+		"Sender": s,
+		"Form":   f,
+	}
+	ctx.SetBreadcrumbs([][2]string{
+		{tr.Gettext("Profile"), v.srv.AbsoluteURL(r, "/profile").String()},
+		{tr.Gettext("Newsletters"), v.srv.AbsoluteURL(r, "/profile/newsletters").String()},
+		{s.Sender},
+	})
+
+	v.srv.RenderTemplate(w, r, 200, "profile/newsletter_sender", ctx)
+}
+
+func (v *profileViews) newsletterSenderDelete(w http.ResponseWriter, r *http.Request) {
+	tr := v.srv.Locale(r)
+	s := r.Context().Value(ctxSenderKey{}).(*newsletters.Sender)
+
+	if err := s.Delete(); err != nil {
+		v.srv.Error(w, r, err)
+		return
+	}
+
+	v.srv.AddFlash(w, r, "success", tr.Gettext("Sender labels removed."))
+	v.srv.Redirect(w, r, "/profile/newsletters")
+}
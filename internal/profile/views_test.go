@@ -10,11 +10,18 @@ import (
 	"net/url"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
+	"github.com/go-shiori/dom"
 	"github.com/stretchr/testify/require"
 
 	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/internal/newsletters"
+	"codeberg.org/readeck/readeck/internal/sitelogins"
+	"codeberg.org/readeck/readeck/pkg/totp"
+
 	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
 )
 
@@ -208,4 +215,293 @@ func TestViews(t *testing.T) {
 			},
 		)
 	})
+
+	t.Run("webhooks", func(t *testing.T) {
+		RunRequestSequence(t, client, "user",
+			RequestTest{Target: "/profile/webhooks", ExpectStatus: 200},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/profile/webhooks",
+				Form:         url.Values{"url": {"not a url"}},
+				ExpectStatus: 422,
+			},
+			RequestTest{Target: "/profile/webhooks"},
+			RequestTest{
+				Method: "POST",
+				Target: "/profile/webhooks",
+				Form: url.Values{
+					"url":    {"https://example.org/hook"},
+					"events": {"bookmark.created"},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/webhooks/.+",
+			},
+			RequestTest{
+				Target:         "{{ (index .History 0).Redirect }}",
+				ExpectStatus:   200,
+				ExpectContains: "Webhook created",
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "{{ (index .History 0).Path }}",
+				Form: url.Values{
+					"url":        {"https://example.net/hook"},
+					"is_enabled": {"f"},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/webhooks/.+",
+			},
+			RequestTest{
+				Target:         "{{ (index .History 0).Redirect }}",
+				ExpectStatus:   200,
+				ExpectContains: "https://example.net/hook",
+			},
+			RequestTest{
+				Method:         "POST",
+				Target:         "{{ (index .History 0).Path }}/delete",
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/webhooks",
+			},
+			RequestTest{
+				Target:       "{{ (index .History 1).Path }}",
+				ExpectStatus: 404,
+			},
+		)
+	})
+	t.Run("site logins", func(t *testing.T) {
+		RunRequestSequence(t, client, "user",
+			RequestTest{Target: "/profile/logins", ExpectStatus: 200},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/profile/logins",
+				Form:         url.Values{"domain": {"not a domain"}},
+				ExpectStatus: 422,
+			},
+			RequestTest{Target: "/profile/logins"},
+			RequestTest{
+				Method: "POST",
+				Target: "/profile/logins",
+				Form: url.Values{
+					"domain":   {"https://www.example.net/"},
+					"username": {"alice"},
+					"password": {"s3cr3t"},
+					"cookies":  {"session=abc"},
+				},
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/logins/.+",
+			},
+			RequestTest{
+				Target:       "{{ (index .History 0).Redirect }}",
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					require.Contains(t, string(r.Body), "Login saved")
+					require.Contains(t, string(r.Body), `value="example.net"`)
+					require.Contains(t, string(r.Body), `value="alice"`)
+					require.NotContains(t, string(r.Body), "s3cr3t")
+					require.NotContains(t, string(r.Body), "session=abc")
+				},
+			},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/profile/logins",
+				Form:         url.Values{"domain": {"example.net"}},
+				ExpectStatus: 422,
+				Assert: func(t *testing.T, r *Response) {
+					require.Contains(t, string(r.Body), "you already have a login for this domain")
+				},
+			},
+			RequestTest{Target: "{{ (index .History 1).Path }}"},
+			RequestTest{
+				Method:         "POST",
+				Target:         "{{ (index .History 0).Path }}",
+				Form:           url.Values{"username": {"bob"}, "password": {""}},
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/logins/.+",
+			},
+			RequestTest{
+				Target:       "/profile/logins",
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					require.Contains(t, string(r.Body), "Username: bob")
+					require.Contains(t, string(r.Body), "1 cookie")
+				},
+			},
+		)
+
+		l, err := sitelogins.Logins.FindForHost(app.Users["user"].User.ID, "www.example.net")
+		require.NoError(t, err)
+		c, err := l.Credentials()
+		require.NoError(t, err)
+		require.Equal(t, &sitelogins.Credentials{Username: "bob", Password: "s3cr3t", Cookies: "session=abc"}, c)
+
+		// Another user can't see or change the login
+		RunRequestSequence(t, client, "staff",
+			RequestTest{Target: "/profile/logins/" + l.UID, ExpectStatus: 404},
+			RequestTest{Target: "/profile/logins"},
+			RequestTest{Method: "POST", Target: "/profile/logins/" + l.UID + "/delete", ExpectStatus: 404},
+		)
+
+		RunRequestSequence(t, client, "user",
+			RequestTest{Target: "/profile/logins/" + l.UID},
+			RequestTest{
+				Method:         "POST",
+				Target:         "/profile/logins/" + l.UID + "/delete",
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/logins",
+			},
+			RequestTest{Target: "/profile/logins/" + l.UID, ExpectStatus: 404},
+		)
+	})
+	t.Run("newsletters", func(t *testing.T) {
+		RunRequestSequence(t, client, "user",
+			RequestTest{
+				Target:       "/profile/newsletters",
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					require.Contains(t, string(r.Body), "The inbound email server is not enabled")
+				},
+			},
+			RequestTest{Method: "POST", Target: "/profile/newsletters/address", ExpectStatus: 404},
+			RequestTest{Target: "/profile/newsletters"},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/profile/newsletters",
+				Form:         url.Values{"sender": {"not a sender"}, "labels": {"news"}},
+				ExpectStatus: 422,
+			},
+			RequestTest{Target: "/profile/newsletters"},
+			RequestTest{
+				Method:         "POST",
+				Target:         "/profile/newsletters",
+				Form:           url.Values{"sender": {"News@Example.net"}, "labels": {"news, weekly"}},
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/newsletters",
+			},
+			RequestTest{
+				Target:       "/profile/newsletters",
+				ExpectStatus: 200,
+				Assert: func(t *testing.T, r *Response) {
+					require.Contains(t, string(r.Body), "news@example.net")
+					require.Contains(t, string(r.Body), "news, weekly")
+				},
+			},
+			RequestTest{
+				Method:       "POST",
+				Target:       "/profile/newsletters",
+				Form:         url.Values{"sender": {"news@example.net"}, "labels": {"other"}},
+				ExpectStatus: 422,
+			},
+		)
+
+		s, err := newsletters.Senders.GetOne(
+			goqu.C("user_id").Eq(app.Users["user"].User.ID),
+			goqu.C("sender").Eq("news@example.net"),
+		)
+		require.NoError(t, err)
+
+		RunRequestSequence(t, client, "staff",
+			RequestTest{Target: "/profile/newsletters/" + s.UID, ExpectStatus: 404},
+		)
+
+		RunRequestSequence(t, client, "user",
+			RequestTest{Target: "/profile/newsletters/" + s.UID, ExpectStatus: 200},
+			RequestTest{
+				Method:         "POST",
+				Target:         "/profile/newsletters/" + s.UID,
+				Form:           url.Values{"sender": {"example.net"}, "labels": {"letters"}},
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/newsletters/" + s.UID,
+			},
+			RequestTest{Target: "/profile/newsletters/" + s.UID},
+			RequestTest{
+				Method:         "POST",
+				Target:         "/profile/newsletters/" + s.UID + "/delete",
+				ExpectStatus:   303,
+				ExpectRedirect: "/profile/newsletters",
+			},
+			RequestTest{Target: "/profile/newsletters/" + s.UID, ExpectStatus: 404},
+		)
+	})
+
+}
+
+func TestTOTPViews(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+	user := app.Users["staff"]
+	user.Login(client)
+
+	getUser := func() *users.User {
+		u, err := users.Users.GetOne(goqu.C("id").Eq(user.User.ID))
+		require.NoError(t, err)
+		return u
+	}
+	recoveryCodes := func(r *Response) []string {
+		res := []string{}
+		for _, n := range dom.QuerySelectorAll(r.HTML, "ul.font-mono li") {
+			res = append(res, dom.TextContent(n))
+		}
+		return res
+	}
+
+	rsp := client.Get("/profile/totp")
+	rsp.AssertStatus(t, 200)
+	secret := dom.GetAttribute(dom.QuerySelector(rsp.HTML, `input[name="secret"]`), "value")
+	require.Len(t, secret, 32)
+
+	// Invalid code
+	rsp = client.PostForm("/profile/totp", url.Values{"secret": {secret}, "code": {"abc"}})
+	rsp.AssertStatus(t, 422)
+	require.False(t, getUser().HasTOTP())
+
+	// Enable
+	client.Get("/profile/totp")
+	code, err := totp.Code(secret, time.Now())
+	require.NoError(t, err)
+	rsp = client.PostForm("/profile/totp", url.Values{"secret": {secret}, "code": {code}})
+	rsp.AssertStatus(t, 200)
+	require.Contains(t, string(rsp.Body), "Your recovery codes")
+	codes := recoveryCodes(rsp)
+	require.Len(t, codes, 10)
+
+	u := getUser()
+	require.Equal(t, secret, u.TOTPSecret)
+	require.NotEqual(t, user.User.Seed, u.Seed)
+
+	// The session follows the new seed
+	rsp = client.Get("/profile/totp")
+	rsp.AssertStatus(t, 200)
+	require.Contains(t, string(rsp.Body), "You have 10 recovery codes left.")
+
+	// New recovery codes
+	rsp = client.PostForm("/profile/totp/recovery", url.Values{"current": {"nope"}})
+	rsp.AssertStatus(t, 422)
+	client.Get("/profile/totp")
+	rsp = client.PostForm("/profile/totp/recovery", url.Values{"current": {user.Password()}})
+	rsp.AssertStatus(t, 200)
+	newCodes := recoveryCodes(rsp)
+	require.Len(t, newCodes, 10)
+	require.NotEqual(t, codes, newCodes)
+
+	u = getUser()
+	require.False(t, u.CheckTOTP(codes[0]))
+	require.True(t, u.CheckTOTP(newCodes[0]))
+	require.Len(t, getUser().TOTPRecovery, 9)
+
+	// Disable
+	client.Get("/profile/totp")
+	rsp = client.PostForm("/profile/totp/disable", url.Values{"current": {"nope"}})
+	rsp.AssertStatus(t, 422)
+	client.Get("/profile/totp")
+	rsp = client.PostForm("/profile/totp/disable", url.Values{"current": {user.Password()}})
+	rsp.AssertStatus(t, 303)
+	rsp.AssertRedirect(t, "/profile/totp")
+	require.False(t, getUser().HasTOTP())
+
+	client.Get("/profile/totp").AssertStatus(t, 200)
+
 }
@@ -346,6 +346,27 @@ func (q SearchQuery) Unfield(names ...string) SearchQuery {
 	return res
 }
 
+// Words returns every word of the query's terms as a search term,
+// leaving out the excluded terms. When a term has a wildcard, only
+// its last word keeps it.
+func (q SearchQuery) Words() []SearchTerm {
+	res := []SearchTerm{}
+	for _, t := range q.Terms {
+		if t.Exclude {
+			continue
+		}
+		words := strings.Fields(valueCleanup.Replace(t.Value))
+		for i, w := range words {
+			res = append(res, SearchTerm{
+				Field:    t.Field,
+				Value:    w,
+				Wildcard: t.Wildcard && i == len(words)-1,
+			})
+		}
+	}
+	return res
+}
+
 // ParseQuery returns a new SearchQuery after parsing
 // the input string.
 func ParseQuery(s string) SearchQuery {
@@ -468,3 +468,37 @@ func TestQueryToString(t *testing.T) {
 		})
 	}
 }
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []SearchTerm
+	}{
+		{"", []SearchTerm{}},
+		{"simple test", []SearchTerm{
+			{Value: "simple"},
+			{Value: "test"},
+		}},
+		{`"long string" -excluded title:"a title"*`, []SearchTerm{
+			{Value: "long"},
+			{Value: "string"},
+			{Field: "title", Value: "a"},
+			{Field: "title", Value: "title", Wildcard: true},
+		}},
+		{`"some, punctuation!" 🦊`, []SearchTerm{
+			{Value: "some"},
+			{Value: "punctuation"},
+			{Value: "🦊"},
+		}},
+		{"AGENT @home", []SearchTerm{
+			{Value: "AGENT"},
+			{Value: "home"},
+		}},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i+1), func(t *testing.T) {
+			require.Equal(t, test.expected, ParseQuery(test.query).Words())
+		})
+	}
+}
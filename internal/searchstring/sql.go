@@ -14,11 +14,21 @@ import (
 
 const sqliteCatchAll = "catchall:oooooo"
 
+// SnippetStart and SnippetEnd surround the matching terms
+// in a snippet returned by [SnippetSQL].
+const (
+	SnippetStart = "\x02"
+	SnippetEnd   = "\x03"
+)
+
+// snippetTokens is the approximate number of words in a snippet.
+const snippetTokens = 24
+
 var valueCleanup = func() *strings.Replacer {
 	excluded := [][2]int{
 		{0x00, 0x1f},
 		{0x21, 0x2f},
-		{0x3a, 0x41},
+		{0x3a, 0x40},
 		{0x5b, 0x60},
 		{0x7b, 0xbf},
 
@@ -56,6 +66,7 @@ type BuilderConfig struct {
 	relation      [2]exp.IdentifierExpression
 	fieldList     [][2]string
 	allowedFields map[string]string
+	snippet       string
 }
 
 // NewBuilderConfig returns a new BuilderConfig.
@@ -73,6 +84,14 @@ func NewBuilderConfig(left, right exp.IdentifierExpression, fields [][2]string)
 	return res
 }
 
+// WithSnippet sets the source of the text snippets. On SQLite, it's
+// the index of the FTS table column. On PostgreSQL, it's the text column
+// on which the headline is computed.
+func (c *BuilderConfig) WithSnippet(source string) *BuilderConfig {
+	c.snippet = source
+	return c
+}
+
 // BuildSQL returns a new dataset with the search query.
 func BuildSQL(ds *goqu.SelectDataset, q SearchQuery, conf *BuilderConfig) *goqu.SelectDataset {
 	switch ds.Dialect().Dialect() {
@@ -85,6 +104,51 @@ func BuildSQL(ds *goqu.SelectDataset, q SearchQuery, conf *BuilderConfig) *goqu.
 	panic("dialect not implemented")
 }
 
+// SnippetSQL returns an expression that selects a text excerpt around
+// the matching terms, delimited by [SnippetStart] and [SnippetEnd].
+// The expression must be used in a dataset built with [BuildSQL] and the
+// same query. It returns nil when the configuration has no snippet
+// source or when the query has no term to highlight.
+func SnippetSQL(dialect string, q SearchQuery, cfg *BuilderConfig) exp.Expression {
+	if cfg.snippet == "" {
+		return nil
+	}
+
+	switch dialect {
+	case "postgres":
+		values := []string{}
+		groups := regroupFields(q.Terms, cfg)
+		for _, x := range cfg.fieldList {
+			for _, t := range groups[x[0]] {
+				if !t.Exclude {
+					values = append(values, "("+postgresTermValue(t)+")")
+				}
+			}
+		}
+		if len(values) == 0 {
+			return nil
+		}
+
+		return goqu.L(
+			"ts_headline('ts', ?, to_tsquery('ts', ?), ?)",
+			goqu.L(cfg.snippet),
+			goqu.V(strings.Join(values, " | ")),
+			goqu.V(fmt.Sprintf(
+				"StartSel=%s, StopSel=%s, MinWords=%d, MaxWords=%d, MaxFragments=2, FragmentDelimiter=\" … \"",
+				SnippetStart, SnippetEnd, snippetTokens/2, snippetTokens,
+			)),
+		)
+	case "sqlite3":
+		return goqu.L(
+			"snippet(?, "+cfg.snippet+", ?, ?, ?, ?)",
+			goqu.T(cfg.relation[1].GetTable()),
+			goqu.V(SnippetStart), goqu.V(SnippetEnd), goqu.V("…"), goqu.V(snippetTokens),
+		)
+	}
+
+	panic("dialect not implemented")
+}
+
 func buildSqlite(ds *goqu.SelectDataset, q SearchQuery, cfg *BuilderConfig) *goqu.SelectDataset {
 	// We need the first catchall query in order to use
 	// any operator (AND and NOT)later.
@@ -137,28 +201,12 @@ func builderPostgres(ds *goqu.SelectDataset, q SearchQuery, cfg *BuilderConfig)
 		values := []string{}
 
 		for _, t := range terms {
-			words := strings.Fields(t.Value)
 			neg := ""
 			if t.Exclude {
 				neg = "!"
 			}
 
-			var value string
-			if t.Exact {
-				value = fmt.Sprintf("'%s'", strings.Join(words, " "))
-				if t.Wildcard {
-					value += ":*"
-				}
-			} else {
-				if t.Wildcard {
-					for i := range words {
-						words[i] += ":*"
-					}
-				}
-				value = strings.Join(words, " & ")
-			}
-
-			values = append(values, fmt.Sprintf("%s(%s)", neg, value))
+			values = append(values, fmt.Sprintf("%s(%s)", neg, postgresTermValue(t)))
 		}
 
 		value := goqu.V(strings.Join(values, " & "))
@@ -177,6 +225,26 @@ func builderPostgres(ds *goqu.SelectDataset, q SearchQuery, cfg *BuilderConfig)
 		Order(order...)
 }
 
+// postgresTermValue returns a search term as a tsquery expression,
+// without its exclusion operator.
+func postgresTermValue(t SearchTerm) string {
+	words := strings.Fields(t.Value)
+	if t.Exact {
+		value := fmt.Sprintf("'%s'", strings.Join(words, " "))
+		if t.Wildcard {
+			value += ":*"
+		}
+		return value
+	}
+
+	if t.Wildcard {
+		for i := range words {
+			words[i] += ":*"
+		}
+	}
+	return strings.Join(words, " & ")
+}
+
 func regroupFields(terms []SearchTerm, cfg *BuilderConfig) map[string][]SearchTerm {
 	groups := map[string][]SearchTerm{}
 
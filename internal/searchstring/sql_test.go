@@ -308,3 +308,76 @@ func TestSQLBuilder(t *testing.T) {
 		}
 	}
 }
+
+func TestSnippetSQL(t *testing.T) {
+	configs := map[string]*BuilderConfig{
+		"postgres": NewBuilderConfig(
+			goqu.I("T.id"),
+			goqu.I("FTS.t_id"),
+			[][2]string{
+				{"", `FTS.title || FTS.label`},
+				{"title", "FTS.title"},
+			},
+		).WithSnippet("T.text"),
+		"sqlite3": NewBuilderConfig(
+			goqu.I("T.id"),
+			goqu.I("FTS.rowid"),
+			[][2]string{
+				{"", "-catchall"},
+				{"title", "title"},
+			},
+		).WithSnippet("3"),
+	}
+
+	tests := []struct {
+		terms    []SearchTerm
+		expected map[string]queryExpect
+	}{
+		{
+			[]SearchTerm{{Value: "C1", Wildcard: true}, {Value: "C2 C3", Exact: true}, {Field: "title", Value: "T1"}, {Value: "C4", Exclude: true}},
+			map[string]queryExpect{
+				"sqlite3": {
+					"SELECT snippet(`FTS`, 3, ?, ?, ?, ?) FROM `T`",
+					[]interface{}{SnippetStart, SnippetEnd, "…", int64(snippetTokens)},
+				},
+				"postgres": {
+					`SELECT ts_headline('ts', T.text, to_tsquery('ts', $1), $2) FROM "T"`,
+					[]interface{}{
+						"(C1:*) | ('C2 C3') | (T1)",
+						"StartSel=\x02, StopSel=\x03, MinWords=12, MaxWords=24, MaxFragments=2, FragmentDelimiter=\" … \"",
+					},
+				},
+			},
+		},
+		{
+			[]SearchTerm{{Value: "C4", Exclude: true}},
+			map[string]queryExpect{
+				"sqlite3": {
+					"SELECT snippet(`FTS`, 3, ?, ?, ?, ?) FROM `T`",
+					[]interface{}{SnippetStart, SnippetEnd, "…", int64(snippetTokens)},
+				},
+				"postgres": {},
+			},
+		},
+	}
+
+	for i, test := range tests {
+		for _, dialect := range []string{"sqlite3", "postgres"} {
+			t.Run(fmt.Sprintf("%d-%s", i+1, dialect), func(t *testing.T) {
+				q := SearchQuery{Terms: test.terms}
+				expr := SnippetSQL(dialect, q, configs[dialect])
+				if test.expected[dialect].sql == "" {
+					require.Nil(t, expr)
+					return
+				}
+
+				sql, args, err := goqu.Dialect(dialect).From("T").Select(expr).Prepared(true).ToSQL()
+				require.NoError(t, err)
+				require.Equal(t, test.expected[dialect].sql, sql)
+				require.Equal(t, test.expected[dialect].args, args)
+			})
+		}
+	}
+
+	require.Nil(t, SnippetSQL("sqlite3", SearchQuery{}, NewBuilderConfig(goqu.I("T.id"), goqu.I("FTS.rowid"), nil)))
+}
@@ -0,0 +1,304 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package sitelogins contains the models and functions to manage
+// the credentials a user provides for the websites they subscribe to.
+package sitelogins
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/net/idna"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+const (
+	// TableName is the site login table name in database.
+	TableName = "site_login"
+)
+
+var (
+	// Logins is the site login manager.
+	Logins = Manager{}
+
+	// ErrNotFound is returned when a site login record was not found.
+	ErrNotFound = errors.New("not found")
+)
+
+// SiteLogin is a site login record in database.
+// The credentials are never stored in clear, the Secret field holds
+// their encrypted version.
+type SiteLogin struct {
+	ID      int       `db:"id" goqu:"skipinsert,skipupdate"`
+	UID     string    `db:"uid"`
+	UserID  *int      `db:"user_id"`
+	Created time.Time `db:"created" goqu:"skipupdate"`
+	Updated time.Time `db:"updated"`
+	Domain  string    `db:"domain"`
+	Secret  string    `db:"secret"`
+}
+
+// Credentials contains the values used to access a website.
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Cookies  string `json:"cookies"`
+}
+
+// HTTPCookies returns the credentials' cookies as a list of [http.Cookie].
+// The cookies are in the "Cookie" header format, with each cookie
+// separated by a semicolon or a new line.
+func (c *Credentials) HTTPCookies() ([]*http.Cookie, error) {
+	v := strings.Join(strings.FieldsFunc(c.Cookies, func(r rune) bool {
+		return r == ';' || r == '\n' || r == '\r'
+	}), ";")
+	if strings.TrimSpace(v) == "" {
+		return []*http.Cookie{}, nil
+	}
+
+	return http.ParseCookie(v)
+}
+
+// Manager is a query helper for site login entries.
+type Manager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *Manager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(TableName).As("sl")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *Manager) GetOne(expressions ...goqu.Expression) (*SiteLogin, error) {
+	var l SiteLogin
+	found, err := m.Query().Where(expressions...).ScanStruct(&l)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrNotFound
+	}
+
+	return &l, nil
+}
+
+// FindForHost returns the user's site login matching a given hostname.
+// A login matches its domain and all its subdomains. When several
+// logins match, the most specific one wins.
+func (m *Manager) FindForHost(userID int, hostname string) (*SiteLogin, error) {
+	hostname = NormalizeDomain(hostname)
+	if hostname == "" {
+		return nil, ErrNotFound
+	}
+
+	candidates := []any{hostname}
+	for i := strings.IndexByte(hostname, '.'); i >= 0; i = strings.IndexByte(hostname, '.') {
+		hostname = hostname[i+1:]
+		candidates = append(candidates, hostname)
+	}
+
+	var items []*SiteLogin
+	err := m.Query().
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("domain").In(candidates...),
+		).
+		ScanStructs(&items)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *SiteLogin
+	for _, x := range items {
+		if res == nil || len(x.Domain) > len(res.Domain) {
+			res = x
+		}
+	}
+	if res == nil {
+		return nil, ErrNotFound
+	}
+	return res, nil
+}
+
+// Create inserts a new site login in the database.
+func (m *Manager) Create(l *SiteLogin) error {
+	if l.UserID == nil {
+		return errors.New("no site login user")
+	}
+	if l.Domain == "" {
+		return errors.New("no domain")
+	}
+
+	l.Created = time.Now()
+	l.Updated = l.Created
+	if l.UID == "" {
+		l.UID = base58.NewUUID()
+	}
+
+	ds := db.Q().Insert(TableName).
+		Rows(l).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	l.ID = id
+	return nil
+}
+
+// Update updates some site login values.
+func (l *SiteLogin) Update(v interface{}) error {
+	if l.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		v["updated"] = time.Now()
+	default:
+		//
+	}
+
+	_, err := db.Q().Update(TableName).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(l.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Save updates all the site login values.
+func (l *SiteLogin) Save() error {
+	l.Updated = time.Now()
+	return l.Update(l)
+}
+
+// Delete removes a site login from the database.
+func (l *SiteLogin) Delete() error {
+	_, err := db.Q().Delete(TableName).Prepared(true).
+		Where(goqu.C("id").Eq(l.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Credentials returns the decrypted credentials.
+func (l *SiteLogin) Credentials() (*Credentials, error) {
+	res := &Credentials{}
+	if l.Secret == "" {
+		return res, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(l.Secret)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = decrypt(data, l.additionalData()); err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SetCredentials encrypts the given credentials and sets the
+// result in the Secret field. It must be called once the UID
+// and UserID are set.
+func (l *SiteLogin) SetCredentials(c *Credentials) error {
+	if l.UserID == nil {
+		return errors.New("no site login user")
+	}
+	if l.UID == "" {
+		l.UID = base58.NewUUID()
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if data, err = encrypt(data, l.additionalData()); err != nil {
+		return err
+	}
+
+	l.Secret = base64.StdEncoding.EncodeToString(data)
+	return nil
+}
+
+// additionalData returns the data authenticated with the encrypted
+// credentials. It binds them to the login owner so a secret can't be
+// moved to another user's record.
+func (l *SiteLogin) additionalData() []byte {
+	userID := 0
+	if l.UserID != nil {
+		userID = *l.UserID
+	}
+	return []byte(strconv.Itoa(userID) + ":" + l.UID)
+}
+
+// NormalizeDomain returns a lower case, ASCII domain name without
+// any "www." prefix. It accepts a full URL as well.
+func NormalizeDomain(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if i := strings.Index(s, "://"); i >= 0 {
+		s = s[i+3:]
+	}
+	if i := strings.IndexAny(s, "/?#"); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.LastIndexByte(s, ':'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimPrefix(strings.Trim(s, "."), "www.")
+
+	res, err := idna.Lookup.ToASCII(s)
+	if err != nil {
+		return ""
+	}
+	return res
+}
+
+func encrypt(data, additionalData []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(configs.Keys.LoginsKey())
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, data, additionalData), nil
+}
+
+func decrypt(data, additionalData []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(configs.Keys.LoginsKey())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("encrypted data too short (%d)", len(data))
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package sitelogins_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/sitelogins"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"example.net", "example.net"},
+		{" WWW.Example.NET. ", "example.net"},
+		{"https://www.example.net:8443/path?q=1", "example.net"},
+		{"news.example.net", "news.example.net"},
+		{"pérotin.com", "xn--protin-bva.com"},
+		{"", ""},
+		{"not a domain", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			require.Equal(t, test.expected, sitelogins.NormalizeDomain(test.value))
+		})
+	}
+}
+
+func TestCredentialsCookies(t *testing.T) {
+	c := &sitelogins.Credentials{Cookies: "session=abc; pref=1\nother=x y"}
+	cookies, err := c.HTTPCookies()
+	require.NoError(t, err)
+	require.Len(t, cookies, 3)
+	require.Equal(t, "session", cookies[0].Name)
+	require.Equal(t, "abc", cookies[0].Value)
+	require.Equal(t, "other", cookies[2].Name)
+
+	c.Cookies = "no cookie"
+	_, err = c.HTTPCookies()
+	require.Error(t, err)
+}
+
+func TestSiteLogin(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	user := app.Users["user"].User
+	staff := app.Users["staff"].User
+
+	create := func(userID int, domain string, c *sitelogins.Credentials) *sitelogins.SiteLogin {
+		l := &sitelogins.SiteLogin{UserID: &userID, Domain: domain}
+		require.NoError(t, l.SetCredentials(c))
+		require.NoError(t, sitelogins.Logins.Create(l))
+		return l
+	}
+
+	l1 := create(user.ID, "example.net", &sitelogins.Credentials{Username: "alice", Password: "s3cr3t"})
+	l2 := create(user.ID, "news.example.net", &sitelogins.Credentials{Cookies: "session=abc"})
+	create(staff.ID, "example.org", &sitelogins.Credentials{Username: "bob"})
+
+	t.Run("encrypted", func(t *testing.T) {
+		l, err := sitelogins.Logins.GetOne()
+		require.NoError(t, err)
+		require.NotContains(t, l.Secret, "alice")
+		require.NotContains(t, l.Secret, "s3cr3t")
+
+		c, err := l.Credentials()
+		require.NoError(t, err)
+		require.Equal(t, &sitelogins.Credentials{Username: "alice", Password: "s3cr3t"}, c)
+	})
+
+	t.Run("bound to user", func(t *testing.T) {
+		l := *l1
+		l.UserID = &staff.ID
+		_, err := l.Credentials()
+		require.Error(t, err)
+	})
+
+	t.Run("find", func(t *testing.T) {
+		tests := []struct {
+			userID   int
+			host     string
+			expected string
+		}{
+			{user.ID, "example.net", l1.UID},
+			{user.ID, "www.example.net", l1.UID},
+			{user.ID, "blog.example.net", l1.UID},
+			{user.ID, "news.example.net", l2.UID},
+			{user.ID, "a.news.example.net", l2.UID},
+			{user.ID, "example.org", ""},
+			{user.ID, "badexample.net", ""},
+			{staff.ID, "example.net", ""},
+		}
+
+		for _, test := range tests {
+			t.Run(test.host, func(t *testing.T) {
+				l, err := sitelogins.Logins.FindForHost(test.userID, test.host)
+				if test.expected == "" {
+					require.ErrorIs(t, err, sitelogins.ErrNotFound)
+					return
+				}
+				require.NoError(t, err)
+				require.Equal(t, test.expected, l.UID)
+			})
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		c, err := l2.Credentials()
+		require.NoError(t, err)
+		c.Cookies = strings.Repeat("x", 10) + "=1"
+		require.NoError(t, l2.SetCredentials(c))
+		require.NoError(t, l2.Save())
+
+		l, err := sitelogins.Logins.FindForHost(user.ID, "news.example.net")
+		require.NoError(t, err)
+		c, err = l.Credentials()
+		require.NoError(t, err)
+		require.Equal(t, "xxxxxxxxxx=1", c.Cookies)
+	})
+}
@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package wallabag
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	"codeberg.org/readeck/readeck/internal/auth/users"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+// tokenRoles are the roles given to the API tokens created for
+// wallabag clients.
+var tokenRoles = []string{"scoped_bookmarks_r", "scoped_bookmarks_w"}
+
+// grantError is an error that's sent to the client as an
+// "invalid_grant" OAuth2 error.
+type grantError string
+
+func (e grantError) Error() string {
+	return string(e)
+}
+
+const (
+	errInvalidLogin = grantError("Invalid username and password combination")
+	errInvalidToken = grantError("Invalid refresh token")
+	errTOTP         = grantError("Two-factor authentication is enabled for this user")
+)
+
+type tokenForm struct {
+	*forms.Form
+}
+
+func newTokenForm(tr forms.Translator) *tokenForm {
+	return &tokenForm{forms.Must(
+		forms.WithTranslator(context.Background(), tr),
+		forms.NewTextField("grant_type", forms.Trim, forms.Required, forms.ChoicesPairs([][2]string{
+			{"password", "password"}, {"refresh_token", "refresh_token"},
+		})),
+		forms.NewTextField("client_id", forms.Trim),
+		forms.NewTextField("client_secret"),
+		forms.NewTextField("username", forms.Trim),
+		forms.NewTextField("password"),
+		forms.NewTextField("refresh_token", forms.Trim),
+	)}
+}
+
+// application returns the name of the API token created for the client.
+func (f *tokenForm) application() string {
+	if v := f.Get("client_id").String(); v != "" {
+		return "wallabag - " + v
+	}
+	return "wallabag"
+}
+
+// passwordGrant checks the user's credentials and returns the client's API token.
+// An existing token for the same client is reused, so a client that logs in
+// again doesn't create a new token each time.
+func (f *tokenForm) passwordGrant() (*tokens.Token, error) {
+	username := f.Get("username").String()
+	col := goqu.C("username")
+	if strings.Contains(username, "@") {
+		col = goqu.C("email")
+	}
+
+	user, err := users.Users.GetOne(col.Eq(username))
+	if errors.Is(err, users.ErrNotFound) {
+		return nil, errInvalidLogin
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.CheckPassword(f.Get("password").String()) {
+		return nil, errInvalidLogin
+	}
+	if user.HasTOTP() {
+		return nil, errTOTP
+	}
+
+	t, err := tokens.Tokens.GetOne(
+		goqu.C("user_id").Eq(user.ID),
+		goqu.C("application").Eq(f.application()),
+		goqu.C("is_enabled").Eq(true),
+	)
+	if err == nil && !t.IsExpired() {
+		return t, nil
+	}
+	if err != nil && !errors.Is(err, tokens.ErrNotFound) {
+		return nil, err
+	}
+
+	t = &tokens.Token{
+		UserID:      &user.ID,
+		IsEnabled:   true,
+		Application: f.application(),
+		Roles:       tokenRoles,
+	}
+	if err = tokens.Tokens.Create(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// refreshGrant returns the API token given as a refresh token, as long
+// as it's still valid.
+func (f *tokenForm) refreshGrant() (*tokens.Token, error) {
+	uid, err := tokens.DecodeToken(f.Get("refresh_token").String())
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	res, err := tokens.Tokens.GetUser(uid)
+	if errors.Is(err, tokens.ErrNotFound) {
+		return nil, errInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.Token.IsExpired() {
+		return nil, errInvalidToken
+	}
+
+	return res.Token, nil
+}
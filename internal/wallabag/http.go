@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package wallabag provides a wallabag compatible API, so the existing
+// wallabag clients and e-readers integrations can work with Readeck.
+package wallabag
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"codeberg.org/readeck/readeck/internal/auth"
+	"codeberg.org/readeck/readeck/internal/auth/tokens"
+	bookmark_routes "codeberg.org/readeck/readeck/internal/bookmarks/routes"
+	"codeberg.org/readeck/readeck/internal/server"
+	"codeberg.org/readeck/readeck/pkg/forms"
+)
+
+const (
+	// version is the wallabag API version we're compatible with.
+	version = "2.6.10"
+
+	// tokenLifetime is the access token lifetime, in seconds, sent to
+	// the clients. Readeck tokens don't expire but clients expect
+	// to refresh them from time to time.
+	tokenLifetime = 3600
+
+	timeFormat = "2006-01-02T15:04:05-0700"
+)
+
+type wallabagRouter struct {
+	chi.Router
+	srv *server.Server
+}
+
+// SetupRoutes mounts the wallabag API routes on "/wallabag".
+// Clients must be configured with this path as their server URL.
+func SetupRoutes(s *server.Server) {
+	r := chi.NewRouter()
+	r.Use(withoutFormat)
+
+	h := &wallabagRouter{r, s}
+
+	r.Post("/oauth/v2/token", h.token)
+
+	r.Route("/api", func(r chi.Router) {
+		r.Get("/version", h.version)
+		r.Get("/info", h.info)
+
+		api := s.AuthenticatedRouter()
+		api.With(s.WithPermission("api:profile", "read")).Get("/user", h.user)
+		api.Group(bookmark_routes.NewWallabagRouteHandler(s))
+		r.Mount("/", api)
+	})
+
+	s.AddRoute("/wallabag", h)
+}
+
+// token is the OAuth2 token endpoint. It supports the "password" and
+// "refresh_token" grant types. The client ID and secret are not checked,
+// the client ID only names the API token that's created for the client.
+func (h *wallabagRouter) token(w http.ResponseWriter, r *http.Request) {
+	f := newTokenForm(h.srv.Locale(r))
+	forms.Bind(f, r)
+
+	if !f.IsValid() {
+		h.oauthError(w, r, "invalid_request", f.Errors().Error())
+		return
+	}
+
+	var t *tokens.Token
+	var err error
+	switch f.Get("grant_type").String() {
+	case "password":
+		t, err = f.passwordGrant()
+	case "refresh_token":
+		t, err = f.refreshGrant()
+	}
+	if err != nil {
+		var errGrant grantError
+		if errors.As(err, &errGrant) {
+			h.oauthError(w, r, "invalid_grant", errGrant.Error())
+			return
+		}
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	token, err := tokens.EncodeToken(t.UID)
+	if err != nil {
+		h.srv.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.srv.Render(w, r, http.StatusOK, tokenResponse{
+		AccessToken:  token,
+		ExpiresIn:    tokenLifetime,
+		TokenType:    "bearer",
+		RefreshToken: token,
+	})
+}
+
+func (h *wallabagRouter) version(w http.ResponseWriter, r *http.Request) {
+	h.srv.Render(w, r, http.StatusOK, version)
+}
+
+func (h *wallabagRouter) info(w http.ResponseWriter, r *http.Request) {
+	h.srv.Render(w, r, http.StatusOK, map[string]any{
+		"appname":              "wallabag",
+		"version":              version,
+		"allowed_registration": false,
+	})
+}
+
+func (h *wallabagRouter) user(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetRequestUser(r)
+	h.srv.Render(w, r, http.StatusOK, userResponse{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Name:      user.Username,
+		CreatedAt: user.Created.Format(timeFormat),
+		UpdatedAt: user.Updated.Format(timeFormat),
+	})
+}
+
+// oauthError sends an OAuth2 error response.
+func (h *wallabagRouter) oauthError(w http.ResponseWriter, r *http.Request, code, description string) {
+	h.srv.Render(w, r, http.StatusBadRequest, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+// withoutFormat removes the ".json" extension from the route path, so
+// every route matches with or without it, like in wallabag.
+func withoutFormat(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			p := rctx.RoutePath
+			if p == "" {
+				p = r.URL.Path
+			}
+			rctx.RoutePath = strings.TrimSuffix(p, ".json")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type tokenResponse struct {
+	AccessToken  string  `json:"access_token"`
+	ExpiresIn    int     `json:"expires_in"`
+	TokenType    string  `json:"token_type"`
+	Scope        *string `json:"scope"`
+	RefreshToken string  `json:"refresh_token"`
+}
+
+type userResponse struct {
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
@@ -0,0 +1,439 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package wallabag_test
+
+import (
+	"crypto/sha1" //nolint:gosec
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+)
+
+func TestWallabagInfo(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	RunRequestSequence(t, client, "",
+		RequestTest{
+			Target:       "/wallabag/api/version.json",
+			JSON:         true,
+			ExpectStatus: 200,
+			ExpectJSON:   `"2.6.10"`,
+		},
+		RequestTest{
+			Target:       "/wallabag/api/info",
+			JSON:         true,
+			ExpectStatus: 200,
+			ExpectJSON: `{
+				"appname": "wallabag",
+				"version": "2.6.10",
+				"allowed_registration": false
+			}`,
+		},
+		RequestTest{
+			Target:       "/wallabag/api/entries.json",
+			JSON:         true,
+			ExpectStatus: 401,
+		},
+	)
+}
+
+func TestWallabagToken(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	token := func(values url.Values) *Response {
+		client.Logout()
+		return client.Request(client.NewFormRequest("POST", "/wallabag/oauth/v2/token", values))
+	}
+
+	t.Run("invalid request", func(t *testing.T) {
+		rsp := token(url.Values{"grant_type": {"client_credentials"}})
+		rsp.AssertStatus(t, 400)
+		require.Equal(t, "invalid_request", rsp.JSON.(map[string]any)["error"])
+	})
+
+	t.Run("invalid password", func(t *testing.T) {
+		rsp := token(url.Values{
+			"grant_type": {"password"},
+			"client_id":  {"test"},
+			"username":   {"user"},
+			"password":   {"nope"},
+		})
+		rsp.AssertStatus(t, 400)
+		require.Equal(t, "invalid_grant", rsp.JSON.(map[string]any)["error"])
+	})
+
+	var accessToken string
+	t.Run("password", func(t *testing.T) {
+		values := url.Values{
+			"grant_type":    {"password"},
+			"client_id":     {"test"},
+			"client_secret": {"secret"},
+			"username":      {"user"},
+			"password":      {app.Users["user"].Password()},
+		}
+		rsp := token(values)
+		rsp.AssertStatus(t, 200)
+		rsp.AssertJSON(t, `{
+			"access_token": "<<PRESENCE>>",
+			"expires_in": 3600,
+			"token_type": "bearer",
+			"scope": null,
+			"refresh_token": "<<PRESENCE>>"
+		}`)
+		accessToken = rsp.JSON.(map[string]any)["access_token"].(string)
+
+		// Login again, the same token is used
+		rsp = token(values)
+		rsp.AssertStatus(t, 200)
+		require.Equal(t, accessToken, rsp.JSON.(map[string]any)["access_token"])
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		rsp := token(url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {accessToken},
+		})
+		rsp.AssertStatus(t, 200)
+		require.Equal(t, accessToken, rsp.JSON.(map[string]any)["access_token"])
+
+		rsp = token(url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {"abcdef"},
+		})
+		rsp.AssertStatus(t, 400)
+		require.Equal(t, "invalid_grant", rsp.JSON.(map[string]any)["error"])
+	})
+
+	t.Run("api", func(t *testing.T) {
+		req := client.NewJSONRequest("GET", "/wallabag/api/user.json", nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		rsp := client.Request(req)
+		rsp.AssertStatus(t, 200)
+		require.Equal(t, "user", rsp.JSON.(map[string]any)["username"])
+
+		req = client.NewJSONRequest("GET", "/wallabag/api/entries.json", nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		rsp = client.Request(req)
+		rsp.AssertStatus(t, 200)
+		require.EqualValues(t, len(app.Users["user"].Bookmarks), rsp.JSON.(map[string]any)["total"])
+	})
+}
+
+func TestWallabagEntries(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	b := app.Users["user"].Bookmarks[0]
+	b.Files = bookmarks.BookmarkFiles{"article": {Name: "index.html"}}
+	require.NoError(t, b.Save())
+	hashedURL := fmt.Sprintf("%x", sha1.Sum([]byte(b.URL)))
+
+	b2 := &bookmarks.Bookmark{
+		UserID: b.UserID,
+		URL:    "https://example.org/article",
+		Title:  "Article",
+		State:  bookmarks.StateLoaded,
+	}
+	require.NoError(t, bookmarks.Bookmarks.Create(b2))
+	app.Users["user"].Bookmarks = append(app.Users["user"].Bookmarks, b2)
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Target:       "/wallabag/api/entries.json?perPage=1",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				data := r.JSON.(map[string]any)
+				require.EqualValues(t, 1, data["page"])
+				require.EqualValues(t, 1, data["limit"])
+				require.EqualValues(t, 2, data["pages"])
+				require.Len(t, data["_embedded"].(map[string]any)["items"], 1)
+				require.Contains(t, data["_links"], "next")
+			},
+		},
+		RequestTest{
+			Target:       "/wallabag/api/entries.json?page=100",
+			JSON:         true,
+			ExpectStatus: 404,
+		},
+		RequestTest{
+			Target:       "/wallabag/api/entries/{{(index .User.Bookmarks 0).ID}}.json",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				data := r.JSON.(map[string]any)
+				require.EqualValues(t, b.ID, data["id"])
+				require.Equal(t, b.URL, data["url"])
+				require.Equal(t, "ea3d7fa798219559bec83799e0a3e6d4533553a0", data["hashed_url"])
+				require.EqualValues(t, 0, data["is_archived"])
+				require.EqualValues(t, 0, data["is_starred"])
+				require.Contains(t, data["content"], "<section")
+				require.Equal(t, []any{}, data["annotations"])
+			},
+		},
+		RequestTest{
+			Method: "PATCH",
+			Target: "/wallabag/api/entries/{{(index .User.Bookmarks 0).ID}}.json",
+			JSON: map[string]any{
+				"archive": 1,
+				"starred": "1",
+				"tags":    "wallabag, tag 2",
+			},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				data := r.JSON.(map[string]any)
+				require.EqualValues(t, 1, data["is_archived"])
+				require.EqualValues(t, 1, data["is_starred"])
+
+				labels := []string{}
+				for _, x := range data["tags"].([]any) {
+					labels = append(labels, x.(map[string]any)["label"].(string))
+				}
+				require.Equal(t, []string{"tag 2", "wallabag"}, labels)
+			},
+		},
+		RequestTest{
+			Target:       "/wallabag/api/entries.json?archive=1&starred=1",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.EqualValues(t, 1, r.JSON.(map[string]any)["total"])
+			},
+		},
+		RequestTest{
+			Target:       "/wallabag/api/entries.json?tags=wallabag",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.EqualValues(t, 1, r.JSON.(map[string]any)["total"])
+			},
+		},
+		RequestTest{
+			Target:       "/wallabag/api/entries/exists.json?return_id=1&url={{ (index .User.Bookmarks 0).URL | urlquery }}",
+			JSON:         true,
+			ExpectStatus: 200,
+			ExpectJSON:   `{"exists": {{(index .User.Bookmarks 0).ID}}}`,
+		},
+		RequestTest{
+			Target:       "/wallabag/api/entries/exists.json?url=https%3A%2F%2Fexample.org%2Fnope",
+			JSON:         true,
+			ExpectStatus: 200,
+			ExpectJSON:   `{"exists": false}`,
+		},
+		RequestTest{
+			Target:       "/wallabag/api/entries/exists.json?urls[]={{ (index .User.Bookmarks 0).URL | urlquery }}&urls[]=https%3A%2F%2Fexample.org%2Fnope",
+			JSON:         true,
+			ExpectStatus: 200,
+			ExpectJSON: `{
+				"{{ (index .User.Bookmarks 0).URL }}": true,
+				"https://example.org/nope": false
+			}`,
+		},
+		RequestTest{
+			Target:       "/wallabag/api/entries/exists.json?return_id=1&hashed_url=" + hashedURL,
+			JSON:         true,
+			ExpectStatus: 200,
+			ExpectJSON:   `{"exists": {{(index .User.Bookmarks 0).ID}}}`,
+		},
+		RequestTest{
+			Target:       "/wallabag/api/entries/exists.json?hashed_urls[]=" + hashedURL + "&hashed_urls[]=0000",
+			JSON:         true,
+			ExpectStatus: 200,
+			ExpectJSON:   `{"` + hashedURL + `": true, "0000": false}`,
+		},
+		RequestTest{
+			Target:       "/wallabag/api/tags.json",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				labels := []string{}
+				for _, x := range r.JSON.([]any) {
+					labels = append(labels, x.(map[string]any)["label"].(string))
+				}
+				require.Contains(t, labels, "wallabag")
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			Target:       "/wallabag/api/tag/label.json?tag=wallabag",
+			JSON:         true,
+			ExpectStatus: 200,
+		},
+		RequestTest{
+			Target:       "/wallabag/api/entries.json?tags=wallabag",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.EqualValues(t, 0, r.JSON.(map[string]any)["total"])
+
+				// The label can be restored from the trash
+				var l bookmarks.TrashedLabel
+				found, err := bookmarks.Trash.Labels().ScanStruct(&l)
+				require.NoError(t, err)
+				require.True(t, found)
+				require.Equal(t, "wallabag", l.Name)
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			Target:       "/wallabag/api/entries/{{(index .User.Bookmarks 1).ID}}.json?expect=id",
+			JSON:         true,
+			ExpectStatus: 200,
+			ExpectJSON:   `{"id": {{(index .User.Bookmarks 1).ID}}}`,
+		},
+		RequestTest{
+			Target:       "/wallabag/api/entries/{{(index .User.Bookmarks 1).ID}}.json",
+			JSON:         true,
+			ExpectStatus: 404,
+		},
+	)
+
+	// Entries from other users are not visible
+	RunRequestSequence(t, client, "staff",
+		RequestTest{
+			Target:       fmt.Sprintf("/wallabag/api/entries/%d.json", b.ID),
+			JSON:         true,
+			ExpectStatus: 404,
+		},
+	)
+}
+
+func TestWallabagAnnotations(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	b := app.Users["user"].Bookmarks[0]
+	b.Files = bookmarks.BookmarkFiles{"article": {Name: "index.html"}}
+	require.NoError(t, b.Save())
+
+	RunRequestSequence(t, client, "user",
+		RequestTest{
+			Method: "POST",
+			Target: "/wallabag/api/annotations/{{(index .User.Bookmarks 0).ID}}.json",
+			JSON: map[string]any{
+				"text":  "a note",
+				"quote": "2003",
+				"ranges": []map[string]any{{
+					"start":       "/section/div[1]",
+					"startOffset": 8,
+					"end":         "/section/div[1]",
+					"endOffset":   12,
+				}},
+			},
+			ExpectStatus: 200,
+			ExpectJSON: `{
+				"id": "<<PRESENCE>>",
+				"annotator_schema_version": "v1.0",
+				"text": "a note",
+				"quote": "2003",
+				"ranges": [{
+					"start": "/section/div[1]",
+					"startOffset": 8,
+					"end": "/section/div[1]",
+					"endOffset": 12
+				}],
+				"created_at": "<<PRESENCE>>",
+				"updated_at": "<<PRESENCE>>"
+			}`,
+		},
+		RequestTest{
+			Target:       "/wallabag/api/annotations/{{(index .User.Bookmarks 0).ID}}.json",
+			JSON:         true,
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				data := r.JSON.(map[string]any)
+				require.EqualValues(t, 1, data["total"])
+				require.Len(t, data["rows"], 1)
+			},
+		},
+		RequestTest{
+			Method:       "PUT",
+			Target:       "/wallabag/api/annotations/{{printf \"%.0f\" (index (index .History 1).JSON \"id\")}}.json",
+			JSON:         map[string]any{"text": "updated note"},
+			ExpectStatus: 200,
+			Assert: func(t *testing.T, r *Response) {
+				require.Equal(t, "updated note", r.JSON.(map[string]any)["text"])
+			},
+		},
+		RequestTest{
+			Method:       "DELETE",
+			Target:       "/wallabag/api/annotations/{{printf \"%.0f\" (index (index .History 2).JSON \"id\")}}.json",
+			JSON:         true,
+			ExpectStatus: 200,
+		},
+		RequestTest{
+			Target:       "/wallabag/api/annotations/{{(index .User.Bookmarks 0).ID}}.json",
+			JSON:         true,
+			ExpectStatus: 200,
+			ExpectJSON:   `{"total": 0, "rows": []}`,
+		},
+	)
+}
+
+func TestWallabagPermissions(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	client := NewClient(t, app)
+
+	for _, user := range []string{"admin", "staff", "user", "disabled", ""} {
+		RunRequestSequence(t, client, user,
+			RequestTest{
+				Target: "/wallabag/api/entries.json",
+				JSON:   true,
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 200)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					case "":
+						r.AssertStatus(t, 401)
+					}
+				},
+			},
+			RequestTest{
+				Method: "POST",
+				Target: "/wallabag/api/entries.json",
+				JSON:   map[string]any{},
+				Assert: func(t *testing.T, r *Response) {
+					switch user {
+					case "admin", "staff", "user":
+						r.AssertStatus(t, 400)
+					case "disabled":
+						r.AssertStatus(t, 403)
+					case "":
+						r.AssertStatus(t, 401)
+					}
+				},
+			},
+		)
+	}
+}
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/configs"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+// SignatureHeader is the header holding the payload's signature.
+const SignatureHeader = "X-Readeck-Signature"
+
+// retryDelays contains the delays between two delivery attempts.
+// A delivery is abandoned after its last retry.
+var retryDelays = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	30 * time.Minute,
+	2 * time.Hour,
+	6 * time.Hour,
+}
+
+// client is the HTTP client sending the payloads. It never follows
+// redirects and its dialer refuses to connect to the addresses
+// denied to the extractor, so a webhook can't reach an internal
+// service. Connections are not reused so every delivery goes
+// through this check.
+var client = &http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: checkDestIP,
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		DisableKeepAlives:   true,
+	},
+	CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Message is the JSON document sent to a webhook.
+type Message struct {
+	ID      string    `json:"id"`
+	Event   string    `json:"event"`
+	Created time.Time `json:"created"`
+	Data    any       `json:"data"`
+}
+
+// Sign returns the signature of a payload. It's the hex encoded
+// HMAC-SHA256 of the payload, using the webhook's secret,
+// prefixed with "sha256=".
+func Sign(secret string, payload []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(payload)
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Dispatch creates a delivery for every enabled webhook of the user
+// that subscribes to the event, and launches their delivery tasks.
+func Dispatch(userID int, event string, data any) error {
+	var items []*Webhook
+	err := Webhooks.Query().Where(
+		goqu.C("user_id").Eq(userID),
+		goqu.C("is_enabled").Eq(true),
+	).ScanStructs(&items)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range items {
+		if !w.HasEvent(event) {
+			continue
+		}
+
+		now := time.Now()
+		d := &Delivery{
+			WebhookID:   w.ID,
+			Event:       event,
+			NextAttempt: &now,
+		}
+		if err = newDelivery(d, data); err != nil {
+			return err
+		}
+
+		if err = DeliverTask.Run(d.ID, d.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newDelivery creates a new delivery and its payload.
+func newDelivery(d *Delivery, data any) error {
+	// The payload carries the delivery's UID so the receiver
+	// can recognize a retried delivery.
+	d.UID = base58.NewUUID()
+	payload, err := json.Marshal(Message{
+		ID:      d.UID,
+		Event:   d.Event,
+		Created: time.Now(),
+		Data:    data,
+	})
+	if err != nil {
+		return err
+	}
+
+	d.Payload = string(payload)
+	return Deliveries.Create(d)
+}
+
+// Deliver sends a delivery's payload to its webhook and records
+// the result. When the attempt fails, the next attempt is
+// scheduled with an increasing delay.
+func Deliver(d *Delivery) error {
+	w, err := Webhooks.GetOne(goqu.C("id").Eq(d.WebhookID))
+	if err != nil {
+		return err
+	}
+
+	d.Attempts++
+	d.StatusCode = 0
+	d.LastError = ""
+	d.NextAttempt = nil
+
+	if !w.IsEnabled {
+		d.LastError = "webhook is disabled"
+		return d.Save()
+	}
+
+	if d.StatusCode, err = send(w, d); err != nil {
+		d.LastError = err.Error()
+		if d.Attempts <= len(retryDelays) {
+			next := time.Now().Add(retryDelays[d.Attempts-1])
+			d.NextAttempt = &next
+		}
+	} else {
+		now := time.Now()
+		d.Delivered = &now
+	}
+
+	return d.Save()
+}
+
+// checkDestIP returns an error when the resolved address
+// of a connection is in the list of denied IP ranges.
+func checkDestIP(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid address %s", address)
+	}
+
+	for _, cidr := range configs.ExtractorDeniedIPs() {
+		if cidr.Contains(ip) {
+			return fmt.Errorf("ip %s is blocked by rule %s", ip, cidr)
+		}
+	}
+	return nil
+}
+
+// send performs the HTTP request to the webhook.
+func send(w *Webhook, d *Delivery) (int, error) {
+	body := []byte(d.Payload)
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Readeck Webhook")
+	req.Header.Set("X-Readeck-Event", d.Event)
+	req.Header.Set("X-Readeck-Delivery", d.UID)
+	req.Header.Set(SignatureHeader, Sign(w.Secret, body))
+
+	rsp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close() //nolint:errcheck
+
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return rsp.StatusCode, fmt.Errorf("invalid status code (%d)", rsp.StatusCode)
+	}
+
+	return rsp.StatusCode, nil
+}
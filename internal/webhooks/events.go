@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package webhooks
+
+import (
+	"log/slog"
+	"time"
+
+	"codeberg.org/readeck/readeck/internal/bookmarks"
+)
+
+// BookmarkData is the bookmark information sent with
+// every bookmark event.
+type BookmarkData struct {
+	ID         string    `json:"id"`
+	Created    time.Time `json:"created"`
+	Updated    time.Time `json:"updated"`
+	State      string    `json:"state"`
+	URL        string    `json:"url"`
+	Title      string    `json:"title"`
+	SiteName   string    `json:"site_name"`
+	IsMarked   bool      `json:"is_marked"`
+	IsArchived bool      `json:"is_archived"`
+	Labels     []string  `json:"labels"`
+}
+
+// BookmarkEventData is the data of a bookmark event.
+type BookmarkEventData struct {
+	Bookmark   BookmarkData                  `json:"bookmark"`
+	Annotation *bookmarks.BookmarkAnnotation `json:"annotation,omitempty"`
+}
+
+// SendBookmarkEvent dispatches a bookmark event to the webhooks of
+// the bookmark's owner. The annotation is only needed for
+// [EventBookmarkAnnotated].
+// Errors are only logged, an event must never prevent the
+// operation that triggered it.
+func SendBookmarkEvent(event string, b *bookmarks.Bookmark, annotation *bookmarks.BookmarkAnnotation) {
+	if b.UserID == nil {
+		return
+	}
+
+	data := BookmarkEventData{
+		Bookmark: BookmarkData{
+			ID:         b.UID,
+			Created:    b.Created,
+			Updated:    b.Updated,
+			State:      b.StateName(),
+			URL:        b.URL,
+			Title:      b.Title,
+			SiteName:   b.SiteName,
+			IsMarked:   b.IsMarked,
+			IsArchived: b.IsArchived,
+			Labels:     b.Labels,
+		},
+		Annotation: annotation,
+	}
+	if data.Bookmark.Labels == nil {
+		data.Bookmark.Labels = []string{}
+	}
+
+	if err := Dispatch(*b.UserID, event, data); err != nil {
+		slog.Error("webhook dispatch",
+			slog.String("event", event),
+			slog.Int("bookmark_id", b.ID),
+			slog.Any("err", err),
+		)
+	}
+}
@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package webhooks
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/bus"
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+// deliveryRetention is the time a delivery is kept in the log.
+const deliveryRetention = 30 * 24 * time.Hour
+
+var (
+	// DeliverTask is the task that performs one delivery attempt.
+	DeliverTask superbus.Task
+	// RetryTask is the periodic task that launches the pending
+	// deliveries and removes the old ones.
+	RetryTask superbus.Task
+)
+
+func init() {
+	bus.OnReady(func() {
+		DeliverTask = bus.Tasks().NewTask(
+			"webhook.deliver",
+			superbus.WithUnmarshall(func(data []byte) interface{} {
+				var res int
+				err := json.Unmarshal(data, &res)
+				if err != nil {
+					panic(err)
+				}
+				return res
+			}),
+			superbus.WithTaskHandler(deliverHandler),
+		)
+
+		RetryTask = bus.Tasks().NewTask(
+			"webhook.retry",
+			superbus.WithTaskInterval(time.Minute),
+			superbus.WithTaskHandler(retryHandler),
+		)
+	})
+}
+
+func deliverHandler(data interface{}) {
+	id := data.(int)
+	logger := slog.With(slog.Int("delivery_id", id))
+
+	d, err := Deliveries.GetOne(goqu.C("id").Eq(id))
+	if err != nil {
+		logger.Error("delivery retrieve", slog.Any("err", err))
+		return
+	}
+
+	if err = Deliver(d); err != nil {
+		logger.Error("delivery", slog.Any("err", err))
+		return
+	}
+
+	logger.Info("webhook delivery",
+		slog.String("event", d.Event),
+		slog.Int("attempts", d.Attempts),
+		slog.Int("status", d.StatusCode),
+		slog.String("error", d.LastError),
+	)
+}
+
+func retryHandler(_ interface{}) {
+	var ids []int
+	err := Deliveries.Query().
+		Select(goqu.C("id")).
+		Where(
+			goqu.C("delivered").IsNull(),
+			goqu.C("next_attempt").Lte(time.Now()),
+		).
+		Order(goqu.C("next_attempt").Asc()).
+		ScanVals(&ids)
+	if err != nil {
+		slog.Error("delivery list", slog.Any("err", err))
+		return
+	}
+
+	for _, id := range ids {
+		if DeliverTask.IsRunning(id) {
+			continue
+		}
+		if err := DeliverTask.Run(id, id); err != nil {
+			slog.Error("delivery launch", slog.Int("delivery_id", id), slog.Any("err", err))
+		}
+	}
+
+	if _, err := Deliveries.DeleteBefore(time.Now().Add(-deliveryRetention)); err != nil {
+		slog.Error("delivery cleanup", slog.Any("err", err))
+	}
+}
@@ -0,0 +1,274 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package webhooks contains the models and functions to manage
+// user webhooks and their deliveries.
+package webhooks
+
+import (
+	"crypto/rand"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"codeberg.org/readeck/readeck/internal/db"
+	"codeberg.org/readeck/readeck/internal/db/types"
+	"codeberg.org/readeck/readeck/pkg/base58"
+)
+
+const (
+	// TableName is the webhook table name in database.
+	TableName = "webhook"
+	// DeliveryTableName is the webhook delivery table name in database.
+	DeliveryTableName = "webhook_delivery"
+)
+
+// Bookmark events.
+const (
+	EventBookmarkCreated   = "bookmark.created"
+	EventBookmarkExtracted = "bookmark.extracted"
+	EventBookmarkArchived  = "bookmark.archived"
+	EventBookmarkMarked    = "bookmark.marked"
+	EventBookmarkLabeled   = "bookmark.labeled"
+	EventBookmarkAnnotated = "bookmark.annotated"
+)
+
+var (
+	// Webhooks is the webhook manager.
+	Webhooks = Manager{}
+
+	// Deliveries is the webhook delivery manager.
+	Deliveries = DeliveryManager{}
+
+	// ErrNotFound is returned when a webhook or delivery record was not found.
+	ErrNotFound = errors.New("not found")
+
+	// Events is the list of all the events a webhook can subscribe to.
+	Events = []string{
+		EventBookmarkCreated,
+		EventBookmarkExtracted,
+		EventBookmarkArchived,
+		EventBookmarkMarked,
+		EventBookmarkLabeled,
+		EventBookmarkAnnotated,
+	}
+)
+
+// Webhook is a webhook record in database.
+type Webhook struct {
+	ID        int           `db:"id" goqu:"skipinsert,skipupdate"`
+	UID       string        `db:"uid"`
+	UserID    *int          `db:"user_id"`
+	Created   time.Time     `db:"created" goqu:"skipupdate"`
+	Updated   time.Time     `db:"updated"`
+	URL       string        `db:"url"`
+	Secret    string        `db:"secret"`
+	Events    types.Strings `db:"events"`
+	IsEnabled bool          `db:"is_enabled"`
+}
+
+// Manager is a query helper for webhook entries.
+type Manager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *Manager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(TableName).As("w")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *Manager) GetOne(expressions ...goqu.Expression) (*Webhook, error) {
+	var w Webhook
+	found, err := m.Query().Where(expressions...).ScanStruct(&w)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrNotFound
+	}
+
+	return &w, nil
+}
+
+// Create inserts a new webhook in the database.
+// A new signing secret is generated when none is set.
+func (m *Manager) Create(w *Webhook) error {
+	if w.UserID == nil {
+		return errors.New("no webhook user")
+	}
+
+	w.Created = time.Now()
+	w.Updated = w.Created
+	w.UID = base58.NewUUID()
+	if w.Secret == "" {
+		w.Secret = NewSecret()
+	}
+	if w.Events == nil {
+		w.Events = types.Strings{}
+	}
+
+	ds := db.Q().Insert(TableName).
+		Rows(w).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	w.ID = id
+	return nil
+}
+
+// Update updates some webhook values.
+func (w *Webhook) Update(v interface{}) error {
+	if w.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		v["updated"] = time.Now()
+	default:
+		//
+	}
+
+	_, err := db.Q().Update(TableName).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(w.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Save updates all the webhook values.
+func (w *Webhook) Save() error {
+	w.Updated = time.Now()
+	return w.Update(w)
+}
+
+// Delete removes a webhook and its deliveries from the database.
+func (w *Webhook) Delete() error {
+	_, err := db.Q().Delete(TableName).Prepared(true).
+		Where(goqu.C("id").Eq(w.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// HasEvent returns true when the webhook subscribes to the given event.
+func (w *Webhook) HasEvent(name string) bool {
+	return slices.Contains(w.Events, name)
+}
+
+// Delivery is a webhook delivery record in database.
+// It holds the payload sent to the webhook and the result
+// of the last attempt.
+type Delivery struct {
+	ID          int        `db:"id" goqu:"skipinsert,skipupdate"`
+	UID         string     `db:"uid"`
+	WebhookID   int        `db:"webhook_id"`
+	Created     time.Time  `db:"created" goqu:"skipupdate"`
+	Event       string     `db:"event"`
+	Payload     string     `db:"payload"`
+	Attempts    int        `db:"attempts"`
+	NextAttempt *time.Time `db:"next_attempt"`
+	Delivered   *time.Time `db:"delivered"`
+	StatusCode  int        `db:"status_code"`
+	LastError   string     `db:"last_error"`
+}
+
+// DeliveryManager is a query helper for delivery entries.
+type DeliveryManager struct{}
+
+// Query returns a prepared goqu SelectDataset that can be extended later.
+func (m *DeliveryManager) Query() *goqu.SelectDataset {
+	return db.Q().From(goqu.T(DeliveryTableName).As("d")).Prepared(true)
+}
+
+// GetOne executes the a select query and returns the first result or an error
+// when there's no result.
+func (m *DeliveryManager) GetOne(expressions ...goqu.Expression) (*Delivery, error) {
+	var d Delivery
+	found, err := m.Query().Where(expressions...).ScanStruct(&d)
+
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, ErrNotFound
+	}
+
+	return &d, nil
+}
+
+// Create inserts a new delivery in the database.
+func (m *DeliveryManager) Create(d *Delivery) error {
+	if d.WebhookID == 0 {
+		return errors.New("no delivery webhook")
+	}
+
+	d.Created = time.Now()
+	if d.UID == "" {
+		d.UID = base58.NewUUID()
+	}
+
+	ds := db.Q().Insert(DeliveryTableName).
+		Rows(d).
+		Prepared(true)
+
+	id, err := db.InsertWithID(ds, "id")
+	if err != nil {
+		return err
+	}
+
+	d.ID = id
+	return nil
+}
+
+// DeleteBefore removes all the deliveries created before the given date.
+func (m *DeliveryManager) DeleteBefore(t time.Time) (int64, error) {
+	res, err := db.Q().Delete(DeliveryTableName).Prepared(true).
+		Where(goqu.C("created").Lt(t)).
+		Executor().Exec()
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// Update updates some delivery values.
+func (d *Delivery) Update(v interface{}) error {
+	if d.ID == 0 {
+		return errors.New("no ID")
+	}
+
+	_, err := db.Q().Update(DeliveryTableName).Prepared(true).
+		Set(v).
+		Where(goqu.C("id").Eq(d.ID)).
+		Executor().Exec()
+
+	return err
+}
+
+// Save updates all the delivery values.
+func (d *Delivery) Save() error {
+	return d.Update(d)
+}
+
+// IsPending returns true when the delivery is waiting for a new attempt.
+func (d *Delivery) IsPending() bool {
+	return d.Delivered == nil && d.NextAttempt != nil
+}
+
+// NewSecret returns a new random signing secret.
+func NewSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b) //nolint:errcheck
+	return base58.EncodeToString(b)
+}
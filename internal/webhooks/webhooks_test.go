@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package webhooks_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/configs"
+	. "codeberg.org/readeck/readeck/internal/testing" //revive:disable:dot-imports
+	"codeberg.org/readeck/readeck/internal/webhooks"
+)
+
+func TestSign(t *testing.T) {
+	require.Equal(t,
+		"sha256=4a735a43e7ae6518589db067fa7af9c75fcda827a52e0dc5e455f7031695ef70",
+		webhooks.Sign("secret", []byte(`{"id":"abc"}`)),
+	)
+}
+
+func TestDeliver(t *testing.T) {
+	app := NewTestApp(t)
+	defer func() {
+		app.Close(t)
+	}()
+
+	// The test server listens on a loopback address
+	deniedIPs := configs.Config.Extractor.DeniedIPs
+	configs.Config.Extractor.DeniedIPs = nil
+	configs.InitConfiguration()
+	defer func() {
+		configs.Config.Extractor.DeniedIPs = deniedIPs
+		configs.InitConfiguration()
+	}()
+
+	status := http.StatusOK
+	received := []*http.Request{}
+	bodies := [][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r)
+		bodies = append(bodies, body)
+		if status == http.StatusFound {
+			w.Header().Set("Location", "/next")
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	user := app.Users["user"].User
+	w := &webhooks.Webhook{
+		UserID:    &user.ID,
+		URL:       srv.URL,
+		Events:    []string{webhooks.EventBookmarkCreated},
+		IsEnabled: true,
+	}
+	require.NoError(t, webhooks.Webhooks.Create(w))
+	require.NotEmpty(t, w.Secret)
+
+	getDeliveries := func() []*webhooks.Delivery {
+		res := []*webhooks.Delivery{}
+		require.NoError(t, webhooks.Deliveries.Query().
+			Where(goqu.C("webhook_id").Eq(w.ID)).
+			Order(goqu.C("id").Asc()).
+			ScanStructs(&res))
+		return res
+	}
+
+	// Unsubscribed event
+	require.NoError(t, webhooks.Dispatch(user.ID, webhooks.EventBookmarkArchived, nil))
+	require.Empty(t, getDeliveries())
+
+	// Another user
+	require.NoError(t, webhooks.Dispatch(app.Users["staff"].User.ID, webhooks.EventBookmarkCreated, nil))
+	require.Empty(t, getDeliveries())
+
+	require.NoError(t, webhooks.Dispatch(user.ID, webhooks.EventBookmarkCreated, map[string]string{"foo": "bar"}))
+	deliveries := getDeliveries()
+	require.Len(t, deliveries, 1)
+	d := deliveries[0]
+	require.True(t, d.IsPending())
+
+	msg := map[string]any{}
+	require.NoError(t, json.Unmarshal([]byte(d.Payload), &msg))
+	require.Equal(t, d.UID, msg["id"])
+	require.Equal(t, webhooks.EventBookmarkCreated, msg["event"])
+	require.Equal(t, map[string]any{"foo": "bar"}, msg["data"])
+
+	// The delivery task was launched
+	require.True(t, webhooks.DeliverTask.IsRunning(d.ID))
+
+	t.Run("success", func(t *testing.T) {
+		require.NoError(t, webhooks.Deliver(d))
+		require.Len(t, received, 1)
+		require.Equal(t, "application/json", received[0].Header.Get("Content-Type"))
+		require.Equal(t, webhooks.EventBookmarkCreated, received[0].Header.Get("X-Readeck-Event"))
+		require.Equal(t, d.UID, received[0].Header.Get("X-Readeck-Delivery"))
+		require.Equal(t,
+			webhooks.Sign(w.Secret, bodies[0]),
+			received[0].Header.Get(webhooks.SignatureHeader),
+		)
+		require.Equal(t, d.Payload, string(bodies[0]))
+
+		d = getDeliveries()[0]
+		require.False(t, d.IsPending())
+		require.NotNil(t, d.Delivered)
+		require.Equal(t, 1, d.Attempts)
+		require.Equal(t, 200, d.StatusCode)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		d.Delivered = nil
+
+		require.NoError(t, webhooks.Deliver(d))
+		d = getDeliveries()[0]
+		require.True(t, d.IsPending())
+		require.Nil(t, d.Delivered)
+		require.Equal(t, 2, d.Attempts)
+		require.Equal(t, 500, d.StatusCode)
+		require.Equal(t, "invalid status code (500)", d.LastError)
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		status = http.StatusFound
+		count := len(received)
+
+		require.NoError(t, webhooks.Deliver(d))
+		require.Len(t, received, count+1)
+		d = getDeliveries()[0]
+		require.Equal(t, 302, d.StatusCode)
+		require.Equal(t, "invalid status code (302)", d.LastError)
+	})
+
+	t.Run("denied ip", func(t *testing.T) {
+		status = http.StatusOK
+		count := len(received)
+
+		configs.Config.Extractor.DeniedIPs = deniedIPs
+		configs.InitConfiguration()
+		defer func() {
+			configs.Config.Extractor.DeniedIPs = nil
+			configs.InitConfiguration()
+		}()
+
+		require.NoError(t, webhooks.Deliver(d))
+		require.Len(t, received, count)
+		d = getDeliveries()[0]
+		require.True(t, d.IsPending())
+		require.Equal(t, 0, d.StatusCode)
+		require.Contains(t, d.LastError, "ip 127.0.0.1 is blocked by rule 127.0.0.0/8")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		require.NoError(t, w.Update(map[string]any{"is_enabled": false}))
+		count := len(received)
+
+		require.NoError(t, webhooks.Deliver(d))
+		require.Len(t, received, count)
+		d = getDeliveries()[0]
+		require.False(t, d.IsPending())
+		require.Equal(t, "webhook is disabled", d.LastError)
+	})
+}
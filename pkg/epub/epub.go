@@ -94,6 +94,11 @@ func (c *Writer) SetCreator(value string) {
 	c.pkg.Metadata.Creator = value
 }
 
+// SetDate sets the book's publication date.
+func (c *Writer) SetDate(value time.Time) {
+	c.pkg.Metadata.Date = value.Format(time.DateOnly)
+}
+
 // AddChapter adds a new chapter to the book.
 func (c *Writer) AddChapter(id, title, name string, r io.Reader) error {
 	c.pkg.Manifest.Items = append(c.pkg.Manifest.Items, ManifestItem{
@@ -129,6 +134,15 @@ func (c *Writer) AddImage(id, name string, r io.Reader) error {
 	)
 }
 
+// AddCover adds the book's cover image.
+func (c *Writer) AddCover(name string, r io.Reader) error {
+	if err := c.AddImage("cover-image", name, r); err != nil {
+		return err
+	}
+	c.pkg.Metadata.Meta = append(c.pkg.Metadata.Meta, Meta{Name: "cover", Content: "cover-image"})
+	return nil
+}
+
 // AddFile adds a file to the book.
 func (c *Writer) AddFile(id, name, mediaType string, r io.Reader) error {
 	c.pkg.Manifest.Items = append(c.pkg.Manifest.Items, ManifestItem{
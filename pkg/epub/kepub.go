@@ -0,0 +1,220 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package epub
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// KepubContentType is the media type of a Kobo EPUB file.
+const KepubContentType = "application/kepub+zip"
+
+const koboStyle = `div#book-inner { margin-top: 0; margin-bottom: 0; }`
+
+// rxSentenceEnd matches the end of a sentence, with its closing
+// quotes or parenthesis and the following spaces.
+var rxSentenceEnd = regexp.MustCompile(`[.!?…]+['"”’»)\]]*\s+`)
+
+// koboBlocks are the elements starting a new paragraph.
+var koboBlocks = map[atom.Atom]bool{
+	atom.Address:    true,
+	atom.Blockquote: true,
+	atom.Dd:         true,
+	atom.Div:        true,
+	atom.Dt:         true,
+	atom.Figcaption: true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Li:         true,
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Td:         true,
+	atom.Th:         true,
+}
+
+// koboSkip are the elements whose content is never segmented.
+var koboSkip = map[atom.Atom]bool{
+	atom.Math:     true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Textarea: true,
+}
+
+// ConvertKepub converts an XHTML chapter to a Kobo chapter.
+// Kobo readers rely on a "koboSpan" element around every sentence and
+// image to track the reading progress and the highlights. The body's
+// content is wrapped in the "book-columns" and "book-inner" elements
+// that the reader uses for its layout.
+func ConvertKepub(w io.Writer, r io.Reader) error {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return err
+	}
+
+	var head, body *html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch c.DataAtom {
+			case atom.Head:
+				head = c
+			case atom.Body:
+				body = c
+			}
+			if c.DataAtom == atom.Html {
+				walk(c)
+			}
+		}
+	}
+	walk(doc)
+	if head == nil || body == nil {
+		return errors.New("invalid document")
+	}
+
+	// The XML declaration is a comment for the HTML parser
+	for c := doc.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode && strings.HasPrefix(c.Data, "?xml") {
+			doc.RemoveChild(c)
+		}
+		c = next
+	}
+
+	style := newElement(atom.Style, "type", "text/css", "id", "kobostylehacks")
+	style.AppendChild(&html.Node{Type: html.TextNode, Data: koboStyle})
+	head.AppendChild(style)
+
+	(&koboSpanner{}).walk(body)
+
+	columns := newElement(atom.Div, "id", "book-columns")
+	inner := newElement(atom.Div, "id", "book-inner")
+	columns.AppendChild(inner)
+	for body.FirstChild != nil {
+		c := body.FirstChild
+		body.RemoveChild(c)
+		inner.AppendChild(c)
+	}
+	body.AppendChild(columns)
+
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return html.Render(w, doc)
+}
+
+// koboSpanner adds the "koboSpan" elements to a document.
+// Each span receives an ID "kobo.{paragraph}.{segment}".
+type koboSpanner struct {
+	para int
+	seg  int
+}
+
+func (k *koboSpanner) walk(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+
+		switch c.Type {
+		case html.ElementNode:
+			switch {
+			case koboSkip[c.DataAtom]:
+				// noop
+			case c.DataAtom == atom.Img:
+				k.nextPara()
+				span := k.newSpan()
+				n.InsertBefore(span, c)
+				n.RemoveChild(c)
+				span.AppendChild(c)
+			default:
+				if koboBlocks[c.DataAtom] {
+					k.nextPara()
+				}
+				k.walk(c)
+			}
+		case html.TextNode:
+			k.text(n, c)
+		}
+
+		c = next
+	}
+}
+
+// text replaces a text node with one span per sentence.
+func (k *koboSpanner) text(parent, n *html.Node) {
+	if strings.TrimSpace(n.Data) == "" {
+		return
+	}
+	if k.para == 0 {
+		k.nextPara()
+	}
+
+	for _, s := range splitSentences(n.Data) {
+		if strings.TrimSpace(s) == "" {
+			parent.InsertBefore(&html.Node{Type: html.TextNode, Data: s}, n)
+			continue
+		}
+		span := k.newSpan()
+		span.AppendChild(&html.Node{Type: html.TextNode, Data: s})
+		parent.InsertBefore(span, n)
+	}
+	parent.RemoveChild(n)
+}
+
+func (k *koboSpanner) nextPara() {
+	k.para++
+	k.seg = 0
+}
+
+func (k *koboSpanner) newSpan() *html.Node {
+	k.seg++
+	return newElement(atom.Span,
+		"class", "koboSpan",
+		"id", fmt.Sprintf("kobo.%d.%d", k.para, k.seg),
+	)
+}
+
+// splitSentences splits a text on sentence boundaries.
+// The leading spaces are kept apart so they don't end up in a span.
+func splitSentences(s string) []string {
+	res := []string{}
+	trimmed := strings.TrimLeft(s, " \t\r\n")
+	if len(trimmed) < len(s) {
+		res = append(res, s[:len(s)-len(trimmed)])
+		s = trimmed
+	}
+
+	start := 0
+	for _, idx := range rxSentenceEnd.FindAllStringIndex(s, -1) {
+		if idx[1] == len(s) {
+			break
+		}
+		res = append(res, s[start:idx[1]])
+		start = idx[1]
+	}
+	if start < len(s) {
+		res = append(res, s[start:])
+	}
+	return res
+}
+
+func newElement(a atom.Atom, attrs ...string) *html.Node {
+	n := &html.Node{Type: html.ElementNode, DataAtom: a, Data: a.String()}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		n.Attr = append(n.Attr, html.Attribute{Key: attrs[i], Val: attrs[i+1]})
+	}
+	return n
+}
@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package epub
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"", []string{}},
+		{"Hello", []string{"Hello"}},
+		{"  Hello. ", []string{"  ", "Hello. "}},
+		{"One. Two! Three? Four", []string{"One. ", "Two! ", "Three? ", "Four"}},
+		{`He said "stop." Then left.`, []string{`He said "stop." `, "Then left."}},
+		{"Version 1.2 is out", []string{"Version 1.2 is out"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			require.Equal(t, test.expected, splitSentences(test.text))
+		})
+	}
+}
+
+func TestConvertKepub(t *testing.T) {
+	src := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN"
+  "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml" lang="en">
+<head><title>Test</title></head>
+<body><h1>Title</h1>
+<p>First sentence. Second <em>one</em>.</p>
+<p><img src="a.png" alt=""/><br/></p>
+<pre>code</pre>
+<svg><text>vector</text></svg>
+</body>
+</html>`
+
+	buf := new(strings.Builder)
+	require.NoError(t, ConvertKepub(buf, strings.NewReader(src)))
+
+	require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN" "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd">`+
+		`<html xmlns="http://www.w3.org/1999/xhtml" lang="en"><head><title>Test</title>`+
+		`<style type="text/css" id="kobostylehacks">`+koboStyle+`</style></head>`+"\n"+
+		`<body><div id="book-columns"><div id="book-inner">`+
+		`<h1><span class="koboSpan" id="kobo.1.1">Title</span></h1>`+"\n"+
+		`<p><span class="koboSpan" id="kobo.2.1">First sentence. </span>`+
+		`<span class="koboSpan" id="kobo.2.2">Second </span>`+
+		`<em><span class="koboSpan" id="kobo.2.3">one</span></em>`+
+		`<span class="koboSpan" id="kobo.2.4">.</span></p>`+"\n"+
+		`<p><span class="koboSpan" id="kobo.4.1"><img src="a.png" alt=""/></span><br/></p>`+"\n"+
+		`<pre><span class="koboSpan" id="kobo.5.1">code</span></pre>`+"\n"+
+		`<svg><text>vector</text></svg>`+"\n\n"+
+		`</div></div></body></html>`,
+		buf.String(),
+	)
+}
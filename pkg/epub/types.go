@@ -35,6 +35,14 @@ type Metadata struct {
 	Language   string     `xml:"dc:language"`
 	Title      string     `xml:"dc:title"`
 	Creator    string     `xml:"dc:creator"`
+	Date       string     `xml:"dc:date,omitempty"`
+	Meta       []Meta     `xml:"meta"`
+}
+
+// Meta is a metadata>meta tag.
+type Meta struct {
+	Name    string `xml:"name,attr"`
+	Content string `xml:"content,attr"`
 }
 
 // Identifier is the metadata>dc:identifier tag.
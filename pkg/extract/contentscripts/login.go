@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package contentscripts
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/antchfx/htmlquery"
+
+	"codeberg.org/readeck/readeck/pkg/extract"
+)
+
+// LoginCredentials contains the values used to access
+// a website requiring a login.
+type LoginCredentials struct {
+	// Domain is the domain to which the cookies are sent.
+	// It includes all its subdomains.
+	Domain   string
+	Username string
+	Password string
+	Cookies  []*http.Cookie
+}
+
+// LoginProvider is a function returning the credentials
+// for a given URL, or nil when there are none.
+type LoginProvider func(src *url.URL) *LoginCredentials
+
+// SiteLogin uses the credentials returned by the given provider
+// to access the extracted page.
+//
+// The provided cookies are added to the extractor's client cookie jar.
+// When the site configuration defines a login URI and the credentials
+// contain a username, the login form is sent before loading the page
+// so the client receives the session cookies.
+//
+// It must run after [LoadSiteConfig].
+func SiteLogin(provider LoginProvider) extract.Processor {
+	var credentials *LoginCredentials
+
+	return func(m *extract.ProcessMessage, next extract.Processor) extract.Processor {
+		if m.Position() > 0 || provider == nil {
+			return next
+		}
+
+		switch m.Step() {
+		case extract.StepStart:
+			credentials = provider(m.Extractor.Drop().URL)
+			if credentials == nil {
+				return next
+			}
+			setLoginCookies(m, credentials)
+			if cfg := getConfig(m.Extractor.Context); cfg != nil {
+				sendLoginForm(m, cfg, credentials)
+			}
+		case extract.StepDom:
+			checkLoggedIn(m, credentials)
+		}
+
+		return next
+	}
+}
+
+func setLoginCookies(m *extract.ProcessMessage, credentials *LoginCredentials) {
+	jar := m.Extractor.Client().Jar
+	if jar == nil || len(credentials.Cookies) == 0 {
+		return
+	}
+
+	cookies := make([]*http.Cookie, len(credentials.Cookies))
+	for i, x := range credentials.Cookies {
+		c := *x
+		if c.Domain == "" {
+			c.Domain = credentials.Domain
+		}
+		if c.Path == "" {
+			c.Path = "/"
+		}
+		cookies[i] = &c
+	}
+
+	jar.SetCookies(m.Extractor.Drop().URL, cookies)
+	m.Log().Debug("site login cookies", slog.Int("count", len(cookies)))
+}
+
+func sendLoginForm(m *extract.ProcessMessage, cfg *SiteConfig, credentials *LoginCredentials) {
+	if cfg.LoginURI == "" || cfg.LoginUsernameField == "" || credentials.Username == "" {
+		return
+	}
+
+	loginURL, err := m.Extractor.Drop().URL.Parse(cfg.LoginURI)
+	if err != nil || (loginURL.Scheme != "http" && loginURL.Scheme != "https") {
+		m.Log().Warn("invalid login URI", slog.String("uri", cfg.LoginURI))
+		return
+	}
+
+	values := url.Values{}
+	for k, v := range cfg.LoginExtraFields {
+		// Expressions (@=...) can't be evaluated here.
+		if strings.HasPrefix(v, "@=") {
+			m.Log().Debug("login extra field ignored", slog.String("name", k))
+			continue
+		}
+		values.Set(k, v)
+	}
+	values.Set(cfg.LoginUsernameField, credentials.Username)
+	if cfg.LoginPasswordField != "" {
+		values.Set(cfg.LoginPasswordField, credentials.Password)
+	}
+
+	rsp, err := m.Extractor.Client().PostForm(loginURL.String(), values)
+	if err != nil {
+		m.Log().Warn("site login", slog.Any("err", err))
+		return
+	}
+	defer rsp.Body.Close()        //nolint:errcheck
+	io.Copy(io.Discard, rsp.Body) //nolint:errcheck
+
+	if rsp.StatusCode >= 400 {
+		m.Log().Warn("site login",
+			slog.String("url", loginURL.String()),
+			slog.Int("status", rsp.StatusCode),
+		)
+		return
+	}
+
+	m.Log().Info("site login", slog.String("url", loginURL.String()))
+}
+
+// checkLoggedIn applies the "not_logged_in_xpath" directives and logs a
+// warning when the page is still the one of an anonymous visitor.
+func checkLoggedIn(m *extract.ProcessMessage, credentials *LoginCredentials) {
+	cfg := getConfig(m.Extractor.Context)
+	if cfg == nil || m.Dom == nil {
+		return
+	}
+
+	for _, selector := range cfg.NotLoggedInXPath {
+		if node, _ := htmlquery.Query(m.Dom, selector); node != nil {
+			if credentials == nil {
+				m.Log().Warn("this page requires a login")
+			} else {
+				m.Log().Warn("site login failed, the page requires a login")
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package contentscripts_test
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/extract"
+	"codeberg.org/readeck/readeck/pkg/extract/contentscripts"
+)
+
+func TestSiteLogin(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	defaultFiles := contentscripts.SiteConfigFiles
+	defer func() {
+		contentscripts.SiteConfigFiles = defaultFiles
+	}()
+	contentscripts.SiteConfigFiles = contentscripts.NewSiteconfigDiscovery(fstest.MapFS{
+		"example.net.json": &fstest.MapFile{
+			Data: []byte(`{
+				"requires_login": true,
+				"login_uri": "/login",
+				"login_username_field": "user",
+				"login_password_field": "pass",
+				"login_extra_fields": {"remember": "1", "token": "@=xpath('//input')"},
+				"not_logged_in_xpath": ["//div[@class='paywall']"]
+			}`),
+		},
+	})
+
+	var loginForm url.Values
+	httpmock.RegisterResponder("POST", "https://www.example.net/login", func(r *http.Request) (*http.Response, error) {
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		loginForm = r.PostForm
+		rsp := httpmock.NewStringResponse(200, "ok")
+		rsp.Header.Add("Set-Cookie", "session=s3cr3t; Path=/")
+		return rsp, nil
+	})
+
+	var received []*http.Cookie
+	httpmock.RegisterResponder("GET", `=~^https://(www\.)?example\.net/article`, func(r *http.Request) (*http.Response, error) {
+		received = r.Cookies()
+		rsp := httpmock.NewStringResponse(200, `<html><body><div class="paywall">subscribe</div></body></html>`)
+		rsp.Header.Set("Content-Type", "text/html")
+		rsp.Request = r
+		return rsp, nil
+	})
+
+	provider := func(src *url.URL) *contentscripts.LoginCredentials {
+		if src.Hostname() != "www.example.net" {
+			return nil
+		}
+		return &contentscripts.LoginCredentials{
+			Domain:   "example.net",
+			Username: "alice",
+			Password: "passw0rd",
+			Cookies:  []*http.Cookie{{Name: "pref", Value: "abc"}},
+		}
+	}
+
+	hasLog := func(ex *extract.Extractor, msg string) bool {
+		return slices.ContainsFunc(ex.Logs, func(s string) bool {
+			return strings.Contains(s, msg)
+		})
+	}
+
+	run := func(src string) *extract.Extractor {
+		ex, err := extract.New(src)
+		require.NoError(t, err)
+		ex.AddProcessors(
+			contentscripts.LoadScripts(),
+			contentscripts.LoadSiteConfig,
+			contentscripts.SiteLogin(provider),
+		)
+		ex.Run()
+		return ex
+	}
+
+	t.Run("login", func(t *testing.T) {
+		ex := run("https://www.example.net/article")
+
+		require.Equal(t, url.Values{
+			"user":     {"alice"},
+			"pass":     {"passw0rd"},
+			"remember": {"1"},
+		}, loginForm)
+
+		names := map[string]string{}
+		for _, c := range received {
+			names[c.Name] = c.Value
+		}
+		require.Equal(t, map[string]string{"pref": "abc", "session": "s3cr3t"}, names)
+		require.True(t, hasLog(ex, "site login failed"))
+	})
+
+	t.Run("no credentials", func(t *testing.T) {
+		loginForm = nil
+		received = nil
+		ex := run("https://example.net/article")
+
+		require.Nil(t, loginForm)
+		require.Empty(t, received)
+		require.True(t, hasLog(ex, "this page requires a login"))
+	})
+}
@@ -35,6 +35,13 @@ type SiteConfig struct {
 	ReplaceStrings          [][2]string       `json:"replace_strings"            js:"replaceStrings"`
 	HTTPHeaders             map[string]string `json:"http_headers"               js:"httpHeaders"`
 	Tests                   []FilterTest      `json:"tests"`
+
+	RequiresLogin      bool              `json:"requires_login"       js:"requiresLogin"`
+	LoginURI           string            `json:"login_uri"            js:"loginUri"`
+	LoginUsernameField string            `json:"login_username_field" js:"loginUsernameField"`
+	LoginPasswordField string            `json:"login_password_field" js:"loginPasswordField"`
+	LoginExtraFields   map[string]string `json:"login_extra_fields"   js:"loginExtraFields"`
+	NotLoggedInXPath   []string          `json:"not_logged_in_xpath"  js:"notLoggedInXpath"`
 }
 
 // FilterTest holds the values for a filter's test.
@@ -93,6 +100,7 @@ func NewSiteConfig(r io.Reader) (*SiteConfig, error) {
 func NewConfigForURL(discovery *SiteConfigDiscovery, src *url.URL) (*SiteConfig, error) {
 	res := &SiteConfig{}
 	res.HTTPHeaders = map[string]string{}
+	res.LoginExtraFields = map[string]string{}
 	res.AutoDetectOnFailure = true
 
 	hostname := strings.TrimPrefix(src.Hostname(), "www.")
@@ -145,6 +153,22 @@ func (cf *SiteConfig) Merge(src *SiteConfig) {
 	for k, v := range src.HTTPHeaders {
 		cf.HTTPHeaders[k] = v
 	}
+
+	// Login directives are only taken from the first
+	// configuration defining a login URI.
+	cf.RequiresLogin = cf.RequiresLogin || src.RequiresLogin
+	cf.NotLoggedInXPath = append(cf.NotLoggedInXPath, src.NotLoggedInXPath...)
+	if cf.LoginURI == "" && src.LoginURI != "" {
+		cf.LoginURI = src.LoginURI
+		cf.LoginUsernameField = src.LoginUsernameField
+		cf.LoginPasswordField = src.LoginPasswordField
+		if cf.LoginExtraFields == nil {
+			cf.LoginExtraFields = map[string]string{}
+		}
+		for k, v := range src.LoginExtraFields {
+			cf.LoginExtraFields[k] = v
+		}
+	}
 }
 
 // Files returns the files used to create the configuration.
@@ -36,6 +36,16 @@ var (
 	mediaTypes = []string{"photo", "video", "audio", "music"}
 )
 
+// StatusError is returned when a resource's response has
+// an invalid status code.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Invalid status code (%d)", e.Code)
+}
+
 // Drop is the result of a content extraction of one resource.
 type Drop struct {
 	URL          *url.URL
@@ -57,6 +67,10 @@ type Drop struct {
 	Properties DropProperties
 	Body       []byte `json:"-"`
 
+	// Document is the original document, when it's not an
+	// HTML page (a PDF file for instance).
+	Document []byte `json:"-"`
+
 	Pictures map[string]*Picture
 }
 
@@ -91,6 +105,17 @@ func (d *Drop) SetURL(src *url.URL) {
 
 // Load loads the remote URL and retrieve data.
 func (d *Drop) Load(client *http.Client) error {
+	return d.load(client, true)
+}
+
+// LoadLink loads the remote URL like [Drop.Load] but doesn't read
+// documents (PDF files), only their content type. It's enough to
+// get information about a link.
+func (d *Drop) LoadLink(client *http.Client) error {
+	return d.load(client, false)
+}
+
+func (d *Drop) load(client *http.Client, documents bool) error {
 	if d.URL == nil {
 		return errors.New("No document URL")
 	}
@@ -130,7 +155,7 @@ func (d *Drop) Load(client *http.Client) error {
 	d.Site = d.URL.Hostname()
 
 	if rsp.StatusCode/100 != 2 {
-		return fmt.Errorf("Invalid status code (%d)", rsp.StatusCode)
+		return &StatusError{rsp.StatusCode}
 	}
 
 	switch {
@@ -140,6 +165,8 @@ func (d *Drop) Load(client *http.Client) error {
 		return d.loadTextPlain(rsp)
 	case strings.HasPrefix(d.ContentType, "image/"):
 		return d.loadImage(rsp)
+	case d.ContentType == "application/pdf" && documents:
+		return d.loadPDF(rsp)
 	}
 
 	return nil
@@ -11,6 +11,8 @@ import (
 
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/pdf"
 )
 
 func TestDrop(t *testing.T) {
@@ -51,6 +53,14 @@ func TestDrop(t *testing.T) {
 		newContentResponder(200,
 			map[string]string{"content-type": "text/html"},
 			"html/ch4.html"))
+	httpmock.RegisterResponder("GET", "/report.pdf",
+		newContentResponder(200,
+			map[string]string{"content-type": "application/pdf"},
+			"pdf/report.pdf"))
+	httpmock.RegisterResponder("GET", "/invalid.pdf",
+		newContentResponder(200,
+			map[string]string{"content-type": "application/pdf"},
+			"html/ch1.html"))
 
 	t.Run("errors", func(t *testing.T) {
 		tests := []struct {
@@ -75,6 +85,10 @@ func TestDrop(t *testing.T) {
 				require.Equal(t, x.err, err.Error())
 			})
 		}
+
+		var se *StatusError
+		require.ErrorAs(t, NewDrop(mustParse("http://x/404")).Load(nil), &se)
+		require.Equal(t, 404, se.Code)
 	})
 
 	t.Run("url", func(t *testing.T) {
@@ -157,6 +171,39 @@ func TestDrop(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("pdf", func(t *testing.T) {
+		assert := require.New(t)
+		d := NewDrop(mustParse("http://x/report.pdf"))
+
+		err := d.Load(nil)
+		assert.NoError(err)
+		assert.True(d.IsHTML())
+		assert.Equal("pdf", d.DocumentType)
+		assert.Len(d.Document, 1267)
+
+		body := string(d.Body)
+		assert.Contains(body, `<html lang="en"><head><title>Reading Habits</title>`)
+		assert.Contains(body, `<meta name="author" content="Alice Martin"><meta name="author" content="Bob Durand">`)
+		assert.Contains(body, `<meta name="date" content="2024-06-12T09:00:00Z">`)
+		assert.Contains(body, "<p>People save articles to read them later. This report looks at how long "+
+			"they wait before reading them and how many they finally read.</p>")
+		assert.Contains(body, "<p>Second page &amp; conclusion.</p>")
+
+		assert.Contains(d.Pictures, "image")
+		assert.Contains(d.Pictures, "thumbnail")
+		assert.Equal([2]int{380, 537}, d.Pictures["thumbnail"].Size)
+
+		// Links don't load documents
+		d = NewDrop(mustParse("http://x/report.pdf"))
+		assert.NoError(d.LoadLink(nil))
+		assert.Equal("application/pdf", d.ContentType)
+		assert.Empty(d.Document)
+		assert.Empty(d.Body)
+
+		d = NewDrop(mustParse("http://x/invalid.pdf"))
+		assert.ErrorIs(d.Load(nil), pdf.ErrInvalid)
+	})
 }
 
 func TestDropAuthors(t *testing.T) {
@@ -181,6 +181,7 @@ type Extractor struct {
 	logger          *slog.Logger
 	processors      ProcessList
 	errors          Error
+	loadErr         error
 	drops           []*Drop
 	cachedResources map[string]*cachedResource
 }
@@ -243,8 +244,14 @@ func SetDeniedIPs(netList []*net.IPNet) func(e *Extractor) {
 // SetProxyList adds a new proxy dispatcher function to the HTTP transport.
 func SetProxyList(list []ProxyMatcher) func(e *Extractor) {
 	return func(e *Extractor) {
-		t := e.client.Transport.(*Transport)
-		htr := t.tr.(*http.Transport)
+		t, ok := e.client.Transport.(*Transport)
+		if !ok {
+			return
+		}
+		htr, ok := t.tr.(*http.Transport)
+		if !ok {
+			return
+		}
 		htr.Proxy = func(r *http.Request) (*url.URL, error) {
 			for _, p := range list {
 				if glob.Glob(p.Host(), r.URL.Host) {
@@ -285,6 +292,12 @@ func (e *Extractor) Errors() Error {
 	return e.errors
 }
 
+// LoadError returns the error that stopped the extraction when
+// a resource could not be loaded.
+func (e *Extractor) LoadError() error {
+	return e.loadErr
+}
+
 // AddError add a new error to the extractor's error list.
 func (e *Extractor) AddError(err error) {
 	e.errors = append(e.errors, err)
@@ -380,6 +393,7 @@ func (e *Extractor) Run() {
 		err := d.Load(e.client)
 		if err != nil {
 			m.Log().Error("cannot load resource", slog.Any("err", err))
+			e.loadErr = err
 			return
 		}
 
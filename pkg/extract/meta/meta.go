@@ -136,7 +136,7 @@ func SetDropProperties(m *extract.ProcessMessage, next extract.Processor) extrac
 
 	// Document type is only a predefined set and nothing more
 	switch d.DocumentType {
-	case "article", "photo", "video":
+	case "article", "photo", "video", "pdf":
 		// Valid values
 	default:
 		d.DocumentType = "article"
@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"codeberg.org/readeck/readeck/pkg/pdf"
+)
+
+// maxPDFSize is the maximum size of a PDF document we load.
+const maxPDFSize = 100 << 20
+
+// loadPDF loads a PDF document. The original document is kept in the
+// drop and its text is converted to an HTML document, with the PDF
+// metadata, so it can follow the rest of the extraction process.
+func (d *Drop) loadPDF(rsp *http.Response) error {
+	body, err := io.ReadAll(io.LimitReader(rsp.Body, maxPDFSize+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > maxPDFSize {
+		return fmt.Errorf("PDF document is too big (more than %d bytes)", maxPDFSize)
+	}
+
+	doc, err := pdf.Open(body)
+	if err != nil {
+		return err
+	}
+
+	d.Document = body
+	d.DocumentType = "pdf"
+	d.ContentType = "text/html"
+	d.Charset = "utf-8"
+
+	info := doc.Info()
+	title := strings.TrimSpace(info.Title)
+	if title == "" {
+		title = strings.TrimSuffix(path.Base(d.URL.Path), path.Ext(d.URL.Path))
+		title = rxTitleSpaces.ReplaceAllLiteralString(title, " ")
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("<html")
+	if info.Lang != "" {
+		fmt.Fprintf(buf, ` lang="%s"`, html.EscapeString(info.Lang))
+	}
+	fmt.Fprintf(buf, "><head><title>%s</title>", html.EscapeString(title))
+	writeMeta := func(name, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			fmt.Fprintf(buf, `<meta name="%s" content="%s">`, name, html.EscapeString(value))
+		}
+	}
+	for _, x := range strings.Split(info.Author, ";") {
+		writeMeta("author", x)
+	}
+	writeMeta("description", info.Subject)
+	writeMeta("keywords", info.Keywords)
+	if !info.Created.IsZero() {
+		writeMeta("date", info.Created.Format(time.RFC3339))
+	}
+
+	buf.WriteString("</head><body><article>")
+	for _, p := range doc.Pages() {
+		for _, x := range strings.Split(p.Text(), "\n\n") {
+			if x != "" {
+				fmt.Fprintf(buf, "<p>%s</p>\n", html.EscapeString(x))
+			}
+		}
+	}
+	buf.WriteString("</article></body></html>")
+	d.Body = buf.Bytes()
+
+	// The first page is the document's picture.
+	if pages := doc.Pages(); len(pages) > 0 {
+		if err = d.setPDFPicture(pages[0].Thumbnail()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setPDFPicture sets the drop's image and thumbnail from
+// a PDF page image.
+func (d *Drop) setPDFPicture(im image.Image) error {
+	if im == nil {
+		return nil
+	}
+
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, im); err != nil {
+		return err
+	}
+
+	p := &Picture{Href: d.URL.String(), Type: "image/png", bytes: buf.Bytes()}
+
+	picture, err := p.Copy(800, "")
+	if err != nil {
+		return err
+	}
+	thumbnail, err := p.Copy(380, "")
+	if err != nil {
+		return err
+	}
+
+	d.Pictures["image"] = picture
+	d.Pictures["thumbnail"] = thumbnail
+	return nil
+}
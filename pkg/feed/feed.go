@@ -0,0 +1,242 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package feed provides a minimal RSS and Atom feed parser.
+// It only retrieves what's needed to follow a feed's entries:
+// the feed title and link, and, for every entry, its ID, link,
+// title and publication date.
+package feed
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/araddon/dateparse"
+	"golang.org/x/net/html/charset"
+)
+
+// ErrUnknownFormat is returned when the document is neither
+// an RSS nor an Atom feed.
+var ErrUnknownFormat = errors.New("unknown feed format")
+
+// Feed is a parsed feed.
+type Feed struct {
+	Title string
+	Link  string
+	Items []Item
+}
+
+// Item is a feed entry.
+type Item struct {
+	ID        string
+	Link      string
+	Title     string
+	Published time.Time
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+}
+
+type atomFeed struct {
+	Title   string      `xml:"title"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	GUID    string `xml:"guid"`
+	Title   string `xml:"title"`
+	Link    string `xml:"link"`
+	About   string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	PubDate string `xml:"pubDate"`
+	Date    string `xml:"http://purl.org/dc/elements/1.1/ date"`
+}
+
+type rssChannel struct {
+	Title string    `xml:"title"`
+	Link  string    `xml:"link"`
+	Items []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	Channel rssChannel `xml:"channel"`
+	// RSS 1.0 (RDF) has its items outside of the channel element.
+	Items []rssItem `xml:"item"`
+}
+
+// Parse reads an RSS (0.9x, 1.0, 2.0) or Atom document
+// and returns a [Feed]. Relative links are resolved against base,
+// which can be nil.
+func Parse(r io.Reader, base *url.URL) (*Feed, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charset.NewReaderLabel
+	dec.Strict = false
+
+	// Find the root element
+	var root xml.StartElement
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, ErrUnknownFormat
+			}
+			return nil, err
+		}
+		if s, ok := t.(xml.StartElement); ok {
+			root = s
+			break
+		}
+	}
+
+	var res *Feed
+	switch strings.ToLower(root.Name.Local) {
+	case "feed":
+		var f atomFeed
+		if err := dec.DecodeElement(&f, &root); err != nil {
+			return nil, err
+		}
+		res = f.toFeed()
+	case "rss", "rdf":
+		var f rssFeed
+		if err := dec.DecodeElement(&f, &root); err != nil {
+			return nil, err
+		}
+		res = f.toFeed()
+	default:
+		return nil, ErrUnknownFormat
+	}
+
+	res.resolve(base)
+	return res, nil
+}
+
+func (f atomFeed) toFeed() *Feed {
+	res := &Feed{
+		Title: strings.TrimSpace(f.Title),
+		Link:  atomAlternate(f.Links),
+		Items: make([]Item, 0, len(f.Entries)),
+	}
+
+	for _, e := range f.Entries {
+		item := Item{
+			ID:    strings.TrimSpace(e.ID),
+			Link:  atomAlternate(e.Links),
+			Title: strings.TrimSpace(e.Title),
+		}
+		item.Published = parseDate(e.Published)
+		if item.Published.IsZero() {
+			item.Published = parseDate(e.Updated)
+		}
+		res.Items = append(res.Items, item)
+	}
+
+	return res
+}
+
+func (f rssFeed) toFeed() *Feed {
+	items := f.Channel.Items
+	if len(items) == 0 {
+		items = f.Items
+	}
+
+	res := &Feed{
+		Title: strings.TrimSpace(f.Channel.Title),
+		Link:  strings.TrimSpace(f.Channel.Link),
+		Items: make([]Item, 0, len(items)),
+	}
+
+	for _, e := range items {
+		item := Item{
+			ID:    strings.TrimSpace(e.GUID),
+			Link:  strings.TrimSpace(e.Link),
+			Title: strings.TrimSpace(e.Title),
+		}
+		if item.ID == "" {
+			item.ID = strings.TrimSpace(e.About)
+		}
+		item.Published = parseDate(e.PubDate)
+		if item.Published.IsZero() {
+			item.Published = parseDate(e.Date)
+		}
+		res.Items = append(res.Items, item)
+	}
+
+	return res
+}
+
+// resolve makes every link absolute and sets the missing
+// item IDs to their link. Items without a link are removed.
+func (f *Feed) resolve(base *url.URL) {
+	f.Link = resolveURL(base, f.Link)
+
+	items := f.Items[:0]
+	for _, item := range f.Items {
+		item.Link = resolveURL(base, item.Link)
+		if item.Link == "" {
+			continue
+		}
+		if item.ID == "" {
+			item.ID = item.Link
+		}
+		items = append(items, item)
+	}
+	f.Items = items
+}
+
+// atomAlternate returns the first alternate link of a list of atom links.
+func atomAlternate(links []atomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return strings.TrimSpace(l.Href)
+		}
+	}
+	return ""
+}
+
+func resolveURL(base *url.URL, s string) string {
+	if s == "" {
+		return ""
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	if d, err := time.Parse(time.RFC3339, s); err == nil {
+		return d
+	}
+	if d, err := time.Parse(time.RFC1123Z, s); err == nil {
+		return d
+	}
+	d, _ := dateparse.ParseAny(s)
+	return d
+}
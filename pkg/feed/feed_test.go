@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package feed_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/feed"
+)
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://example.net/feed.xml")
+
+	tests := []struct {
+		name     string
+		src      string
+		expected *feed.Feed
+		err      string
+	}{
+		{
+			"rss2",
+			`<?xml version="1.0" encoding="utf-8"?>
+			<rss version="2.0"><channel>
+				<title> Example </title>
+				<link>https://example.net/</link>
+				<item>
+					<title>Post 1</title>
+					<link>https://example.net/post-1</link>
+					<guid isPermaLink="false">post-1</guid>
+					<pubDate>Mon, 06 Jan 2025 10:00:00 +0000</pubDate>
+				</item>
+				<item>
+					<title>Post 2</title>
+					<link>/post-2</link>
+				</item>
+				<item>
+					<title>No link</title>
+				</item>
+			</channel></rss>`,
+			&feed.Feed{
+				Title: "Example",
+				Link:  "https://example.net/",
+				Items: []feed.Item{
+					{
+						ID: "post-1", Link: "https://example.net/post-1", Title: "Post 1",
+						Published: time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC),
+					},
+					{ID: "https://example.net/post-2", Link: "https://example.net/post-2", Title: "Post 2"},
+				},
+			},
+			"",
+		},
+		{
+			"rss1",
+			`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+				xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+				<channel><title>RDF</title><link>https://example.net/</link></channel>
+				<item rdf:about="https://example.net/a">
+					<title>A</title>
+					<link>https://example.net/a</link>
+					<dc:date>2025-01-06T10:00:00Z</dc:date>
+				</item>
+			</rdf:RDF>`,
+			&feed.Feed{
+				Title: "RDF",
+				Link:  "https://example.net/",
+				Items: []feed.Item{
+					{
+						ID: "https://example.net/a", Link: "https://example.net/a", Title: "A",
+						Published: time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			"",
+		},
+		{
+			"atom",
+			`<feed xmlns="http://www.w3.org/2005/Atom">
+				<title>Atom</title>
+				<link rel="self" href="https://example.net/feed.xml" />
+				<link href="https://example.net/" />
+				<entry>
+					<id>urn:uuid:1</id>
+					<title>Entry 1</title>
+					<link rel="alternate" href="entry-1" />
+					<updated>2025-01-06T10:00:00Z</updated>
+				</entry>
+			</feed>`,
+			&feed.Feed{
+				Title: "Atom",
+				Link:  "https://example.net/",
+				Items: []feed.Item{
+					{
+						ID: "urn:uuid:1", Link: "https://example.net/entry-1", Title: "Entry 1",
+						Published: time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			"",
+		},
+		{
+			"html",
+			`<html><body></body></html>`,
+			nil,
+			"unknown feed format",
+		},
+		{
+			"empty",
+			``,
+			nil,
+			"unknown feed format",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+			f, err := feed.Parse(strings.NewReader(test.src), base)
+			if test.err != "" {
+				assert.EqualError(err, test.err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(test.expected.Title, f.Title)
+			assert.Equal(test.expected.Link, f.Link)
+			assert.Len(f.Items, len(test.expected.Items))
+			for i, item := range test.expected.Items {
+				assert.Equal(item.ID, f.Items[i].ID)
+				assert.Equal(item.Link, f.Items[i].Link)
+				assert.Equal(item.Title, f.Items[i].Title)
+				assert.True(item.Published.Equal(f.Items[i].Published))
+			}
+		})
+	}
+}
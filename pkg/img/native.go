@@ -20,7 +20,6 @@ import (
 	_ "golang.org/x/image/tiff"                  // TIFF decoder
 	_ "golang.org/x/image/webp"                  // WEBP decoder
 
-	"github.com/anthonynsimon/bild/effect"
 	"github.com/anthonynsimon/bild/transform"
 )
 
@@ -133,8 +132,13 @@ func (im *NativeImage) Resize(w, h uint) error {
 }
 
 // Grayscale transforms the image to a grayscale version.
+// Transparent areas are flattened on a white background.
 func (im *NativeImage) Grayscale() error {
-	im.m = effect.Grayscale(im.m)
+	b := im.m.Bounds()
+	m := image.NewGray(b)
+	draw.Draw(m, b, image.White, image.Point{}, draw.Src)
+	draw.Draw(m, b, im.m, b.Min, draw.Over)
+	im.m = m
 	return nil
 }
 
@@ -5,14 +5,18 @@
 package img
 
 import (
+	"errors"
 	"fmt"
 	"image"
+	"image/draw"
 	"io"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/antchfx/xmlquery"
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
 )
 
 func init() {
@@ -26,10 +30,14 @@ func init() {
 
 var reViewBox = regexp.MustCompile(`[\s,]+`)
 
+// svgMaxPixels is the maximum number of pixels of a rasterized image.
+const svgMaxPixels = 30000000
+
 // SvgImage is the Image implementation for SVG images.
 type SvgImage struct {
 	node   *xmlquery.Node
 	bounds image.Rectangle
+	orig   image.Rectangle
 }
 
 // NewSvgImage returns an SvgImage instance.
@@ -39,9 +47,11 @@ func NewSvgImage(r io.Reader) (*SvgImage, error) {
 		return nil, err
 	}
 
+	bounds := getSVGDimensions(node)
 	return &SvgImage{
 		node:   node,
-		bounds: getSVGDimensions(node),
+		bounds: bounds,
+		orig:   bounds,
 	}, nil
 }
 
@@ -118,6 +128,59 @@ func (im *SvgImage) Encode(w io.Writer) error {
 	return err
 }
 
+// Rasterize renders the SVG image, at its current size, to a bitmap
+// image on a white background. Its encoding format is PNG.
+// Only the shapes, paths and gradients are rendered, the text
+// elements are ignored.
+// It fails when the image is bigger than 30Mpx.
+func (im *SvgImage) Rasterize() (*NativeImage, error) {
+	w, h := int(im.Width()), int(im.Height())
+	if w <= 0 || h <= 0 {
+		return nil, errors.New("empty image")
+	}
+	if w > svgMaxPixels/h {
+		return nil, errors.New("image is too big")
+	}
+
+	node := xmlquery.FindOne(im.node, "/svg")
+	if node == nil {
+		return nil, errors.New("no svg element")
+	}
+
+	// The renderer only needs a viewBox. Dimensions like
+	// "100%" or "12em" would make it fail.
+	if node.SelectAttr("viewBox") == "" {
+		node.SetAttr("viewBox", fmt.Sprintf("0 0 %d %d", im.orig.Dx(), im.orig.Dy()))
+		defer node.RemoveAttr("viewBox")
+	}
+	node.RemoveAttr("width")
+	node.RemoveAttr("height")
+	defer func() {
+		node.SetAttr("width", strconv.Itoa(w))
+		node.SetAttr("height", strconv.Itoa(h))
+	}()
+
+	icon, err := oksvg.ReadIconStream(strings.NewReader(im.node.OutputXMLWithOptions(
+		xmlquery.WithOutputSelf(),
+	)), oksvg.IgnoreErrorMode)
+	if err != nil {
+		return nil, err
+	}
+	icon.SetTarget(0, 0, float64(w), float64(h))
+
+	m := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(m, m.Bounds(), image.White, image.Point{}, draw.Src)
+	scanner := rasterx.NewScannerGV(w, h, m, m.Bounds())
+	icon.Draw(rasterx.NewDasher(w, h, scanner), 1)
+
+	return &NativeImage{
+		m:           m,
+		format:      "png",
+		compression: CompressionFast,
+		quality:     80,
+	}, nil
+}
+
 // Clean sanitizes the SVG image by keeping only a specific set of tags and attributes.
 func (im *SvgImage) Clean() error {
 	for _, node := range xmlquery.Find(im.node, "//*") {
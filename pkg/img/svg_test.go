@@ -6,6 +6,7 @@ package img_test
 
 import (
 	"bytes"
+	"image"
 	"regexp"
 	"strconv"
 	"strings"
@@ -224,6 +225,76 @@ func TestSvgImage(t *testing.T) {
 	})
 }
 
+func TestSvgRasterize(t *testing.T) {
+	tests := []struct {
+		name string
+		svg  string
+		w, h uint
+	}{
+		{
+			"viewbox",
+			`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 40 20">
+			  <rect x="20" y="0" width="20" height="20" fill="#000"/>
+			</svg>`,
+			40, 20,
+		},
+		{
+			"relative dimensions",
+			`<svg xmlns="http://www.w3.org/2000/svg" width="4em" height="2em" viewBox="0 0 40 20">
+			  <rect x="20" y="0" width="20" height="20" fill="#000"/>
+			  <text x="0" y="10">ignored</text>
+			</svg>`,
+			40, 20,
+		},
+		{
+			"resized",
+			`<svg xmlns="http://www.w3.org/2000/svg" width="400" height="200">
+			  <rect x="200" y="0" width="200" height="200" fill="#000"/>
+			</svg>`,
+			40, 20,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+			im, err := img.NewSvgImage(strings.NewReader(test.svg))
+			assert.NoError(err)
+			assert.NoError(img.Fit(im, test.w, test.h))
+
+			ri, err := im.Rasterize()
+			assert.NoError(err)
+			assert.Equal(test.w, ri.Width())
+			assert.Equal(test.h, ri.Height())
+
+			// White background on the left, black rectangle on the right
+			m := ri.Image()
+			r, g, b, a := m.At(5, 10).RGBA()
+			assert.Equal([]uint32{0xffff, 0xffff, 0xffff, 0xffff}, []uint32{r, g, b, a})
+			r, g, b, a = m.At(30, 10).RGBA()
+			assert.Equal([]uint32{0, 0, 0, 0xffff}, []uint32{r, g, b, a})
+
+			buf := new(bytes.Buffer)
+			assert.NoError(ri.Encode(buf))
+			_, format, err := image.DecodeConfig(buf)
+			assert.NoError(err)
+			assert.Equal("png", format)
+		})
+	}
+}
+
+func TestSvgRasterizeTooBig(t *testing.T) {
+	assert := require.New(t)
+	im, err := img.NewSvgImage(strings.NewReader(
+		`<svg xmlns="http://www.w3.org/2000/svg" width="100000" height="100000"></svg>`,
+	))
+	assert.NoError(err)
+
+	ri, err := im.Rasterize()
+	assert.Nil(ri)
+	assert.EqualError(err, "image is too big")
+}
+
 func TestClean(t *testing.T) {
 	tests := []struct {
 		svg      string
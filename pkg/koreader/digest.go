@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package koreader provides tools to share documents and reading
+// positions with KOReader.
+package koreader
+
+import (
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"hash"
+	"path"
+)
+
+const (
+	sampleSize  = 1024
+	sampleCount = 12
+)
+
+// sampleOffset returns the offset of the i-th sample of a [PartialMD5].
+func sampleOffset(i int) int64 {
+	if i == 0 {
+		return 0
+	}
+	return sampleSize << (2 * (i - 1))
+}
+
+// PartialMD5 computes the document digest KOReader uses to identify a file
+// during a progress synchronization. It's the MD5 sum of 1KB samples taken
+// at the offsets 0, 1K, 4K, 16K, and so on until 1G or the end of the file.
+//
+// PartialMD5 is an [io.Writer] so the digest is computed while the file
+// is written. Sum must be called once all the file was written.
+type PartialMD5 struct {
+	h      hash.Hash
+	offset int64
+	sample int
+	buf    []byte
+}
+
+// NewPartialMD5 returns a new [PartialMD5].
+func NewPartialMD5() *PartialMD5 {
+	return &PartialMD5{
+		h:   md5.New(), //nolint:gosec
+		buf: make([]byte, 0, sampleSize),
+	}
+}
+
+// Write implements [io.Writer].
+func (d *PartialMD5) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 && d.sample < sampleCount {
+		start := sampleOffset(d.sample)
+		end := start + sampleSize
+		if d.offset+int64(len(p)) <= start {
+			break
+		}
+		if d.offset < start {
+			p = p[start-d.offset:]
+			d.offset = start
+		}
+
+		k := min(int64(len(p)), end-d.offset)
+		d.buf = append(d.buf, p[:k]...)
+		p = p[k:]
+		d.offset += k
+
+		if d.offset == end {
+			d.h.Write(d.buf) //nolint:errcheck
+			d.buf = d.buf[:0]
+			d.sample++
+		}
+	}
+	d.offset += int64(len(p))
+	return n, nil
+}
+
+// Sum returns the hex encoded digest.
+func (d *PartialMD5) Sum() string {
+	if len(d.buf) > 0 {
+		// The file ends in the middle of a sample
+		d.h.Write(d.buf) //nolint:errcheck
+		d.buf = d.buf[:0]
+		d.sample = sampleCount
+	}
+	return hex.EncodeToString(d.h.Sum(nil))
+}
+
+// FilenameMD5 returns the document digest KOReader uses when it's
+// configured to identify files by their name.
+func FilenameMD5(name string) string {
+	h := md5.Sum([]byte(path.Base(name))) //nolint:gosec
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package koreader_test
+
+import (
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+
+	"codeberg.org/readeck/readeck/pkg/koreader"
+)
+
+// partialMD5 is KOReader's implementation, reading samples of a file.
+func partialMD5(data []byte) string {
+	h := md5.New() //nolint:gosec
+	for i := -1; i <= 10; i++ {
+		offset := 0
+		if i >= 0 {
+			offset = 1024 << (2 * i)
+		}
+		if offset >= len(data) {
+			break
+		}
+		h.Write(data[offset:min(offset+1024, len(data))])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestPartialMD5(t *testing.T) {
+	for _, size := range []int{0, 10, 1024, 1500, 2048, 4096, 5000, 70000, 300000} {
+		for _, chunk := range []int{1, 100, 1024, 4000, 1 << 20} {
+			t.Run(strconv.Itoa(size)+"/"+strconv.Itoa(chunk), func(t *testing.T) {
+				data := make([]byte, size)
+				for i := range data {
+					data[i] = byte(i * 7 % 251)
+				}
+
+				d := koreader.NewPartialMD5()
+				for i := 0; i < size; i += chunk {
+					n, err := d.Write(data[i:min(i+chunk, size)])
+					require.NoError(t, err)
+					require.Equal(t, min(chunk, size-i), n)
+				}
+				require.Equal(t, partialMD5(data), d.Sum())
+			})
+		}
+	}
+}
+
+func TestFilenameMD5(t *testing.T) {
+	h := md5.Sum([]byte("2025-01-02-some-article.epub")) //nolint:gosec
+	require.Equal(t,
+		hex.EncodeToString(h[:]),
+		koreader.FilenameMD5("/mnt/onboard/books/2025-01-02-some-article.epub"),
+	)
+}
+
+func TestPositions(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<section>
+		<h2>Title</h2>
+		<p>one</p>
+		<div><p>two</p></div>
+		<p>three</p>
+		<ul><li>a</li><li>b</li></ul>
+		<p>four <em>emphasis</em></p>
+	</section>
+	<footer><p>end</p></footer>`))
+	require.NoError(t, err)
+
+	// html > head, body
+	root := doc.FirstChild.LastChild
+	require.Equal(t, "body", root.Data)
+
+	base := "/body/DocFragment[1]/body/main"
+
+	t.Run("to selector", func(t *testing.T) {
+		tests := []struct {
+			xpointer string
+			expected string
+		}{
+			{"/body/DocFragment/body/main/section/p[3]/text().4", "section:nth-child(1) > p:nth-child(6)"},
+			{"/body/DocFragment[1]/body/main/section/p[3]", "section:nth-child(1) > p:nth-child(6)"},
+			{"/body/DocFragment/body/main/section/div/p/text().0", "section:nth-child(1) > div:nth-child(3) > p:nth-child(1)"},
+			{"/body/DocFragment/body/main/section/ul/li[2].1", "section:nth-child(1) > ul:nth-child(5) > li:nth-child(2)"},
+			{"/body/DocFragment/body/main/footer/p", "footer:nth-child(2) > p:nth-child(1)"},
+			{"/body/DocFragment/body/main/section/p[12]", ""},
+			{"/body/DocFragment/body/main", ""},
+			{"/body/DocFragment[2]/body/main/section", ""},
+			{"/body/DocFragment/body/h1/text().0", ""},
+			{"", ""},
+		}
+
+		for i, test := range tests {
+			t.Run(strconv.Itoa(i), func(t *testing.T) {
+				require.Equal(t, test.expected, koreader.ToSelector(root, base, test.xpointer))
+			})
+		}
+	})
+
+	t.Run("to xpointer", func(t *testing.T) {
+		tests := []struct {
+			selector string
+			expected string
+		}{
+			{"section:nth-child(1) > p:nth-child(6)", base + "/section[1]/p[3]"},
+			{"section:nth-child(1) > div:nth-child(3) > p:nth-child(1)", base + "/section[1]/div[1]/p[1]"},
+			{"section:nth-child(1)>ul:nth-child(5)>li:nth-child(2)", base + "/section[1]/ul[1]/li[2]"},
+			{"footer:nth-child(2) > p:nth-child(1)", base + "/footer[1]/p[1]"},
+			{"section:nth-child(1) > p:nth-child(3)", base},
+			{"section:nth-child(1) > p:nth-child(50)", base},
+			{"section > p", base},
+			{"", base},
+		}
+
+		for i, test := range tests {
+			t.Run(strconv.Itoa(i), func(t *testing.T) {
+				require.Equal(t, test.expected, koreader.ToXPointer(root, base, test.selector))
+			})
+		}
+	})
+}
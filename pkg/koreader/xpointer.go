@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package koreader
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// KOReader gives the reading position in reflowable documents as an
+// XPointer, like "/body/DocFragment[2]/body/div/p[4]/text().12".
+// Each step is an element name with its position among the siblings
+// having the same name. The position is omitted when it's the only one.
+//
+// The positions in Readeck's reader are CSS selectors like
+// "section:nth-child(1) > p:nth-child(5)", where the position counts
+// all the element siblings.
+//
+// Both paths are converted with the help of the HTML document they refer to.
+
+var rxNthChild = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9-]*):nth-child\((\d+)\)$`)
+
+type step struct {
+	name  string
+	index int
+}
+
+func (s step) String() string {
+	return fmt.Sprintf("%s[%d]", s.name, s.index)
+}
+
+// parseXPointer returns the element steps of an XPointer.
+// The text node and offset are left out.
+func parseXPointer(xp string) []step {
+	res := []step{}
+	for _, p := range strings.Split(xp, "/") {
+		if p == "" {
+			continue
+		}
+		if strings.HasPrefix(p, "text()") {
+			break
+		}
+		// An element can have an offset
+		if i := strings.LastIndex(p, "."); i > 0 {
+			if _, err := strconv.Atoi(p[i+1:]); err == nil {
+				p = p[:i]
+			}
+		}
+
+		s := step{name: p, index: 1}
+		if i := strings.Index(p, "["); i > 0 && strings.HasSuffix(p, "]") {
+			n, err := strconv.Atoi(p[i+1 : len(p)-1])
+			if err != nil {
+				return nil
+			}
+			s = step{name: p[:i], index: n}
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+// elementChildren returns the element children of a node.
+func elementChildren(n *html.Node) []*html.Node {
+	res := []*html.Node{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// ToSelector converts an XPointer to a CSS selector relative to root.
+// base is the XPointer of root. It returns an empty string when the
+// XPointer is not in root or doesn't match any element.
+func ToSelector(root *html.Node, base, xpointer string) string {
+	baseSteps := parseXPointer(base)
+	steps := parseXPointer(xpointer)
+	if len(steps) <= len(baseSteps) {
+		return ""
+	}
+	for i, s := range baseSteps {
+		if steps[i] != s {
+			return ""
+		}
+	}
+
+	parts := []string{}
+	node := root
+	for _, s := range steps[len(baseSteps):] {
+		var next *html.Node
+		count := 0
+		for i, c := range elementChildren(node) {
+			if c.Data != s.name {
+				continue
+			}
+			count++
+			if count == s.index {
+				next = c
+				parts = append(parts, fmt.Sprintf("%s:nth-child(%d)", c.Data, i+1))
+				break
+			}
+		}
+		if next == nil {
+			return ""
+		}
+		node = next
+	}
+
+	return strings.Join(parts, " > ")
+}
+
+// ToXPointer converts a CSS selector relative to root to an XPointer.
+// base is the XPointer of root. The selector is a list of "name:nth-child(n)"
+// parts separated by ">". It returns base when the selector doesn't match
+// any element.
+func ToXPointer(root *html.Node, base, selector string) string {
+	steps := []step{}
+	node := root
+	for _, p := range strings.Split(selector, ">") {
+		m := rxNthChild.FindStringSubmatch(strings.TrimSpace(p))
+		if m == nil {
+			return base
+		}
+		n, _ := strconv.Atoi(m[2])
+		children := elementChildren(node)
+		if n < 1 || n > len(children) || children[n-1].Data != strings.ToLower(m[1]) {
+			return base
+		}
+
+		node = children[n-1]
+		s := step{name: node.Data}
+		for _, c := range children[:n] {
+			if c.Data == node.Data {
+				s.index++
+			}
+		}
+		steps = append(steps, s)
+	}
+
+	res := strings.TrimSuffix(base, "/")
+	for _, s := range steps {
+		res += "/" + s.String()
+	}
+	return res
+}
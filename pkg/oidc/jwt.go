@@ -0,0 +1,286 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package oidc
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// clockSkew is the tolerance applied on the token dates.
+const clockSkew = time.Minute
+
+// Claims contains an ID token or a userinfo response claims.
+type Claims map[string]any
+
+// String returns a claim's string value or an empty string
+// when the claim does not exist or is not a string.
+func (c Claims) String(name string) string {
+	if v, ok := c[name].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// Strings returns a claim's values. A single string claim
+// is returned as a slice of one item.
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		res := []string{}
+		for _, x := range v {
+			if s, ok := x.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// Bool returns a claim's boolean value and whether the claim exists.
+func (c Claims) Bool(name string) (value bool, ok bool) {
+	switch v := c[name].(type) {
+	case bool:
+		return v, true
+	case string:
+		// Some providers send "true" or "false" strings
+		return v == "true", v == "true" || v == "false"
+	}
+	return false, false
+}
+
+// Time returns a numeric date claim as a [time.Time].
+func (c Claims) Time(name string) time.Time {
+	switch v := c[name].(type) {
+	case float64:
+		return time.Unix(int64(v), 0)
+	case json.Number:
+		i, _ := v.Int64()
+		return time.Unix(i, 0)
+	}
+	return time.Time{}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type keySet map[string]crypto.PublicKey
+
+// verifySignature checks the token's signature and returns its claims.
+func (c *Client) verifySignature(ctx context.Context, m *Metadata, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	header := jwtHeader{}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	key, err := c.getKey(ctx, m, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = verify(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	claims := Claims{}
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// getKey returns the provider's key with the given ID. The key set
+// is loaded again when the key is not found, in case of a key rotation.
+func (c *Client) getKey(ctx context.Context, m *Metadata, kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	keys := c.keys
+	c.mu.Unlock()
+
+	if k := keys.find(kid); k != nil {
+		return k, nil
+	}
+
+	doc := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	if err := c.getJSON(ctx, m.JWKSURI, "", &doc); err != nil {
+		return nil, fmt.Errorf("key set: %w", err)
+	}
+
+	keys = keySet{}
+	for _, x := range doc.Keys {
+		if x.Use != "" && x.Use != "sig" {
+			continue
+		}
+		if k, err := x.publicKey(); err == nil {
+			keys[x.Kid] = k
+		}
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.mu.Unlock()
+
+	if k := keys.find(kid); k != nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+}
+
+// find returns the key with the given ID. When the token has no
+// key ID, the set's key is returned only when there's just one.
+func (ks keySet) find(kid string) crypto.PublicKey {
+	if kid == "" && len(ks) == 1 {
+		for _, k := range ks {
+			return k
+		}
+	}
+	return ks[kid]
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// verify checks a signature with the given algorithm and key.
+func verify(alg string, key crypto.PublicKey, data, sig []byte) error {
+	var h crypto.Hash
+	switch alg {
+	case "RS256", "ES256", "PS256":
+		h = crypto.SHA256
+	case "RS384", "ES384", "PS384":
+		h = crypto.SHA384
+	case "RS512", "ES512", "PS512":
+		h = crypto.SHA512
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, alg)
+	}
+
+	hh := h.New()
+	hh.Write(data)
+	digest := hh.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		var err error
+		switch alg[0] {
+		case 'R':
+			err = rsa.VerifyPKCS1v15(k, h, digest, sig)
+		case 'P':
+			err = rsa.VerifyPSS(k, h, digest, sig, nil)
+		default:
+			err = fmt.Errorf("algorithm %q with an RSA key", alg)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if alg[0] != 'E' || len(sig) != 2*size {
+			return fmt.Errorf("%w: invalid signature", ErrInvalidToken)
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return fmt.Errorf("%w: invalid signature", ErrInvalidToken)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%w: unsupported key", ErrInvalidToken)
+}
+
+func decodeSegment(s string, dest any) error {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err = dec.Decode(dest); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,282 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package oidc implements an OpenID Connect relying party for the
+// authorization code flow, with PKCE.
+//
+// It covers the provider discovery, the authorization URL, the code
+// exchange and the ID token verification. Only asymmetric signatures
+// (RSA and ECDSA) are supported.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+var (
+	// ErrInvalidToken is returned when an ID token could not be verified.
+	ErrInvalidToken = errors.New("invalid ID token")
+
+	// ErrInvalidResponse is returned when the provider sends an unexpected
+	// response.
+	ErrInvalidResponse = errors.New("invalid provider response")
+)
+
+// Metadata contains the provider's metadata, as returned
+// by the discovery endpoint.
+type Metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	UserinfoEndpoint      string   `json:"userinfo_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	SigningAlgs           []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// Token is the token endpoint response.
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Client is an OpenID Connect client. The provider metadata
+// and signing keys are retrieved on first use and kept for the
+// client's lifetime.
+type Client struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	HTTPClient   *http.Client
+
+	mu       sync.Mutex
+	metadata *Metadata
+	keys     keySet
+}
+
+// NewClient returns a new [Client].
+func NewClient(issuer, clientID, clientSecret string, scopes []string) *Client {
+	if !slices.Contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	return &Client{
+		Issuer:       strings.TrimSuffix(issuer, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+		HTTPClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Metadata returns the provider's metadata. It performs the
+// discovery request on the first call.
+func (c *Client) Metadata(ctx context.Context) (*Metadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.metadata != nil {
+		return c.metadata, nil
+	}
+
+	m := new(Metadata)
+	if err := c.getJSON(ctx, c.Issuer+discoveryPath, "", m); err != nil {
+		return nil, fmt.Errorf("provider discovery: %w", err)
+	}
+
+	if strings.TrimSuffix(m.Issuer, "/") != c.Issuer {
+		return nil, fmt.Errorf("%w: issuer mismatch (%s)", ErrInvalidResponse, m.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete provider metadata", ErrInvalidResponse)
+	}
+
+	c.metadata = m
+	return m, nil
+}
+
+// AuthCodeURL returns the URL of the provider's authorization endpoint.
+// The state and nonce must be random values, kept by the caller to
+// check the callback request and the ID token. The verifier is the PKCE
+// code verifier, only its challenge is sent.
+func (c *Client) AuthCodeURL(ctx context.Context, redirectURI, state, nonce, verifier string) (string, error) {
+	m, err := c.Metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(m.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", c.ClientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("scope", strings.Join(c.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", Challenge(verifier))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// Exchange exchanges an authorization code for a token.
+func (c *Client) Exchange(ctx context.Context, code, redirectURI, verifier string) (*Token, error) {
+	m, err := c.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	t := new(Token)
+	if err = c.doJSON(req, t); err != nil {
+		return nil, fmt.Errorf("token exchange: %w", err)
+	}
+	if t.IDToken == "" {
+		return nil, fmt.Errorf("%w: no ID token", ErrInvalidResponse)
+	}
+
+	return t, nil
+}
+
+// Verify checks the ID token's signature and its claims, then
+// returns the claims. The nonce must be the one sent in the
+// authorization request.
+func (c *Client) Verify(ctx context.Context, idToken, nonce string) (Claims, error) {
+	m, err := c.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := c.verifySignature(ctx, m, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	switch {
+	case strings.TrimSuffix(claims.String("iss"), "/") != c.Issuer:
+		return nil, fmt.Errorf("%w: issuer mismatch", ErrInvalidToken)
+	case !slices.Contains(claims.Strings("aud"), c.ClientID):
+		return nil, fmt.Errorf("%w: audience mismatch", ErrInvalidToken)
+	case claims.String("azp") != "" && claims.String("azp") != c.ClientID:
+		return nil, fmt.Errorf("%w: authorized party mismatch", ErrInvalidToken)
+	case claims.String("sub") == "":
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	case claims.String("nonce") != nonce:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	case now.Add(-clockSkew).After(claims.Time("exp")):
+		return nil, fmt.Errorf("%w: token is expired", ErrInvalidToken)
+	case now.Add(clockSkew).Before(claims.Time("iat")):
+		return nil, fmt.Errorf("%w: token is issued in the future", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// Userinfo retrieves the user's claims from the userinfo endpoint.
+// It returns nil when the provider has no userinfo endpoint.
+func (c *Client) Userinfo(ctx context.Context, accessToken string) (Claims, error) {
+	m, err := c.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if m.UserinfoEndpoint == "" {
+		return nil, nil
+	}
+
+	claims := Claims{}
+	if err = c.getJSON(ctx, m.UserinfoEndpoint, accessToken, &claims); err != nil {
+		return nil, fmt.Errorf("userinfo: %w", err)
+	}
+	return claims, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, uri, accessToken string, dest any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	return c.doJSON(req, dest)
+}
+
+func (c *Client) doJSON(req *http.Request, dest any) error {
+	rsp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close() //nolint:errcheck
+
+	body := io.LimitReader(rsp.Body, 1<<20)
+	if rsp.StatusCode != http.StatusOK {
+		// Try to get an OAuth2 error message
+		e := struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}{}
+		if json.NewDecoder(body).Decode(&e) == nil && e.Error != "" {
+			return fmt.Errorf("%w: %s (%s)", ErrInvalidResponse, e.Error, e.Description)
+		}
+		return fmt.Errorf("%w: status code %d", ErrInvalidResponse, rsp.StatusCode)
+	}
+
+	if err = json.NewDecoder(body).Decode(dest); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+	return nil
+}
+
+// RandomString returns a random URL safe string that can be used
+// as a state, a nonce or a PKCE code verifier.
+func RandomString() string {
+	b := make([]byte, 32)
+	rand.Read(b) //nolint:errcheck
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Challenge returns the PKCE S256 code challenge of a verifier.
+func Challenge(verifier string) string {
+	h := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
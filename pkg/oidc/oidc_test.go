@@ -0,0 +1,175 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/oidc"
+	"codeberg.org/readeck/readeck/pkg/oidc/oidctest"
+)
+
+func TestChallenge(t *testing.T) {
+	// RFC 7636, appendix B
+	require.Equal(t,
+		"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		oidc.Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"),
+	)
+}
+
+func TestClaims(t *testing.T) {
+	claims := oidc.Claims{
+		"sub":    "abc",
+		"groups": []any{"a", "b", 1},
+		"role":   "admin",
+		"ok":     "true",
+		"exp":    float64(1700000000),
+	}
+
+	require.Equal(t, "abc", claims.String("sub"))
+	require.Equal(t, "", claims.String("exp"))
+	require.Equal(t, []string{"a", "b"}, claims.Strings("groups"))
+	require.Equal(t, []string{"admin"}, claims.Strings("role"))
+	require.Nil(t, claims.Strings("nope"))
+	require.Equal(t, time.Unix(1700000000, 0), claims.Time("exp"))
+
+	v, ok := claims.Bool("ok")
+	require.True(t, v)
+	require.True(t, ok)
+	_, ok = claims.Bool("sub")
+	require.False(t, ok)
+}
+
+func TestFlow(t *testing.T) {
+	idp := oidctest.NewServer("readeck", "secret")
+	defer idp.Close()
+	idp.Claims["email"] = "alice@example.org"
+
+	ctx := context.Background()
+	client := oidc.NewClient(idp.Issuer()+"/", "readeck", "secret", []string{"email"})
+	require.Equal(t, []string{"openid", "email"}, client.Scopes)
+
+	// Prevent redirects, we want the callback URL
+	httpClient := &http.Client{CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	authorize := func(t *testing.T, redirectURI, state, nonce, verifier string) string {
+		u, err := client.AuthCodeURL(ctx, redirectURI, state, nonce, verifier)
+		require.NoError(t, err)
+
+		rsp, err := httpClient.Get(u)
+		require.NoError(t, err)
+		rsp.Body.Close() //nolint:errcheck
+		require.Equal(t, http.StatusFound, rsp.StatusCode)
+
+		loc, _ := url.Parse(rsp.Header.Get("Location"))
+		require.Equal(t, state, loc.Query().Get("state"))
+		return loc.Query().Get("code")
+	}
+
+	t.Run("success", func(t *testing.T) {
+		nonce, verifier := oidc.RandomString(), oidc.RandomString()
+		code := authorize(t, "http://readeck/callback", "abc", nonce, verifier)
+
+		token, err := client.Exchange(ctx, code, "http://readeck/callback", verifier)
+		require.NoError(t, err)
+
+		claims, err := client.Verify(ctx, token.IDToken, nonce)
+		require.NoError(t, err)
+		require.Equal(t, "test-subject", claims.String("sub"))
+		require.Equal(t, "alice@example.org", claims.String("email"))
+
+		// No userinfo endpoint
+		info, err := client.Userinfo(ctx, token.AccessToken)
+		require.NoError(t, err)
+		require.Nil(t, info)
+	})
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		code := authorize(t, "http://readeck/callback", "abc", "nonce", oidc.RandomString())
+		_, err := client.Exchange(ctx, code, "http://readeck/callback", oidc.RandomString())
+		require.ErrorIs(t, err, oidc.ErrInvalidResponse)
+		require.ErrorContains(t, err, "invalid_grant")
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		verifier := oidc.RandomString()
+		code := authorize(t, "http://readeck/callback", "abc", "nonce", verifier)
+		token, err := client.Exchange(ctx, code, "http://readeck/callback", verifier)
+		require.NoError(t, err)
+
+		_, err = client.Verify(ctx, token.IDToken, "other")
+		require.ErrorIs(t, err, oidc.ErrInvalidToken)
+		require.ErrorContains(t, err, "nonce mismatch")
+	})
+
+	t.Run("tokens", func(t *testing.T) {
+		now := time.Now()
+		base := func() map[string]any {
+			return map[string]any{
+				"iss":   idp.Issuer(),
+				"aud":   []string{"readeck", "other"},
+				"sub":   "abc",
+				"iat":   now.Unix(),
+				"exp":   now.Add(time.Minute).Unix(),
+				"nonce": "n",
+			}
+		}
+
+		tests := []struct {
+			name   string
+			update func(map[string]any)
+			err    string
+		}{
+			{"valid", func(_ map[string]any) {}, ""},
+			{"issuer", func(c map[string]any) { c["iss"] = "https://example.org" }, "issuer mismatch"},
+			{"audience", func(c map[string]any) { c["aud"] = "other" }, "audience mismatch"},
+			{"azp", func(c map[string]any) { c["azp"] = "other" }, "authorized party mismatch"},
+			{"subject", func(c map[string]any) { delete(c, "sub") }, "no subject"},
+			{"expired", func(c map[string]any) { c["exp"] = now.Add(-time.Hour).Unix() }, "token is expired"},
+			{"future", func(c map[string]any) { c["iat"] = now.Add(time.Hour).Unix() }, "issued in the future"},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				c := base()
+				test.update(c)
+				claims, err := client.Verify(ctx, idp.Sign(c), "n")
+				if test.err == "" {
+					require.NoError(t, err)
+					require.Equal(t, "abc", claims.String("sub"))
+					return
+				}
+				require.ErrorIs(t, err, oidc.ErrInvalidToken)
+				require.ErrorContains(t, err, test.err)
+			})
+		}
+
+		t.Run("signature", func(t *testing.T) {
+			token := idp.Sign(base())
+			_, err := client.Verify(ctx, token[:len(token)-4]+"AAAA", "n")
+			require.ErrorIs(t, err, oidc.ErrInvalidToken)
+		})
+
+		t.Run("malformed", func(t *testing.T) {
+			_, err := client.Verify(ctx, "abc.def", "n")
+			require.True(t, errors.Is(err, oidc.ErrInvalidToken))
+		})
+	})
+
+	t.Run("discovery error", func(t *testing.T) {
+		c := oidc.NewClient(idp.Issuer()+"/nope", "readeck", "secret", nil)
+		_, err := c.Metadata(ctx)
+		require.ErrorIs(t, err, oidc.ErrInvalidResponse)
+	})
+}
@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package oidctest provides a minimal OpenID Connect provider
+// for testing purposes.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Server is a test OpenID Connect provider. Its authorization
+// endpoint immediately redirects to the client with a code
+// issuing an ID token containing the server's Claims.
+type Server struct {
+	*httptest.Server
+	Key      *rsa.PrivateKey
+	ClientID string
+	Secret   string
+
+	// Claims are added to every issued ID token.
+	Claims map[string]any
+
+	mu    sync.Mutex
+	codes map[string]codeInfo
+}
+
+type codeInfo struct {
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+// NewServer starts and returns a new [Server].
+func NewServer(clientID, secret string) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Server{
+		Key:      key,
+		ClientID: clientID,
+		Secret:   secret,
+		Claims:   map[string]any{},
+		codes:    map[string]codeInfo{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /authorize", s.authorize)
+	mux.HandleFunc("POST /token", s.token)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Issuer returns the server's issuer URL.
+func (s *Server) Issuer() string {
+	return s.URL
+}
+
+// Sign returns a signed RS256 JWT with the given claims.
+func (s *Server) Sign(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+
+	data := b64(header) + "." + b64(payload)
+	h := sha256.Sum256([]byte(data))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.Key, crypto.SHA256, h[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return data + "." + b64(sig)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                s.URL,
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != s.ClientID || q.Get("code_challenge_method") != "S256" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c := make([]byte, 16)
+	rand.Read(c) //nolint:errcheck
+	code := b64(c)
+	s.mu.Lock()
+	s.codes[code] = codeInfo{
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		redirectURI: q.Get("redirect_uri"),
+	}
+	s.mu.Unlock()
+
+	u, _ := url.Parse(q.Get("redirect_uri"))
+	uq := u.Query()
+	uq.Set("code", code)
+	uq.Set("state", q.Get("state"))
+	u.RawQuery = uq.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, _ := r.BasicAuth()
+	if id != s.ClientID || secret != s.Secret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	r.ParseForm() //nolint:errcheck
+	s.mu.Lock()
+	info, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	h := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || info.redirectURI != r.PostForm.Get("redirect_uri") || b64(h[:]) != info.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := map[string]any{
+		"iss":   s.URL,
+		"aud":   s.ClientID,
+		"sub":   "test-subject",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": info.nonce,
+	}
+	for k, v := range s.Claims {
+		claims[k] = v
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     s.Sign(claims),
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   b64(s.Key.N.Bytes()),
+			"e":   b64(big.NewInt(int64(s.Key.E)).Bytes()),
+		}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data) //nolint:errcheck
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -19,6 +19,10 @@ const (
 	OPDSTypeNavigation = "application/atom+xml; profile=opds-catalog; kind=navigation"
 	// OPDSTypeAcquisistion is the link type for acquisition.
 	OPDSTypeAcquisistion = "application/atom+xml; profile=opds-catalog; kind=acquisition"
+	// OpenSearchType is the link type for an OpenSearch description.
+	OpenSearchType = "application/opensearchdescription+xml"
+	// RelFacet is the link relation of a facet.
+	RelFacet = "http://opds-spec.org/facet"
 )
 
 // Feed root element for acquisition or navigation feed.
@@ -26,6 +30,8 @@ type Feed struct {
 	XMLName      xml.Name `xml:"feed"`
 	XMLns        string   `xml:"xmlns,attr"`
 	XMLnsDC      string   `xml:"xmlns:dc,attr"`
+	XMLnsOPDS    string   `xml:"xmlns:opds,attr"`
+	XMLnsOS      string   `xml:"xmlns:opensearch,attr"`
 	Lang         string   `xml:"xml:lang,attr"`
 	ID           UUID     `xml:"id"`
 	Title        string   `xml:"title"`
@@ -33,8 +39,9 @@ type Feed struct {
 	Updated      Time     `xml:"updated"`
 	Entries      []Entry  `xml:"entry"`
 	Links        []Link   `xml:"link"`
-	TotalResults int      `xml:"totalResults,omitempty"`
-	ItemsPerPage int      `xml:"itemsPerPage,omitempty"`
+	TotalResults int      `xml:"opensearch:totalResults,omitempty"`
+	ItemsPerPage int      `xml:"opensearch:itemsPerPage,omitempty"`
+	StartIndex   int      `xml:"opensearch:startIndex,omitempty"`
 	FeedType     string   `xml:"-"`
 }
 
@@ -44,7 +51,8 @@ type Link struct {
 	Href                string                `xml:"href,attr"`
 	TypeLink            string                `xml:"type,attr,omitempty"`
 	Title               string                `xml:"title,attr,omitempty"`
-	FacetGroup          string                `xml:"facetGroup,attr,omitempty"`
+	FacetGroup          string                `xml:"opds:facetGroup,attr,omitempty"`
+	ActiveFacet         bool                  `xml:"opds:activeFacet,attr,omitempty"`
 	Count               int                   `xml:"count,attr,omitempty"`
 	IndirectAcquisition []IndirectAcquisition `xml:"indirectAcquisition,omitempty"`
 }
@@ -165,7 +173,41 @@ func (f *Feed) Encode(w io.Writer) (err error) {
 	f.Lang = "en"
 	f.XMLns = "http://www.w3.org/2005/Atom"
 	f.XMLnsDC = "http://purl.org/dc/terms/"
+	f.XMLnsOPDS = "http://opds-spec.org/2010/catalog"
+	f.XMLnsOS = "http://a9.com/-/spec/opensearch/1.1/"
 
 	enc := xml.NewEncoder(w)
 	return enc.Encode(f)
 }
+
+// OpenSearchDescription describes how a client can search a catalog.
+type OpenSearchDescription struct {
+	XMLName        xml.Name        `xml:"OpenSearchDescription"`
+	XMLns          string          `xml:"xmlns,attr"`
+	ShortName      string          `xml:"ShortName"`
+	Description    string          `xml:"Description"`
+	InputEncoding  string          `xml:"InputEncoding"`
+	OutputEncoding string          `xml:"OutputEncoding"`
+	URLs           []OpenSearchURL `xml:"Url"`
+}
+
+// OpenSearchURL is a search URL template. The client replaces
+// "{searchTerms}" with the search query.
+type OpenSearchURL struct {
+	TypeLink string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// Encode encodes an OpenSearch description on a writer.
+func (d *OpenSearchDescription) Encode(w io.Writer) (err error) {
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return
+	}
+
+	d.XMLns = "http://a9.com/-/spec/opensearch/1.1/"
+	d.InputEncoding = "UTF-8"
+	d.OutputEncoding = "UTF-8"
+
+	enc := xml.NewEncoder(w)
+	return enc.Encode(d)
+}
@@ -0,0 +1,212 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package pdf
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxStreamSize is the maximum size of a decoded stream.
+const maxStreamSize = 64 << 20
+
+// ErrUnsupportedFilter is returned when a stream is encoded
+// with a filter we can't decode.
+var ErrUnsupportedFilter = errors.New("unsupported stream filter")
+
+// filters returns the stream's filters and their parameters.
+func (d *Document) filters(s *Stream) ([]Name, []Dict) {
+	var names []Name
+	var params []Dict
+
+	switch f := d.Resolve(s.Dict["Filter"]).(type) {
+	case Name:
+		names = []Name{f}
+		params = []Dict{d.dict(s.Dict["DecodeParms"])}
+	case Array:
+		p := d.array(s.Dict["DecodeParms"])
+		for i, x := range f {
+			n, _ := d.Resolve(x).(Name)
+			names = append(names, n)
+			if i < len(p) {
+				params = append(params, d.dict(p[i]))
+			} else {
+				params = append(params, nil)
+			}
+		}
+	}
+
+	return names, params
+}
+
+// Decode returns the decoded content of a stream.
+func (d *Document) Decode(s *Stream) ([]byte, error) {
+	names, params := d.filters(s)
+	return decodeStream(s.Data, names, params)
+}
+
+// decodeStream applies the given filters. When it stops on an image
+// filter (DCTDecode, JPXDecode...), it returns the data encoded with
+// this filter and ErrUnsupportedFilter.
+func decodeStream(data []byte, names []Name, params []Dict) ([]byte, error) {
+	var err error
+	for i, name := range names {
+		switch name {
+		case "FlateDecode", "Fl":
+			if data, err = flateDecode(data); err != nil {
+				return nil, err
+			}
+			if data, err = unpredict(data, params[i]); err != nil {
+				return nil, err
+			}
+		case "ASCIIHexDecode", "AHx":
+			data = asciiHexDecode(data)
+		case "ASCII85Decode", "A85":
+			if data, err = ascii85Decode(data); err != nil {
+				return nil, err
+			}
+		default:
+			return data, fmt.Errorf("%w: %s", ErrUnsupportedFilter, name)
+		}
+	}
+
+	return data, nil
+}
+
+func flateDecode(data []byte) ([]byte, error) {
+	var r io.ReadCloser
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		// Some producers omit the zlib header.
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	defer r.Close() //nolint:errcheck
+
+	res, err := io.ReadAll(io.LimitReader(r, maxStreamSize))
+	if err != nil && len(res) == 0 {
+		return nil, err
+	}
+	// A truncated or badly terminated stream still has
+	// useful content.
+	return res, nil
+}
+
+func asciiHexDecode(data []byte) []byte {
+	src := make([]byte, 0, len(data))
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		if !isSpace(c) {
+			src = append(src, c)
+		}
+	}
+	if len(src)%2 == 1 {
+		src = append(src, '0')
+	}
+	res := make([]byte, len(src)/2)
+	n, _ := hex.Decode(res, src)
+	return res[:n]
+}
+
+func ascii85Decode(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	data = bytes.TrimPrefix(data, []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i != -1 {
+		data = data[:i]
+	}
+
+	res := make([]byte, 4*len(data)/5+4)
+	n, _, err := ascii85.Decode(res, data, true)
+	if err != nil {
+		return nil, err
+	}
+	return res[:n], nil
+}
+
+// unpredict reverses the PNG predictors used by the Flate filter.
+func unpredict(data []byte, params Dict) ([]byte, error) {
+	predictor, _ := params["Predictor"].(int64)
+	if predictor < 10 {
+		if predictor == 2 {
+			return nil, fmt.Errorf("%w: TIFF predictor", ErrUnsupportedFilter)
+		}
+		return data, nil
+	}
+
+	colors, bpc, columns := int64(1), int64(8), int64(1)
+	if v, ok := params["Colors"].(int64); ok && v > 0 {
+		colors = v
+	}
+	if v, ok := params["BitsPerComponent"].(int64); ok && v > 0 {
+		bpc = v
+	}
+	if v, ok := params["Columns"].(int64); ok && v > 0 {
+		columns = v
+	}
+
+	bpp := int(max((colors*bpc+7)/8, 1))
+	rowSize := int((colors*bpc*columns + 7) / 8)
+	if rowSize <= 0 || rowSize > maxStreamSize {
+		return nil, errSyntax
+	}
+
+	res := make([]byte, 0, len(data))
+	prev := make([]byte, rowSize)
+	for len(data) > rowSize {
+		filter := data[0]
+		row := data[1 : rowSize+1]
+		data = data[rowSize+1:]
+
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left = row[i-bpp]
+				upLeft = prev[i-bpp]
+			}
+			up := prev[i]
+
+			switch filter {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		res = append(res, row...)
+		prev = row
+	}
+
+	return res, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,390 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package pdf
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// maxCMapRange limits the size of a bfrange entry.
+const maxCMapRange = 1 << 16
+
+// glyph is a decoded character code.
+type glyph struct {
+	text  string
+	width float64 // in text space units (1/1000)
+	space bool    // single byte code 32, subject to word spacing
+}
+
+// pdfFont decodes the strings shown with a font.
+type pdfFont struct {
+	cid       bool
+	toUnicode *cmap
+	encoding  [256]string
+	widths    map[int]float64
+	dw        float64
+}
+
+// cmap is a ToUnicode character map.
+type cmap struct {
+	codespaces [][2][]byte
+	chars      map[string]string
+}
+
+// newFont returns a font from its dictionary.
+func newFont(d *Document, dict Dict) *pdfFont {
+	f := &pdfFont{
+		widths: map[int]float64{},
+		dw:     500,
+	}
+	if dict == nil {
+		f.setEncoding(d, nil)
+		return f
+	}
+
+	if s, ok := d.Resolve(dict["ToUnicode"]).(*Stream); ok {
+		if data, err := d.Decode(s); err == nil {
+			f.toUnicode = parseCMap(data)
+		}
+	}
+
+	if dict["Subtype"] == Name("Type0") {
+		f.cid = true
+		f.dw = 1000
+		descendants := d.array(dict["DescendantFonts"])
+		if len(descendants) > 0 {
+			df := d.dict(descendants[0])
+			if v, ok := d.number(df["DW"]); ok {
+				f.dw = v
+			}
+			f.setCIDWidths(d, d.array(df["W"]))
+		}
+		return f
+	}
+
+	f.setEncoding(d, d.Resolve(dict["Encoding"]))
+	first, _ := d.number(dict["FirstChar"])
+	for i, w := range d.array(dict["Widths"]) {
+		if v, ok := d.number(w); ok {
+			f.widths[int(first)+i] = v
+		}
+	}
+	if fd := d.dict(dict["FontDescriptor"]); fd != nil {
+		if v, ok := d.number(fd["MissingWidth"]); ok && v > 0 {
+			f.dw = v
+		}
+	}
+
+	return f
+}
+
+// setEncoding sets a simple font's encoding, from a base encoding
+// and an optional list of differences.
+func (f *pdfFont) setEncoding(d *Document, enc Object) {
+	cm := charmap.Windows1252
+	var diff Array
+
+	switch e := enc.(type) {
+	case Name:
+		if e == "MacRomanEncoding" {
+			cm = charmap.Macintosh
+		}
+	case Dict:
+		if d.Resolve(e["BaseEncoding"]) == Name("MacRomanEncoding") {
+			cm = charmap.Macintosh
+		}
+		diff = d.array(e["Differences"])
+	}
+
+	for i := range f.encoding {
+		if i < 32 {
+			continue
+		}
+		r := cm.DecodeByte(byte(i))
+		if r != '�' {
+			f.encoding[i] = string(r)
+		}
+	}
+
+	code := 0
+	for _, x := range diff {
+		switch v := d.Resolve(x).(type) {
+		case int64:
+			code = int(v)
+		case Name:
+			if code >= 0 && code < 256 {
+				f.encoding[code] = glyphName(string(v))
+			}
+			code++
+		}
+	}
+}
+
+// setCIDWidths reads a CID font's W array.
+func (f *pdfFont) setCIDWidths(d *Document, w Array) {
+	for i := 0; i < len(w); {
+		first, ok := d.number(w[i])
+		if !ok || i+1 >= len(w) {
+			return
+		}
+		if list := d.array(w[i+1]); list != nil {
+			for j, x := range list {
+				if v, ok := d.number(x); ok {
+					f.widths[int(first)+j] = v
+				}
+			}
+			i += 2
+			continue
+		}
+
+		last, ok1 := d.number(w[i+1])
+		if i+2 >= len(w) {
+			return
+		}
+		v, ok2 := d.number(w[i+2])
+		if ok1 && ok2 && last-first < maxCMapRange {
+			for c := int(first); c <= int(last); c++ {
+				f.widths[c] = v
+			}
+		}
+		i += 3
+	}
+}
+
+func (f *pdfFont) width(code int) float64 {
+	if w, ok := f.widths[code]; ok {
+		return w
+	}
+	return f.dw
+}
+
+// decode splits a string into glyphs.
+func (f *pdfFont) decode(s []byte) []glyph {
+	res := []glyph{}
+	for len(s) > 0 {
+		n := 1
+		if f.toUnicode != nil {
+			n = f.toUnicode.codeLength(s, f.cid)
+		} else if f.cid {
+			n = 2
+		}
+		n = min(n, len(s))
+
+		code := 0
+		for _, c := range s[:n] {
+			code = code<<8 | int(c)
+		}
+
+		g := glyph{width: f.width(code), space: n == 1 && code == 32}
+		switch {
+		case f.toUnicode != nil:
+			g.text = f.toUnicode.chars[string(s[:n])]
+			if g.text == "" && !f.cid {
+				g.text = f.encoding[code]
+			}
+		case !f.cid:
+			g.text = f.encoding[code]
+		}
+
+		res = append(res, g)
+		s = s[n:]
+	}
+
+	return res
+}
+
+// codeLength returns the length of the character code at the
+// beginning of s, based on the map's codespace ranges.
+func (m *cmap) codeLength(s []byte, cid bool) int {
+	for n := 1; n <= 4 && n <= len(s); n++ {
+		for _, r := range m.codespaces {
+			if len(r[0]) != n {
+				continue
+			}
+			if bytes.Compare(s[:n], r[0]) >= 0 && bytes.Compare(s[:n], r[1]) <= 0 {
+				return n
+			}
+		}
+	}
+	if cid {
+		return 2
+	}
+	return 1
+}
+
+// parseCMap reads the codespace ranges and character
+// mappings of a ToUnicode CMap.
+func parseCMap(data []byte) *cmap {
+	m := &cmap{chars: map[string]string{}}
+	l := newLexer(data)
+
+	var operands []Object
+	for {
+		o, err := l.readObject()
+		if err != nil {
+			break
+		}
+		k, ok := o.(keyword)
+		if !ok {
+			operands = append(operands, o)
+			continue
+		}
+
+		switch k {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				lo, ok1 := operands[i].(String)
+				hi, ok2 := operands[i+1].(String)
+				if ok1 && ok2 && len(lo) == len(hi) {
+					m.codespaces = append(m.codespaces, [2][]byte{lo, hi})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(String)
+				dst, ok2 := operands[i+1].(String)
+				if ok1 && ok2 {
+					m.chars[string(src)] = decodeUTF16(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(String)
+				hi, ok2 := operands[i+1].(String)
+				if ok1 && ok2 && len(lo) == len(hi) {
+					m.addRange(lo, hi, operands[i+2])
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+
+	return m
+}
+
+// addRange adds a bfrange entry. The destination is either
+// a string, incremented for each code, or an array of strings.
+func (m *cmap) addRange(lo, hi []byte, dst Object) {
+	start, end := 0, 0
+	for i := range lo {
+		start = start<<8 | int(lo[i])
+		end = end<<8 | int(hi[i])
+	}
+	if end < start || end-start >= maxCMapRange {
+		return
+	}
+
+	code := bytes.Clone(lo)
+	for i := 0; i <= end-start; i++ {
+		v := start + i
+		for j := len(code) - 1; j >= 0; j-- {
+			code[j] = byte(v)
+			v >>= 8
+		}
+
+		switch dst := dst.(type) {
+		case String:
+			if len(dst) == 0 {
+				return
+			}
+			next := bytes.Clone(dst)
+			next[len(next)-1] += byte(i)
+			m.chars[string(code)] = decodeUTF16(next)
+		case Array:
+			if i < len(dst) {
+				if s, ok := dst[i].(String); ok {
+					m.chars[string(code)] = decodeUTF16(s)
+				}
+			}
+		}
+	}
+}
+
+func decodeUTF16(s []byte) string {
+	if len(s) == 1 {
+		return string(rune(s[0]))
+	}
+	u := make([]uint16, len(s)/2)
+	for i := range u {
+		u[i] = uint16(s[i*2])<<8 | uint16(s[i*2+1])
+	}
+	return string(utf16.Decode(u))
+}
+
+// glyphNames contains the glyph names we're likely to find
+// in an encoding's differences, other than single letters.
+var glyphNames = map[string]string{
+	"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#",
+	"dollar": "$", "percent": "%", "ampersand": "&", "quotesingle": "'",
+	"parenleft": "(", "parenright": ")", "asterisk": "*", "plus": "+",
+	"comma": ",", "hyphen": "-", "period": ".", "slash": "/",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4",
+	"five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+	"colon": ":", "semicolon": ";", "less": "<", "equal": "=",
+	"greater": ">", "question": "?", "at": "@", "bracketleft": "[",
+	"backslash": "\\", "bracketright": "]", "underscore": "_",
+	"braceleft": "{", "bar": "|", "braceright": "}", "asciitilde": "~",
+	"quoteleft": "‘", "quoteright": "’", "quotedblleft": "“",
+	"quotedblright": "”", "quotesinglbase": "‚", "quotedblbase": "„",
+	"endash": "–", "emdash": "—", "bullet": "•", "ellipsis": "…",
+	"minus": "−", "fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi",
+	"ffl": "ffl", "dagger": "†", "daggerdbl": "‡", "degree": "°",
+	"copyright": "©", "registered": "®", "trademark": "™",
+	"section": "§", "paragraph": "¶", "periodcentered": "·",
+	"guillemotleft": "«", "guillemotright": "»", "Euro": "€",
+	"eacute": "é", "egrave": "è", "ecircumflex": "ê", "agrave": "à",
+	"acircumflex": "â", "ccedilla": "ç", "ocircumflex": "ô",
+	"ucircumflex": "û", "ugrave": "ù", "icircumflex": "î",
+	"idieresis": "ï", "edieresis": "ë", "adieresis": "ä",
+	"odieresis": "ö", "udieresis": "ü", "germandbls": "ß",
+	"Eacute": "É", "aacute": "á", "iacute": "í", "oacute": "ó",
+	"uacute": "ú", "ntilde": "ñ", "dotlessi": "ı",
+}
+
+// glyphName returns the text of a glyph name.
+func glyphName(name string) string {
+	if v, ok := glyphNames[name]; ok {
+		return v
+	}
+
+	// Variants like "a.sc" or "f_i"
+	if base, _, ok := strings.Cut(name, "."); ok && base != "" {
+		return glyphName(base)
+	}
+	if strings.Contains(name, "_") {
+		res := ""
+		for _, x := range strings.Split(name, "_") {
+			res += glyphName(x)
+		}
+		return res
+	}
+
+	if len(name) == 1 {
+		return name
+	}
+	if hex, ok := strings.CutPrefix(name, "uni"); ok && len(hex) >= 4 && len(hex)%4 == 0 {
+		res := []uint16{}
+		for i := 0; i < len(hex); i += 4 {
+			v, err := strconv.ParseUint(hex[i:i+4], 16, 16)
+			if err != nil {
+				return ""
+			}
+			res = append(res, uint16(v))
+		}
+		return string(utf16.Decode(res))
+	}
+	if hex, ok := strings.CutPrefix(name, "u"); ok && len(hex) >= 4 && len(hex) <= 6 {
+		if v, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return string(rune(v))
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,386 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+)
+
+type (
+	// Object is any PDF object: nil, bool, int64, float64, Name,
+	// String, Array, Dict, Ref or *Stream.
+	Object any
+
+	// Name is a PDF name object.
+	Name string
+
+	// String is a raw PDF string object.
+	String []byte
+
+	// Array is a PDF array object.
+	Array []Object
+
+	// Dict is a PDF dictionary object.
+	Dict map[Name]Object
+
+	// Ref is an indirect object reference.
+	Ref struct {
+		ID  int
+		Gen int
+	}
+
+	// Stream is a PDF stream object. Data is the raw,
+	// still encoded, stream content.
+	Stream struct {
+		Dict Dict
+		Data []byte
+	}
+
+	// keyword is a bare keyword, like an operator in a content
+	// stream or a closing delimiter.
+	keyword string
+)
+
+var errSyntax = errors.New("syntax error")
+
+// maxDepth limits nested arrays and dictionaries.
+const maxDepth = 64
+
+// lexer reads PDF objects from a byte slice.
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+func newLexer(data []byte) *lexer {
+	return &lexer{data: data}
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isDelim(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func isRegular(c byte) bool {
+	return !isSpace(c) && !isDelim(c)
+}
+
+// skipSpace skips whitespace and comments.
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// readObject returns the next object. Keywords (operators, closing
+// delimiters, "obj", "stream"...) are returned as keyword values.
+func (l *lexer) readObject() (Object, error) {
+	return l.readObjectDepth(0)
+}
+
+func (l *lexer) readObjectDepth(depth int) (Object, error) {
+	if depth > maxDepth {
+		return nil, errSyntax
+	}
+
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		return l.readName(), nil
+	case c == '(':
+		return l.readLiteralString()
+	case c == '<' && l.peek(1) == '<':
+		l.pos += 2
+		return l.readDict(depth)
+	case c == '<':
+		return l.readHexString()
+	case c == '>' && l.peek(1) == '>':
+		l.pos += 2
+		return keyword(">>"), nil
+	case c == '[':
+		l.pos++
+		return l.readArray(depth)
+	case c == ']', c == '{', c == '}', c == ')', c == '>':
+		l.pos++
+		return keyword(l.data[l.pos-1 : l.pos]), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return l.readNumberOrRef()
+	}
+
+	kw := l.readRegular()
+	switch kw {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return keyword(kw), nil
+}
+
+func (l *lexer) peek(n int) byte {
+	if l.pos+n < len(l.data) {
+		return l.data[l.pos+n]
+	}
+	return 0
+}
+
+func (l *lexer) readRegular() string {
+	start := l.pos
+	for l.pos < len(l.data) && isRegular(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		// Stray byte, skip it
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+func (l *lexer) readName() Name {
+	l.pos++ // "/"
+	start := l.pos
+	for l.pos < len(l.data) && isRegular(l.data[l.pos]) {
+		l.pos++
+	}
+	raw := l.data[start:l.pos]
+	if bytes.IndexByte(raw, '#') == -1 {
+		return Name(raw)
+	}
+
+	res := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			if v, err := strconv.ParseUint(string(raw[i+1:i+3]), 16, 8); err == nil {
+				res = append(res, byte(v))
+				i += 2
+				continue
+			}
+		}
+		res = append(res, raw[i])
+	}
+	return Name(res)
+}
+
+func (l *lexer) readNumber() (Object, bool) {
+	start := l.pos
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9') {
+			l.pos++
+			continue
+		}
+		break
+	}
+	s := string(l.data[start:l.pos])
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, true
+	}
+	return int64(0), false
+}
+
+// readNumberOrRef reads a number and looks ahead for an
+// indirect reference ("12 0 R").
+func (l *lexer) readNumberOrRef() (Object, error) {
+	n, _ := l.readNumber()
+	id, ok := n.(int64)
+	if !ok || id < 0 {
+		return n, nil
+	}
+
+	save := l.pos
+	l.skipSpace()
+	if l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '9' {
+		g, _ := l.readNumber()
+		if gen, ok := g.(int64); ok {
+			l.skipSpace()
+			if l.pos < len(l.data) && l.data[l.pos] == 'R' &&
+				(l.pos+1 == len(l.data) || !isRegular(l.data[l.pos+1])) {
+				l.pos++
+				return Ref{int(id), int(gen)}, nil
+			}
+		}
+	}
+	l.pos = save
+	return n, nil
+}
+
+func (l *lexer) readLiteralString() (Object, error) {
+	l.pos++ // "("
+	res := []byte{}
+	level := 1
+
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			level++
+		case ')':
+			level--
+			if level == 0 {
+				return String(res), nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return String(res), nil
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data); i++ {
+						d := l.data[l.pos]
+						if d < '0' || d > '7' {
+							break
+						}
+						v = v*8 + int(d-'0')
+						l.pos++
+					}
+					c = byte(v)
+				}
+			}
+		}
+		res = append(res, c)
+	}
+
+	return String(res), nil
+}
+
+func (l *lexer) readHexString() (Object, error) {
+	l.pos++ // "<"
+	res := []byte{}
+	var cur byte
+	half := false
+
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		var v byte
+		switch {
+		case c == '>':
+			if half {
+				res = append(res, cur<<4)
+			}
+			return String(res), nil
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			v = c - 'A' + 10
+		default:
+			continue
+		}
+		if half {
+			res = append(res, cur<<4|v)
+		} else {
+			cur = v
+		}
+		half = !half
+	}
+
+	return String(res), nil
+}
+
+func (l *lexer) readArray(depth int) (Object, error) {
+	res := Array{}
+	for {
+		o, err := l.readObjectDepth(depth + 1)
+		if err != nil {
+			return res, err
+		}
+		if k, ok := o.(keyword); ok {
+			if k == "]" {
+				return res, nil
+			}
+			if k == ">>" || k == "endobj" {
+				return res, errSyntax
+			}
+		}
+		res = append(res, o)
+	}
+}
+
+func (l *lexer) readDict(depth int) (Object, error) {
+	res := Dict{}
+	for {
+		o, err := l.readObjectDepth(depth + 1)
+		if err != nil {
+			return res, err
+		}
+		switch k := o.(type) {
+		case keyword:
+			if k == ">>" {
+				return res, nil
+			}
+			if k == "endobj" || k == "stream" {
+				return res, errSyntax
+			}
+			continue
+		case Name:
+			v, err := l.readObjectDepth(depth + 1)
+			if err != nil {
+				return res, err
+			}
+			if kw, ok := v.(keyword); ok && kw == ">>" {
+				return res, nil
+			}
+			res[k] = v
+		}
+	}
+}
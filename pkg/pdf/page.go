@@ -0,0 +1,419 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package pdf
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxFormDepth limits nested form XObjects.
+const maxFormDepth = 5
+
+var (
+	rxSpaces     = regexp.MustCompile(`[ \t\f\r\x00]+`)
+	rxParagraphs = regexp.MustCompile(`\n{2,}`)
+)
+
+// Page is a document's page.
+type Page struct {
+	doc       *Document
+	dict      Dict
+	resources Dict
+	mediaBox  [4]float64
+}
+
+// matrix is a transformation matrix [a b c d e f].
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul returns m × n.
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// graphicState is the part of the graphic state we need.
+type graphicState struct {
+	ctm     matrix
+	font    *pdfFont
+	size    float64
+	charSp  float64
+	wordSp  float64
+	scale   float64
+	leading float64
+}
+
+// pageImage is an image drawn on a page, with its area on the page.
+type pageImage struct {
+	stream *Stream
+	area   float64
+}
+
+// contentReader interprets a page's content streams.
+type contentReader struct {
+	doc    *Document
+	fonts  map[Ref]*pdfFont
+	text   strings.Builder
+	images []pageImage
+
+	gs     graphicState
+	stack  []graphicState
+	tm     matrix
+	tlm    matrix
+	lastX  float64
+	lastY  float64
+	hasPos bool
+}
+
+func newPage(d *Document, dict Dict, attrs Dict) *Page {
+	p := &Page{
+		doc:       d,
+		dict:      dict,
+		resources: d.dict(attrs["Resources"]),
+		mediaBox:  [4]float64{0, 0, 612, 792},
+	}
+
+	if box := d.array(attrs["MediaBox"]); len(box) == 4 {
+		for i, x := range box {
+			p.mediaBox[i], _ = d.number(x)
+		}
+	}
+
+	return p
+}
+
+// Size returns the page's width and height, in points.
+func (p *Page) Size() (float64, float64) {
+	return math.Abs(p.mediaBox[2] - p.mediaBox[0]), math.Abs(p.mediaBox[3] - p.mediaBox[1])
+}
+
+// Text returns the page's text. Paragraphs are separated
+// by an empty line.
+func (p *Page) Text() string {
+	return p.read().pageText()
+}
+
+// pageText returns the text, with joined lines.
+func (r *contentReader) pageText() string {
+	paragraphs := rxParagraphs.Split(r.text.String(), -1)
+	res := make([]string, 0, len(paragraphs))
+	for _, x := range paragraphs {
+		if x = joinLines(x); x != "" {
+			res = append(res, x)
+		}
+	}
+	return strings.Join(res, "\n\n")
+}
+
+// read interprets the page's content.
+func (p *Page) read() *contentReader {
+	r := &contentReader{
+		doc:   p.doc,
+		fonts: map[Ref]*pdfFont{},
+		gs:    graphicState{ctm: identity, scale: 1},
+	}
+
+	var content []byte
+	switch c := p.doc.Resolve(p.dict["Contents"]).(type) {
+	case *Stream:
+		content, _ = p.doc.Decode(c)
+	case Array:
+		parts := [][]byte{}
+		for _, x := range c {
+			if s, ok := p.doc.Resolve(x).(*Stream); ok {
+				if data, err := p.doc.Decode(s); err == nil {
+					parts = append(parts, data)
+				}
+			}
+		}
+		content = bytes.Join(parts, []byte("\n"))
+	}
+
+	r.run(content, p.resources, 0)
+	return r
+}
+
+// joinLines joins the lines of a paragraph, removing
+// hyphenation at the end of lines.
+func joinLines(s string) string {
+	parts := []string{}
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(rxSpaces.ReplaceAllLiteralString(line, " "))
+		switch {
+		case line == "":
+			continue
+		case len(parts) > 0 && isHyphenated(parts[len(parts)-1], line):
+			prev := parts[len(parts)-1]
+			parts[len(parts)-1] = prev[:len(prev)-1] + line
+		default:
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// isHyphenated returns true when a word is split
+// between two lines.
+func isHyphenated(line, next string) bool {
+	word, ok := strings.CutSuffix(line, "-")
+	if !ok || word == "" {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(word)
+	first, _ := utf8.DecodeRuneInString(next)
+	return unicode.IsLetter(last) && unicode.IsLower(first)
+}
+
+// font returns a font from the resources. Fonts are cached
+// by their reference.
+func (r *contentReader) font(resources Dict, name Name) *pdfFont {
+	fonts := r.doc.dict(resources["Font"])
+	ref, isRef := fonts[name].(Ref)
+	if f, ok := r.fonts[ref]; ok && isRef {
+		return f
+	}
+
+	f := newFont(r.doc, r.doc.dict(fonts[name]))
+	if isRef {
+		r.fonts[ref] = f
+	}
+	return f
+}
+
+// run executes a content stream.
+func (r *contentReader) run(content []byte, resources Dict, depth int) {
+	l := newLexer(content)
+	var operands []Object
+
+	for {
+		o, err := l.readObject()
+		if err != nil {
+			return
+		}
+		op, ok := o.(keyword)
+		if !ok {
+			operands = append(operands, o)
+			continue
+		}
+
+		switch op {
+		case "q":
+			r.stack = append(r.stack, r.gs)
+		case "Q":
+			if len(r.stack) > 0 {
+				r.gs = r.stack[len(r.stack)-1]
+				r.stack = r.stack[:len(r.stack)-1]
+			}
+		case "cm":
+			if m, ok := r.matrix(operands); ok {
+				r.gs.ctm = m.mul(r.gs.ctm)
+			}
+		case "BT":
+			r.tm, r.tlm = identity, identity
+		case "Tf":
+			if len(operands) == 2 {
+				name, _ := operands[0].(Name)
+				r.gs.size, _ = r.doc.number(operands[1])
+				r.gs.font = r.font(resources, name)
+			}
+		case "Tc":
+			r.gs.charSp = r.num(operands, 0)
+		case "Tw":
+			r.gs.wordSp = r.num(operands, 0)
+		case "Tz":
+			r.gs.scale = r.num(operands, 0) / 100
+		case "TL":
+			r.gs.leading = r.num(operands, 0)
+		case "Td":
+			r.moveLine(r.num(operands, 0), r.num(operands, 1))
+		case "TD":
+			r.gs.leading = -r.num(operands, 1)
+			r.moveLine(r.num(operands, 0), r.num(operands, 1))
+		case "Tm":
+			if m, ok := r.matrix(operands); ok {
+				r.tm, r.tlm = m, m
+			}
+		case "T*":
+			r.moveLine(0, -r.gs.leading)
+		case "Tj":
+			if len(operands) > 0 {
+				r.show(operands[0])
+			}
+		case "'":
+			r.moveLine(0, -r.gs.leading)
+			if len(operands) > 0 {
+				r.show(operands[0])
+			}
+		case "\"":
+			if len(operands) == 3 {
+				r.gs.wordSp = r.num(operands, 0)
+				r.gs.charSp = r.num(operands, 1)
+				r.moveLine(0, -r.gs.leading)
+				r.show(operands[2])
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				for _, x := range r.doc.array(operands[0]) {
+					if n, ok := r.doc.number(x); ok {
+						r.advance(-n / 1000 * r.gs.size * r.gs.scale)
+						continue
+					}
+					r.show(x)
+				}
+			}
+		case "Do":
+			if len(operands) > 0 {
+				name, _ := operands[0].(Name)
+				r.xobject(resources, name, depth)
+			}
+		case "BI":
+			// Skip inline images
+			if l.pos = skipInlineImage(content, l.pos); l.pos == len(content) {
+				return
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// skipInlineImage returns the position after the end of an inline
+// image ("EI" keyword).
+func skipInlineImage(content []byte, pos int) int {
+	for pos < len(content) {
+		i := bytes.Index(content[pos:], []byte("EI"))
+		if i == -1 {
+			return len(content)
+		}
+		start, end := pos+i, pos+i+2
+		pos = end
+		if start > 0 && isSpace(content[start-1]) && (end == len(content) || !isRegular(content[end])) {
+			return end
+		}
+	}
+	return len(content)
+}
+
+func (r *contentReader) num(operands []Object, i int) float64 {
+	if i >= len(operands) {
+		return 0
+	}
+	v, _ := r.doc.number(operands[i])
+	return v
+}
+
+func (r *contentReader) matrix(operands []Object) (matrix, bool) {
+	if len(operands) != 6 {
+		return identity, false
+	}
+	m := matrix{}
+	for i := range m {
+		m[i] = r.num(operands, i)
+	}
+	return m, true
+}
+
+func (r *contentReader) moveLine(tx, ty float64) {
+	r.tlm = matrix{1, 0, 0, 1, tx, ty}.mul(r.tlm)
+	r.tm = r.tlm
+}
+
+// advance moves the text matrix horizontally.
+func (r *contentReader) advance(tx float64) {
+	r.tm = matrix{1, 0, 0, 1, tx, 0}.mul(r.tm)
+}
+
+// show appends a string to the page text. It compares the current
+// position with the end of the previous string to find new lines,
+// paragraphs and spaces.
+func (r *contentReader) show(o Object) {
+	s, ok := o.(String)
+	if !ok || r.gs.font == nil {
+		return
+	}
+
+	m := r.tm.mul(r.gs.ctm)
+	x, y := m[4], m[5]
+	h := r.gs.size * math.Hypot(m[2], m[3])
+	if h <= 0 {
+		h = 1
+	}
+
+	if r.hasPos {
+		dy := math.Abs(y - r.lastY)
+		dx := x - r.lastX
+		switch {
+		case dy > h*1.9:
+			r.text.WriteString("\n\n")
+		case dy > h*0.5:
+			r.text.WriteString("\n")
+		case dx > h*0.15 || dx < -h:
+			r.text.WriteString(" ")
+		}
+	}
+
+	for _, g := range r.gs.font.decode(s) {
+		r.text.WriteString(g.text)
+		tx := g.width/1000*r.gs.size + r.gs.charSp
+		if g.space {
+			tx += r.gs.wordSp
+		}
+		r.advance(tx * r.gs.scale)
+	}
+
+	m = r.tm.mul(r.gs.ctm)
+	r.lastX, r.lastY = m[4], y
+	r.hasPos = true
+}
+
+// xobject handles the "Do" operator. Forms are executed
+// and images are collected.
+func (r *contentReader) xobject(resources Dict, name Name, depth int) {
+	s, ok := r.doc.Resolve(r.doc.dict(resources["XObject"])[name]).(*Stream)
+	if !ok {
+		return
+	}
+
+	switch s.Dict["Subtype"] {
+	case Name("Image"):
+		// The image is drawn in the unit square, transformed by the CTM.
+		m := r.gs.ctm
+		area := math.Abs(m[0]*m[3] - m[1]*m[2])
+		r.images = append(r.images, pageImage{s, area})
+	case Name("Form"):
+		if depth >= maxFormDepth {
+			return
+		}
+		data, err := r.doc.Decode(s)
+		if err != nil {
+			return
+		}
+		res := r.doc.dict(s.Dict["Resources"])
+		if res == nil {
+			res = resources
+		}
+
+		r.stack = append(r.stack, r.gs)
+		if m, ok := r.matrix(r.doc.array(s.Dict["Matrix"])); ok {
+			r.gs.ctm = m.mul(r.gs.ctm)
+		}
+		tm, tlm := r.tm, r.tlm
+		r.run(data, res, depth+1)
+		r.tm, r.tlm = tm, tlm
+		r.gs = r.stack[len(r.stack)-1]
+		r.stack = r.stack[:len(r.stack)-1]
+	}
+}
@@ -0,0 +1,416 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+Package pdf is a minimal PDF reader.
+
+It doesn't render anything. It only reads a document's metadata,
+the text of its pages and the pictures they contain, which is all
+we need to save a PDF document as a bookmark.
+
+Encrypted documents are not supported.
+*/
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode/utf16"
+)
+
+var (
+	// ErrInvalid is returned when a document is not a PDF file.
+	ErrInvalid = errors.New("not a PDF document")
+	// ErrEncrypted is returned when a document is encrypted.
+	ErrEncrypted = errors.New("encrypted PDF documents are not supported")
+)
+
+// maxPages is the maximum number of pages a document can have.
+const maxPages = 5000
+
+var (
+	rxObject = regexp.MustCompile(`(\d+)[\x00\t\n\f\r ]+(\d+)[\x00\t\n\f\r ]+obj\b|trailer\b`)
+	rxDate   = regexp.MustCompile(`^(?:D:)?(\d{4})(\d{2})?(\d{2})?(\d{2})?(\d{2})?(\d{2})?([Zz+-])?(\d{2})?'?(\d{2})?'?`)
+)
+
+// Info contains the document's metadata.
+type Info struct {
+	Title    string
+	Author   string
+	Subject  string
+	Keywords string
+	Creator  string
+	Producer string
+	Lang     string
+	Created  time.Time
+	Modified time.Time
+}
+
+// Document is a parsed PDF document.
+type Document struct {
+	objects map[int]indirect
+	trailer Dict
+	pages   []*Page
+}
+
+// indirect is an indirect object and the position it was found at.
+// When an object is defined more than once (incremental updates),
+// the last definition wins.
+type indirect struct {
+	pos    int
+	object Object
+}
+
+// Open parses a PDF document.
+func Open(data []byte) (*Document, error) {
+	header := data[:min(len(data), 1024)]
+	if !bytes.Contains(header, []byte("%PDF-")) {
+		return nil, ErrInvalid
+	}
+
+	d := &Document{
+		objects: map[int]indirect{},
+		trailer: Dict{},
+	}
+
+	d.scan(data)
+	if err := d.loadObjectStreams(); err != nil {
+		return nil, err
+	}
+
+	if _, ok := d.trailer["Encrypt"]; ok {
+		return nil, ErrEncrypted
+	}
+
+	if _, ok := d.Resolve(d.trailer["Root"]).(Dict); !ok {
+		// Broken or missing trailer, look for the catalog.
+		for id, x := range d.objects {
+			if o, ok := x.object.(Dict); ok && o["Type"] == Name("Catalog") {
+				d.trailer["Root"] = Ref{ID: id}
+				break
+			}
+		}
+	}
+	root, ok := d.Resolve(d.trailer["Root"]).(Dict)
+	if !ok {
+		return nil, ErrInvalid
+	}
+
+	d.loadPages(root["Pages"], Dict{}, map[Ref]bool{})
+	return d, nil
+}
+
+// scan reads all the objects and trailers in the document. It doesn't
+// rely on the cross-reference table, which is often broken, and
+// reads the objects one after the other, skipping the stream contents.
+func (d *Document) scan(data []byte) {
+	pos := 0
+	for pos < len(data) {
+		m := rxObject.FindSubmatchIndex(data[pos:])
+		if m == nil {
+			return
+		}
+		base := pos
+		start := base + m[0]
+		end := base + m[1]
+		pos = end
+
+		if start > 0 && isRegular(data[start-1]) {
+			continue
+		}
+
+		l := newLexer(data)
+		l.pos = end
+
+		if m[2] == -1 {
+			// trailer
+			if t, err := l.readObject(); err == nil {
+				if t, ok := t.(Dict); ok {
+					d.setTrailer(t)
+				}
+			}
+			pos = l.pos
+			continue
+		}
+
+		id, _ := strconv.Atoi(string(data[base+m[2] : base+m[3]]))
+		o, err := l.readObject()
+		if err != nil {
+			continue
+		}
+
+		if dict, ok := o.(Dict); ok {
+			save := l.pos
+			if k, _ := l.readObject(); k == keyword("stream") {
+				s := &Stream{Dict: dict}
+				s.Data, l.pos = readStreamData(data, l.pos, dict)
+				o = s
+
+				if dict["Type"] == Name("XRef") {
+					d.setTrailer(dict)
+				}
+			} else {
+				l.pos = save
+			}
+		}
+
+		d.objects[id] = indirect{start, o}
+		pos = l.pos
+	}
+}
+
+// readStreamData returns a stream's data and the position after it.
+func readStreamData(data []byte, pos int, dict Dict) ([]byte, int) {
+	// The stream keyword is followed by an end of line.
+	if pos < len(data) && data[pos] == '\r' {
+		pos++
+	}
+	if pos < len(data) && data[pos] == '\n' {
+		pos++
+	}
+
+	// Trust a direct length when it's followed by "endstream".
+	if n, ok := dict["Length"].(int64); ok && n >= 0 && pos+int(n) <= len(data) {
+		end := pos + int(n)
+		rest := bytes.TrimLeft(data[end:min(len(data), end+32)], "\x00\t\n\f\r ")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			return data[pos:end], end
+		}
+	}
+
+	idx := bytes.Index(data[pos:], []byte("endstream"))
+	if idx == -1 {
+		return data[pos:], len(data)
+	}
+	end := pos + idx
+	res := data[pos:end]
+	res = bytes.TrimSuffix(res, []byte("\n"))
+	res = bytes.TrimSuffix(res, []byte("\r"))
+	return res, end
+}
+
+// setTrailer merges a trailer dictionary into the document's trailer.
+// The last trailer has precedence.
+func (d *Document) setTrailer(t Dict) {
+	for _, k := range []Name{"Root", "Info", "Encrypt", "ID"} {
+		if v, ok := t[k]; ok {
+			d.trailer[k] = v
+		}
+	}
+}
+
+// loadObjectStreams adds the objects contained in object streams.
+// It fails when a stream announces more objects than it can contain.
+func (d *Document) loadObjectStreams() error {
+	streams := []indirect{}
+	for _, x := range d.objects {
+		if s, ok := x.object.(*Stream); ok && s.Dict["Type"] == Name("ObjStm") {
+			streams = append(streams, x)
+		}
+	}
+
+	for _, x := range streams {
+		s := x.object.(*Stream)
+		data, err := d.Decode(s)
+		if err != nil {
+			continue
+		}
+		n, _ := s.Dict["N"].(int64)
+		first, _ := s.Dict["First"].(int64)
+		if first < 0 || int(first) > len(data) {
+			continue
+		}
+		// Each entry takes at least 4 bytes ("1 0 ")
+		if n < 0 || n > int64(len(data)/4) {
+			return fmt.Errorf("%w: invalid object stream size", ErrInvalid)
+		}
+
+		l := newLexer(data)
+		offsets := make([][2]int, 0, n)
+		for range n {
+			id, _ := l.readObject()
+			off, _ := l.readObject()
+			i, ok1 := id.(int64)
+			o, ok2 := off.(int64)
+			if !ok1 || !ok2 {
+				break
+			}
+			offsets = append(offsets, [2]int{int(i), int(o)})
+		}
+
+		for _, p := range offsets {
+			if prev, ok := d.objects[p[0]]; ok && prev.pos > x.pos {
+				continue
+			}
+			l.pos = int(first) + p[1]
+			if p[1] < 0 || l.pos > len(data) {
+				continue
+			}
+			if o, err := l.readObject(); err == nil {
+				d.objects[p[0]] = indirect{x.pos, o}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Resolve returns the object an indirect reference points to.
+// Any other object is returned as is.
+func (d *Document) Resolve(o Object) Object {
+	for range 10 {
+		r, ok := o.(Ref)
+		if !ok {
+			return o
+		}
+		o = d.objects[r.ID].object
+	}
+	return nil
+}
+
+func (d *Document) dict(o Object) Dict {
+	if s, ok := d.Resolve(o).(*Stream); ok {
+		return s.Dict
+	}
+	res, _ := d.Resolve(o).(Dict)
+	return res
+}
+
+func (d *Document) array(o Object) Array {
+	res, _ := d.Resolve(o).(Array)
+	return res
+}
+
+func (d *Document) number(o Object) (float64, bool) {
+	switch v := d.Resolve(o).(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func (d *Document) text(o Object) string {
+	s, _ := d.Resolve(o).(String)
+	return decodeTextString(s)
+}
+
+// Info returns the document's metadata.
+func (d *Document) Info() Info {
+	info := d.dict(d.trailer["Info"])
+	root := d.dict(d.trailer["Root"])
+
+	return Info{
+		Title:    d.text(info["Title"]),
+		Author:   d.text(info["Author"]),
+		Subject:  d.text(info["Subject"]),
+		Keywords: d.text(info["Keywords"]),
+		Creator:  d.text(info["Creator"]),
+		Producer: d.text(info["Producer"]),
+		Lang:     d.text(root["Lang"]),
+		Created:  parseDate(d.text(info["CreationDate"])),
+		Modified: parseDate(d.text(info["ModDate"])),
+	}
+}
+
+// Pages returns the document's pages.
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+// loadPages walks the page tree. Resources and MediaBox
+// are inherited from the parent nodes.
+func (d *Document) loadPages(o Object, inherited Dict, seen map[Ref]bool) {
+	if r, ok := o.(Ref); ok {
+		if seen[r] {
+			return
+		}
+		seen[r] = true
+	}
+
+	node := d.dict(o)
+	if node == nil || len(d.pages) >= maxPages {
+		return
+	}
+
+	attrs := Dict{}
+	for k, v := range inherited {
+		attrs[k] = v
+	}
+	for _, k := range []Name{"Resources", "MediaBox"} {
+		if v, ok := node[k]; ok {
+			attrs[k] = v
+		}
+	}
+
+	kids, isNode := node["Kids"]
+	if !isNode || node["Type"] == Name("Page") {
+		d.pages = append(d.pages, newPage(d, node, attrs))
+		return
+	}
+	for _, k := range d.array(kids) {
+		d.loadPages(k, attrs, seen)
+	}
+}
+
+// decodeTextString decodes a PDF text string, encoded either
+// in UTF-16BE, UTF-8 or PDFDocEncoding.
+func decodeTextString(s []byte) string {
+	switch {
+	case bytes.HasPrefix(s, []byte{0xfe, 0xff}):
+		s = s[2:]
+		u := make([]uint16, len(s)/2)
+		for i := range u {
+			u[i] = uint16(s[i*2])<<8 | uint16(s[i*2+1])
+		}
+		return string(utf16.Decode(u))
+	case bytes.HasPrefix(s, []byte{0xef, 0xbb, 0xbf}):
+		return string(bytes.ToValidUTF8(s[3:], []byte("�")))
+	}
+
+	res := make([]rune, len(s))
+	for i, c := range s {
+		res[i] = pdfDocEncoding(c)
+	}
+	return string(res)
+}
+
+// pdfDocEncoding returns the unicode character of a PDFDocEncoding byte.
+// Outside the 0x80-0xa0 range, it's the same as Latin-1.
+func pdfDocEncoding(c byte) rune {
+	if c >= 0x80 && c <= 0xa0 {
+		return []rune("•†‡…—–ƒ⁄‹›−‰„“”‘’‚™ﬁﬂŁŒŠŸŽıłœšž�€")[c-0x80]
+	}
+	return rune(c)
+}
+
+// parseDate parses a PDF date (D:YYYYMMDDHHmmSSOHH'mm').
+func parseDate(s string) time.Time {
+	m := rxDate.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}
+	}
+
+	v := make([]int, 9)
+	for i, x := range m[1:] {
+		v[i], _ = strconv.Atoi(x)
+	}
+	month, day := max(v[1], 1), max(v[2], 1)
+
+	loc := time.UTC
+	if m[7] == "+" || m[7] == "-" {
+		offset := v[7]*3600 + v[8]*60
+		if m[7] == "-" {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+
+	return time.Date(v[0], time.Month(month), day, v[3], v[4], v[5], 0, loc)
+}
@@ -0,0 +1,269 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package pdf_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/pdf"
+)
+
+// builder writes a PDF document for tests.
+type builder struct {
+	objects []string
+	trailer string
+}
+
+// add adds an object and returns its ID.
+func (b *builder) add(o string) int {
+	b.objects = append(b.objects, o)
+	return len(b.objects)
+}
+
+// stream returns a Flate compressed stream object.
+func stream(dict string, data []byte) string {
+	buf := new(bytes.Buffer)
+	w := zlib.NewWriter(buf)
+	w.Write(data) //nolint:errcheck
+	w.Close()     //nolint:errcheck
+	return fmt.Sprintf("<< %s /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream",
+		dict, buf.Len(), buf.Bytes())
+}
+
+func (b *builder) bytes() []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(b.objects))
+	for i, o := range b.objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(b.objects)+1)
+	for _, x := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", x)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d %s >>\nstartxref\n%d\n%%%%EOF\n", len(b.objects)+1, b.trailer, xref)
+	return buf.Bytes()
+}
+
+// newDocument returns a document with one page per content stream.
+func newDocument(font string, contents ...string) *builder {
+	b := &builder{}
+	catalog := b.add("")
+	pages := b.add("")
+	f := b.add(font)
+
+	kids := []string{}
+	for _, c := range contents {
+		content := b.add(stream("", []byte(c)))
+		page := b.add(fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /Contents %d 0 R >>", pages, content,
+		))
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+
+	b.objects[catalog-1] = fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R /Lang (en-US) >>", pages)
+	b.objects[pages-1] = fmt.Sprintf(
+		"<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 612 792] /Resources << /Font << /F1 %d 0 R >> >> >>",
+		strings.Join(kids, " "), len(kids), f,
+	)
+	b.trailer = fmt.Sprintf("/Root %d 0 R", catalog)
+	return b
+}
+
+const helvetica = "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"
+
+func TestOpen(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		_, err := pdf.Open([]byte("<html></html>"))
+		require.ErrorIs(t, err, pdf.ErrInvalid)
+
+		_, err = pdf.Open([]byte("%PDF-1.4\nnothing here"))
+		require.ErrorIs(t, err, pdf.ErrInvalid)
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		b := newDocument(helvetica, "")
+		b.trailer += " /Encrypt << /Filter /Standard >>"
+		_, err := pdf.Open(b.bytes())
+		require.ErrorIs(t, err, pdf.ErrEncrypted)
+	})
+
+	t.Run("info", func(t *testing.T) {
+		b := newDocument(helvetica, "")
+		info := b.add(`<<
+			/Title <FEFF0054006800E8006D0065>
+			/Author (Alice \(and Bob\))
+			/Subject (A test\040document)
+			/CreationDate (D:20240315143000+01'00')
+			/ModDate (D:2024)
+		>>`)
+		b.trailer += fmt.Sprintf(" /Info %d 0 R", info)
+
+		doc, err := pdf.Open(b.bytes())
+		require.NoError(t, err)
+
+		i := doc.Info()
+		require.Equal(t, "Thème", i.Title)
+		require.Equal(t, "Alice (and Bob)", i.Author)
+		require.Equal(t, "A test document", i.Subject)
+		require.Equal(t, "en-US", i.Lang)
+		require.True(t, time.Date(2024, 3, 15, 13, 30, 0, 0, time.UTC).Equal(i.Created))
+		require.True(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Equal(i.Modified))
+		require.Len(t, doc.Pages(), 1)
+	})
+
+	t.Run("incremental update", func(t *testing.T) {
+		b := newDocument(helvetica, "")
+		data := b.bytes()
+		data = append(data, []byte("3 0 obj\n<< /Type /Info /Title (Updated) >>\nendobj\n"+
+			"trailer\n<< /Info 3 0 R >>\n%%EOF\n")...)
+
+		doc, err := pdf.Open(data)
+		require.NoError(t, err)
+		require.Equal(t, "Updated", doc.Info().Title)
+	})
+
+	t.Run("object streams", func(t *testing.T) {
+		content := stream("", []byte("BT /F1 12 Tf 72 720 Td (Compressed objects) Tj ET"))
+		objects := fmt.Sprintf(
+			"<< /Type /Catalog /Pages 2 0 R >> "+
+				"<< /Type /Pages /Kids [3 0 R] /Count 1 >> "+
+				"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /Font << /F1 %s >> >> >>",
+			helvetica,
+		)
+		offsets := []int{0}
+		for _, x := range strings.SplitAfter(objects, ">> ")[:2] {
+			offsets = append(offsets, offsets[len(offsets)-1]+len(x))
+		}
+		header := fmt.Sprintf("1 %d 2 %d 3 %d ", offsets[0], offsets[1], offsets[2])
+		objStm := stream(fmt.Sprintf("/Type /ObjStm /N 3 /First %d", len(header)), []byte(header+objects))
+
+		data := "%PDF-1.5\n" +
+			"4 0 obj\n" + content + "\nendobj\n" +
+			"5 0 obj\n" + objStm + "\nendobj\n" +
+			"6 0 obj\n<< /Type /XRef /Root 1 0 R /Size 7 /W [1 2 1] /Length 0 >>\nstream\n\nendstream\nendobj\n"
+
+		doc, err := pdf.Open([]byte(data))
+		require.NoError(t, err)
+		require.Len(t, doc.Pages(), 1)
+		require.Equal(t, "Compressed objects", doc.Pages()[0].Text())
+	})
+
+	t.Run("invalid object stream", func(t *testing.T) {
+		for _, n := range []string{"-1", "1000000000000"} {
+			objStm := stream("/Type /ObjStm /N "+n+" /First 4", []byte("1 0 << >>"))
+			data := "%PDF-1.5\n" +
+				"1 0 obj\n" + objStm + "\nendobj\n"
+
+			_, err := pdf.Open([]byte(data))
+			require.ErrorIs(t, err, pdf.ErrInvalid)
+		}
+	})
+}
+
+func TestText(t *testing.T) {
+	t.Run("simple font", func(t *testing.T) {
+		doc, err := pdf.Open(newDocument(helvetica,
+			"BT /F1 12 Tf 72 720 Td (Readeck is a simple web application that lets you save the pre-) Tj "+
+				"0 -14 Td (cious readable content of web pages you like) Tj "+
+				"( and want to keep forever.) Tj "+
+				"0 -40 Td [(New) -300 (para) 20 (graph) -300 (\\223quoted\\224)] TJ ET",
+			"BT /F1 10 Tf 1 0 0 1 72 700 Tm (Page two) Tj T* ET",
+		).bytes())
+		require.NoError(t, err)
+		require.Len(t, doc.Pages(), 2)
+
+		require.Equal(t,
+			"Readeck is a simple web application that lets you save the precious "+
+				"readable content of web pages you like and want to keep forever.\n\n"+
+				"New paragraph “quoted”",
+			doc.Pages()[0].Text(),
+		)
+		require.Equal(t, "Page two", doc.Pages()[1].Text())
+	})
+
+	t.Run("differences", func(t *testing.T) {
+		doc, err := pdf.Open(newDocument(
+			"<< /Type /Font /Subtype /Type1 /Encoding << /Differences [1 /T /h /e /f_i /uni00E9 /space /quoteright] >> >>",
+			"BT /F1 12 Tf 72 720 Td <01020306070405> Tj ET",
+		).bytes())
+		require.NoError(t, err)
+		require.Equal(t, "The ’fié", doc.Pages()[0].Text())
+	})
+
+	t.Run("to unicode", func(t *testing.T) {
+		cmap := "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n" +
+			"1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+			"2 beginbfchar <0001> <0048> <0002> <00E9> endbfchar\n" +
+			"2 beginbfrange <0010> <0013> <006C> <0020> <0021> [<0020> <D83DDE00>] endbfrange\n" +
+			"endcmap CMapName currentdict /CMap defineresource pop end end"
+
+		b := newDocument("", "BT /F1 12 Tf 72 720 Td <000100020010001000130020> Tj <0021> Tj ET")
+		toUnicode := b.add(stream("", []byte(cmap)))
+		b.objects[2] = fmt.Sprintf(
+			"<< /Type /Font /Subtype /Type0 /Encoding /Identity-H /ToUnicode %d 0 R "+
+				"/DescendantFonts [<< /Type /Font /Subtype /CIDFontType2 /DW 600 /W [1 [500 500] 16 18 400] >>] >>",
+			toUnicode,
+		)
+
+		doc, err := pdf.Open(b.bytes())
+		require.NoError(t, err)
+		require.Equal(t, "Héllo 😀", doc.Pages()[0].Text())
+	})
+
+	t.Run("forms and inline images", func(t *testing.T) {
+		b := newDocument(helvetica,
+			"BI /W 2 /H 1 /BPC 8 /CS /G ID \x00EI\xff EI "+
+				"q 1 0 0 1 0 0 cm /X1 Do Q")
+		form := b.add(stream("/Type /XObject /Subtype /Form /BBox [0 0 612 792]",
+			[]byte("BT /F1 12 Tf 72 720 Td (From a form) Tj ET")))
+		b.objects[1] = strings.Replace(b.objects[1], "/Resources <<",
+			fmt.Sprintf("/Resources << /XObject << /X1 %d 0 R >>", form), 1)
+
+		doc, err := pdf.Open(b.bytes())
+		require.NoError(t, err)
+		require.Equal(t, "From a form", doc.Pages()[0].Text())
+	})
+}
+
+func TestThumbnail(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		doc, err := pdf.Open(newDocument(helvetica, "BT /F1 12 Tf 72 720 Td (Some text) Tj ET").bytes())
+		require.NoError(t, err)
+
+		img := doc.Pages()[0].Thumbnail()
+		require.NotNil(t, img)
+		require.Equal(t, 420, img.Bounds().Dx())
+		require.Equal(t, 543, img.Bounds().Dy())
+	})
+
+	t.Run("image", func(t *testing.T) {
+		pixels := bytes.Repeat([]byte{0xff, 0x00, 0x00}, 40*30)
+		b := newDocument(helvetica, "q 500 0 0 400 50 50 cm /Im1 Do Q")
+		im := b.add(stream("/Type /XObject /Subtype /Image /Width 40 /Height 30 /ColorSpace /DeviceRGB /BitsPerComponent 8", pixels))
+		b.objects[1] = strings.Replace(b.objects[1], "/Resources <<",
+			fmt.Sprintf("/Resources << /XObject << /Im1 %d 0 R >>", im), 1)
+
+		doc, err := pdf.Open(b.bytes())
+		require.NoError(t, err)
+
+		img := doc.Pages()[0].Thumbnail()
+		require.Equal(t, 40, img.Bounds().Dx())
+		require.Equal(t, 30, img.Bounds().Dy())
+		r, g, _, _ := img.At(10, 10).RGBA()
+		require.Equal(t, uint32(0xffff), r)
+		require.Equal(t, uint32(0), g)
+	})
+}
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// thumbnailWidth is the width of a text preview.
+	thumbnailWidth = 420
+	// minImageCoverage is the part of the page an image must cover
+	// to be used as the page's thumbnail.
+	minImageCoverage = 0.2
+	// maxImagePixels limits the size of a decoded image.
+	maxImagePixels = 50_000_000
+)
+
+// Thumbnail returns a picture of the page. It's the largest image
+// drawn on the page, when it covers a good part of it, or a preview
+// of the page's text. We don't render PDF pages, this is only a
+// rough idea of what the page looks like.
+func (p *Page) Thumbnail() image.Image {
+	r := p.read()
+	w, h := p.Size()
+
+	var best *pageImage
+	for i, x := range r.images {
+		if best == nil || x.area > best.area {
+			best = &r.images[i]
+		}
+	}
+	if best != nil && best.area >= w*h*minImageCoverage {
+		if res := p.doc.decodeImage(best.stream); res != nil {
+			return res
+		}
+	}
+
+	return textPreview(r.pageText(), w, h)
+}
+
+// decodeImage returns an image from an image XObject. It only supports
+// JPEG images and 8 bits per component samples.
+func (d *Document) decodeImage(s *Stream) image.Image {
+	names, params := d.filters(s)
+
+	if len(names) > 0 && (names[len(names)-1] == "DCTDecode" || names[len(names)-1] == "DCT") {
+		data, err := decodeStream(s.Data, names[:len(names)-1], params)
+		if err != nil {
+			return nil
+		}
+		res, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil
+		}
+		return res
+	}
+
+	width, _ := d.number(s.Dict["Width"])
+	height, _ := d.number(s.Dict["Height"])
+	bpc, _ := d.number(s.Dict["BitsPerComponent"])
+	w, h := int(width), int(height)
+	if bpc != 8 || w <= 0 || h <= 0 || w*h > maxImagePixels {
+		return nil
+	}
+
+	components := 0
+	switch cs := d.Resolve(s.Dict["ColorSpace"]).(type) {
+	case Name:
+		components = map[Name]int{"DeviceGray": 1, "DeviceRGB": 3, "DeviceCMYK": 4}[cs]
+	case Array:
+		if len(cs) == 2 && d.Resolve(cs[0]) == Name("ICCBased") {
+			n, _ := d.number(d.dict(cs[1])["N"])
+			components = int(n)
+		}
+	}
+	if components == 0 {
+		return nil
+	}
+
+	data, err := decodeStream(s.Data, names, params)
+	if err != nil || len(data) < w*h*components {
+		return nil
+	}
+
+	rect := image.Rect(0, 0, w, h)
+	switch components {
+	case 1:
+		return &image.Gray{Pix: data[:w*h], Stride: w, Rect: rect}
+	case 3:
+		res := image.NewRGBA(rect)
+		for i := range w * h {
+			copy(res.Pix[i*4:], data[i*3:i*3+3])
+			res.Pix[i*4+3] = 0xff
+		}
+		return res
+	case 4:
+		return &image.CMYK{Pix: data[:w*h*4], Stride: w * 4, Rect: rect}
+	}
+
+	return nil
+}
+
+// textPreview draws the text on a page shaped image.
+func textPreview(text string, w, h float64) image.Image {
+	width := thumbnailWidth
+	height := width * 1414 / 1000
+	if w > 0 && h > 0 {
+		height = int(float64(width) * min(h/w, 3))
+	}
+
+	res := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(res, res.Bounds(), image.White, image.Point{}, draw.Src)
+
+	face := basicfont.Face7x13
+	margin := width / 12
+	lineHeight := face.Height + 3
+	maxChars := (width - margin*2) / face.Advance
+
+	drawer := &font.Drawer{
+		Dst:  res,
+		Src:  image.NewUniform(color.Gray{Y: 0x40}),
+		Face: face,
+	}
+
+	y := margin + face.Ascent
+	for _, p := range strings.Split(text, "\n\n") {
+		for _, line := range wrapText(p, maxChars) {
+			if y > height-margin {
+				return res
+			}
+			drawer.Dot = fixed.P(margin, y)
+			drawer.DrawString(line)
+			y += lineHeight
+		}
+		y += lineHeight / 2
+	}
+
+	return res
+}
+
+// wrapText splits a text in lines of, at most, n characters.
+func wrapText(s string, n int) []string {
+	res := []string{}
+	line := []rune{}
+	for _, word := range strings.Fields(s) {
+		w := []rune(word)
+		if len(line) > 0 && len(line)+1+len(w) > n {
+			res = append(res, string(line))
+			line = line[:0]
+		}
+		if len(line) > 0 {
+			line = append(line, ' ')
+		}
+		line = append(line, w...)
+		for len(line) > n {
+			res = append(res, string(line[:n]))
+			line = append(line[:0], line[n:]...)
+		}
+	}
+	if len(line) > 0 {
+		res = append(res, string(line))
+	}
+	return res
+}
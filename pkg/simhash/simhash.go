@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package simhash computes similarity hashes of texts.
+//
+// A similarity hash (Charikar's simhash) is a 64-bit fingerprint for which
+// close texts get close values. The number of different bits between
+// two fingerprints, their distance, tells how similar the texts are.
+package simhash
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+const (
+	// shingleSize is the number of words of each text feature.
+	shingleSize = 3
+
+	// MinWords is the number of words under which a text is too short
+	// to get a meaningful fingerprint.
+	MinWords = 50
+
+	// MaxDistance is the distance under which (inclusive) two
+	// fingerprints are considered similar.
+	MaxDistance = 7
+
+	// bandCount is the number of parts a fingerprint is split into
+	// by an [Index]. Two fingerprints at MaxDistance or closer have at
+	// least one equal part.
+	bandCount = MaxDistance + 1
+)
+
+// Sum returns the fingerprint of a text. It returns 0 when the text
+// has less than MinWords words.
+func Sum(text string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) < MinWords {
+		return 0
+	}
+
+	var v [64]int
+	h := fnv.New64a()
+	for i := range len(words) - shingleSize + 1 {
+		h.Reset()
+		for _, w := range words[i : i+shingleSize] {
+			h.Write([]byte(w)) //nolint:errcheck
+			h.Write([]byte{0}) //nolint:errcheck
+		}
+		x := h.Sum64()
+		for b := range 64 {
+			if x&(1<<b) != 0 {
+				v[b]++
+			} else {
+				v[b]--
+			}
+		}
+	}
+
+	var res uint64
+	for b := range 64 {
+		if v[b] > 0 {
+			res |= 1 << b
+		}
+	}
+	return res
+}
+
+// Distance returns the number of different bits between two fingerprints.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Similar returns true when two fingerprints are at most at MaxDistance.
+// An empty fingerprint is similar to nothing.
+func Similar(a, b uint64) bool {
+	return a != 0 && b != 0 && Distance(a, b) <= MaxDistance
+}
+
+type entry struct {
+	id   int
+	hash uint64
+}
+
+// Index finds similar fingerprints among a list.
+type Index struct {
+	bands [bandCount]map[uint64][]entry
+}
+
+// NewIndex returns an empty [Index].
+func NewIndex() *Index {
+	idx := &Index{}
+	for i := range idx.bands {
+		idx.bands[i] = map[uint64][]entry{}
+	}
+	return idx
+}
+
+// band returns the i-th part of a fingerprint.
+func band(h uint64, i int) uint64 {
+	size := 64 / bandCount
+	start := i * size
+	if i == bandCount-1 {
+		return h >> start
+	}
+	return (h >> start) & (1<<size - 1)
+}
+
+// Add adds a fingerprint with its identifier to the index.
+// Empty fingerprints are ignored.
+func (idx *Index) Add(id int, h uint64) {
+	if h == 0 {
+		return
+	}
+	for i := range idx.bands {
+		k := band(h, i)
+		idx.bands[i][k] = append(idx.bands[i][k], entry{id, h})
+	}
+}
+
+// Find returns the identifiers of the fingerprints similar to h.
+func (idx *Index) Find(h uint64) []int {
+	if h == 0 {
+		return nil
+	}
+
+	seen := map[int]struct{}{}
+	res := []int{}
+	for i := range idx.bands {
+		for _, e := range idx.bands[i][band(h, i)] {
+			if _, ok := seen[e.id]; ok {
+				continue
+			}
+			seen[e.id] = struct{}{}
+			if Similar(h, e.hash) {
+				res = append(res, e.id)
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package simhash_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/simhash"
+)
+
+const article = `
+The town council met on Tuesday evening to discuss the future of the old
+railway station, a building that has stood empty for more than a decade.
+Several residents came forward with proposals, ranging from a covered market
+to a small museum dedicated to the history of the line. The mayor reminded
+the audience that any project would have to find its own funding, since the
+municipal budget is already stretched by the renovation of the two schools.
+A local association presented a detailed plan for a cooperative café and
+workshop space, run by volunteers and financed by a crowdfunding campaign.
+Its members explained that the roof had been inspected last spring and that
+the structure was sound, although the windows and the heating system would
+need to be replaced. Some council members expressed doubts about the
+long-term viability of a volunteer-run venue, pointing to similar projects in
+neighbouring towns that closed after a few years. Others argued that the
+building had been neglected for too long and that any activity would be
+better than letting it decay further. The debate went on for nearly three
+hours. In the end, the council agreed to launch a public consultation during
+the summer and to commission a study of the renovation costs. A final
+decision is expected before the end of the year, once the results of the
+consultation and the study are known. In the meantime, the association plans
+to organise open days so that residents can visit the station and see its
+condition for themselves.
+`
+
+const other = `
+Preheat the oven and butter a large baking dish. Peel the apples, remove
+their cores and cut them into thin slices. In a bowl, mix the flour, the
+sugar and a pinch of salt, then rub in the cold butter with your fingertips
+until the mixture looks like coarse breadcrumbs. Arrange the apple slices in
+the dish, sprinkle them with cinnamon and a little lemon juice, then cover
+them evenly with the crumble. Bake for about forty minutes, until the top is
+golden and the fruit juices bubble at the edges. Let it rest for a few
+minutes before serving with cream or vanilla ice cream. The crumble keeps
+well for two days in the fridge and can be warmed up in the oven.
+`
+
+func TestSum(t *testing.T) {
+	require.Equal(t, uint64(0), simhash.Sum(""))
+	require.Equal(t, uint64(0), simhash.Sum(" -- "))
+	require.Equal(t, uint64(0), simhash.Sum(strings.Repeat("word ", simhash.MinWords-1)))
+	require.NotEqual(t, uint64(0), simhash.Sum(strings.Repeat("word ", simhash.MinWords)))
+	require.Equal(t, simhash.Sum(article), simhash.Sum(strings.ToUpper(article)))
+
+	h := simhash.Sum(article)
+
+	t.Run("syndicated copy", func(t *testing.T) {
+		copy := "Originally published in the Town Gazette. Follow us for more stories.\n" +
+			article +
+			"\nSubscribe to our newsletter to receive the next articles. Share this story."
+		require.True(t, simhash.Similar(h, simhash.Sum(copy)))
+	})
+
+	t.Run("edited copy", func(t *testing.T) {
+		copy := strings.Replace(article, "Tuesday", "Wednesday", 1)
+		copy = strings.Replace(copy, "three", "two", 1)
+		require.True(t, simhash.Similar(h, simhash.Sum(copy)))
+	})
+
+	t.Run("other text", func(t *testing.T) {
+		require.False(t, simhash.Similar(h, simhash.Sum(other)))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		require.False(t, simhash.Similar(0, 0))
+		require.False(t, simhash.Similar(h, 0))
+	})
+}
+
+func TestIndex(t *testing.T) {
+	idx := simhash.NewIndex()
+	idx.Add(1, simhash.Sum(article))
+	idx.Add(2, simhash.Sum(other))
+	idx.Add(3, simhash.Sum("From our partners. "+article))
+	idx.Add(4, 0)
+
+	require.ElementsMatch(t, []int{1, 3}, idx.Find(simhash.Sum(article)))
+	require.Equal(t, []int{2}, idx.Find(simhash.Sum(other)))
+	require.Empty(t, idx.Find(0))
+
+	// A fingerprint at the maximum distance is found, one bit further
+	// it is not.
+	h := simhash.Sum(article)
+	idx = simhash.NewIndex()
+	idx.Add(1, h^(1|1<<8|1<<16|1<<24|1<<32|1<<40|1<<48))
+	idx.Add(2, h^(1|1<<8|1<<16|1<<24|1<<32|1<<40|1<<48|1<<56))
+	require.Equal(t, []int{1}, idx.Find(h))
+}
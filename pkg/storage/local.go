@@ -0,0 +1,213 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local is a storage on the local file system.
+type Local struct {
+	root string
+}
+
+// NewLocal returns a [Local] storage using the given
+// root directory.
+func NewLocal(root string) *Local {
+	return &Local{root: root}
+}
+
+// Root returns the storage's root directory.
+func (s *Local) Root() string {
+	return s.root
+}
+
+// Path returns the local path of a file.
+func (s *Local) Path(name string) (string, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(name)), nil
+}
+
+// Open implements [Storage].
+func (s *Local) Open(name string) (File, error) {
+	p, err := s.Path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := fd.Stat()
+	if err != nil {
+		fd.Close() //nolint:errcheck
+		return nil, err
+	}
+	if fi.IsDir() {
+		fd.Close() //nolint:errcheck
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return &localFile{fd, fi.Size()}, nil
+}
+
+// Create implements [Storage]. The content is written to a temporary
+// file that's renamed when the writer is closed.
+func (s *Local) Create(name string) (io.WriteCloser, error) {
+	p, err := s.Path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return nil, err
+	}
+
+	fd, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &localWriter{File: fd, dest: p}, nil
+}
+
+// Stat implements [Storage].
+func (s *Local) Stat(name string) (FileInfo, error) {
+	p, err := s.Path(name)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	fi, err := os.Stat(p)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if fi.IsDir() {
+		return FileInfo{}, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+
+	name, _ = cleanName(name)
+	return FileInfo{Name: name, Size: fi.Size(), ModTime: fi.ModTime()}, nil
+}
+
+// Remove implements [Storage]. Empty parent directories
+// are removed as well.
+func (s *Local) Remove(name string) error {
+	p, err := s.Path(name)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(p); err != nil {
+		return err
+	}
+
+	s.removeEmptyDirs(filepath.Dir(p))
+	return nil
+}
+
+// RemoveAll implements [Storage].
+func (s *Local) RemoveAll(dir string) error {
+	dir = cleanDir(dir)
+	if dir == "" {
+		return &fs.PathError{Op: "remove", Path: dir, Err: ErrInvalidName}
+	}
+
+	p := filepath.Join(s.root, filepath.FromSlash(dir))
+	if err := os.RemoveAll(p); err != nil {
+		return err
+	}
+
+	s.removeEmptyDirs(filepath.Dir(p))
+	return nil
+}
+
+// Walk implements [Storage].
+func (s *Local) Walk(dir string, fn WalkFunc) error {
+	dir = cleanDir(dir)
+	err := filepath.WalkDir(filepath.Join(s.root, filepath.FromSlash(dir)), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+
+		return fn(FileInfo{
+			Name:    filepath.ToSlash(name),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	})
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// removeEmptyDirs removes empty directories, from dir
+// up to the storage's root.
+func (s *Local) removeEmptyDirs(dir string) {
+	root := filepath.Clean(s.root)
+	for dir != root && strings.HasPrefix(dir, root) {
+		// Just try to remove and if it's not empty it will complain
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+type localFile struct {
+	*os.File
+	size int64
+}
+
+func (f *localFile) Size() int64 {
+	return f.size
+}
+
+type localWriter struct {
+	*os.File
+	dest string
+}
+
+// Close closes the temporary file and moves it to its destination.
+func (w *localWriter) Close() error {
+	if err := w.File.Close(); err != nil {
+		os.Remove(w.Name()) //nolint:errcheck
+		return err
+	}
+	if err := os.Chmod(w.Name(), 0o640); err != nil {
+		os.Remove(w.Name()) //nolint:errcheck
+		return err
+	}
+	return os.Rename(w.Name(), w.dest)
+}
+
+// Abort discards the file.
+func (w *localWriter) Abort() error {
+	w.File.Close() //nolint:errcheck
+	return os.Remove(w.Name())
+}
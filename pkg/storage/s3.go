@@ -0,0 +1,471 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// s3BlockSize is the minimum size of a range read. Reading an archive
+// performs many small reads, close to each other, that are served
+// from the last fetched block.
+const s3BlockSize = 256 << 10
+
+// s3Timeout is the default time limit of a request, including
+// the transfer of its body.
+const s3Timeout = 5 * time.Minute
+
+// S3Config contains an S3 storage configuration.
+type S3Config struct {
+	// Endpoint is the service URL (ie. https://s3.eu-west-3.amazonaws.com).
+	Endpoint string
+	Region   string
+	Bucket   string
+	// Prefix is prepended to every object key.
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	// PathStyle sets the bucket in the URL path instead of
+	// the host name. Most self-hosted services need it.
+	PathStyle bool
+	// Client is the HTTP client. It defaults to a client with
+	// a 5 minutes timeout.
+	Client *http.Client
+}
+
+// S3 is a storage using an S3 compatible object storage.
+type S3 struct {
+	endpoint *url.URL
+	cfg      S3Config
+	now      func() time.Time
+}
+
+// NewS3 returns an [S3] storage.
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("s3: endpoint and bucket are required")
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("s3: invalid endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: s3Timeout}
+	}
+	cfg.Prefix = cleanDir(cfg.Prefix)
+
+	return &S3{endpoint: u, cfg: cfg, now: time.Now}, nil
+}
+
+// objectURL returns an object's URL. An empty key returns
+// the bucket's URL.
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	p := strings.TrimSuffix(u.Path, "/")
+	if s.cfg.PathStyle {
+		p += "/" + s.cfg.Bucket
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+	}
+	p += "/" + key
+
+	u.Path = p
+	u.RawPath = escapePath(p)
+	return &u
+}
+
+// do sends a signed request.
+func (s *S3) do(ctx context.Context, method string, u *url.URL, header http.Header, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	r, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if header != nil {
+		r.Header = header
+	}
+	if body != nil {
+		r.ContentLength = size
+	}
+	if payloadHash == "" {
+		payloadHash = emptyPayloadHash
+	}
+
+	signRequest(r, s.cfg.AccessKey, s.cfg.SecretKey, s.cfg.Region, payloadHash, s.now())
+	return s.cfg.Client.Do(r)
+}
+
+// checkResponse returns an error when the response is not a success.
+// A 404 error wraps [fs.ErrNotExist].
+func checkResponse(rsp *http.Response, op, name string) error {
+	if rsp.StatusCode >= 200 && rsp.StatusCode < 300 {
+		return nil
+	}
+	defer rsp.Body.Close() //nolint:errcheck
+
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	xml.NewDecoder(io.LimitReader(rsp.Body, 1<<16)).Decode(&e) //nolint:errcheck
+
+	if rsp.StatusCode == http.StatusNotFound {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	msg := rsp.Status
+	if e.Code != "" {
+		msg += " " + e.Code
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return &fs.PathError{Op: op, Path: name, Err: errors.New("s3: " + msg)}
+}
+
+// Open implements [Storage].
+func (s *S3) Open(name string) (File, error) {
+	info, err := s.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3File{s: s, name: info.Name, size: info.Size}, nil
+}
+
+// Create implements [Storage]. The content is written to a temporary
+// file that's sent to the object storage when the writer is closed.
+func (s *S3) Create(name string) (io.WriteCloser, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := os.CreateTemp("", "readeck-s3-*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3Writer{s: s, name: name, fd: fd, hash: sha256.New()}, nil
+}
+
+// Stat implements [Storage].
+func (s *S3) Stat(name string) (FileInfo, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	rsp, err := s.do(context.Background(), http.MethodHead, s.objectURL(s.cfg.Prefix+name), nil, nil, 0, "")
+	if err != nil {
+		return FileInfo{}, err
+	}
+	defer rsp.Body.Close() //nolint:errcheck
+	if err = checkResponse(rsp, "stat", name); err != nil {
+		return FileInfo{}, err
+	}
+
+	res := FileInfo{Name: name, Size: rsp.ContentLength}
+	res.ModTime, _ = http.ParseTime(rsp.Header.Get("Last-Modified"))
+	return res, nil
+}
+
+// Remove implements [Storage].
+func (s *S3) Remove(name string) error {
+	name, err := cleanName(name)
+	if err != nil {
+		return err
+	}
+
+	// Deleting a missing object is not an error for S3
+	if _, err = s.Stat(name); err != nil {
+		return err
+	}
+
+	rsp, err := s.do(context.Background(), http.MethodDelete, s.objectURL(s.cfg.Prefix+name), nil, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close() //nolint:errcheck
+	return checkResponse(rsp, "remove", name)
+}
+
+// RemoveAll implements [Storage].
+func (s *S3) RemoveAll(dir string) error {
+	dir = cleanDir(dir)
+	if dir == "" {
+		return &fs.PathError{Op: "remove", Path: dir, Err: ErrInvalidName}
+	}
+
+	names := []string{}
+	err := s.Walk(dir, func(info FileInfo) error {
+		names = append(names, info.Name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err = s.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Walk implements [Storage].
+func (s *S3) Walk(dir string, fn WalkFunc) error {
+	prefix := s.cfg.Prefix + cleanDir(dir)
+	token := ""
+
+	for {
+		u := s.objectURL("")
+		q := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			q.Set("prefix", prefix)
+		}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u.RawQuery = q.Encode()
+
+		rsp, err := s.do(context.Background(), http.MethodGet, u, nil, nil, 0, "")
+		if err != nil {
+			return err
+		}
+		if err = checkResponse(rsp, "walk", dir); err != nil {
+			return err
+		}
+
+		var res struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(rsp.Body).Decode(&res)
+		rsp.Body.Close() //nolint:errcheck
+		if err != nil {
+			return err
+		}
+
+		for _, x := range res.Contents {
+			if strings.HasSuffix(x.Key, "/") {
+				continue
+			}
+			err = fn(FileInfo{
+				Name:    strings.TrimPrefix(x.Key, s.cfg.Prefix),
+				Size:    x.Size,
+				ModTime: x.LastModified,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			return nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+// readRange reads a part of an object.
+func (s *S3) readRange(name string, off, size int64) ([]byte, error) {
+	header := http.Header{}
+	header.Set("Range", "bytes="+strconv.FormatInt(off, 10)+"-"+strconv.FormatInt(off+size-1, 10))
+
+	rsp, err := s.do(context.Background(), http.MethodGet, s.objectURL(s.cfg.Prefix+name), header, nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close() //nolint:errcheck
+	if err = checkResponse(rsp, "read", name); err != nil {
+		return nil, err
+	}
+
+	if rsp.StatusCode == http.StatusOK {
+		// The server ignored the range
+		if _, err = io.CopyN(io.Discard, rsp.Body, off); err != nil {
+			return nil, err
+		}
+	}
+
+	res := make([]byte, size)
+	n, err := io.ReadFull(rsp.Body, res)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil
+	}
+	return res[:n], err
+}
+
+// s3File is an object opened for reading. Every read is a range
+// request. The last fetched block is kept to serve the following
+// reads when possible. ReadAt can be called concurrently, a block
+// is never modified once fetched.
+type s3File struct {
+	s      *S3
+	name   string
+	size   int64
+	offset int64
+
+	mu       sync.Mutex
+	block    []byte
+	blockOff int64
+}
+
+// cachedBlock returns the last fetched block and its offset.
+func (f *s3File) cachedBlock() ([]byte, int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.block, f.blockOff
+}
+
+// setBlock replaces the last fetched block.
+func (f *s3File) setBlock(block []byte, off int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.block, f.blockOff = block, off
+}
+
+func (f *s3File) Size() int64 {
+	return f.size
+}
+
+func (f *s3File) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if off >= f.size {
+		return 0, io.EOF
+	}
+
+	n := 0
+	for n < len(p) && off < f.size {
+		block, blockOff := f.cachedBlock()
+		if off < blockOff || off >= blockOff+int64(len(block)) {
+			size := min(max(int64(len(p)-n), s3BlockSize), f.size-off)
+			var err error
+			if block, err = f.s.readRange(f.name, off, size); err != nil {
+				return n, err
+			}
+			if len(block) == 0 {
+				return n, io.ErrUnexpectedEOF
+			}
+			blockOff = off
+			f.setBlock(block, blockOff)
+		}
+
+		c := copy(p[n:], block[off-blockOff:])
+		n += c
+		off += int64(c)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *s3File) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *s3File) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.size
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *s3File) Close() error {
+	f.block = nil
+	return nil
+}
+
+// s3Writer writes an object's content to a temporary file
+// and sends it on close.
+type s3Writer struct {
+	s    *S3
+	name string
+	fd   *os.File
+	hash hash.Hash
+	size int64
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	n, err := w.fd.Write(p)
+	w.hash.Write(p[:n])
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *s3Writer) Close() error {
+	defer w.Abort() //nolint:errcheck
+
+	if _, err := w.fd.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	var body io.Reader = io.NopCloser(w.fd)
+	if w.size == 0 {
+		body = http.NoBody
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+	rsp, err := w.s.do(context.Background(), http.MethodPut, w.s.objectURL(w.s.cfg.Prefix+w.name),
+		header, body, w.size, hex.EncodeToString(w.hash.Sum(nil)),
+	)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close() //nolint:errcheck
+	return checkResponse(rsp, "create", w.name)
+}
+
+// Abort discards the content.
+func (w *s3Writer) Abort() error {
+	w.fd.Close() //nolint:errcheck
+	return os.Remove(w.fd.Name())
+}
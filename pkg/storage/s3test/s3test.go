@@ -0,0 +1,218 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package s3test provides a minimal S3 compatible server, storing
+// objects in memory, for tests. It only supports path style requests
+// on a single bucket.
+package s3test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type object struct {
+	data    []byte
+	modTime time.Time
+}
+
+// Server is a fake S3 server.
+type Server struct {
+	*httptest.Server
+	Bucket    string
+	AccessKey string
+
+	mu       sync.Mutex
+	objects  map[string]object
+	requests []Request
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Key    string
+	Range  string
+}
+
+// NewServer starts a new server. It must be closed by the caller.
+func NewServer(bucket, accessKey string) *Server {
+	s := &Server{
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		objects:   map[string]object{},
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// Requests returns the received requests.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// Reset clears the received requests.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+// Keys returns all the object keys.
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]string, 0, len(s.objects))
+	for k := range s.objects {
+		res = append(res, k)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// ServeHTTP implements [http.Handler].
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential="+s.AccessKey+"/") {
+		writeError(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	p := strings.TrimPrefix(r.URL.Path, "/")
+	bucket, key, _ := strings.Cut(p, "/")
+	if bucket != s.Bucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Key: key, Range: r.Header.Get("Range")})
+	s.mu.Unlock()
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		s.list(w, r)
+	case key == "":
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		s.get(w, r, key)
+	case r.Method == http.MethodPut:
+		s.put(w, r, key)
+	case r.Method == http.MethodDelete:
+		s.mu.Lock()
+		delete(s.objects, key)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request, key string) {
+	s.mu.Lock()
+	o, ok := s.objects[key]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, "", o.modTime, bytes.NewReader(o.data))
+}
+
+func (s *Server) put(w http.ResponseWriter, r *http.Request, key string) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody")
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		writeError(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch")
+		return
+	}
+
+	s.mu.Lock()
+	s.objects[key] = object{data: data, modTime: time.Now().UTC().Truncate(time.Second)}
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+type listContent struct {
+	Key          string    `xml:"Key"`
+	Size         int64     `xml:"Size"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+type listResult struct {
+	XMLName               xml.Name      `xml:"ListBucketResult"`
+	Name                  string        `xml:"Name"`
+	Prefix                string        `xml:"Prefix"`
+	KeyCount              int           `xml:"KeyCount"`
+	IsTruncated           bool          `xml:"IsTruncated"`
+	NextContinuationToken string        `xml:"NextContinuationToken,omitempty"`
+	Contents              []listContent `xml:"Contents"`
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("list-type") != "2" {
+		writeError(w, http.StatusNotImplemented, "NotImplemented")
+		return
+	}
+	prefix := q.Get("prefix")
+	start := q.Get("continuation-token")
+	maxKeys := 1000
+	if x, err := strconv.Atoi(q.Get("max-keys")); err == nil && x > 0 {
+		maxKeys = x
+	}
+
+	res := listResult{Name: s.Bucket, Prefix: prefix}
+	for _, k := range s.Keys() {
+		if !strings.HasPrefix(k, prefix) || k <= start {
+			continue
+		}
+		if len(res.Contents) == maxKeys {
+			res.IsTruncated = true
+			res.NextContinuationToken = res.Contents[len(res.Contents)-1].Key
+			break
+		}
+		s.mu.Lock()
+		o := s.objects[k]
+		s.mu.Unlock()
+		res.Contents = append(res.Contents, listContent{
+			Key:          k,
+			Size:         int64(len(o.data)),
+			LastModified: o.modTime,
+		})
+	}
+	res.KeyCount = len(res.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))   //nolint:errcheck
+	xml.NewEncoder(w).Encode(res) //nolint:errcheck
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))       //nolint:errcheck
+	xml.NewEncoder(w).Encode(struct { //nolint:errcheck
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+	}{Code: code})
+}
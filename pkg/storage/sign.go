@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	signAlgorithm  = "AWS4-HMAC-SHA256"
+	signTimeFormat = "20060102T150405Z"
+	signDateFormat = "20060102"
+
+	// emptyPayloadHash is the SHA256 sum of an empty payload.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// signRequest adds an AWS signature version 4 to a request.
+// The payloadHash is the hex encoded SHA256 sum of the request body.
+func signRequest(r *http.Request, accessKey, secretKey, region, payloadHash string, now time.Time) {
+	now = now.UTC()
+	r.Header.Set("X-Amz-Date", now.Format(signTimeFormat))
+	r.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+
+	// Canonical headers
+	headers := map[string]string{"host": host}
+	for k, v := range r.Header {
+		k = strings.ToLower(k)
+		if k == "content-type" || strings.HasPrefix(k, "x-amz-") || k == "range" {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+
+	canonicalHeaders := new(strings.Builder)
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		escapePath(r.URL.Path),
+		canonicalQuery(r.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format(signDateFormat), region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		signAlgorithm,
+		now.Format(signTimeFormat),
+		scope,
+		hexSum([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSum([]byte("AWS4"+secretKey), now.Format(signDateFormat))
+	key = hmacSum(key, region)
+	key = hmacSum(key, "s3")
+	key = hmacSum(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSum(key, stringToSign))
+
+	r.Header.Set("Authorization",
+		signAlgorithm+
+			" Credential="+accessKey+"/"+scope+
+			", SignedHeaders="+signedHeaders+
+			", Signature="+signature,
+	)
+}
+
+func hmacSum(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSum(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// canonicalQuery returns the sorted and encoded query string.
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	parts := []string{}
+	for _, k := range keys {
+		values := slices.Clone(q[k])
+		slices.Sort(values)
+		for _, v := range values {
+			parts = append(parts, escape(k)+"="+escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath encodes every segment of a path.
+func escapePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	segments := strings.Split(p, "/")
+	for i, x := range segments {
+		segments[i] = escape(x)
+	}
+	return strings.Join(segments, "/")
+}
+
+// escape encodes a string the way AWS expects it: every
+// byte but the unreserved characters is percent encoded.
+func escape(s string) string {
+	const hexChars = "0123456789ABCDEF"
+	res := new(strings.Builder)
+	for i := range len(s) {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			res.WriteByte(c)
+			continue
+		}
+		res.WriteByte('%')
+		res.WriteByte(hexChars[c>>4])
+		res.WriteByte(hexChars[c&15])
+	}
+	return res.String()
+}
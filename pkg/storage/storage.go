@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package storage provides file storage backends. Files are identified
+// by slash separated names, relative to the storage's root, and can be
+// stored on a local disk or in an S3 compatible object storage.
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrInvalidName is returned when a file name is not valid.
+var ErrInvalidName = errors.New("invalid file name")
+
+// Storage is a file storage.
+type Storage interface {
+	// Open opens a file for reading.
+	Open(name string) (File, error)
+
+	// Create returns a writer to a new file. The file is only
+	// visible, and replaces an existing file with the same name,
+	// once the writer is closed.
+	Create(name string) (io.WriteCloser, error)
+
+	// Stat returns a file's information.
+	Stat(name string) (FileInfo, error)
+
+	// Remove removes a file.
+	Remove(name string) error
+
+	// RemoveAll removes every file under the given directory.
+	RemoveAll(dir string) error
+
+	// Walk calls fn for every file under the given directory.
+	// An empty dir walks the whole storage.
+	Walk(dir string, fn WalkFunc) error
+}
+
+// File is a file opened from a storage. It supports random
+// access reads so an archive can be read without loading
+// the whole file.
+type File interface {
+	io.ReadSeekCloser
+	io.ReaderAt
+	Size() int64
+}
+
+// FileInfo describes a file.
+type FileInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// WalkFunc is the function called by [Storage.Walk] for each file.
+type WalkFunc func(info FileInfo) error
+
+// Copy copies a file from a storage to another one. Both storages
+// can be the same.
+func Copy(dst Storage, dstName string, src Storage, srcName string) error {
+	r, err := src.Open(srcName)
+	if err != nil {
+		return err
+	}
+	defer r.Close() //nolint:errcheck
+
+	w, err := dst.Create(dstName)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(w, r); err != nil {
+		if a, ok := w.(interface{ Abort() error }); ok {
+			a.Abort() //nolint:errcheck
+		}
+		return err
+	}
+	return w.Close()
+}
+
+// cleanName returns a clean file name. It fails when the name
+// is empty or tries to escape from the storage's root.
+func cleanName(name string) (string, error) {
+	if name == "" || strings.Contains(name, "\\") {
+		return "", &fs.PathError{Op: "open", Path: name, Err: ErrInvalidName}
+	}
+	res := path.Clean("/" + name)[1:]
+	if res == "" {
+		return "", &fs.PathError{Op: "open", Path: name, Err: ErrInvalidName}
+	}
+	return res, nil
+}
+
+// cleanDir returns a clean directory name, with a trailing slash,
+// or an empty string for the storage's root.
+func cleanDir(dir string) string {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	if dir == "" {
+		return ""
+	}
+	return dir + "/"
+}
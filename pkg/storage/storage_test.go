@@ -0,0 +1,306 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storage_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/storage"
+	"codeberg.org/readeck/readeck/pkg/storage/s3test"
+)
+
+func writeFile(t *testing.T, s storage.Storage, name string, data []byte) {
+	t.Helper()
+	w, err := s.Create(name)
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+}
+
+func readFile(t *testing.T, s storage.Storage, name string) []byte {
+	t.Helper()
+	f, err := s.Open(name)
+	require.NoError(t, err)
+	defer f.Close() //nolint:errcheck
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return data
+}
+
+func walkNames(t *testing.T, s storage.Storage, dir string) []string {
+	t.Helper()
+	res := []string{}
+	require.NoError(t, s.Walk(dir, func(info storage.FileInfo) error {
+		res = append(res, info.Name)
+		return nil
+	}))
+	return res
+}
+
+func testStorage(t *testing.T, s storage.Storage) {
+	t.Run("create and open", func(t *testing.T) {
+		writeFile(t, s, "ab/file.txt", []byte("some content"))
+		require.Equal(t, []byte("some content"), readFile(t, s, "ab/file.txt"))
+		require.Equal(t, []byte("some content"), readFile(t, s, "/ab/../ab/file.txt"))
+
+		info, err := s.Stat("ab/file.txt")
+		require.NoError(t, err)
+		require.Equal(t, "ab/file.txt", info.Name)
+		require.Equal(t, int64(12), info.Size)
+
+		// Replace
+		writeFile(t, s, "ab/file.txt", []byte("new"))
+		require.Equal(t, []byte("new"), readFile(t, s, "ab/file.txt"))
+
+		// Empty file
+		writeFile(t, s, "ab/empty.txt", nil)
+		require.Empty(t, readFile(t, s, "ab/empty.txt"))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := s.Open("ab/nope.txt")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		_, err = s.Stat("ab/nope.txt")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		require.ErrorIs(t, s.Remove("ab/nope.txt"), fs.ErrNotExist)
+		_, err = s.Stat("ab")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		_, err := s.Open("")
+		require.ErrorIs(t, err, storage.ErrInvalidName)
+		_, err = s.Create("/")
+		require.ErrorIs(t, err, storage.ErrInvalidName)
+		require.ErrorIs(t, s.RemoveAll("/"), storage.ErrInvalidName)
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		w, err := s.Create("ab/aborted.txt")
+		require.NoError(t, err)
+		_, err = w.Write([]byte("test"))
+		require.NoError(t, err)
+		require.NoError(t, w.(interface{ Abort() error }).Abort())
+		_, err = s.Stat("ab/aborted.txt")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("random access", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		z := zip.NewWriter(buf)
+		for i := range 20 {
+			w, err := z.Create(fmt.Sprintf("file-%02d.txt", i))
+			require.NoError(t, err)
+			_, err = w.Write([]byte(strings.Repeat(fmt.Sprintf("%d ", i), 1000)))
+			require.NoError(t, err)
+		}
+		require.NoError(t, z.Close())
+		writeFile(t, s, "cd/archive.zip", buf.Bytes())
+
+		f, err := s.Open("cd/archive.zip")
+		require.NoError(t, err)
+		defer f.Close() //nolint:errcheck
+		require.Equal(t, int64(buf.Len()), f.Size())
+
+		zr, err := zip.NewReader(f, f.Size())
+		require.NoError(t, err)
+		require.Len(t, zr.File, 20)
+
+		fd, err := zr.Open("file-12.txt")
+		require.NoError(t, err)
+		data, err := io.ReadAll(fd)
+		require.NoError(t, err)
+		require.Equal(t, strings.Repeat("12 ", 1000), string(data))
+
+		p := make([]byte, 4)
+		n, err := f.ReadAt(p, f.Size()-2)
+		require.Equal(t, 2, n)
+		require.ErrorIs(t, err, io.EOF)
+
+		pos, err := f.Seek(-4, io.SeekEnd)
+		require.NoError(t, err)
+		require.Equal(t, f.Size()-4, pos)
+		n, err = f.Read(p)
+		require.NoError(t, err)
+		require.Equal(t, 4, n)
+		require.Equal(t, buf.Bytes()[buf.Len()-4:], p)
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		require.NoError(t, storage.Copy(s, "cd/copy.txt", s, "ab/file.txt"))
+		require.Equal(t, []byte("new"), readFile(t, s, "cd/copy.txt"))
+		require.ErrorIs(t, storage.Copy(s, "cd/copy2.txt", s, "ab/nope.txt"), fs.ErrNotExist)
+	})
+
+	t.Run("walk and remove", func(t *testing.T) {
+		writeFile(t, s, "cd/sub/file.txt", []byte("test"))
+
+		require.ElementsMatch(t, []string{
+			"ab/empty.txt", "ab/file.txt",
+			"cd/archive.zip", "cd/copy.txt", "cd/sub/file.txt",
+		}, walkNames(t, s, ""))
+		require.ElementsMatch(t, []string{
+			"cd/archive.zip", "cd/copy.txt", "cd/sub/file.txt",
+		}, walkNames(t, s, "cd"))
+		require.Empty(t, walkNames(t, s, "ef"))
+
+		require.NoError(t, s.Remove("ab/empty.txt"))
+		require.NoError(t, s.RemoveAll("cd/"))
+		require.NoError(t, s.RemoveAll("ef"))
+		require.Equal(t, []string{"ab/file.txt"}, walkNames(t, s, ""))
+	})
+}
+
+func TestLocal(t *testing.T) {
+	s := storage.NewLocal(t.TempDir())
+	testStorage(t, s)
+
+	p, err := s.Path("ab/file.txt")
+	require.NoError(t, err)
+	require.FileExists(t, p)
+	require.NoError(t, s.Remove("ab/file.txt"))
+	require.NoDirExists(t, s.Root()+"/ab")
+	require.DirExists(t, s.Root())
+}
+
+func TestS3(t *testing.T) {
+	srv := s3test.NewServer("readeck", "access-key")
+	defer srv.Close()
+
+	t.Run("storage", func(t *testing.T) {
+		s, err := storage.NewS3(storage.S3Config{
+			Endpoint:  srv.URL,
+			Bucket:    "readeck",
+			Prefix:    "/data/",
+			AccessKey: "access-key",
+			SecretKey: "secret",
+			PathStyle: true,
+		})
+		require.NoError(t, err)
+		testStorage(t, s)
+		require.Equal(t, []string{"data/ab/file.txt"}, srv.Keys())
+	})
+
+	t.Run("range reads", func(t *testing.T) {
+		s, err := storage.NewS3(storage.S3Config{
+			Endpoint:  srv.URL,
+			Bucket:    "readeck",
+			AccessKey: "access-key",
+			PathStyle: true,
+		})
+		require.NoError(t, err)
+
+		data := bytes.Repeat([]byte("0123456789"), 100_000)
+		writeFile(t, s, "large.bin", data)
+		srv.Reset()
+
+		f, err := s.Open("large.bin")
+		require.NoError(t, err)
+		p := make([]byte, 10)
+		_, err = f.ReadAt(p, 500_000)
+		require.NoError(t, err)
+		require.Equal(t, []byte("0123456789"), p)
+
+		// Served from the same block
+		_, err = f.ReadAt(p, 500_010)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		require.Equal(t, []s3test.Request{
+			{Method: "HEAD", Key: "large.bin"},
+			{Method: "GET", Key: "large.bin", Range: "bytes=500000-762143"},
+		}, srv.Requests())
+	})
+
+	t.Run("concurrent reads", func(t *testing.T) {
+		s, err := storage.NewS3(storage.S3Config{
+			Endpoint:  srv.URL,
+			Bucket:    "readeck",
+			AccessKey: "access-key",
+			PathStyle: true,
+		})
+		require.NoError(t, err)
+
+		data := bytes.Repeat([]byte("0123456789"), 100_000)
+		writeFile(t, s, "large.bin", data)
+
+		f, err := s.Open("large.bin")
+		require.NoError(t, err)
+		defer f.Close() //nolint:errcheck
+
+		var wg sync.WaitGroup
+		errs := make([]error, 8)
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p := make([]byte, 10)
+				for j := range 20 {
+					off := int64((i*20+j)*3_000 + i)
+					if _, err := f.ReadAt(p, off); err != nil {
+						errs[i] = err
+						return
+					}
+					if !bytes.Equal(data[off:off+10], p) {
+						errs[i] = fmt.Errorf("wrong data at %d", off)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		for _, err := range errs {
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("list pages", func(t *testing.T) {
+		s, err := storage.NewS3(storage.S3Config{
+			Endpoint:  srv.URL,
+			Bucket:    "readeck",
+			Prefix:    "pages",
+			AccessKey: "access-key",
+			PathStyle: true,
+		})
+		require.NoError(t, err)
+
+		expected := []string{}
+		for i := range 1005 {
+			name := fmt.Sprintf("f/%04d", i)
+			writeFile(t, s, name, []byte("x"))
+			expected = append(expected, name)
+		}
+		require.Equal(t, expected, walkNames(t, s, "f"))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		s, err := storage.NewS3(storage.S3Config{
+			Endpoint:  srv.URL,
+			Bucket:    "readeck",
+			AccessKey: "wrong",
+			PathStyle: true,
+		})
+		require.NoError(t, err)
+		_, err = s.Stat("large.bin")
+		require.ErrorContains(t, err, "403 Forbidden")
+		err = s.Walk("", func(storage.FileInfo) error { return nil })
+		require.ErrorContains(t, err, "403 Forbidden AccessDenied")
+
+		_, err = storage.NewS3(storage.S3Config{Endpoint: "ftp://example.net", Bucket: "test"})
+		require.Error(t, err)
+		_, err = storage.NewS3(storage.S3Config{Endpoint: "https://example.net"})
+		require.Error(t, err)
+	})
+}
@@ -17,6 +17,9 @@ import (
 
 // Event is an event sent to the wire. It contains a name and a value that can be unmarshalled later.
 type Event struct {
+	// ID is set by the event managers that need to identify an event
+	// on acknowledgement.
+	ID    int    `json:"-"`
 	Name  string `json:"name"`
 	Value []byte `json:"value"`
 }
@@ -36,6 +39,15 @@ type EventManager interface {
 	On(name string, f EventHandler)
 }
 
+// AckEventManager is an event manager that keeps an event until it's
+// acknowledged. When the process stops before an event is acknowledged,
+// the event is delivered again.
+type AckEventManager interface {
+	EventManager
+	// Ack marks an event as fully processed.
+	Ack(e Event) error
+}
+
 // EagerEventManager is a simple event manager using channels for event management.
 type EagerEventManager struct {
 	ch       chan Event
@@ -11,11 +11,20 @@ import (
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// ErrStopped is returned when a task is launched while the task
+// manager is stopping.
+var ErrStopped = errors.New("task manager is stopping")
+
+// retryDelays contains the delays, in seconds, between two attempts
+// of a failed task. The last delay is used for every following attempt.
+var retryDelays = []int{30, 120, 600, 1800, 3600}
+
 type (
 	// Operation is the event sent when we launch a task.
 	Operation struct {
@@ -25,13 +34,19 @@ type (
 
 	// Payload is the stored content of a task.
 	Payload struct {
-		ID    uuid.UUID `json:"id"`
-		Delay int       `json:"delay"`
-		Data  []byte    `json:"data"`
+		ID      uuid.UUID `json:"id"`
+		Delay   int       `json:"delay"`
+		Attempt int       `json:"attempt,omitempty"`
+		Data    []byte    `json:"data"`
 	}
 
-	// TaskHandler is the function called on a task.
-	TaskHandler func(*Operation, *Payload)
+	// TaskHandler is the function called on a task. The task fails
+	// when it returns an error.
+	TaskHandler func(*Operation, *Payload) error
+
+	// DeadLetterHandler is the function called when a task failed
+	// on its last attempt.
+	DeadLetterHandler func(*Operation, *Payload, error)
 
 	// TaskManager is the task manager.
 	TaskManager struct {
@@ -45,6 +60,22 @@ type (
 		workerGroup *sync.WaitGroup
 		timerGroup  *sync.WaitGroup
 		keyPrefix   string
+		payloadTTL  time.Duration
+		retries     int
+		taskRetries map[string]int
+		deadLetters DeadLetterHandler
+		schedules   []Task
+		stopTicker  chan struct{}
+
+		// stopping is set when Stop is called. stopMu prevents an
+		// operation from being sent while the event manager stops.
+		stopping atomic.Bool
+		stopMu   sync.RWMutex
+
+		// timers are the pending operations, they're canceled on stop.
+		timerMu   sync.Mutex
+		timers    map[uint64]*time.Timer
+		nextTimer uint64
 	}
 
 	// TaskManagerOption is a function that sets TaskManager option upon creation.
@@ -58,8 +89,10 @@ type (
 		tm             *TaskManager
 		name           string
 		delay          int
+		interval       time.Duration
+		retries        int
 		unmarshallData func(data []byte) interface{}
-		taskHandler    func(data interface{})
+		taskHandler    func(data interface{}) error
 	}
 )
 
@@ -74,6 +107,11 @@ func NewTaskManager(m EventManager, s Store, options ...TaskManagerOption) *Task
 		workerGroup: &sync.WaitGroup{},
 		timerGroup:  &sync.WaitGroup{},
 		keyPrefix:   "tasks",
+		payloadTTL:  time.Second * 30,
+		taskRetries: make(map[string]int),
+		schedules:   []Task{},
+		stopTicker:  make(chan struct{}),
+		timers:      make(map[uint64]*time.Timer),
 	}
 
 	for _, o := range options {
@@ -99,11 +137,45 @@ func WithOperationPrefix(p string) TaskManagerOption {
 	}
 }
 
+// WithPayloadTTL sets how long a task payload is kept in the store,
+// after the task's delay, when the task doesn't run.
+func WithPayloadTTL(d time.Duration) TaskManagerOption {
+	return func(tm *TaskManager) {
+		tm.payloadTTL = d
+	}
+}
+
+// WithRetries sets how many times a failed task is launched again.
+// A task fails when its handler returns an error or panics.
+func WithRetries(n int) TaskManagerOption {
+	return func(tm *TaskManager) {
+		tm.retries = n
+	}
+}
+
+// WithDeadLetterHandler registers the function that receives
+// the tasks that failed on their last attempt.
+func WithDeadLetterHandler(f DeadLetterHandler) TaskManagerOption {
+	return func(tm *TaskManager) {
+		tm.deadLetters = f
+	}
+}
+
+// ack acknowledges an event when the event manager needs it.
+func (tm *TaskManager) ack(e Event) {
+	if em, ok := tm.em.(AckEventManager); ok {
+		if err := em.Ack(e); err != nil {
+			slog.Error("event acknowledgement", slog.Any("err", err))
+		}
+	}
+}
+
 // onTask is the task's event handler.
 func (tm *TaskManager) onTask(e Event) {
 	var op Operation
 	if err := json.Unmarshal(e.Value, &op); err != nil {
 		slog.Error("", slog.Any("err", err))
+		tm.ack(e)
 		return
 	}
 	l := slog.With(
@@ -116,6 +188,7 @@ func (tm *TaskManager) onTask(e Event) {
 	p0, err := tm.getPayload(&op)
 	if err != nil {
 		l.Debug("", slog.Any("err", err))
+		tm.ack(e)
 		return
 	}
 
@@ -126,19 +199,34 @@ func (tm *TaskManager) onTask(e Event) {
 		if err = tm.delPayload(&op); err != nil {
 			l.Error("removing payload", slog.Any("err", err))
 		}
+		tm.ack(e)
+		return
+	}
+
+	// Enqueue the task to the queue. The event is not acknowledged
+	// when the task manager stops, so it can be delivered again.
+	tm.timerMu.Lock()
+	defer tm.timerMu.Unlock()
+	if tm.stopping.Load() {
 		return
 	}
 
-	// Enqueue the task to the queue
+	id := tm.nextTimer
+	tm.nextTimer++
 	tm.timerGroup.Add(1)
-	time.AfterFunc(time.Second*time.Duration(p0.Delay), func() {
+	tm.timers[id] = time.AfterFunc(time.Second*time.Duration(p0.Delay), func() {
 		defer tm.timerGroup.Done()
 
+		tm.timerMu.Lock()
+		delete(tm.timers, id)
+		tm.timerMu.Unlock()
+
 		// Fetch the payload
 		// If the payload is gone, the task was canceled
 		p1, err := tm.getPayload(&op)
 		if err != nil {
 			l.Error("", slog.Any("err", err))
+			tm.ack(e)
 			return
 		}
 
@@ -147,25 +235,86 @@ func (tm *TaskManager) onTask(e Event) {
 		// we don't need it anymore.
 		if p0.ID != p1.ID {
 			l.Error("not matching payloads")
+			tm.ack(e)
 			return
 		}
 
 		// Push the worker to the queue.
 		tm.queue <- func() {
-			defer func() {
-				if r := recover(); r != nil {
-					l.Error("task error", slog.Any("err", err))
-				}
-				// The payload can be removed when we're done.
-				if err := tm.delPayload(&op); err != nil {
-					l.Error("removing payload", slog.Any("err", err))
+			if err := runHandler(f, &op, &p1); err != nil {
+				l.Error("task error", slog.Any("err", err))
+				if errors.Is(tm.retry(&op, &p1, err), ErrStopped) {
+					// Left for the next start
+					return
 				}
-			}()
-			f(&op, &p1)
+				tm.ack(e)
+				return
+			}
+
+			// The payload can be removed when we're done.
+			if err := tm.delPayload(&op); err != nil {
+				l.Error("removing payload", slog.Any("err", err))
+			}
+			tm.ack(e)
 		}
 	})
 }
 
+// runHandler calls a task handler and returns its error, or an error
+// when the handler panics.
+func runHandler(f TaskHandler, op *Operation, p *Payload) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return f(op, p)
+}
+
+// retry launches a failed operation again, after a delay that grows
+// with each attempt. When the operation has no attempt left, its
+// payload is removed and passed to the dead letter handler.
+// It returns [ErrStopped], and leaves the operation as is, when the
+// task manager is stopping.
+func (tm *TaskManager) retry(op *Operation, p *Payload, cause error) error {
+	l := slog.With(
+		slog.Any("operation", *op),
+		slog.Int("attempt", p.Attempt+1),
+	)
+
+	retries, ok := tm.taskRetries[op.Name]
+	if !ok {
+		retries = tm.retries
+	}
+
+	if p.Attempt < retries {
+		payload := Payload{
+			ID:      uuid.New(),
+			Delay:   retryDelays[min(p.Attempt, len(retryDelays)-1)],
+			Attempt: p.Attempt + 1,
+			Data:    p.Data,
+		}
+		err := tm.launch(op, payload)
+		if err == nil {
+			l.Warn("task will be retried", slog.Int("delay", payload.Delay))
+			return nil
+		}
+		if errors.Is(err, ErrStopped) {
+			return err
+		}
+		l.Error("task retry", slog.Any("err", err))
+	}
+
+	if err := tm.delPayload(op); err != nil {
+		l.Error("removing payload", slog.Any("err", err))
+	}
+	if tm.deadLetters != nil {
+		tm.deadLetters(op, p, cause)
+	}
+	return nil
+}
+
 // getOperationKey returns the store key for an operation.
 func (tm *TaskManager) getOperationKey(name string, id interface{}) string {
 	return fmt.Sprintf("%s:%s:%v", tm.keyPrefix, name, id)
@@ -187,7 +336,8 @@ func (tm *TaskManager) delPayload(t *Operation) error {
 	return tm.store.Del(tm.getOperationKey(t.Name, t.ID))
 }
 
-// Start starts the events listener and the process workers.
+// Start starts the events listener, the process workers and
+// the periodic tasks.
 func (tm *TaskManager) Start() {
 	go tm.em.Listen()
 	for i := 0; i < tm.numWorkers; i++ {
@@ -199,14 +349,56 @@ func (tm *TaskManager) Start() {
 			}
 		}(i)
 	}
+
+	for _, t := range tm.schedules {
+		go tm.tick(t)
+	}
+}
+
+// tick launches a periodic task on every interval, until the
+// task manager stops.
+// The task always has the same ID so, when several processes share
+// the same store, only the last launched operation runs.
+func (tm *TaskManager) tick(t Task) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-tm.stopTicker:
+			return
+		case <-ticker.C:
+			if err := t.Run(t.name, nil); err != nil {
+				t.Log().Error("periodic task", slog.Any("err", err))
+			}
+		}
+	}
 }
 
 // Stop stops the event listener and wait for running tasks to finish.
+// The pending operations are canceled. Their events are not acknowledged,
+// so an [AckEventManager] delivers them again on the next start.
 func (tm *TaskManager) Stop() {
+	// Stop the periodic tasks
+	close(tm.stopTicker)
+
+	// No operation can be launched anymore
+	tm.stopMu.Lock()
+	tm.stopping.Store(true)
+	tm.stopMu.Unlock()
+
 	// Stop the event bus (can't receive any new event)
 	tm.em.Stop()
 
-	// Wait for all timers
+	// Cancel the pending timers and wait for the ones that already fired
+	tm.timerMu.Lock()
+	for id, t := range tm.timers {
+		if t.Stop() {
+			tm.timerGroup.Done()
+		}
+		delete(tm.timers, id)
+	}
+	tm.timerMu.Unlock()
 	tm.timerGroup.Wait()
 
 	// Stop the worker group
@@ -216,12 +408,6 @@ func (tm *TaskManager) Stop() {
 
 // Launch sends a task order for later launch.
 func (tm *TaskManager) Launch(name string, id interface{}, delay int, data interface{}) error {
-	t := Operation{
-		Name: name,
-		ID:   id,
-	}
-
-	// Store the payload
 	payload := Payload{
 		ID:    uuid.New(),
 		Delay: delay,
@@ -231,17 +417,32 @@ func (tm *TaskManager) Launch(name string, id interface{}, delay int, data inter
 		return err
 	}
 
+	return tm.launch(&Operation{Name: name, ID: id}, payload)
+}
+
+// launch stores an operation's payload and sends the task event.
+// It returns [ErrStopped] once the task manager is stopping.
+func (tm *TaskManager) launch(op *Operation, payload Payload) error {
+	tm.stopMu.RLock()
+	defer tm.stopMu.RUnlock()
+	if tm.stopping.Load() {
+		return ErrStopped
+	}
+
 	p, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
-	err = tm.store.Set(tm.getOperationKey(name, id), string(p), time.Duration(delay)+time.Second*30)
+	err = tm.store.Set(
+		tm.getOperationKey(op.Name, op.ID), string(p),
+		time.Second*time.Duration(payload.Delay)+tm.payloadTTL,
+	)
 	if err != nil {
 		return err
 	}
 
 	// Send the event
-	e, _ := json.Marshal(t)
+	e, _ := json.Marshal(op)
 	return tm.em.Push("task", e)
 }
 
@@ -256,27 +457,57 @@ func (tm *TaskManager) Register(name string, f TaskHandler) {
 // NewTask creates a new Task instance.
 func (tm *TaskManager) NewTask(name string, options ...TaskOption) Task {
 	t := Task{
-		tm:    tm,
-		name:  name,
-		delay: 0,
+		tm:      tm,
+		name:    name,
+		delay:   0,
+		retries: -1,
 	}
 
 	for _, o := range options {
 		o(&t)
 	}
-	tm.Register(t.name, func(_ *Operation, p *Payload) {
+
+	// A periodic task runs again on the next interval.
+	if t.interval > 0 && t.retries < 0 {
+		t.retries = 0
+	}
+	if t.retries >= 0 {
+		tm.Lock()
+		tm.taskRetries[t.name] = t.retries
+		tm.Unlock()
+	}
+	tm.Register(t.name, func(_ *Operation, p *Payload) error {
 		var data interface{} = p.Data
 		if t.unmarshallData != nil {
 			data = t.unmarshallData(p.Data)
 		}
-		t.taskHandler(data)
+		return t.taskHandler(data)
 	})
 
+	if t.interval > 0 {
+		tm.Lock()
+		tm.schedules = append(tm.schedules, t)
+		tm.Unlock()
+	}
+
 	return t
 }
 
 // WithTaskHandler adds the given handler to the task.
+// The task only fails when the handler panics.
 func WithTaskHandler(f func(data interface{})) TaskOption {
+	return func(t *Task) {
+		t.taskHandler = func(data interface{}) error {
+			f(data)
+			return nil
+		}
+	}
+}
+
+// WithTaskHandlerE adds the given handler to the task.
+// The task fails, and can be retried, when the handler returns
+// an error.
+func WithTaskHandlerE(f func(data interface{}) error) TaskOption {
 	return func(t *Task) {
 		t.taskHandler = f
 	}
@@ -296,6 +527,22 @@ func WithTaskDelay(d int) TaskOption {
 	}
 }
 
+// WithTaskInterval makes the task periodic. Once the task manager
+// is started, the task runs on every interval.
+func WithTaskInterval(d time.Duration) TaskOption {
+	return func(t *Task) {
+		t.interval = d
+	}
+}
+
+// WithTaskRetries sets how many times the task is launched again
+// when it fails. It overrides the task manager's value.
+func WithTaskRetries(n int) TaskOption {
+	return func(t *Task) {
+		t.retries = n
+	}
+}
+
 // Run launches the task.
 func (t Task) Run(id interface{}, data interface{}) error {
 	t.Log().Info("starting task", slog.Any("id", id))
@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package superbus_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/superbus"
+)
+
+// nopEventManager is an event manager that drops every event.
+type nopEventManager struct{}
+
+func (nopEventManager) Listen()                              {}
+func (nopEventManager) Stop()                                {}
+func (nopEventManager) Push(_ string, _ []byte) error        { return nil }
+func (nopEventManager) On(_ string, _ superbus.EventHandler) {}
+
+// ttlStore is a store that records the expiration of every key.
+type ttlStore struct {
+	data map[string]string
+	ttl  map[string]time.Duration
+}
+
+func (s *ttlStore) Get(key string) string {
+	return s.data[key]
+}
+
+func (s *ttlStore) Set(key, value string, expiration time.Duration) error {
+	s.data[key] = value
+	s.ttl[key] = expiration
+	return nil
+}
+
+func (s *ttlStore) Del(key string) error {
+	delete(s.data, key)
+	delete(s.ttl, key)
+	return nil
+}
+
+func TestLaunchPayloadTTL(t *testing.T) {
+	store := &ttlStore{
+		data: map[string]string{},
+		ttl:  map[string]time.Duration{},
+	}
+	tm := superbus.NewTaskManager(nopEventManager{}, store,
+		superbus.WithPayloadTTL(10*time.Second),
+	)
+
+	tests := []struct {
+		id       int
+		delay    int
+		expected time.Duration
+	}{
+		{1, 0, 10 * time.Second},
+		{2, 60, 70 * time.Second},
+		{3, 86400, 24*time.Hour + 10*time.Second},
+	}
+
+	for _, test := range tests {
+		// The delay is in seconds
+		require.NoError(t, tm.Launch("test", test.id, test.delay, nil))
+		key := fmt.Sprintf("tasks:test:%d", test.id)
+		require.NotEmpty(t, store.Get(key))
+		require.Equal(t, test.expected, store.ttl[key])
+	}
+}
+
+func TestStop(t *testing.T) {
+	newTaskManager := func(f superbus.TaskHandler) *superbus.TaskManager {
+		tm := superbus.NewTaskManager(
+			superbus.NewEagerEventManager(), superbus.NewMemStore(),
+			superbus.WithRetries(3),
+		)
+		tm.Register("test", f)
+		tm.Start()
+		return tm
+	}
+
+	t.Run("pending retry", func(t *testing.T) {
+		called := make(chan struct{}, 1)
+		tm := newTaskManager(func(_ *superbus.Operation, _ *superbus.Payload) error {
+			called <- struct{}{}
+			return errors.New("not now")
+		})
+		require.NoError(t, tm.Launch("test", 1, 0, nil))
+		<-called
+
+		// The retry waits for 30s, it's canceled
+		start := time.Now()
+		tm.Stop()
+		require.Less(t, time.Since(start), 5*time.Second)
+
+		require.ErrorIs(t, tm.Launch("test", 2, 0, nil), superbus.ErrStopped)
+	})
+
+	t.Run("failure while stopping", func(t *testing.T) {
+		called := make(chan struct{})
+		release := make(chan struct{})
+		tm := newTaskManager(func(_ *superbus.Operation, _ *superbus.Payload) error {
+			close(called)
+			<-release
+			return errors.New("not now")
+		})
+		require.NoError(t, tm.Launch("test", 1, 0, nil))
+		<-called
+
+		// The task fails after the event manager stopped,
+		// it can't be launched again.
+		stopped := make(chan struct{})
+		go func() {
+			tm.Stop()
+			close(stopped)
+		}()
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Fatal("task manager did not stop")
+		}
+	})
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package totp_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/totp"
+)
+
+// RFC 6238 SHA1 secret
+var secret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// RFC 6238 test vectors, truncated to 6 digits.
+	tests := []struct {
+		ts       int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			code, err := totp.Code(secret, time.Unix(test.ts, 0))
+			require.NoError(t, err)
+			require.Equal(t, test.expected, code)
+		})
+	}
+
+	_, err := totp.Code("not base32!", time.Now())
+	require.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+
+	require.True(t, totp.Validate(secret, "081804", now))
+	require.True(t, totp.Validate(secret, " 081 804 ", now))
+	require.True(t, totp.Validate(secret, "081804", now.Add(totp.Period)))
+	require.True(t, totp.Validate(secret, "081804", now.Add(-totp.Period)))
+	require.False(t, totp.Validate(secret, "081804", now.Add(3*totp.Period)))
+	require.False(t, totp.Validate(secret, "081805", now))
+	require.False(t, totp.Validate(secret, "81804", now))
+	require.False(t, totp.Validate("", "081804", now))
+
+	// The step is the code's one, not the current one
+	step, ok := totp.ValidateStep(secret, "081804", now.Add(totp.Period))
+	require.True(t, ok)
+	require.Equal(t, uint64(37037036), step)
+	_, ok = totp.ValidateStep(secret, "081805", now)
+	require.False(t, ok)
+
+	s := totp.NewSecret()
+	require.Len(t, s, 32)
+	code, err := totp.Code(s, now)
+	require.NoError(t, err)
+	require.True(t, totp.Validate(s, code, now))
+}
+
+func TestURI(t *testing.T) {
+	u, err := url.Parse(totp.URI("Readeck", "alice", "ABCDEF"))
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", u.Scheme)
+	require.Equal(t, "totp", u.Host)
+	require.Equal(t, "/Readeck:alice", u.Path)
+	require.Equal(t, url.Values{
+		"secret":    {"ABCDEF"},
+		"issuer":    {"Readeck"},
+		"algorithm": {"SHA1"},
+		"digits":    {"6"},
+		"period":    {"30"},
+	}, u.Query())
+}
@@ -0,0 +1,175 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package urlcanon normalizes URLs so the different addresses of
+// a same page can be compared.
+//
+// A Canonicalizer provides two operations:
+//   - Clean returns a URL without its tracking parameters and, for an AMP
+//     address, the URL of the original page. The result can still be
+//     used to retrieve the page.
+//   - Key returns a comparison key that ignores the scheme, the "www."
+//     prefix, a trailing slash and the query parameters' order.
+package urlcanon
+
+import (
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"codeberg.org/readeck/readeck/pkg/glob"
+)
+
+// DefaultTrackingParams is the default list of query parameters removed
+// from a URL. An entry can contain a "*" wildcard.
+var DefaultTrackingParams = []string{
+	"utm_*",
+	"_ga", "_gl", "gclid", "gclsrc", "dclid", "gbraid", "wbraid",
+	"fbclid", "igshid", "msclkid", "twclid", "yclid", "ttclid",
+	"mc_cid", "mc_eid", "_hsenc", "_hsmi", "mkt_tok", "vero_id",
+	"oly_anon_id", "oly_enc_id", "ref_src", "ref_url", "__twitter_impression",
+	"wt_mc", "wt_zmc", "s_cid", "spm",
+}
+
+// ampParams are the query parameters that only select an AMP version
+// of a page.
+var ampParams = []string{"amp", "amp_js_v", "amp_gsa", "usqp", "outputtype"}
+
+// Canonicalizer cleans URLs.
+type Canonicalizer struct {
+	params []string
+}
+
+// New returns a Canonicalizer that removes the given query parameters.
+// Parameter names are case insensitive.
+func New(params ...string) *Canonicalizer {
+	c := &Canonicalizer{params: make([]string, len(params))}
+	for i, p := range params {
+		c.params[i] = strings.ToLower(p)
+	}
+	return c
+}
+
+// Clean returns a copy of the URL without its fragment and tracking parameters.
+// When the URL is a known AMP address, it returns the URL of the original page.
+func (c *Canonicalizer) Clean(src *url.URL) *url.URL {
+	u := *src
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Host = strings.ToLower(u.Host)
+
+	if res := unwrapAMP(&u); res != nil {
+		u = *res
+	}
+
+	if u.RawQuery == "" {
+		return &u
+	}
+
+	q := u.Query()
+	changed := false
+	for k := range q {
+		if c.isTracking(k) {
+			q.Del(k)
+			changed = true
+		}
+	}
+	if changed {
+		u.RawQuery = q.Encode()
+	}
+
+	return &u
+}
+
+// Key returns the comparison key of a URL. Two URLs with the same key
+// are considered to point to the same page.
+func (c *Canonicalizer) Key(src *url.URL) string {
+	u := c.Clean(src)
+
+	host := strings.TrimPrefix(u.Hostname(), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	} else if p != "/" {
+		p = strings.TrimSuffix(p, "/")
+	}
+
+	res := host + p
+	if u.RawQuery != "" {
+		// Encode sorts the values by key
+		res += "?" + u.Query().Encode()
+	}
+	return res
+}
+
+func (c *Canonicalizer) isTracking(name string) bool {
+	name = strings.ToLower(name)
+	if slices.Contains(ampParams, name) {
+		return true
+	}
+	for _, p := range c.params {
+		if glob.Glob(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// unwrapAMP returns the original URL of an AMP address or nil when
+// the URL is not an AMP address.
+func unwrapAMP(u *url.URL) *url.URL {
+	var rest string
+	switch {
+	case strings.HasSuffix(u.Hostname(), ".cdn.ampproject.org"):
+		// https://example-com.cdn.ampproject.org/c/s/example.com/page
+		parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+		if len(parts) < 2 || !slices.Contains([]string{"c", "v", "i"}, parts[0]) {
+			return nil
+		}
+		rest = parts[1]
+	case isGoogleHost(u.Hostname()) && strings.HasPrefix(u.Path, "/amp/"):
+		// https://www.google.com/amp/s/example.com/page
+		rest = strings.TrimPrefix(u.Path, "/amp/")
+	default:
+		// https://example.com/page/amp or https://example.com/page.amp.html
+		trimmed := strings.TrimSuffix(u.Path, "/")
+		dir, name := path.Split(trimmed)
+		switch {
+		case name == "amp" && dir != "/":
+			res := *u
+			res.Path = dir
+			res.RawPath = ""
+			return &res
+		case strings.HasSuffix(name, ".amp.html"):
+			res := *u
+			res.Path = dir + strings.TrimSuffix(name, ".amp.html") + ".html"
+			res.RawPath = ""
+			return &res
+		}
+		return nil
+	}
+
+	scheme := "http"
+	if after, ok := strings.CutPrefix(rest, "s/"); ok {
+		scheme = "https"
+		rest = after
+	}
+
+	res, err := url.Parse(scheme + "://" + rest)
+	if err != nil || res.Host == "" {
+		return nil
+	}
+	res.RawQuery = u.RawQuery
+	return res
+}
+
+func isGoogleHost(host string) bool {
+	host = strings.TrimPrefix(host, "www.")
+	return host == "google.com" || strings.HasPrefix(host, "google.")
+}
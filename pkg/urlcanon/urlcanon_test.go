@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: © 2025 Olivier Meunier <olivier@neokraft.net>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package urlcanon_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/readeck/readeck/pkg/urlcanon"
+)
+
+func TestClean(t *testing.T) {
+	c := urlcanon.New(urlcanon.DefaultTrackingParams...)
+
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"https://example.net/", "https://example.net/"},
+		{"https://Example.NET/Page#top", "https://example.net/Page"},
+		{"https://example.net/?utm_source=rss&utm_medium=feed", "https://example.net/"},
+		{"https://example.net/a?id=2&UTM_Campaign=x&fbclid=abc", "https://example.net/a?id=2"},
+		{"https://example.net/a?b=2&a=1", "https://example.net/a?b=2&a=1"},
+		{"https://example.net/article/amp", "https://example.net/article/"},
+		{"https://example.net/article/amp/", "https://example.net/article/"},
+		{"https://example.net/amp/", "https://example.net/amp/"},
+		{"https://example.net/article.amp.html", "https://example.net/article.html"},
+		{"https://example.net/article?amp=1", "https://example.net/article"},
+		{"https://www.google.com/amp/s/example.net/article", "https://example.net/article"},
+		{"https://www.google.fr/amp/example.net/article", "http://example.net/article"},
+		{"https://example-net.cdn.ampproject.org/c/s/example.net/article?utm_source=x", "https://example.net/article"},
+		{"https://example-net.cdn.ampproject.org/", "https://example-net.cdn.ampproject.org/"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.src, func(t *testing.T) {
+			u, _ := url.Parse(test.src)
+			require.Equal(t, test.expected, c.Clean(u).String())
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	c := urlcanon.New("utm_*", "ref")
+
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"https://example.net", "example.net/"},
+		{"https://example.net/", "example.net/"},
+		{"http://www.example.net/page/", "example.net/page"},
+		{"https://example.net:443/page?utm_source=a", "example.net/page"},
+		{"https://example.net:8443/page", "example.net:8443/page"},
+		{"https://example.net/page?b=2&a=1&ref=home", "example.net/page?a=1&b=2"},
+		{"https://example.net/page?fbclid=1", "example.net/page?fbclid=1"},
+		{"https://www.google.com/amp/s/www.example.net/page/", "example.net/page"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.src, func(t *testing.T) {
+			u, _ := url.Parse(test.src)
+			require.Equal(t, test.expected, c.Key(u))
+		})
+	}
+}
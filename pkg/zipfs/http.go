@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"strconv"
@@ -25,6 +26,18 @@ import (
 // with deflate.
 type HTTPZipFile string
 
+// File is a zip file opened for random access reads.
+type File interface {
+	io.ReaderAt
+	io.Closer
+	Size() int64
+}
+
+// HTTPZipOpener serves, like [HTTPZipFile], the zip file returned by
+// the function. It lets the zip file live somewhere else than on the
+// local file system.
+type HTTPZipOpener func() (File, error)
+
 type serveOption func(w http.ResponseWriter, status int)
 
 func (f HTTPZipFile) ServeHTTP(w http.ResponseWriter, r *http.Request, options ...serveOption) {
@@ -33,25 +46,50 @@ func (f HTTPZipFile) ServeHTTP(w http.ResponseWriter, r *http.Request, options .
 		return
 	}
 
-	filename := r.URL.Path
 	zr, err := zip.OpenReader(string(f))
 	if err != nil {
-		f.error(w, err)
+		serveError(w, err)
 		return
 	}
 	defer zr.Close()
 
-	zf, err := f.getEntry(filename, zr)
+	serveZip(w, r, &zr.Reader, options...)
+}
+
+func (f HTTPZipOpener) ServeHTTP(w http.ResponseWriter, r *http.Request, options ...serveOption) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	fd, err := f()
+	if err != nil {
+		serveError(w, err, options...)
+		return
+	}
+	defer fd.Close() //nolint:errcheck
+
+	zr, err := zip.NewReader(fd, fd.Size())
+	if err != nil {
+		serveError(w, err, options...)
+		return
+	}
+
+	serveZip(w, r, zr, options...)
+}
+
+func serveZip(w http.ResponseWriter, r *http.Request, zr *zip.Reader, options ...serveOption) {
+	zf, err := getEntry(r.URL.Path, zr)
 	if err != nil {
-		f.error(w, err)
+		serveError(w, err, options...)
 		return
 	}
 
-	f.serveEntry(w, r, zf, options...)
+	serveEntry(w, r, zf, options...)
 }
 
 // getEntry returns a zip file entry. It must be.
-func (f HTTPZipFile) getEntry(name string, zr *zip.ReadCloser) (*zip.File, error) {
+func getEntry(name string, zr *zip.Reader) (*zip.File, error) {
 	for _, x := range zr.File {
 		if x.Name == name && !x.FileInfo().IsDir() {
 			return x, nil
@@ -60,8 +98,8 @@ func (f HTTPZipFile) getEntry(name string, zr *zip.ReadCloser) (*zip.File, error
 	return nil, os.ErrNotExist
 }
 
-func (f HTTPZipFile) serveEntry(w http.ResponseWriter, r *http.Request, zf *zip.File, options ...serveOption) {
-	if f.checkIfModifiedSince(r, zf.Modified.UTC()) {
+func serveEntry(w http.ResponseWriter, r *http.Request, zf *zip.File, options ...serveOption) {
+	if checkIfModifiedSince(r, zf.Modified.UTC()) {
 		applyOptions(options, w, http.StatusNotModified)
 		w.WriteHeader(http.StatusNotModified)
 		return
@@ -71,7 +109,7 @@ func (f HTTPZipFile) serveEntry(w http.ResponseWriter, r *http.Request, zf *zip.
 
 	fp, err := zf.Open()
 	if err != nil {
-		f.error(w, err, options...)
+		serveError(w, err, options...)
 		return
 	}
 	defer fp.Close() //nolint:errcheck
@@ -80,7 +118,7 @@ func (f HTTPZipFile) serveEntry(w http.ResponseWriter, r *http.Request, zf *zip.
 	buf := new(bytes.Buffer)
 	mtype, err := mimetype.DetectReader(io.TeeReader(fp, buf))
 	if err != nil {
-		f.error(w, err, options...)
+		serveError(w, err, options...)
 		return
 	}
 
@@ -99,7 +137,7 @@ func (f HTTPZipFile) serveEntry(w http.ResponseWriter, r *http.Request, zf *zip.
 
 		cfp, err := zf.OpenRaw()
 		if err != nil {
-			f.error(w, err, options...)
+			serveError(w, err, options...)
 			return
 		}
 
@@ -120,9 +158,9 @@ func (f HTTPZipFile) serveEntry(w http.ResponseWriter, r *http.Request, zf *zip.
 	}
 }
 
-func (f HTTPZipFile) error(w http.ResponseWriter, err error, options ...serveOption) {
+func serveError(w http.ResponseWriter, err error, options ...serveOption) {
 	status := http.StatusInternalServerError
-	if os.IsNotExist(err) {
+	if errors.Is(err, fs.ErrNotExist) {
 		status = http.StatusNotFound
 	}
 
@@ -130,7 +168,7 @@ func (f HTTPZipFile) error(w http.ResponseWriter, err error, options ...serveOpt
 	http.Error(w, http.StatusText(status), status)
 }
 
-func (f HTTPZipFile) checkIfModifiedSince(r *http.Request, modtime time.Time) bool {
+func checkIfModifiedSince(r *http.Request, modtime time.Time) bool {
 	ius := r.Header.Get("If-Modified-Since")
 	if ius == "" {
 		return false
@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"testing"
 
+	"codeberg.org/readeck/readeck/pkg/storage"
 	"codeberg.org/readeck/readeck/pkg/zipfs"
 	"github.com/stretchr/testify/require"
 )
@@ -139,4 +140,25 @@ func TestHttp(t *testing.T) {
 		zipfs.HTTPZipFile("fixtures/corrupt.zip").ServeHTTP(w, r)
 		assert.Equal(http.StatusInternalServerError, w.Result().StatusCode)
 	})
+
+	//nolint:bodyclose
+	t.Run("opener", func(t *testing.T) {
+		assert := require.New(t)
+		opener := zipfs.HTTPZipOpener(func() (zipfs.File, error) {
+			return storage.NewLocal("fixtures").Open("http.zip")
+		})
+
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest("GET", "test-base.txt", nil)
+		assert.NoError(err)
+		opener.ServeHTTP(w, r)
+		assert.Equal(http.StatusOK, w.Result().StatusCode)
+		assert.Equal("some content that's not compressed\n", w.Body.String())
+
+		w = httptest.NewRecorder()
+		zipfs.HTTPZipOpener(func() (zipfs.File, error) {
+			return storage.NewLocal("fixtures").Open("noop.zip")
+		}).ServeHTTP(w, r)
+		assert.Equal(http.StatusNotFound, w.Result().StatusCode)
+	})
 }
@@ -121,6 +121,13 @@ type Config struct {
 	ReplaceStrings          [][2]string       `json:"replace_strings"`
 	HTTPHeaders             map[string]string `json:"http_headers"`
 	Tests                   []FilterTest      `json:"tests"`
+
+	RequiresLogin      bool              `json:"requires_login,omitempty"`
+	LoginURI           string            `json:"login_uri,omitempty"`
+	LoginUsernameField string            `json:"login_username_field,omitempty"`
+	LoginPasswordField string            `json:"login_password_field,omitempty"`
+	LoginExtraFields   map[string]string `json:"login_extra_fields,omitempty"`
+	NotLoggedInXPath   []string          `json:"not_logged_in_xpath,omitempty"`
 }
 
 // FilterTest holds the values for a filter's test.
@@ -205,6 +212,12 @@ func newConfig(file io.Reader) (*Config, error) {
 		"find_string":           setReplaceString,
 		"replace_string":        setReplaceString,
 		"test_url":              setFilterTest,
+		"requires_login":        simpleBoolValue(&res.RequiresLogin),
+		"login_uri":             singleStringValue(&res.LoginURI),
+		"login_username_field":  singleStringValue(&res.LoginUsernameField),
+		"login_password_field":  singleStringValue(&res.LoginPasswordField),
+		"login_extra_fields":    setLoginExtraField,
+		"not_logged_in_xpath":   simpleStringValue(&res.NotLoggedInXPath),
 	}
 
 	for i, line := range entries {
@@ -244,6 +257,13 @@ func simpleStringValue(v *[]string) entryParser {
 	}
 }
 
+func singleStringValue(v *string) entryParser {
+	return func(_ *Config, i int, entries [][3]string) error {
+		*v = entries[i][2]
+		return nil
+	}
+}
+
 func simpleBoolValue(v *bool) entryParser {
 	return func(_ *Config, i int, entries [][3]string) error {
 		*v = entries[i][2] == "yes"
@@ -263,6 +283,19 @@ func setHeaderValue(cfg *Config, i int, entries [][3]string) error {
 	return nil
 }
 
+func setLoginExtraField(cfg *Config, i int, entries [][3]string) error {
+	name, value, ok := strings.Cut(entries[i][2], "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("invalid login extra field (%s)", entries[i][2])
+	}
+
+	if cfg.LoginExtraFields == nil {
+		cfg.LoginExtraFields = map[string]string{}
+	}
+	cfg.LoginExtraFields[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	return nil
+}
+
 func setReplaceString(cfg *Config, i int, entries [][3]string) error {
 	line := entries[i]
 	switch line[0] {
@@ -252,6 +252,35 @@ export default class extends Controller {
     await this.reload()
   }
 
+  /**
+   * editNote sets the note of the selected annotations
+   *
+   * @param {Event} evt received event
+   */
+  async editNote(evt) {
+    // Notes are present in the title of the annotation's last node
+    let current = ""
+    this.annotation.coveredAnnotations().forEach((n) => {
+      current = n.title || current
+    })
+
+    const note = window.prompt(evt.params.prompt, current)
+    if (note === null) {
+      return
+    }
+
+    const baseURL = new URL(`${this.apiUrlValue}/`, document.URL)
+    await this.iterCoveredAnnotations(async (id) => {
+      await request(new URL(id, baseURL), {
+        method: "PATCH",
+        body: {
+          note: note,
+        },
+      })
+    })
+    await this.reload()
+  }
+
   /**
    * delete removes the selected annotations
    */